@@ -0,0 +1,80 @@
+// Command retention-purge applies the data retention periods (RETENTION_<CATEGORY>_DAYS) outside of the
+// server's daily job. With -dry-run it reports what would be deleted or anonymised without changing anything.
+//
+//	go run ./cmd/retention-purge [-category rejected_candidates] [-dry-run]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage"
+	"seculoc-back/internal/adapter/storage/postgres"
+	"seculoc-back/internal/core/service"
+	"seculoc-back/internal/platform/logger"
+)
+
+func main() {
+	category := flag.String("category", "", "only purge this category (default: all)")
+	dryRun := flag.Bool("dry-run", false, "report what would be purged without deleting anything")
+	flag.Parse()
+
+	viper.SetConfigFile(".env")
+	viper.AutomaticEnv()
+	if err := viper.ReadInConfig(); err != nil {
+		fmt.Fprintf(os.Stderr, "Config file not found: %s \n", err)
+	}
+
+	logger.Init(viper.GetString("ENV"))
+	log := logger.Get()
+	defer logger.Sync()
+
+	dbURL := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s",
+		viper.GetString("DB_USER"),
+		viper.GetString("DB_PASSWORD"),
+		viper.GetString("DB_HOST"),
+		viper.GetString("DB_PORT"),
+		viper.GetString("DB_NAME"),
+		viper.GetString("DB_SSL_MODE"),
+	)
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dbURL)
+	if err != nil {
+		log.Fatal("Unable to connect to database", zap.Error(err))
+	}
+	defer pool.Close()
+
+	// Deleting a file does not need the master keys: the raw backend is enough
+	backend, err := storage.BackendFromEnv()
+	if err != nil {
+		log.Fatal("failed to open storage", zap.Error(err))
+	}
+
+	svc := service.NewRetentionService(postgres.NewTxManager(pool), backend, log)
+	var reports []service.RetentionReport
+	if *category != "" {
+		var report *service.RetentionReport
+		report, err = svc.Purge(ctx, *category, *dryRun)
+		if report != nil {
+			reports = append(reports, *report)
+		}
+	} else {
+		reports, err = svc.Run(ctx, *dryRun)
+	}
+	if err != nil {
+		log.Error("retention purge failed", zap.Error(err))
+	}
+
+	out, _ := json.MarshalIndent(reports, "", "  ")
+	fmt.Println(string(out))
+	if err != nil {
+		os.Exit(1)
+	}
+}
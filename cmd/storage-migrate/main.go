@@ -0,0 +1,66 @@
+// Command storage-migrate copies the files of the local store (STORAGE_DIR) into the S3 bucket
+// configured by the S3_* variables, before switching STORAGE_DRIVER to "s3".
+//
+//	go run ./cmd/storage-migrate [-prefix properties/] [-overwrite] [-dry-run]
+//
+// It can be run several times: files already present in the bucket are skipped.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage"
+	"seculoc-back/internal/adapter/storage/local"
+	"seculoc-back/internal/adapter/storage/s3"
+	"seculoc-back/internal/platform/logger"
+)
+
+func main() {
+	prefix := flag.String("prefix", "", "only migrate files whose name starts with this prefix")
+	overwrite := flag.Bool("overwrite", false, "replace files already present in the bucket")
+	dryRun := flag.Bool("dry-run", false, "list what would be copied without writing anything")
+	flag.Parse()
+
+	viper.SetConfigFile(".env")
+	viper.AutomaticEnv()
+	if err := viper.ReadInConfig(); err != nil {
+		fmt.Fprintf(os.Stderr, "Config file not found: %s \n", err)
+	}
+
+	logger.Init(viper.GetString("ENV"))
+	log := logger.Get()
+	defer logger.Sync()
+
+	src, err := local.NewFileStore()
+	if err != nil {
+		log.Fatal("failed to open local store", zap.Error(err))
+	}
+	dst, err := s3.NewFileStore()
+	if err != nil {
+		log.Fatal("failed to configure s3 store", zap.Error(err))
+	}
+
+	report, err := storage.Migrate(src, dst, *prefix, *overwrite, *dryRun)
+	if err != nil {
+		log.Fatal("migration failed", zap.Error(err))
+	}
+
+	for name, err := range report.Failed {
+		log.Error("file not migrated", zap.String("file", name), zap.Error(err))
+	}
+	log.Info("storage migration finished",
+		zap.String("source", src.BaseDir),
+		zap.Bool("dry_run", *dryRun),
+		zap.Int("copied", len(report.Copied)),
+		zap.Int("skipped", len(report.Skipped)),
+		zap.Int("failed", len(report.Failed)),
+	)
+	if len(report.Failed) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,70 @@
+// Command storage-rotate brings every stored file under the active master key (STORAGE_ACTIVE_KEY_ID):
+// files written before encryption was enabled are encrypted, and data keys wrapped by an older
+// master key are re-wrapped. Only the file headers change for the latter.
+//
+//	go run ./cmd/storage-rotate [-prefix documents/] [-dry-run]
+//
+// To rotate: add the new key to STORAGE_MASTER_KEYS, make it active, deploy, run this command,
+// then remove the old key once it reports no failure.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage"
+	"seculoc-back/internal/adapter/storage/encrypted"
+	"seculoc-back/internal/platform/logger"
+)
+
+func main() {
+	prefix := flag.String("prefix", "", "only process files whose name starts with this prefix")
+	dryRun := flag.Bool("dry-run", false, "list what would be encrypted or re-wrapped without writing anything")
+	flag.Parse()
+
+	viper.SetConfigFile(".env")
+	viper.AutomaticEnv()
+	if err := viper.ReadInConfig(); err != nil {
+		fmt.Fprintf(os.Stderr, "Config file not found: %s \n", err)
+	}
+
+	logger.Init(viper.GetString("ENV"))
+	log := logger.Get()
+	defer logger.Sync()
+
+	backend, err := storage.BackendFromEnv()
+	if err != nil {
+		log.Fatal("failed to open storage", zap.Error(err))
+	}
+	keyring, err := encrypted.KeyringFromEnv()
+	if err != nil {
+		log.Fatal("invalid master keys", zap.Error(err))
+	}
+	if keyring == nil {
+		log.Fatal("STORAGE_MASTER_KEYS is not set")
+	}
+
+	report, err := encrypted.New(backend, keyring).Rotate(*prefix, *dryRun)
+	if err != nil {
+		log.Fatal("rotation failed", zap.Error(err))
+	}
+
+	for name, err := range report.Failed {
+		log.Error("file not rotated", zap.String("file", name), zap.Error(err))
+	}
+	log.Info("storage key rotation finished",
+		zap.String("active_key_id", keyring.ActiveID),
+		zap.Bool("dry_run", *dryRun),
+		zap.Int("encrypted", len(report.Encrypted)),
+		zap.Int("rewrapped", len(report.Rewrapped)),
+		zap.Int("current", report.Current),
+		zap.Int("failed", len(report.Failed)),
+	)
+	if len(report.Failed) > 0 {
+		os.Exit(1)
+	}
+}
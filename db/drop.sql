@@ -2,25 +2,48 @@
 -- DROP EVERYTHING (RESET)
 -- =============================================
 
--- 1. Views
-DROP VIEW IF EXISTS view_user_credit_balance CASCADE;
-
--- 2. Tables (Ordre inverse de création pour respecter les FK, ou CASCADE)
+-- 1. Tables (Ordre inverse de création pour respecter les FK, ou CASCADE)
+DROP TABLE IF EXISTS credit_accounts CASCADE;
+DROP TABLE IF EXISTS subscription_items CASCADE;
+DROP TABLE IF EXISTS subscription_events CASCADE;
+DROP TABLE IF EXISTS invoice_sequences CASCADE;
+DROP TABLE IF EXISTS idempotency_keys CASCADE;
+DROP TABLE IF EXISTS invoices CASCADE;
+DROP TABLE IF EXISTS catalog_items CASCADE;
+DROP TABLE IF EXISTS retention_purges CASCADE;
+DROP TABLE IF EXISTS dossier_shares CASCADE;
+DROP TABLE IF EXISTS tenant_dossiers CASCADE;
+DROP TABLE IF EXISTS solvency_guarantors CASCADE;
+DROP TABLE IF EXISTS solvency_policies CASCADE;
+DROP TABLE IF EXISTS webhook_events CASCADE;
+DROP TABLE IF EXISTS document_access_logs CASCADE;
+DROP TABLE IF EXISTS document_links CASCADE;
+DROP TABLE IF EXISTS documents CASCADE;
 DROP TABLE IF EXISTS transactions CASCADE;
 DROP TABLE IF EXISTS seasonal_bookings CASCADE;
 DROP TABLE IF EXISTS rent_payments CASCADE;
 DROP TABLE IF EXISTS lease_invitations CASCADE;
+DROP TABLE IF EXISTS lease_parties CASCADE;
 DROP TABLE IF EXISTS leases CASCADE;
 DROP TABLE IF EXISTS solvency_checks CASCADE;
+DROP TABLE IF EXISTS property_media CASCADE;
 DROP TABLE IF EXISTS properties CASCADE;
 DROP TABLE IF EXISTS credit_transactions CASCADE;
 DROP TABLE IF EXISTS subscriptions CASCADE;
 DROP TABLE IF EXISTS users CASCADE;
 
--- 3. Types (Enums)
+-- 2. Types (Enums)
 DROP TYPE IF EXISTS escrow_status CASCADE;
 DROP TYPE IF EXISTS solvency_status CASCADE;
 DROP TYPE IF EXISTS billing_freq CASCADE;
 DROP TYPE IF EXISTS sub_plan CASCADE;
 DROP TYPE IF EXISTS property_type CASCADE;
 DROP TYPE IF EXISTS user_role CASCADE;
+
+-- 3. Functions
+DROP FUNCTION IF EXISTS protect_issued_invoice CASCADE;
+DROP FUNCTION IF EXISTS post_credit_transaction CASCADE;
+DROP FUNCTION IF EXISTS check_credit_entry_balanced CASCADE;
+
+-- 4. Sequences
+DROP SEQUENCE IF EXISTS credit_entry_seq CASCADE;
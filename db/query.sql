@@ -60,11 +60,6 @@ SET
 WHERE id = $1 AND owner_id = $2
 RETURNING id, owner_id, name, address, rental_type, details, rent_amount, rent_charges_amount, deposit_amount, is_furnished, seasonal_price_per_night, vacancy_credits, is_active, created_at;
 
--- name: DecreasePropertyCredits :exec
-UPDATE properties
-SET vacancy_credits = vacancy_credits - 1
-WHERE id = $1 AND vacancy_credits > 0;
-
 
 -- name: ListPropertiesByOwner :many
 SELECT id, owner_id, name, address, rental_type, details, rent_amount, rent_charges_amount, deposit_amount, is_furnished, seasonal_price_per_night, vacancy_credits, is_active, created_at FROM properties
@@ -85,16 +80,16 @@ SET is_active = false
 WHERE id = $1 AND owner_id = $2 AND is_active = true
 RETURNING id;
 
--- name: UpdateSubscriptionLimit :exec
+-- name: SetSubscriptionPropertyLimit :exec
 UPDATE subscriptions
-SET max_properties_limit = max_properties_limit + $2
-WHERE user_id = $1 AND status = 'active';
+SET max_properties_limit = $2
+WHERE id = $1;
 
 -- name: CreateSolvencyCheck :one
 INSERT INTO solvency_checks (
-    initiator_owner_id, candidate_id, token, property_id, status, credit_source
+    initiator_owner_id, candidate_id, token, property_id, status, credit_source, expires_at, credit_transaction_id
 ) VALUES (
-    $1, $2, $3, $4, 'pending', $5
+    $1, $2, $3, $4, 'pending', $5, $6, $7
 )
 RETURNING *;
 
@@ -102,14 +97,16 @@ RETURNING *;
 SELECT * FROM solvency_checks
 WHERE id = $1;
 
--- name: CancelSolvencyCheck :exec
+-- name: CancelSolvencyCheck :execrows
+-- Only a check still waiting for the candidate is cancelled: a concurrent decision or expiry wins
 UPDATE solvency_checks
 SET status = 'cancelled'
-WHERE id = $1;
+WHERE id = $1 AND status IN ('pending', 'insufficient_docs');
 
 -- name: GetSolvencyCheckByToken :one
 SELECT 
-    sc.id, sc.initiator_owner_id, sc.candidate_id, sc.token, sc.property_id, sc.status, sc.created_at,
+    sc.id, sc.initiator_owner_id, sc.candidate_id, sc.token, sc.property_id, sc.status, sc.created_at, sc.expires_at,
+    sc.documents_json, sc.missing_documents, sc.employment_type, sc.guarantee_type,
     u.email as candidate_email, u.first_name as candidate_first_name, u.last_name as candidate_last_name,
     p.address as property_address, p.rent_amount as property_rent_amount, p.name as property_name
 FROM solvency_checks sc
@@ -118,9 +115,48 @@ JOIN properties p ON sc.property_id = p.id
 WHERE sc.token = $1
  LIMIT 1;
 
--- name: UpdateSolvencyCheckResult :exec
+-- name: GetSolvencyCheckByTokenForUpdate :one
+SELECT * FROM solvency_checks
+WHERE token = $1
+FOR UPDATE;
+
+-- name: GetSolvencyCheckForUpdate :one
+SELECT * FROM solvency_checks
+WHERE id = $1
+FOR UPDATE;
+
+-- name: UpdateSolvencyCheckDocuments :exec
+UPDATE solvency_checks
+SET documents_json = $2, missing_documents = $3, status = $4
+WHERE id = $1;
+
+-- name: SetSolvencyCheckBankConsent :exec
+UPDATE solvency_checks
+SET bank_provider = $2, bank_consent_id = $3, bank_connection_id = NULL
+WHERE id = $1;
+
+-- name: SetSolvencyCheckBankConnection :exec
+UPDATE solvency_checks
+SET bank_connection_id = $2
+WHERE id = $1;
+
+-- name: GetSolvencyCheckByBankConnection :one
+SELECT * FROM solvency_checks
+WHERE bank_provider = $1 AND bank_connection_id = $2
+LIMIT 1;
+
+-- name: UpdateSolvencyCheckResult :execrows
+-- Only while the check is still in the status the decision was made from: a check cancelled or expired
+-- (and refunded) in the meantime is left as it is.
+UPDATE solvency_checks
+SET status = sqlc.arg(status), score_result = sqlc.arg(score_result), report_url = sqlc.arg(report_url),
+    analysis_json = sqlc.arg(analysis_json), policy_results = sqlc.arg(policy_results),
+    combined_score = sqlc.arg(combined_score)
+WHERE id = sqlc.arg(id) AND status = sqlc.arg(current_status);
+
+-- name: UpdateSolvencyCheckProfile :exec
 UPDATE solvency_checks
-SET status = $2, score_result = $3, report_url = $4
+SET employment_type = $2, guarantee_type = $3
 WHERE id = $1;
 
 -- name: ListSolvencyChecksByOwner :many
@@ -138,53 +174,118 @@ JOIN users u ON sc.candidate_id = u.id
 WHERE sc.property_id = $1
 ORDER BY sc.created_at DESC;
 
--- name: CleanupProvisionalUsers :exec
-DELETE FROM users 
-WHERE is_provisional = TRUE 
-AND created_at < NOW() - INTERVAL '30 days';
+-- name: SetSolvencyCheckSelection :exec
+UPDATE solvency_checks
+SET selection = $2, selection_at = NOW()
+WHERE id = $1;
+
+-- name: ListSolvencyChecksAwaitingCandidate :many
+-- Checks still waiting for the candidate, with reminders left to send
+SELECT sc.id, sc.token, sc.created_at, sc.expires_at, sc.reminders_sent,
+    u.email as candidate_email, u.first_name as candidate_first_name, p.address as property_address
+FROM solvency_checks sc
+JOIN users u ON sc.candidate_id = u.id
+JOIN properties p ON sc.property_id = p.id
+WHERE sc.status IN ('pending', 'insufficient_docs')
+AND sc.expires_at > NOW()
+AND sc.reminders_sent < sqlc.arg(max_reminders)::int;
+
+-- name: MarkSolvencyCheckReminderSent :exec
+UPDATE solvency_checks
+SET reminders_sent = reminders_sent + 1
+WHERE id = $1;
+
+-- name: ListExpiredSolvencyChecks :many
+SELECT id FROM solvency_checks
+WHERE status IN ('pending', 'insufficient_docs')
+AND expires_at <= NOW()
+ORDER BY expires_at;
+
+-- name: ExpireSolvencyCheck :one
+-- Only a check still waiting for the candidate expires: a concurrent decision wins
+UPDATE solvency_checks
+SET status = 'expired'
+WHERE id = $1 AND status IN ('pending', 'insufficient_docs') AND expires_at <= NOW()
+RETURNING *;
+
+-- name: CleanupProvisionalUsers :many
+-- Provisional accounts nobody refers to any more (candidates are detached when anonymised).
+DELETE FROM users u
+WHERE u.is_provisional = TRUE
+AND u.created_at < $1
+AND NOT EXISTS (SELECT 1 FROM solvency_checks sc WHERE sc.candidate_id = u.id OR sc.initiator_owner_id = u.id)
+AND NOT EXISTS (SELECT 1 FROM leases l WHERE l.tenant_id = u.id)
+AND NOT EXISTS (SELECT 1 FROM lease_parties lp WHERE lp.user_id = u.id)
+AND NOT EXISTS (SELECT 1 FROM lease_invitations li WHERE li.owner_id = u.id)
+AND NOT EXISTS (SELECT 1 FROM properties p WHERE p.owner_id = u.id)
+AND NOT EXISTS (SELECT 1 FROM subscriptions s WHERE s.user_id = u.id)
+AND NOT EXISTS (SELECT 1 FROM credit_transactions ct WHERE ct.user_id = u.id)
+AND NOT EXISTS (SELECT 1 FROM transactions t WHERE t.user_id = u.id)
+AND NOT EXISTS (SELECT 1 FROM seasonal_bookings sb WHERE sb.tenant_id = u.id)
+AND NOT EXISTS (SELECT 1 FROM document_links dl WHERE dl.user_id = u.id)
+AND NOT EXISTS (SELECT 1 FROM document_access_logs dal WHERE dal.user_id = u.id)
+AND NOT EXISTS (SELECT 1 FROM solvency_policies sp WHERE sp.owner_id = u.id)
+AND NOT EXISTS (SELECT 1 FROM tenant_dossiers td WHERE td.user_id = u.id)
+RETURNING u.id;
 
 -- name: CreateSubscription :one
 INSERT INTO subscriptions (
-    user_id, plan_type, frequency, start_date, end_date, max_properties_limit
+    user_id, plan_type, frequency, start_date, end_date, max_properties_limit, catalog_item_id,
+    current_period_start, current_period_end, status
 ) VALUES (
-    $1, $2, $3, $4, $5, $6
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
 )
 RETURNING *;
 
 -- name: CreateCreditTransaction :one
+-- Credits (positive amount) or debits a wallet against the system account, as one balanced entry: the
+-- user's global wallet, or the wallet of property_id when set. The system line is inserted first and only
+-- the wallet line is returned. A refund names the line it gives back in refund_of, which can be refunded
+-- only once.
+WITH system_line AS (
+    INSERT INTO credit_transactions (
+        entry_id, account_id, user_id, property_id, amount, transaction_type, description, refund_of
+    ) VALUES (
+        nextval('credit_entry_seq'), (SELECT id FROM credit_accounts WHERE kind = 'system'), NULL, NULL,
+        -sqlc.arg(amount)::int, sqlc.arg(transaction_type), sqlc.arg(description), NULL
+    )
+    RETURNING entry_id
+)
 INSERT INTO credit_transactions (
-    user_id, amount, transaction_type, description
-) VALUES (
-    $1, $2, $3, $4
+    entry_id, account_id, user_id, property_id, amount, transaction_type, description, refund_of
 )
+SELECT entry_id, NULL::int, sqlc.narg(user_id)::int, sqlc.narg(property_id)::int, sqlc.arg(amount)::int,
+    sqlc.arg(transaction_type), sqlc.arg(description), sqlc.narg(refund_of)::int
+FROM system_line
 RETURNING *;
 
--- name: IncreasePropertyCredits :exec
-UPDATE properties
-SET vacancy_credits = vacancy_credits + 1
-WHERE id = $1;
-
 -- name: GetUserSubscription :one
+-- The current subscription: at most one per user is incomplete, active or past due (idx_subscriptions_current).
 SELECT * FROM subscriptions
-WHERE user_id = $1 AND status = 'active'
-ORDER BY created_at DESC
-LIMIT 1;
+WHERE user_id = $1 AND status IN ('incomplete', 'active', 'past_due');
 
 -- name: GetUserCreditBalance :one
-SELECT current_balance::int FROM view_user_credit_balance
-WHERE user_id = $1;
+SELECT balance FROM credit_accounts
+WHERE kind = 'global' AND user_id = sqlc.arg(user_id)::int;
 
 -- name: GetPropertyForUpdate :one
 SELECT * FROM properties
 WHERE id = $1 FOR UPDATE;
 
+-- name: LockPropertiesByOwner :exec
+-- Locks the owner's properties in id order: properties are locked before the user and its wallets.
+SELECT id FROM properties
+WHERE owner_id = $1
+ORDER BY id
+FOR UPDATE;
+
 -- name: GetUserForUpdate :one
 SELECT * FROM users
 WHERE id = $1 FOR UPDATE;
 
 -- name: GetUserCreditBalanceForUpdate :one
-SELECT current_balance::int FROM view_user_credit_balance
-WHERE user_id = $1;
+SELECT balance FROM credit_accounts
+WHERE kind = 'global' AND user_id = sqlc.arg(user_id)::int FOR UPDATE;
 
 -- name: HasReceivedInitialBonus :one
 SELECT EXISTS(
@@ -207,8 +308,11 @@ SET status = $2
 WHERE id = $1;
 
 -- name: CountLeasesByTenant :one
-SELECT COUNT(*) FROM leases
-WHERE tenant_id = $1 AND lease_status != 'terminated';
+SELECT COUNT(*) FROM leases l
+WHERE l.lease_status != 'terminated' AND (
+    l.tenant_id = $1
+    OR EXISTS (SELECT 1 FROM lease_parties lp WHERE lp.lease_id = l.id AND lp.user_id = $1 AND lp.status = 'active')
+);
 
 -- name: CountBookingsByTenant :one
 SELECT COUNT(*) FROM seasonal_bookings
@@ -224,15 +328,17 @@ RETURNING *;
 
 -- name: CreateDraftLease :one
 INSERT INTO leases (
-    property_id, start_date, end_date, rent_amount, charges_amount, deposit_amount, payment_day, special_clauses, lease_status
+    property_id, start_date, end_date, rent_amount, charges_amount, deposit_amount, payment_day, special_clauses,
+    joint_liability, individual_rent_shares, lease_status
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8, 'draft'
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, 'draft'
 )
 RETURNING *;
 
 -- name: UpdateLeaseTenant :exec
+-- Le premier locataire qui accepte reste le titulaire de leases.tenant_id, les suivants sont des colocataires
 UPDATE leases
-SET tenant_id = $2, lease_status = 'active' -- Or 'pending_signature'
+SET tenant_id = COALESCE(tenant_id, $2), lease_status = 'active' -- Or 'pending_signature'
 WHERE id = $1;
 
 -- name: ListLeasesByTenant :many
@@ -242,6 +348,7 @@ SELECT
 FROM leases l
 JOIN properties p ON l.property_id = p.id
 WHERE l.tenant_id = $1
+   OR EXISTS (SELECT 1 FROM lease_parties lp WHERE lp.lease_id = l.id AND lp.user_id = $1 AND lp.status != 'invited')
 ORDER BY l.created_at DESC;
 
 -- name: GetLease :one
@@ -258,8 +365,8 @@ SET contract_url = $2
 WHERE id = $1;
 
 -- name: CreateInvitationWithLease :one
-INSERT INTO lease_invitations (property_id, lease_id, owner_id, tenant_email, token, expires_at)
-VALUES ($1, $2, $3, $4, $5, $6)
+INSERT INTO lease_invitations (property_id, lease_id, owner_id, tenant_email, token, expires_at, party_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
 RETURNING *;
 
 -- name: GetInvitationByEmailAndProperty :one
@@ -269,3 +376,1017 @@ WHERE tenant_email = $1 AND property_id = $2 AND status = 'pending' LIMIT 1;
 -- name: GetInvitationByLeaseID :one
 SELECT * FROM lease_invitations
 WHERE lease_id = $1 LIMIT 1;
+
+-- name: CreatePropertyMedia :one
+INSERT INTO property_media (
+    property_id, media_kind, diagnostic_type, original_filename, storage_key, thumbnail_key, content_type, size_bytes, position, is_cover, expires_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+)
+RETURNING *;
+
+-- name: GetPropertyMedia :one
+SELECT * FROM property_media
+WHERE id = $1 AND property_id = $2 LIMIT 1;
+
+-- name: ListPropertyMedia :many
+SELECT * FROM property_media
+WHERE property_id = $1
+ORDER BY media_kind DESC, position ASC, id ASC;
+
+-- name: GetNextPhotoPosition :one
+SELECT COALESCE(MAX(position) + 1, 0)::int FROM property_media
+WHERE property_id = $1 AND media_kind = 'photo';
+
+-- name: UpdatePropertyMediaPosition :exec
+UPDATE property_media
+SET position = $3
+WHERE id = $1 AND property_id = $2;
+
+-- name: ClearPropertyCover :exec
+UPDATE property_media
+SET is_cover = FALSE
+WHERE property_id = $1 AND is_cover;
+
+-- name: SetPropertyMediaCover :exec
+UPDATE property_media
+SET is_cover = TRUE
+WHERE id = $1 AND property_id = $2 AND media_kind = 'photo';
+
+-- name: DeletePropertyMedia :exec
+DELETE FROM property_media
+WHERE id = $1 AND property_id = $2;
+
+-- name: GetPropertyDiagnosticByType :one
+SELECT * FROM property_media
+WHERE property_id = $1 AND media_kind = 'diagnostic' AND diagnostic_type = $2
+LIMIT 1;
+
+-- name: ListExpiringDiagnostics :many
+SELECT pm.*, p.address as property_address, u.email as owner_email
+FROM property_media pm
+JOIN properties p ON pm.property_id = p.id
+JOIN users u ON p.owner_id = u.id
+WHERE pm.media_kind = 'diagnostic'
+  AND pm.expires_at IS NOT NULL
+  AND pm.expires_at <= $1
+  AND pm.reminder_sent_at IS NULL
+  AND p.is_active = TRUE
+ORDER BY pm.expires_at ASC;
+
+-- name: MarkDiagnosticReminderSent :exec
+UPDATE property_media
+SET reminder_sent_at = NOW()
+WHERE id = $1;
+
+-- name: GetNextDocumentVersion :one
+SELECT COALESCE(MAX(version) + 1, 1)::int FROM documents
+WHERE document_type = $1 AND entity_id = $2;
+
+-- name: CreateDocument :one
+INSERT INTO documents (document_type, entity_id, version, storage_key, content_type, filename)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetDocument :one
+SELECT * FROM documents
+WHERE id = $1 LIMIT 1;
+
+-- name: GetLatestDocument :one
+SELECT * FROM documents
+WHERE document_type = $1 AND entity_id = $2
+ORDER BY version DESC
+LIMIT 1;
+
+-- name: ListDocumentsByEntity :many
+SELECT * FROM documents
+WHERE document_type = $1 AND entity_id = $2
+ORDER BY version DESC;
+
+-- name: CreateDocumentLink :one
+INSERT INTO document_links (document_id, user_id, expires_at)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: GetDocumentLink :one
+SELECT * FROM document_links
+WHERE id = $1 LIMIT 1;
+
+-- name: RevokeDocumentLink :execrows
+UPDATE document_links
+SET revoked_at = NOW()
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL;
+
+-- name: CreateDocumentAccessLog :exec
+INSERT INTO document_access_logs (link_id, document_id, user_id, ip_address, user_agent)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: RecordWebhookEvent :execrows
+INSERT INTO webhook_events (provider, event_id, event_type)
+VALUES ($1, $2, $3)
+ON CONFLICT (provider, event_id) DO NOTHING;
+
+-- name: GetEffectiveSolvencyPolicy :one
+-- La politique propre au bien l'emporte sur la politique par défaut du propriétaire
+SELECT * FROM solvency_policies
+WHERE owner_id = $1 AND (property_id = sqlc.narg('property_id') OR property_id IS NULL)
+ORDER BY property_id NULLS LAST
+LIMIT 1;
+
+-- name: UpsertOwnerSolvencyPolicy :one
+INSERT INTO solvency_policies (
+    owner_id, income_multiplier, include_charges, count_guarantor_income,
+    min_employment_type, accepted_guarantees, require_guarantee
+) VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (owner_id) WHERE property_id IS NULL DO UPDATE SET
+    income_multiplier = EXCLUDED.income_multiplier,
+    include_charges = EXCLUDED.include_charges,
+    count_guarantor_income = EXCLUDED.count_guarantor_income,
+    min_employment_type = EXCLUDED.min_employment_type,
+    accepted_guarantees = EXCLUDED.accepted_guarantees,
+    require_guarantee = EXCLUDED.require_guarantee,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: UpsertPropertySolvencyPolicy :one
+INSERT INTO solvency_policies (
+    owner_id, property_id, income_multiplier, include_charges, count_guarantor_income,
+    min_employment_type, accepted_guarantees, require_guarantee
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (property_id) DO UPDATE SET
+    income_multiplier = EXCLUDED.income_multiplier,
+    include_charges = EXCLUDED.include_charges,
+    count_guarantor_income = EXCLUDED.count_guarantor_income,
+    min_employment_type = EXCLUDED.min_employment_type,
+    accepted_guarantees = EXCLUDED.accepted_guarantees,
+    require_guarantee = EXCLUDED.require_guarantee,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: DeletePropertySolvencyPolicy :execrows
+DELETE FROM solvency_policies
+WHERE property_id = $1 AND owner_id = $2;
+
+-- name: DeleteWebhookEvent :exec
+DELETE FROM webhook_events
+WHERE provider = $1 AND event_id = $2;
+
+-- name: CreateSolvencyGuarantor :one
+INSERT INTO solvency_guarantors (check_id, email, first_name, last_name, phone_number, token)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: ListGuarantorsByCheck :many
+SELECT * FROM solvency_guarantors
+WHERE check_id = $1
+ORDER BY id;
+
+-- name: GetSolvencyGuarantor :one
+SELECT * FROM solvency_guarantors
+WHERE id = $1;
+
+-- name: CountGuarantorsByCheck :one
+SELECT COUNT(*) FROM solvency_guarantors
+WHERE check_id = $1;
+
+-- name: GetGuarantorByTokenForUpdate :one
+SELECT * FROM solvency_guarantors
+WHERE token = $1
+FOR UPDATE;
+
+-- name: DeleteSolvencyGuarantor :execrows
+DELETE FROM solvency_guarantors
+WHERE id = $1 AND check_id = $2;
+
+-- name: UpdateGuarantorDocuments :exec
+UPDATE solvency_guarantors
+SET documents_json = $2
+WHERE id = $1;
+
+-- name: SetGuarantorBankConsent :exec
+UPDATE solvency_guarantors
+SET bank_provider = $2, bank_consent_id = $3, bank_connection_id = NULL
+WHERE id = $1;
+
+-- name: SetGuarantorBankConnection :exec
+UPDATE solvency_guarantors
+SET bank_connection_id = $2
+WHERE id = $1;
+
+-- name: GetGuarantorByBankConnection :one
+SELECT * FROM solvency_guarantors
+WHERE bank_provider = $1 AND bank_connection_id = $2
+LIMIT 1;
+
+-- name: UpdateGuarantorAnalysis :exec
+UPDATE solvency_guarantors
+SET status = 'completed', monthly_income = $2, analysis_json = $3
+WHERE id = $1;
+
+-- name: CompleteGuarantorDocuments :exec
+-- Dossier de garant complété par pièces justificatives, sans analyse bancaire
+UPDATE solvency_guarantors
+SET status = 'completed'
+WHERE id = $1;
+
+-- name: AttachGuarantorsToLease :many
+-- Garants complétés (analyse bancaire ou pièces) du dernier dossier accepté du locataire pour ce bien
+UPDATE solvency_guarantors g
+SET lease_id = sqlc.arg('lease_id')
+WHERE g.lease_id IS NULL AND g.status = 'completed' AND g.check_id = (
+    SELECT sc.id FROM solvency_checks sc
+    WHERE sc.property_id = sqlc.arg('property_id') AND sc.candidate_id = sqlc.arg('tenant_id') AND sc.status = 'approved'
+    ORDER BY sc.created_at DESC
+    LIMIT 1
+)
+RETURNING g.*;
+
+-- name: ListGuarantorsByLease :many
+SELECT * FROM solvency_guarantors
+WHERE lease_id = $1
+ORDER BY id;
+
+-- name: SetGuarantorMention :exec
+UPDATE solvency_guarantors
+SET mention_text = $2, mention_signed_at = NOW()
+WHERE id = $1;
+
+-- name: CreateLeaseParty :one
+INSERT INTO lease_parties (lease_id, user_id, email, first_name, last_name, rent_share, status, joined_at, replaces_party_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING *;
+
+-- name: ListLeaseParties :many
+SELECT * FROM lease_parties
+WHERE lease_id = $1
+ORDER BY id;
+
+-- name: GetLeaseParty :one
+SELECT * FROM lease_parties
+WHERE id = $1 AND lease_id = $2
+LIMIT 1;
+
+-- name: GetLeasePartyByUser :one
+-- Colocataire ayant accepté son invitation (parti ou non)
+SELECT * FROM lease_parties
+WHERE lease_id = $1 AND user_id = $2 AND status != 'invited'
+LIMIT 1;
+
+-- name: GetLeasePartyByReplaced :one
+SELECT * FROM lease_parties
+WHERE replaces_party_id = $1
+LIMIT 1;
+
+-- name: ActivateLeaseParty :exec
+UPDATE lease_parties
+SET user_id = $2, status = 'active', joined_at = NOW()
+WHERE id = $1;
+
+-- name: SetLeasePartyDeparture :exec
+UPDATE lease_parties
+SET status = 'left', notice_date = $2, left_at = $3, solidarity_ends_at = $4
+WHERE id = $1;
+
+-- name: SetLeasePartySolidarityEnd :exec
+UPDATE lease_parties
+SET solidarity_ends_at = $2
+WHERE id = $1;
+
+-- name: UpdateLeasePartyShare :exec
+UPDATE lease_parties
+SET rent_share = $3
+WHERE id = $1 AND lease_id = $2;
+
+-- name: UpdateLeaseRentSplit :exec
+UPDATE leases
+SET joint_liability = $2, individual_rent_shares = $3
+WHERE id = $1;
+
+-- name: UpsertTenantDossier :one
+INSERT INTO tenant_dossiers (
+    user_id, source_check_id, documents_json, analysis_json, employment_type, guarantee_type, verified_at, expires_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+)
+ON CONFLICT (user_id) DO UPDATE SET
+    source_check_id = EXCLUDED.source_check_id,
+    documents_json = EXCLUDED.documents_json,
+    analysis_json = EXCLUDED.analysis_json,
+    employment_type = EXCLUDED.employment_type,
+    guarantee_type = EXCLUDED.guarantee_type,
+    verified_at = EXCLUDED.verified_at,
+    expires_at = EXCLUDED.expires_at,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: GetTenantDossierByUser :one
+SELECT * FROM tenant_dossiers
+WHERE user_id = $1;
+
+-- name: GetTenantDossier :one
+SELECT * FROM tenant_dossiers
+WHERE id = $1;
+
+-- name: DeleteTenantDossier :exec
+DELETE FROM tenant_dossiers
+WHERE id = $1;
+
+-- name: DeleteExpiredTenantDossiers :many
+-- Dossiers expirés avant la date limite ; leurs partages suivent (ON DELETE CASCADE)
+DELETE FROM tenant_dossiers
+WHERE expires_at < $1
+RETURNING id, documents_json;
+
+-- name: CreateDossierShare :one
+INSERT INTO dossier_shares (dossier_id, token, label)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: ListDossierShares :many
+SELECT * FROM dossier_shares
+WHERE dossier_id = $1
+ORDER BY created_at DESC;
+
+-- name: GetDossierShareByToken :one
+SELECT * FROM dossier_shares
+WHERE token = $1;
+
+-- name: RevokeDossierShare :execrows
+UPDATE dossier_shares
+SET revoked_at = NOW()
+WHERE id = $1 AND dossier_id = $2 AND revoked_at IS NULL;
+
+-- name: SetSolvencyCheckDossierShare :exec
+UPDATE solvency_checks
+SET dossier_share_id = $2
+WHERE id = $1;
+
+-- name: ListSolvencyChecksForDocumentPurge :many
+-- Closed checks whose candidate did not become a tenant of the property (the tenant's are purged with the lease).
+SELECT sc.id, sc.documents_json FROM solvency_checks sc
+WHERE sc.status NOT IN ('pending', 'insufficient_docs')
+AND sc.created_at < $1
+AND sc.documents_purged_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM leases l
+    WHERE l.property_id = sc.property_id
+    AND (l.tenant_id = sc.candidate_id OR EXISTS (
+        SELECT 1 FROM lease_parties lp WHERE lp.lease_id = l.id AND lp.user_id = sc.candidate_id
+    ))
+)
+ORDER BY sc.id;
+
+-- name: MarkSolvencyCheckDocumentsPurged :exec
+UPDATE solvency_checks
+SET documents_json = NULL, missing_documents = NULL, report_url = NULL, documents_purged_at = NOW()
+WHERE id = $1;
+
+-- name: ClearGuarantorDocumentsByCheck :exec
+UPDATE solvency_guarantors
+SET documents_json = NULL
+WHERE check_id = $1;
+
+-- name: ListSolvencyChecksForAnonymization :many
+-- Closed checks of candidates who were not retained: no lease with them on the property.
+SELECT sc.id, sc.documents_json FROM solvency_checks sc
+WHERE sc.status NOT IN ('pending', 'insufficient_docs')
+AND sc.created_at < $1
+AND sc.anonymized_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM leases l
+    WHERE l.property_id = sc.property_id
+    AND (l.tenant_id = sc.candidate_id OR EXISTS (
+        SELECT 1 FROM lease_parties lp WHERE lp.lease_id = l.id AND lp.user_id = sc.candidate_id
+    ))
+)
+ORDER BY sc.id;
+
+-- name: ListSolvencyChecksByLeaseTenants :many
+SELECT sc.id, sc.documents_json FROM solvency_checks sc
+JOIN leases l ON l.property_id = sc.property_id
+WHERE l.id = $1
+AND sc.anonymized_at IS NULL
+AND (sc.candidate_id = l.tenant_id OR EXISTS (
+    SELECT 1 FROM lease_parties lp WHERE lp.lease_id = l.id AND lp.user_id = sc.candidate_id
+))
+ORDER BY sc.id;
+
+-- name: AnonymizeSolvencyCheck :exec
+-- Keeps the status and scores for the owner's statistics.
+UPDATE solvency_checks
+SET candidate_id = NULL, token = NULL, analysis_json = NULL, documents_json = NULL, missing_documents = NULL,
+    report_url = NULL, bank_consent_id = NULL, bank_connection_id = NULL, employment_type = NULL,
+    guarantee_type = NULL, policy_results = NULL,
+    documents_purged_at = COALESCE(documents_purged_at, NOW()), anonymized_at = NOW()
+WHERE id = $1;
+
+-- name: DeleteUnattachedGuarantorsByCheck :exec
+DELETE FROM solvency_guarantors
+WHERE check_id = $1 AND lease_id IS NULL;
+
+-- name: ListLeasesForAnonymization :many
+SELECT id FROM leases
+WHERE lease_status = 'terminated'
+AND end_date < $1
+AND anonymized_at IS NULL
+ORDER BY id;
+
+-- name: ListLeaseDocuments :many
+-- Every generated document of a lease: contract versions and guarantee deeds.
+SELECT d.* FROM documents d
+WHERE (d.document_type = 'lease' AND d.entity_id = $1)
+OR (d.document_type = 'guarantee_deed' AND d.entity_id IN (SELECT sg.id FROM solvency_guarantors sg WHERE sg.lease_id = $1))
+ORDER BY d.id;
+
+-- name: AnonymizeLease :exec
+UPDATE leases
+SET contract_url = NULL, anonymized_at = NOW()
+WHERE id = $1;
+
+-- name: AnonymizeLeaseParties :exec
+UPDATE lease_parties
+SET email = 'anonymized-' || id || '@invalid', first_name = NULL, last_name = NULL
+WHERE lease_id = $1;
+
+-- name: AnonymizeLeaseInvitations :exec
+UPDATE lease_invitations
+SET tenant_email = 'anonymized-' || id || '@invalid'
+WHERE lease_id = $1;
+
+-- name: AnonymizeLeaseGuarantors :exec
+UPDATE solvency_guarantors
+SET email = 'anonymized-' || id || '@invalid', first_name = NULL, last_name = NULL, phone_number = NULL,
+    documents_json = NULL, bank_consent_id = NULL, bank_connection_id = NULL, analysis_json = NULL, mention_text = NULL
+WHERE lease_id = $1;
+
+-- name: DeleteDocumentAccessLogsByDocuments :exec
+DELETE FROM document_access_logs
+WHERE document_id = ANY(@document_ids::int[]);
+
+-- name: DeleteDocumentLinksByDocuments :exec
+DELETE FROM document_links
+WHERE document_id = ANY(@document_ids::int[]);
+
+-- name: DeleteDocuments :exec
+DELETE FROM documents
+WHERE id = ANY(@document_ids::int[]);
+
+-- name: DeleteDocumentAccessLogsBefore :execrows
+DELETE FROM document_access_logs
+WHERE accessed_at < $1;
+
+-- name: DeleteDocumentLinksBefore :execrows
+-- Links expired before the cutoff and no longer referenced by an access log.
+DELETE FROM document_links dl
+WHERE dl.expires_at < $1
+AND NOT EXISTS (SELECT 1 FROM document_access_logs dal WHERE dal.link_id = dl.id);
+
+-- name: DeleteWebhookEventsBefore :execrows
+DELETE FROM webhook_events
+WHERE received_at < $1;
+
+-- name: CreateRetentionPurge :one
+INSERT INTO retention_purges (category, retention_days, cutoff, records, files, record_ids)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: ListLeasesByOwner :many
+SELECT l.*, p.address as property_address, p.rental_type
+FROM leases l
+JOIN properties p ON l.property_id = p.id
+WHERE p.owner_id = $1
+ORDER BY l.created_at DESC;
+
+-- name: CountLeasesByOwner :one
+-- Signed leases still running; drafts do not bind the owner.
+SELECT COUNT(*) FROM leases l
+JOIN properties p ON l.property_id = p.id
+WHERE p.owner_id = $1 AND l.lease_status IN ('signed_waiting_deposit', 'active');
+
+-- name: ListRentPaymentsByLease :many
+SELECT * FROM rent_payments
+WHERE lease_id = $1
+ORDER BY due_date ASC, id ASC;
+
+-- name: ListSolvencyChecksByCandidate :many
+SELECT sc.*, p.address as property_address
+FROM solvency_checks sc
+LEFT JOIN properties p ON sc.property_id = p.id
+WHERE sc.candidate_id = $1
+ORDER BY sc.created_at DESC;
+
+-- name: ListCreditTransactionsByUser :many
+SELECT * FROM credit_transactions
+WHERE user_id = $1
+ORDER BY created_at ASC, id ASC;
+
+-- name: ListPaymentTransactionsByUser :many
+SELECT * FROM transactions
+WHERE user_id = $1
+ORDER BY created_at ASC, id ASC;
+
+-- name: ListSubscriptionsByUser :many
+SELECT * FROM subscriptions
+WHERE user_id = $1
+ORDER BY created_at ASC, id ASC;
+
+-- name: ListInvitationsByOwner :many
+SELECT * FROM lease_invitations
+WHERE owner_id = $1
+ORDER BY created_at DESC;
+
+-- name: ListInvitationsByEmail :many
+SELECT * FROM lease_invitations
+WHERE tenant_email = $1
+ORDER BY created_at DESC;
+
+-- name: RevokePendingInvitationsForUser :exec
+UPDATE lease_invitations
+SET status = 'revoked'
+WHERE status = 'pending' AND (owner_id = $1 OR tenant_email = $2);
+
+-- name: RevokeDocumentLinksByUser :exec
+UPDATE document_links
+SET revoked_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
+
+-- name: DeactivatePropertiesByOwner :exec
+UPDATE properties
+SET is_active = FALSE
+WHERE owner_id = $1;
+
+-- name: CancelUserSubscriptions :exec
+UPDATE subscriptions
+SET status = 'cancelled'
+WHERE user_id = $1 AND status IN ('active', 'past_due', 'incomplete');
+
+-- name: IsUserActive :one
+-- An account deleted by its user (deleted_at) or gone no longer authenticates.
+SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL);
+
+-- name: IsUserAdmin :one
+-- The role is read at each request: a demoted administrator loses access without waiting for the token to expire.
+SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL AND role = 'admin');
+
+-- name: AnonymizeUser :exec
+-- The row stays for the financial records and contracts referring to it; the email is freed.
+UPDATE users
+SET email = 'deleted-' || id || '@invalid', password_hash = NULL, first_name = NULL, last_name = NULL,
+    phone_number = NULL, is_verified = FALSE, deleted_at = NOW()
+WHERE id = $1;
+
+-- name: ListActiveCatalogItems :many
+SELECT * FROM catalog_items
+WHERE valid_from <= NOW() AND (valid_until IS NULL OR valid_until > NOW())
+ORDER BY kind DESC, COALESCE(monthly_price_cents, price_cents), id;
+
+-- name: ListCatalogItems :many
+SELECT * FROM catalog_items
+ORDER BY kind DESC, code, valid_from;
+
+-- name: GetCatalogItem :one
+SELECT * FROM catalog_items
+WHERE id = $1 LIMIT 1;
+
+-- name: GetCatalogItemForUpdate :one
+-- FOR UPDATE (not NO KEY UPDATE) also waits for rows being inserted with a reference to this version.
+SELECT * FROM catalog_items
+WHERE id = $1 LIMIT 1
+FOR UPDATE;
+
+-- name: IsCatalogItemReferenced :one
+-- A version already sold: subscriptions (current or scheduled terms), their history, invoices or pack payments refer to it.
+SELECT (
+    EXISTS (SELECT 1 FROM subscriptions s WHERE s.catalog_item_id = @id::int OR s.scheduled_catalog_item_id = @id::int)
+    OR EXISTS (SELECT 1 FROM subscription_events e WHERE e.from_catalog_item_id = @id::int OR e.to_catalog_item_id = @id::int)
+    OR EXISTS (SELECT 1 FROM invoices i WHERE i.catalog_item_id = @id::int)
+    OR EXISTS (SELECT 1 FROM transactions t WHERE t.related_entity_type = 'pack_purchase' AND t.related_entity_id = @id::int)
+)::boolean AS referenced;
+
+-- name: GetActiveCatalogItem :one
+SELECT * FROM catalog_items
+WHERE kind = $1 AND code = $2
+AND valid_from <= NOW() AND (valid_until IS NULL OR valid_until > NOW())
+LIMIT 1;
+
+-- name: CountOverlappingCatalogItems :one
+-- Versions of the same offer whose validity period overlaps [valid_from, valid_until).
+SELECT COUNT(*) FROM catalog_items
+WHERE code = @code AND id != @exclude_id
+AND valid_from < COALESCE(sqlc.narg('valid_until')::timestamp, 'infinity')
+AND COALESCE(valid_until, 'infinity') > @valid_from;
+
+-- name: CreateCatalogItem :one
+INSERT INTO catalog_items (
+    code, kind, name, plan_type, monthly_price_cents, yearly_price_cents, price_cents,
+    max_properties, included_credits, slot_price_cents, valid_from, valid_until
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
+)
+RETURNING *;
+
+-- name: UpdateCatalogItem :one
+UPDATE catalog_items
+SET code = $2, kind = $3, name = $4, plan_type = $5, monthly_price_cents = $6, yearly_price_cents = $7,
+    price_cents = $8, max_properties = $9, included_credits = $10, slot_price_cents = $11,
+    valid_from = $12, valid_until = $13, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: RetireCatalogItem :one
+-- Ends the validity now; subscriptions already taken keep referring to it.
+UPDATE catalog_items
+SET valid_until = NOW(), updated_at = NOW()
+WHERE id = $1 AND (valid_until IS NULL OR valid_until > NOW())
+RETURNING *;
+
+-- name: ListSubscriptionsDueForRenewal :many
+SELECT id FROM subscriptions
+WHERE status = 'active' AND current_period_end <= @today::date
+ORDER BY current_period_end ASC, id ASC;
+
+-- name: ListSubscriptionsDueForCredits :many
+SELECT id FROM subscriptions
+WHERE status = 'active' AND next_credit_grant <= @today::date
+AND (end_date IS NULL OR next_credit_grant < end_date)
+ORDER BY next_credit_grant ASC, id ASC;
+
+-- name: GetSubscriptionForUpdate :one
+SELECT * FROM subscriptions
+WHERE id = $1 FOR UPDATE;
+
+-- name: AdvanceSubscriptionPeriod :exec
+-- Back to 'active' once the period is paid; end_date follows the period for prepaid (yearly) plans.
+UPDATE subscriptions
+SET current_period_start = @period_start, current_period_end = @period_end,
+    end_date = sqlc.narg(end_date), status = 'active'
+WHERE id = @id;
+
+-- name: SetSubscriptionStatus :exec
+UPDATE subscriptions
+SET status = $2
+WHERE id = $1;
+
+-- name: RecordSubscriptionCreditGrant :exec
+UPDATE subscriptions
+SET next_credit_grant = $2, granted_credits = $3
+WHERE id = $1;
+
+-- name: GetCreditUsageSinceLastPlanGrant :one
+-- Global credits spent (net of refunds) since the plan credits were last granted.
+SELECT COALESCE(-SUM(ct.amount), 0)::int FROM credit_transactions ct
+WHERE ct.user_id = $1 AND ct.transaction_type IN ('check_usage', 'refund') AND ct.property_id IS NULL
+AND ct.created_at >= (
+    SELECT MAX(g.created_at) FROM credit_transactions g
+    WHERE g.user_id = $1 AND g.transaction_type = 'plan_renewal'
+);
+
+-- name: GetInvoiceForPeriod :one
+SELECT * FROM invoices
+WHERE subscription_id = $1 AND period_start = $2;
+
+-- name: CreateInvoice :one
+INSERT INTO invoices (
+    user_id, subscription_id, catalog_item_id, description, amount_cents, period_start, period_end, status,
+    slot_quantity, credit_property_id
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+)
+RETURNING *;
+
+-- name: MarkInvoicePaid :one
+UPDATE invoices
+SET status = 'paid', attempts = attempts + 1, last_attempt_at = NOW(), paid_at = NOW()
+WHERE id = $1 AND status IN ('open', 'processing', 'pending', 'failed')
+RETURNING *;
+
+-- name: MarkInvoiceFailed :one
+UPDATE invoices
+SET status = 'failed', attempts = attempts + 1, last_attempt_at = NOW()
+WHERE id = $1 AND status IN ('open', 'processing', 'pending', 'failed')
+RETURNING *;
+
+-- name: MarkInvoicePending :exec
+-- Payment accepted by the provider but not confirmed yet (3DS, SEPA debit): settled by its webhook.
+UPDATE invoices
+SET status = 'pending', last_attempt_at = NOW()
+WHERE id = $1 AND status IN ('open', 'processing', 'failed');
+
+-- name: ClaimInvoiceForCollection :one
+-- Reserves an invoice for the instance about to charge it. A claim older than @stale_before was left by
+-- an instance that stopped mid-charge: it is taken over, with the same attempt number.
+UPDATE invoices
+SET status = 'processing', last_attempt_at = NOW()
+WHERE id = @id AND (status IN ('open', 'failed') OR (status = 'processing' AND last_attempt_at <= @stale_before))
+RETURNING *;
+
+-- name: GetInvoice :one
+SELECT * FROM invoices
+WHERE id = $1;
+
+-- name: GetInvoiceForUpdate :one
+SELECT * FROM invoices
+WHERE id = $1
+FOR UPDATE;
+
+-- name: VoidInvoice :exec
+UPDATE invoices
+SET status = 'void'
+WHERE id = $1;
+
+-- name: ListInvoicesToRetry :many
+-- Failed renewals of subscriptions still past due, last attempted before @retry_before, and retries
+-- abandoned mid-charge (claimed before @stale_before).
+SELECT i.* FROM invoices i
+JOIN subscriptions s ON s.id = i.subscription_id
+WHERE s.status = 'past_due' AND (
+    (i.status = 'failed' AND i.last_attempt_at <= @retry_before)
+    OR (i.status = 'processing' AND i.last_attempt_at <= @stale_before)
+)
+ORDER BY i.last_attempt_at ASC, i.id ASC;
+
+-- name: ListInvoicesByUser :many
+SELECT * FROM invoices
+WHERE user_id = $1
+ORDER BY created_at ASC, id ASC;
+
+-- name: NextInvoiceNumber :one
+-- Locks the counter of the series until the issuing transaction ends, so numbers have no gap.
+INSERT INTO invoice_sequences (series, year, last_number)
+VALUES ($1, $2, 1)
+ON CONFLICT (series, year) DO UPDATE SET last_number = invoice_sequences.last_number + 1
+RETURNING last_number;
+
+-- name: IssueInvoice :one
+UPDATE invoices
+SET number = $2, issued_at = NOW(), amount_excl_vat_cents = $3, vat_rate_bps = $4, vat_cents = $5,
+    buyer_name = $6, buyer_email = $7
+WHERE id = $1 AND number IS NULL
+RETURNING *;
+
+-- name: CreateCreditNote :one
+INSERT INTO invoices (
+    user_id, kind, credited_invoice_id, description, amount_cents, status, paid_at
+) VALUES (
+    $1, 'credit_note', $2, $3, $4, 'paid', NOW()
+)
+RETURNING *;
+
+-- name: ListIssuedInvoicesByUser :many
+SELECT * FROM invoices
+WHERE user_id = $1 AND number IS NOT NULL
+ORDER BY issued_at DESC, id DESC;
+
+-- name: GetIssuedInvoice :one
+SELECT * FROM invoices
+WHERE id = $1 AND number IS NOT NULL;
+
+-- name: ListInvoicesToArchive :many
+-- Issued invoices and credit notes whose PDF is not archived yet.
+SELECT * FROM invoices
+WHERE number IS NOT NULL AND document_id IS NULL
+ORDER BY issued_at ASC, id ASC
+LIMIT $1;
+
+-- name: SetInvoiceDocument :execrows
+UPDATE invoices
+SET document_id = $2
+WHERE id = $1 AND document_id IS NULL;
+
+-- name: SetUserPaymentCustomer :exec
+UPDATE users
+SET stripe_customer_id = $2
+WHERE id = $1;
+
+-- name: SetUserSepaMandate :exec
+UPDATE users
+SET sepa_mandate_id = $2
+WHERE id = $1;
+
+-- name: CreatePaymentTransaction :one
+-- An intent replayed by the provider (same idempotency key) keeps the row of the interrupted attempt.
+INSERT INTO transactions (
+    user_id, related_entity_type, related_entity_id, amount, direction,
+    stripe_payment_intent_id, stripe_refund_id, status, failure_reason
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9
+)
+ON CONFLICT (stripe_payment_intent_id) WHERE direction = 'inbound' DO UPDATE SET id = transactions.id
+RETURNING *;
+
+-- name: GetPaymentTransactionByIntentForUpdate :one
+SELECT * FROM transactions
+WHERE stripe_payment_intent_id = $1 AND direction = 'inbound'
+FOR UPDATE;
+
+-- name: GetPaymentTransactionForUpdate :one
+SELECT * FROM transactions
+WHERE id = $1
+FOR UPDATE;
+
+-- name: SetPaymentTransactionStatus :exec
+UPDATE transactions
+SET status = $2, failure_reason = $3
+WHERE id = $1;
+
+-- name: SetPaymentTransactionEntity :exec
+UPDATE transactions
+SET related_entity_type = $2, related_entity_id = $3
+WHERE id = $1;
+
+-- name: GetPendingRefund :one
+-- Refund recorded but not confirmed by the provider yet, e.g. interrupted: resumed with its idempotency key.
+SELECT * FROM transactions
+WHERE related_entity_type = 'refund' AND related_entity_id = $1 AND status = 'pending'
+ORDER BY id
+LIMIT 1;
+
+-- name: SettleRefundTransaction :one
+UPDATE transactions
+SET status = 'success', stripe_refund_id = $2
+WHERE id = $1 AND status = 'pending'
+RETURNING *;
+
+-- name: GetRefundedCents :one
+SELECT COALESCE(SUM(amount) * 100, 0)::int FROM transactions
+WHERE related_entity_type = 'refund' AND related_entity_id = $1 AND status = 'success';
+
+-- name: CreateIdempotencyKey :execrows
+-- Réserve la clé ; 0 ligne quand elle existe déjà (requête rejouée ou concurrente).
+INSERT INTO idempotency_keys (user_id, idempotency_key, request_fingerprint)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, idempotency_key) DO NOTHING;
+
+-- name: GetIdempotencyKey :one
+SELECT * FROM idempotency_keys
+WHERE user_id = $1 AND idempotency_key = $2;
+
+-- name: CompleteIdempotencyKey :exec
+UPDATE idempotency_keys
+SET status = 'completed', response_code = $3, response_content_type = $4, response_body = $5, completed_at = NOW()
+WHERE user_id = $1 AND idempotency_key = $2;
+
+-- name: DeleteIdempotencyKey :exec
+DELETE FROM idempotency_keys
+WHERE user_id = $1 AND idempotency_key = $2;
+
+-- name: TakeOverIdempotencyKey :execrows
+-- Reprend une clé restée 'processing' sans signe de vie depuis @stale_before (instance arrêtée pendant la
+-- requête) ; 0 ligne si la requête s'exécute encore ou si une autre l'a reprise entre-temps.
+UPDATE idempotency_keys
+SET heartbeat_at = NOW()
+WHERE user_id = @user_id AND idempotency_key = @idempotency_key AND request_fingerprint = @request_fingerprint
+  AND status = 'processing' AND heartbeat_at < @stale_before;
+
+-- name: TouchIdempotencyKey :exec
+-- Signe de vie de la requête qui détient la clé : elle ne peut pas être reprise tant qu'elle s'exécute.
+UPDATE idempotency_keys
+SET heartbeat_at = NOW()
+WHERE user_id = $1 AND idempotency_key = $2 AND status = 'processing';
+
+-- name: PurgeIdempotencyKeys :execrows
+DELETE FROM idempotency_keys
+WHERE created_at < @created_before;
+
+-- name: GetUserSubscriptionForUpdate :one
+SELECT * FROM subscriptions
+WHERE user_id = $1 AND status IN ('incomplete', 'active', 'past_due')
+FOR UPDATE;
+
+-- name: ChangeSubscriptionPlan :exec
+-- Applies a plan change; any change scheduled for the period end is dropped.
+UPDATE subscriptions
+SET catalog_item_id = $2, plan_type = $3, max_properties_limit = $4,
+    scheduled_catalog_item_id = NULL, scheduled_archived_property_ids = NULL
+WHERE id = $1;
+
+-- name: ScheduleSubscriptionChange :exec
+UPDATE subscriptions
+SET scheduled_catalog_item_id = sqlc.narg(catalog_item_id), scheduled_archived_property_ids = sqlc.narg(archived_property_ids)::int[]
+WHERE id = @id;
+
+-- name: SetSubscriptionCancelAtPeriodEnd :exec
+UPDATE subscriptions
+SET cancel_at_period_end = $2
+WHERE id = $1;
+
+-- name: EndSubscription :exec
+UPDATE subscriptions
+SET status = 'cancelled', ended_at = $2, scheduled_catalog_item_id = NULL, scheduled_archived_property_ids = NULL
+WHERE id = $1;
+
+-- name: CreateSubscriptionEvent :one
+INSERT INTO subscription_events (
+    subscription_id, user_id, event_type, from_catalog_item_id, to_catalog_item_id, effective_date,
+    amount_cents, invoice_id, archived_property_ids, slot_quantity
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+)
+RETURNING *;
+
+-- name: ListSubscriptionEventsByUser :many
+SELECT e.*, f.code AS from_plan, t.code AS to_plan FROM subscription_events e
+LEFT JOIN catalog_items f ON f.id = e.from_catalog_item_id
+LEFT JOIN catalog_items t ON t.id = e.to_catalog_item_id
+WHERE e.user_id = $1
+ORDER BY e.id ASC;
+
+-- name: ListActivePropertiesByOwnerAndType :many
+-- Latest first: the first ones are archived when a downgrade leaves too many properties.
+SELECT id, name, address FROM properties
+WHERE owner_id = $1 AND rental_type = $2 AND is_active = true
+ORDER BY created_at DESC, id DESC;
+
+-- name: ArchiveProperties :execrows
+UPDATE properties
+SET is_active = FALSE
+WHERE owner_id = @owner_id AND id = ANY(@ids::int[]) AND is_active = TRUE;
+
+-- name: GetSubscriptionItem :one
+SELECT * FROM subscription_items
+WHERE subscription_id = $1 AND kind = $2;
+
+-- name: AddSubscriptionItemQuantity :one
+-- A removal scheduled at the period end keeps the quantity added.
+INSERT INTO subscription_items (subscription_id, kind, quantity, unit_price_cents)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (subscription_id, kind) DO UPDATE
+SET quantity = subscription_items.quantity + EXCLUDED.quantity,
+    unit_price_cents = EXCLUDED.unit_price_cents,
+    scheduled_quantity = subscription_items.scheduled_quantity + EXCLUDED.quantity,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: ScheduleSubscriptionItemQuantity :exec
+UPDATE subscription_items
+SET scheduled_quantity = sqlc.narg(scheduled_quantity), updated_at = NOW()
+WHERE id = @id;
+
+-- name: UpdateSubscriptionItem :exec
+-- Applies the quantity and price of the period starting; a removal scheduled is done.
+UPDATE subscription_items
+SET quantity = $2, unit_price_cents = $3, scheduled_quantity = NULL, updated_at = NOW()
+WHERE id = $1;
+
+-- name: ListCreditTransactionsPage :many
+SELECT ct.*, p.name AS property_name FROM credit_transactions ct
+LEFT JOIN properties p ON p.id = ct.property_id
+WHERE ct.user_id = $1
+AND (sqlc.narg(transaction_type)::text IS NULL OR ct.transaction_type = sqlc.narg(transaction_type))
+ORDER BY ct.id DESC
+LIMIT $2 OFFSET $3;
+
+-- name: CountCreditTransactions :one
+SELECT COUNT(*) FROM credit_transactions
+WHERE user_id = $1
+AND (sqlc.narg(transaction_type)::text IS NULL OR transaction_type = sqlc.narg(transaction_type));
+
+-- name: ListCreditBalanceDrifts :many
+-- Wallets whose balance differs from the sum of their ledger lines.
+SELECT a.id AS account_id, a.kind, a.user_id, a.property_id, a.balance, COALESCE(SUM(ct.amount), 0)::int AS ledger_balance
+FROM credit_accounts a
+LEFT JOIN credit_transactions ct ON ct.account_id = a.id
+WHERE a.kind <> 'system'
+GROUP BY a.id
+HAVING a.balance <> COALESCE(SUM(ct.amount), 0);
+
+-- name: ListPropertyCreditDrifts :many
+-- Properties whose vacancy credits differ from the balance of their wallet.
+SELECT p.id AS property_id, p.owner_id, p.vacancy_credits, a.balance AS ledger_balance
+FROM properties p
+JOIN credit_accounts a ON a.property_id = p.id
+WHERE p.vacancy_credits <> a.balance;
+
+-- name: ListUnbalancedCreditEntries :many
+SELECT entry_id, SUM(amount)::int AS total FROM credit_transactions
+GROUP BY entry_id
+HAVING SUM(amount) <> 0;
+
+-- name: ListCreditAccountsByUser :many
+-- Wallets of a user: the global one first, then those of their properties.
+SELECT a.id, a.kind, a.property_id, a.balance, p.name AS property_name, p.address AS property_address,
+    COALESCE(p.is_active, true)::boolean AS is_active
+FROM credit_accounts a
+LEFT JOIN properties p ON p.id = a.property_id
+WHERE a.user_id = $1
+ORDER BY a.kind = 'property', a.id;
+
+-- name: GetCreditAccountProperty :one
+-- Property of a wallet (NULL for the global wallet); it never changes, no lock needed.
+SELECT property_id FROM credit_accounts
+WHERE id = $1;
+
+-- name: GetCreditAccountForUpdate :one
+SELECT a.*, COALESCE(p.is_active, true)::boolean AS is_active FROM credit_accounts a
+LEFT JOIN properties p ON p.id = a.property_id
+WHERE a.id = $1
+FOR UPDATE OF a;
+
+-- name: CreateCreditTransfer :many
+-- Moves credits between two wallets as one balanced entry; the debited line comes first.
+INSERT INTO credit_transactions (
+    entry_id, account_id, amount, transaction_type, description
+) VALUES
+    (nextval('credit_entry_seq'), sqlc.arg(from_account_id)::int, -sqlc.arg(amount)::int, 'transfer', sqlc.arg(description)),
+    (currval('credit_entry_seq'), sqlc.arg(to_account_id)::int, sqlc.arg(amount)::int, 'transfer', sqlc.arg(description))
+RETURNING *;
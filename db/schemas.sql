@@ -6,7 +6,7 @@ CREATE TYPE user_role AS ENUM ('admin', 'user'); -- Rôle système. Le métier (
 CREATE TYPE property_type AS ENUM ('long_term', 'seasonal'); -- [cite: 5]
 CREATE TYPE sub_plan AS ENUM ('discovery', 'serenity', 'premium'); -- [cite: 31, 37, 46]
 CREATE TYPE billing_freq AS ENUM ('monthly', 'yearly'); -- [cite: 39]
-CREATE TYPE solvency_status AS ENUM ('pending', 'approved', 'rejected', 'insufficient_docs', 'cancelled');
+CREATE TYPE solvency_status AS ENUM ('pending', 'approved', 'rejected', 'insufficient_docs', 'cancelled', 'expired');
 CREATE TYPE escrow_status AS ENUM ('held', 'released', 'disputed', 'refunded'); -- [cite: 27, 58]
 
 -- =============================================
@@ -22,8 +22,11 @@ CREATE TABLE users (
     phone_number VARCHAR(20),
     is_verified BOOLEAN DEFAULT FALSE, -- KYC de l'utilisateur lui-même
     stripe_customer_id VARCHAR(100), -- Pour les prélèvements abonnements/packs
+    sepa_mandate_id VARCHAR(100), -- Mandat SEPA chez le prestataire de paiement (renouvellements)
     is_provisional BOOLEAN DEFAULT TRUE,
     last_context_used VARCHAR(50) DEFAULT 'owner', -- 'owner' or 'tenant'
+    role user_role NOT NULL DEFAULT 'user', -- 'admin' : gestion du catalogue des offres
+    deleted_at TIMESTAMP, -- Compte supprimé à la demande de l'utilisateur : données personnelles effacées
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
@@ -32,7 +35,44 @@ CREATE TABLE users (
 -- Un user est "Locataire" s'il a une entrée dans 'leases' ou 'bookings'.
 
 -- =============================================
--- 3. ABONNEMENTS & CRÉDITS (Le "Cœur" du Business Model)
+-- 3. CATALOGUE DES OFFRES
+-- =============================================
+
+-- Plans d'abonnement et packs de crédits. Une offre peut avoir plusieurs versions successives (même code,
+-- périodes de validité disjointes) : un changement de tarif ne demande pas de déploiement.
+CREATE EXTENSION IF NOT EXISTS btree_gist; -- Égalité sur code dans la contrainte d'exclusion
+CREATE TABLE catalog_items (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(50) NOT NULL, -- 'discovery', 'serenity', 'premium', 'pack_20'...
+    kind VARCHAR(10) NOT NULL, -- 'plan' ou 'pack'
+    name VARCHAR(100) NOT NULL,
+    plan_type sub_plan, -- Plans : type enregistré sur l'abonnement
+    monthly_price_cents INT, -- Plans : prix mensuel
+    yearly_price_cents INT, -- Plans : prix annuel (2 mois offerts)
+    price_cents INT, -- Packs : prix du pack
+    max_properties INT NOT NULL DEFAULT 0, -- Plans : biens longue durée inclus
+    included_credits INT NOT NULL DEFAULT 0, -- Plans : crédits accordés chaque mois ; packs : crédits du pack
+    slot_price_cents INT, -- Plans : prix mensuel d'un bien supplémentaire (NULL = pas d'ajout possible)
+    valid_from TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    valid_until TIMESTAMP, -- NULL = sans fin
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (kind IN ('plan', 'pack')),
+    -- Deux versions d'une même offre ne peuvent pas être valides en même temps
+    CONSTRAINT catalog_items_no_overlap EXCLUDE USING gist (code WITH =, tsrange(valid_from, valid_until) WITH &&)
+);
+
+CREATE INDEX idx_catalog_items_code ON catalog_items(code, valid_from);
+
+INSERT INTO catalog_items (code, kind, name, plan_type, monthly_price_cents, yearly_price_cents, max_properties, included_credits, slot_price_cents) VALUES
+    ('discovery', 'plan', 'Discovery', 'discovery', 0, 0, 1, 0, NULL),
+    ('serenity', 'plan', 'Serenity', 'serenity', 990, 9900, 1, 20, 990),
+    ('premium', 'plan', 'Premium', 'premium', 2990, 29900, 5, 30, 990);
+INSERT INTO catalog_items (code, kind, name, price_cents, included_credits) VALUES
+    ('pack_20', 'pack', 'Pack 20 vérifications', 1990, 20);
+
+-- =============================================
+-- 4. ABONNEMENTS (Le "Cœur" du Business Model)
 -- =============================================
 
 CREATE TABLE subscriptions (
@@ -40,33 +80,27 @@ CREATE TABLE subscriptions (
     user_id INT REFERENCES users(id),
     plan_type sub_plan NOT NULL, -- Discovery (Gratuit), Serenity (9.90), Premium (29.90)
     frequency billing_freq, -- Mensuel ou Annuel (2 mois offerts gérés par le billing engine)
-    status VARCHAR(50) DEFAULT 'active', -- active, cancelled, past_due
+    status VARCHAR(50) DEFAULT 'active', -- incomplete (premier paiement en attente), active, cancelled, past_due
     start_date DATE NOT NULL,
     end_date DATE, -- Null si renouvellement auto, ou date de fin prépayée
     max_properties_limit INT DEFAULT 0, -- 0 (Free), 1 (Serenity), 5 (Premium) [cite: 51]
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    current_period_start DATE, -- Période de facturation en cours
+    current_period_end DATE, -- Échéance : le billing engine facture la période suivante à cette date
+    next_credit_grant DATE, -- Prochain versement mensuel des crédits inclus (mensuel aussi pour les plans annuels)
+    granted_credits INT NOT NULL DEFAULT 0, -- Crédits du plan versés au dernier cycle (base de leur expiration)
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    catalog_item_id INT REFERENCES catalog_items(id), -- Version de l'offre souscrite (tarif et crédits appliqués)
+    cancel_at_period_end BOOLEAN NOT NULL DEFAULT FALSE, -- Résiliée à l'échéance (current_period_end)
+    scheduled_catalog_item_id INT REFERENCES catalog_items(id), -- Formule appliquée à l'échéance
+    scheduled_archived_property_ids INT[], -- Biens archivés à l'échéance de la descente en gamme
+    ended_at DATE -- Fin effective de l'accès d'un abonnement résilié
 );
 
--- TABLE CRITIQUE : Gestion des Crédits de Solvabilité ("Ledger")
--- Plutôt qu'un compteur simple, on utilise un journal de transactions pour gérer les packs et les resets mensuels.
-CREATE TABLE credit_transactions (
-    id SERIAL PRIMARY KEY,
-    user_id INT REFERENCES users(id),
-    amount INT NOT NULL, -- Positif (ajout) ou Négatif (consommation)
-    transaction_type VARCHAR(50) NOT NULL, 
-    -- Types: 'plan_renewal' (+20/+30), 'pack_purchase' (+20), 'check_usage' (-1), 'initial_free' (+3)
-    description TEXT, -- Ex: "Pack Achat à l'acte", "Renouvellement Mensuel Premium"
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-);
-
--- Vue pour obtenir le solde actuel instantanément
-CREATE VIEW view_user_credit_balance AS
-SELECT user_id, SUM(amount) as current_balance
-FROM credit_transactions
-GROUP BY user_id;
+-- Un seul abonnement en cours par utilisateur ; les abonnements terminés restent pour l'historique.
+CREATE UNIQUE INDEX idx_subscriptions_current ON subscriptions(user_id) WHERE status IN ('incomplete', 'active', 'past_due');
 
 -- =============================================
--- 4. GESTION DES BIENS (LOGEMENTS)
+-- 5. GESTION DES BIENS (LOGEMENTS)
 -- =============================================
 
 CREATE TABLE properties (
@@ -81,13 +115,161 @@ CREATE TABLE properties (
     deposit_amount DECIMAL(10, 2),
     is_furnished BOOLEAN DEFAULT FALSE, -- Meublé ou Non
     seasonal_price_per_night DECIMAL(10, 2), -- Prix nuitée saisonnier
-    vacancy_credits INTEGER NOT NULL DEFAULT 20,
+    vacancy_credits INTEGER NOT NULL DEFAULT 0, -- Reflet du solde du compte de crédits du bien (versés par une écriture)
     is_active BOOLEAN DEFAULT TRUE,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+-- Photos & diagnostics obligatoires (DPE, ERP, plomb, électricité, gaz)
+CREATE TABLE property_media (
+    id SERIAL PRIMARY KEY,
+    property_id INT NOT NULL REFERENCES properties(id),
+    media_kind VARCHAR(20) NOT NULL, -- 'photo' or 'diagnostic'
+    diagnostic_type VARCHAR(20), -- dpe, erp, lead, electricity, gas (NULL for photos)
+    original_filename TEXT NOT NULL,
+    storage_key TEXT NOT NULL, -- Nom du fichier dans le FileStorage
+    thumbnail_key TEXT, -- Miniature JPEG (photos uniquement)
+    content_type VARCHAR(100) NOT NULL, -- Type MIME détecté (sniffing), pas celui déclaré par le client
+    size_bytes BIGINT NOT NULL,
+    position INT NOT NULL DEFAULT 0, -- Ordre d'affichage des photos
+    is_cover BOOLEAN NOT NULL DEFAULT FALSE, -- Photo de couverture (une seule par bien)
+    expires_at DATE, -- Fin de validité du diagnostic
+    reminder_sent_at TIMESTAMP, -- Relance d'expiration envoyée au propriétaire
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_property_media_cover ON property_media(property_id) WHERE is_cover;
+-- Un seul diagnostic de chaque type par bien : une nouvelle version remplace la précédente
+CREATE UNIQUE INDEX idx_property_media_diagnostic ON property_media(property_id, diagnostic_type) WHERE media_kind = 'diagnostic';
+
+-- =============================================
+-- 6. CRÉDITS DE SOLVABILITÉ (GRAND LIVRE EN PARTIE DOUBLE)
+-- =============================================
+
+-- Comptes de crédits : portefeuille global d'un utilisateur ('global'), portefeuille d'un bien ('property',
+-- ses crédits de vacance) et compte de la plateforme ('system'), contrepartie des crédits émis (plans, packs,
+-- bonus) et consommés (vérifications). Le solde des portefeuilles est tenu à chaque écriture ;
+-- properties.vacancy_credits n'est que le reflet du solde du compte du bien.
+CREATE TABLE credit_accounts (
+    id SERIAL PRIMARY KEY,
+    kind VARCHAR(20) NOT NULL, -- 'global', 'property', 'system'
+    user_id INT REFERENCES users(id), -- Titulaire du portefeuille (NULL pour le compte système)
+    property_id INT UNIQUE REFERENCES properties(id),
+    balance INT NOT NULL DEFAULT 0, -- Non tenu pour le compte système
+    last_transaction_id INT, -- Dernière ligne appliquée
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (kind = 'system' OR balance >= 0),
+    CHECK ((kind = 'property') = (property_id IS NOT NULL)),
+    CHECK ((kind = 'system') = (user_id IS NULL))
+);
+
+CREATE UNIQUE INDEX idx_credit_accounts_global ON credit_accounts(user_id) WHERE kind = 'global';
+CREATE UNIQUE INDEX idx_credit_accounts_system ON credit_accounts(kind) WHERE kind = 'system';
+
+INSERT INTO credit_accounts (kind) VALUES ('system');
+
+-- TABLE CRITIQUE : journal des crédits ("Ledger"). Une écriture (entry_id) regroupe une ligne par compte
+-- mouvementé, de somme nulle : un crédit accordé débite le compte système, une vérification le crédite, un
+-- transfert entre portefeuilles débite l'un et crédite l'autre. user_id et property_id sont ceux du compte de
+-- la ligne ; les crédits d'un bien ne comptent pas dans le solde global.
+CREATE SEQUENCE credit_entry_seq;
+
+CREATE TABLE credit_transactions (
+    id SERIAL PRIMARY KEY,
+    user_id INT REFERENCES users(id),
+    amount INT NOT NULL, -- Positif (ajout) ou Négatif (consommation)
+    transaction_type VARCHAR(50) NOT NULL,
+    -- Types: 'plan_renewal' (+20/+30), 'plan_upgrade' (complément à la montée en gamme), 'plan_expiry' (crédits du plan non utilisés), 'pack_purchase' (+20), 'pack_refund' (crédits d'un pack remboursé), 'check_usage' (-1), 'initial_free' (+3), 'refund' (+1), 'property_grant' (+20 à la création d'un bien), 'opening_balance' (reprise des crédits des biens), 'transfer'
+    description TEXT, -- Ex: "Pack Achat à l'acte", "Renouvellement Mensuel Premium"
+    refund_of INT UNIQUE REFERENCES credit_transactions(id), -- Ligne remboursée par ce 'refund' : une consommation n'est remboursée qu'une fois
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    property_id INT REFERENCES properties(id), -- Portefeuille d'un bien
+    balance_after INT, -- Solde du portefeuille après la ligne (NULL sur le compte système)
+    account_id INT REFERENCES credit_accounts(id),
+    entry_id INT -- Écriture à laquelle appartient la ligne
+);
+
+CREATE INDEX idx_credit_transactions_user ON credit_transactions(user_id, id);
+CREATE INDEX idx_credit_transactions_account ON credit_transactions(account_id, id);
+CREATE INDEX idx_credit_transactions_entry ON credit_transactions(entry_id);
+
+-- Chaque ligne met à jour le solde de son portefeuille dans la même transaction. Une ligne peut désigner son
+-- portefeuille par son titulaire (user_id, ou property_id) plutôt que par account_id : le compte est alors
+-- ouvert au besoin. Le solde doit être celui laissé par la dernière ligne du compte : un écart (modification
+-- hors journal) bloque toute nouvelle écriture sur ce compte. Seuls les portefeuilles sont verrouillés : le
+-- compte système, sans solde tenu, est mouvementé par presque toutes les écritures. Un portefeuille de bien est
+-- verrouillé après son bien (ordre suivi par l'application : bien, utilisateur, puis portefeuille).
+CREATE FUNCTION post_credit_transaction() RETURNS trigger AS $$
+DECLARE
+    wallet credit_accounts%ROWTYPE;
+    expected INT;
+BEGIN
+    IF NEW.account_id IS NULL THEN
+        IF NEW.property_id IS NOT NULL THEN
+            INSERT INTO credit_accounts (kind, user_id, property_id)
+            SELECT 'property', owner_id, id FROM properties WHERE id = NEW.property_id
+            ON CONFLICT (property_id) DO NOTHING;
+            SELECT id INTO NEW.account_id FROM credit_accounts WHERE property_id = NEW.property_id;
+        ELSE
+            INSERT INTO credit_accounts (kind, user_id) VALUES ('global', NEW.user_id)
+            ON CONFLICT (user_id) WHERE kind = 'global' DO NOTHING;
+            SELECT id INTO NEW.account_id FROM credit_accounts WHERE kind = 'global' AND user_id = NEW.user_id;
+        END IF;
+    END IF;
+
+    SELECT * INTO wallet FROM credit_accounts WHERE id = NEW.account_id;
+    IF NOT FOUND THEN
+        RAISE EXCEPTION 'no credit account for the transaction';
+    END IF;
+    NEW.user_id := wallet.user_id;
+    NEW.property_id := wallet.property_id;
+    NEW.entry_id := COALESCE(NEW.entry_id, nextval('credit_entry_seq'));
+    IF wallet.kind = 'system' THEN
+        NEW.balance_after := NULL;
+        RETURN NEW;
+    END IF;
+
+    SELECT * INTO wallet FROM credit_accounts WHERE id = NEW.account_id FOR UPDATE;
+
+    SELECT COALESCE((SELECT balance_after FROM credit_transactions WHERE id = wallet.last_transaction_id), 0)
+    INTO expected;
+    IF wallet.balance <> expected THEN
+        RAISE EXCEPTION 'credit account % has a balance of % but the ledger ends at %', wallet.id, wallet.balance, expected;
+    END IF;
+
+    NEW.balance_after := wallet.balance + NEW.amount;
+    UPDATE credit_accounts
+    SET balance = NEW.balance_after, last_transaction_id = NEW.id, updated_at = NOW()
+    WHERE id = wallet.id;
+    IF wallet.kind = 'property' THEN
+        UPDATE properties SET vacancy_credits = NEW.balance_after WHERE id = wallet.property_id;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER credit_transactions_post
+BEFORE INSERT ON credit_transactions
+FOR EACH ROW EXECUTE FUNCTION post_credit_transaction();
+
+-- Partie double : à la validation de la transaction, les lignes de chaque écriture doivent s'équilibrer.
+CREATE FUNCTION check_credit_entry_balanced() RETURNS trigger AS $$
+BEGIN
+    IF (SELECT SUM(amount) FROM credit_transactions WHERE entry_id = NEW.entry_id) <> 0 THEN
+        RAISE EXCEPTION 'credit entry % is not balanced', NEW.entry_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER credit_entries_balanced
+AFTER INSERT ON credit_transactions
+DEFERRABLE INITIALLY DEFERRED
+FOR EACH ROW EXECUTE FUNCTION check_credit_entry_balanced();
+
 -- =============================================
--- 5. MODULE VÉRIFICATION SOLVABILITÉ (Check Locataire)
+-- 7. MODULE VÉRIFICATION SOLVABILITÉ (Check Locataire)
 -- =============================================
 
 CREATE TABLE solvency_checks (
@@ -98,14 +280,31 @@ CREATE TABLE solvency_checks (
     property_id INT REFERENCES properties(id),
     status solvency_status DEFAULT 'pending',
     credit_source VARCHAR(20), -- 'property' or 'global'
-    score_result INT, -- Résultat de l'algo
+    score_result INT, -- Score 0-100 de l'analyse des revenus
+    analysis_json JSONB, -- Analyse détaillée : revenus récurrents, charges, facteurs du score
     report_url VARCHAR(255), -- Lien vers le PDF généré
-    documents_json JSONB, -- Liens vers fiches de paie stockées sécurisées
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    documents_json JSONB, -- Pièces déposées par le candidat (type, fichier chiffré), cf. SolvencyService
+    missing_documents JSONB, -- Pièces manquantes demandées par le propriétaire (statut 'insufficient_docs')
+    bank_provider VARCHAR(30), -- Agrégateur Open Banking utilisé ('fake', ...)
+    bank_consent_id VARCHAR(255), -- Session de consentement ouverte pour le candidat
+    bank_connection_id VARCHAR(255), -- Connexion obtenue après échange du code (jamais de jeton stocké)
+    employment_type VARCHAR(30), -- Situation professionnelle déclarée par le candidat ('cdi', 'civil_servant', ...)
+    guarantee_type VARCHAR(30), -- Garantie proposée par le candidat ('visale', 'guarantor', ...)
+    policy_results JSONB, -- Résultat de chaque règle de la politique d'acceptation du propriétaire
+    combined_score INT, -- Score du candidat et de ses garants réunis (NULL sans garant analysé)
+    expires_at TIMESTAMP, -- Passé ce délai sans réponse du candidat, le dossier expire et le crédit est rendu
+    reminders_sent INT NOT NULL DEFAULT 0, -- Nombre de relances envoyées au candidat
+    selection VARCHAR(20), -- Choix du propriétaire parmi les candidats : 'shortlisted' ou 'declined'
+    selection_at TIMESTAMP,
+    documents_purged_at TIMESTAMP, -- Pièces et rapport supprimés au terme de la durée de conservation
+    anonymized_at TIMESTAMP, -- Candidat non retenu : données personnelles effacées, seuls statut et score restent
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    dossier_share_id INT, -- Partage de dossier locataire utilisé pour créer le dossier (clé étrangère en section 16)
+    credit_transaction_id INT REFERENCES credit_transactions(id) -- Ligne du portefeuille (global ou du bien) débitée
 );
 
 -- =============================================
--- 6. LOCATION LONGUE DURÉE (Bail & Loyer)
+-- 8. LOCATION LONGUE DURÉE (Bail & Loyer)
 -- =============================================
 
 CREATE TABLE leases (
@@ -124,9 +323,34 @@ CREATE TABLE leases (
     signature_envelope_id TEXT, -- ID from external provider (Yousign/DocuSign)
     contract_url VARCHAR(255), -- Bail signé électroniquement [cite: 25]
     escrow_deposit_status escrow_status DEFAULT 'held', -- Séquestre de la caution [cite: 27]
+    joint_liability BOOLEAN DEFAULT TRUE, -- Colocation : clause de solidarité entre colocataires
+    individual_rent_shares BOOLEAN DEFAULT FALSE, -- Colocation : chaque colocataire règle sa part (échéancier scindé)
+    anonymized_at TIMESTAMP, -- Bail terminé anonymisé au terme de la durée de conservation
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+-- Parties au bail : un bail peut réunir plusieurs colocataires, chacun signataire.
+-- leases.tenant_id reste le premier locataire ayant accepté (compatibilité).
+CREATE TABLE lease_parties (
+    id SERIAL PRIMARY KEY,
+    lease_id INT NOT NULL REFERENCES leases(id) ON DELETE CASCADE,
+    user_id INT REFERENCES users(id), -- NULL tant que l'invitation n'est pas acceptée
+    email VARCHAR(255) NOT NULL,
+    first_name VARCHAR(100),
+    last_name VARCHAR(100),
+    rent_share DECIMAL(10, 2), -- Part mensuelle charges comprises (NULL : répartition à parts égales)
+    status VARCHAR(20) NOT NULL DEFAULT 'invited', -- invited, active, left
+    joined_at TIMESTAMP,
+    notice_date DATE, -- Réception du congé par le bailleur
+    left_at DATE, -- Date d'effet du congé (fin du préavis)
+    solidarity_ends_at DATE, -- Fin de la solidarité : remplaçant au bail, au plus tard 6 mois après left_at
+    replaces_party_id INT REFERENCES lease_parties(id), -- Colocataire sortant remplacé par celui-ci
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (lease_id, email)
+);
+
+CREATE INDEX idx_lease_parties_user ON lease_parties(user_id);
+
 -- Gestion des quittances et paiements récurrents
 CREATE TABLE rent_payments (
     id SERIAL PRIMARY KEY,
@@ -140,7 +364,7 @@ CREATE TABLE rent_payments (
 );
 
 -- =============================================
--- 7. LOCATION SAISONNIÈRE (Vacances)
+-- 9. LOCATION SAISONNIÈRE (Vacances)
 -- =============================================
 
 CREATE TABLE seasonal_bookings (
@@ -159,30 +383,36 @@ CREATE TABLE seasonal_bookings (
 );
 
 -- =============================================
--- 8. FLUX FINANCIERS GLOBAUX
+-- 10. FLUX FINANCIERS GLOBAUX
 -- =============================================
 
 CREATE TABLE transactions (
     id SERIAL PRIMARY KEY,
     user_id INT REFERENCES users(id), -- Qui a payé ou reçu
-    related_entity_type VARCHAR(50), -- 'subscription', 'pack_purchase', 'rent_payment', 'seasonal_booking'
-    related_entity_id INT, -- ID de la commande/réservation
+    related_entity_type VARCHAR(50), -- 'invoice' (abonnement), 'pack_purchase', 'refund', 'rent_payment', 'seasonal_booking'
+    related_entity_id INT, -- ID de la facture / de l'offre du catalogue / de la transaction remboursée
     amount DECIMAL(10, 2) NOT NULL,
     currency VARCHAR(3) DEFAULT 'EUR',
-    direction VARCHAR(10), -- 'inbound' (Locataire -> Séculoc), 'outbound' (Séculoc -> Proprio)
+    direction VARCHAR(10), -- 'inbound' (Locataire -> Séculoc), 'outbound' (Séculoc -> Proprio, remboursements)
     stripe_payment_intent_id VARCHAR(100),
-    status VARCHAR(50) DEFAULT 'success',
+    stripe_refund_id VARCHAR(100),
+    status VARCHAR(50) DEFAULT 'success', -- pending (3DS, prélèvement SEPA en cours), success, failed
+    failure_reason TEXT, -- Motif du refus renvoyé par le prestataire
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+-- Un paiement entrant par intention de paiement : les webhooks retrouvent la transaction par cet identifiant
+CREATE UNIQUE INDEX idx_transactions_payment_intent ON transactions(stripe_payment_intent_id) WHERE direction = 'inbound';
+
 -- =============================================
--- 9. GESTION DES INVITATIONS & ONBOARDING LOCATAIRES
+-- 11. GESTION DES INVITATIONS & ONBOARDING LOCATAIRES
 -- =============================================
 
 CREATE TABLE lease_invitations (
     id SERIAL PRIMARY KEY,
     property_id INT NOT NULL REFERENCES properties(id),
     lease_id INT REFERENCES leases(id), -- Linked Draft Lease
+    party_id INT REFERENCES lease_parties(id) ON DELETE CASCADE, -- Colocataire invité (une invitation par colocataire)
     owner_id INT NOT NULL REFERENCES users(id), -- L'expéditeur
     tenant_email VARCHAR(255) NOT NULL, -- Le destinataire
     token VARCHAR(255) UNIQUE NOT NULL, -- Token sécurisé envoyé par mail
@@ -190,3 +420,308 @@ CREATE TABLE lease_invitations (
     expires_at TIMESTAMP NOT NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
+
+-- =============================================
+-- 12. DOCUMENTS STOCKÉS & LIENS DE TÉLÉCHARGEMENT SIGNÉS
+-- =============================================
+
+-- Chaque version d'un document généré (bail, rapport de solvabilité, acte de caution, facture) est immuable.
+CREATE TABLE documents (
+    id SERIAL PRIMARY KEY,
+    document_type VARCHAR(30) NOT NULL, -- 'lease', 'solvency_report', 'guarantee_deed', 'invoice'
+    entity_id INT NOT NULL, -- leases.id, solvency_checks.id, solvency_guarantors.id ou invoices.id selon le type
+    version INT NOT NULL,
+    storage_key TEXT NOT NULL, -- Nom du fichier dans le FileStorage
+    content_type VARCHAR(100) NOT NULL,
+    filename TEXT NOT NULL, -- Nom proposé au téléchargement
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (document_type, entity_id, version)
+);
+
+-- Lien signé (HMAC) et expirant, limité à une version de document et à un utilisateur
+CREATE TABLE document_links (
+    id SERIAL PRIMARY KEY,
+    document_id INT NOT NULL REFERENCES documents(id),
+    user_id INT NOT NULL REFERENCES users(id), -- Seul utilisateur autorisé à utiliser le lien
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP, -- Non NULL = lien révoqué
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Journal d'audit : qui a consulté quel document, et quand
+CREATE TABLE document_access_logs (
+    id SERIAL PRIMARY KEY,
+    link_id INT REFERENCES document_links(id),
+    document_id INT NOT NULL REFERENCES documents(id),
+    user_id INT NOT NULL REFERENCES users(id),
+    ip_address VARCHAR(45),
+    user_agent TEXT,
+    accessed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- =============================================
+-- 13. WEBHOOKS PRESTATAIRES (anti-rejeu)
+-- =============================================
+
+-- Chaque événement reçu est enregistré une seule fois : une nouvelle livraison du même ID est ignorée
+CREATE TABLE webhook_events (
+    id SERIAL PRIMARY KEY,
+    provider VARCHAR(30) NOT NULL,
+    event_id VARCHAR(255) NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    received_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (provider, event_id)
+);
+
+-- =============================================
+-- 14. POLITIQUES D'ACCEPTATION DES DOSSIERS
+-- =============================================
+
+-- Critères d'acceptation d'un propriétaire : par défaut (property_id NULL) ou propres à un bien
+CREATE TABLE solvency_policies (
+    id SERIAL PRIMARY KEY,
+    owner_id INT NOT NULL REFERENCES users(id),
+    property_id INT REFERENCES properties(id), -- NULL = politique par défaut du propriétaire
+    income_multiplier DECIMAL(4, 2) NOT NULL DEFAULT 3, -- Revenus >= N x loyer
+    include_charges BOOLEAN NOT NULL DEFAULT TRUE, -- Le loyer de référence inclut les charges
+    count_guarantor_income BOOLEAN NOT NULL DEFAULT FALSE, -- Les revenus du garant s'ajoutent à ceux du candidat
+    min_employment_type VARCHAR(30), -- Situation minimale exigée (NULL = pas d'exigence)
+    accepted_guarantees JSONB NOT NULL DEFAULT '[]', -- Garanties acceptées ('visale', 'guarantor', ...)
+    require_guarantee BOOLEAN NOT NULL DEFAULT FALSE, -- Une garantie acceptée est obligatoire
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (property_id),
+    CHECK (income_multiplier > 0 AND income_multiplier <= 10)
+);
+
+CREATE UNIQUE INDEX idx_solvency_policies_owner_default ON solvency_policies(owner_id) WHERE property_id IS NULL;
+
+CREATE INDEX idx_solvency_checks_bank_connection ON solvency_checks(bank_provider, bank_connection_id);
+
+-- =============================================
+-- 15. GARANTS (CAUTIONS PERSONNES PHYSIQUES)
+-- =============================================
+
+-- Garant rattaché à un dossier de solvabilité : lien d'invitation, pièces et connexion bancaire propres
+CREATE TABLE solvency_guarantors (
+    id SERIAL PRIMARY KEY,
+    check_id INT NOT NULL REFERENCES solvency_checks(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    first_name VARCHAR(100),
+    last_name VARCHAR(100),
+    phone_number VARCHAR(20),
+    token VARCHAR(255) UNIQUE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'invited', -- invited, completed (revenus analysés)
+    documents_json JSONB, -- Pièces déposées par le garant (mêmes types que le candidat)
+    bank_provider VARCHAR(30),
+    bank_consent_id VARCHAR(255),
+    bank_connection_id VARCHAR(255),
+    monthly_income DECIMAL(10, 2), -- Revenus mensuels retenus par l'analyse
+    analysis_json JSONB, -- Analyse détaillée des revenus du garant
+    lease_id INT REFERENCES leases(id), -- Bail garanti, renseigné à la signature (acte de cautionnement)
+    mention_text TEXT, -- Mentions reproduites par le garant (équivalent électronique des mentions manuscrites)
+    mention_signed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_solvency_guarantors_check ON solvency_guarantors(check_id);
+CREATE INDEX idx_solvency_guarantors_bank_connection ON solvency_guarantors(bank_provider, bank_connection_id);
+
+-- =============================================
+-- 16. DOSSIER LOCATAIRE PORTABLE
+-- =============================================
+
+-- Dossier vérifié d'un candidat, construit à partir d'un de ses dossiers de solvabilité terminés et réutilisable
+-- auprès d'autres propriétaires pendant sa durée de validité
+CREATE TABLE tenant_dossiers (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    source_check_id INT REFERENCES solvency_checks(id) ON DELETE SET NULL, -- Dossier de solvabilité d'origine
+    documents_json JSONB, -- Copie des pièces du candidat (fichiers propres au dossier)
+    analysis_json JSONB NOT NULL, -- Analyse des revenus, re-notée selon le loyer de chaque bien
+    employment_type VARCHAR(30),
+    guarantee_type VARCHAR(30),
+    verified_at TIMESTAMP NOT NULL, -- Date de l'analyse bancaire d'origine
+    expires_at TIMESTAMP NOT NULL, -- Au-delà, le dossier ne peut plus être partagé
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Partage révocable d'un dossier avec un propriétaire
+CREATE TABLE dossier_shares (
+    id SERIAL PRIMARY KEY,
+    dossier_id INT NOT NULL REFERENCES tenant_dossiers(id) ON DELETE CASCADE,
+    token VARCHAR(255) UNIQUE NOT NULL,
+    label VARCHAR(255), -- Destinataire indiqué par le candidat
+    revoked_at TIMESTAMP, -- Non NULL = partage révoqué
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_dossier_shares_dossier ON dossier_shares(dossier_id);
+
+-- Partage utilisé pour créer un dossier de solvabilité. Contrainte déclarée après coup : tenant_dossiers
+-- référence déjà solvency_checks.
+ALTER TABLE solvency_checks ADD CONSTRAINT solvency_checks_dossier_share_id_fkey
+    FOREIGN KEY (dossier_share_id) REFERENCES dossier_shares(id) ON DELETE SET NULL;
+
+-- =============================================
+-- 17. CONSERVATION DES DONNÉES (RGPD)
+-- =============================================
+
+-- Journal des purges : une ligne par catégorie et par passage ayant supprimé ou anonymisé des données
+CREATE TABLE retention_purges (
+    id SERIAL PRIMARY KEY,
+    category VARCHAR(30) NOT NULL, -- 'solvency_documents', 'rejected_candidates', 'ended_leases', 'expired_dossiers', 'logs', 'provisional_users'
+    retention_days INT NOT NULL,
+    cutoff TIMESTAMP NOT NULL, -- Données antérieures à cette date
+    records INT NOT NULL, -- Lignes supprimées ou anonymisées
+    files INT NOT NULL, -- Fichiers supprimés du stockage
+    record_ids JSONB, -- Identifiants traités (aucune donnée personnelle)
+    purged_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- =============================================
+-- 18. FACTURATION RÉCURRENTE
+-- =============================================
+
+-- Une facture par période d'abonnement payante, émise par le billing engine à l'échéance.
+-- Un échec de paiement passe l'abonnement en 'past_due' ; le paiement est retenté chaque jour.
+-- Une facture est aussi émise pour un pack de crédits, une montée en gamme ou l'achat de biens supplémentaires
+-- (rattachée à l'abonnement, sans période). Elle est émise (numérotée) lorsqu'elle est payée ; un remboursement
+-- émet un avoir (kind = 'credit_note') sur la facture remboursée. Les mentions légales (numéro, montants HT,
+-- TVA, acheteur) sont figées à l'émission ; le PDF archivé ne change plus (documents, type 'invoice').
+CREATE TABLE invoices (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id),
+    subscription_id INT REFERENCES subscriptions(id),
+    description TEXT NOT NULL, -- Ex: "Premium (yearly)"
+    amount_cents INT NOT NULL,
+    period_start DATE,
+    period_end DATE,
+    status VARCHAR(20) NOT NULL DEFAULT 'open', -- open, processing (prélèvement en cours par une instance), pending (confirmation attendue du prestataire), paid, failed, void
+    attempts INT NOT NULL DEFAULT 0, -- Tentatives de paiement
+    last_attempt_at TIMESTAMP,
+    paid_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    kind VARCHAR(20) NOT NULL DEFAULT 'invoice', -- invoice, credit_note
+    catalog_item_id INT REFERENCES catalog_items(id), -- Pack acheté (facture sans abonnement), formule visée par une montée en gamme
+    credited_invoice_id INT REFERENCES invoices(id), -- Facture remboursée par l'avoir
+    number VARCHAR(20) UNIQUE, -- Ex: F-2026-000001, AV-2026-000001 ; NULL tant que non émise
+    issued_at TIMESTAMP,
+    amount_excl_vat_cents INT, -- amount_cents est TTC
+    vat_rate_bps INT, -- Taux de TVA en points de base (2000 = 20 %)
+    vat_cents INT,
+    buyer_name VARCHAR(255), -- Copie de l'identité de l'acheteur (le compte peut être anonymisé)
+    buyer_email VARCHAR(255),
+    document_id INT REFERENCES documents(id), -- PDF archivé
+    slot_quantity INT, -- Achat de biens supplémentaires : nombre de biens facturés au prorata
+    credit_property_id INT REFERENCES properties(id) -- Pack acheté pour le portefeuille d'un bien plutôt que le portefeuille global
+);
+
+CREATE UNIQUE INDEX idx_invoices_subscription_period ON invoices(subscription_id, period_start);
+
+-- Numérotation continue, sans trou, par série et par année : le compteur est incrémenté dans la
+-- transaction qui émet la facture, une émission annulée libère donc son numéro.
+CREATE TABLE invoice_sequences (
+    series VARCHAR(5) NOT NULL, -- F (factures), AV (avoirs)
+    year INT NOT NULL,
+    last_number INT NOT NULL,
+    PRIMARY KEY (series, year)
+);
+
+-- Une facture émise ne peut plus être modifiée (seul son PDF archivé peut être rattaché) ni supprimée.
+CREATE FUNCTION protect_issued_invoice() RETURNS trigger AS $$
+BEGIN
+    IF OLD.number IS NULL THEN
+        RETURN COALESCE(NEW, OLD);
+    END IF;
+    IF TG_OP = 'DELETE' OR NEW.number IS DISTINCT FROM OLD.number OR NEW.amount_cents <> OLD.amount_cents
+        OR NEW.amount_excl_vat_cents IS DISTINCT FROM OLD.amount_excl_vat_cents OR NEW.vat_cents IS DISTINCT FROM OLD.vat_cents
+        OR NEW.vat_rate_bps IS DISTINCT FROM OLD.vat_rate_bps OR NEW.issued_at IS DISTINCT FROM OLD.issued_at
+        OR NEW.description <> OLD.description OR NEW.buyer_name IS DISTINCT FROM OLD.buyer_name
+        OR NEW.buyer_email IS DISTINCT FROM OLD.buyer_email
+        OR (OLD.document_id IS NOT NULL AND NEW.document_id IS DISTINCT FROM OLD.document_id) THEN
+        RAISE EXCEPTION 'invoice % is issued and cannot be changed', OLD.number;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER invoices_issued_immutable
+BEFORE UPDATE OR DELETE ON invoices
+FOR EACH ROW EXECUTE FUNCTION protect_issued_invoice();
+
+-- =============================================
+-- 19. IDEMPOTENCE DES REQUÊTES
+-- =============================================
+
+-- Réponse des requêtes envoyées avec un en-tête Idempotency-Key : une requête rejouée avec la même clé
+-- reçoit la réponse enregistrée au lieu d'être exécutée une seconde fois. Clés conservées 24 heures ; une clé
+-- restée 'processing' plus de 5 minutes (requête interrompue) peut être reprise.
+CREATE TABLE idempotency_keys (
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    idempotency_key VARCHAR(255) NOT NULL,
+    request_fingerprint VARCHAR(64) NOT NULL, -- SHA-256 de la méthode, du chemin et du corps
+    status VARCHAR(20) NOT NULL DEFAULT 'processing', -- processing, completed
+    response_code INT,
+    response_content_type VARCHAR(100),
+    response_body BYTEA,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    heartbeat_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, -- Rafraîchi tant que la requête s'exécute
+    completed_at TIMESTAMP,
+    PRIMARY KEY (user_id, idempotency_key)
+);
+
+CREATE INDEX idx_idempotency_keys_created_at ON idempotency_keys(created_at);
+
+-- =============================================
+-- 20. CHANGEMENTS DE FORMULE & RÉSILIATION
+-- =============================================
+
+-- Une montée en gamme s'applique immédiatement : le prorata de la nouvelle formule sur les jours restants,
+-- moins celui de l'ancienne, est facturé (facture rattachée à l'abonnement, sans période, catalog_item_id =
+-- formule visée). Une descente en gamme est programmée à l'échéance, avec les biens longue durée choisis
+-- par l'utilisateur pour être archivés (subscriptions.scheduled_*). Une résiliation conserve l'accès jusqu'à
+-- l'échéance (subscriptions.cancel_at_period_end).
+
+-- Historique des abonnements : une ligne par évènement, jamais modifiée.
+CREATE TABLE subscription_events (
+    id SERIAL PRIMARY KEY,
+    subscription_id INT NOT NULL REFERENCES subscriptions(id),
+    user_id INT NOT NULL REFERENCES users(id),
+    event_type VARCHAR(30) NOT NULL,
+    -- Types: 'subscribed', 'upgraded', 'downgrade_scheduled', 'downgrade_cancelled', 'downgraded',
+    -- 'cancellation_scheduled', 'cancellation_reverted', 'cancelled', 'slots_added', 'slots_removal_scheduled',
+    -- 'slots_removed'
+    from_catalog_item_id INT REFERENCES catalog_items(id),
+    to_catalog_item_id INT REFERENCES catalog_items(id),
+    effective_date DATE NOT NULL, -- Date d'effet (l'échéance pour un changement programmé)
+    amount_cents INT, -- Prorata facturé à la montée en gamme
+    invoice_id INT REFERENCES invoices(id),
+    archived_property_ids INT[], -- Biens archivés par la descente en gamme
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    slot_quantity INT -- Nombre de biens supplémentaires concernés ('slots_*')
+);
+
+CREATE INDEX idx_subscription_events_user ON subscription_events(user_id, id);
+
+-- =============================================
+-- 21. BIENS SUPPLÉMENTAIRES (ADD-ONS)
+-- =============================================
+
+-- Éléments facturés en plus de la formule, un par type d'ajout : biens longue durée supplémentaires
+-- ('property_slot'). Le prix unitaire mensuel est celui de la formule au moment de l'achat ; un achat en
+-- cours de période est facturé au prorata, un retrait prend effet à l'échéance (scheduled_quantity).
+-- max_properties_limit de l'abonnement = biens inclus dans la formule + quantity.
+CREATE TABLE subscription_items (
+    id SERIAL PRIMARY KEY,
+    subscription_id INT NOT NULL REFERENCES subscriptions(id),
+    kind VARCHAR(20) NOT NULL, -- 'property_slot'
+    quantity INT NOT NULL DEFAULT 0,
+    unit_price_cents INT NOT NULL, -- Prix mensuel unitaire (x12 pour un abonnement annuel)
+    scheduled_quantity INT, -- Quantité appliquée à l'échéance, NULL si aucun retrait programmé
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (subscription_id, kind),
+    CHECK (quantity >= 0 AND scheduled_quantity >= 0)
+);
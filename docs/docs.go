@@ -24,36 +24,75 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/auth/login": {
+        "/admin/catalog": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Every version of every offer, past and scheduled ones included",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List the catalog (admin)",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/seculoc-back_internal_core_service.CatalogItem"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
             "post": {
-                "description": "Authenticate user and return JWT token",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Adds a plan or a pack, or a new version of one (same code). Its validity period cannot overlap\nanother version: set valid_until on the current version first.",
                 "consumes": [
-                    "application/json",
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "Login user",
+                "summary": "Add a catalog item (admin)",
                 "parameters": [
                     {
-                        "description": "Login Credentials",
+                        "description": "Catalog item",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/internal_adapter_http_handler.LoginRequest"
+                            "$ref": "#/definitions/internal_adapter_http_handler.CatalogItemRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/internal_adapter_http_handler.LoginResponse"
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.CatalogItem"
                         }
                     },
                     "400": {
@@ -65,8 +104,17 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -77,9 +125,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/auth/register": {
-            "post": {
-                "description": "Create a new user account",
+        "/admin/catalog/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replaces a version of an offer that has not been sold yet. A version referred to by a subscription, an invoice or a payment cannot be edited (409): retire it and create a new version.",
                 "consumes": [
                     "application/json"
                 ],
@@ -87,26 +140,32 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "Register a new user",
+                "summary": "Update a catalog item (admin)",
                 "parameters": [
                     {
-                        "description": "Registration Info",
+                        "type": "integer",
+                        "description": "Catalog item ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Catalog item",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/internal_adapter_http_handler.RegisterRequest"
+                            "$ref": "#/definitions/internal_adapter_http_handler.CatalogItemRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.CatalogItem"
                         }
                     },
                     "400": {
@@ -117,13 +176,95 @@ const docTemplate = `{
                                 "type": "string"
                             }
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Ends the offer's validity now. Existing subscriptions keep it; it can no longer be subscribed or bought.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Retire a catalog item (admin)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Catalog item ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.CatalogItem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
                     }
                 }
             }
         },
-        "/auth/switch-context": {
+        "/admin/transactions/{id}/refund": {
             "post": {
-                "description": "Switch between owner and tenant context",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Refunds a successful payment, fully or partially. The credits of a pack are taken back in\nproportion, up to the balance of the wallet they went to; subscription periods are not.\nA refund interrupted earlier is resumed first: amount_cents must then be omitted or match it.",
                 "consumes": [
                     "application/json"
                 ],
@@ -131,17 +272,23 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "Switch user context",
+                "summary": "Refund a payment (admin)",
                 "parameters": [
                     {
-                        "description": "Context Info",
+                        "type": "integer",
+                        "description": "Transaction ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Amount",
                         "name": "request",
                         "in": "body",
-                        "required": true,
                         "schema": {
-                            "$ref": "#/definitions/internal_adapter_http_handler.SwitchContextRequest"
+                            "$ref": "#/definitions/internal_adapter_http_handler.RefundRequest"
                         }
                     }
                 ],
@@ -149,7 +296,8 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_adapter_http_handler.LoginResponse"
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     },
                     "400": {
@@ -160,37 +308,53 @@ const docTemplate = `{
                                 "type": "string"
                             }
                         }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
                     }
                 }
             }
         },
-        "/invitations": {
+        "/auth/login": {
             "post": {
-                "description": "Send an invitation to a tenant",
+                "description": "Authenticate user and return JWT token",
                 "consumes": [
+                    "application/json",
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "invitations"
+                    "auth"
                 ],
-                "summary": "Invite a tenant",
+                "summary": "Login user",
                 "parameters": [
                     {
-                        "description": "Invitation Info",
+                        "description": "Login Credentials",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/internal_adapter_http_handler.InviteTenantRequest"
+                            "$ref": "#/definitions/internal_adapter_http_handler.LoginRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.LoginResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -198,8 +362,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -210,9 +374,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/invitations/accept": {
+        "/auth/register": {
             "post": {
-                "description": "Accept a tenant invitation",
+                "description": "Create a new user account",
                 "consumes": [
                     "application/json"
                 ],
@@ -220,28 +384,26 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "invitations"
+                    "auth"
                 ],
-                "summary": "Accept an invitation",
+                "summary": "Register a new user",
                 "parameters": [
                     {
-                        "description": "Token",
+                        "description": "Registration Info",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/internal_adapter_http_handler.AcceptInvitationRequest"
+                            "$ref": "#/definitions/internal_adapter_http_handler.RegisterRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "additionalProperties": true
                         }
                     },
                     "400": {
@@ -256,9 +418,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/invitations/{token}": {
-            "get": {
-                "description": "Get details for an invitation by token",
+        "/auth/switch-context": {
+            "post": {
+                "description": "Switch between owner and tenant context",
                 "consumes": [
                     "application/json"
                 ],
@@ -266,23 +428,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "invitations"
+                    "auth"
                 ],
-                "summary": "Get invitation details",
+                "summary": "Switch user context",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Invitation Token",
-                        "name": "token",
-                        "in": "path",
-                        "required": true
+                        "description": "Context Info",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.SwitchContextRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/seculoc-back_internal_core_service.InvitationDetailsDTO"
+                            "$ref": "#/definitions/internal_adapter_http_handler.LoginResponse"
                         }
                     },
                     "400": {
@@ -297,36 +461,49 @@ const docTemplate = `{
                 }
             }
         },
-        "/leases": {
+        "/documents": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get all active leases where the authenticated user is the tenant",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "List the stored versions of a lease or solvency report (newest first)",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "leases"
+                    "documents"
+                ],
+                "summary": "List document versions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "lease or solvency_report",
+                        "name": "type",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Lease or solvency check ID",
+                        "name": "entity_id",
+                        "in": "query",
+                        "required": true
+                    }
                 ],
-                "summary": "List user leases",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/seculoc-back_internal_core_service.LeaseDTO"
+                                "$ref": "#/definitions/seculoc-back_internal_core_service.DocumentDTO"
                             }
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -334,8 +511,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -346,14 +523,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/leases/draft": {
+        "/documents/links": {
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Create a new lease in draft mode and invite the tenant",
+                "description": "Issue a short-lived, revocable link to one document version, usable only by the caller.\nThe link can be opened without the Authorization header (email, embedded PDF viewer).",
                 "consumes": [
                     "application/json"
                 ],
@@ -361,17 +538,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "leases"
+                    "documents"
                 ],
-                "summary": "Create a draft lease",
+                "summary": "Create a signed download link",
                 "parameters": [
                     {
-                        "description": "Draft Lease Details",
+                        "description": "Document version or latest of an entity",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/seculoc-back_internal_core_service.DraftLeaseRequest"
+                            "$ref": "#/definitions/internal_adapter_http_handler.CreateDocumentLinkRequest"
                         }
                     }
                 ],
@@ -379,8 +556,7 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.DocumentLinkDTO"
                         }
                     },
                     "400": {
@@ -392,8 +568,17 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -404,28 +589,37 @@ const docTemplate = `{
                 }
             }
         },
-        "/leases/{id}/download": {
+        "/documents/links/{linkId}": {
             "get": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Generate and download the lease contract as PDF",
+                "description": "Public endpoint: the HMAC signature replaces the bearer token. Each access is audited.",
                 "produces": [
-                    "application/pdf"
+                    "application/octet-stream"
                 ],
                 "tags": [
-                    "leases"
+                    "documents"
                 ],
-                "summary": "Download lease document (PDF)",
+                "summary": "Open a signed link",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Lease ID",
-                        "name": "id",
+                        "description": "Link ID",
+                        "name": "linkId",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Expiry (unix timestamp)",
+                        "name": "expires",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "HMAC signature",
+                        "name": "signature",
+                        "in": "query",
+                        "required": true
                     }
                 ],
                 "responses": {
@@ -435,15 +629,6 @@ const docTemplate = `{
                             "type": "file"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
-                    },
                     "403": {
                         "description": "Forbidden",
                         "schema": {
@@ -453,8 +638,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "410": {
+                        "description": "Gone",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -463,41 +648,32 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/leases/{id}/preview": {
-            "get": {
+            },
+            "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get the lease contract as HTML for display",
                 "produces": [
-                    "text/html"
+                    "application/json"
                 ],
                 "tags": [
-                    "leases"
+                    "documents"
                 ],
-                "summary": "Preview lease document",
+                "summary": "Revoke a signed link",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Lease ID",
-                        "name": "id",
+                        "description": "Link ID",
+                        "name": "linkId",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "HTML Content",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
+                        "description": "OK",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -513,27 +689,13 @@ const docTemplate = `{
                                 "type": "string"
                             }
                         }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
                     }
                 }
             }
         },
-        "/properties": {
-            "get": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Get all properties belonging to the authenticated user",
+        "/invitations": {
+            "post": {
+                "description": "Send an invitation to a tenant",
                 "consumes": [
                     "application/json"
                 ],
@@ -541,29 +703,45 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "properties"
+                    "invitations"
+                ],
+                "summary": "Invite a tenant",
+                "parameters": [
+                    {
+                        "description": "Invitation Info",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.InviteTenantRequest"
+                        }
+                    }
                 ],
-                "summary": "List user properties",
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "type": "object",
-                                "additionalProperties": true
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     }
                 }
-            },
+            }
+        },
+        "/invitations/accept": {
             "post": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Create a property listing (Long Term or Seasonal)",
+                "description": "Accept a tenant invitation",
                 "consumes": [
                     "application/json"
                 ],
@@ -571,29 +749,23 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "properties"
+                    "invitations"
                 ],
-                "summary": "Create a new property",
+                "summary": "Accept an invitation",
                 "parameters": [
                     {
-                        "description": "Property Info",
+                        "description": "Token",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/internal_adapter_http_handler.CreatePropertyRequest"
+                            "$ref": "#/definitions/internal_adapter_http_handler.AcceptInvitationRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
-                        "schema": {
-                            "$ref": "#/definitions/internal_adapter_http_handler.PropertyResponse"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
+                    "200": {
+                        "description": "OK",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -601,8 +773,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "403": {
-                        "description": "Forbidden",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -613,65 +785,35 @@ const docTemplate = `{
                 }
             }
         },
-        "/properties/{id}": {
-            "put": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    },
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Soft delete a property belonging to the authenticated user\nUpdate details of an existing property",
+        "/invitations/{token}": {
+            "get": {
+                "description": "Get details for an invitation by token",
                 "consumes": [
-                    "application/json",
                     "application/json"
                 ],
                 "produces": [
-                    "application/json",
                     "application/json"
                 ],
                 "tags": [
-                    "properties",
-                    "properties"
+                    "invitations"
                 ],
-                "summary": "Update a property",
+                "summary": "Get invitation details",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Property ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Property ID",
-                        "name": "id",
+                        "type": "string",
+                        "description": "Invitation Token",
+                        "name": "token",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Property Update Info",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/internal_adapter_http_handler.UpdatePropertyRequest"
-                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_adapter_http_handler.PropertyResponse"
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.InvitationDetailsDTO"
                         }
                     },
-                    "204": {
-                        "description": "No Content"
-                    },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
@@ -680,78 +822,40 @@ const docTemplate = `{
                                 "type": "string"
                             }
                         }
-                    },
-                    "403": {
-                        "description": "Forbidden",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/leases": {
+            "get": {
                 "security": [
-                    {
-                        "BearerAuth": []
-                    },
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Soft delete a property belonging to the authenticated user\nUpdate details of an existing property",
+                "description": "Get all active leases where the authenticated user is the tenant",
                 "consumes": [
-                    "application/json",
                     "application/json"
                 ],
                 "produces": [
-                    "application/json",
                     "application/json"
                 ],
                 "tags": [
-                    "properties",
-                    "properties"
-                ],
-                "summary": "Update a property",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Property ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Property ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Property Update Info",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/internal_adapter_http_handler.UpdatePropertyRequest"
-                        }
-                    }
+                    "leases"
                 ],
+                "summary": "List user leases",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_adapter_http_handler.PropertyResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/seculoc-back_internal_core_service.LeaseDTO"
+                            }
                         }
                     },
-                    "204": {
-                        "description": "No Content"
-                    },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -759,8 +863,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "403": {
-                        "description": "Forbidden",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -771,14 +875,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/solvency/check": {
+        "/leases/draft": {
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Consume credit to run solvency check on candidate and send invitation email",
+                "description": "Create a new lease in draft mode and invite the tenant",
                 "consumes": [
                     "application/json"
                 ],
@@ -786,17 +890,23 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "solvency"
+                    "leases"
                 ],
-                "summary": "Initiate Solvency Check",
+                "summary": "Create a draft lease",
                 "parameters": [
                     {
-                        "description": "Check Info",
+                        "type": "string",
+                        "description": "Unique key of the request: a retry with the same key replays the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Draft Lease Details",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/internal_adapter_http_handler.CreateCheckRequest"
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.DraftLeaseRequest"
                         }
                     }
                 ],
@@ -804,7 +914,8 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/internal_adapter_http_handler.SolvencyCheckResponse"
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     },
                     "400": {
@@ -815,29 +926,38 @@ const docTemplate = `{
                                 "type": "string"
                             }
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
                     }
                 }
             }
         },
-        "/solvency/check/{id}/cancel": {
-            "post": {
+        "/leases/{id}/download": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Cancel a pending check and refund credit to property or global wallet",
+                "description": "Generate and download the lease contract as PDF",
                 "produces": [
-                    "application/json"
+                    "application/pdf"
                 ],
                 "tags": [
-                    "solvency"
+                    "leases"
                 ],
-                "summary": "Cancel Solvency Check",
+                "summary": "Download lease document (PDF)",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Check ID",
+                        "description": "Lease ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -846,6 +966,12 @@ const docTemplate = `{
                 "responses": {
                     "200": {
                         "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -853,8 +979,17 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -865,27 +1000,28 @@ const docTemplate = `{
                 }
             }
         },
-        "/solvency/checks": {
+        "/leases/{id}/guarantors": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get all solvency checks for the current owner, optionally filtered by property",
+                "description": "Guarantors of the signed lease with their latest guarantee deed, for the owner and the tenant",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "solvency"
+                    "leases"
                 ],
-                "summary": "List Solvency Checks",
+                "summary": "List the guarantors of a lease",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Property ID filter",
-                        "name": "property_id",
-                        "in": "query"
+                        "description": "Lease ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
@@ -894,7 +1030,7 @@ const docTemplate = `{
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/internal_adapter_http_handler.SolvencyCheckResponse"
+                                "$ref": "#/definitions/seculoc-back_internal_core_service.LeaseGuarantorDTO"
                             }
                         }
                     },
@@ -906,18 +1042,27 @@ const docTemplate = `{
                                 "type": "string"
                             }
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
                     }
                 }
             }
         },
-        "/solvency/credits": {
+        "/leases/{id}/links": {
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Buy solvency check credits (e.g., pack_20)",
+                "description": "Issue a signed link to the latest version of the lease document, generating it if needed",
                 "consumes": [
                     "application/json"
                 ],
@@ -925,27 +1070,31 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "solvency"
+                    "leases"
                 ],
-                "summary": "Purchase Credit Pack",
+                "summary": "Create a signed link to the lease contract",
                 "parameters": [
                     {
-                        "description": "Credit Pack Info",
+                        "type": "integer",
+                        "description": "Lease ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Link lifetime",
                         "name": "request",
                         "in": "body",
-                        "required": true,
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/internal_adapter_http_handler.CreateLeaseLinkRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.DocumentLinkDTO"
                         }
                     },
                     "400": {
@@ -956,25 +1105,39 @@ const docTemplate = `{
                                 "type": "string"
                             }
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
                     }
                 }
             }
         },
-        "/solvency/public/check/{token}": {
+        "/leases/{id}/parties": {
             "get": {
-                "description": "Allows candidate to see the solvency check request",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Co-tenants of the lease with their status, rent share and solidarity end date",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "solvency"
+                    "leases"
                 ],
-                "summary": "Get Solvency Check Details (Public)",
+                "summary": "List the parties of a lease",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Check Token",
-                        "name": "token",
+                        "type": "integer",
+                        "description": "Lease ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
@@ -983,7 +1146,25 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_adapter_http_handler.PublicSolvencyCheckResponse"
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.LeasePartiesDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "404": {
@@ -996,11 +1177,14 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/solvency/public/check/{token}/callback": {
+            },
             "post": {
-                "description": "Endpoint for mocking Open Banking data callback",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Adds a co-tenant (colocation) to a long-term lease and emails them their own invitation link.\nreplaces_party_id takes over from a co-tenant who gave notice, which ends their solidarity.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1008,30 +1192,36 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "solvency"
+                    "leases"
                 ],
-                "summary": "Open Banking Callback (Public)",
+                "summary": "Invite a co-tenant",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Check Token",
-                        "name": "token",
+                        "type": "integer",
+                        "description": "Lease ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Transaction Data",
+                        "description": "Co-tenant",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/internal_adapter_http_handler.OpenBankingCallback"
+                            "$ref": "#/definitions/internal_adapter_http_handler.CoTenantRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.LeasePartyDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1039,8 +1229,26 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1051,14 +1259,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/subscriptions": {
+        "/leases/{id}/parties/{partyId}/leave": {
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Subscribe user to Discovery, Serenity, or Premium plan",
+                "description": "The co-tenant or the owner records the notice. The co-tenant leaves after the notice period\nand, under joint liability, remains liable for the rent for six more months unless replaced.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1066,26 +1274,39 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "subscriptions"
+                    "leases"
                 ],
-                "summary": "Subscribe to a plan",
+                "summary": "Record a co-tenant's notice",
                 "parameters": [
                     {
-                        "description": "Subscription Info",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": true
-                        }
-                    }
-                ],
-                "responses": {
+                        "type": "integer",
+                        "description": "Lease ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Party ID",
+                        "name": "partyId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Notice",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.DepartureRequest"
+                        }
+                    }
+                ],
+                "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_adapter_http_handler.SubscriptionResponse"
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.LeasePartyDTO"
                         }
                     },
                     "400": {
@@ -1096,18 +1317,176 @@ const docTemplate = `{
                                 "type": "string"
                             }
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
                     }
                 }
             }
         },
-        "/subscriptions/upgrade": {
-            "post": {
+        "/leases/{id}/preview": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the lease contract as HTML for display",
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "leases"
+                ],
+                "summary": "Preview lease document",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Lease ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "HTML Content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/leases/{id}/rent-schedule": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Monthly due dates from the current month, split per co-tenant when rent shares are individual,\nwith the departed co-tenants still jointly liable",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "leases"
+                ],
+                "summary": "Rent schedule of a lease",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Lease ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of months (1 to 24, default 12)",
+                        "name": "months",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/seculoc-back_internal_core_service.RentScheduleMonth"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/leases/{id}/rent-split": {
+            "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Purchase additional property slots (Serenity/Premium only)",
+                "description": "Joint and several liability and individual rent shares of a co-tenancy. When shares are given,\nthey must cover every current co-tenant and add up to the rent plus charges.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1115,18 +1494,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "subscriptions"
+                    "leases"
                 ],
-                "summary": "Increase property limit",
+                "summary": "Set how the rent is shared",
                 "parameters": [
                     {
-                        "description": "Limit Info",
+                        "type": "integer",
+                        "description": "Lease ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Rent split",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/internal_adapter_http_handler.RentSplitRequest"
                         }
                     }
                 ],
@@ -1134,8 +1519,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.LeasePartiesDTO"
                         }
                     },
                     "400": {
@@ -1146,504 +1530,6287 @@ const docTemplate = `{
                                 "type": "string"
                             }
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "internal_adapter_http_handler.AcceptInvitationRequest": {
-            "type": "object",
-            "required": [
-                "token"
-            ],
-            "properties": {
-                "token": {
-                    "type": "string"
+        },
+        "/me": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Erases the user's personal data (RGPD right to erasure). Credit ledger, payments and signed leases are\nkept, attached to the anonymised account, for the legal retention periods. Refused while a lease of\nthe user, as owner or tenant, is not terminated.",
+                "tags": [
+                    "account"
+                ],
+                "summary": "Delete my account",
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
                 }
             }
         },
-        "internal_adapter_http_handler.CreateCheckRequest": {
-            "type": "object",
-            "required": [
-                "candidate_email",
-                "property_id"
-            ],
-            "properties": {
-                "candidate_email": {
-                    "type": "string"
+        "/me/credits/accounts": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Wallets of the user with their balance: the global wallet, then the vacancy credits of each property.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "credits"
+                ],
+                "summary": "List my credit wallets",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/seculoc-back_internal_core_service.CreditAccountDTO"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/me/credits/transactions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Credit ledger of the user, latest first: global wallet entries (grants, packs, checks, refunds, expiries)\nand movements of the vacancy credits of their properties (wallet \"property\"). balance_after is the\nbalance of the wallet moved after the entry.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "credits"
+                ],
+                "summary": "List my credit transactions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "plan_renewal, plan_upgrade, plan_expiry, pack_purchase, check_usage, initial_free, refund, property_grant, opening_balance or transfer",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page, from 1",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Entries per page (max 100)",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.CreditTransactionPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/me/credits/transfers": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Moves credits from one wallet of the user to another (see GET /me/credits/accounts), e.g. from the\nglobal wallet to the vacancy credits of a property. The target property must be active.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "credits"
+                ],
+                "summary": "Transfer credits between my wallets",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Unique key of the request: a retry with the same key replays the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Wallets and amount",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.TransferCreditsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/seculoc-back_internal_core_service.CreditAccountDTO"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/me/export": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Zip archive of everything held about the user (RGPD right of access): data.json with the profile,\nproperties, leases, solvency checks as owner and as candidate, credit ledger, payments and invitations,\nand the stored files it lists under files/.",
+                "produces": [
+                    "application/zip"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "Export my data",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/me/invoices": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Invoices and credit notes issued to the user, latest first: number, VAT breakdown (amounts in cents,\nrate in basis points) and archived PDF. An invoice is issued once paid; a refund issues a credit note.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "invoices"
+                ],
+                "summary": "List my invoices",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/seculoc-back_internal_core_service.InvoiceDTO"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/me/invoices/{id}/pdf": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Archived PDF of an invoice or credit note of the user. It is rendered once and never changes afterwards.\nEach download is audited.",
+                "produces": [
+                    "application/pdf"
+                ],
+                "tags": [
+                    "invoices"
+                ],
+                "summary": "Download an invoice",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Invoice ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/me/payment-method/sepa": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Registers the IBAN with the payment provider. Subscription renewals and purchases without\npayment method are then debited from it. Only the mandate reference is stored.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payments"
+                ],
+                "summary": "Set up SEPA Direct Debit",
+                "parameters": [
+                    {
+                        "description": "Bank account",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.SepaMandateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/plans": {
+            "get": {
+                "description": "The offers currently available, with prices in cents",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "catalog"
+                ],
+                "summary": "List plans and credit packs",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.PlansResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/properties": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get all properties belonging to the authenticated user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "properties"
+                ],
+                "summary": "List user properties",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "object",
+                                "additionalProperties": true
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a property listing (Long Term or Seasonal)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "properties"
+                ],
+                "summary": "Create a new property",
+                "parameters": [
+                    {
+                        "description": "Property Info",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.CreatePropertyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.PropertyResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/properties/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Soft delete a property belonging to the authenticated user\nUpdate details of an existing property",
+                "consumes": [
+                    "application/json",
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/json"
+                ],
+                "tags": [
+                    "properties",
+                    "properties"
+                ],
+                "summary": "Update a property",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Property ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Property ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Property Update Info",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.UpdatePropertyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.PropertyResponse"
+                        }
+                    },
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Soft delete a property belonging to the authenticated user\nUpdate details of an existing property",
+                "consumes": [
+                    "application/json",
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/json"
+                ],
+                "tags": [
+                    "properties",
+                    "properties"
+                ],
+                "summary": "Update a property",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Property ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Property ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Property Update Info",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.UpdatePropertyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.PropertyResponse"
+                        }
+                    },
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/properties/{id}/candidates": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Ranks the completed checks of the property on a 0-100 ranking score weighing the solvency score (35),\nthe effort rate (25), the income stability (15), the completeness of the file (10) and the guarantee (15).\nEach signal gives the gap to the best candidate and highlights sum up the differences.\nDeclined candidates are listed last, unranked.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Compare the candidates of a property",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Property ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.CandidateComparison"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/properties/{id}/media": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Photos in display order followed by diagnostics (with expiry)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "property-media"
+                ],
+                "summary": "List property media",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Property ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/seculoc-back_internal_core_service.PropertyMediaDTO"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/properties/{id}/media/diagnostics": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Upload a diagnostic (PDF, JPEG or PNG). Replaces the previous diagnostic of the same type.\nThe expiry date defaults to the issue date plus the legal validity of the diagnostic.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "property-media"
+                ],
+                "summary": "Upload a property diagnostic",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Property ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "Diagnostic document",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "dpe, erp, lead, electricity or gas",
+                        "name": "diagnostic_type",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Issue date (YYYY-MM-DD)",
+                        "name": "issued_at",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Expiry date (YYYY-MM-DD)",
+                        "name": "expires_at",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.PropertyMediaDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/properties/{id}/media/order": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Set the display order of all the photos of a property",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "property-media"
+                ],
+                "summary": "Reorder property photos",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Property ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Photo IDs in display order",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.ReorderPhotosRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/properties/{id}/media/photos": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Upload a JPEG, PNG or GIF photo. A thumbnail is generated; the first photo becomes the cover.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "property-media"
+                ],
+                "summary": "Upload a property photo",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Property ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "Photo",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.PropertyMediaDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/properties/{id}/media/{mediaId}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Stream the original file, or its thumbnail with variant=thumbnail.\nWith object storage, redirects to a short-lived presigned URL instead.",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "property-media"
+                ],
+                "summary": "Download a property media",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Property ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Media ID",
+                        "name": "mediaId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "thumbnail",
+                        "name": "variant",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "302": {
+                        "description": "Redirect to the presigned URL",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "property-media"
+                ],
+                "summary": "Delete a property media",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Property ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Media ID",
+                        "name": "mediaId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/properties/{id}/media/{mediaId}/cover": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "property-media"
+                ],
+                "summary": "Set the cover photo",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Property ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Photo ID",
+                        "name": "mediaId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/properties/{id}/solvency-policy": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the owner's default policy (/solvency/policy) or the policy applying to a property\n(/properties/{id}/solvency-policy): its own override, else the owner's default. scope tells which one.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Get the solvency acceptance policy",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Property ID",
+                        "name": "id",
+                        "in": "path"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.SolvencyPolicy"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replaces the owner's default policy or a property's policy. A candidate is approved when the\nscore reaches SOLVENCY_MIN_SCORE and every rule passes: income \u003e= income_multiplier x rent\n(charges included or not, guarantor income counted or not; 0 disables it), minimum employment\ntype, accepted guarantees.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Set the solvency acceptance policy",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Property ID",
+                        "name": "id",
+                        "in": "path"
+                    },
+                    {
+                        "description": "Policy",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.SolvencyPolicyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.SolvencyPolicy"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "The owner's default policy applies again to the property's checks",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Remove a property's solvency policy",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Property ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/solvency/check": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Consume credit to run solvency check on candidate and send invitation email",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Initiate Solvency Check",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Unique key of the request: a retry with the same key replays the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Check Info",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.CreateCheckRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.SolvencyCheckResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/solvency/check/from-dossier": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Completes a check at once from a tenant dossier shared with the owner: the analysis is re-scored\nagainst the property rent and the owner's policy applied. Consumes a credit only when\nSOLVENCY_DOSSIER_CONSUMES_CREDIT is set.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Initiate a solvency check from a shared dossier",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Unique key of the request: a retry with the same key replays the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Share token and property",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.CheckFromDossierRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.SolvencyCheckResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "402": {
+                        "description": "Payment Required",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "410": {
+                        "description": "Gone",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/solvency/check/{id}/cancel": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Cancel a pending check (or one stuck on insufficient documents) and refund credit to property or global wallet",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Cancel Solvency Check",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Check ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/solvency/check/{id}/documents/{docId}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "The owner who initiated the check downloads a document uploaded by the candidate",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Download a candidate document",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Check ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Document ID",
+                        "name": "docId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/solvency/check/{id}/guarantors": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Guarantors with their status, documents and income analysis",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "List the guarantors of a check",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Check ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/seculoc-back_internal_core_service.GuarantorDTO"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Invites a guarantor (caution) by email with their own link, where they upload their documents or\nconnect their bank. At most 2 guarantors per check.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Add a guarantor to a check",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Check ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Guarantor",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.GuarantorRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.GuarantorDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/solvency/check/{id}/guarantors/{guarantorId}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Removes a guarantor not yet bound to a lease. A processed check is re-decided without them.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Remove a guarantor",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Check ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Guarantor ID",
+                        "name": "guarantorId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/solvency/check/{id}/guarantors/{guarantorId}/documents/{docId}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "The owner who initiated the check downloads a document uploaded by a guarantor",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Download a guarantor document",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Check ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Guarantor ID",
+                        "name": "guarantorId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Document ID",
+                        "name": "docId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/solvency/check/{id}/insufficient-docs": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Move the check to 'insufficient_docs' and email the candidate the list of missing pieces.\nThe check returns to 'pending' once a document of each requested type is uploaded.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Request missing documents",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Check ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Missing pieces",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.RequestMissingDocumentsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/solvency/check/{id}/report": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Latest report of a processed check (PDF): candidate, property and rent, monthly income,\nrecurring charges, effort rate, score factors and document checklist. Each download is audited.",
+                "produces": [
+                    "application/pdf"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Download the solvency report",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Check ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/solvency/check/{id}/selection": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Marks the candidate of a completed check as shortlisted or declined (\"none\" clears the shortlist).\nA declined candidate is notified by email, without the reasons nor their score, and cannot be shortlisted again.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Shortlist or decline a candidate",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Check ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Selection",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.CandidateSelectionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/solvency/checks": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get all solvency checks for the current owner, optionally filtered by property",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "List Solvency Checks",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Property ID filter",
+                        "name": "property_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/internal_adapter_http_handler.SolvencyCheckResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/solvency/credits": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Buy a credit pack of the catalog (e.g., pack_20, see GET /plans). The credits are added once\nthe payment succeeded: 202 means it awaits 3-D Secure (see payment.next_action_url), 402 that it failed.\nWith property_id, the credits top up the wallet of that active property instead of the global wallet.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Purchase Credit Pack",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Unique key of the request: a retry with the same key replays the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Credit Pack Info",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.BuyCreditsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.BuyCreditsResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.BuyCreditsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "402": {
+                        "description": "Payment Required",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.BuyCreditsResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/solvency/dossier": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "The candidate's portable dossier: documents, income analysis, validity and shares",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Get my tenant dossier",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.TenantDossierDTO"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Builds (or replaces) the candidate's portable dossier from one of their completed solvency checks.\nIt is valid SOLVENCY_DOSSIER_VALIDITY_DAYS from the date of its bank data.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Build my tenant dossier",
+                "parameters": [
+                    {
+                        "description": "Source check",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.BuildDossierRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.TenantDossierDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "410": {
+                        "description": "Gone",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Deletes the dossier, its documents and its shares. Checks already created from it are kept.",
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Delete my tenant dossier",
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/solvency/dossier/shares": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates a revocable share token to hand to an owner",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Share my tenant dossier",
+                "parameters": [
+                    {
+                        "description": "Share",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.ShareDossierRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.DossierShareDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "410": {
+                        "description": "Gone",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/solvency/dossier/shares/{shareId}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "The token can no longer be used; checks already created with it are kept",
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Revoke a share of my tenant dossier",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Share ID",
+                        "name": "shareId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/solvency/policy": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the owner's default policy (/solvency/policy) or the policy applying to a property\n(/properties/{id}/solvency-policy): its own override, else the owner's default. scope tells which one.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Get the solvency acceptance policy",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.SolvencyPolicy"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replaces the owner's default policy or a property's policy. A candidate is approved when the\nscore reaches SOLVENCY_MIN_SCORE and every rule passes: income \u003e= income_multiplier x rent\n(charges included or not, guarantor income counted or not; 0 disables it), minimum employment\ntype, accepted guarantees.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Set the solvency acceptance policy",
+                "parameters": [
+                    {
+                        "description": "Policy",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.SolvencyPolicyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.SolvencyPolicy"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/solvency/public/check/{token}": {
+            "get": {
+                "description": "Allows candidate to see the solvency check request",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Get Solvency Check Details (Public)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Check Token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.PublicSolvencyCheckResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/solvency/public/check/{token}/callback": {
+            "post": {
+                "description": "Endpoint for mocking Open Banking data callback. Only enabled with the fake provider\n(OPEN_BANKING_PROVIDER=fake): real checks go through the consent flow.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Open Banking Callback (Public)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Check Token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Transaction Data",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.OpenBankingCallback"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/solvency/public/check/{token}/documents": {
+            "post": {
+                "description": "The candidate uploads a piece of the rental application through the check token (PDF, JPEG or PNG).\nTypes: identity, payslip, tax_notice, employment_contract. Files are encrypted at rest.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Upload a candidate document (Public)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Check Token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Document type",
+                        "name": "type",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "Document",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.CandidateDocumentDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/solvency/public/check/{token}/documents/{docId}": {
+            "delete": {
+                "description": "The candidate removes an uploaded document while the check is pending or missing documents",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Delete a candidate document (Public)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Check Token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Document ID",
+                        "name": "docId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/solvency/public/check/{token}/guarantors": {
+            "post": {
+                "description": "The candidate invites their guarantor through the check token. At most 2 guarantors per check.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Add a guarantor (Public)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Check Token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Guarantor",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.GuarantorRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/solvency/public/check/{token}/open-banking/complete": {
+            "post": {
+                "description": "Exchanges the authorization code returned by the provider and analyzes the candidate's\ntransactions. If the provider is still aggregating, the check stays pending until its webhook.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Complete the bank connection (Public)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Check Token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Authorization code",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.CompleteBankConsentRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/solvency/public/check/{token}/open-banking/consent": {
+            "post": {
+                "description": "Opens a consent session with the open banking provider. The candidate is redirected to\nconsent_url, then back to the frontend with a code to send to the complete endpoint.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Start the bank connection (Public)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Check Token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.BankConsentResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/solvency/public/check/{token}/profile": {
+            "put": {
+                "description": "Employment type and offered guarantee, evaluated against the owner's acceptance policy",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Declare the candidate's situation (Public)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Check Token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Profile",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.CandidateProfileRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/solvency/public/guarantor/{token}": {
+            "get": {
+                "description": "What the guarantor sees from their link: candidate, property, rent and uploaded documents",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Get the guarantor's request (Public)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Guarantor Token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.GuarantorPublicView"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/solvency/public/guarantor/{token}/deed": {
+            "get": {
+                "description": "Latest version of the guarantor's deed (acte de cautionnement)",
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Download the guarantee deed (Public)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Guarantor Token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/solvency/public/guarantor/{token}/documents": {
+            "post": {
+                "description": "Same document types and formats as the candidate. Files are encrypted at rest.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Upload a guarantor document (Public)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Guarantor Token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Document type",
+                        "name": "type",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "Document",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.CandidateDocumentDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/solvency/public/guarantor/{token}/documents/{docId}": {
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Delete a guarantor document (Public)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Guarantor Token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Document ID",
+                        "name": "docId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/solvency/public/guarantor/{token}/mention": {
+            "get": {
+                "description": "Once the lease is signed, the guarantor reproduces this mention (art. 22-1 of the law of\n6 July 1989) to complete the guarantee deed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Get the mention to reproduce (Public)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Guarantor Token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.GuaranteeMentionDTO"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "The text must match the mention (case, accents and punctuation ignored). A new version of\nthe deed including it is stored.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Reproduce the mention (Public)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Guarantor Token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Reproduced mention",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.GuaranteeMentionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/solvency/public/guarantor/{token}/open-banking/complete": {
+            "post": {
+                "description": "Analyzes the guarantor's income. A check already decided is re-decided with the combined score.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Complete the guarantor's bank connection (Public)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Guarantor Token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Authorization code",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.CompleteBankConsentRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/solvency/public/guarantor/{token}/open-banking/consent": {
+            "post": {
+                "description": "Same flow as the candidate's: redirect to consent_url, then send the returned code to complete.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Start the guarantor's bank connection (Public)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Guarantor Token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.BankConsentResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/solvency/public/guarantor/{token}/submit": {
+            "post": {
+                "description": "Completes the file of a guarantor who does not connect their bank: an identity document and a\nproof of income (payslip, tax notice or employment contract) must have been uploaded. The guarantor\nis then bound to the lease once signed and receives their guarantee deed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Submit the guarantor's documents (Public)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Guarantor Token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Subscribe user to a plan of the catalog (Discovery, Serenity, Premium..., see GET /plans).\nPaid plans start once their first period is paid: 202 means the payment awaits 3-D Secure\n(see payment.next_action_url) and the subscription is activated by the provider's webhook,\n402 that it failed.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Subscribe to a plan",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Unique key of the request: a retry with the same key replays the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Subscription Info",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.SubscribeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.SubscriptionResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "402": {
+                        "description": "Payment Required",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "409": {
+                        "description": "A subscription is already in progress: change its plan instead",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/cancel": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "The subscription keeps its access until the end of the period paid, then ends instead of renewing\n(see POST /subscriptions/resume); a downgrade scheduled is dropped. A past due subscription ends right away.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Cancel my subscription",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.SubscriptionState"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/change": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Moves the subscription to another plan, at the same billing frequency. A more expensive plan applies\nright away: the new plan's prorata for the days left, less the unused part of the current plan, is\ninvoiced and charged (202: awaiting 3-D Secure, applied by the provider's webhook; 402: failed, the\nplan is unchanged). A cheaper plan is scheduled at the period end. When it allows fewer long-term\nproperties than the owner has, 409 lists them: archive_property_ids names those to archive then.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Change plan",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Unique key of the request: a retry with the same key replays the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Plan Info",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.ChangePlanRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.PlanChange"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.PlanChange"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "402": {
+                        "description": "Payment Required",
+                        "schema": {
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.PlanChange"
+                        }
+                    },
+                    "409": {
+                        "description": "Properties to archive, or a subscription that cannot change",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/current": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Current subscription with its pending changes: cancellation or downgrade at the period end.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Get my subscription",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.SubscriptionState"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/history": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Timeline of the user's subscriptions, oldest first: subscribed, upgraded (with the prorata invoiced),\ndowngrade_scheduled, downgrade_cancelled, downgraded (with the properties archived),\ncancellation_scheduled, cancellation_reverted, cancelled.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Subscription history",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/seculoc-back_internal_core_service.SubscriptionEventDTO"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/resume": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Reverts a cancellation before the period end: the subscription renews again.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Resume my subscription",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.SubscriptionState"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/scheduled-change": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "The current plan renews at the period end and no property is archived.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Cancel the scheduled downgrade",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.SubscriptionState"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/slots": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Purchase additional long-term property slots (plans with a slot price only). Their prorata until\nthe period end is invoiced and charged: 202 means the payment awaits 3-D Secure and the slots are\nadded by the provider's webhook, 402 that it failed. Slots are then renewed with the plan.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Increase property limit",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Unique key of the request: a retry with the same key replays the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Limit Info",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.UpgradeLimitRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.SlotChange"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.SlotChange"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "402": {
+                        "description": "Payment Required",
+                        "schema": {
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.SlotChange"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Schedule the removal of extra long-term property slots at the period end: they stay usable and are\nnot refunded until then. Long-term properties must fit in the limit left (409 otherwise). Buying\nslots again before the period end keeps them back at no cost.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Remove property slots",
+                "parameters": [
+                    {
+                        "description": "Slots to remove",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.RemoveSlotsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.SubscriptionState"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/upgrade": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Purchase additional long-term property slots (plans with a slot price only). Their prorata until\nthe period end is invoiced and charged: 202 means the payment awaits 3-D Secure and the slots are\nadded by the provider's webhook, 402 that it failed. Slots are then renewed with the plan.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Increase property limit",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Unique key of the request: a retry with the same key replays the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Limit Info",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapter_http_handler.UpgradeLimitRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.SlotChange"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.SlotChange"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "402": {
+                        "description": "Payment Required",
+                        "schema": {
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.SlotChange"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/open-banking": {
+            "post": {
+                "description": "Notifications from the open banking provider. The signature header is verified against\nOPEN_BANKING_WEBHOOK_SECRET and each event is processed once.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "solvency"
+                ],
+                "summary": "Open banking provider webhook",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/payments": {
+            "post": {
+                "description": "Payment notifications (payment_intent.succeeded, payment_intent.payment_failed). The signature\nheader is verified against PAYMENT_WEBHOOK_SECRET and each event is processed once.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payments"
+                ],
+                "summary": "Payment provider webhook",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "internal_adapter_http_handler.AcceptInvitationRequest": {
+            "type": "object",
+            "required": [
+                "token"
+            ],
+            "properties": {
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_adapter_http_handler.BankConsentResponse": {
+            "type": "object",
+            "properties": {
+                "consent_url": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_adapter_http_handler.BuildDossierRequest": {
+            "type": "object",
+            "required": [
+                "check_id"
+            ],
+            "properties": {
+                "check_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_adapter_http_handler.BuyCreditsRequest": {
+            "type": "object",
+            "required": [
+                "pack_type"
+            ],
+            "properties": {
+                "pack_type": {
+                    "type": "string",
+                    "maxLength": 50
+                },
+                "payment_method_id": {
+                    "description": "PaymentMethodID is a payment method collected by the provider's frontend SDK; the saved\npayment method or SEPA mandate is used when empty.",
+                    "type": "string",
+                    "maxLength": 100
+                },
+                "property_id": {
+                    "description": "PropertyID tops up the wallet of that property instead of the global wallet",
+                    "type": "integer",
+                    "minimum": 1
+                }
+            }
+        },
+        "internal_adapter_http_handler.BuyCreditsResponse": {
+            "type": "object",
+            "properties": {
+                "added": {
+                    "type": "integer"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "payment": {
+                    "$ref": "#/definitions/seculoc-back_internal_core_service.PaymentResult"
+                }
+            }
+        },
+        "internal_adapter_http_handler.CandidateProfileRequest": {
+            "type": "object",
+            "required": [
+                "employment_type"
+            ],
+            "properties": {
+                "employment_type": {
+                    "type": "string",
+                    "enum": [
+                        "civil_servant",
+                        "cdi",
+                        "retired",
+                        "cdd",
+                        "self_employed",
+                        "interim",
+                        "student",
+                        "unemployed"
+                    ]
+                },
+                "guarantee_type": {
+                    "type": "string",
+                    "enum": [
+                        "visale",
+                        "garantme",
+                        "guarantor",
+                        "bank_guarantee"
+                    ]
+                }
+            }
+        },
+        "internal_adapter_http_handler.CandidateSelectionRequest": {
+            "type": "object",
+            "required": [
+                "selection"
+            ],
+            "properties": {
+                "selection": {
+                    "type": "string",
+                    "enum": [
+                        "shortlisted",
+                        "declined",
+                        "none"
+                    ]
+                }
+            }
+        },
+        "internal_adapter_http_handler.CatalogItemRequest": {
+            "type": "object",
+            "required": [
+                "code",
+                "kind",
+                "name"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "maxLength": 50
+                },
+                "included_credits": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "kind": {
+                    "type": "string",
+                    "enum": [
+                        "plan",
+                        "pack"
+                    ]
+                },
+                "max_properties": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "monthly_price_cents": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 100
+                },
+                "plan_type": {
+                    "type": "string",
+                    "enum": [
+                        "discovery",
+                        "serenity",
+                        "premium"
+                    ]
+                },
+                "price_cents": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "slot_price_cents": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "valid_from": {
+                    "description": "default: now (creation) or unchanged (update)",
+                    "type": "string"
+                },
+                "valid_until": {
+                    "type": "string"
+                },
+                "yearly_price_cents": {
+                    "type": "integer",
+                    "minimum": 0
+                }
+            }
+        },
+        "internal_adapter_http_handler.ChangePlanRequest": {
+            "type": "object",
+            "required": [
+                "plan"
+            ],
+            "properties": {
+                "archive_property_ids": {
+                    "description": "ArchivePropertyIDs are the long-term properties to archive when a downgrade leaves more than the new plan allows",
+                    "type": "array",
+                    "maxItems": 100,
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "payment_method_id": {
+                    "description": "PaymentMethodID pays the prorata of an upgrade; the saved payment method or SEPA mandate is used when empty",
+                    "type": "string",
+                    "maxLength": 100
+                },
+                "plan": {
+                    "type": "string",
+                    "maxLength": 50
+                }
+            }
+        },
+        "internal_adapter_http_handler.CheckFromDossierRequest": {
+            "type": "object",
+            "required": [
+                "property_id",
+                "share_token"
+            ],
+            "properties": {
+                "property_id": {
+                    "type": "integer"
+                },
+                "share_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_adapter_http_handler.CoTenantRequest": {
+            "type": "object",
+            "required": [
+                "email"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "first_name": {
+                    "type": "string"
+                },
+                "last_name": {
+                    "type": "string"
+                },
+                "rent_share": {
+                    "type": "number",
+                    "minimum": 0
+                },
+                "replaces_party_id": {
+                    "description": "ReplacesPartyID is the co-tenant who gave notice and is taken over by the new one",
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_adapter_http_handler.CompleteBankConsentRequest": {
+            "type": "object",
+            "required": [
+                "code"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_adapter_http_handler.CreateCheckRequest": {
+            "type": "object",
+            "required": [
+                "candidate_email",
+                "property_id"
+            ],
+            "properties": {
+                "candidate_email": {
+                    "type": "string"
+                },
+                "candidate_first_name": {
+                    "type": "string"
+                },
+                "candidate_last_name": {
+                    "type": "string"
+                },
+                "candidate_phone": {
+                    "type": "string"
+                },
+                "expires_in_days": {
+                    "description": "ExpiresInDays overrides SOLVENCY_CHECK_EXPIRY_DAYS for this check",
+                    "type": "integer",
+                    "maximum": 60,
+                    "minimum": 1
+                },
+                "property_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_adapter_http_handler.CreateDocumentLinkRequest": {
+            "type": "object",
+            "properties": {
+                "document_id": {
+                    "description": "Either a specific version (document_id) or the latest version of (type, entity_id)",
+                    "type": "integer"
+                },
+                "entity_id": {
+                    "type": "integer"
+                },
+                "expires_in_minutes": {
+                    "type": "integer",
+                    "maximum": 1440,
+                    "minimum": 1
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "lease",
+                        "solvency_report"
+                    ]
+                }
+            }
+        },
+        "internal_adapter_http_handler.CreateLeaseLinkRequest": {
+            "type": "object",
+            "properties": {
+                "expires_in_minutes": {
+                    "type": "integer",
+                    "maximum": 1440,
+                    "minimum": 1
+                }
+            }
+        },
+        "internal_adapter_http_handler.CreatePropertyRequest": {
+            "type": "object",
+            "required": [
+                "address",
+                "details",
+                "rental_type"
+            ],
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "charges_amount": {
+                    "description": "Alias",
+                    "type": "number"
+                },
+                "deposit_amount": {
+                    "type": "number"
+                },
+                "details": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "is_furnished": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "rent_amount": {
+                    "type": "number"
+                },
+                "rent_charges_amount": {
+                    "type": "number"
+                },
+                "rental_type": {
+                    "type": "string",
+                    "enum": [
+                        "long_term",
+                        "seasonal"
+                    ]
+                },
+                "seasonal_price_per_night": {
+                    "type": "number"
+                }
+            }
+        },
+        "internal_adapter_http_handler.DepartureRequest": {
+            "type": "object",
+            "required": [
+                "notice_date"
+            ],
+            "properties": {
+                "notice_date": {
+                    "type": "string",
+                    "example": "2024-03-01"
+                },
+                "reduced_notice": {
+                    "description": "ReducedNotice applies the one month notice of a tense area, a new job or a health reason",
+                    "type": "boolean"
+                }
+            }
+        },
+        "internal_adapter_http_handler.GuaranteeMentionRequest": {
+            "type": "object",
+            "required": [
+                "text"
+            ],
+            "properties": {
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_adapter_http_handler.GuarantorRequest": {
+            "type": "object",
+            "required": [
+                "email"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "first_name": {
+                    "type": "string"
+                },
+                "last_name": {
+                    "type": "string"
+                },
+                "phone": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_adapter_http_handler.InviteTenantRequest": {
+            "type": "object",
+            "required": [
+                "email",
+                "property_id"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "property_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_adapter_http_handler.LoginRequest": {
+            "type": "object",
+            "required": [
+                "email",
+                "password"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_adapter_http_handler.LoginResponse": {
+            "type": "object",
+            "properties": {
+                "capabilities": {
+                    "$ref": "#/definitions/seculoc-back_internal_core_service.Capabilities"
+                },
+                "current_context": {
+                    "$ref": "#/definitions/seculoc-back_internal_core_service.UserContext"
+                },
+                "token": {
+                    "type": "string"
+                },
+                "user": {
+                    "type": "object",
+                    "properties": {
+                        "email": {
+                            "type": "string"
+                        },
+                        "first_name": {
+                            "type": "string"
+                        },
+                        "id": {
+                            "type": "integer"
+                        },
+                        "is_verified": {
+                            "type": "boolean"
+                        },
+                        "last_name": {
+                            "type": "string"
+                        },
+                        "owner_profile": {
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.UserProfile"
+                        },
+                        "phone": {
+                            "type": "string"
+                        },
+                        "stripe_customer_id": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "internal_adapter_http_handler.OpenBankingCallback": {
+            "type": "object",
+            "properties": {
+                "transactions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/seculoc-back_internal_core_service.TransactionData"
+                    }
+                }
+            }
+        },
+        "internal_adapter_http_handler.PlansResponse": {
+            "type": "object",
+            "properties": {
+                "packs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/seculoc-back_internal_core_service.CatalogItem"
+                    }
+                },
+                "plans": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/seculoc-back_internal_core_service.CatalogItem"
+                    }
+                }
+            }
+        },
+        "internal_adapter_http_handler.PropertyResponse": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "charges_amount": {
+                    "description": "Alias",
+                    "type": "number"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deposit_amount": {
+                    "type": "number"
+                },
+                "details": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_active": {
+                    "type": "boolean"
+                },
+                "is_furnished": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "owner_id": {
+                    "type": "integer"
+                },
+                "rent_amount": {
+                    "type": "number"
+                },
+                "rent_charges_amount": {
+                    "type": "number"
+                },
+                "rental_type": {
+                    "type": "string"
+                },
+                "seasonal_price_per_night": {
+                    "type": "number"
+                },
+                "vacancy_credits": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_adapter_http_handler.PublicSolvencyCheckResponse": {
+            "type": "object",
+            "properties": {
+                "candidate_email": {
+                    "type": "string"
+                },
+                "candidate_first_name": {
+                    "type": "string"
+                },
+                "candidate_last_name": {
+                    "type": "string"
+                },
+                "documents": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/seculoc-back_internal_core_service.CandidateDocumentDTO"
+                    }
+                },
+                "employment_type": {
+                    "type": "string"
+                },
+                "guarantee_type": {
+                    "type": "string"
+                },
+                "missing_documents": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/seculoc-back_internal_core_service.MissingDocument"
+                    }
+                },
+                "property_address": {
+                    "type": "string"
+                },
+                "property_id": {
+                    "type": "integer"
+                },
+                "property_name": {
+                    "type": "string"
+                },
+                "rent_amount": {
+                    "type": "number"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_adapter_http_handler.RefundRequest": {
+            "type": "object",
+            "properties": {
+                "amount_cents": {
+                    "description": "AmountCents to refund, the whole remaining amount when omitted",
+                    "type": "integer",
+                    "minimum": 0
+                }
+            }
+        },
+        "internal_adapter_http_handler.RegisterRequest": {
+            "type": "object",
+            "required": [
+                "email",
+                "first_name",
+                "last_name",
+                "password",
+                "phone"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "first_name": {
+                    "type": "string"
+                },
+                "invite_token": {
+                    "description": "Optional",
+                    "type": "string"
+                },
+                "last_name": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string",
+                    "minLength": 8
+                },
+                "phone": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_adapter_http_handler.RemoveSlotsRequest": {
+            "type": "object",
+            "required": [
+                "slots"
+            ],
+            "properties": {
+                "slots": {
+                    "type": "integer",
+                    "maximum": 100,
+                    "minimum": 1
+                }
+            }
+        },
+        "internal_adapter_http_handler.RentSplitRequest": {
+            "type": "object",
+            "properties": {
+                "individual_rent_shares": {
+                    "type": "boolean"
+                },
+                "joint_liability": {
+                    "type": "boolean"
+                },
+                "shares": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/seculoc-back_internal_core_service.PartyShare"
+                    }
+                }
+            }
+        },
+        "internal_adapter_http_handler.ReorderPhotosRequest": {
+            "type": "object",
+            "required": [
+                "media_ids"
+            ],
+            "properties": {
+                "media_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "internal_adapter_http_handler.RequestMissingDocumentsRequest": {
+            "type": "object",
+            "required": [
+                "missing"
+            ],
+            "properties": {
+                "missing": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/seculoc-back_internal_core_service.MissingDocument"
+                    }
+                }
+            }
+        },
+        "internal_adapter_http_handler.SepaMandateRequest": {
+            "type": "object",
+            "required": [
+                "account_holder",
+                "iban"
+            ],
+            "properties": {
+                "account_holder": {
+                    "type": "string",
+                    "maxLength": 100
+                },
+                "iban": {
+                    "type": "string",
+                    "maxLength": 40,
+                    "minLength": 15
+                }
+            }
+        },
+        "internal_adapter_http_handler.ShareDossierRequest": {
+            "type": "object",
+            "properties": {
+                "label": {
+                    "type": "string",
+                    "maxLength": 255
+                }
+            }
+        },
+        "internal_adapter_http_handler.SolvencyCheckResponse": {
+            "type": "object",
+            "properties": {
+                "analysis": {
+                    "$ref": "#/definitions/seculoc-back_internal_core_service.IncomeAnalysis"
+                },
+                "candidate_email": {
+                    "type": "string"
+                },
+                "candidate_first_name": {
+                    "type": "string"
+                },
+                "candidate_last_name": {
+                    "type": "string"
+                },
+                "combined_score": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "documents": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/seculoc-back_internal_core_service.CandidateDocumentDTO"
+                    }
+                },
+                "employment_type": {
+                    "type": "string"
+                },
+                "guarantee_type": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "missing_documents": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/seculoc-back_internal_core_service.MissingDocument"
+                    }
+                },
+                "policy_results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/seculoc-back_internal_core_service.PolicyRuleResult"
+                    }
+                },
+                "property_address": {
+                    "type": "string"
+                },
+                "property_id": {
+                    "type": "integer"
+                },
+                "report_url": {
+                    "type": "string"
+                },
+                "score_result": {
+                    "type": "integer"
+                },
+                "selection": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                },
+                "verification_url": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_adapter_http_handler.SolvencyPolicyRequest": {
+            "type": "object",
+            "properties": {
+                "accepted_guarantees": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "count_guarantor_income": {
+                    "type": "boolean"
+                },
+                "include_charges": {
+                    "type": "boolean"
+                },
+                "income_multiplier": {
+                    "type": "number",
+                    "maximum": 10
+                },
+                "min_employment_type": {
+                    "type": "string",
+                    "enum": [
+                        "civil_servant",
+                        "cdi",
+                        "retired",
+                        "cdd",
+                        "self_employed",
+                        "interim",
+                        "student",
+                        "unemployed"
+                    ]
+                },
+                "require_guarantee": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "internal_adapter_http_handler.SubscribeRequest": {
+            "type": "object",
+            "required": [
+                "frequency",
+                "plan"
+            ],
+            "properties": {
+                "frequency": {
+                    "type": "string",
+                    "enum": [
+                        "monthly",
+                        "yearly"
+                    ]
+                },
+                "payment_method_id": {
+                    "description": "PaymentMethodID pays the first period of paid plans; the saved payment method or SEPA mandate is used when empty",
+                    "type": "string",
+                    "maxLength": 100
+                },
+                "plan": {
+                    "type": "string",
+                    "maxLength": 50
+                }
+            }
+        },
+        "internal_adapter_http_handler.SubscriptionResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "object",
+                    "properties": {
+                        "user": {
+                            "type": "object",
+                            "properties": {
+                                "email": {
+                                    "type": "string"
+                                },
+                                "first_name": {
+                                    "type": "string"
+                                },
+                                "id": {
+                                    "type": "integer"
+                                },
+                                "is_verified": {
+                                    "type": "boolean"
+                                },
+                                "last_name": {
+                                    "type": "string"
+                                },
+                                "owner_profile": {
+                                    "$ref": "#/definitions/seculoc-back_internal_core_service.UserProfile"
+                                },
+                                "phone": {
+                                    "type": "string"
+                                },
+                                "stripe_customer_id": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                },
+                "payment": {
+                    "description": "Payment of the first period, for paid plans",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.PaymentResult"
+                        }
+                    ]
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_adapter_http_handler.SwitchContextRequest": {
+            "type": "object",
+            "required": [
+                "target_context"
+            ],
+            "properties": {
+                "target_context": {
+                    "type": "string",
+                    "enum": [
+                        "owner",
+                        "tenant"
+                    ]
+                }
+            }
+        },
+        "internal_adapter_http_handler.TransferCreditsRequest": {
+            "type": "object",
+            "required": [
+                "amount",
+                "from_account_id",
+                "to_account_id"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "from_account_id": {
+                    "type": "integer"
+                },
+                "to_account_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_adapter_http_handler.UpdatePropertyRequest": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "charges_amount": {
+                    "description": "Alias",
+                    "type": "number"
+                },
+                "deposit_amount": {
+                    "type": "number"
+                },
+                "details": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "is_furnished": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "rent_amount": {
+                    "type": "number"
+                },
+                "rent_charges_amount": {
+                    "type": "number"
+                },
+                "rental_type": {
+                    "type": "string",
+                    "enum": [
+                        "long_term",
+                        "seasonal"
+                    ]
+                },
+                "seasonal_price_per_night": {
+                    "type": "number"
+                }
+            }
+        },
+        "internal_adapter_http_handler.UpgradeLimitRequest": {
+            "type": "object",
+            "required": [
+                "additional_slots"
+            ],
+            "properties": {
+                "additional_slots": {
+                    "type": "integer",
+                    "maximum": 100,
+                    "minimum": 1
+                },
+                "payment_method_id": {
+                    "description": "PaymentMethodID pays the prorata of the extra properties; the saved payment method or SEPA mandate is used when empty",
+                    "type": "string",
+                    "maxLength": 100
+                }
+            }
+        },
+        "seculoc-back_internal_core_service.CandidateComparison": {
+            "type": "object",
+            "properties": {
+                "awaiting_candidates": {
+                    "description": "AwaitingCandidates counts the checks still waiting for their candidate, not compared yet",
+                    "type": "integer"
+                },
+                "candidates": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/seculoc-back_internal_core_service.RankedCandidate"
+                    }
+                },
+                "property_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "seculoc-back_internal_core_service.CandidateDocumentDTO": {
+            "type": "object",
+            "properties": {
+                "content_type": {
+                    "type": "string"
+                },
+                "filename": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "size_bytes": {
+                    "type": "integer"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "uploaded_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "seculoc-back_internal_core_service.CandidateSignal": {
+            "type": "object",
+            "properties": {
+                "best": {
+                    "type": "boolean"
+                },
+                "code": {
+                    "type": "string"
+                },
+                "display": {
+                    "type": "string"
+                },
+                "gap_to_best": {
+                    "type": "number"
+                },
+                "label": {
+                    "type": "string"
+                },
+                "max_points": {
+                    "type": "number"
+                },
+                "points": {
+                    "type": "number"
+                },
+                "value": {
+                    "type": "number"
+                }
+            }
+        },
+        "seculoc-back_internal_core_service.Capabilities": {
+            "type": "object",
+            "properties": {
+                "can_act_as_owner": {
+                    "type": "boolean"
+                },
+                "can_act_as_tenant": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "seculoc-back_internal_core_service.CatalogItem": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "included_credits": {
+                    "description": "plans: per month; packs: in the pack",
+                    "type": "integer"
+                },
+                "kind": {
+                    "description": "plan or pack",
+                    "type": "string"
+                },
+                "max_properties": {
+                    "type": "integer"
+                },
+                "monthly_price_cents": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "plan_type": {
+                    "description": "plans: discovery, serenity or premium",
+                    "type": "string"
+                },
+                "price_cents": {
+                    "description": "packs",
+                    "type": "integer"
+                },
+                "slot_price_cents": {
+                    "description": "plans: monthly price of an extra property, nil if none can be added",
+                    "type": "integer"
+                },
+                "valid_from": {
+                    "type": "string"
+                },
+                "valid_until": {
+                    "type": "string"
+                },
+                "yearly_price_cents": {
+                    "type": "integer"
+                }
+            }
+        },
+        "seculoc-back_internal_core_service.CreditAccountDTO": {
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_active": {
+                    "description": "IsActive is false for the wallet of an archived property: it can only be emptied",
+                    "type": "boolean"
+                },
+                "property_address": {
+                    "type": "string"
+                },
+                "property_id": {
+                    "type": "integer"
+                },
+                "property_name": {
+                    "type": "string"
+                },
+                "wallet": {
+                    "type": "string"
+                }
+            }
+        },
+        "seculoc-back_internal_core_service.CreditTransactionDTO": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "balance_after": {
+                    "description": "BalanceAfter is the balance of the wallet moved (global or property) after the entry",
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "property_id": {
+                    "type": "integer"
+                },
+                "property_name": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "wallet": {
+                    "type": "string"
+                }
+            }
+        },
+        "seculoc-back_internal_core_service.CreditTransactionPage": {
+            "type": "object",
+            "properties": {
+                "page": {
+                    "type": "integer"
+                },
+                "page_size": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "transactions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/seculoc-back_internal_core_service.CreditTransactionDTO"
+                    }
+                }
+            }
+        },
+        "seculoc-back_internal_core_service.DocumentDTO": {
+            "type": "object",
+            "properties": {
+                "content_type": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "entity_id": {
+                    "type": "integer"
+                },
+                "filename": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "seculoc-back_internal_core_service.DocumentLinkDTO": {
+            "type": "object",
+            "properties": {
+                "document_id": {
+                    "type": "integer"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "link_id": {
+                    "type": "integer"
+                },
+                "url": {
+                    "type": "string"
                 },
-                "candidate_first_name": {
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "seculoc-back_internal_core_service.DossierShareDTO": {
+            "type": "object",
+            "properties": {
+                "created_at": {
                     "type": "string"
                 },
-                "candidate_last_name": {
+                "id": {
+                    "type": "integer"
+                },
+                "label": {
                     "type": "string"
                 },
-                "candidate_phone": {
+                "revoked": {
+                    "type": "boolean"
+                },
+                "share_url": {
                     "type": "string"
                 },
-                "property_id": {
-                    "type": "integer"
+                "token": {
+                    "type": "string"
                 }
             }
         },
-        "internal_adapter_http_handler.CreatePropertyRequest": {
+        "seculoc-back_internal_core_service.DraftLeaseRequest": {
             "type": "object",
             "required": [
-                "address",
-                "details",
-                "rental_type"
+                "property_id",
+                "tenant_info",
+                "terms"
             ],
             "properties": {
-                "address": {
+                "clauses": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "property_id": {
+                    "type": "integer"
+                },
+                "tenant_info": {
+                    "$ref": "#/definitions/seculoc-back_internal_core_service.TenantDraft"
+                },
+                "terms": {
+                    "$ref": "#/definitions/seculoc-back_internal_core_service.LeaseTerms"
+                }
+            }
+        },
+        "seculoc-back_internal_core_service.GuaranteeMentionDTO": {
+            "type": "object",
+            "properties": {
+                "signed": {
+                    "type": "boolean"
+                },
+                "signed_at": {
                     "type": "string"
                 },
-                "deposit_amount": {
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "seculoc-back_internal_core_service.GuarantorDTO": {
+            "type": "object",
+            "properties": {
+                "analysis": {
+                    "$ref": "#/definitions/seculoc-back_internal_core_service.IncomeAnalysis"
+                },
+                "documents": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/seculoc-back_internal_core_service.CandidateDocumentDTO"
+                    }
+                },
+                "email": {
+                    "type": "string"
+                },
+                "first_name": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_name": {
+                    "type": "string"
+                },
+                "lease_id": {
+                    "type": "integer"
+                },
+                "mention_signed": {
+                    "type": "boolean"
+                },
+                "monthly_income": {
                     "type": "number"
                 },
-                "details": {
-                    "type": "object",
-                    "additionalProperties": true
+                "status": {
+                    "type": "string"
                 },
-                "is_furnished": {
+                "verification_url": {
+                    "type": "string"
+                }
+            }
+        },
+        "seculoc-back_internal_core_service.GuarantorPublicView": {
+            "type": "object",
+            "properties": {
+                "candidate_name": {
+                    "type": "string"
+                },
+                "deed_available": {
+                    "description": "DeedAvailable is set once the lease is signed: the guarantor must then reproduce the mentions",
                     "type": "boolean"
                 },
-                "name": {
+                "documents": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/seculoc-back_internal_core_service.CandidateDocumentDTO"
+                    }
+                },
+                "email": {
+                    "type": "string"
+                },
+                "first_name": {
+                    "type": "string"
+                },
+                "last_name": {
+                    "type": "string"
+                },
+                "mention_signed": {
+                    "type": "boolean"
+                },
+                "property_address": {
                     "type": "string"
                 },
                 "rent_amount": {
                     "type": "number"
                 },
-                "rent_charges_amount": {
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "seculoc-back_internal_core_service.IncomeAnalysis": {
+            "type": "object",
+            "properties": {
+                "current_rent": {
                     "type": "number"
                 },
-                "rental_type": {
-                    "type": "string",
-                    "enum": [
-                        "long_term",
-                        "seasonal"
-                    ]
+                "debt_ratio": {
+                    "description": "(rent + loans) / monthly income",
+                    "type": "number"
                 },
-                "seasonal_price_per_night": {
+                "effort_rate": {
+                    "description": "rent / monthly income",
+                    "type": "number"
+                },
+                "excluded_refunds": {
+                    "type": "integer"
+                },
+                "excluded_transfers": {
+                    "type": "integer"
+                },
+                "factors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/seculoc-back_internal_core_service.ScoreFactor"
+                    }
+                },
+                "ignored_transactions": {
+                    "type": "integer"
+                },
+                "income_by_month": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/seculoc-back_internal_core_service.MonthlyIncome"
+                    }
+                },
+                "income_sources": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/seculoc-back_internal_core_service.RecurringFlow"
+                    }
+                },
+                "monthly_income": {
+                    "type": "number"
+                },
+                "monthly_loan_repayments": {
+                    "type": "number"
+                },
+                "monthly_other_income": {
+                    "type": "number"
+                },
+                "monthly_other_recurring_debits": {
+                    "type": "number"
+                },
+                "monthly_recurring_income": {
+                    "type": "number"
+                },
+                "months_covered": {
+                    "type": "number"
+                },
+                "period_end": {
+                    "type": "string"
+                },
+                "period_start": {
+                    "type": "string"
+                },
+                "recurring_debits": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/seculoc-back_internal_core_service.RecurringFlow"
+                    }
+                },
+                "rent_amount": {
                     "type": "number"
+                },
+                "score": {
+                    "type": "integer"
                 }
             }
         },
-        "internal_adapter_http_handler.InviteTenantRequest": {
+        "seculoc-back_internal_core_service.InvitationDetailsDTO": {
             "type": "object",
-            "required": [
-                "email",
-                "property_id"
-            ],
             "properties": {
+                "charges_amount": {
+                    "type": "number"
+                },
+                "deposit_amount": {
+                    "type": "number"
+                },
                 "email": {
                     "type": "string"
                 },
+                "owner_name": {
+                    "type": "string"
+                },
+                "property_address": {
+                    "type": "string"
+                },
+                "rent_amount": {
+                    "type": "number"
+                }
+            }
+        },
+        "seculoc-back_internal_core_service.InvoiceDTO": {
+            "type": "object",
+            "properties": {
+                "amount_cents": {
+                    "type": "integer"
+                },
+                "amount_excl_vat_cents": {
+                    "type": "integer"
+                },
+                "credited_invoice_id": {
+                    "type": "integer"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "document_id": {
+                    "description": "DocumentID is the archived PDF, once rendered",
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "issued_at": {
+                    "type": "string"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "number": {
+                    "type": "string"
+                },
+                "period_end": {
+                    "type": "string"
+                },
+                "period_start": {
+                    "type": "string"
+                },
+                "vat_cents": {
+                    "type": "integer"
+                },
+                "vat_rate_bps": {
+                    "type": "integer"
+                }
+            }
+        },
+        "seculoc-back_internal_core_service.LeaseDTO": {
+            "type": "object",
+            "properties": {
+                "charges_amount": {
+                    "description": "Added",
+                    "type": "number"
+                },
+                "contract_url": {
+                    "type": "string"
+                },
+                "deposit_amount": {
+                    "type": "number"
+                },
+                "end_date": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "property_address": {
+                    "type": "string"
+                },
                 "property_id": {
                     "type": "integer"
+                },
+                "rent_amount": {
+                    "type": "number"
+                },
+                "rental_type": {
+                    "type": "string"
+                },
+                "start_date": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
                 }
             }
         },
-        "internal_adapter_http_handler.LoginRequest": {
+        "seculoc-back_internal_core_service.LeaseGuarantorDTO": {
             "type": "object",
-            "required": [
-                "email",
-                "password"
-            ],
             "properties": {
+                "deed": {
+                    "$ref": "#/definitions/seculoc-back_internal_core_service.DocumentDTO"
+                },
                 "email": {
                     "type": "string"
                 },
-                "password": {
+                "guarantor_id": {
+                    "type": "integer"
+                },
+                "mention_signed": {
+                    "type": "boolean"
+                },
+                "name": {
                     "type": "string"
                 }
             }
         },
-        "internal_adapter_http_handler.LoginResponse": {
+        "seculoc-back_internal_core_service.LeasePartiesDTO": {
             "type": "object",
             "properties": {
-                "capabilities": {
-                    "$ref": "#/definitions/seculoc-back_internal_core_service.Capabilities"
+                "individual_rent_shares": {
+                    "type": "boolean"
                 },
-                "current_context": {
-                    "$ref": "#/definitions/seculoc-back_internal_core_service.UserContext"
+                "joint_liability": {
+                    "type": "boolean"
                 },
-                "token": {
-                    "type": "string"
+                "lease_id": {
+                    "type": "integer"
                 },
-                "user": {
-                    "type": "object",
-                    "properties": {
-                        "email": {
-                            "type": "string"
-                        },
-                        "first_name": {
-                            "type": "string"
-                        },
-                        "id": {
-                            "type": "integer"
-                        },
-                        "is_verified": {
-                            "type": "boolean"
-                        },
-                        "last_name": {
-                            "type": "string"
-                        },
-                        "owner_profile": {
-                            "$ref": "#/definitions/seculoc-back_internal_core_service.UserProfile"
-                        },
-                        "phone": {
-                            "type": "string"
-                        },
-                        "stripe_customer_id": {
-                            "type": "string"
-                        }
-                    }
-                }
-            }
-        },
-        "internal_adapter_http_handler.OpenBankingCallback": {
-            "type": "object",
-            "properties": {
-                "transactions": {
+                "monthly_total": {
+                    "type": "number"
+                },
+                "parties": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/seculoc-back_internal_core_service.TransactionData"
+                        "$ref": "#/definitions/seculoc-back_internal_core_service.LeasePartyDTO"
                     }
                 }
             }
         },
-        "internal_adapter_http_handler.PropertyResponse": {
+        "seculoc-back_internal_core_service.LeasePartyDTO": {
             "type": "object",
             "properties": {
-                "address": {
+                "email": {
                     "type": "string"
                 },
-                "created_at": {
+                "first_name": {
                     "type": "string"
                 },
-                "deposit_amount": {
-                    "type": "number"
-                },
-                "details": {
-                    "type": "object",
-                    "additionalProperties": true
-                },
                 "id": {
                     "type": "integer"
                 },
-                "is_active": {
-                    "type": "boolean"
+                "joined_at": {
+                    "type": "string"
                 },
-                "is_furnished": {
-                    "type": "boolean"
+                "last_name": {
+                    "type": "string"
                 },
-                "name": {
+                "left_at": {
                     "type": "string"
                 },
-                "owner_id": {
-                    "type": "integer"
+                "notice_date": {
+                    "type": "string"
                 },
-                "rent_amount": {
+                "rent_share": {
                     "type": "number"
                 },
-                "rent_charges_amount": {
-                    "type": "number"
+                "replaces_party_id": {
+                    "type": "integer"
                 },
-                "rental_type": {
+                "solidarity_ends_at": {
                     "type": "string"
                 },
-                "seasonal_price_per_night": {
-                    "type": "number"
-                },
-                "vacancy_credits": {
-                    "type": "integer"
+                "status": {
+                    "type": "string"
                 }
             }
         },
-        "internal_adapter_http_handler.PublicSolvencyCheckResponse": {
+        "seculoc-back_internal_core_service.LeaseTerms": {
             "type": "object",
+            "required": [
+                "deposit_amount",
+                "payment_day",
+                "rent_amount",
+                "start_date"
+            ],
             "properties": {
-                "candidate_email": {
-                    "type": "string"
+                "charges_amount": {
+                    "type": "number"
                 },
-                "candidate_first_name": {
-                    "type": "string"
+                "deposit_amount": {
+                    "type": "number"
                 },
-                "candidate_last_name": {
+                "end_date": {
+                    "description": "YYYY-MM-DD (Optional)",
                     "type": "string"
                 },
-                "property_address": {
-                    "type": "string"
+                "individual_rent_shares": {
+                    "type": "boolean"
                 },
-                "property_id": {
-                    "type": "integer"
+                "joint_liability": {
+                    "description": "Co-tenancy: joint liability clause (default true) and one payment per co-tenant",
+                    "type": "boolean"
                 },
-                "property_name": {
-                    "type": "string"
+                "payment_day": {
+                    "type": "integer",
+                    "maximum": 31,
+                    "minimum": 1
                 },
                 "rent_amount": {
                     "type": "number"
                 },
-                "status": {
+                "start_date": {
+                    "description": "YYYY-MM-DD",
                     "type": "string"
                 }
             }
         },
-        "internal_adapter_http_handler.RegisterRequest": {
+        "seculoc-back_internal_core_service.MissingDocument": {
             "type": "object",
             "required": [
-                "email",
-                "first_name",
-                "last_name",
-                "password",
-                "phone"
+                "type"
             ],
             "properties": {
-                "email": {
-                    "type": "string"
+                "comment": {
+                    "type": "string",
+                    "maxLength": 500
                 },
-                "first_name": {
+                "type": {
                     "type": "string"
-                },
-                "invite_token": {
-                    "description": "Optional",
+                }
+            }
+        },
+        "seculoc-back_internal_core_service.MonthlyIncome": {
+            "type": "object",
+            "properties": {
+                "month": {
+                    "description": "YYYY-MM",
                     "type": "string"
                 },
-                "last_name": {
-                    "type": "string"
+                "other": {
+                    "type": "number"
                 },
-                "password": {
-                    "type": "string",
-                    "minLength": 8
+                "recurring": {
+                    "type": "number"
+                }
+            }
+        },
+        "seculoc-back_internal_core_service.PartyShare": {
+            "type": "object",
+            "required": [
+                "party_id"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "number"
                 },
-                "phone": {
-                    "type": "string"
+                "party_id": {
+                    "type": "integer"
                 }
             }
         },
-        "internal_adapter_http_handler.SolvencyCheckResponse": {
+        "seculoc-back_internal_core_service.PaymentResult": {
             "type": "object",
             "properties": {
-                "candidate_email": {
+                "amount_cents": {
+                    "type": "integer"
+                },
+                "client_secret": {
                     "type": "string"
                 },
-                "candidate_first_name": {
+                "failure_reason": {
                     "type": "string"
                 },
-                "candidate_last_name": {
+                "next_action_url": {
                     "type": "string"
                 },
-                "created_at": {
+                "status": {
                     "type": "string"
                 },
-                "id": {
+                "transaction_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "seculoc-back_internal_core_service.PlanChange": {
+            "type": "object",
+            "properties": {
+                "amount_due_cents": {
                     "type": "integer"
                 },
-                "property_address": {
-                    "type": "string"
+                "archived_property_ids": {
+                    "description": "ArchivedPropertyIDs are the long-term properties archived when a downgrade takes effect",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
                 },
-                "property_id": {
+                "charge_cents": {
                     "type": "integer"
                 },
-                "report_url": {
+                "credit_cents": {
+                    "description": "CreditCents is the unused part of the current plan, deducted from ChargeCents, the price of the\nnew plan for the rest of the period",
+                    "type": "integer"
+                },
+                "effective_date": {
                     "type": "string"
                 },
-                "score_result": {
-                    "type": "integer"
+                "payment": {
+                    "$ref": "#/definitions/seculoc-back_internal_core_service.PaymentResult"
+                },
+                "plan": {
+                    "type": "string"
                 },
                 "status": {
                     "type": "string"
+                }
+            }
+        },
+        "seculoc-back_internal_core_service.PolicyRuleResult": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
                 },
-                "token": {
+                "detail": {
                     "type": "string"
                 },
-                "verification_url": {
+                "label": {
                     "type": "string"
+                },
+                "passed": {
+                    "type": "boolean"
                 }
             }
         },
-        "internal_adapter_http_handler.SubscriptionResponse": {
+        "seculoc-back_internal_core_service.PropertyMediaDTO": {
             "type": "object",
             "properties": {
-                "data": {
-                    "type": "object",
-                    "properties": {
-                        "user": {
-                            "type": "object",
-                            "properties": {
-                                "email": {
-                                    "type": "string"
-                                },
-                                "first_name": {
-                                    "type": "string"
-                                },
-                                "id": {
-                                    "type": "integer"
-                                },
-                                "is_verified": {
-                                    "type": "boolean"
-                                },
-                                "last_name": {
-                                    "type": "string"
-                                },
-                                "owner_profile": {
-                                    "$ref": "#/definitions/seculoc-back_internal_core_service.UserProfile"
-                                },
-                                "phone": {
-                                    "type": "string"
-                                },
-                                "stripe_customer_id": {
-                                    "type": "string"
-                                }
-                            }
-                        }
-                    }
+                "content_type": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "diagnostic_type": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "filename": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_cover": {
+                    "type": "boolean"
+                },
+                "is_expired": {
+                    "type": "boolean"
                 },
-                "status": {
+                "kind": {
+                    "type": "string"
+                },
+                "position": {
+                    "type": "integer"
+                },
+                "size_bytes": {
+                    "type": "integer"
+                },
+                "thumbnail_url": {
+                    "type": "string"
+                },
+                "url": {
                     "type": "string"
                 }
             }
         },
-        "internal_adapter_http_handler.SwitchContextRequest": {
+        "seculoc-back_internal_core_service.PropertySlots": {
             "type": "object",
-            "required": [
-                "target_context"
-            ],
             "properties": {
-                "target_context": {
-                    "type": "string",
-                    "enum": [
-                        "owner",
-                        "tenant"
-                    ]
+                "quantity": {
+                    "type": "integer"
+                },
+                "quantity_at_renewal": {
+                    "description": "QuantityAtRenewal is set when a removal is scheduled at the period end",
+                    "type": "integer"
+                },
+                "unit_price_cents": {
+                    "description": "UnitPriceCents is the monthly price of one extra property",
+                    "type": "integer"
                 }
             }
         },
-        "internal_adapter_http_handler.UpdatePropertyRequest": {
+        "seculoc-back_internal_core_service.RankedCandidate": {
             "type": "object",
             "properties": {
-                "address": {
+                "candidate_email": {
                     "type": "string"
                 },
-                "deposit_amount": {
-                    "type": "number"
+                "candidate_name": {
+                    "type": "string"
                 },
-                "details": {
-                    "type": "object",
-                    "additionalProperties": true
+                "check_id": {
+                    "type": "integer"
                 },
-                "is_furnished": {
-                    "type": "boolean"
+                "highlights": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 },
-                "name": {
+                "rank": {
+                    "type": "integer"
+                },
+                "ranking_score": {
+                    "type": "integer"
+                },
+                "selection": {
                     "type": "string"
                 },
-                "rent_amount": {
-                    "type": "number"
+                "signals": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/seculoc-back_internal_core_service.CandidateSignal"
+                    }
                 },
-                "rent_charges_amount": {
-                    "type": "number"
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "seculoc-back_internal_core_service.RecurringFlow": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "string"
                 },
-                "rental_type": {
-                    "type": "string",
-                    "enum": [
-                        "long_term",
-                        "seasonal"
-                    ]
+                "counterparty": {
+                    "type": "string"
                 },
-                "seasonal_price_per_night": {
+                "interval_days": {
+                    "type": "integer"
+                },
+                "monthly_amount": {
                     "type": "number"
+                },
+                "occurrences": {
+                    "type": "integer"
                 }
             }
         },
-        "seculoc-back_internal_core_service.Capabilities": {
+        "seculoc-back_internal_core_service.RentScheduleLine": {
             "type": "object",
             "properties": {
-                "can_act_as_owner": {
-                    "type": "boolean"
+                "amount": {
+                    "type": "number"
                 },
-                "can_act_as_tenant": {
-                    "type": "boolean"
+                "party_id": {
+                    "type": "integer"
+                },
+                "tenant": {
+                    "type": "string"
                 }
             }
         },
-        "seculoc-back_internal_core_service.DraftLeaseRequest": {
+        "seculoc-back_internal_core_service.RentScheduleMonth": {
             "type": "object",
-            "required": [
-                "property_id",
-                "tenant_info",
-                "terms"
-            ],
             "properties": {
-                "clauses": {
+                "due_date": {
+                    "type": "string"
+                },
+                "lines": {
                     "type": "array",
                     "items": {
-                        "type": "string"
+                        "$ref": "#/definitions/seculoc-back_internal_core_service.RentScheduleLine"
                     }
                 },
-                "property_id": {
-                    "type": "integer"
-                },
-                "tenant_info": {
-                    "$ref": "#/definitions/seculoc-back_internal_core_service.TenantDraft"
+                "solidary_parties": {
+                    "description": "SolidaryParties left the flat but still answer for the whole rent (six-month rule).",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 },
-                "terms": {
-                    "$ref": "#/definitions/seculoc-back_internal_core_service.LeaseTerms"
+                "total": {
+                    "type": "number"
                 }
             }
         },
-        "seculoc-back_internal_core_service.InvitationDetailsDTO": {
+        "seculoc-back_internal_core_service.ScoreFactor": {
             "type": "object",
             "properties": {
-                "deposit_amount": {
-                    "type": "number"
-                },
-                "email": {
+                "code": {
                     "type": "string"
                 },
-                "owner_name": {
+                "detail": {
                     "type": "string"
                 },
-                "property_address": {
+                "label": {
                     "type": "string"
                 },
-                "rent_amount": {
-                    "type": "number"
+                "max_points": {
+                    "type": "integer"
+                },
+                "points": {
+                    "type": "integer"
                 }
             }
         },
-        "seculoc-back_internal_core_service.LeaseDTO": {
+        "seculoc-back_internal_core_service.SlotChange": {
             "type": "object",
             "properties": {
-                "charges_amount": {
-                    "description": "Added",
-                    "type": "number"
+                "amount_due_cents": {
+                    "type": "integer"
                 },
-                "contract_url": {
+                "effective_date": {
                     "type": "string"
                 },
-                "deposit_amount": {
-                    "type": "number"
+                "max_properties_limit": {
+                    "type": "integer"
                 },
-                "end_date": {
-                    "type": "string"
+                "payment": {
+                    "$ref": "#/definitions/seculoc-back_internal_core_service.PaymentResult"
                 },
-                "id": {
+                "slots": {
                     "type": "integer"
                 },
-                "property_address": {
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "seculoc-back_internal_core_service.SolvencyPolicy": {
+            "type": "object",
+            "properties": {
+                "accepted_guarantees": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "count_guarantor_income": {
+                    "type": "boolean"
+                },
+                "include_charges": {
+                    "type": "boolean"
+                },
+                "income_multiplier": {
+                    "description": "0 only for the default policy, which has no such rule",
+                    "type": "number"
+                },
+                "min_employment_type": {
                     "type": "string"
                 },
                 "property_id": {
                     "type": "integer"
                 },
-                "rent_amount": {
-                    "type": "number"
+                "require_guarantee": {
+                    "type": "boolean"
                 },
-                "rental_type": {
+                "scope": {
+                    "description": "default, owner or property",
+                    "type": "string"
+                }
+            }
+        },
+        "seculoc-back_internal_core_service.SubscriptionDTO": {
+            "type": "object",
+            "properties": {
+                "cancel_at_period_end": {
+                    "description": "CancelAtPeriodEnd: the subscription ends at CurrentPeriodEnd instead of renewing",
+                    "type": "boolean"
+                },
+                "current_period_end": {
+                    "type": "string"
+                },
+                "end_date": {
+                    "type": "string"
+                },
+                "frequency": {
                     "type": "string"
                 },
+                "max_properties_limit": {
+                    "type": "integer"
+                },
+                "plan_type": {
+                    "type": "string"
+                },
+                "property_slots": {
+                    "description": "PropertySlots are the long-term properties bought on top of the plan, counted in MaxPropertiesLimit",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.PropertySlots"
+                        }
+                    ]
+                },
                 "start_date": {
                     "type": "string"
                 },
@@ -1652,43 +7819,53 @@ const docTemplate = `{
                 }
             }
         },
-        "seculoc-back_internal_core_service.LeaseTerms": {
+        "seculoc-back_internal_core_service.SubscriptionEventDTO": {
             "type": "object",
-            "required": [
-                "deposit_amount",
-                "payment_day",
-                "rent_amount",
-                "start_date"
-            ],
             "properties": {
-                "charges_amount": {
-                    "type": "number"
+                "amount_cents": {
+                    "type": "integer"
                 },
-                "deposit_amount": {
-                    "type": "number"
+                "archived_property_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
                 },
-                "end_date": {
-                    "description": "YYYY-MM-DD (Optional)",
+                "effective_date": {
                     "type": "string"
                 },
-                "payment_day": {
-                    "type": "integer",
-                    "maximum": 31,
-                    "minimum": 1
+                "from_plan": {
+                    "type": "string"
                 },
-                "rent_amount": {
-                    "type": "number"
+                "invoice_id": {
+                    "type": "integer"
                 },
-                "start_date": {
-                    "description": "YYYY-MM-DD",
+                "recorded_at": {
+                    "type": "string"
+                },
+                "slot_quantity": {
+                    "description": "SlotQuantity is the number of extra properties added or removed",
+                    "type": "integer"
+                },
+                "to_plan": {
+                    "type": "string"
+                },
+                "type": {
                     "type": "string"
                 }
             }
         },
-        "seculoc-back_internal_core_service.SubscriptionDTO": {
+        "seculoc-back_internal_core_service.SubscriptionState": {
             "type": "object",
             "properties": {
-                "end_date": {
+                "cancel_at_period_end": {
+                    "description": "CancelAtPeriodEnd: the access ends at CurrentPeriodEnd",
+                    "type": "boolean"
+                },
+                "current_period_end": {
+                    "type": "string"
+                },
+                "current_period_start": {
                     "type": "string"
                 },
                 "frequency": {
@@ -1697,10 +7874,25 @@ const docTemplate = `{
                 "max_properties_limit": {
                     "type": "integer"
                 },
-                "plan_type": {
+                "plan": {
                     "type": "string"
                 },
-                "start_date": {
+                "property_slots": {
+                    "description": "PropertySlots are the long-term properties bought on top of the plan, counted in MaxPropertiesLimit",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/seculoc-back_internal_core_service.PropertySlots"
+                        }
+                    ]
+                },
+                "scheduled_archived_property_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "scheduled_plan": {
+                    "description": "ScheduledPlan is applied at CurrentPeriodEnd, archiving ScheduledArchivedPropertyIDs",
                     "type": "string"
                 },
                 "status": {
@@ -1708,6 +7900,47 @@ const docTemplate = `{
                 }
             }
         },
+        "seculoc-back_internal_core_service.TenantDossierDTO": {
+            "type": "object",
+            "properties": {
+                "analysis": {
+                    "$ref": "#/definitions/seculoc-back_internal_core_service.IncomeAnalysis"
+                },
+                "documents": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/seculoc-back_internal_core_service.CandidateDocumentDTO"
+                    }
+                },
+                "employment_type": {
+                    "type": "string"
+                },
+                "expired": {
+                    "type": "boolean"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "guarantee_type": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "shares": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/seculoc-back_internal_core_service.DossierShareDTO"
+                    }
+                },
+                "source_check_id": {
+                    "type": "integer"
+                },
+                "verified_at": {
+                    "type": "string"
+                }
+            }
+        },
         "seculoc-back_internal_core_service.TenantDraft": {
             "type": "object",
             "required": [
@@ -1727,6 +7960,11 @@ const docTemplate = `{
                 },
                 "phone": {
                     "type": "string"
+                },
+                "rent_share": {
+                    "description": "Co-tenancy: monthly share, rent and charges included",
+                    "type": "number",
+                    "minimum": 0
                 }
             }
         },
@@ -1736,6 +7974,10 @@ const docTemplate = `{
                 "amount": {
                     "type": "number"
                 },
+                "counterparty": {
+                    "description": "Counterparty is optional: when the provider does not send it, it is derived from the description",
+                    "type": "string"
+                },
                 "date": {
                     "type": "string"
                 },
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"seculoc-back/internal/adapter/http/middleware"
+	"seculoc-back/internal/core/service"
+	"seculoc-back/internal/platform/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type AccountHandler struct {
+	svc *service.AccountService
+}
+
+func NewAccountHandler(svc *service.AccountService) *AccountHandler {
+	return &AccountHandler{svc: svc}
+}
+
+func (h *AccountHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrAccountNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrActiveLease):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// Export godoc
+// @Summary      Export my data
+// @Description  Zip archive of everything held about the user (RGPD right of access): data.json with the profile,
+// @Description  properties, leases, solvency checks as owner and as candidate, credit ledger, payments and invitations,
+// @Description  and the stored files it lists under files/.
+// @Tags         account
+// @Produce      application/zip
+// @Security     BearerAuth
+// @Success      200  {file}  binary
+// @Failure      404  {object}  map[string]string
+// @Router       /me/export [get]
+func (h *AccountHandler) Export(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	export, err := h.svc.Export(ctx, userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("seculoc-export-%d.zip", userID)))
+	c.Header("Cache-Control", "private, no-store")
+	c.Status(http.StatusOK)
+	// The archive is streamed: once started, a failure can only cut it short
+	if err := h.svc.WriteArchive(ctx, export, c.Writer); err != nil {
+		logger.FromContext(ctx).Error("failed to write data export", zap.Int32("user_id", userID), zap.Error(err))
+	}
+}
+
+// Delete godoc
+// @Summary      Delete my account
+// @Description  Erases the user's personal data (RGPD right to erasure). Credit ledger, payments and signed leases are
+// @Description  kept, attached to the anonymised account, for the legal retention periods. Refused while a lease of
+// @Description  the user, as owner or tenant, is not terminated.
+// @Tags         account
+// @Security     BearerAuth
+// @Success      204
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /me [delete]
+func (h *AccountHandler) Delete(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := h.svc.DeleteAccount(c.Request.Context(), userID); err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
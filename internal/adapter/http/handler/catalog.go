@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"seculoc-back/internal/core/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CatalogHandler struct {
+	svc *service.CatalogService
+}
+
+func NewCatalogHandler(svc *service.CatalogService) *CatalogHandler {
+	return &CatalogHandler{svc: svc}
+}
+
+// PlansResponse is the public catalog.
+type PlansResponse struct {
+	Plans []service.CatalogItem `json:"plans"`
+	Packs []service.CatalogItem `json:"packs"`
+}
+
+type CatalogItemRequest struct {
+	Code              string     `json:"code" binding:"required,max=50"`
+	Kind              string     `json:"kind" binding:"required,oneof=plan pack"`
+	Name              string     `json:"name" binding:"required,max=100"`
+	PlanType          string     `json:"plan_type" binding:"omitempty,oneof=discovery serenity premium"`
+	MonthlyPriceCents *int32     `json:"monthly_price_cents" binding:"omitempty,gte=0"`
+	YearlyPriceCents  *int32     `json:"yearly_price_cents" binding:"omitempty,gte=0"`
+	PriceCents        *int32     `json:"price_cents" binding:"omitempty,gte=0"`
+	MaxProperties     int32      `json:"max_properties" binding:"gte=0"`
+	IncludedCredits   int32      `json:"included_credits" binding:"gte=0"`
+	SlotPriceCents    *int32     `json:"slot_price_cents" binding:"omitempty,gte=0"`
+	ValidFrom         time.Time  `json:"valid_from"` // default: now (creation) or unchanged (update)
+	ValidUntil        *time.Time `json:"valid_until"`
+}
+
+func (r CatalogItemRequest) toItem() service.CatalogItem {
+	return service.CatalogItem{
+		Code:              r.Code,
+		Kind:              r.Kind,
+		Name:              r.Name,
+		PlanType:          r.PlanType,
+		MonthlyPriceCents: r.MonthlyPriceCents,
+		YearlyPriceCents:  r.YearlyPriceCents,
+		PriceCents:        r.PriceCents,
+		MaxProperties:     r.MaxProperties,
+		IncludedCredits:   r.IncludedCredits,
+		SlotPriceCents:    r.SlotPriceCents,
+		ValidFrom:         r.ValidFrom,
+		ValidUntil:        r.ValidUntil,
+	}
+}
+
+func (h *CatalogHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrCatalogItemNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidCatalogItem):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrCatalogOverlap), errors.Is(err, service.ErrCatalogItemInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func catalogItemID(c *gin.Context) (int32, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid catalog item id"})
+		return 0, false
+	}
+	return int32(id), true
+}
+
+// ListPlans godoc
+// @Summary      List plans and credit packs
+// @Description  The offers currently available, with prices in cents
+// @Tags         catalog
+// @Produce      json
+// @Success      200  {object}  PlansResponse
+// @Router       /plans [get]
+func (h *CatalogHandler) ListPlans(c *gin.Context) {
+	items, err := h.svc.ListActive(c.Request.Context())
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	resp := PlansResponse{Plans: []service.CatalogItem{}, Packs: []service.CatalogItem{}}
+	for _, item := range items {
+		if item.Kind == service.CatalogKindPack {
+			resp.Packs = append(resp.Packs, item)
+		} else {
+			resp.Plans = append(resp.Plans, item)
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// List godoc
+// @Summary      List the catalog (admin)
+// @Description  Every version of every offer, past and scheduled ones included
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {array}   service.CatalogItem
+// @Failure      403  {object}  map[string]string
+// @Router       /admin/catalog [get]
+func (h *CatalogHandler) List(c *gin.Context) {
+	items, err := h.svc.List(c.Request.Context())
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+// Create godoc
+// @Summary      Add a catalog item (admin)
+// @Description  Adds a plan or a pack, or a new version of one (same code). Its validity period cannot overlap
+// @Description  another version: set valid_until on the current version first.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request  body  CatalogItemRequest  true  "Catalog item"
+// @Success      201  {object}  service.CatalogItem
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /admin/catalog [post]
+func (h *CatalogHandler) Create(c *gin.Context) {
+	var req CatalogItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	item, err := h.svc.Create(c.Request.Context(), req.toItem())
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, item)
+}
+
+// Update godoc
+// @Summary      Update a catalog item (admin)
+// @Description  Replaces a version of an offer that has not been sold yet. A version referred to by a subscription, an invoice or a payment cannot be edited (409): retire it and create a new version.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path  int                 true  "Catalog item ID"
+// @Param        request  body  CatalogItemRequest  true  "Catalog item"
+// @Success      200  {object}  service.CatalogItem
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /admin/catalog/{id} [put]
+func (h *CatalogHandler) Update(c *gin.Context) {
+	id, ok := catalogItemID(c)
+	if !ok {
+		return
+	}
+	var req CatalogItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	item, err := h.svc.Update(c.Request.Context(), id, req.toItem())
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+// Retire godoc
+// @Summary      Retire a catalog item (admin)
+// @Description  Ends the offer's validity now. Existing subscriptions keep it; it can no longer be subscribed or bought.
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      int  true  "Catalog item ID"
+// @Success      200  {object}  service.CatalogItem
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /admin/catalog/{id} [delete]
+func (h *CatalogHandler) Retire(c *gin.Context) {
+	id, ok := catalogItemID(c)
+	if !ok {
+		return
+	}
+
+	item, err := h.svc.Retire(c.Request.Context(), id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"seculoc-back/internal/adapter/http/middleware"
+	"seculoc-back/internal/core/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreditHandler struct {
+	svc *service.CreditService
+}
+
+func NewCreditHandler(svc *service.CreditService) *CreditHandler {
+	return &CreditHandler{svc: svc}
+}
+
+// ListTransactions godoc
+// @Summary      List my credit transactions
+// @Description  Credit ledger of the user, latest first: global wallet entries (grants, packs, checks, refunds, expiries)
+// @Description  and movements of the vacancy credits of their properties (wallet "property"). balance_after is the
+// @Description  balance of the wallet moved after the entry.
+// @Tags         credits
+// @Produce      json
+// @Security     BearerAuth
+// @Param        type       query     string  false  "plan_renewal, plan_upgrade, plan_expiry, pack_purchase, check_usage, initial_free, refund, property_grant, opening_balance or transfer"
+// @Param        page       query     int     false  "Page, from 1"  default(1)
+// @Param        page_size  query     int     false  "Entries per page (max 100)"  default(20)
+// @Success      200  {object}  service.CreditTransactionPage
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Router       /me/credits/transactions [get]
+func (h *CreditHandler) ListTransactions(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "page must be a positive integer"})
+		return
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if err != nil || pageSize < 1 || pageSize > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "page_size must be between 1 and 100"})
+		return
+	}
+
+	result, err := h.svc.ListTransactions(c.Request.Context(), userID, c.Query("type"), page, pageSize)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCreditTransactionType) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// ListAccounts godoc
+// @Summary      List my credit wallets
+// @Description  Wallets of the user with their balance: the global wallet, then the vacancy credits of each property.
+// @Tags         credits
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {array}   service.CreditAccountDTO
+// @Failure      401  {object}  map[string]string
+// @Router       /me/credits/accounts [get]
+func (h *CreditHandler) ListAccounts(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	accounts, err := h.svc.ListAccounts(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, accounts)
+}
+
+type TransferCreditsRequest struct {
+	FromAccountID int32 `json:"from_account_id" binding:"required"`
+	ToAccountID   int32 `json:"to_account_id" binding:"required"`
+	Amount        int32 `json:"amount" binding:"required,min=1"`
+}
+
+// TransferCredits godoc
+// @Summary      Transfer credits between my wallets
+// @Description  Moves credits from one wallet of the user to another (see GET /me/credits/accounts), e.g. from the
+// @Description  global wallet to the vacancy credits of a property. The target property must be active.
+// @Tags         credits
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        Idempotency-Key header string false "Unique key of the request: a retry with the same key replays the first response"
+// @Param        request body TransferCreditsRequest true "Wallets and amount"
+// @Success      200  {array}   service.CreditAccountDTO
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /me/credits/transfers [post]
+func (h *CreditHandler) TransferCredits(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req TransferCreditsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	accounts, err := h.svc.TransferCredits(c.Request.Context(), userID, req.FromAccountID, req.ToAccountID, req.Amount)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrCreditAccountNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrInvalidCreditTransfer), errors.Is(err, service.ErrNotEnoughWalletCredits):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, accounts)
+}
@@ -0,0 +1,259 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"seculoc-back/internal/adapter/http/middleware"
+	"seculoc-back/internal/core/service"
+)
+
+type DocumentHandler struct {
+	svc      *service.DocumentService
+	leaseSvc *service.LeaseService
+	// frontendURL may embed signed documents in an iframe (PDF viewer)
+	frontendURL string
+}
+
+func NewDocumentHandler(svc *service.DocumentService, leaseSvc *service.LeaseService, frontendURL string) *DocumentHandler {
+	return &DocumentHandler{svc: svc, leaseSvc: leaseSvc, frontendURL: frontendURL}
+}
+
+type CreateDocumentLinkRequest struct {
+	// Either a specific version (document_id) or the latest version of (type, entity_id)
+	DocumentID       int32  `json:"document_id"`
+	Type             string `json:"type" binding:"omitempty,oneof=lease solvency_report"`
+	EntityID         int32  `json:"entity_id"`
+	ExpiresInMinutes int    `json:"expires_in_minutes" binding:"omitempty,min=1,max=1440"`
+}
+
+type CreateLeaseLinkRequest struct {
+	ExpiresInMinutes int `json:"expires_in_minutes" binding:"omitempty,min=1,max=1440"`
+}
+
+func (h *DocumentHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrDocumentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrDocumentAccessDenied), errors.Is(err, service.ErrInvalidDocumentLink):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrDocumentLinkExpired):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// List godoc
+// @Summary      List document versions
+// @Description  List the stored versions of a lease or solvency report (newest first)
+// @Tags         documents
+// @Produce      json
+// @Security     BearerAuth
+// @Param        type       query     string  true  "lease or solvency_report"
+// @Param        entity_id  query     int     true  "Lease or solvency check ID"
+// @Success      200  {array}   service.DocumentDTO
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Router       /documents [get]
+func (h *DocumentHandler) List(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	entityID, err := strconv.Atoi(c.Query("entity_id"))
+	if err != nil || c.Query("type") == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type and entity_id are required"})
+		return
+	}
+
+	docs, err := h.svc.ListDocuments(c.Request.Context(), userID, c.Query("type"), int32(entityID))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, docs)
+}
+
+// CreateLink godoc
+// @Summary      Create a signed download link
+// @Description  Issue a short-lived, revocable link to one document version, usable only by the caller.
+// @Description  The link can be opened without the Authorization header (email, embedded PDF viewer).
+// @Tags         documents
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request  body  CreateDocumentLinkRequest  true  "Document version or latest of an entity"
+// @Success      201  {object}  service.DocumentLinkDTO
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /documents/links [post]
+func (h *DocumentHandler) CreateLink(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req CreateDocumentLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.DocumentID == 0 && (req.Type == "" || req.EntityID == 0) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "document_id or type and entity_id are required"})
+		return
+	}
+
+	ttl := time.Duration(req.ExpiresInMinutes) * time.Minute
+	var link *service.DocumentLinkDTO
+	var err error
+	if req.DocumentID != 0 {
+		link, err = h.svc.CreateLink(c.Request.Context(), userID, req.DocumentID, ttl)
+	} else {
+		link, err = h.svc.CreateLatestLink(c.Request.Context(), userID, req.Type, req.EntityID, ttl)
+	}
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, link)
+}
+
+// CreateLeaseLink godoc
+// @Summary      Create a signed link to the lease contract
+// @Description  Issue a signed link to the latest version of the lease document, generating it if needed
+// @Tags         leases
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path  int                     true   "Lease ID"
+// @Param        request  body  CreateLeaseLinkRequest  false  "Link lifetime"
+// @Success      201  {object}  service.DocumentLinkDTO
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Router       /leases/{id}/links [post]
+func (h *DocumentHandler) CreateLeaseLink(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	leaseID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid lease id"})
+		return
+	}
+
+	var req CreateLeaseLinkRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	documentID, err := h.leaseSvc.EnsureLeaseDocument(c.Request.Context(), int32(leaseID), userID)
+	if err != nil {
+		if err.Error() == fmt.Sprintf("access denied: user %d is not a party to this lease", userID) ||
+			err.Error() == fmt.Sprintf("access denied: user %d is not the owner of this draft lease", userID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	link, err := h.svc.CreateLink(c.Request.Context(), userID, documentID, time.Duration(req.ExpiresInMinutes)*time.Minute)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, link)
+}
+
+// RevokeLink godoc
+// @Summary      Revoke a signed link
+// @Tags         documents
+// @Produce      json
+// @Security     BearerAuth
+// @Param        linkId  path  int  true  "Link ID"
+// @Success      200  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Router       /documents/links/{linkId} [delete]
+func (h *DocumentHandler) RevokeLink(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	linkID, err := strconv.Atoi(c.Param("linkId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid link id"})
+		return
+	}
+
+	if err := h.svc.RevokeLink(c.Request.Context(), userID, int32(linkID)); err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "revoked"})
+}
+
+// OpenLink godoc
+// @Summary      Open a signed link
+// @Description  Public endpoint: the HMAC signature replaces the bearer token. Each access is audited.
+// @Tags         documents
+// @Produce      octet-stream
+// @Param        linkId     path   int     true  "Link ID"
+// @Param        expires    query  int     true  "Expiry (unix timestamp)"
+// @Param        signature  query  string  true  "HMAC signature"
+// @Success      200  {file}    file
+// @Failure      403  {object}  map[string]string
+// @Failure      410  {object}  map[string]string
+// @Router       /documents/links/{linkId} [get]
+func (h *DocumentHandler) OpenLink(c *gin.Context) {
+	linkID, err := strconv.Atoi(c.Param("linkId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid link id"})
+		return
+	}
+	expires, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if err != nil || c.Query("signature") == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": service.ErrInvalidDocumentLink.Error()})
+		return
+	}
+
+	reader, doc, err := h.svc.OpenLink(c.Request.Context(), service.LinkAccess{
+		LinkID:    int32(linkID),
+		Expires:   expires,
+		Signature: c.Query("signature"),
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	defer reader.Close()
+
+	// The global X-Frame-Options: DENY would block the frontend's embedded viewer
+	c.Writer.Header().Del("X-Frame-Options")
+	c.Header("Content-Security-Policy", "frame-ancestors 'self' "+h.frontendURL)
+
+	// inline so browsers and embedded viewers display the document; never cache a signed response
+	c.DataFromReader(http.StatusOK, -1, doc.ContentType, reader, map[string]string{
+		"Content-Disposition": fmt.Sprintf("inline; filename=%q", doc.Filename),
+		"Cache-Control":       "private, no-store",
+	})
+}
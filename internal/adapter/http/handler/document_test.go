@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateDocumentLink_Validation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		payload    string
+		expectCode int
+	}{
+		{
+			name:       "Neither Document Nor Entity",
+			payload:    `{"expires_in_minutes": 10}`,
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			name:       "Type Without Entity",
+			payload:    `{"type": "lease"}`,
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			name:       "Invalid Type",
+			payload:    `{"type": "payslip", "entity_id": 1}`,
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			name:       "Lifetime Too Long",
+			payload:    `{"document_id": 1, "expires_in_minutes": 10000}`,
+			expectCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewDocumentHandler(nil, nil, "") // Service not needed for binding failure
+			r := gin.New()
+			r.Use(func(c *gin.Context) {
+				c.Set("userID", int32(1))
+				c.Next()
+			})
+			r.POST("/documents/links", h.CreateLink)
+
+			req, _ := http.NewRequest("POST", "/documents/links", bytes.NewBufferString(tt.payload))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+		})
+	}
+}
+
+func TestOpenDocumentLink_MissingSignature(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	h := NewDocumentHandler(nil, nil, "")
+	r := gin.New()
+	r.GET("/documents/links/:linkId", h.OpenLink)
+
+	for _, url := range []string{"/documents/links/1", "/documents/links/1?expires=abc&signature=x", "/documents/links/1?expires=1700000000"} {
+		req, _ := http.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code, url)
+	}
+}
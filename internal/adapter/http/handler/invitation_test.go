@@ -72,3 +72,23 @@ func TestAcceptInvitation_Validation(t *testing.T) {
 	r.ServeHTTP(w, req)
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
+
+func TestInviteCoTenant_Validation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewInvitationHandler(nil)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", int32(1))
+		c.Next()
+	})
+	r.POST("/leases/:id/parties", h.InviteCoTenant)
+
+	for _, payload := range []string{`{}`, `{"email": "not-email"}`, `{"email": "bob@example.com", "rent_share": -1}`} {
+		req, _ := http.NewRequest("POST", "/leases/4/parties", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, payload)
+	}
+}
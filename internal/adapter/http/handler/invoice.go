@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"seculoc-back/internal/adapter/http/middleware"
+	"seculoc-back/internal/core/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type InvoiceHandler struct {
+	svc *service.InvoiceService
+}
+
+func NewInvoiceHandler(svc *service.InvoiceService) *InvoiceHandler {
+	return &InvoiceHandler{svc: svc}
+}
+
+// List godoc
+// @Summary      List my invoices
+// @Description  Invoices and credit notes issued to the user, latest first: number, VAT breakdown (amounts in cents,
+// @Description  rate in basis points) and archived PDF. An invoice is issued once paid; a refund issues a credit note.
+// @Tags         invoices
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {array}   service.InvoiceDTO
+// @Failure      401  {object}  map[string]string
+// @Router       /me/invoices [get]
+func (h *InvoiceHandler) List(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	invoices, err := h.svc.ListInvoices(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, invoices)
+}
+
+// Download godoc
+// @Summary      Download an invoice
+// @Description  Archived PDF of an invoice or credit note of the user. It is rendered once and never changes afterwards.
+// @Description  Each download is audited.
+// @Tags         invoices
+// @Produce      application/pdf
+// @Security     BearerAuth
+// @Param        id  path  int  true  "Invoice ID"
+// @Success      200  {file}    file
+// @Failure      404  {object}  map[string]string
+// @Router       /me/invoices/{id}/pdf [get]
+func (h *InvoiceHandler) Download(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	invoiceID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid invoice id"})
+		return
+	}
+
+	reader, doc, err := h.svc.OpenInvoice(c.Request.Context(), userID, int32(invoiceID), c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		if errors.Is(err, service.ErrInvoiceNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer reader.Close()
+
+	c.DataFromReader(http.StatusOK, -1, doc.ContentType, reader, map[string]string{
+		"Content-Disposition": fmt.Sprintf("attachment; filename=%q", doc.Filename),
+		"Cache-Control":       "private, no-store",
+	})
+}
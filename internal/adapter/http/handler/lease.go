@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -133,6 +134,42 @@ func (h *LeaseHandler) Download(c *gin.Context) {
 	c.Data(http.StatusOK, "application/pdf", pdfBytes)
 }
 
+// ListGuarantors godoc
+// @Summary      List the guarantors of a lease
+// @Description  Guarantors of the signed lease with their latest guarantee deed, for the owner and the tenant
+// @Tags         leases
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      int  true  "Lease ID"
+// @Success      200  {array}   service.LeaseGuarantorDTO
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Router       /leases/{id}/guarantors [get]
+func (h *LeaseHandler) ListGuarantors(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid lease id"})
+		return
+	}
+
+	guarantors, err := h.svc.ListLeaseGuarantors(c.Request.Context(), userID, int32(id))
+	if err != nil {
+		if errors.Is(err, service.ErrDocumentAccessDenied) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, guarantors)
+}
+
 // CreateDraft godoc
 // @Summary      Create a draft lease
 // @Description  Create a new lease in draft mode and invite the tenant
@@ -140,6 +177,7 @@ func (h *LeaseHandler) Download(c *gin.Context) {
 // @Accept       json
 // @Produce      json
 // @Security     BearerAuth
+// @Param        Idempotency-Key header string false "Unique key of the request: a retry with the same key replays the first response"
 // @Param        request body service.DraftLeaseRequest true "Draft Lease Details"
 // @Success      201  {object}  map[string]interface{}
 // @Failure      400  {object}  map[string]string
@@ -0,0 +1,276 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"seculoc-back/internal/adapter/http/middleware"
+	"seculoc-back/internal/core/service"
+)
+
+type CoTenantRequest struct {
+	Email     string  `json:"email" binding:"required,email"`
+	FirstName string  `json:"first_name"`
+	LastName  string  `json:"last_name"`
+	RentShare float64 `json:"rent_share" binding:"gte=0"`
+	// ReplacesPartyID is the co-tenant who gave notice and is taken over by the new one
+	ReplacesPartyID int32 `json:"replaces_party_id"`
+}
+
+type RentSplitRequest struct {
+	JointLiability       bool                 `json:"joint_liability"`
+	IndividualRentShares bool                 `json:"individual_rent_shares"`
+	Shares               []service.PartyShare `json:"shares" binding:"dive"`
+}
+
+type DepartureRequest struct {
+	NoticeDate string `json:"notice_date" binding:"required" example:"2024-03-01"`
+	// ReducedNotice applies the one month notice of a tense area, a new job or a health reason
+	ReducedNotice bool `json:"reduced_notice"`
+}
+
+func handleLeasePartyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrLeaseNotFound), errors.Is(err, service.ErrLeasePartyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrLeaseAccessDenied):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidRentShares):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrLeasePartyNotActive), errors.Is(err, service.ErrCoTenantAlreadyInvited),
+		errors.Is(err, service.ErrInvalidReplacement), errors.Is(err, service.ErrLeaseClosedToParties):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// leasePartyPath reads the :id lease and :partyId parameters of the co-tenancy routes.
+func leasePartyPath(c *gin.Context) (leaseID, partyID int32, ok bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid lease id"})
+		return 0, 0, false
+	}
+	if c.Param("partyId") == "" {
+		return int32(id), 0, true
+	}
+	pid, err := strconv.Atoi(c.Param("partyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid party id"})
+		return 0, 0, false
+	}
+	return int32(id), int32(pid), true
+}
+
+// InviteCoTenant godoc
+// @Summary      Invite a co-tenant
+// @Description  Adds a co-tenant (colocation) to a long-term lease and emails them their own invitation link.
+// @Description  replaces_party_id takes over from a co-tenant who gave notice, which ends their solidarity.
+// @Tags         leases
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path  int              true  "Lease ID"
+// @Param        request  body  CoTenantRequest  true  "Co-tenant"
+// @Success      201  {object}  service.LeasePartyDTO
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /leases/{id}/parties [post]
+func (h *InvitationHandler) InviteCoTenant(c *gin.Context) {
+	ownerID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	leaseID, _, ok := leasePartyPath(c)
+	if !ok {
+		return
+	}
+
+	var req CoTenantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	party, err := h.svc.InviteCoTenant(c.Request.Context(), ownerID, leaseID, service.CoTenantParams{
+		Email:           req.Email,
+		FirstName:       req.FirstName,
+		LastName:        req.LastName,
+		RentShare:       req.RentShare,
+		ReplacesPartyID: req.ReplacesPartyID,
+	})
+	if err != nil {
+		handleLeasePartyError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, party)
+}
+
+// ListParties godoc
+// @Summary      List the parties of a lease
+// @Description  Co-tenants of the lease with their status, rent share and solidarity end date
+// @Tags         leases
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      int  true  "Lease ID"
+// @Success      200  {object}  service.LeasePartiesDTO
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /leases/{id}/parties [get]
+func (h *LeaseHandler) ListParties(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	leaseID, _, ok := leasePartyPath(c)
+	if !ok {
+		return
+	}
+
+	parties, err := h.svc.ListParties(c.Request.Context(), userID, leaseID)
+	if err != nil {
+		handleLeasePartyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, parties)
+}
+
+// SetRentSplit godoc
+// @Summary      Set how the rent is shared
+// @Description  Joint and several liability and individual rent shares of a co-tenancy. When shares are given,
+// @Description  they must cover every current co-tenant and add up to the rent plus charges.
+// @Tags         leases
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path  int               true  "Lease ID"
+// @Param        request  body  RentSplitRequest  true  "Rent split"
+// @Success      200  {object}  service.LeasePartiesDTO
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /leases/{id}/rent-split [put]
+func (h *LeaseHandler) SetRentSplit(c *gin.Context) {
+	ownerID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	leaseID, _, ok := leasePartyPath(c)
+	if !ok {
+		return
+	}
+
+	var req RentSplitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	parties, err := h.svc.SetRentSplit(c.Request.Context(), ownerID, leaseID, service.RentSplit{
+		JointLiability:   req.JointLiability,
+		IndividualShares: req.IndividualRentShares,
+		Shares:           req.Shares,
+	})
+	if err != nil {
+		handleLeasePartyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, parties)
+}
+
+// RecordDeparture godoc
+// @Summary      Record a co-tenant's notice
+// @Description  The co-tenant or the owner records the notice. The co-tenant leaves after the notice period
+// @Description  and, under joint liability, remains liable for the rent for six more months unless replaced.
+// @Tags         leases
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path  int               true  "Lease ID"
+// @Param        partyId  path  int               true  "Party ID"
+// @Param        request  body  DepartureRequest  true  "Notice"
+// @Success      200  {object}  service.LeasePartyDTO
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /leases/{id}/parties/{partyId}/leave [post]
+func (h *LeaseHandler) RecordDeparture(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	leaseID, partyID, ok := leasePartyPath(c)
+	if !ok {
+		return
+	}
+
+	var req DepartureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	noticeDate, err := time.Parse("2006-01-02", req.NoticeDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "notice_date must be YYYY-MM-DD"})
+		return
+	}
+
+	party, err := h.svc.RecordDeparture(c.Request.Context(), userID, leaseID, partyID, noticeDate, req.ReducedNotice)
+	if err != nil {
+		handleLeasePartyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, party)
+}
+
+// RentSchedule godoc
+// @Summary      Rent schedule of a lease
+// @Description  Monthly due dates from the current month, split per co-tenant when rent shares are individual,
+// @Description  with the departed co-tenants still jointly liable
+// @Tags         leases
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id      path   int  true   "Lease ID"
+// @Param        months  query  int  false  "Number of months (1 to 24, default 12)"
+// @Success      200  {array}   service.RentScheduleMonth
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /leases/{id}/rent-schedule [get]
+func (h *LeaseHandler) RentSchedule(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	leaseID, _, ok := leasePartyPath(c)
+	if !ok {
+		return
+	}
+
+	months, err := strconv.Atoi(c.DefaultQuery("months", "12"))
+	if err != nil || months < 1 || months > 24 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "months must be between 1 and 24"})
+		return
+	}
+
+	schedule, err := h.svc.RentSchedule(c.Request.Context(), userID, leaseID, time.Now(), months)
+	if err != nil {
+		handleLeasePartyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, schedule)
+}
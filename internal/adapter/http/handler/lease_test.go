@@ -3,6 +3,7 @@ package handler
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -38,3 +39,64 @@ func TestDownloadLease_InvalidID(t *testing.T) {
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
+
+func TestRecordDeparture_InvalidNoticeDate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewLeaseHandler(nil)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", int32(123))
+		c.Next()
+	})
+	r.POST("/leases/:id/parties/:partyId/leave", h.RecordDeparture)
+
+	for _, body := range []string{`{}`, `{"notice_date":"01/03/2024"}`} {
+		req, _ := http.NewRequest("POST", "/leases/1/parties/2/leave", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+
+	req, _ := http.NewRequest("POST", "/leases/1/parties/abc/leave", strings.NewReader(`{"notice_date":"2024-03-01"}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestRentSchedule_InvalidMonths(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewLeaseHandler(nil)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", int32(123))
+		c.Next()
+	})
+	r.GET("/leases/:id/rent-schedule", h.RentSchedule)
+
+	req, _ := http.NewRequest("GET", "/leases/1/rent-schedule?months=36", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestSetRentSplit_InvalidShare(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewLeaseHandler(nil)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", int32(123))
+		c.Next()
+	})
+	r.PUT("/leases/:id/rent-split", h.SetRentSplit)
+
+	body := `{"individual_rent_shares":true,"shares":[{"party_id":1,"amount":-10}]}`
+	req, _ := http.NewRequest("PUT", "/leases/1/rent-split", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
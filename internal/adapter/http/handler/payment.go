@@ -0,0 +1,234 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"seculoc-back/internal/adapter/http/middleware"
+	"seculoc-back/internal/core/service"
+	"seculoc-back/internal/platform/webhook"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PaymentHandler struct {
+	svc *service.PaymentService
+}
+
+func NewPaymentHandler(svc *service.PaymentService) *PaymentHandler {
+	return &PaymentHandler{svc: svc}
+}
+
+// paymentStatusCode is the HTTP status of a request which charged the user: 402 when the payment
+// failed, 202 while it awaits 3-D Secure or the bank.
+func paymentStatusCode(payment *service.PaymentResult) int {
+	switch {
+	case payment == nil:
+		return http.StatusOK
+	case payment.Status == service.PaymentFailed:
+		return http.StatusPaymentRequired
+	case payment.Pending():
+		return http.StatusAccepted
+	default:
+		return http.StatusOK
+	}
+}
+
+func (h *PaymentHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrPaymentUnavailable):
+		// Refused before anything was recorded
+		middleware.ReleaseIdempotencyKey(c)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrPaymentNotFound), errors.Is(err, service.ErrCreditAccountNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrCatalogItemNotFound), errors.Is(err, service.ErrRefundNotAllowed),
+		errors.Is(err, service.ErrInactivePropertyWallet):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+type BuyCreditsRequest struct {
+	PackType string `json:"pack_type" binding:"required,max=50"`
+	// PaymentMethodID is a payment method collected by the provider's frontend SDK; the saved
+	// payment method or SEPA mandate is used when empty.
+	PaymentMethodID string `json:"payment_method_id" binding:"max=100"`
+	// PropertyID tops up the wallet of that property instead of the global wallet
+	PropertyID int32 `json:"property_id" binding:"omitempty,min=1"`
+}
+
+type BuyCreditsResponse struct {
+	Message string                 `json:"message"`
+	Added   int32                  `json:"added"`
+	Payment *service.PaymentResult `json:"payment"`
+}
+
+// BuyCredits godoc
+// @Summary      Purchase Credit Pack
+// @Description  Buy a credit pack of the catalog (e.g., pack_20, see GET /plans). The credits are added once
+// @Description  the payment succeeded: 202 means it awaits 3-D Secure (see payment.next_action_url), 402 that it failed.
+// @Description  With property_id, the credits top up the wallet of that active property instead of the global wallet.
+// @Tags         solvency
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        Idempotency-Key header string false "Unique key of the request: a retry with the same key replays the first response"
+// @Param        request body BuyCreditsRequest true "Credit Pack Info"
+// @Success      200  {object}  BuyCreditsResponse
+// @Success      202  {object}  BuyCreditsResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      402  {object}  BuyCreditsResponse
+// @Failure      404  {object}  map[string]string
+// @Router       /solvency/credits [post]
+func (h *PaymentHandler) BuyCredits(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req BuyCreditsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !service.ValidCatalogCode(req.PackType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid pack type"})
+		return
+	}
+
+	amount, payment, err := h.svc.BuyPack(c.Request.Context(), userID, req.PackType, req.PaymentMethodID, req.PropertyID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	resp := BuyCreditsResponse{Message: "credits purchased", Added: amount, Payment: payment}
+	switch {
+	case payment.Status == service.PaymentFailed:
+		resp.Message, resp.Added = "payment failed", 0
+	case payment.Pending():
+		resp.Message, resp.Added = "payment pending", 0
+	}
+	c.JSON(paymentStatusCode(payment), resp)
+}
+
+type SepaMandateRequest struct {
+	IBAN          string `json:"iban" binding:"required,min=15,max=40"`
+	AccountHolder string `json:"account_holder" binding:"required,max=100"`
+}
+
+// SetupSepaMandate godoc
+// @Summary      Set up SEPA Direct Debit
+// @Description  Registers the IBAN with the payment provider. Subscription renewals and purchases without
+// @Description  payment method are then debited from it. Only the mandate reference is stored.
+// @Tags         payments
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body SepaMandateRequest true "Bank account"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Router       /me/payment-method/sepa [post]
+func (h *PaymentHandler) SetupSepaMandate(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req SepaMandateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	mandate, err := h.svc.SetupSepaMandate(c.Request.Context(), userID, req.IBAN, req.AccountHolder)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"mandate_id": mandate.ID, "iban_last4": mandate.Last4})
+}
+
+type RefundRequest struct {
+	// AmountCents to refund, the whole remaining amount when omitted
+	AmountCents int32 `json:"amount_cents" binding:"min=0"`
+}
+
+// Refund godoc
+// @Summary      Refund a payment (admin)
+// @Description  Refunds a successful payment, fully or partially. The credits of a pack are taken back in
+// @Description  proportion, up to the balance of the wallet they went to; subscription periods are not.
+// @Description  A refund interrupted earlier is resumed first: amount_cents must then be omitted or match it.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id path int true "Transaction ID"
+// @Param        request body RefundRequest false "Amount"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /admin/transactions/{id}/refund [post]
+func (h *PaymentHandler) Refund(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid transaction id"})
+		return
+	}
+
+	var req RefundRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	refund, err := h.svc.Refund(c.Request.Context(), int32(id), req.AmountCents)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, refund)
+}
+
+// Webhook godoc
+// @Summary      Payment provider webhook
+// @Description  Payment notifications (payment_intent.succeeded, payment_intent.payment_failed). The signature
+// @Description  header is verified against PAYMENT_WEBHOOK_SECRET and each event is processed once.
+// @Tags         payments
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Router       /webhooks/payments [post]
+func (h *PaymentHandler) Webhook(c *gin.Context) {
+	payload, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot read body"})
+		return
+	}
+
+	err = h.svc.HandleWebhook(c.Request.Context(), payload, c.Request.Header)
+	if err != nil {
+		switch {
+		case errors.Is(err, webhook.ErrInvalidSignature), errors.Is(err, webhook.ErrExpired):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrPaymentUnavailable):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		default:
+			// Let the provider retry: the event was released
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "webhook processing failed"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "ok"})
+}
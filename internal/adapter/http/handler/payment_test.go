@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuyCredits_Validation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewPaymentHandler(nil)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", int32(1))
+		c.Next()
+	})
+	r.POST("/solvency/credits", h.BuyCredits)
+
+	// Malformed pack code (unknown packs are refused by the catalog, see service tests)
+	req, _ := http.NewRequest("POST", "/solvency/credits", bytes.NewBufferString(`{"pack_type": "Pack 20!"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestSepaMandate_Validation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewPaymentHandler(nil)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", int32(1))
+		c.Next()
+	})
+	r.POST("/me/payment-method/sepa", h.SetupSepaMandate)
+
+	for _, payload := range []string{`{}`, `{"iban": "FR76", "account_holder": "Jean Dupont"}`} {
+		req, _ := http.NewRequest("POST", "/me/payment-method/sepa", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, payload)
+	}
+}
@@ -0,0 +1,371 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"seculoc-back/internal/adapter/http/middleware"
+	"seculoc-back/internal/core/service"
+)
+
+type PropertyMediaHandler struct {
+	svc *service.PropertyMediaService
+}
+
+func NewPropertyMediaHandler(svc *service.PropertyMediaService) *PropertyMediaHandler {
+	return &PropertyMediaHandler{svc: svc}
+}
+
+type ReorderPhotosRequest struct {
+	MediaIDs []int32 `json:"media_ids" binding:"required"`
+}
+
+// mediaPathIDs parses the property id and, when present, the media id.
+func mediaPathIDs(c *gin.Context) (int32, int32, bool) {
+	propertyID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid property id"})
+		return 0, 0, false
+	}
+	if c.Param("mediaId") == "" {
+		return int32(propertyID), 0, true
+	}
+	mediaID, err := strconv.Atoi(c.Param("mediaId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid media id"})
+		return 0, 0, false
+	}
+	return int32(propertyID), int32(mediaID), true
+}
+
+// readUpload reads the "file" multipart field, bounded by the largest accepted size.
+func (h *PropertyMediaHandler) readUpload(c *gin.Context) (string, []byte, bool) {
+	return readUploadFile(c, h.svc.MaxUploadBytes())
+}
+
+// readUploadFile reads the "file" multipart field of at most maxBytes.
+func readUploadFile(c *gin.Context, maxBytes int64) (string, []byte, bool) {
+	// A little headroom for the multipart envelope; the service enforces the exact limit per kind.
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes+1<<20)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": service.ErrMediaTooLarge.Error()})
+			return "", nil, false
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return "", nil, false
+	}
+
+	f, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"})
+		return "", nil, false
+	}
+	defer f.Close()
+
+	content, err := io.ReadAll(f)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"})
+		return "", nil, false
+	}
+	return fileHeader.Filename, content, true
+}
+
+func (h *PropertyMediaHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrMediaTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrUnsupportedMediaType):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrMediaNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err.Error() == "property not found or access denied":
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+// UploadPhoto godoc
+// @Summary      Upload a property photo
+// @Description  Upload a JPEG, PNG or GIF photo. A thumbnail is generated; the first photo becomes the cover.
+// @Tags         property-media
+// @Accept       multipart/form-data
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id    path      int   true  "Property ID"
+// @Param        file  formData  file  true  "Photo"
+// @Success      201  {object}  service.PropertyMediaDTO
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      413  {object}  map[string]string
+// @Failure      415  {object}  map[string]string
+// @Router       /properties/{id}/media/photos [post]
+func (h *PropertyMediaHandler) UploadPhoto(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	propertyID, _, ok := mediaPathIDs(c)
+	if !ok {
+		return
+	}
+	filename, content, ok := h.readUpload(c)
+	if !ok {
+		return
+	}
+
+	media, err := h.svc.UploadPhoto(c.Request.Context(), userID, propertyID, filename, content)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, media)
+}
+
+// UploadDiagnostic godoc
+// @Summary      Upload a property diagnostic
+// @Description  Upload a diagnostic (PDF, JPEG or PNG). Replaces the previous diagnostic of the same type.
+// @Description  The expiry date defaults to the issue date plus the legal validity of the diagnostic.
+// @Tags         property-media
+// @Accept       multipart/form-data
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id               path      int     true   "Property ID"
+// @Param        file             formData  file    true   "Diagnostic document"
+// @Param        diagnostic_type  formData  string  true   "dpe, erp, lead, electricity or gas"
+// @Param        issued_at        formData  string  false  "Issue date (YYYY-MM-DD)"
+// @Param        expires_at       formData  string  false  "Expiry date (YYYY-MM-DD)"
+// @Success      201  {object}  service.PropertyMediaDTO
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      413  {object}  map[string]string
+// @Failure      415  {object}  map[string]string
+// @Router       /properties/{id}/media/diagnostics [post]
+func (h *PropertyMediaHandler) UploadDiagnostic(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	propertyID, _, ok := mediaPathIDs(c)
+	if !ok {
+		return
+	}
+	filename, content, ok := h.readUpload(c)
+	if !ok {
+		return
+	}
+
+	params := service.UploadDiagnosticParams{
+		OwnerID:        userID,
+		PropertyID:     propertyID,
+		DiagnosticType: c.PostForm("diagnostic_type"),
+		Filename:       filename,
+		Content:        content,
+	}
+	for field, target := range map[string]**time.Time{"issued_at": &params.IssuedAt, "expires_at": &params.ExpiresAt} {
+		value := c.PostForm(field)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid %s format (YYYY-MM-DD)", field)})
+			return
+		}
+		*target = &t
+	}
+
+	media, err := h.svc.UploadDiagnostic(c.Request.Context(), params)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, media)
+}
+
+// List godoc
+// @Summary      List property media
+// @Description  Photos in display order followed by diagnostics (with expiry)
+// @Tags         property-media
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      int  true  "Property ID"
+// @Success      200  {array}   service.PropertyMediaDTO
+// @Failure      403  {object}  map[string]string
+// @Router       /properties/{id}/media [get]
+func (h *PropertyMediaHandler) List(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	propertyID, _, ok := mediaPathIDs(c)
+	if !ok {
+		return
+	}
+
+	media, err := h.svc.ListMedia(c.Request.Context(), userID, propertyID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, media)
+}
+
+// Reorder godoc
+// @Summary      Reorder property photos
+// @Description  Set the display order of all the photos of a property
+// @Tags         property-media
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path  int                   true  "Property ID"
+// @Param        request  body  ReorderPhotosRequest  true  "Photo IDs in display order"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Router       /properties/{id}/media/order [put]
+func (h *PropertyMediaHandler) Reorder(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	propertyID, _, ok := mediaPathIDs(c)
+	if !ok {
+		return
+	}
+
+	var req ReorderPhotosRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.svc.ReorderPhotos(c.Request.Context(), userID, propertyID, req.MediaIDs); err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "reordered"})
+}
+
+// SetCover godoc
+// @Summary      Set the cover photo
+// @Tags         property-media
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path  int  true  "Property ID"
+// @Param        mediaId  path  int  true  "Photo ID"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /properties/{id}/media/{mediaId}/cover [put]
+func (h *PropertyMediaHandler) SetCover(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	propertyID, mediaID, ok := mediaPathIDs(c)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.SetCover(c.Request.Context(), userID, propertyID, mediaID); err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "cover updated"})
+}
+
+// Delete godoc
+// @Summary      Delete a property media
+// @Tags         property-media
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path  int  true  "Property ID"
+// @Param        mediaId  path  int  true  "Media ID"
+// @Success      200  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /properties/{id}/media/{mediaId} [delete]
+func (h *PropertyMediaHandler) Delete(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	propertyID, mediaID, ok := mediaPathIDs(c)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.DeleteMedia(c.Request.Context(), userID, propertyID, mediaID); err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+// Download godoc
+// @Summary      Download a property media
+// @Description  Stream the original file, or its thumbnail with variant=thumbnail.
+// @Description  With object storage, redirects to a short-lived presigned URL instead.
+// @Tags         property-media
+// @Produce      octet-stream
+// @Security     BearerAuth
+// @Param        id       path   int     true   "Property ID"
+// @Param        mediaId  path   int     true   "Media ID"
+// @Param        variant  query  string  false  "thumbnail"
+// @Success      200  {file}    file
+// @Success      302  {string}  string "Redirect to the presigned URL"
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /properties/{id}/media/{mediaId} [get]
+func (h *PropertyMediaHandler) Download(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	propertyID, mediaID, ok := mediaPathIDs(c)
+	if !ok {
+		return
+	}
+
+	thumbnail := c.Query("variant") == "thumbnail"
+
+	url, err := h.svc.MediaDownloadURL(c.Request.Context(), userID, propertyID, mediaID, thumbnail)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	if url != "" {
+		c.Redirect(http.StatusFound, url)
+		return
+	}
+
+	reader, media, err := h.svc.OpenMedia(c.Request.Context(), userID, propertyID, mediaID, thumbnail)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	defer reader.Close()
+
+	c.DataFromReader(http.StatusOK, -1, media.ContentType, reader, map[string]string{
+		"Content-Disposition": fmt.Sprintf("inline; filename=%q", media.Filename),
+	})
+}
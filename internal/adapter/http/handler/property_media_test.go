@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReorderPhotos_Validation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		url        string
+		payload    string
+		expectCode int
+	}{
+		{
+			name:       "Invalid Property ID",
+			url:        "/properties/abc/media/order",
+			payload:    `{"media_ids": [1, 2]}`,
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			name:       "Missing Media IDs",
+			url:        "/properties/1/media/order",
+			payload:    `{}`,
+			expectCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewPropertyMediaHandler(nil) // Service not needed for binding failure
+			r := gin.New()
+			r.Use(func(c *gin.Context) {
+				c.Set("userID", int32(1))
+				c.Next()
+			})
+			r.PUT("/properties/:id/media/order", h.Reorder)
+
+			req, _ := http.NewRequest("PUT", tt.url, bytes.NewBufferString(tt.payload))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+		})
+	}
+}
+
+func TestMediaRoutes_InvalidMediaID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	h := NewPropertyMediaHandler(nil)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", int32(1))
+		c.Next()
+	})
+	r.PUT("/properties/:id/media/order", h.Reorder)
+	r.PUT("/properties/:id/media/:mediaId/cover", h.SetCover)
+	r.DELETE("/properties/:id/media/:mediaId", h.Delete)
+
+	for _, method := range []string{"PUT", "DELETE"} {
+		url := "/properties/1/media/xyz"
+		if method == "PUT" {
+			url += "/cover"
+		}
+		req, _ := http.NewRequest(method, url, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, method)
+	}
+}
@@ -1,11 +1,15 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"seculoc-back/internal/adapter/http/middleware"
 	"seculoc-back/internal/core/service"
+	"seculoc-back/internal/platform/webhook"
 
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/viper"
@@ -28,10 +32,19 @@ type SolvencyCheckResponse struct {
 	PropertyAddress    string `json:"property_address,omitempty"`
 	Status             string `json:"status"`
 	ScoreResult        int32  `json:"score_result,omitempty"`
+	CombinedScore      int32  `json:"combined_score,omitempty"`
 	ReportUrl          string `json:"report_url,omitempty"`
 	CreatedAt          string `json:"created_at"`
 	Token              string `json:"token"`
 	VerificationUrl    string `json:"verification_url"`
+	EmploymentType     string `json:"employment_type,omitempty"`
+	GuaranteeType      string `json:"guarantee_type,omitempty"`
+	Selection          string `json:"selection,omitempty"`
+
+	Documents        []service.CandidateDocumentDTO `json:"documents"`
+	MissingDocuments []service.MissingDocument      `json:"missing_documents"`
+	Analysis         *service.IncomeAnalysis        `json:"analysis,omitempty"`
+	PolicyResults    []service.PolicyRuleResult     `json:"policy_results,omitempty"`
 }
 
 type CreateCheckRequest struct {
@@ -40,6 +53,8 @@ type CreateCheckRequest struct {
 	CandidateLastName  string `json:"candidate_last_name"`
 	CandidatePhone     string `json:"candidate_phone"`
 	PropertyID         int32  `json:"property_id" binding:"required"`
+	// ExpiresInDays overrides SOLVENCY_CHECK_EXPIRY_DAYS for this check
+	ExpiresInDays int `json:"expires_in_days" binding:"omitempty,min=1,max=60"`
 }
 
 // CreateCheck godoc
@@ -49,6 +64,7 @@ type CreateCheckRequest struct {
 // @Accept       json
 // @Produce      json
 // @Security     BearerAuth
+// @Param        Idempotency-Key header string false "Unique key of the request: a retry with the same key replays the first response"
 // @Param        request body CreateCheckRequest true "Check Info"
 // @Success      201  {object}  SolvencyCheckResponse
 // @Failure      400  {object}  map[string]string
@@ -72,17 +88,11 @@ func (h *SolvencyHandler) CreateCheck(c *gin.Context) {
 		CandidateLastName:  req.CandidateLastName,
 		CandidatePhone:     req.CandidatePhone,
 		PropertyID:         req.PropertyID,
+		ExpiresInDays:      req.ExpiresInDays,
 	})
 	if err != nil {
 		if insErr, ok := err.(*service.ErrInsufficientCredits); ok {
-			c.JSON(http.StatusPaymentRequired, gin.H{
-				"error":   "ERR_INSUFFICIENT_CREDITS",
-				"message": "Solde insuffisant. Veuillez recharger votre compte ou votre logement.",
-				"details": gin.H{
-					"global_balance":   insErr.GlobalBalance,
-					"property_balance": insErr.PropertyBalance,
-				},
-			})
+			insufficientCredits(c, insErr)
 			return
 		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -146,10 +156,18 @@ func (h *SolvencyHandler) ListChecks(c *gin.Context) {
 			PropertyAddress:    sc.PropertyAddress,
 			Status:             string(sc.Status.SolvencyStatus),
 			ScoreResult:        sc.ScoreResult.Int32,
+			CombinedScore:      sc.CombinedScore.Int32,
 			ReportUrl:          sc.ReportUrl.String,
 			CreatedAt:          sc.CreatedAt.Time.String(),
 			Token:              sc.Token.String,
 			VerificationUrl:    fmt.Sprintf("%s/check/%s", viper.GetString("FRONTEND_URL"), sc.Token.String),
+			EmploymentType:     sc.EmploymentType.String,
+			GuaranteeType:      sc.GuaranteeType.String,
+			Selection:          sc.Selection.String,
+			Documents:          service.CandidateDocumentsFromJSON(sc.DocumentsJson),
+			MissingDocuments:   service.MissingDocumentsFromJSON(sc.MissingDocuments),
+			Analysis:           service.IncomeAnalysisFromJSON(sc.AnalysisJson),
+			PolicyResults:      service.PolicyResultsFromJSON(sc.PolicyResults),
 		}
 	}
 
@@ -157,6 +175,17 @@ func (h *SolvencyHandler) ListChecks(c *gin.Context) {
 }
 
 // bindJSON is a helper to bind and handle common error
+func insufficientCredits(c *gin.Context, insErr *service.ErrInsufficientCredits) {
+	c.JSON(http.StatusPaymentRequired, gin.H{
+		"error":   "ERR_INSUFFICIENT_CREDITS",
+		"message": "Solde insuffisant. Veuillez recharger votre compte ou votre logement.",
+		"details": gin.H{
+			"global_balance":   insErr.GlobalBalance,
+			"property_balance": insErr.PropertyBalance,
+		},
+	})
+}
+
 func (h *SolvencyHandler) bindJSON(c *gin.Context, obj interface{}) error {
 	if err := c.ShouldBindJSON(obj); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -165,43 +194,6 @@ func (h *SolvencyHandler) bindJSON(c *gin.Context, obj interface{}) error {
 	return nil
 }
 
-type BuyCreditsRequest struct {
-	PackType string `json:"pack_type" binding:"required,oneof=pack_20"`
-}
-
-// BuyCredits godoc
-// @Summary      Purchase Credit Pack
-// @Description  Buy solvency check credits (e.g., pack_20)
-// @Tags         solvency
-// @Accept       json
-// @Produce      json
-// @Security     BearerAuth
-// @Param        request body map[string]interface{} true "Credit Pack Info"
-// @Success      200  {object}  map[string]interface{}
-// @Failure      400  {object}  map[string]string
-// @Router       /solvency/credits [post]
-func (h *SolvencyHandler) BuyCredits(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
-	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
-		return
-	}
-
-	var req BuyCreditsRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	amount, err := h.svc.PurchaseCredits(c.Request.Context(), userID, req.PackType)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{"message": "credits purchased", "added": amount})
-}
-
 // PublicSolvencyCheckResponse defines the public view of a solvency check
 type PublicSolvencyCheckResponse struct {
 	CandidateEmail     string  `json:"candidate_email"`
@@ -212,6 +204,11 @@ type PublicSolvencyCheckResponse struct {
 	PropertyAddress    string  `json:"property_address"`
 	PropertyName       string  `json:"property_name"`
 	RentAmount         float64 `json:"rent_amount"`
+	EmploymentType     string  `json:"employment_type,omitempty"`
+	GuaranteeType      string  `json:"guarantee_type,omitempty"`
+
+	Documents        []service.CandidateDocumentDTO `json:"documents"`
+	MissingDocuments []service.MissingDocument      `json:"missing_documents"`
 }
 
 // GetCheckByToken godoc
@@ -244,6 +241,10 @@ func (h *SolvencyHandler) GetCheckByToken(c *gin.Context) {
 		PropertyAddress:    check.PropertyAddress,
 		PropertyName:       check.PropertyName,
 		RentAmount:         check.PropertyRent,
+		EmploymentType:     check.EmploymentType.String,
+		GuaranteeType:      check.GuaranteeType.String,
+		Documents:          check.Documents,
+		MissingDocuments:   check.Missing,
 	})
 }
 
@@ -253,7 +254,8 @@ type OpenBankingCallback struct {
 
 // ProcessCallback godoc
 // @Summary      Open Banking Callback (Public)
-// @Description  Endpoint for mocking Open Banking data callback
+// @Description  Endpoint for mocking Open Banking data callback. Only enabled with the fake provider
+// @Description  (OPEN_BANKING_PROVIDER=fake): real checks go through the consent flow.
 // @Tags         solvency
 // @Accept       json
 // @Produce      json
@@ -271,6 +273,10 @@ func (h *SolvencyHandler) ProcessCallback(c *gin.Context) {
 
 	err := h.svc.ProcessOpenBankingResult(c.Request.Context(), token, req.Transactions)
 	if err != nil {
+		if errors.Is(err, service.ErrRawTransactionsDisabled) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -278,9 +284,124 @@ func (h *SolvencyHandler) ProcessCallback(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "callback processed successfully"})
 }
 
+func (h *SolvencyHandler) handleBankError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrOpenBankingUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case err.Error() == "check not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrCheckAlreadyProcessed), errors.Is(err, service.ErrBankConsentMissing):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrBankConsentMismatch):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+type BankConsentResponse struct {
+	ConsentURL string `json:"consent_url"`
+	ExpiresAt  string `json:"expires_at"`
+}
+
+// StartBankConsent godoc
+// @Summary      Start the bank connection (Public)
+// @Description  Opens a consent session with the open banking provider. The candidate is redirected to
+// @Description  consent_url, then back to the frontend with a code to send to the complete endpoint.
+// @Tags         solvency
+// @Produce      json
+// @Param        token path string true "Check Token"
+// @Success      200  {object}  BankConsentResponse
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /solvency/public/check/{token}/open-banking/consent [post]
+func (h *SolvencyHandler) StartBankConsent(c *gin.Context) {
+	token := c.Param("token")
+	// The redirect target is fixed server-side to avoid turning the provider into an open redirect
+	redirectURL := fmt.Sprintf("%s/check/%s/bank", viper.GetString("FRONTEND_URL"), token)
+
+	session, err := h.svc.StartBankConsent(c.Request.Context(), token, redirectURL)
+	if err != nil {
+		h.handleBankError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, BankConsentResponse{
+		ConsentURL: session.URL,
+		ExpiresAt:  session.ExpiresAt.Format(time.RFC3339),
+	})
+}
+
+type CompleteBankConsentRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// CompleteBankConsent godoc
+// @Summary      Complete the bank connection (Public)
+// @Description  Exchanges the authorization code returned by the provider and analyzes the candidate's
+// @Description  transactions. If the provider is still aggregating, the check stays pending until its webhook.
+// @Tags         solvency
+// @Accept       json
+// @Produce      json
+// @Param        token path string true "Check Token"
+// @Param        request body CompleteBankConsentRequest true "Authorization code"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /solvency/public/check/{token}/open-banking/complete [post]
+func (h *SolvencyHandler) CompleteBankConsent(c *gin.Context) {
+	token := c.Param("token")
+	var req CompleteBankConsentRequest
+	if err := h.bindJSON(c, &req); err != nil {
+		return
+	}
+
+	if err := h.svc.CompleteBankConsent(c.Request.Context(), token, req.Code); err != nil {
+		h.handleBankError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "bank connection completed"})
+}
+
+// OpenBankingWebhook godoc
+// @Summary      Open banking provider webhook
+// @Description  Notifications from the open banking provider. The signature header is verified against
+// @Description  OPEN_BANKING_WEBHOOK_SECRET and each event is processed once.
+// @Tags         solvency
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Router       /webhooks/open-banking [post]
+func (h *SolvencyHandler) OpenBankingWebhook(c *gin.Context) {
+	payload, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot read body"})
+		return
+	}
+
+	err = h.svc.HandleOpenBankingWebhook(c.Request.Context(), payload, c.Request.Header)
+	if err != nil {
+		switch {
+		case errors.Is(err, webhook.ErrInvalidSignature), errors.Is(err, webhook.ErrExpired):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrOpenBankingUnavailable):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		default:
+			// Let the provider retry: the event was released
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "webhook processing failed"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "ok"})
+}
+
 // CancelCheck godoc
 // @Summary      Cancel Solvency Check
-// @Description  Cancel a pending check and refund credit to property or global wallet
+// @Description  Cancel a pending check (or one stuck on insufficient documents) and refund credit to property or global wallet
 // @Tags         solvency
 // @Produce      json
 // @Security     BearerAuth
@@ -311,3 +432,200 @@ func (h *SolvencyHandler) CancelCheck(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "check cancelled and credit refunded"})
 }
+
+type RequestMissingDocumentsRequest struct {
+	Missing []service.MissingDocument `json:"missing" binding:"required,min=1,dive"`
+}
+
+func (h *SolvencyHandler) handleDocumentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrMediaTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrUnsupportedMediaType):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrCandidateDocumentNotFound), err.Error() == "check not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrCheckNotOwned):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrCheckClosedForDocuments), errors.Is(err, service.ErrCannotRequestMoreDocuments),
+		errors.Is(err, service.ErrCandidateDocumentLimit):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+// UploadCandidateDocument godoc
+// @Summary      Upload a candidate document (Public)
+// @Description  The candidate uploads a piece of the rental application through the check token (PDF, JPEG or PNG).
+// @Description  Types: identity, payslip, tax_notice, employment_contract. Files are encrypted at rest.
+// @Tags         solvency
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        token  path      string  true  "Check Token"
+// @Param        type   formData  string  true  "Document type"
+// @Param        file   formData  file    true  "Document"
+// @Success      201  {object}  service.CandidateDocumentDTO
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Failure      413  {object}  map[string]string
+// @Failure      415  {object}  map[string]string
+// @Router       /solvency/public/check/{token}/documents [post]
+func (h *SolvencyHandler) UploadCandidateDocument(c *gin.Context) {
+	filename, content, ok := readUploadFile(c, h.svc.MaxCandidateDocumentBytes)
+	if !ok {
+		return
+	}
+	docType := c.PostForm("type")
+	if docType == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type is required"})
+		return
+	}
+
+	doc, err := h.svc.UploadCandidateDocument(c.Request.Context(), c.Param("token"), docType, filename, content)
+	if err != nil {
+		h.handleDocumentError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, doc)
+}
+
+// DeleteCandidateDocument godoc
+// @Summary      Delete a candidate document (Public)
+// @Description  The candidate removes an uploaded document while the check is pending or missing documents
+// @Tags         solvency
+// @Produce      json
+// @Param        token  path  string  true  "Check Token"
+// @Param        docId  path  string  true  "Document ID"
+// @Success      200  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /solvency/public/check/{token}/documents/{docId} [delete]
+func (h *SolvencyHandler) DeleteCandidateDocument(c *gin.Context) {
+	if err := h.svc.DeleteCandidateDocument(c.Request.Context(), c.Param("token"), c.Param("docId")); err != nil {
+		h.handleDocumentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "document deleted"})
+}
+
+// DownloadCandidateDocument godoc
+// @Summary      Download a candidate document
+// @Description  The owner who initiated the check downloads a document uploaded by the candidate
+// @Tags         solvency
+// @Produce      octet-stream
+// @Security     BearerAuth
+// @Param        id     path  int     true  "Check ID"
+// @Param        docId  path  string  true  "Document ID"
+// @Success      200  {file}    file
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /solvency/check/{id}/documents/{docId} [get]
+func (h *SolvencyHandler) DownloadCandidateDocument(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	checkID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid check id"})
+		return
+	}
+
+	reader, doc, err := h.svc.OpenCandidateDocument(c.Request.Context(), userID, int32(checkID), c.Param("docId"))
+	if err != nil {
+		h.handleDocumentError(c, err)
+		return
+	}
+	defer reader.Close()
+
+	c.DataFromReader(http.StatusOK, -1, doc.ContentType, reader, map[string]string{
+		"Content-Disposition": fmt.Sprintf("inline; filename=%q", doc.Filename),
+		"Cache-Control":       "private, no-store",
+	})
+}
+
+// DownloadReport godoc
+// @Summary      Download the solvency report
+// @Description  Latest report of a processed check (PDF): candidate, property and rent, monthly income,
+// @Description  recurring charges, effort rate, score factors and document checklist. Each download is audited.
+// @Tags         solvency
+// @Produce      application/pdf
+// @Security     BearerAuth
+// @Param        id  path  int  true  "Check ID"
+// @Success      200  {file}    file
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /solvency/check/{id}/report [get]
+func (h *SolvencyHandler) DownloadReport(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	checkID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid check id"})
+		return
+	}
+
+	reader, doc, err := h.svc.OpenSolvencyReport(c.Request.Context(), userID, int32(checkID), c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrDocumentAccessDenied):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrDocumentNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "report not available yet"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	defer reader.Close()
+
+	c.DataFromReader(http.StatusOK, -1, doc.ContentType, reader, map[string]string{
+		"Content-Disposition": fmt.Sprintf("attachment; filename=%q", doc.Filename),
+		"Cache-Control":       "private, no-store",
+	})
+}
+
+// RequestMissingDocuments godoc
+// @Summary      Request missing documents
+// @Description  Move the check to 'insufficient_docs' and email the candidate the list of missing pieces.
+// @Description  The check returns to 'pending' once a document of each requested type is uploaded.
+// @Tags         solvency
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path  int                             true  "Check ID"
+// @Param        request  body  RequestMissingDocumentsRequest  true  "Missing pieces"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /solvency/check/{id}/insufficient-docs [post]
+func (h *SolvencyHandler) RequestMissingDocuments(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	checkID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid check id"})
+		return
+	}
+
+	var req RequestMissingDocumentsRequest
+	if err := h.bindJSON(c, &req); err != nil {
+		return
+	}
+
+	if err := h.svc.RequestMissingDocuments(c.Request.Context(), userID, int32(checkID), req.Missing); err != nil {
+		h.handleDocumentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "missing documents requested", "status": "insufficient_docs"})
+}
@@ -0,0 +1,235 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"seculoc-back/internal/adapter/http/middleware"
+	"seculoc-back/internal/core/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+type BuildDossierRequest struct {
+	CheckID int32 `json:"check_id" binding:"required"`
+}
+
+type ShareDossierRequest struct {
+	Label string `json:"label" binding:"max=255"`
+}
+
+type CheckFromDossierRequest struct {
+	ShareToken string `json:"share_token" binding:"required"`
+	PropertyID int32  `json:"property_id" binding:"required"`
+}
+
+func (h *SolvencyHandler) handleDossierError(c *gin.Context, err error) {
+	var insErr *service.ErrInsufficientCredits
+	switch {
+	case errors.As(err, &insErr):
+		insufficientCredits(c, insErr)
+	case err.Error() == "property not found or access denied",
+		errors.Is(err, service.ErrDossierNotFound), errors.Is(err, service.ErrDossierShareNotFound), errors.Is(err, service.ErrDossierShareInvalid):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrDossierSourceInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrDossierExpired):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// GetDossier godoc
+// @Summary      Get my tenant dossier
+// @Description  The candidate's portable dossier: documents, income analysis, validity and shares
+// @Tags         solvency
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  service.TenantDossierDTO
+// @Failure      404  {object}  map[string]string
+// @Router       /solvency/dossier [get]
+func (h *SolvencyHandler) GetDossier(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	dossier, err := h.svc.GetDossier(c.Request.Context(), userID)
+	if err != nil {
+		h.handleDossierError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dossier)
+}
+
+// BuildDossier godoc
+// @Summary      Build my tenant dossier
+// @Description  Builds (or replaces) the candidate's portable dossier from one of their completed solvency checks.
+// @Description  It is valid SOLVENCY_DOSSIER_VALIDITY_DAYS from the date of its bank data.
+// @Tags         solvency
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request  body  BuildDossierRequest  true  "Source check"
+// @Success      201  {object}  service.TenantDossierDTO
+// @Failure      400  {object}  map[string]string
+// @Failure      410  {object}  map[string]string
+// @Router       /solvency/dossier [post]
+func (h *SolvencyHandler) BuildDossier(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req BuildDossierRequest
+	if err := h.bindJSON(c, &req); err != nil {
+		return
+	}
+
+	dossier, err := h.svc.BuildDossier(c.Request.Context(), userID, req.CheckID)
+	if err != nil {
+		h.handleDossierError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, dossier)
+}
+
+// DeleteDossier godoc
+// @Summary      Delete my tenant dossier
+// @Description  Deletes the dossier, its documents and its shares. Checks already created from it are kept.
+// @Tags         solvency
+// @Security     BearerAuth
+// @Success      204
+// @Failure      404  {object}  map[string]string
+// @Router       /solvency/dossier [delete]
+func (h *SolvencyHandler) DeleteDossier(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := h.svc.DeleteDossier(c.Request.Context(), userID); err != nil {
+		h.handleDossierError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ShareDossier godoc
+// @Summary      Share my tenant dossier
+// @Description  Creates a revocable share token to hand to an owner
+// @Tags         solvency
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request  body  ShareDossierRequest  false  "Share"
+// @Success      201  {object}  service.DossierShareDTO
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      410  {object}  map[string]string
+// @Router       /solvency/dossier/shares [post]
+func (h *SolvencyHandler) ShareDossier(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req ShareDossierRequest
+	if c.Request.ContentLength > 0 {
+		if err := h.bindJSON(c, &req); err != nil {
+			return
+		}
+	}
+
+	share, err := h.svc.ShareDossier(c.Request.Context(), userID, req.Label)
+	if err != nil {
+		h.handleDossierError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, share)
+}
+
+// RevokeDossierShare godoc
+// @Summary      Revoke a share of my tenant dossier
+// @Description  The token can no longer be used; checks already created with it are kept
+// @Tags         solvency
+// @Security     BearerAuth
+// @Param        shareId  path  int  true  "Share ID"
+// @Success      204
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /solvency/dossier/shares/{shareId} [delete]
+func (h *SolvencyHandler) RevokeDossierShare(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	shareID, err := strconv.Atoi(c.Param("shareId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid share id"})
+		return
+	}
+
+	if err := h.svc.RevokeDossierShare(c.Request.Context(), userID, int32(shareID)); err != nil {
+		h.handleDossierError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// CreateCheckFromDossier godoc
+// @Summary      Initiate a solvency check from a shared dossier
+// @Description  Completes a check at once from a tenant dossier shared with the owner: the analysis is re-scored
+// @Description  against the property rent and the owner's policy applied. Consumes a credit only when
+// @Description  SOLVENCY_DOSSIER_CONSUMES_CREDIT is set.
+// @Tags         solvency
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        Idempotency-Key header string false "Unique key of the request: a retry with the same key replays the first response"
+// @Param        request  body  CheckFromDossierRequest  true  "Share token and property"
+// @Success      201  {object}  SolvencyCheckResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      402  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]string
+// @Failure      410  {object}  map[string]string
+// @Router       /solvency/check/from-dossier [post]
+func (h *SolvencyHandler) CreateCheckFromDossier(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req CheckFromDossierRequest
+	if err := h.bindJSON(c, &req); err != nil {
+		return
+	}
+
+	check, err := h.svc.InitiateCheckFromDossier(c.Request.Context(), userID, req.PropertyID, req.ShareToken)
+	if err != nil {
+		h.handleDossierError(c, err)
+		return
+	}
+
+	baseURL := viper.GetString("FRONTEND_URL")
+	if baseURL == "" {
+		baseURL = "https://seculoc.com"
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"id":               check.ID,
+		"status":           check.Status.SolvencyStatus,
+		"score_result":     check.ScoreResult.Int32,
+		"token":            check.Token.String,
+		"verification_url": fmt.Sprintf("%s/check/%s", baseURL, check.Token.String),
+	})
+}
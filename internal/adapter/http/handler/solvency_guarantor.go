@@ -0,0 +1,455 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"seculoc-back/internal/adapter/http/middleware"
+	"seculoc-back/internal/core/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+type GuarantorRequest struct {
+	Email     string `json:"email" binding:"required,email"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Phone     string `json:"phone"`
+}
+
+func (r GuarantorRequest) toParams() service.GuarantorParams {
+	return service.GuarantorParams{
+		Email:     r.Email,
+		FirstName: r.FirstName,
+		LastName:  r.LastName,
+		Phone:     r.Phone,
+	}
+}
+
+func (h *SolvencyHandler) handleGuarantorError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrGuarantorNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrGuarantorLimit), errors.Is(err, service.ErrGuarantorAlreadyAnalyzed),
+		errors.Is(err, service.ErrGuarantorBoundToLease), errors.Is(err, service.ErrCheckClosedForGuarantors),
+		errors.Is(err, service.ErrGuaranteeDeedNotReady), errors.Is(err, service.ErrGuaranteeMentionSigned),
+		errors.Is(err, service.ErrGuarantorDocsSubmitted):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrGuaranteeMentionMismatch), errors.Is(err, service.ErrGuarantorDocsIncomplete):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrMediaTooLarge), errors.Is(err, service.ErrUnsupportedMediaType),
+		errors.Is(err, service.ErrCandidateDocumentNotFound), errors.Is(err, service.ErrCandidateDocumentLimit),
+		errors.Is(err, service.ErrCheckNotOwned):
+		h.handleDocumentError(c, err)
+	default:
+		h.handleBankError(c, err)
+	}
+}
+
+// guarantorPath reads the :id check and :guarantorId parameters of the owner routes.
+func guarantorPath(c *gin.Context) (checkID, guarantorID int32, ok bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid check id"})
+		return 0, 0, false
+	}
+	if c.Param("guarantorId") == "" {
+		return int32(id), 0, true
+	}
+	gid, err := strconv.Atoi(c.Param("guarantorId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid guarantor id"})
+		return 0, 0, false
+	}
+	return int32(id), int32(gid), true
+}
+
+// AddGuarantor godoc
+// @Summary      Add a guarantor to a check
+// @Description  Invites a guarantor (caution) by email with their own link, where they upload their documents or
+// @Description  connect their bank. At most 2 guarantors per check.
+// @Tags         solvency
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path  int               true  "Check ID"
+// @Param        request  body  GuarantorRequest  true  "Guarantor"
+// @Success      201  {object}  service.GuarantorDTO
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /solvency/check/{id}/guarantors [post]
+func (h *SolvencyHandler) AddGuarantor(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	checkID, _, ok := guarantorPath(c)
+	if !ok {
+		return
+	}
+	var req GuarantorRequest
+	if err := h.bindJSON(c, &req); err != nil {
+		return
+	}
+
+	guarantor, err := h.svc.AddGuarantor(c.Request.Context(), userID, checkID, req.toParams())
+	if err != nil {
+		h.handleGuarantorError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, guarantor)
+}
+
+// AddGuarantorByToken godoc
+// @Summary      Add a guarantor (Public)
+// @Description  The candidate invites their guarantor through the check token. At most 2 guarantors per check.
+// @Tags         solvency
+// @Accept       json
+// @Produce      json
+// @Param        token    path  string            true  "Check Token"
+// @Param        request  body  GuarantorRequest  true  "Guarantor"
+// @Success      201  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /solvency/public/check/{token}/guarantors [post]
+func (h *SolvencyHandler) AddGuarantorByToken(c *gin.Context) {
+	var req GuarantorRequest
+	if err := h.bindJSON(c, &req); err != nil {
+		return
+	}
+
+	// The guarantor's own link is only sent to them: the candidate does not get it back
+	if _, err := h.svc.AddGuarantorByToken(c.Request.Context(), c.Param("token"), req.toParams()); err != nil {
+		h.handleGuarantorError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "guarantor invited"})
+}
+
+// ListGuarantors godoc
+// @Summary      List the guarantors of a check
+// @Description  Guarantors with their status, documents and income analysis
+// @Tags         solvency
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path  int  true  "Check ID"
+// @Success      200  {array}   service.GuarantorDTO
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /solvency/check/{id}/guarantors [get]
+func (h *SolvencyHandler) ListGuarantors(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	checkID, _, ok := guarantorPath(c)
+	if !ok {
+		return
+	}
+
+	guarantors, err := h.svc.ListGuarantors(c.Request.Context(), userID, checkID)
+	if err != nil {
+		h.handleGuarantorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, guarantors)
+}
+
+// RemoveGuarantor godoc
+// @Summary      Remove a guarantor
+// @Description  Removes a guarantor not yet bound to a lease. A processed check is re-decided without them.
+// @Tags         solvency
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id           path  int  true  "Check ID"
+// @Param        guarantorId  path  int  true  "Guarantor ID"
+// @Success      200  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /solvency/check/{id}/guarantors/{guarantorId} [delete]
+func (h *SolvencyHandler) RemoveGuarantor(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	checkID, guarantorID, ok := guarantorPath(c)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.RemoveGuarantor(c.Request.Context(), userID, checkID, guarantorID); err != nil {
+		h.handleGuarantorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "guarantor removed"})
+}
+
+// DownloadGuarantorDocument godoc
+// @Summary      Download a guarantor document
+// @Description  The owner who initiated the check downloads a document uploaded by a guarantor
+// @Tags         solvency
+// @Produce      octet-stream
+// @Security     BearerAuth
+// @Param        id           path  int     true  "Check ID"
+// @Param        guarantorId  path  int     true  "Guarantor ID"
+// @Param        docId        path  string  true  "Document ID"
+// @Success      200  {file}    file
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /solvency/check/{id}/guarantors/{guarantorId}/documents/{docId} [get]
+func (h *SolvencyHandler) DownloadGuarantorDocument(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	checkID, guarantorID, ok := guarantorPath(c)
+	if !ok {
+		return
+	}
+
+	reader, doc, err := h.svc.OpenGuarantorDocument(c.Request.Context(), userID, checkID, guarantorID, c.Param("docId"))
+	if err != nil {
+		h.handleGuarantorError(c, err)
+		return
+	}
+	defer reader.Close()
+
+	c.DataFromReader(http.StatusOK, -1, doc.ContentType, reader, map[string]string{
+		"Content-Disposition": fmt.Sprintf("inline; filename=%q", doc.Filename),
+		"Cache-Control":       "private, no-store",
+	})
+}
+
+// GetGuarantorByToken godoc
+// @Summary      Get the guarantor's request (Public)
+// @Description  What the guarantor sees from their link: candidate, property, rent and uploaded documents
+// @Tags         solvency
+// @Produce      json
+// @Param        token  path  string  true  "Guarantor Token"
+// @Success      200  {object}  service.GuarantorPublicView
+// @Failure      404  {object}  map[string]string
+// @Router       /solvency/public/guarantor/{token} [get]
+func (h *SolvencyHandler) GetGuarantorByToken(c *gin.Context) {
+	view, err := h.svc.GetGuarantorByToken(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		h.handleGuarantorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, view)
+}
+
+// UploadGuarantorDocument godoc
+// @Summary      Upload a guarantor document (Public)
+// @Description  Same document types and formats as the candidate. Files are encrypted at rest.
+// @Tags         solvency
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        token  path      string  true  "Guarantor Token"
+// @Param        type   formData  string  true  "Document type"
+// @Param        file   formData  file    true  "Document"
+// @Success      201  {object}  service.CandidateDocumentDTO
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Failure      413  {object}  map[string]string
+// @Failure      415  {object}  map[string]string
+// @Router       /solvency/public/guarantor/{token}/documents [post]
+func (h *SolvencyHandler) UploadGuarantorDocument(c *gin.Context) {
+	filename, content, ok := readUploadFile(c, h.svc.MaxCandidateDocumentBytes)
+	if !ok {
+		return
+	}
+	docType := c.PostForm("type")
+	if docType == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type is required"})
+		return
+	}
+
+	doc, err := h.svc.UploadGuarantorDocument(c.Request.Context(), c.Param("token"), docType, filename, content)
+	if err != nil {
+		h.handleGuarantorError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, doc)
+}
+
+// DeleteGuarantorDocument godoc
+// @Summary      Delete a guarantor document (Public)
+// @Tags         solvency
+// @Produce      json
+// @Param        token  path  string  true  "Guarantor Token"
+// @Param        docId  path  string  true  "Document ID"
+// @Success      200  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /solvency/public/guarantor/{token}/documents/{docId} [delete]
+func (h *SolvencyHandler) DeleteGuarantorDocument(c *gin.Context) {
+	if err := h.svc.DeleteGuarantorDocument(c.Request.Context(), c.Param("token"), c.Param("docId")); err != nil {
+		h.handleGuarantorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "document deleted"})
+}
+
+// SubmitGuarantorDocuments godoc
+// @Summary      Submit the guarantor's documents (Public)
+// @Description  Completes the file of a guarantor who does not connect their bank: an identity document and a
+// @Description  proof of income (payslip, tax notice or employment contract) must have been uploaded. The guarantor
+// @Description  is then bound to the lease once signed and receives their guarantee deed.
+// @Tags         solvency
+// @Produce      json
+// @Param        token  path  string  true  "Guarantor Token"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /solvency/public/guarantor/{token}/submit [post]
+func (h *SolvencyHandler) SubmitGuarantorDocuments(c *gin.Context) {
+	if err := h.svc.SubmitGuarantorDocuments(c.Request.Context(), c.Param("token")); err != nil {
+		h.handleGuarantorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "guarantor documents submitted"})
+}
+
+// StartGuarantorBankConsent godoc
+// @Summary      Start the guarantor's bank connection (Public)
+// @Description  Same flow as the candidate's: redirect to consent_url, then send the returned code to complete.
+// @Tags         solvency
+// @Produce      json
+// @Param        token  path  string  true  "Guarantor Token"
+// @Success      200  {object}  BankConsentResponse
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /solvency/public/guarantor/{token}/open-banking/consent [post]
+func (h *SolvencyHandler) StartGuarantorBankConsent(c *gin.Context) {
+	token := c.Param("token")
+	redirectURL := fmt.Sprintf("%s/guarantor/%s/bank", viper.GetString("FRONTEND_URL"), token)
+
+	session, err := h.svc.StartGuarantorBankConsent(c.Request.Context(), token, redirectURL)
+	if err != nil {
+		h.handleGuarantorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, BankConsentResponse{
+		ConsentURL: session.URL,
+		ExpiresAt:  session.ExpiresAt.Format(time.RFC3339),
+	})
+}
+
+// CompleteGuarantorBankConsent godoc
+// @Summary      Complete the guarantor's bank connection (Public)
+// @Description  Analyzes the guarantor's income. A check already decided is re-decided with the combined score.
+// @Tags         solvency
+// @Accept       json
+// @Produce      json
+// @Param        token    path  string                      true  "Guarantor Token"
+// @Param        request  body  CompleteBankConsentRequest  true  "Authorization code"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /solvency/public/guarantor/{token}/open-banking/complete [post]
+func (h *SolvencyHandler) CompleteGuarantorBankConsent(c *gin.Context) {
+	var req CompleteBankConsentRequest
+	if err := h.bindJSON(c, &req); err != nil {
+		return
+	}
+
+	if err := h.svc.CompleteGuarantorBankConsent(c.Request.Context(), c.Param("token"), req.Code); err != nil {
+		h.handleGuarantorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "bank connection completed"})
+}
+
+// GetGuaranteeMention godoc
+// @Summary      Get the mention to reproduce (Public)
+// @Description  Once the lease is signed, the guarantor reproduces this mention (art. 22-1 of the law of
+// @Description  6 July 1989) to complete the guarantee deed.
+// @Tags         solvency
+// @Produce      json
+// @Param        token  path  string  true  "Guarantor Token"
+// @Success      200  {object}  service.GuaranteeMentionDTO
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /solvency/public/guarantor/{token}/mention [get]
+func (h *SolvencyHandler) GetGuaranteeMention(c *gin.Context) {
+	mention, err := h.svc.GetGuaranteeMention(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		h.handleGuarantorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, mention)
+}
+
+type GuaranteeMentionRequest struct {
+	Text string `json:"text" binding:"required"`
+}
+
+// SignGuaranteeMention godoc
+// @Summary      Reproduce the mention (Public)
+// @Description  The text must match the mention (case, accents and punctuation ignored). A new version of
+// @Description  the deed including it is stored.
+// @Tags         solvency
+// @Accept       json
+// @Produce      json
+// @Param        token    path  string                   true  "Guarantor Token"
+// @Param        request  body  GuaranteeMentionRequest  true  "Reproduced mention"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /solvency/public/guarantor/{token}/mention [post]
+func (h *SolvencyHandler) SignGuaranteeMention(c *gin.Context) {
+	var req GuaranteeMentionRequest
+	if err := h.bindJSON(c, &req); err != nil {
+		return
+	}
+
+	if err := h.svc.SignGuaranteeMention(c.Request.Context(), c.Param("token"), req.Text); err != nil {
+		h.handleGuarantorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "mention signed"})
+}
+
+// DownloadGuaranteeDeed godoc
+// @Summary      Download the guarantee deed (Public)
+// @Description  Latest version of the guarantor's deed (acte de cautionnement)
+// @Tags         solvency
+// @Produce      html
+// @Param        token  path  string  true  "Guarantor Token"
+// @Success      200  {file}    file
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /solvency/public/guarantor/{token}/deed [get]
+func (h *SolvencyHandler) DownloadGuaranteeDeed(c *gin.Context) {
+	reader, doc, err := h.svc.OpenGuaranteeDeed(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		if errors.Is(err, service.ErrDocumentNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.handleGuarantorError(c, err)
+		return
+	}
+	defer reader.Close()
+
+	c.DataFromReader(http.StatusOK, -1, doc.ContentType, reader, map[string]string{
+		"Content-Disposition": fmt.Sprintf("inline; filename=%q", doc.Filename),
+		"Cache-Control":       "private, no-store",
+	})
+}
@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"seculoc-back/internal/adapter/http/middleware"
+	"seculoc-back/internal/core/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SolvencyPolicyRequest struct {
+	IncomeMultiplier     float64  `json:"income_multiplier" binding:"gt=0,lte=10"`
+	IncludeCharges       bool     `json:"include_charges"`
+	CountGuarantorIncome bool     `json:"count_guarantor_income"`
+	MinEmploymentType    string   `json:"min_employment_type" binding:"omitempty,oneof=civil_servant cdi retired cdd self_employed interim student unemployed"`
+	AcceptedGuarantees   []string `json:"accepted_guarantees" binding:"omitempty,dive,oneof=visale garantme guarantor bank_guarantee"`
+	RequireGuarantee     bool     `json:"require_guarantee"`
+}
+
+func (r SolvencyPolicyRequest) toPolicy() service.SolvencyPolicy {
+	return service.SolvencyPolicy{
+		IncomeMultiplier:     r.IncomeMultiplier,
+		IncludeCharges:       r.IncludeCharges,
+		CountGuarantorIncome: r.CountGuarantorIncome,
+		MinEmploymentType:    r.MinEmploymentType,
+		AcceptedGuarantees:   r.AcceptedGuarantees,
+		RequireGuarantee:     r.RequireGuarantee,
+	}
+}
+
+func (h *SolvencyHandler) handlePolicyError(c *gin.Context, err error) {
+	switch {
+	case err.Error() == "property not found or access denied":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidEmploymentType), errors.Is(err, service.ErrInvalidGuaranteeType),
+		errors.Is(err, service.ErrInvalidIncomeMultiplier):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// policyPropertyID reads the optional :id property parameter (0 for the owner's default policy).
+func policyPropertyID(c *gin.Context) (int32, bool) {
+	if c.Param("id") == "" {
+		return 0, true
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid property id"})
+		return 0, false
+	}
+	return int32(id), true
+}
+
+// GetPolicy godoc
+// @Summary      Get the solvency acceptance policy
+// @Description  Returns the owner's default policy (/solvency/policy) or the policy applying to a property
+// @Description  (/properties/{id}/solvency-policy): its own override, else the owner's default. scope tells which one.
+// @Tags         solvency
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      int  false  "Property ID"
+// @Success      200  {object}  service.SolvencyPolicy
+// @Failure      404  {object}  map[string]string
+// @Router       /solvency/policy [get]
+// @Router       /properties/{id}/solvency-policy [get]
+func (h *SolvencyHandler) GetPolicy(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	propertyID, ok := policyPropertyID(c)
+	if !ok {
+		return
+	}
+
+	policy, err := h.svc.GetPolicy(c.Request.Context(), userID, propertyID)
+	if err != nil {
+		h.handlePolicyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, policy)
+}
+
+// SetPolicy godoc
+// @Summary      Set the solvency acceptance policy
+// @Description  Replaces the owner's default policy or a property's policy. A candidate is approved when the
+// @Description  score reaches SOLVENCY_MIN_SCORE and every rule passes: income >= income_multiplier x rent
+// @Description  (charges included or not, guarantor income counted or not; 0 disables it), minimum employment
+// @Description  type, accepted guarantees.
+// @Tags         solvency
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path  int                    false  "Property ID"
+// @Param        request  body  SolvencyPolicyRequest  true   "Policy"
+// @Success      200  {object}  service.SolvencyPolicy
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /solvency/policy [put]
+// @Router       /properties/{id}/solvency-policy [put]
+func (h *SolvencyHandler) SetPolicy(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	propertyID, ok := policyPropertyID(c)
+	if !ok {
+		return
+	}
+	var req SolvencyPolicyRequest
+	if err := h.bindJSON(c, &req); err != nil {
+		return
+	}
+
+	policy, err := h.svc.SetPolicy(c.Request.Context(), userID, propertyID, req.toPolicy())
+	if err != nil {
+		h.handlePolicyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, policy)
+}
+
+// DeletePropertyPolicy godoc
+// @Summary      Remove a property's solvency policy
+// @Description  The owner's default policy applies again to the property's checks
+// @Tags         solvency
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      int  true  "Property ID"
+// @Success      200  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /properties/{id}/solvency-policy [delete]
+func (h *SolvencyHandler) DeletePropertyPolicy(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	propertyID, ok := policyPropertyID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.DeletePropertyPolicy(c.Request.Context(), userID, propertyID); err != nil {
+		h.handlePolicyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "property policy removed"})
+}
+
+type CandidateProfileRequest struct {
+	EmploymentType string `json:"employment_type" binding:"required,oneof=civil_servant cdi retired cdd self_employed interim student unemployed"`
+	GuaranteeType  string `json:"guarantee_type" binding:"omitempty,oneof=visale garantme guarantor bank_guarantee"`
+}
+
+// UpdateCandidateProfile godoc
+// @Summary      Declare the candidate's situation (Public)
+// @Description  Employment type and offered guarantee, evaluated against the owner's acceptance policy
+// @Tags         solvency
+// @Accept       json
+// @Produce      json
+// @Param        token    path  string                   true  "Check Token"
+// @Param        request  body  CandidateProfileRequest  true  "Profile"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /solvency/public/check/{token}/profile [put]
+func (h *SolvencyHandler) UpdateCandidateProfile(c *gin.Context) {
+	var req CandidateProfileRequest
+	if err := h.bindJSON(c, &req); err != nil {
+		return
+	}
+
+	if err := h.svc.UpdateCandidateProfile(c.Request.Context(), c.Param("token"), req.EmploymentType, req.GuaranteeType); err != nil {
+		h.handleBankError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "profile updated"})
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"seculoc-back/internal/adapter/http/middleware"
+	"seculoc-back/internal/core/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CandidateSelectionRequest struct {
+	Selection string `json:"selection" binding:"required,oneof=shortlisted declined none"`
+}
+
+func (h *SolvencyHandler) handleSelectionError(c *gin.Context, err error) {
+	switch {
+	case err.Error() == "property not found or access denied", err.Error() == "check not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrCheckNotOwned):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidSelection):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrCheckNotCompleted), errors.Is(err, service.ErrCandidateDeclined):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// CompareCandidates godoc
+// @Summary      Compare the candidates of a property
+// @Description  Ranks the completed checks of the property on a 0-100 ranking score weighing the solvency score (35),
+// @Description  the effort rate (25), the income stability (15), the completeness of the file (10) and the guarantee (15).
+// @Description  Each signal gives the gap to the best candidate and highlights sum up the differences.
+// @Description  Declined candidates are listed last, unranked.
+// @Tags         solvency
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      int  true  "Property ID"
+// @Success      200  {object}  service.CandidateComparison
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /properties/{id}/candidates [get]
+func (h *SolvencyHandler) CompareCandidates(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid property id"})
+		return
+	}
+
+	comparison, err := h.svc.CompareCandidates(c.Request.Context(), userID, int32(id))
+	if err != nil {
+		h.handleSelectionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, comparison)
+}
+
+// SetCandidateSelection godoc
+// @Summary      Shortlist or decline a candidate
+// @Description  Marks the candidate of a completed check as shortlisted or declined ("none" clears the shortlist).
+// @Description  A declined candidate is notified by email, without the reasons nor their score, and cannot be shortlisted again.
+// @Tags         solvency
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path  int                        true  "Check ID"
+// @Param        request  body  CandidateSelectionRequest  true  "Selection"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /solvency/check/{id}/selection [put]
+func (h *SolvencyHandler) SetCandidateSelection(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid check id"})
+		return
+	}
+
+	var req CandidateSelectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.svc.SetCandidateSelection(c.Request.Context(), userID, int32(id), req.Selection); err != nil {
+		h.handleSelectionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"selection": req.Selection})
+}
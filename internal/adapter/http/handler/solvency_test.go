@@ -33,6 +33,11 @@ func TestInitiateCheck_Validation(t *testing.T) {
 			payload:    `{"candidate_email": "not-email", "property_id": 1}`,
 			expectCode: http.StatusBadRequest,
 		},
+		{
+			name:       "Expiry Too Long",
+			payload:    `{"candidate_email": "test@example.com", "property_id": 1, "expires_in_days": 90}`,
+			expectCode: http.StatusBadRequest,
+		},
 	}
 
 	for _, tt := range tests {
@@ -55,22 +60,196 @@ func TestInitiateCheck_Validation(t *testing.T) {
 	}
 }
 
-func TestPurchaseCredits_Validation(t *testing.T) {
+func TestRequestMissingDocuments_Validation(t *testing.T) {
 	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		path       string
+		payload    string
+		expectCode int
+	}{
+		{name: "Invalid Check ID", path: "/solvency/check/abc/insufficient-docs", payload: `{"missing": [{"type": "payslip"}]}`, expectCode: http.StatusBadRequest},
+		{name: "Empty List", path: "/solvency/check/1/insufficient-docs", payload: `{"missing": []}`, expectCode: http.StatusBadRequest},
+		{name: "Missing Type", path: "/solvency/check/1/insufficient-docs", payload: `{"missing": [{"comment": "illisible"}]}`, expectCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewSolvencyHandler(nil)
+			r := gin.New()
+			r.Use(func(c *gin.Context) {
+				c.Set("userID", int32(1))
+				c.Next()
+			})
+			r.POST("/solvency/check/:id/insufficient-docs", h.RequestMissingDocuments)
+
+			req, _ := http.NewRequest("POST", tt.path, bytes.NewBufferString(tt.payload))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+		})
+	}
+}
+
+func TestCompleteBankConsent_Validation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
 	h := NewSolvencyHandler(nil)
 	r := gin.New()
-	r.Use(func(c *gin.Context) {
-		c.Set("userID", int32(1))
-		c.Next()
-	})
-	r.POST("/solvency/credits", h.BuyCredits)
+	r.POST("/solvency/public/check/:token/open-banking/complete", h.CompleteBankConsent)
 
-	// Invalid Pack
-	req, _ := http.NewRequest("POST", "/solvency/credits", bytes.NewBufferString(`{"pack_type": "invalid"}`))
+	req, _ := http.NewRequest("POST", "/solvency/public/check/tok/open-banking/complete", bytes.NewBufferString(`{}`))
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 	r.ServeHTTP(w, req)
+
 	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestSetPolicy_Validation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		path       string
+		payload    string
+		expectCode int
+	}{
+		{name: "Invalid Property ID", path: "/properties/abc/solvency-policy", payload: `{"income_multiplier": 3}`, expectCode: http.StatusBadRequest},
+		{name: "Multiplier Too High", path: "/properties/1/solvency-policy", payload: `{"income_multiplier": 20}`, expectCode: http.StatusBadRequest},
+		{name: "Negative Multiplier", path: "/properties/1/solvency-policy", payload: `{"income_multiplier": -2}`, expectCode: http.StatusBadRequest},
+		{name: "Missing Multiplier", path: "/properties/1/solvency-policy", payload: `{"include_charges": true}`, expectCode: http.StatusBadRequest},
+		{name: "Unknown Employment Type", path: "/properties/1/solvency-policy", payload: `{"min_employment_type": "astronaut"}`, expectCode: http.StatusBadRequest},
+		{name: "Unknown Guarantee", path: "/properties/1/solvency-policy", payload: `{"accepted_guarantees": ["visale", "bitcoin"]}`, expectCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewSolvencyHandler(nil)
+			r := gin.New()
+			r.Use(func(c *gin.Context) {
+				c.Set("userID", int32(1))
+				c.Next()
+			})
+			r.PUT("/properties/:id/solvency-policy", h.SetPolicy)
+
+			req, _ := http.NewRequest("PUT", tt.path, bytes.NewBufferString(tt.payload))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+		})
+	}
+}
+
+func TestUpdateCandidateProfile_Validation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	h := NewSolvencyHandler(nil)
+	r := gin.New()
+	r.PUT("/solvency/public/check/:token/profile", h.UpdateCandidateProfile)
 
-	// Missing field handled by oneof validation or required
+	req, _ := http.NewRequest("PUT", "/solvency/public/check/tok/profile", bytes.NewBufferString(`{"guarantee_type": "visale"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAddGuarantor_Validation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		path       string
+		payload    string
+		expectCode int
+	}{
+		{name: "Invalid Check ID", path: "/solvency/check/abc/guarantors", payload: `{"email": "garant@example.com"}`, expectCode: http.StatusBadRequest},
+		{name: "Missing Email", path: "/solvency/check/1/guarantors", payload: `{"first_name": "Marie"}`, expectCode: http.StatusBadRequest},
+		{name: "Invalid Email", path: "/solvency/check/1/guarantors", payload: `{"email": "not-an-email"}`, expectCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewSolvencyHandler(nil)
+			r := gin.New()
+			r.Use(func(c *gin.Context) {
+				c.Set("userID", int32(1))
+				c.Next()
+			})
+			r.POST("/solvency/check/:id/guarantors", h.AddGuarantor)
+
+			req, _ := http.NewRequest("POST", tt.path, bytes.NewBufferString(tt.payload))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+		})
+	}
+}
+
+func TestSignGuaranteeMention_Validation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	h := NewSolvencyHandler(nil)
+	r := gin.New()
+	r.POST("/solvency/public/guarantor/:token/mention", h.SignGuaranteeMention)
+
+	req, _ := http.NewRequest("POST", "/solvency/public/guarantor/tok/mention", bytes.NewBufferString(`{"text": ""}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestSetCandidateSelection_Validation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewSolvencyHandler(nil)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", int32(1))
+		c.Next()
+	})
+	r.PUT("/solvency/check/:id/selection", h.SetCandidateSelection)
+
+	for _, payload := range []string{`{}`, `{"selection": "maybe"}`} {
+		req, _ := http.NewRequest("PUT", "/solvency/check/7/selection", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, payload)
+	}
+}
+
+func TestCreateCheckFromDossier_Validation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewSolvencyHandler(nil)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", int32(1))
+		c.Next()
+	})
+	r.POST("/solvency/check/from-dossier", h.CreateCheckFromDossier)
+	r.POST("/solvency/dossier", h.BuildDossier)
+
+	for path, payload := range map[string]string{
+		"/solvency/check/from-dossier": `{"property_id": 1}`,
+		"/solvency/dossier":            `{}`,
+	} {
+		req, _ := http.NewRequest("POST", path, bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, path)
+	}
 }
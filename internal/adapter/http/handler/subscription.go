@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 
 	"seculoc-back/internal/adapter/http/middleware"
@@ -21,8 +22,10 @@ func NewSubscriptionHandler(svc *service.SubscriptionService, userSvc *service.U
 }
 
 type SubscribeRequest struct {
-	Plan      string `json:"plan" binding:"required,oneof=discovery serenity premium"`
+	Plan      string `json:"plan" binding:"required,max=50"`
 	Frequency string `json:"frequency" binding:"required,oneof=monthly yearly"`
+	// PaymentMethodID pays the first period of paid plans; the saved payment method or SEPA mandate is used when empty
+	PaymentMethodID string `json:"payment_method_id" binding:"max=100"`
 }
 
 type SubscriptionResponse struct {
@@ -33,18 +36,27 @@ type SubscriptionResponse struct {
 			OwnerProfile service.UserProfile `json:"owner_profile"`
 		} `json:"user"`
 	} `json:"data"`
+	// Payment of the first period, for paid plans
+	Payment *service.PaymentResult `json:"payment,omitempty"`
 }
 
 // Subscribe godoc
 // @Summary      Subscribe to a plan
-// @Description  Subscribe user to Discovery, Serenity, or Premium plan
+// @Description  Subscribe user to a plan of the catalog (Discovery, Serenity, Premium..., see GET /plans).
+// @Description  Paid plans start once their first period is paid: 202 means the payment awaits 3-D Secure
+// @Description  (see payment.next_action_url) and the subscription is activated by the provider's webhook,
+// @Description  402 that it failed.
 // @Tags         subscriptions
 // @Accept       json
 // @Produce      json
 // @Security     BearerAuth
-// @Param        request body map[string]interface{} true "Subscription Info"
+// @Param        Idempotency-Key header string false "Unique key of the request: a retry with the same key replays the first response"
+// @Param        request body SubscribeRequest true "Subscription Info"
 // @Success      200  {object}  SubscriptionResponse
+// @Success      202  {object}  map[string]interface{}
 // @Failure      400  {object}  map[string]string
+// @Failure      402  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]string  "A subscription is already in progress: change its plan instead"
 // @Router       /subscriptions [post]
 func (h *SubscriptionHandler) Subscribe(c *gin.Context) {
 	userID, ok := middleware.GetUserID(c)
@@ -59,12 +71,34 @@ func (h *SubscriptionHandler) Subscribe(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if !service.ValidCatalogCode(req.Plan) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid plan"})
+		return
+	}
 
-	err := h.svc.SubscribeUser(c.Request.Context(), userID, req.Plan, req.Frequency)
+	payment, err := h.svc.SubscribeUser(c.Request.Context(), userID, req.Plan, req.Frequency, req.PaymentMethodID)
+	if errors.Is(err, service.ErrPaymentUnavailable) {
+		// Refused before anything was recorded
+		middleware.ReleaseIdempotencyKey(c)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrSubscriptionExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if code := paymentStatusCode(payment); code != http.StatusOK {
+		status := "payment_pending"
+		if code == http.StatusPaymentRequired {
+			status = "payment_failed"
+		}
+		c.JSON(code, gin.H{"status": status, "payment": payment})
+		return
+	}
 
 	// Fetch updated user profile
 	user, err := h.userSvc.GetUserByID(c.Request.Context(), userID)
@@ -100,7 +134,8 @@ func (h *SubscriptionHandler) Subscribe(c *gin.Context) {
 	}
 
 	response := SubscriptionResponse{
-		Status: "success",
+		Status:  "success",
+		Payment: payment,
 	}
 	response.Data.User.SafeUser = safeUser
 	response.Data.User.OwnerProfile = authResp.Profile
@@ -111,19 +146,33 @@ func (h *SubscriptionHandler) Subscribe(c *gin.Context) {
 }
 
 type UpgradeLimitRequest struct {
-	AdditionalSlots int32 `json:"additional_slots" binding:"required,min=1"`
+	AdditionalSlots int32 `json:"additional_slots" binding:"required,min=1,max=100"`
+	// PaymentMethodID pays the prorata of the extra properties; the saved payment method or SEPA mandate is used when empty
+	PaymentMethodID string `json:"payment_method_id" binding:"max=100"`
+}
+
+type RemoveSlotsRequest struct {
+	Slots int32 `json:"slots" binding:"required,min=1,max=100"`
 }
 
 // IncreaseLimit godoc
 // @Summary      Increase property limit
-// @Description  Purchase additional property slots (Serenity/Premium only)
+// @Description  Purchase additional long-term property slots (plans with a slot price only). Their prorata until
+// @Description  the period end is invoiced and charged: 202 means the payment awaits 3-D Secure and the slots are
+// @Description  added by the provider's webhook, 402 that it failed. Slots are then renewed with the plan.
 // @Tags         subscriptions
 // @Accept       json
 // @Produce      json
 // @Security     BearerAuth
-// @Param        request body map[string]interface{} true "Limit Info"
-// @Success      200  {object}  map[string]interface{}
+// @Param        Idempotency-Key header string false "Unique key of the request: a retry with the same key replays the first response"
+// @Param        request body UpgradeLimitRequest true "Limit Info"
+// @Success      200  {object}  service.SlotChange
+// @Success      202  {object}  service.SlotChange
 // @Failure      400  {object}  map[string]string
+// @Failure      402  {object}  service.SlotChange
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /subscriptions/slots [post]
 // @Router       /subscriptions/upgrade [post]
 func (h *SubscriptionHandler) IncreaseLimit(c *gin.Context) {
 	userID, ok := middleware.GetUserID(c)
@@ -139,11 +188,253 @@ func (h *SubscriptionHandler) IncreaseLimit(c *gin.Context) {
 		return
 	}
 
-	err := h.svc.IncreaseLimit(c.Request.Context(), userID, req.AdditionalSlots)
+	change, err := h.svc.IncreaseLimit(c.Request.Context(), userID, req.AdditionalSlots, req.PaymentMethodID)
 	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(paymentStatusCode(change.Payment), change)
+}
+
+// RemoveSlots godoc
+// @Summary      Remove property slots
+// @Description  Schedule the removal of extra long-term property slots at the period end: they stay usable and are
+// @Description  not refunded until then. Long-term properties must fit in the limit left (409 otherwise). Buying
+// @Description  slots again before the period end keeps them back at no cost.
+// @Tags         subscriptions
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body RemoveSlotsRequest true "Slots to remove"
+// @Success      200  {object}  service.SubscriptionState
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /subscriptions/slots [delete]
+func (h *SubscriptionHandler) RemoveSlots(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req RemoveSlotsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.Status(http.StatusOK)
+	state, err := h.svc.RemoveSlots(c.Request.Context(), userID, req.Slots)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, state)
+}
+
+func (h *SubscriptionHandler) handleError(c *gin.Context, err error) {
+	var archiveErr *service.ErrPropertiesToArchive
+	switch {
+	case errors.As(err, &archiveErr):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":      err.Error(),
+			"limit":      archiveErr.Limit,
+			"to_archive": archiveErr.ToArchive,
+			"properties": archiveErr.Properties,
+		})
+	case errors.Is(err, service.ErrNoSubscription):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrSubscriptionNotActive), errors.Is(err, service.ErrSamePlan),
+		errors.Is(err, service.ErrCancellationScheduled), errors.Is(err, service.ErrCancellationNotScheduled),
+		errors.Is(err, service.ErrNoScheduledChange), errors.Is(err, service.ErrSlotsInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrCatalogItemNotFound), errors.Is(err, service.ErrInvalidPropertiesToArchive),
+		errors.Is(err, service.ErrSlotsNotAvailable), errors.Is(err, service.ErrNotEnoughSlots):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrPaymentUnavailable):
+		// Refused before anything was recorded
+		middleware.ReleaseIdempotencyKey(c)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+type ChangePlanRequest struct {
+	Plan string `json:"plan" binding:"required,max=50"`
+	// ArchivePropertyIDs are the long-term properties to archive when a downgrade leaves more than the new plan allows
+	ArchivePropertyIDs []int32 `json:"archive_property_ids" binding:"max=100"`
+	// PaymentMethodID pays the prorata of an upgrade; the saved payment method or SEPA mandate is used when empty
+	PaymentMethodID string `json:"payment_method_id" binding:"max=100"`
+}
+
+// GetCurrent godoc
+// @Summary      Get my subscription
+// @Description  Current subscription with its pending changes: cancellation or downgrade at the period end.
+// @Tags         subscriptions
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  service.SubscriptionState
+// @Failure      404  {object}  map[string]string
+// @Router       /subscriptions/current [get]
+func (h *SubscriptionHandler) GetCurrent(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	state, err := h.svc.GetSubscription(c.Request.Context(), userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, state)
+}
+
+// ChangePlan godoc
+// @Summary      Change plan
+// @Description  Moves the subscription to another plan, at the same billing frequency. A more expensive plan applies
+// @Description  right away: the new plan's prorata for the days left, less the unused part of the current plan, is
+// @Description  invoiced and charged (202: awaiting 3-D Secure, applied by the provider's webhook; 402: failed, the
+// @Description  plan is unchanged). A cheaper plan is scheduled at the period end. When it allows fewer long-term
+// @Description  properties than the owner has, 409 lists them: archive_property_ids names those to archive then.
+// @Tags         subscriptions
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        Idempotency-Key header string false "Unique key of the request: a retry with the same key replays the first response"
+// @Param        request body ChangePlanRequest true "Plan Info"
+// @Success      200  {object}  service.PlanChange
+// @Success      202  {object}  service.PlanChange
+// @Failure      400  {object}  map[string]string
+// @Failure      402  {object}  service.PlanChange
+// @Failure      409  {object}  map[string]interface{}  "Properties to archive, or a subscription that cannot change"
+// @Router       /subscriptions/change [post]
+func (h *SubscriptionHandler) ChangePlan(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req ChangePlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.FromContext(c.Request.Context()).Warn("invalid plan change request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !service.ValidCatalogCode(req.Plan) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid plan"})
+		return
+	}
+
+	change, err := h.svc.ChangePlan(c.Request.Context(), userID, req.Plan, req.ArchivePropertyIDs, req.PaymentMethodID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(paymentStatusCode(change.Payment), change)
+}
+
+// Cancel godoc
+// @Summary      Cancel my subscription
+// @Description  The subscription keeps its access until the end of the period paid, then ends instead of renewing
+// @Description  (see POST /subscriptions/resume); a downgrade scheduled is dropped. A past due subscription ends right away.
+// @Tags         subscriptions
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  service.SubscriptionState
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /subscriptions/cancel [post]
+func (h *SubscriptionHandler) Cancel(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	state, err := h.svc.Cancel(c.Request.Context(), userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, state)
+}
+
+// Resume godoc
+// @Summary      Resume my subscription
+// @Description  Reverts a cancellation before the period end: the subscription renews again.
+// @Tags         subscriptions
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  service.SubscriptionState
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /subscriptions/resume [post]
+func (h *SubscriptionHandler) Resume(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	state, err := h.svc.Resume(c.Request.Context(), userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, state)
+}
+
+// CancelScheduledChange godoc
+// @Summary      Cancel the scheduled downgrade
+// @Description  The current plan renews at the period end and no property is archived.
+// @Tags         subscriptions
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  service.SubscriptionState
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /subscriptions/scheduled-change [delete]
+func (h *SubscriptionHandler) CancelScheduledChange(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	state, err := h.svc.CancelScheduledChange(c.Request.Context(), userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, state)
+}
+
+// History godoc
+// @Summary      Subscription history
+// @Description  Timeline of the user's subscriptions, oldest first: subscribed, upgraded (with the prorata invoiced),
+// @Description  downgrade_scheduled, downgrade_cancelled, downgraded (with the properties archived),
+// @Description  cancellation_scheduled, cancellation_reverted, cancelled.
+// @Tags         subscriptions
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {array}   service.SubscriptionEventDTO
+// @Router       /subscriptions/history [get]
+func (h *SubscriptionHandler) History(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	events, err := h.svc.History(c.Request.Context(), userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, events)
 }
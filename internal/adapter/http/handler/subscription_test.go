@@ -71,4 +71,31 @@ func TestIncreaseLimit_Validation(t *testing.T) {
 	w := httptest.NewRecorder()
 	r.ServeHTTP(w, req)
 	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Removal: a number of slots is required
+	r.DELETE("/subscriptions/slots", h.RemoveSlots)
+	req, _ = http.NewRequest("DELETE", "/subscriptions/slots", bytes.NewBufferString(`{"slots": -1}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestChangePlan_Validation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewSubscriptionHandler(nil, nil)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", int32(1))
+		c.Next()
+	})
+	r.POST("/subscriptions/change", h.ChangePlan)
+
+	for _, payload := range []string{`{}`, `{"plan": "Ultra Pro"}`, `{"plan": "serenity", "archive_property_ids": "all"}`} {
+		req, _ := http.NewRequest("POST", "/subscriptions/change", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, payload)
+	}
 }
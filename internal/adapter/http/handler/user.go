@@ -115,7 +115,7 @@ func (h *UserHandler) Login(c *gin.Context) {
 	}
 
 	// Generate Token
-	token, err := auth.GenerateToken(authResp.User.ID, authResp.User.Email, string(authResp.CurrentContext))
+	token, err := auth.GenerateToken(authResp.User.ID, authResp.User.Email, string(authResp.CurrentContext), string(authResp.User.Role))
 	if err != nil {
 		log.Error("failed to generate token", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
@@ -195,7 +195,7 @@ func (h *UserHandler) SwitchContext(c *gin.Context) {
 
 	// I need to check auth.GenerateToken signature.
 	// Assuming for now I can call it.
-	token, err := auth.GenerateToken(authResp.User.ID, authResp.User.Email, string(authResp.CurrentContext))
+	token, err := auth.GenerateToken(authResp.User.ID, authResp.User.Email, string(authResp.CurrentContext), string(authResp.User.Role))
 	if err != nil {
 		log.Error("failed to generate token", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
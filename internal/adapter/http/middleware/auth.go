@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"strings"
 
@@ -11,8 +12,15 @@ import (
 	"go.uber.org/zap"
 )
 
-// AuthMiddleware ensures that the request has a valid JWT token.
-func AuthMiddleware() gin.HandlerFunc {
+// AccountChecker tells whether the user of a token still has an account, and whether they are an
+// administrator (see service.AccountService).
+type AccountChecker interface {
+	IsActive(ctx context.Context, userID int32) (bool, error)
+	IsAdmin(ctx context.Context, userID int32) (bool, error)
+}
+
+// AuthMiddleware ensures that the request has a valid JWT token whose account has not been deleted since.
+func AuthMiddleware(accounts AccountChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -36,9 +44,22 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// A token outlives the deletion of its account: it is refused as soon as the account is gone
+		active, err := accounts.IsActive(c.Request.Context(), claims.UserID)
+		if err != nil {
+			logger.FromContext(c.Request.Context()).Error("failed to check account", zap.Int32("user_id", claims.UserID), zap.Error(err))
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+			return
+		}
+		if !active {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "account deleted"})
+			return
+		}
+
 		// Store user ID in context
 		c.Set("userID", claims.UserID)
 		c.Set("email", claims.Email)
+		c.Set("role", claims.Role)
 
 		// Also update the request context logger to include UserID for subsequent logs
 		// This is tricky because we replaced the request context logger in RequestLogger middleware
@@ -49,6 +70,25 @@ func AuthMiddleware() gin.HandlerFunc {
 	}
 }
 
+// RequireAdmin restricts a route to platform administrators. It runs after AuthMiddleware. The role is
+// checked against the account, not the token: a demoted administrator is refused at once.
+func RequireAdmin(accounts AccountChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := GetUserID(c)
+		admin, err := accounts.IsAdmin(c.Request.Context(), userID)
+		if err != nil {
+			logger.FromContext(c.Request.Context()).Error("failed to check admin role", zap.Int32("user_id", userID), zap.Error(err))
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+			return
+		}
+		if !admin {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin role required"})
+			return
+		}
+		c.Next()
+	}
+}
+
 // GetUserID retrieves the user ID from the Gin context.
 func GetUserID(c *gin.Context) (int32, bool) {
 	val, exists := c.Get("userID")
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -12,6 +13,21 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+// activeAccounts reports every account as active, except the deleted ones, and only the listed admins as
+// administrators.
+type activeAccounts struct {
+	deleted map[int32]bool
+	admins  map[int32]bool
+}
+
+func (a activeAccounts) IsActive(_ context.Context, userID int32) (bool, error) {
+	return !a.deleted[userID], nil
+}
+
+func (a activeAccounts) IsAdmin(_ context.Context, userID int32) (bool, error) {
+	return a.admins[userID], nil
+}
+
 func TestValidateToken_Success(t *testing.T) {
 	// Setup Gin
 	gin.SetMode(gin.TestMode)
@@ -22,10 +38,10 @@ func TestValidateToken_Success(t *testing.T) {
 	viper.Set("JWT_EXPIRATION_HOURS", 24)
 
 	// Generate Valid Token
-	token, _ := auth.GenerateToken(1, "test@example.com", "owner")
+	token, _ := auth.GenerateToken(1, "test@example.com", "owner", "")
 
 	// Apply Middleware
-	r.Use(AuthMiddleware())
+	r.Use(AuthMiddleware(activeAccounts{}))
 	r.GET("/protected", func(c *gin.Context) {
 		userID, _ := c.Get("userID")
 		email, _ := c.Get("email")
@@ -48,7 +64,7 @@ func TestValidateToken_Success(t *testing.T) {
 func TestValidateToken_MissingHeader(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
-	r.Use(AuthMiddleware())
+	r.Use(AuthMiddleware(activeAccounts{}))
 	r.GET("/protected", func(c *gin.Context) {
 		c.Status(http.StatusOK)
 	})
@@ -63,7 +79,7 @@ func TestValidateToken_MissingHeader(t *testing.T) {
 func TestValidateToken_InvalidFormat(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
-	r.Use(AuthMiddleware())
+	r.Use(AuthMiddleware(activeAccounts{}))
 	r.GET("/protected", func(c *gin.Context) { c.Status(http.StatusOK) })
 
 	req, _ := http.NewRequest("GET", "/protected", nil)
@@ -78,7 +94,7 @@ func TestValidateToken_InvalidToken(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	viper.Set("JWT_SECRET", "testsecret")
 	r := gin.New()
-	r.Use(AuthMiddleware())
+	r.Use(AuthMiddleware(activeAccounts{}))
 	r.GET("/protected", func(c *gin.Context) { c.Status(http.StatusOK) })
 
 	req, _ := http.NewRequest("GET", "/protected", nil)
@@ -88,3 +104,41 @@ func TestValidateToken_InvalidToken(t *testing.T) {
 
 	assert.Equal(t, http.StatusUnauthorized, w.Code)
 }
+
+func TestValidateToken_DeletedAccount(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	viper.Set("JWT_SECRET", "testsecret")
+	r := gin.New()
+	r.Use(AuthMiddleware(activeAccounts{deleted: map[int32]bool{2: true}}))
+	r.GET("/protected", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	// Still valid, but issued before the account was deleted
+	token, _ := auth.GenerateToken(2, "deleted@example.com", "owner", "")
+	req, _ := http.NewRequest("GET", "/protected", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "account deleted")
+}
+
+func TestRequireAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	viper.Set("JWT_SECRET", "testsecret")
+	r := gin.New()
+	accounts := activeAccounts{admins: map[int32]bool{1: true}}
+	r.Use(AuthMiddleware(accounts), RequireAdmin(accounts))
+	r.GET("/admin", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	// The account decides, not the role claimed by the token (user 2 was demoted since)
+	for userID, code := range map[int32]int{1: http.StatusOK, 2: http.StatusForbidden} {
+		token, _ := auth.GenerateToken(userID, "test@example.com", "owner", "admin")
+		req, _ := http.NewRequest("GET", "/admin", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, code, w.Code, "user %d", userID)
+	}
+}
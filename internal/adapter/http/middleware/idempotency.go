@@ -0,0 +1,177 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/core/service"
+	"seculoc-back/internal/platform/logger"
+)
+
+const (
+	// IdempotencyKeyHeader carries the client-generated key (a UUID) of a request which must not run twice.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on responses replayed from a previous request.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+)
+
+// IdempotencyStore keeps the responses of requests by idempotency key (see service.IdempotencyService).
+type IdempotencyStore interface {
+	Begin(ctx context.Context, userID int32, key, fingerprint string) (*service.StoredResponse, error)
+	KeepAlive(ctx context.Context, userID int32, key string) error
+	Complete(ctx context.Context, userID int32, key string, resp service.StoredResponse) error
+	Release(ctx context.Context, userID int32, key string) error
+}
+
+// idempotencyReleaseKey marks a request whose key is released instead of keeping its response.
+const idempotencyReleaseKey = "idempotencyRelease"
+
+// ReleaseIdempotencyKey tells the Idempotency middleware that the request failed without changing
+// anything, e.g. refused before any write: its key is freed so that it can be retried with the same key.
+// Handlers call it only for errors the service documents as such.
+func ReleaseIdempotencyKey(c *gin.Context) {
+	c.Set(idempotencyReleaseKey, true)
+}
+
+// recordingWriter holds the response body back until it is recorded.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+// requestFingerprint identifies a request: the same key must come back with the same method, path and body.
+func requestFingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, method+" "+path+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Idempotency makes a route safe to retry. When the request has an Idempotency-Key header, its response
+// is stored and replayed to any retry with the same key and body; the same key with another body gets a
+// 409. Server errors and panics are recorded too, as their outcome is unknown: only a request released
+// with ReleaseIdempotencyKey can run again. The response is sent once recorded, so that a client never
+// gets a response a retry would not replay. Runs after AuthMiddleware and inside the recovery middleware:
+// keys are scoped to the user.
+func Idempotency(store IdempotencyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "idempotency key too long"})
+			return
+		}
+		userID, ok := GetUserID(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "cannot read body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		log := logger.FromContext(ctx).With(zap.String("idempotency_key", key))
+		stored, err := store.Begin(ctx, userID, key, requestFingerprint(c.Request.Method, c.Request.URL.Path, body))
+		switch {
+		case errors.Is(err, service.ErrIdempotencyKeyReused), errors.Is(err, service.ErrIdempotencyInProgress):
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			log.Error("failed to reserve idempotency key", zap.Error(err))
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+			return
+		case stored != nil:
+			c.Header(IdempotentReplayedHeader, "true")
+			c.Data(stored.StatusCode, stored.ContentType, stored.Body)
+			c.Abort()
+			return
+		}
+
+		// The request context may be cancelled by the time the outcome is known: it is recorded regardless
+		ctx = context.WithoutCancel(ctx)
+		stop := keepAlive(ctx, store, userID, key, log)
+		defer func() {
+			stop()
+			// A panicking handler may have changed something: the key stays taken, with the 500 the
+			// recovery middleware answers
+			if r := recover(); r != nil {
+				if err := store.Complete(ctx, userID, key, service.StoredResponse{StatusCode: http.StatusInternalServerError}); err != nil {
+					log.Error("failed to store idempotent response", zap.Error(err))
+				}
+				panic(r)
+			}
+		}()
+
+		writer := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		if c.GetBool(idempotencyReleaseKey) {
+			err = store.Release(ctx, userID, key)
+		} else {
+			err = store.Complete(ctx, userID, key, service.StoredResponse{
+				StatusCode:  writer.Status(),
+				ContentType: writer.Header().Get("Content-Type"),
+				Body:        writer.body.Bytes(),
+			})
+		}
+		if err != nil {
+			// The outcome cannot be replayed: the client must not take this response for the final one
+			log.Error("failed to record idempotent response", zap.Error(err))
+			if !c.Writer.Written() {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to record the response"})
+				return
+			}
+		}
+		c.Writer.Write(writer.body.Bytes())
+	}
+}
+
+// keepAlive refreshes the key of a running request every service.IdempotencyKeepAliveInterval, so that no
+// retry takes it over while the handler runs. The returned function stops it.
+func keepAlive(ctx context.Context, store IdempotencyStore, userID int32, key string, log *zap.Logger) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(service.IdempotencyKeepAliveInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := store.KeepAlive(ctx, userID, key); err != nil {
+					log.Warn("failed to keep idempotency key alive", zap.Error(err))
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
@@ -0,0 +1,172 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"seculoc-back/internal/core/service"
+)
+
+type memoryIdempotencyStore struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+	// failing makes Complete fail, as when the database is unreachable
+	failing bool
+}
+
+type memoryEntry struct {
+	fingerprint string
+	resp        *service.StoredResponse
+}
+
+func (s *memoryIdempotencyStore) Begin(ctx context.Context, userID int32, key, fingerprint string) (*service.StoredResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	switch {
+	case !ok:
+		s.entries[key] = &memoryEntry{fingerprint: fingerprint}
+		return nil, nil
+	case e.fingerprint != fingerprint:
+		return nil, service.ErrIdempotencyKeyReused
+	case e.resp == nil:
+		return nil, service.ErrIdempotencyInProgress
+	}
+	return e.resp, nil
+}
+
+func (s *memoryIdempotencyStore) KeepAlive(ctx context.Context, userID int32, key string) error {
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Complete(ctx context.Context, userID int32, key string, resp service.StoredResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failing {
+		return errors.New("connection refused")
+	}
+	s.entries[key].resp = &resp
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(ctx context.Context, userID int32, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+func TestIdempotency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := &memoryIdempotencyStore{entries: map[string]*memoryEntry{}}
+	calls := 0
+	failing := true
+
+	r := gin.New()
+	r.Use(gin.Recovery())
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", int32(1))
+		c.Next()
+	})
+	r.Use(Idempotency(store))
+	r.POST("/credits", func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusOK, gin.H{"call": calls})
+	})
+	r.POST("/flaky", func(c *gin.Context) {
+		calls++
+		if failing {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db down"})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"call": calls})
+	})
+	r.POST("/unavailable", func(c *gin.Context) {
+		calls++
+		if failing {
+			ReleaseIdempotencyKey(c)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "payment provider not configured"})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"call": calls})
+	})
+	r.POST("/panicking", func(c *gin.Context) {
+		calls++
+		if failing {
+			panic("nil map")
+		}
+		c.JSON(http.StatusCreated, gin.H{"call": calls})
+	})
+
+	send := func(path, key, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	first := send("/credits", "key-1", `{"pack_type":"pack_20"}`)
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.JSONEq(t, `{"call":1}`, first.Body.String())
+
+	// Retry: replayed, not run again
+	retry := send("/credits", "key-1", `{"pack_type":"pack_20"}`)
+	assert.Equal(t, http.StatusOK, retry.Code)
+	assert.JSONEq(t, `{"call":1}`, retry.Body.String())
+	assert.Equal(t, "true", retry.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, "application/json; charset=utf-8", retry.Header().Get("Content-Type"))
+
+	// Same key, other request
+	assert.Equal(t, http.StatusConflict, send("/credits", "key-1", `{"pack_type":"pack_50"}`).Code)
+	assert.Equal(t, http.StatusConflict, send("/flaky", "key-1", `{"pack_type":"pack_20"}`).Code)
+
+	// Without key, nothing changes
+	assert.JSONEq(t, `{"call":2}`, send("/credits", "", `{}`).Body.String())
+	assert.Equal(t, 2, calls)
+
+	// A server error may have changed something: replayed, not run again
+	assert.Equal(t, http.StatusInternalServerError, send("/flaky", "key-2", `{}`).Code)
+	failing = false
+	replayed := send("/flaky", "key-2", `{}`)
+	assert.Equal(t, http.StatusInternalServerError, replayed.Code)
+	assert.JSONEq(t, `{"error":"db down"}`, replayed.Body.String())
+	assert.Equal(t, 3, calls)
+
+	// Unless the handler released the key: nothing was done
+	failing = true
+	assert.Equal(t, http.StatusServiceUnavailable, send("/unavailable", "key-5", `{}`).Code)
+	assert.NotContains(t, store.entries, "key-5")
+	failing = false
+	assert.Equal(t, http.StatusCreated, send("/unavailable", "key-5", `{}`).Code)
+	assert.Equal(t, 5, calls)
+
+	// A panic keeps the key as well
+	failing = true
+	assert.Equal(t, http.StatusInternalServerError, send("/panicking", "key-4", `{}`).Code)
+	failing = false
+	assert.Equal(t, http.StatusInternalServerError, send("/panicking", "key-4", `{}`).Code)
+	assert.Equal(t, 6, calls)
+
+	// A response which could not be recorded is not sent: a retry would not get it back
+	store.failing = true
+	unrecorded := send("/credits", "key-6", `{}`)
+	assert.Equal(t, http.StatusInternalServerError, unrecorded.Code)
+	assert.NotContains(t, unrecorded.Body.String(), "call")
+	store.failing = false
+
+	// In progress
+	store.entries["key-3"] = &memoryEntry{fingerprint: requestFingerprint("POST", "/credits", []byte(`{}`))}
+	assert.Equal(t, http.StatusConflict, send("/credits", "key-3", `{}`).Code)
+}
@@ -0,0 +1,175 @@
+// Package fake implements a deterministic OpenBankingProvider for local development and tests.
+//
+// Consent, code and connection identifiers are derived from the consent reference, so the whole flow
+// works without any state or network access. The generated history (salary, rent, groceries) is
+// relative to Now and depends only on MonthlyIncome, which makes analysis results reproducible.
+// Webhooks are signed exactly like a real provider would, with the configured secret.
+package fake
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"seculoc-back/internal/core/service"
+	"seculoc-back/internal/platform/webhook"
+)
+
+const (
+	// SignatureHeader carries the webhook signature (see package webhook).
+	SignatureHeader = "Fake-Bank-Signature"
+
+	defaultMonthlyIncome = 3000
+	consentTTL           = 30 * time.Minute
+)
+
+// Provider is the fake aggregator.
+type Provider struct {
+	Secret []byte
+	// MonthlyIncome is the salary credited every month on the generated account
+	MonthlyIncome float64
+	// Pending makes ListAccounts report that aggregation is still running (exercise the webhook path)
+	Pending bool
+	Now     func() time.Time
+}
+
+// New returns a provider with default settings, signing webhooks with secret.
+func New(secret string) *Provider {
+	return &Provider{
+		Secret:        []byte(secret),
+		MonthlyIncome: defaultMonthlyIncome,
+		Now:           time.Now,
+	}
+}
+
+// NewFromEnv reads OPEN_BANKING_WEBHOOK_SECRET, which is required.
+func NewFromEnv() (*Provider, error) {
+	secret := viper.GetString("OPEN_BANKING_WEBHOOK_SECRET")
+	if secret == "" {
+		return nil, fmt.Errorf("OPEN_BANKING_WEBHOOK_SECRET is required")
+	}
+	return New(secret), nil
+}
+
+func (p *Provider) Name() string { return "fake" }
+
+func digest(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:8])
+}
+
+func (p *Provider) StartConsent(ctx context.Context, req service.ConsentRequest) (*service.ConsentSession, error) {
+	if req.Reference == "" {
+		return nil, fmt.Errorf("missing consent reference")
+	}
+	id := digest(req.Reference)
+
+	// The hosted page is skipped: the candidate is sent straight back with an authorization code.
+	u, err := url.Parse(req.RedirectURL)
+	if err != nil || req.RedirectURL == "" {
+		return nil, fmt.Errorf("invalid redirect url")
+	}
+	q := u.Query()
+	q.Set("code", "code-"+id)
+	q.Set("state", req.Reference)
+	u.RawQuery = q.Encode()
+
+	return &service.ConsentSession{
+		ID:        "consent-" + id,
+		URL:       u.String(),
+		ExpiresAt: p.Now().Add(consentTTL),
+	}, nil
+}
+
+func (p *Provider) ExchangeCode(ctx context.Context, code string) (*service.BankConnection, error) {
+	id, ok := strings.CutPrefix(code, "code-")
+	if !ok || id == "" {
+		return nil, fmt.Errorf("invalid authorization code")
+	}
+	return &service.BankConnection{ID: "conn-" + id, ConsentID: "consent-" + id}, nil
+}
+
+func (p *Provider) ListAccounts(ctx context.Context, connectionID string) ([]service.BankAccount, error) {
+	if !strings.HasPrefix(connectionID, "conn-") {
+		return nil, fmt.Errorf("unknown connection")
+	}
+	if p.Pending {
+		return nil, service.ErrBankDataPending
+	}
+	return []service.BankAccount{{
+		ID:       "acc-" + strings.TrimPrefix(connectionID, "conn-"),
+		Name:     "Compte courant",
+		IBAN:     "FR7630006000011234567890189",
+		Currency: "EUR",
+	}}, nil
+}
+
+// ListTransactions generates a monthly salary, a monthly rent and weekly groceries between from and to.
+func (p *Provider) ListTransactions(ctx context.Context, connectionID, accountID string, from, to time.Time) ([]service.TransactionData, error) {
+	if p.Pending {
+		return nil, service.ErrBankDataPending
+	}
+
+	var txs []service.TransactionData
+	add := func(d time.Time, amount float64, label, counterparty string) {
+		if d.Before(from) || d.After(to) {
+			return
+		}
+		txs = append(txs, service.TransactionData{Amount: amount, Description: label, Date: d, Counterparty: counterparty})
+	}
+
+	start := time.Date(from.Year(), from.Month(), 1, 12, 0, 0, 0, time.UTC)
+	for m := start; !m.After(to); m = m.AddDate(0, 1, 0) {
+		add(m.AddDate(0, 0, 27), p.MonthlyIncome, "VIR SALAIRE ACME SAS", "ACME SAS")
+		add(m.AddDate(0, 0, 4), -p.MonthlyIncome*0.25, "PRLV LOYER FONCIA", "FONCIA")
+	}
+	for d := start; !d.After(to); d = d.AddDate(0, 0, 7) {
+		add(d.AddDate(0, 0, 2), -65.40, "CB SUPERMARCHE", "")
+	}
+	return txs, nil
+}
+
+type webhookPayload struct {
+	ID           string `json:"id"`
+	Type         string `json:"type"`
+	ConnectionID string `json:"connection_id"`
+	ConsentID    string `json:"consent_id,omitempty"`
+}
+
+func (p *Provider) ParseWebhook(payload []byte, headers http.Header) (*service.WebhookEvent, error) {
+	if err := webhook.Verify(p.Secret, headers.Get(SignatureHeader), payload, webhook.DefaultTolerance, p.Now()); err != nil {
+		return nil, err
+	}
+	var body webhookPayload
+	if err := json.Unmarshal(payload, &body); err != nil || body.ID == "" || body.Type == "" {
+		return nil, fmt.Errorf("%w: malformed payload", webhook.ErrInvalidSignature)
+	}
+	return &service.WebhookEvent{
+		ID:           body.ID,
+		Type:         body.Type,
+		ConnectionID: body.ConnectionID,
+		ConsentID:    body.ConsentID,
+	}, nil
+}
+
+// BuildWebhook returns a signed notification as the provider would deliver it.
+func (p *Provider) BuildWebhook(event service.WebhookEvent) ([]byte, http.Header) {
+	payload, _ := json.Marshal(webhookPayload{
+		ID:           event.ID,
+		Type:         event.Type,
+		ConnectionID: event.ConnectionID,
+		ConsentID:    event.ConsentID,
+	})
+	headers := http.Header{}
+	headers.Set("Content-Type", "application/json")
+	headers.Set(SignatureHeader, webhook.Sign(p.Secret, p.Now(), payload))
+	return payload, headers
+}
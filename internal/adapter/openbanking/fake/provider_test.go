@@ -0,0 +1,86 @@
+package fake
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"seculoc-back/internal/core/service"
+	"seculoc-back/internal/platform/webhook"
+)
+
+func TestConsentFlowIsDeterministic(t *testing.T) {
+	p := New("secret")
+	ctx := context.Background()
+
+	s1, err := p.StartConsent(ctx, service.ConsentRequest{Reference: "check-1", RedirectURL: "http://front/bank?x=1"})
+	require.NoError(t, err)
+	s2, _ := p.StartConsent(ctx, service.ConsentRequest{Reference: "check-1", RedirectURL: "http://front/bank"})
+	other, _ := p.StartConsent(ctx, service.ConsentRequest{Reference: "check-2", RedirectURL: "http://front/bank"})
+	assert.Equal(t, s1.ID, s2.ID)
+	assert.NotEqual(t, s1.ID, other.ID)
+
+	u, _ := url.Parse(s1.URL)
+	assert.Equal(t, "1", u.Query().Get("x"))
+	assert.Equal(t, "check-1", u.Query().Get("state"))
+
+	conn, err := p.ExchangeCode(ctx, u.Query().Get("code"))
+	require.NoError(t, err)
+	assert.Equal(t, s1.ID, conn.ConsentID)
+
+	_, err = p.ExchangeCode(ctx, "garbage")
+	assert.Error(t, err)
+}
+
+func TestTransactionsScoreAsSolvent(t *testing.T) {
+	p := New("secret")
+	ctx := context.Background()
+	to := time.Date(2026, 6, 15, 0, 0, 0, 0, time.UTC)
+	from := to.AddDate(0, -3, 0)
+
+	accounts, err := p.ListAccounts(ctx, "conn-abc")
+	require.NoError(t, err)
+	require.Len(t, accounts, 1)
+
+	txs, err := p.ListTransactions(ctx, "conn-abc", accounts[0].ID, from, to)
+	require.NoError(t, err)
+	for _, tx := range txs {
+		assert.False(t, tx.Date.Before(from) || tx.Date.After(to))
+	}
+	again, _ := p.ListTransactions(ctx, "conn-abc", accounts[0].ID, from, to)
+	assert.Equal(t, txs, again)
+
+	analysis := service.AnalyzeIncome(txs, 800)
+	assert.InDelta(t, 3000, analysis.MonthlyIncome, 1)
+	assert.GreaterOrEqual(t, analysis.Score, 60)
+
+	p.Pending = true
+	_, err = p.ListAccounts(ctx, "conn-abc")
+	assert.ErrorIs(t, err, service.ErrBankDataPending)
+}
+
+func TestWebhookSignature(t *testing.T) {
+	p := New("secret")
+	now := time.Unix(1700000000, 0)
+	p.Now = func() time.Time { return now }
+
+	payload, headers := p.BuildWebhook(service.WebhookEvent{ID: "evt_1", Type: service.WebhookTransactionsReady, ConnectionID: "conn-abc"})
+	event, err := p.ParseWebhook(payload, headers)
+	require.NoError(t, err)
+	assert.Equal(t, "conn-abc", event.ConnectionID)
+
+	tampered := []byte(string(payload[:len(payload)-1]) + ` `)
+	_, err = p.ParseWebhook(tampered, headers)
+	assert.ErrorIs(t, err, webhook.ErrInvalidSignature)
+
+	_, err = New("other").ParseWebhook(payload, headers)
+	assert.ErrorIs(t, err, webhook.ErrInvalidSignature)
+
+	p.Now = func() time.Time { return now.Add(time.Hour) }
+	_, err = p.ParseWebhook(payload, headers)
+	assert.ErrorIs(t, err, webhook.ErrExpired)
+}
@@ -0,0 +1,252 @@
+// Package fake implements an in-process PaymentProvider mimicking Stripe, for local development and tests.
+//
+// Outcomes are chosen with Stripe's test payment methods: pm_card_visa succeeds, pm_card_chargeDeclined
+// is declined and pm_card_threeDSecure2Required waits for a 3-D Secure authentication, which is then
+// completed (or not) by delivering a webhook built with BuildWebhook. Off-session charges use the
+// customer's SEPA mandate, or the last payment method accepted, pm_card_visa by default. Webhooks are signed exactly like
+// a real provider would, with the configured secret.
+package fake
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"seculoc-back/internal/core/service"
+	"seculoc-back/internal/platform/webhook"
+)
+
+const (
+	// SignatureHeader carries the webhook signature (see package webhook).
+	SignatureHeader = "Stripe-Signature"
+
+	// Test payment methods
+	CardSuccess  = "pm_card_visa"
+	CardDeclined = "pm_card_chargeDeclined"
+	CardThreeDS  = "pm_card_threeDSecure2Required"
+	// FailingIBANSuffix ends the IBANs whose SEPA debits are refused by the bank.
+	FailingIBANSuffix = "2607"
+)
+
+type intent struct {
+	customerID string
+	amount     int32
+	refunded   int32
+	status     string
+}
+
+type customer struct {
+	paymentMethodID string
+	mandateID       string
+}
+
+// Provider is the fake payment service provider. It is safe for concurrent use.
+type Provider struct {
+	Secret []byte
+	Now    func() time.Time
+
+	mu        sync.Mutex
+	seq       int
+	customers map[string]*customer
+	mandates  map[string]string // mandate ID -> IBAN
+	intents   map[string]*intent
+	replays   map[string]service.PaymentIntent // idempotency key -> intent created with it
+	refunds   map[string]service.Refund        // idempotency key -> refund made with it
+}
+
+// New returns a provider signing its webhooks with secret.
+func New(secret string) *Provider {
+	return &Provider{
+		Secret:    []byte(secret),
+		Now:       time.Now,
+		customers: map[string]*customer{},
+		mandates:  map[string]string{},
+		intents:   map[string]*intent{},
+		replays:   map[string]service.PaymentIntent{},
+		refunds:   map[string]service.Refund{},
+	}
+}
+
+// NewFromEnv reads PAYMENT_WEBHOOK_SECRET, which is required.
+func NewFromEnv() (*Provider, error) {
+	secret := viper.GetString("PAYMENT_WEBHOOK_SECRET")
+	if secret == "" {
+		return nil, fmt.Errorf("PAYMENT_WEBHOOK_SECRET is required")
+	}
+	return New(secret), nil
+}
+
+func (p *Provider) Name() string { return "fake_stripe" }
+
+// nextID must be called with p.mu held.
+func (p *Provider) nextID(prefix string) string {
+	p.seq++
+	return fmt.Sprintf("%s_fake%d_%d", prefix, p.Now().UnixNano(), p.seq)
+}
+
+func (p *Provider) CreateCustomer(ctx context.Context, req service.CustomerRequest) (*service.PaymentCustomer, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	id := p.nextID("cus")
+	p.customers[id] = &customer{}
+	return &service.PaymentCustomer{ID: id}, nil
+}
+
+// customer returns a customer, also those created by another process (the fake keeps no shared state).
+// Must be called with p.mu held.
+func (p *Provider) customer(id string) *customer {
+	c, ok := p.customers[id]
+	if !ok {
+		c = &customer{}
+		p.customers[id] = c
+	}
+	return c
+}
+
+func (p *Provider) CreatePaymentIntent(ctx context.Context, req service.PaymentIntentRequest) (*service.PaymentIntent, error) {
+	if req.CustomerID == "" || req.AmountCents <= 0 {
+		return nil, fmt.Errorf("invalid payment intent request")
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Like Stripe, a request repeated with the same idempotency key gets the first response back
+	if replay, ok := p.replays[req.IdempotencyKey]; ok && req.IdempotencyKey != "" {
+		return &replay, nil
+	}
+
+	c := p.customer(req.CustomerID)
+	res := &service.PaymentIntent{ID: p.nextID("pi")}
+	method := req.PaymentMethodID
+	switch {
+	case method != "":
+	case req.MandateID != "" || c.mandateID != "":
+		mandate := req.MandateID
+		if mandate == "" {
+			mandate = c.mandateID
+		}
+		res.Status = service.PaymentSucceeded
+		if strings.HasSuffix(p.mandates[mandate], FailingIBANSuffix) {
+			res.Status, res.FailureReason = service.PaymentFailed, "insufficient_funds"
+		}
+	case c.paymentMethodID != "":
+		method = c.paymentMethodID
+	default:
+		method = CardSuccess
+	}
+
+	if res.Status == "" {
+		switch method {
+		case CardDeclined:
+			res.Status, res.FailureReason = service.PaymentFailed, "card_declined"
+		case CardThreeDS:
+			res.Status = service.PaymentRequiresAction
+			res.ClientSecret = res.ID + "_secret"
+			res.NextActionURL = "https://hooks.stripe.test/3d_secure_2/" + res.ID
+		default:
+			res.Status = service.PaymentSucceeded
+		}
+		if res.Status != service.PaymentFailed {
+			c.paymentMethodID = method
+		}
+	}
+
+	p.intents[res.ID] = &intent{customerID: req.CustomerID, amount: req.AmountCents, status: res.Status}
+	if req.IdempotencyKey != "" {
+		p.replays[req.IdempotencyKey] = *res
+	}
+	return res, nil
+}
+
+func (p *Provider) CreateSepaMandate(ctx context.Context, req service.MandateRequest) (*service.Mandate, error) {
+	iban := strings.ReplaceAll(strings.ToUpper(req.IBAN), " ", "")
+	if len(iban) < 15 || req.CustomerID == "" {
+		return nil, fmt.Errorf("invalid iban")
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	id := p.nextID("mandate")
+	p.mandates[id] = iban
+	p.customer(req.CustomerID).mandateID = id
+	return &service.Mandate{ID: id, Last4: iban[len(iban)-4:]}, nil
+}
+
+func (p *Provider) Refund(ctx context.Context, req service.RefundRequest) (*service.Refund, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if replay, ok := p.refunds[req.IdempotencyKey]; ok && req.IdempotencyKey != "" {
+		return &replay, nil
+	}
+	pi, ok := p.intents[req.PaymentIntentID]
+	if ok && (pi.status != service.PaymentSucceeded || pi.refunded+req.AmountCents > pi.amount) {
+		return nil, fmt.Errorf("charge %s cannot be refunded", req.PaymentIntentID)
+	}
+	if ok {
+		pi.refunded += req.AmountCents
+	}
+	res := service.Refund{ID: p.nextID("re"), AmountCents: req.AmountCents}
+	if req.IdempotencyKey != "" {
+		p.refunds[req.IdempotencyKey] = res
+	}
+	return &res, nil
+}
+
+type webhookPayload struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Data struct {
+		Object struct {
+			ID               string `json:"id"`
+			LastPaymentError string `json:"last_payment_error,omitempty"`
+		} `json:"object"`
+	} `json:"data"`
+}
+
+func (p *Provider) ParseWebhook(payload []byte, headers http.Header) (*service.PaymentEvent, error) {
+	if err := webhook.Verify(p.Secret, headers.Get(SignatureHeader), payload, webhook.DefaultTolerance, p.Now()); err != nil {
+		return nil, err
+	}
+	var body webhookPayload
+	if err := json.Unmarshal(payload, &body); err != nil || body.ID == "" || body.Type == "" {
+		return nil, fmt.Errorf("%w: malformed payload", webhook.ErrInvalidSignature)
+	}
+	return &service.PaymentEvent{
+		ID:              body.ID,
+		Type:            body.Type,
+		PaymentIntentID: body.Data.Object.ID,
+		FailureReason:   body.Data.Object.LastPaymentError,
+	}, nil
+}
+
+// BuildWebhook returns a signed notification as the provider would deliver it, e.g. once the customer
+// completed (payment_intent.succeeded) or abandoned (payment_intent.payment_failed) 3-D Secure.
+func (p *Provider) BuildWebhook(event service.PaymentEvent) ([]byte, http.Header) {
+	var body webhookPayload
+	body.ID, body.Type = event.ID, event.Type
+	body.Data.Object.ID = event.PaymentIntentID
+	body.Data.Object.LastPaymentError = event.FailureReason
+	payload, _ := json.Marshal(body)
+
+	p.mu.Lock()
+	if pi, ok := p.intents[event.PaymentIntentID]; ok {
+		switch event.Type {
+		case service.WebhookPaymentSucceeded:
+			pi.status = service.PaymentSucceeded
+		case service.WebhookPaymentFailed:
+			pi.status = service.PaymentFailed
+		}
+	}
+	p.mu.Unlock()
+
+	headers := http.Header{}
+	headers.Set("Content-Type", "application/json")
+	headers.Set(SignatureHeader, webhook.Sign(p.Secret, p.Now(), payload))
+	return payload, headers
+}
@@ -0,0 +1,88 @@
+package fake
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"seculoc-back/internal/core/service"
+	"seculoc-back/internal/platform/webhook"
+)
+
+func TestPaymentOutcomesFollowTestPaymentMethods(t *testing.T) {
+	p := New("secret")
+	ctx := context.Background()
+	cus, err := p.CreateCustomer(ctx, service.CustomerRequest{Email: "owner@test.com"})
+	require.NoError(t, err)
+
+	charge := func(method string) *service.PaymentIntent {
+		pi, err := p.CreatePaymentIntent(ctx, service.PaymentIntentRequest{CustomerID: cus.ID, AmountCents: 2990, PaymentMethodID: method})
+		require.NoError(t, err)
+		return pi
+	}
+
+	assert.Equal(t, service.PaymentSucceeded, charge("").Status, "pm_card_visa by default")
+	assert.Equal(t, service.PaymentSucceeded, charge(CardSuccess).Status)
+	declined := charge(CardDeclined)
+	assert.Equal(t, service.PaymentFailed, declined.Status)
+	assert.Equal(t, "card_declined", declined.FailureReason)
+	threeDS := charge(CardThreeDS)
+	assert.Equal(t, service.PaymentRequiresAction, threeDS.Status)
+	assert.NotEmpty(t, threeDS.ClientSecret)
+
+	// Off-session: last payment method accepted, then the SEPA mandate
+	assert.Equal(t, service.PaymentRequiresAction, charge("").Status)
+	_, err = p.CreateSepaMandate(ctx, service.MandateRequest{CustomerID: cus.ID, IBAN: "FR14 2004 1010 0505 0001 3M02 607"})
+	require.NoError(t, err)
+	assert.Equal(t, service.PaymentFailed, charge("").Status)
+}
+
+func TestIdempotencyKeyReplaysTheFirstIntent(t *testing.T) {
+	p := New("secret")
+	ctx := context.Background()
+	cus, err := p.CreateCustomer(ctx, service.CustomerRequest{Email: "owner@test.com"})
+	require.NoError(t, err)
+
+	req := service.PaymentIntentRequest{CustomerID: cus.ID, AmountCents: 990, IdempotencyKey: "invoice-7-1"}
+	first, err := p.CreatePaymentIntent(ctx, req)
+	require.NoError(t, err)
+	replay, err := p.CreatePaymentIntent(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, replay.ID)
+
+	req.IdempotencyKey = "invoice-7-2"
+	retry, err := p.CreatePaymentIntent(ctx, req)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.ID, retry.ID)
+}
+
+func TestRefundIsCappedByPayment(t *testing.T) {
+	p := New("secret")
+	ctx := context.Background()
+	pi, err := p.CreatePaymentIntent(ctx, service.PaymentIntentRequest{CustomerID: "cus_1", AmountCents: 1000, PaymentMethodID: CardSuccess})
+	require.NoError(t, err)
+
+	first, err := p.Refund(ctx, service.RefundRequest{PaymentIntentID: pi.ID, AmountCents: 600, IdempotencyKey: "refund-1"})
+	require.NoError(t, err)
+	// Resumed with the same key: the same refund, not a second one
+	replay, err := p.Refund(ctx, service.RefundRequest{PaymentIntentID: pi.ID, AmountCents: 600, IdempotencyKey: "refund-1"})
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, replay.ID)
+	_, err = p.Refund(ctx, service.RefundRequest{PaymentIntentID: pi.ID, AmountCents: 600, IdempotencyKey: "refund-2"})
+	assert.Error(t, err)
+}
+
+func TestWebhookRoundTrip(t *testing.T) {
+	p := New("secret")
+	payload, headers := p.BuildWebhook(service.PaymentEvent{ID: "evt_1", Type: service.WebhookPaymentFailed, PaymentIntentID: "pi_1", FailureReason: "authentication_failed"})
+
+	event, err := p.ParseWebhook(payload, headers)
+	require.NoError(t, err)
+	assert.Equal(t, "pi_1", event.PaymentIntentID)
+	assert.Equal(t, "authentication_failed", event.FailureReason)
+
+	_, err = New("other").ParseWebhook(payload, headers)
+	assert.ErrorIs(t, err, webhook.ErrInvalidSignature)
+}
@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/viper"
+
+	"seculoc-back/internal/adapter/storage/local"
+	"seculoc-back/internal/adapter/storage/s3"
+)
+
+// Backend is a raw file store (no encryption layer).
+type Backend interface {
+	Store
+	Delete(filename string) error
+	Open(filename string) (io.ReadCloser, error)
+}
+
+// BackendFromEnv selects the storage backend from STORAGE_DRIVER ("local" by default, or "s3").
+// The local store only works with a single replica.
+func BackendFromEnv() (Backend, error) {
+	switch driver := viper.GetString("STORAGE_DRIVER"); driver {
+	case "", "local":
+		return local.NewFileStore()
+	case "s3":
+		return s3.NewFileStore()
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_DRIVER %q", driver)
+	}
+}
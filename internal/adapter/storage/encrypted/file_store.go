@@ -0,0 +1,362 @@
+// Package encrypted adds envelope encryption at rest on top of any FileStorage backend (local, s3).
+//
+// Each file gets its own random data key (DEK). The DEK encrypts the content with AES-256-GCM and is
+// itself wrapped by a master key from the configuration. The master key ID and the wrapped DEK are
+// stored in a small header in front of the ciphertext, so every file says which key protects it:
+//
+//	"SLENC" | version (1) | len(keyID) (1) | keyID | wrapped DEK (nonce 12 + 32 + tag 16) | nonce (12) | ciphertext+tag
+//
+// Rotating the master key only rewrites headers (see Rotate): the content is never re-encrypted.
+package encrypted
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+var (
+	magic = []byte("SLENC")
+
+	ErrUnknownKey = errors.New("unknown encryption key")
+	ErrCorrupted  = errors.New("encrypted file is corrupted")
+)
+
+const (
+	formatVersion = 1
+	keySize       = 32
+	nonceSize     = 12
+	wrappedSize   = nonceSize + keySize + 16
+)
+
+// Store is the underlying backend holding the encrypted bytes.
+type Store interface {
+	Save(filename string, content []byte) (string, error)
+	Get(filename string) ([]byte, error)
+	Exists(filename string) bool
+	Delete(filename string) error
+	List(prefix string) ([]string, error)
+}
+
+// Keyring holds the master keys by ID. New files are always wrapped with ActiveID;
+// older keys are kept to read files until they are rotated.
+type Keyring struct {
+	ActiveID string
+	keys     map[string][]byte
+}
+
+// ParseKeyring reads "id1:base64key,id2:base64key" (32-byte keys, standard base64).
+func ParseKeyring(spec, activeID string) (*Keyring, error) {
+	kr := &Keyring{ActiveID: activeID, keys: make(map[string][]byte)}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || id == "" || len(id) > 255 {
+			return nil, fmt.Errorf("invalid master key entry %q (expected id:base64key)", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != keySize {
+			return nil, fmt.Errorf("master key %q must be %d bytes, base64 encoded", id, keySize)
+		}
+		kr.keys[id] = key
+	}
+	if len(kr.keys) == 0 {
+		return nil, errors.New("no master key configured")
+	}
+	if kr.ActiveID == "" && len(kr.keys) == 1 {
+		for id := range kr.keys {
+			kr.ActiveID = id
+		}
+	}
+	if _, ok := kr.keys[kr.ActiveID]; !ok {
+		return nil, fmt.Errorf("active master key %q is not in the keyring", kr.ActiveID)
+	}
+	return kr, nil
+}
+
+// KeyringFromEnv reads STORAGE_MASTER_KEYS and STORAGE_ACTIVE_KEY_ID.
+// It returns nil (encryption disabled) when no master key is configured.
+func KeyringFromEnv() (*Keyring, error) {
+	spec := viper.GetString("STORAGE_MASTER_KEYS")
+	if spec == "" {
+		return nil, nil
+	}
+	return ParseKeyring(spec, viper.GetString("STORAGE_ACTIVE_KEY_ID"))
+}
+
+type FileStore struct {
+	inner   Store
+	keyring *Keyring
+}
+
+func New(inner Store, keyring *Keyring) *FileStore {
+	return &FileStore{inner: inner, keyring: keyring}
+}
+
+func gcm(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func seal(key, plaintext, aad []byte) ([]byte, error) {
+	aead, err := gcm(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func open(key, sealed, aad []byte) ([]byte, error) {
+	aead, err := gcm(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < nonceSize+aead.Overhead() {
+		return nil, ErrCorrupted
+	}
+	plaintext, err := aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], aad)
+	if err != nil {
+		return nil, ErrCorrupted
+	}
+	return plaintext, nil
+}
+
+// The wrapped DEK is bound to its master key ID; the content is bound to the file name,
+// so a ciphertext cannot be swapped with another file's.
+func dekAAD(keyID string) []byte { return []byte("seculoc-dek:" + keyID) }
+
+type header struct {
+	keyID      string
+	wrappedDEK []byte
+	body       []byte // nonce + ciphertext
+}
+
+func isEncrypted(raw []byte) bool {
+	return bytes.HasPrefix(raw, magic)
+}
+
+func parse(raw []byte) (*header, error) {
+	rest := raw[len(magic):]
+	if len(rest) < 2 || rest[0] != formatVersion {
+		return nil, ErrCorrupted
+	}
+	idLen := int(rest[1])
+	rest = rest[2:]
+	if len(rest) < idLen+wrappedSize {
+		return nil, ErrCorrupted
+	}
+	return &header{
+		keyID:      string(rest[:idLen]),
+		wrappedDEK: rest[idLen : idLen+wrappedSize],
+		body:       rest[idLen+wrappedSize:],
+	}, nil
+}
+
+func (h *header) encode() []byte {
+	var buf bytes.Buffer
+	buf.Write(magic)
+	buf.WriteByte(formatVersion)
+	buf.WriteByte(byte(len(h.keyID)))
+	buf.WriteString(h.keyID)
+	buf.Write(h.wrappedDEK)
+	buf.Write(h.body)
+	return buf.Bytes()
+}
+
+func (s *FileStore) masterKey(id string) ([]byte, error) {
+	key, ok := s.keyring.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, id)
+	}
+	return key, nil
+}
+
+func (s *FileStore) encrypt(filename string, plaintext []byte) ([]byte, error) {
+	dek := make([]byte, keySize)
+	if _, err := rand.Read(dek); err != nil {
+		return nil, err
+	}
+	body, err := seal(dek, plaintext, []byte(filename))
+	if err != nil {
+		return nil, err
+	}
+
+	master, err := s.masterKey(s.keyring.ActiveID)
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := seal(master, dek, dekAAD(s.keyring.ActiveID))
+	if err != nil {
+		return nil, err
+	}
+	return (&header{keyID: s.keyring.ActiveID, wrappedDEK: wrapped, body: body}).encode(), nil
+}
+
+func (s *FileStore) unwrap(h *header) ([]byte, error) {
+	master, err := s.masterKey(h.keyID)
+	if err != nil {
+		return nil, err
+	}
+	return open(master, h.wrappedDEK, dekAAD(h.keyID))
+}
+
+func (s *FileStore) decrypt(filename string, raw []byte) ([]byte, error) {
+	h, err := parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	dek, err := s.unwrap(h)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key of %s: %w", filename, err)
+	}
+	plaintext, err := open(dek, h.body, []byte(filename))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s: %w", filename, err)
+	}
+	return plaintext, nil
+}
+
+// Save encrypts content with a fresh data key wrapped by the active master key.
+func (s *FileStore) Save(filename string, content []byte) (string, error) {
+	encrypted, err := s.encrypt(filename, content)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt %s: %w", filename, err)
+	}
+	return s.inner.Save(filename, encrypted)
+}
+
+// Get returns the decrypted content. Files written before encryption was enabled are
+// returned as-is until Rotate migrates them.
+func (s *FileStore) Get(filename string) ([]byte, error) {
+	raw, err := s.inner.Get(filename)
+	if err != nil {
+		return nil, err
+	}
+	if !isEncrypted(raw) {
+		return raw, nil
+	}
+	return s.decrypt(filename, raw)
+}
+
+// Open returns a reader on the decrypted content. GCM authenticates the whole file,
+// so it is decrypted in memory before being streamed (uploads are size-limited).
+func (s *FileStore) Open(filename string) (io.ReadCloser, error) {
+	content, err := s.Get(filename)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+func (s *FileStore) Exists(filename string) bool {
+	return s.inner.Exists(filename)
+}
+
+func (s *FileStore) Delete(filename string) error {
+	return s.inner.Delete(filename)
+}
+
+func (s *FileStore) List(prefix string) ([]string, error) {
+	return s.inner.List(prefix)
+}
+
+// RotationReport summarises a Rotate run.
+type RotationReport struct {
+	Encrypted []string // Plaintext files now encrypted
+	Rewrapped []string // Data key re-wrapped with the active master key
+	Current   int      // Already protected by the active key
+	Failed    map[string]error
+}
+
+// Rotate brings every file under the active master key: plaintext files are encrypted and
+// data keys wrapped by an older master key are re-wrapped (the content itself is untouched).
+// Once it reports no failure, retired master keys can be removed from the configuration.
+// With dryRun nothing is written.
+func (s *FileStore) Rotate(prefix string, dryRun bool) (*RotationReport, error) {
+	names, err := s.inner.List(prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+
+	report := &RotationReport{Failed: make(map[string]error)}
+	for _, name := range names {
+		raw, err := s.inner.Get(name)
+		if err != nil {
+			report.Failed[name] = err
+			continue
+		}
+
+		var updated []byte
+		rewrap := isEncrypted(raw)
+		if !rewrap {
+			if !dryRun {
+				updated, err = s.encrypt(name, raw)
+			}
+		} else {
+			h, perr := parse(raw)
+			if perr != nil {
+				report.Failed[name] = perr
+				continue
+			}
+			if h.keyID == s.keyring.ActiveID {
+				report.Current++
+				continue
+			}
+			if !dryRun {
+				updated, err = s.rewrap(h)
+			}
+		}
+		if err != nil {
+			report.Failed[name] = err
+			continue
+		}
+
+		// A file is only reported as done once its new version is saved
+		if updated != nil {
+			if _, err := s.inner.Save(name, updated); err != nil {
+				report.Failed[name] = err
+				continue
+			}
+		}
+		if rewrap {
+			report.Rewrapped = append(report.Rewrapped, name)
+		} else {
+			report.Encrypted = append(report.Encrypted, name)
+		}
+	}
+	return report, nil
+}
+
+func (s *FileStore) rewrap(h *header) ([]byte, error) {
+	dek, err := s.unwrap(h)
+	if err != nil {
+		return nil, err
+	}
+	master, err := s.masterKey(s.keyring.ActiveID)
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := seal(master, dek, dekAAD(s.keyring.ActiveID))
+	if err != nil {
+		return nil, err
+	}
+	return (&header{keyID: s.keyring.ActiveID, wrappedDEK: wrapped, body: h.body}).encode(), nil
+}
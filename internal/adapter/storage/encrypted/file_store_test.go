@@ -0,0 +1,173 @@
+package encrypted
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"seculoc-back/internal/adapter/storage/local"
+)
+
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, keySize))
+}
+
+func newTestStore(t *testing.T, spec, active string) (*FileStore, *local.FileStore) {
+	t.Helper()
+	kr, err := ParseKeyring(spec, active)
+	require.NoError(t, err)
+	inner := &local.FileStore{BaseDir: t.TempDir()}
+	return New(inner, kr), inner
+}
+
+func TestParseKeyring(t *testing.T) {
+	kr, err := ParseKeyring("k1:"+testKey(1), "")
+	require.NoError(t, err)
+	assert.Equal(t, "k1", kr.ActiveID)
+
+	_, err = ParseKeyring("k1:"+testKey(1)+",k2:"+testKey(2), "k3")
+	assert.Error(t, err)
+	_, err = ParseKeyring("k1:"+base64.StdEncoding.EncodeToString([]byte("short")), "k1")
+	assert.Error(t, err)
+	_, err = ParseKeyring("", "k1")
+	assert.Error(t, err)
+}
+
+func TestFileStore_EncryptsAtRest(t *testing.T) {
+	store, inner := newTestStore(t, "k1:"+testKey(1), "k1")
+
+	content := []byte("%PDF-1.4 bulletin de salaire")
+	_, err := store.Save("solvency/1/payslip.pdf", content)
+	require.NoError(t, err)
+
+	raw, err := inner.Get("solvency/1/payslip.pdf")
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(raw, magic))
+	assert.NotContains(t, string(raw), "bulletin")
+	h, err := parse(raw)
+	require.NoError(t, err)
+	assert.Equal(t, "k1", h.keyID)
+
+	got, err := store.Get("solvency/1/payslip.pdf")
+	require.NoError(t, err)
+	assert.Equal(t, content, got)
+
+	reader, err := store.Open("solvency/1/payslip.pdf")
+	require.NoError(t, err)
+	streamed, _ := io.ReadAll(reader)
+	assert.Equal(t, content, streamed)
+}
+
+func TestFileStore_RejectsTamperingAndSwaps(t *testing.T) {
+	store, inner := newTestStore(t, "k1:"+testKey(1), "k1")
+	_, err := store.Save("a.pdf", []byte("A"))
+	require.NoError(t, err)
+
+	raw, _ := inner.Get("a.pdf")
+
+	// Ciphertext moved under another name is rejected
+	_, err = inner.Save("b.pdf", raw)
+	require.NoError(t, err)
+	_, err = store.Get("b.pdf")
+	assert.ErrorIs(t, err, ErrCorrupted)
+
+	tampered := append([]byte{}, raw...)
+	tampered[len(tampered)-1] ^= 0xff
+	_, err = inner.Save("a.pdf", tampered)
+	require.NoError(t, err)
+	_, err = store.Get("a.pdf")
+	assert.ErrorIs(t, err, ErrCorrupted)
+}
+
+func TestFileStore_RotateEncryptsPlaintextAndRewraps(t *testing.T) {
+	old, inner := newTestStore(t, "k1:"+testKey(1), "k1")
+	_, err := old.Save("documents/lease/1/v1.md", []byte("bail"))
+	require.NoError(t, err)
+	_, err = inner.Save("lease_2.html", []byte("<p>legacy</p>"))
+	require.NoError(t, err)
+
+	kr, err := ParseKeyring("k1:"+testKey(1)+",k2:"+testKey(2), "k2")
+	require.NoError(t, err)
+	store := New(inner, kr)
+
+	// Legacy plaintext files are still readable before the migration
+	got, err := store.Get("lease_2.html")
+	require.NoError(t, err)
+	assert.Equal(t, "<p>legacy</p>", string(got))
+
+	before, _ := inner.Get("documents/lease/1/v1.md")
+	dry, err := store.Rotate("", true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"lease_2.html"}, dry.Encrypted)
+	assert.Equal(t, []string{"documents/lease/1/v1.md"}, dry.Rewrapped)
+	after, _ := inner.Get("documents/lease/1/v1.md")
+	assert.Equal(t, before, after)
+
+	report, err := store.Rotate("", false)
+	require.NoError(t, err)
+	assert.Len(t, report.Encrypted, 1)
+	assert.Len(t, report.Rewrapped, 1)
+	assert.Empty(t, report.Failed)
+
+	// The content is untouched, only the header changes
+	rewrapped, _ := inner.Get("documents/lease/1/v1.md")
+	hBefore, _ := parse(before)
+	hAfter, err := parse(rewrapped)
+	require.NoError(t, err)
+	assert.Equal(t, "k2", hAfter.keyID)
+	assert.Equal(t, hBefore.body, hAfter.body)
+
+	// The retired key is no longer needed
+	current, _ := ParseKeyring("k2:"+testKey(2), "k2")
+	store = New(inner, current)
+	for name, want := range map[string]string{"documents/lease/1/v1.md": "bail", "lease_2.html": "<p>legacy</p>"} {
+		got, err := store.Get(name)
+		require.NoError(t, err)
+		assert.Equal(t, want, string(got))
+	}
+
+	again, err := store.Rotate("", false)
+	require.NoError(t, err)
+	assert.Equal(t, 2, again.Current)
+}
+
+// readOnlyStore refuses every write, as a full disk or a bucket without write access would.
+type readOnlyStore struct {
+	*local.FileStore
+}
+
+func (readOnlyStore) Save(string, []byte) (string, error) {
+	return "", errors.New("read-only")
+}
+
+func TestFileStore_RotateReportsFailedSaves(t *testing.T) {
+	old, inner := newTestStore(t, "k1:"+testKey(1), "k1")
+	_, err := old.Save("documents/lease/1/v1.md", []byte("bail"))
+	require.NoError(t, err)
+	_, err = inner.Save("lease_2.html", []byte("<p>legacy</p>"))
+	require.NoError(t, err)
+
+	kr, err := ParseKeyring("k1:"+testKey(1)+",k2:"+testKey(2), "k2")
+	require.NoError(t, err)
+	report, err := New(readOnlyStore{inner}, kr).Rotate("", false)
+
+	require.NoError(t, err)
+	assert.Empty(t, report.Encrypted)
+	assert.Empty(t, report.Rewrapped)
+	assert.Len(t, report.Failed, 2)
+}
+
+func TestFileStore_UnknownKey(t *testing.T) {
+	old, inner := newTestStore(t, "k1:"+testKey(1), "k1")
+	_, err := old.Save("a.pdf", []byte("A"))
+	require.NoError(t, err)
+
+	kr, _ := ParseKeyring("k2:"+testKey(2), "k2")
+	_, err = New(inner, kr).Get("a.pdf")
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
@@ -1,9 +1,14 @@
 package local
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/spf13/viper"
 )
@@ -26,8 +31,24 @@ func NewFileStore() (*FileStore, error) {
 	return &FileStore{BaseDir: baseDir}, nil
 }
 
+// path resolves a storage name (which may contain "/" sub-folders) inside BaseDir.
+// Names escaping BaseDir (e.g. "../x") are rejected.
+func (s *FileStore) path(filename string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(filename))
+	if clean == "." || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid file name %q", filename)
+	}
+	return filepath.Join(s.BaseDir, clean), nil
+}
+
 func (s *FileStore) Save(filename string, content []byte) (string, error) {
-	fullPath := filepath.Join(s.BaseDir, filename)
+	fullPath, err := s.path(filename)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory for %s: %w", filename, err)
+	}
 	if err := os.WriteFile(fullPath, content, 0644); err != nil {
 		return "", fmt.Errorf("failed to write file %s: %w", filename, err)
 	}
@@ -35,12 +56,66 @@ func (s *FileStore) Save(filename string, content []byte) (string, error) {
 }
 
 func (s *FileStore) Get(filename string) ([]byte, error) {
-	fullPath := filepath.Join(s.BaseDir, filename)
+	fullPath, err := s.path(filename)
+	if err != nil {
+		return nil, err
+	}
 	return os.ReadFile(fullPath)
 }
 
 func (s *FileStore) Exists(filename string) bool {
-	fullPath := filepath.Join(s.BaseDir, filename)
-	_, err := os.Stat(fullPath)
+	fullPath, err := s.path(filename)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(fullPath)
 	return !os.IsNotExist(err)
 }
+
+// Delete removes a file. Deleting a missing file is not an error.
+func (s *FileStore) Delete(filename string) error {
+	fullPath, err := s.path(filename)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(fullPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete file %s: %w", filename, err)
+	}
+	return nil
+}
+
+// List returns the names (slash separated, relative to BaseDir) of all files starting with prefix.
+func (s *FileStore) List(prefix string) ([]string, error) {
+	var names []string
+	err := filepath.WalkDir(s.BaseDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(s.BaseDir, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Open returns a reader on the stored file. The caller must close it.
+func (s *FileStore) Open(filename string) (io.ReadCloser, error) {
+	fullPath, err := s.path(filename)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(fullPath)
+}
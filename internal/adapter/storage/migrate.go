@@ -0,0 +1,55 @@
+// Package storage holds helpers shared by the FileStorage implementations (local, s3).
+package storage
+
+import (
+	"fmt"
+)
+
+// Store is the subset of FileStorage needed to copy files between backends.
+type Store interface {
+	Get(filename string) ([]byte, error)
+	Save(filename string, content []byte) (string, error)
+	Exists(filename string) bool
+	List(prefix string) ([]string, error)
+}
+
+// MigrationReport summarises a Migrate run.
+type MigrationReport struct {
+	Copied  []string
+	Skipped []string // Already present in the destination
+	Failed  map[string]error
+}
+
+// Migrate copies every file of src starting with prefix into dst, keeping the same names.
+// Files already in dst are skipped unless overwrite is set. With dryRun nothing is written.
+// A failing file does not stop the migration: it is reported in Failed so the run can be retried.
+func Migrate(src, dst Store, prefix string, overwrite, dryRun bool) (*MigrationReport, error) {
+	names, err := src.List(prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list source files: %w", err)
+	}
+
+	report := &MigrationReport{Failed: make(map[string]error)}
+	for _, name := range names {
+		if !overwrite && dst.Exists(name) {
+			report.Skipped = append(report.Skipped, name)
+			continue
+		}
+		if dryRun {
+			report.Copied = append(report.Copied, name)
+			continue
+		}
+
+		content, err := src.Get(name)
+		if err != nil {
+			report.Failed[name] = fmt.Errorf("read: %w", err)
+			continue
+		}
+		if _, err := dst.Save(name, content); err != nil {
+			report.Failed[name] = fmt.Errorf("write: %w", err)
+			continue
+		}
+		report.Copied = append(report.Copied, name)
+	}
+	return report, nil
+}
@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"seculoc-back/internal/adapter/storage/local"
+	"seculoc-back/internal/adapter/storage/s3"
+	"seculoc-back/internal/adapter/storage/s3/s3test"
+)
+
+func TestMigrate_LocalToS3(t *testing.T) {
+	src := &local.FileStore{BaseDir: t.TempDir()}
+	for name, content := range map[string]string{
+		"lease_1_v1.md":                   "# Bail 1",
+		"properties/3/photos/a.jpg":       "jpeg",
+		"properties/3/photos/a_thumb.jpg": "thumb",
+	} {
+		_, err := src.Save(name, []byte(content))
+		require.NoError(t, err)
+	}
+
+	fake := s3test.NewServer("seculoc", "key")
+	defer fake.Close()
+	dst, err := s3.New(s3.Config{Endpoint: fake.URL, Bucket: "seculoc", AccessKeyID: "key", SecretAccessKey: "secret", PathStyle: true}, fake.Client())
+	require.NoError(t, err)
+
+	// Already migrated files are left untouched
+	_, err = dst.Save("lease_1_v1.md", []byte("already there"))
+	require.NoError(t, err)
+
+	dry, err := Migrate(src, dst, "", false, true)
+	require.NoError(t, err)
+	assert.Len(t, dry.Copied, 2)
+	assert.Equal(t, []string{"lease_1_v1.md"}, fake.Keys())
+
+	report, err := Migrate(src, dst, "", false, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"properties/3/photos/a.jpg", "properties/3/photos/a_thumb.jpg"}, report.Copied)
+	assert.Equal(t, []string{"lease_1_v1.md"}, report.Skipped)
+	assert.Empty(t, report.Failed)
+
+	obj, _ := fake.Object("properties/3/photos/a.jpg")
+	assert.Equal(t, "jpeg", string(obj.Data))
+	obj, _ = fake.Object("lease_1_v1.md")
+	assert.Equal(t, "already there", string(obj.Data))
+}
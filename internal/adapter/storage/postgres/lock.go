@@ -0,0 +1,46 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// jobLockClass namespaces the advisory locks taken for background jobs.
+const jobLockClass = 1
+
+// AdvisoryLocker serializes background jobs across instances with session-level advisory locks.
+type AdvisoryLocker struct {
+	db *pgxpool.Pool
+}
+
+func NewAdvisoryLocker(db *pgxpool.Pool) *AdvisoryLocker {
+	return &AdvisoryLocker{db: db}
+}
+
+// TryLock takes the advisory lock named name without waiting. The lock belongs to the session: the
+// connection is kept out of the pool until release.
+func (l *AdvisoryLocker) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	conn, err := l.db.Acquire(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	var acquired bool
+	if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1, hashtext($2))", jobLockClass, name).Scan(&acquired); err != nil {
+		conn.Release()
+		return nil, false, fmt.Errorf("failed to take advisory lock %s: %w", name, err)
+	}
+	if !acquired {
+		conn.Release()
+		return nil, false, nil
+	}
+	release := func() {
+		// A canceled ctx must not leave the lock held by a pooled connection
+		if _, err := conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1, hashtext($2))", jobLockClass, name); err != nil {
+			conn.Conn().Close(context.Background())
+		}
+		conn.Release()
+	}
+	return release, true, nil
+}
@@ -147,6 +147,7 @@ const (
 	SolvencyStatusRejected         SolvencyStatus = "rejected"
 	SolvencyStatusInsufficientDocs SolvencyStatus = "insufficient_docs"
 	SolvencyStatusCancelled        SolvencyStatus = "cancelled"
+	SolvencyStatusExpired          SolvencyStatus = "expired"
 )
 
 func (e *SolvencyStatus) Scan(src interface{}) error {
@@ -269,38 +270,162 @@ func (ns NullUserRole) Value() (driver.Value, error) {
 	return string(ns.UserRole), nil
 }
 
+type CatalogItem struct {
+	ID                int32            `json:"id"`
+	Code              string           `json:"code"`
+	Kind              string           `json:"kind"`
+	Name              string           `json:"name"`
+	PlanType          NullSubPlan      `json:"plan_type"`
+	MonthlyPriceCents pgtype.Int4      `json:"monthly_price_cents"`
+	YearlyPriceCents  pgtype.Int4      `json:"yearly_price_cents"`
+	PriceCents        pgtype.Int4      `json:"price_cents"`
+	MaxProperties     int32            `json:"max_properties"`
+	IncludedCredits   int32            `json:"included_credits"`
+	SlotPriceCents    pgtype.Int4      `json:"slot_price_cents"`
+	ValidFrom         pgtype.Timestamp `json:"valid_from"`
+	ValidUntil        pgtype.Timestamp `json:"valid_until"`
+	CreatedAt         pgtype.Timestamp `json:"created_at"`
+	UpdatedAt         pgtype.Timestamp `json:"updated_at"`
+}
+
+type CreditAccount struct {
+	ID                int32            `json:"id"`
+	Kind              string           `json:"kind"`
+	UserID            pgtype.Int4      `json:"user_id"`
+	PropertyID        pgtype.Int4      `json:"property_id"`
+	Balance           int32            `json:"balance"`
+	LastTransactionID pgtype.Int4      `json:"last_transaction_id"`
+	CreatedAt         pgtype.Timestamp `json:"created_at"`
+	UpdatedAt         pgtype.Timestamp `json:"updated_at"`
+}
+
 type CreditTransaction struct {
 	ID              int32            `json:"id"`
 	UserID          pgtype.Int4      `json:"user_id"`
 	Amount          int32            `json:"amount"`
 	TransactionType string           `json:"transaction_type"`
 	Description     pgtype.Text      `json:"description"`
+	RefundOf        pgtype.Int4      `json:"refund_of"`
 	CreatedAt       pgtype.Timestamp `json:"created_at"`
+	PropertyID      pgtype.Int4      `json:"property_id"`
+	BalanceAfter    pgtype.Int4      `json:"balance_after"`
+	AccountID       pgtype.Int4      `json:"account_id"`
+	EntryID         pgtype.Int4      `json:"entry_id"`
+}
+
+type Document struct {
+	ID           int32            `json:"id"`
+	DocumentType string           `json:"document_type"`
+	EntityID     int32            `json:"entity_id"`
+	Version      int32            `json:"version"`
+	StorageKey   string           `json:"storage_key"`
+	ContentType  string           `json:"content_type"`
+	Filename     string           `json:"filename"`
+	CreatedAt    pgtype.Timestamp `json:"created_at"`
+}
+
+type DocumentAccessLog struct {
+	ID         int32            `json:"id"`
+	LinkID     pgtype.Int4      `json:"link_id"`
+	DocumentID int32            `json:"document_id"`
+	UserID     int32            `json:"user_id"`
+	IpAddress  pgtype.Text      `json:"ip_address"`
+	UserAgent  pgtype.Text      `json:"user_agent"`
+	AccessedAt pgtype.Timestamp `json:"accessed_at"`
+}
+
+type DocumentLink struct {
+	ID         int32            `json:"id"`
+	DocumentID int32            `json:"document_id"`
+	UserID     int32            `json:"user_id"`
+	ExpiresAt  pgtype.Timestamp `json:"expires_at"`
+	RevokedAt  pgtype.Timestamp `json:"revoked_at"`
+	CreatedAt  pgtype.Timestamp `json:"created_at"`
+}
+
+type DossierShare struct {
+	ID        int32            `json:"id"`
+	DossierID int32            `json:"dossier_id"`
+	Token     string           `json:"token"`
+	Label     pgtype.Text      `json:"label"`
+	RevokedAt pgtype.Timestamp `json:"revoked_at"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+}
+
+type IdempotencyKey struct {
+	UserID              int32            `json:"user_id"`
+	IdempotencyKey      string           `json:"idempotency_key"`
+	RequestFingerprint  string           `json:"request_fingerprint"`
+	Status              string           `json:"status"`
+	ResponseCode        pgtype.Int4      `json:"response_code"`
+	ResponseContentType pgtype.Text      `json:"response_content_type"`
+	ResponseBody        []byte           `json:"response_body"`
+	CreatedAt           pgtype.Timestamp `json:"created_at"`
+	HeartbeatAt         pgtype.Timestamp `json:"heartbeat_at"`
+	CompletedAt         pgtype.Timestamp `json:"completed_at"`
+}
+
+type Invoice struct {
+	ID                 int32            `json:"id"`
+	UserID             int32            `json:"user_id"`
+	SubscriptionID     pgtype.Int4      `json:"subscription_id"`
+	Description        string           `json:"description"`
+	AmountCents        int32            `json:"amount_cents"`
+	PeriodStart        pgtype.Date      `json:"period_start"`
+	PeriodEnd          pgtype.Date      `json:"period_end"`
+	Status             string           `json:"status"`
+	Attempts           int32            `json:"attempts"`
+	LastAttemptAt      pgtype.Timestamp `json:"last_attempt_at"`
+	PaidAt             pgtype.Timestamp `json:"paid_at"`
+	CreatedAt          pgtype.Timestamp `json:"created_at"`
+	Kind               string           `json:"kind"`
+	CatalogItemID      pgtype.Int4      `json:"catalog_item_id"`
+	CreditedInvoiceID  pgtype.Int4      `json:"credited_invoice_id"`
+	Number             pgtype.Text      `json:"number"`
+	IssuedAt           pgtype.Timestamp `json:"issued_at"`
+	AmountExclVatCents pgtype.Int4      `json:"amount_excl_vat_cents"`
+	VatRateBps         pgtype.Int4      `json:"vat_rate_bps"`
+	VatCents           pgtype.Int4      `json:"vat_cents"`
+	BuyerName          pgtype.Text      `json:"buyer_name"`
+	BuyerEmail         pgtype.Text      `json:"buyer_email"`
+	DocumentID         pgtype.Int4      `json:"document_id"`
+	SlotQuantity       pgtype.Int4      `json:"slot_quantity"`
+	CreditPropertyID   pgtype.Int4      `json:"credit_property_id"`
+}
+
+type InvoiceSequence struct {
+	Series     string `json:"series"`
+	Year       int32  `json:"year"`
+	LastNumber int32  `json:"last_number"`
 }
 
 type Lease struct {
-	ID                  int32            `json:"id"`
-	PropertyID          pgtype.Int4      `json:"property_id"`
-	TenantID            pgtype.Int4      `json:"tenant_id"`
-	StartDate           pgtype.Date      `json:"start_date"`
-	EndDate             pgtype.Date      `json:"end_date"`
-	RentAmount          pgtype.Numeric   `json:"rent_amount"`
-	ChargesAmount       pgtype.Numeric   `json:"charges_amount"`
-	DepositAmount       pgtype.Numeric   `json:"deposit_amount"`
-	PaymentDay          pgtype.Int4      `json:"payment_day"`
-	SpecialClauses      []byte           `json:"special_clauses"`
-	LeaseStatus         pgtype.Text      `json:"lease_status"`
-	SignatureStatus     pgtype.Text      `json:"signature_status"`
-	SignatureEnvelopeID pgtype.Text      `json:"signature_envelope_id"`
-	ContractUrl         pgtype.Text      `json:"contract_url"`
-	EscrowDepositStatus NullEscrowStatus `json:"escrow_deposit_status"`
-	CreatedAt           pgtype.Timestamp `json:"created_at"`
+	ID                   int32            `json:"id"`
+	PropertyID           pgtype.Int4      `json:"property_id"`
+	TenantID             pgtype.Int4      `json:"tenant_id"`
+	StartDate            pgtype.Date      `json:"start_date"`
+	EndDate              pgtype.Date      `json:"end_date"`
+	RentAmount           pgtype.Numeric   `json:"rent_amount"`
+	ChargesAmount        pgtype.Numeric   `json:"charges_amount"`
+	DepositAmount        pgtype.Numeric   `json:"deposit_amount"`
+	PaymentDay           pgtype.Int4      `json:"payment_day"`
+	SpecialClauses       []byte           `json:"special_clauses"`
+	LeaseStatus          pgtype.Text      `json:"lease_status"`
+	SignatureStatus      pgtype.Text      `json:"signature_status"`
+	SignatureEnvelopeID  pgtype.Text      `json:"signature_envelope_id"`
+	ContractUrl          pgtype.Text      `json:"contract_url"`
+	EscrowDepositStatus  NullEscrowStatus `json:"escrow_deposit_status"`
+	JointLiability       pgtype.Bool      `json:"joint_liability"`
+	IndividualRentShares pgtype.Bool      `json:"individual_rent_shares"`
+	AnonymizedAt         pgtype.Timestamp `json:"anonymized_at"`
+	CreatedAt            pgtype.Timestamp `json:"created_at"`
 }
 
 type LeaseInvitation struct {
 	ID          int32            `json:"id"`
 	PropertyID  int32            `json:"property_id"`
 	LeaseID     pgtype.Int4      `json:"lease_id"`
+	PartyID     pgtype.Int4      `json:"party_id"`
 	OwnerID     int32            `json:"owner_id"`
 	TenantEmail string           `json:"tenant_email"`
 	Token       string           `json:"token"`
@@ -309,6 +434,23 @@ type LeaseInvitation struct {
 	CreatedAt   pgtype.Timestamp `json:"created_at"`
 }
 
+type LeaseParty struct {
+	ID               int32            `json:"id"`
+	LeaseID          int32            `json:"lease_id"`
+	UserID           pgtype.Int4      `json:"user_id"`
+	Email            string           `json:"email"`
+	FirstName        pgtype.Text      `json:"first_name"`
+	LastName         pgtype.Text      `json:"last_name"`
+	RentShare        pgtype.Numeric   `json:"rent_share"`
+	Status           string           `json:"status"`
+	JoinedAt         pgtype.Timestamp `json:"joined_at"`
+	NoticeDate       pgtype.Date      `json:"notice_date"`
+	LeftAt           pgtype.Date      `json:"left_at"`
+	SolidarityEndsAt pgtype.Date      `json:"solidarity_ends_at"`
+	ReplacesPartyID  pgtype.Int4      `json:"replaces_party_id"`
+	CreatedAt        pgtype.Timestamp `json:"created_at"`
+}
+
 type Property struct {
 	ID                    int32            `json:"id"`
 	OwnerID               pgtype.Int4      `json:"owner_id"`
@@ -326,6 +468,23 @@ type Property struct {
 	CreatedAt             pgtype.Timestamp `json:"created_at"`
 }
 
+type PropertyMedium struct {
+	ID               int32            `json:"id"`
+	PropertyID       int32            `json:"property_id"`
+	MediaKind        string           `json:"media_kind"`
+	DiagnosticType   pgtype.Text      `json:"diagnostic_type"`
+	OriginalFilename string           `json:"original_filename"`
+	StorageKey       string           `json:"storage_key"`
+	ThumbnailKey     pgtype.Text      `json:"thumbnail_key"`
+	ContentType      string           `json:"content_type"`
+	SizeBytes        int64            `json:"size_bytes"`
+	Position         int32            `json:"position"`
+	IsCover          bool             `json:"is_cover"`
+	ExpiresAt        pgtype.Date      `json:"expires_at"`
+	ReminderSentAt   pgtype.Timestamp `json:"reminder_sent_at"`
+	CreatedAt        pgtype.Timestamp `json:"created_at"`
+}
+
 type RentPayment struct {
 	ID                int32            `json:"id"`
 	LeaseID           pgtype.Int4      `json:"lease_id"`
@@ -337,6 +496,17 @@ type RentPayment struct {
 	IsSepaDirectDebit pgtype.Bool      `json:"is_sepa_direct_debit"`
 }
 
+type RetentionPurge struct {
+	ID            int32            `json:"id"`
+	Category      string           `json:"category"`
+	RetentionDays int32            `json:"retention_days"`
+	Cutoff        pgtype.Timestamp `json:"cutoff"`
+	Records       int32            `json:"records"`
+	Files         int32            `json:"files"`
+	RecordIds     []byte           `json:"record_ids"`
+	PurgedAt      pgtype.Timestamp `json:"purged_at"`
+}
+
 type SeasonalBooking struct {
 	ID                 int32            `json:"id"`
 	PropertyID         pgtype.Int4      `json:"property_id"`
@@ -353,29 +523,130 @@ type SeasonalBooking struct {
 }
 
 type SolvencyCheck struct {
-	ID               int32              `json:"id"`
-	InitiatorOwnerID pgtype.Int4        `json:"initiator_owner_id"`
-	CandidateID      pgtype.Int4        `json:"candidate_id"`
-	Token            pgtype.Text        `json:"token"`
-	PropertyID       pgtype.Int4        `json:"property_id"`
-	Status           NullSolvencyStatus `json:"status"`
-	CreditSource     pgtype.Text        `json:"credit_source"`
-	ScoreResult      pgtype.Int4        `json:"score_result"`
-	ReportUrl        pgtype.Text        `json:"report_url"`
-	DocumentsJson    []byte             `json:"documents_json"`
-	CreatedAt        pgtype.Timestamp   `json:"created_at"`
+	ID                  int32              `json:"id"`
+	InitiatorOwnerID    pgtype.Int4        `json:"initiator_owner_id"`
+	CandidateID         pgtype.Int4        `json:"candidate_id"`
+	Token               pgtype.Text        `json:"token"`
+	PropertyID          pgtype.Int4        `json:"property_id"`
+	Status              NullSolvencyStatus `json:"status"`
+	CreditSource        pgtype.Text        `json:"credit_source"`
+	ScoreResult         pgtype.Int4        `json:"score_result"`
+	AnalysisJson        []byte             `json:"analysis_json"`
+	ReportUrl           pgtype.Text        `json:"report_url"`
+	DocumentsJson       []byte             `json:"documents_json"`
+	MissingDocuments    []byte             `json:"missing_documents"`
+	BankProvider        pgtype.Text        `json:"bank_provider"`
+	BankConsentID       pgtype.Text        `json:"bank_consent_id"`
+	BankConnectionID    pgtype.Text        `json:"bank_connection_id"`
+	EmploymentType      pgtype.Text        `json:"employment_type"`
+	GuaranteeType       pgtype.Text        `json:"guarantee_type"`
+	PolicyResults       []byte             `json:"policy_results"`
+	CombinedScore       pgtype.Int4        `json:"combined_score"`
+	ExpiresAt           pgtype.Timestamp   `json:"expires_at"`
+	RemindersSent       int32              `json:"reminders_sent"`
+	Selection           pgtype.Text        `json:"selection"`
+	SelectionAt         pgtype.Timestamp   `json:"selection_at"`
+	DocumentsPurgedAt   pgtype.Timestamp   `json:"documents_purged_at"`
+	AnonymizedAt        pgtype.Timestamp   `json:"anonymized_at"`
+	CreatedAt           pgtype.Timestamp   `json:"created_at"`
+	DossierShareID      pgtype.Int4        `json:"dossier_share_id"`
+	CreditTransactionID pgtype.Int4        `json:"credit_transaction_id"`
+}
+
+type SolvencyGuarantor struct {
+	ID               int32            `json:"id"`
+	CheckID          int32            `json:"check_id"`
+	Email            string           `json:"email"`
+	FirstName        pgtype.Text      `json:"first_name"`
+	LastName         pgtype.Text      `json:"last_name"`
+	PhoneNumber      pgtype.Text      `json:"phone_number"`
+	Token            string           `json:"token"`
+	Status           string           `json:"status"`
+	DocumentsJson    []byte           `json:"documents_json"`
+	BankProvider     pgtype.Text      `json:"bank_provider"`
+	BankConsentID    pgtype.Text      `json:"bank_consent_id"`
+	BankConnectionID pgtype.Text      `json:"bank_connection_id"`
+	MonthlyIncome    pgtype.Numeric   `json:"monthly_income"`
+	AnalysisJson     []byte           `json:"analysis_json"`
+	LeaseID          pgtype.Int4      `json:"lease_id"`
+	MentionText      pgtype.Text      `json:"mention_text"`
+	MentionSignedAt  pgtype.Timestamp `json:"mention_signed_at"`
+	CreatedAt        pgtype.Timestamp `json:"created_at"`
+}
+
+type SolvencyPolicy struct {
+	ID                   int32            `json:"id"`
+	OwnerID              int32            `json:"owner_id"`
+	PropertyID           pgtype.Int4      `json:"property_id"`
+	IncomeMultiplier     pgtype.Numeric   `json:"income_multiplier"`
+	IncludeCharges       bool             `json:"include_charges"`
+	CountGuarantorIncome bool             `json:"count_guarantor_income"`
+	MinEmploymentType    pgtype.Text      `json:"min_employment_type"`
+	AcceptedGuarantees   []byte           `json:"accepted_guarantees"`
+	RequireGuarantee     bool             `json:"require_guarantee"`
+	CreatedAt            pgtype.Timestamp `json:"created_at"`
+	UpdatedAt            pgtype.Timestamp `json:"updated_at"`
 }
 
 type Subscription struct {
-	ID                 int32            `json:"id"`
-	UserID             pgtype.Int4      `json:"user_id"`
-	PlanType           SubPlan          `json:"plan_type"`
-	Frequency          NullBillingFreq  `json:"frequency"`
-	Status             pgtype.Text      `json:"status"`
-	StartDate          pgtype.Date      `json:"start_date"`
-	EndDate            pgtype.Date      `json:"end_date"`
-	MaxPropertiesLimit pgtype.Int4      `json:"max_properties_limit"`
-	CreatedAt          pgtype.Timestamp `json:"created_at"`
+	ID                           int32            `json:"id"`
+	UserID                       pgtype.Int4      `json:"user_id"`
+	PlanType                     SubPlan          `json:"plan_type"`
+	Frequency                    NullBillingFreq  `json:"frequency"`
+	Status                       pgtype.Text      `json:"status"`
+	StartDate                    pgtype.Date      `json:"start_date"`
+	EndDate                      pgtype.Date      `json:"end_date"`
+	MaxPropertiesLimit           pgtype.Int4      `json:"max_properties_limit"`
+	CurrentPeriodStart           pgtype.Date      `json:"current_period_start"`
+	CurrentPeriodEnd             pgtype.Date      `json:"current_period_end"`
+	NextCreditGrant              pgtype.Date      `json:"next_credit_grant"`
+	GrantedCredits               int32            `json:"granted_credits"`
+	CreatedAt                    pgtype.Timestamp `json:"created_at"`
+	CatalogItemID                pgtype.Int4      `json:"catalog_item_id"`
+	CancelAtPeriodEnd            bool             `json:"cancel_at_period_end"`
+	ScheduledCatalogItemID       pgtype.Int4      `json:"scheduled_catalog_item_id"`
+	ScheduledArchivedPropertyIds []int32          `json:"scheduled_archived_property_ids"`
+	EndedAt                      pgtype.Date      `json:"ended_at"`
+}
+
+type SubscriptionEvent struct {
+	ID                  int32            `json:"id"`
+	SubscriptionID      int32            `json:"subscription_id"`
+	UserID              int32            `json:"user_id"`
+	EventType           string           `json:"event_type"`
+	FromCatalogItemID   pgtype.Int4      `json:"from_catalog_item_id"`
+	ToCatalogItemID     pgtype.Int4      `json:"to_catalog_item_id"`
+	EffectiveDate       pgtype.Date      `json:"effective_date"`
+	AmountCents         pgtype.Int4      `json:"amount_cents"`
+	InvoiceID           pgtype.Int4      `json:"invoice_id"`
+	ArchivedPropertyIds []int32          `json:"archived_property_ids"`
+	CreatedAt           pgtype.Timestamp `json:"created_at"`
+	SlotQuantity        pgtype.Int4      `json:"slot_quantity"`
+}
+
+type SubscriptionItem struct {
+	ID                int32            `json:"id"`
+	SubscriptionID    int32            `json:"subscription_id"`
+	Kind              string           `json:"kind"`
+	Quantity          int32            `json:"quantity"`
+	UnitPriceCents    int32            `json:"unit_price_cents"`
+	ScheduledQuantity pgtype.Int4      `json:"scheduled_quantity"`
+	CreatedAt         pgtype.Timestamp `json:"created_at"`
+	UpdatedAt         pgtype.Timestamp `json:"updated_at"`
+}
+
+type TenantDossier struct {
+	ID             int32            `json:"id"`
+	UserID         int32            `json:"user_id"`
+	SourceCheckID  pgtype.Int4      `json:"source_check_id"`
+	DocumentsJson  []byte           `json:"documents_json"`
+	AnalysisJson   []byte           `json:"analysis_json"`
+	EmploymentType pgtype.Text      `json:"employment_type"`
+	GuaranteeType  pgtype.Text      `json:"guarantee_type"`
+	VerifiedAt     pgtype.Timestamp `json:"verified_at"`
+	ExpiresAt      pgtype.Timestamp `json:"expires_at"`
+	CreatedAt      pgtype.Timestamp `json:"created_at"`
+	UpdatedAt      pgtype.Timestamp `json:"updated_at"`
 }
 
 type Transaction struct {
@@ -387,7 +658,9 @@ type Transaction struct {
 	Currency              pgtype.Text      `json:"currency"`
 	Direction             pgtype.Text      `json:"direction"`
 	StripePaymentIntentID pgtype.Text      `json:"stripe_payment_intent_id"`
+	StripeRefundID        pgtype.Text      `json:"stripe_refund_id"`
 	Status                pgtype.Text      `json:"status"`
+	FailureReason         pgtype.Text      `json:"failure_reason"`
 	CreatedAt             pgtype.Timestamp `json:"created_at"`
 }
 
@@ -400,12 +673,18 @@ type User struct {
 	PhoneNumber      pgtype.Text      `json:"phone_number"`
 	IsVerified       pgtype.Bool      `json:"is_verified"`
 	StripeCustomerID pgtype.Text      `json:"stripe_customer_id"`
+	SepaMandateID    pgtype.Text      `json:"sepa_mandate_id"`
 	IsProvisional    pgtype.Bool      `json:"is_provisional"`
 	LastContextUsed  pgtype.Text      `json:"last_context_used"`
+	Role             UserRole         `json:"role"`
+	DeletedAt        pgtype.Timestamp `json:"deleted_at"`
 	CreatedAt        pgtype.Timestamp `json:"created_at"`
 }
 
-type ViewUserCreditBalance struct {
-	UserID         pgtype.Int4 `json:"user_id"`
-	CurrentBalance int64       `json:"current_balance"`
+type WebhookEvent struct {
+	ID         int32            `json:"id"`
+	Provider   string           `json:"provider"`
+	EventID    string           `json:"event_id"`
+	EventType  string           `json:"event_type"`
+	ReceivedAt pgtype.Timestamp `json:"received_at"`
 }
@@ -11,52 +11,289 @@ import (
 )
 
 type Querier interface {
-	CancelSolvencyCheck(ctx context.Context, id int32) error
-	CleanupProvisionalUsers(ctx context.Context) error
+	ActivateLeaseParty(ctx context.Context, arg ActivateLeasePartyParams) error
+	// A removal scheduled at the period end keeps the quantity added.
+	AddSubscriptionItemQuantity(ctx context.Context, arg AddSubscriptionItemQuantityParams) (SubscriptionItem, error)
+	// Back to 'active' once the period is paid; end_date follows the period for prepaid (yearly) plans.
+	AdvanceSubscriptionPeriod(ctx context.Context, arg AdvanceSubscriptionPeriodParams) error
+	AnonymizeLease(ctx context.Context, id int32) error
+	AnonymizeLeaseGuarantors(ctx context.Context, leaseID pgtype.Int4) error
+	AnonymizeLeaseInvitations(ctx context.Context, leaseID pgtype.Int4) error
+	AnonymizeLeaseParties(ctx context.Context, leaseID int32) error
+	// Keeps the status and scores for the owner's statistics.
+	AnonymizeSolvencyCheck(ctx context.Context, id int32) error
+	// The row stays for the financial records and contracts referring to it; the email is freed.
+	AnonymizeUser(ctx context.Context, id int32) error
+	ArchiveProperties(ctx context.Context, arg ArchivePropertiesParams) (int64, error)
+	// Garants complétés (analyse bancaire ou pièces) du dernier dossier accepté du locataire pour ce bien
+	AttachGuarantorsToLease(ctx context.Context, arg AttachGuarantorsToLeaseParams) ([]SolvencyGuarantor, error)
+	// Only a check still waiting for the candidate is cancelled: a concurrent decision or expiry wins
+	CancelSolvencyCheck(ctx context.Context, id int32) (int64, error)
+	CancelUserSubscriptions(ctx context.Context, userID pgtype.Int4) error
+	// Applies a plan change; any change scheduled for the period end is dropped.
+	ChangeSubscriptionPlan(ctx context.Context, arg ChangeSubscriptionPlanParams) error
+	// Reserves an invoice for the instance about to charge it. A claim older than @stale_before was left by
+	// an instance that stopped mid-charge: it is taken over, with the same attempt number.
+	ClaimInvoiceForCollection(ctx context.Context, arg ClaimInvoiceForCollectionParams) (Invoice, error)
+	// Provisional accounts nobody refers to any more (candidates are detached when anonymised).
+	CleanupProvisionalUsers(ctx context.Context, createdAt pgtype.Timestamp) ([]int32, error)
+	ClearGuarantorDocumentsByCheck(ctx context.Context, checkID int32) error
+	ClearPropertyCover(ctx context.Context, propertyID int32) error
+	// Dossier de garant complété par pièces justificatives, sans analyse bancaire
+	CompleteGuarantorDocuments(ctx context.Context, id int32) error
+	CompleteIdempotencyKey(ctx context.Context, arg CompleteIdempotencyKeyParams) error
 	CountBookingsByTenant(ctx context.Context, tenantID pgtype.Int4) (int64, error)
+	CountCreditTransactions(ctx context.Context, arg CountCreditTransactionsParams) (int64, error)
+	CountGuarantorsByCheck(ctx context.Context, checkID int32) (int64, error)
+	// Signed leases still running; drafts do not bind the owner.
+	CountLeasesByOwner(ctx context.Context, ownerID pgtype.Int4) (int64, error)
 	CountLeasesByTenant(ctx context.Context, tenantID pgtype.Int4) (int64, error)
+	// Versions of the same offer whose validity period overlaps [valid_from, valid_until).
+	CountOverlappingCatalogItems(ctx context.Context, arg CountOverlappingCatalogItemsParams) (int64, error)
 	CountPropertiesByOwner(ctx context.Context, ownerID pgtype.Int4) (int64, error)
 	CountPropertiesByOwnerAndType(ctx context.Context, arg CountPropertiesByOwnerAndTypeParams) (int64, error)
+	CreateCatalogItem(ctx context.Context, arg CreateCatalogItemParams) (CatalogItem, error)
+	CreateCreditNote(ctx context.Context, arg CreateCreditNoteParams) (Invoice, error)
+	// Credits (positive amount) or debits a wallet against the system account, as one balanced entry: the
+	// user's global wallet, or the wallet of property_id when set. The system line is inserted first and only
+	// the wallet line is returned. A refund names the line it gives back in refund_of, which can be refunded
+	// only once.
 	CreateCreditTransaction(ctx context.Context, arg CreateCreditTransactionParams) (CreditTransaction, error)
+	// Moves credits between two wallets as one balanced entry; the debited line comes first.
+	CreateCreditTransfer(ctx context.Context, arg CreateCreditTransferParams) ([]CreditTransaction, error)
+	CreateDocument(ctx context.Context, arg CreateDocumentParams) (Document, error)
+	CreateDocumentAccessLog(ctx context.Context, arg CreateDocumentAccessLogParams) error
+	CreateDocumentLink(ctx context.Context, arg CreateDocumentLinkParams) (DocumentLink, error)
+	CreateDossierShare(ctx context.Context, arg CreateDossierShareParams) (DossierShare, error)
 	CreateDraftLease(ctx context.Context, arg CreateDraftLeaseParams) (Lease, error)
+	// Réserve la clé ; 0 ligne quand elle existe déjà (requête rejouée ou concurrente).
+	CreateIdempotencyKey(ctx context.Context, arg CreateIdempotencyKeyParams) (int64, error)
 	CreateInvitation(ctx context.Context, arg CreateInvitationParams) (LeaseInvitation, error)
 	CreateInvitationWithLease(ctx context.Context, arg CreateInvitationWithLeaseParams) (LeaseInvitation, error)
+	CreateInvoice(ctx context.Context, arg CreateInvoiceParams) (Invoice, error)
 	CreateLease(ctx context.Context, arg CreateLeaseParams) (Lease, error)
+	CreateLeaseParty(ctx context.Context, arg CreateLeasePartyParams) (LeaseParty, error)
+	// An intent replayed by the provider (same idempotency key) keeps the row of the interrupted attempt.
+	CreatePaymentTransaction(ctx context.Context, arg CreatePaymentTransactionParams) (Transaction, error)
 	CreateProperty(ctx context.Context, arg CreatePropertyParams) (Property, error)
+	CreatePropertyMedia(ctx context.Context, arg CreatePropertyMediaParams) (PropertyMedium, error)
+	CreateRetentionPurge(ctx context.Context, arg CreateRetentionPurgeParams) (RetentionPurge, error)
 	CreateSolvencyCheck(ctx context.Context, arg CreateSolvencyCheckParams) (SolvencyCheck, error)
+	CreateSolvencyGuarantor(ctx context.Context, arg CreateSolvencyGuarantorParams) (SolvencyGuarantor, error)
 	CreateSubscription(ctx context.Context, arg CreateSubscriptionParams) (Subscription, error)
+	CreateSubscriptionEvent(ctx context.Context, arg CreateSubscriptionEventParams) (SubscriptionEvent, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
-	DecreasePropertyCredits(ctx context.Context, id int32) error
+	DeactivatePropertiesByOwner(ctx context.Context, ownerID pgtype.Int4) error
+	DeleteDocumentAccessLogsBefore(ctx context.Context, accessedAt pgtype.Timestamp) (int64, error)
+	DeleteDocumentAccessLogsByDocuments(ctx context.Context, documentIds []int32) error
+	// Links expired before the cutoff and no longer referenced by an access log.
+	DeleteDocumentLinksBefore(ctx context.Context, expiresAt pgtype.Timestamp) (int64, error)
+	DeleteDocumentLinksByDocuments(ctx context.Context, documentIds []int32) error
+	DeleteDocuments(ctx context.Context, documentIds []int32) error
+	// Dossiers expirés avant la date limite ; leurs partages suivent (ON DELETE CASCADE)
+	DeleteExpiredTenantDossiers(ctx context.Context, expiresAt pgtype.Timestamp) ([]DeleteExpiredTenantDossiersRow, error)
+	DeleteIdempotencyKey(ctx context.Context, arg DeleteIdempotencyKeyParams) error
+	DeletePropertyMedia(ctx context.Context, arg DeletePropertyMediaParams) error
+	DeletePropertySolvencyPolicy(ctx context.Context, arg DeletePropertySolvencyPolicyParams) (int64, error)
+	DeleteSolvencyGuarantor(ctx context.Context, arg DeleteSolvencyGuarantorParams) (int64, error)
+	DeleteTenantDossier(ctx context.Context, id int32) error
+	DeleteUnattachedGuarantorsByCheck(ctx context.Context, checkID int32) error
+	DeleteWebhookEvent(ctx context.Context, arg DeleteWebhookEventParams) error
+	DeleteWebhookEventsBefore(ctx context.Context, receivedAt pgtype.Timestamp) (int64, error)
+	EndSubscription(ctx context.Context, arg EndSubscriptionParams) error
+	// Only a check still waiting for the candidate expires: a concurrent decision wins
+	ExpireSolvencyCheck(ctx context.Context, id int32) (SolvencyCheck, error)
+	GetActiveCatalogItem(ctx context.Context, arg GetActiveCatalogItemParams) (CatalogItem, error)
+	GetCatalogItem(ctx context.Context, id int32) (CatalogItem, error)
+	// FOR UPDATE (not NO KEY UPDATE) also waits for rows being inserted with a reference to this version.
+	GetCatalogItemForUpdate(ctx context.Context, id int32) (CatalogItem, error)
+	GetCreditAccountForUpdate(ctx context.Context, id int32) (GetCreditAccountForUpdateRow, error)
+	// Property of a wallet (NULL for the global wallet); it never changes, no lock needed.
+	GetCreditAccountProperty(ctx context.Context, id int32) (pgtype.Int4, error)
+	// Global credits spent (net of refunds) since the plan credits were last granted.
+	GetCreditUsageSinceLastPlanGrant(ctx context.Context, userID pgtype.Int4) (int32, error)
+	GetDocument(ctx context.Context, id int32) (Document, error)
+	GetDocumentLink(ctx context.Context, id int32) (DocumentLink, error)
+	GetDossierShareByToken(ctx context.Context, token string) (DossierShare, error)
+	// La politique propre au bien l'emporte sur la politique par défaut du propriétaire
+	GetEffectiveSolvencyPolicy(ctx context.Context, arg GetEffectiveSolvencyPolicyParams) (SolvencyPolicy, error)
+	GetGuarantorByBankConnection(ctx context.Context, arg GetGuarantorByBankConnectionParams) (SolvencyGuarantor, error)
+	GetGuarantorByTokenForUpdate(ctx context.Context, token string) (SolvencyGuarantor, error)
+	GetIdempotencyKey(ctx context.Context, arg GetIdempotencyKeyParams) (IdempotencyKey, error)
 	GetInvitationByEmailAndProperty(ctx context.Context, arg GetInvitationByEmailAndPropertyParams) (LeaseInvitation, error)
 	GetInvitationByLeaseID(ctx context.Context, leaseID pgtype.Int4) (LeaseInvitation, error)
 	GetInvitationByToken(ctx context.Context, token string) (LeaseInvitation, error)
+	GetInvoice(ctx context.Context, id int32) (Invoice, error)
+	GetInvoiceForPeriod(ctx context.Context, arg GetInvoiceForPeriodParams) (Invoice, error)
+	GetInvoiceForUpdate(ctx context.Context, id int32) (Invoice, error)
+	GetIssuedInvoice(ctx context.Context, id int32) (Invoice, error)
+	GetLatestDocument(ctx context.Context, arg GetLatestDocumentParams) (Document, error)
 	GetLease(ctx context.Context, id int32) (Lease, error)
 	GetLeaseByPropertyAndStatus(ctx context.Context, arg GetLeaseByPropertyAndStatusParams) (Lease, error)
+	GetLeaseParty(ctx context.Context, arg GetLeasePartyParams) (LeaseParty, error)
+	GetLeasePartyByReplaced(ctx context.Context, replacesPartyID pgtype.Int4) (LeaseParty, error)
+	// Colocataire ayant accepté son invitation (parti ou non)
+	GetLeasePartyByUser(ctx context.Context, arg GetLeasePartyByUserParams) (LeaseParty, error)
+	GetNextDocumentVersion(ctx context.Context, arg GetNextDocumentVersionParams) (int32, error)
+	GetNextPhotoPosition(ctx context.Context, propertyID int32) (int32, error)
+	GetPaymentTransactionByIntentForUpdate(ctx context.Context, stripePaymentIntentID pgtype.Text) (Transaction, error)
+	GetPaymentTransactionForUpdate(ctx context.Context, id int32) (Transaction, error)
+	// Refund recorded but not confirmed by the provider yet, e.g. interrupted: resumed with its idempotency key.
+	GetPendingRefund(ctx context.Context, relatedEntityID pgtype.Int4) (Transaction, error)
 	GetProperty(ctx context.Context, id int32) (Property, error)
+	GetPropertyDiagnosticByType(ctx context.Context, arg GetPropertyDiagnosticByTypeParams) (PropertyMedium, error)
 	GetPropertyForUpdate(ctx context.Context, id int32) (Property, error)
+	GetPropertyMedia(ctx context.Context, arg GetPropertyMediaParams) (PropertyMedium, error)
+	GetRefundedCents(ctx context.Context, relatedEntityID pgtype.Int4) (int32, error)
+	GetSolvencyCheckByBankConnection(ctx context.Context, arg GetSolvencyCheckByBankConnectionParams) (SolvencyCheck, error)
 	GetSolvencyCheckByID(ctx context.Context, id int32) (SolvencyCheck, error)
 	GetSolvencyCheckByToken(ctx context.Context, token pgtype.Text) (GetSolvencyCheckByTokenRow, error)
+	GetSolvencyCheckByTokenForUpdate(ctx context.Context, token pgtype.Text) (SolvencyCheck, error)
+	GetSolvencyCheckForUpdate(ctx context.Context, id int32) (SolvencyCheck, error)
+	GetSolvencyGuarantor(ctx context.Context, id int32) (SolvencyGuarantor, error)
+	GetSubscriptionForUpdate(ctx context.Context, id int32) (Subscription, error)
+	GetSubscriptionItem(ctx context.Context, arg GetSubscriptionItemParams) (SubscriptionItem, error)
+	GetTenantDossier(ctx context.Context, id int32) (TenantDossier, error)
+	GetTenantDossierByUser(ctx context.Context, userID int32) (TenantDossier, error)
 	GetUserByEmail(ctx context.Context, email string) (User, error)
 	GetUserById(ctx context.Context, id int32) (User, error)
-	GetUserCreditBalance(ctx context.Context, userID pgtype.Int4) (int32, error)
-	GetUserCreditBalanceForUpdate(ctx context.Context, userID pgtype.Int4) (int32, error)
+	GetUserCreditBalance(ctx context.Context, userID int32) (int32, error)
+	GetUserCreditBalanceForUpdate(ctx context.Context, userID int32) (int32, error)
 	GetUserForUpdate(ctx context.Context, id int32) (User, error)
+	// The current subscription: at most one per user is incomplete, active or past due (idx_subscriptions_current).
 	GetUserSubscription(ctx context.Context, userID pgtype.Int4) (Subscription, error)
+	GetUserSubscriptionForUpdate(ctx context.Context, userID pgtype.Int4) (Subscription, error)
 	HasReceivedInitialBonus(ctx context.Context, userID pgtype.Int4) (bool, error)
-	IncreasePropertyCredits(ctx context.Context, id int32) error
+	// A version already sold: subscriptions (current or scheduled terms), their history, invoices or pack payments refer to it.
+	IsCatalogItemReferenced(ctx context.Context, id int32) (bool, error)
+	// An account deleted by its user (deleted_at) or gone no longer authenticates.
+	IsUserActive(ctx context.Context, id int32) (bool, error)
+	// The role is read at each request: a demoted administrator loses access without waiting for the token to expire.
+	IsUserAdmin(ctx context.Context, id int32) (bool, error)
+	IssueInvoice(ctx context.Context, arg IssueInvoiceParams) (Invoice, error)
+	ListActiveCatalogItems(ctx context.Context) ([]CatalogItem, error)
+	// Latest first: the first ones are archived when a downgrade leaves too many properties.
+	ListActivePropertiesByOwnerAndType(ctx context.Context, arg ListActivePropertiesByOwnerAndTypeParams) ([]ListActivePropertiesByOwnerAndTypeRow, error)
+	ListCatalogItems(ctx context.Context) ([]CatalogItem, error)
+	// Wallets of a user: the global one first, then those of their properties.
+	ListCreditAccountsByUser(ctx context.Context, userID pgtype.Int4) ([]ListCreditAccountsByUserRow, error)
+	// Wallets whose balance differs from the sum of their ledger lines.
+	ListCreditBalanceDrifts(ctx context.Context) ([]ListCreditBalanceDriftsRow, error)
+	ListCreditTransactionsByUser(ctx context.Context, userID pgtype.Int4) ([]CreditTransaction, error)
+	ListCreditTransactionsPage(ctx context.Context, arg ListCreditTransactionsPageParams) ([]ListCreditTransactionsPageRow, error)
+	ListDocumentsByEntity(ctx context.Context, arg ListDocumentsByEntityParams) ([]Document, error)
+	ListDossierShares(ctx context.Context, dossierID int32) ([]DossierShare, error)
+	ListExpiredSolvencyChecks(ctx context.Context) ([]int32, error)
+	ListExpiringDiagnostics(ctx context.Context, expiresAt pgtype.Date) ([]ListExpiringDiagnosticsRow, error)
+	ListGuarantorsByCheck(ctx context.Context, checkID int32) ([]SolvencyGuarantor, error)
+	ListGuarantorsByLease(ctx context.Context, leaseID pgtype.Int4) ([]SolvencyGuarantor, error)
+	ListInvitationsByEmail(ctx context.Context, tenantEmail string) ([]LeaseInvitation, error)
+	ListInvitationsByOwner(ctx context.Context, ownerID int32) ([]LeaseInvitation, error)
+	ListInvoicesByUser(ctx context.Context, userID int32) ([]Invoice, error)
+	// Issued invoices and credit notes whose PDF is not archived yet.
+	ListInvoicesToArchive(ctx context.Context, limit int32) ([]Invoice, error)
+	// Failed renewals of subscriptions still past due, last attempted before @retry_before, and retries
+	// abandoned mid-charge (claimed before @stale_before).
+	ListInvoicesToRetry(ctx context.Context, arg ListInvoicesToRetryParams) ([]Invoice, error)
+	ListIssuedInvoicesByUser(ctx context.Context, userID int32) ([]Invoice, error)
+	// Every generated document of a lease: contract versions and guarantee deeds.
+	ListLeaseDocuments(ctx context.Context, entityID int32) ([]Document, error)
+	ListLeaseParties(ctx context.Context, leaseID int32) ([]LeaseParty, error)
+	ListLeasesByOwner(ctx context.Context, ownerID pgtype.Int4) ([]ListLeasesByOwnerRow, error)
 	ListLeasesByTenant(ctx context.Context, tenantID pgtype.Int4) ([]ListLeasesByTenantRow, error)
+	ListLeasesForAnonymization(ctx context.Context, endDate pgtype.Date) ([]int32, error)
+	ListPaymentTransactionsByUser(ctx context.Context, userID pgtype.Int4) ([]Transaction, error)
 	ListPropertiesByOwner(ctx context.Context, ownerID pgtype.Int4) ([]Property, error)
+	// Properties whose vacancy credits differ from the balance of their wallet.
+	ListPropertyCreditDrifts(ctx context.Context) ([]ListPropertyCreditDriftsRow, error)
+	ListPropertyMedia(ctx context.Context, propertyID int32) ([]PropertyMedium, error)
+	ListRentPaymentsByLease(ctx context.Context, leaseID pgtype.Int4) ([]RentPayment, error)
+	// Checks still waiting for the candidate, with reminders left to send
+	ListSolvencyChecksAwaitingCandidate(ctx context.Context, maxReminders int32) ([]ListSolvencyChecksAwaitingCandidateRow, error)
+	ListSolvencyChecksByCandidate(ctx context.Context, candidateID pgtype.Int4) ([]ListSolvencyChecksByCandidateRow, error)
+	ListSolvencyChecksByLeaseTenants(ctx context.Context, id int32) ([]ListSolvencyChecksByLeaseTenantsRow, error)
 	ListSolvencyChecksByOwner(ctx context.Context, initiatorOwnerID pgtype.Int4) ([]ListSolvencyChecksByOwnerRow, error)
 	ListSolvencyChecksByProperty(ctx context.Context, propertyID pgtype.Int4) ([]ListSolvencyChecksByPropertyRow, error)
+	// Closed checks of candidates who were not retained: no lease with them on the property.
+	ListSolvencyChecksForAnonymization(ctx context.Context, createdAt pgtype.Timestamp) ([]ListSolvencyChecksForAnonymizationRow, error)
+	// Closed checks whose candidate did not become a tenant of the property (the tenant's are purged with the lease).
+	ListSolvencyChecksForDocumentPurge(ctx context.Context, createdAt pgtype.Timestamp) ([]ListSolvencyChecksForDocumentPurgeRow, error)
+	ListSubscriptionEventsByUser(ctx context.Context, userID int32) ([]ListSubscriptionEventsByUserRow, error)
+	ListSubscriptionsByUser(ctx context.Context, userID pgtype.Int4) ([]Subscription, error)
+	ListSubscriptionsDueForCredits(ctx context.Context, today pgtype.Date) ([]int32, error)
+	ListSubscriptionsDueForRenewal(ctx context.Context, today pgtype.Date) ([]int32, error)
+	ListUnbalancedCreditEntries(ctx context.Context) ([]ListUnbalancedCreditEntriesRow, error)
+	// Locks the owner's properties in id order: properties are locked before the user and its wallets.
+	LockPropertiesByOwner(ctx context.Context, ownerID pgtype.Int4) error
+	MarkDiagnosticReminderSent(ctx context.Context, id int32) error
+	MarkInvoiceFailed(ctx context.Context, id int32) (Invoice, error)
+	MarkInvoicePaid(ctx context.Context, id int32) (Invoice, error)
+	// Payment accepted by the provider but not confirmed yet (3DS, SEPA debit): settled by its webhook.
+	MarkInvoicePending(ctx context.Context, id int32) error
+	MarkSolvencyCheckDocumentsPurged(ctx context.Context, id int32) error
+	MarkSolvencyCheckReminderSent(ctx context.Context, id int32) error
+	// Locks the counter of the series until the issuing transaction ends, so numbers have no gap.
+	NextInvoiceNumber(ctx context.Context, arg NextInvoiceNumberParams) (int32, error)
+	PurgeIdempotencyKeys(ctx context.Context, createdBefore pgtype.Timestamp) (int64, error)
+	RecordSubscriptionCreditGrant(ctx context.Context, arg RecordSubscriptionCreditGrantParams) error
+	RecordWebhookEvent(ctx context.Context, arg RecordWebhookEventParams) (int64, error)
+	// Ends the validity now; subscriptions already taken keep referring to it.
+	RetireCatalogItem(ctx context.Context, id int32) (CatalogItem, error)
+	RevokeDocumentLink(ctx context.Context, arg RevokeDocumentLinkParams) (int64, error)
+	RevokeDocumentLinksByUser(ctx context.Context, userID int32) error
+	RevokeDossierShare(ctx context.Context, arg RevokeDossierShareParams) (int64, error)
+	RevokePendingInvitationsForUser(ctx context.Context, arg RevokePendingInvitationsForUserParams) error
+	ScheduleSubscriptionChange(ctx context.Context, arg ScheduleSubscriptionChangeParams) error
+	ScheduleSubscriptionItemQuantity(ctx context.Context, arg ScheduleSubscriptionItemQuantityParams) error
+	SetGuarantorBankConnection(ctx context.Context, arg SetGuarantorBankConnectionParams) error
+	SetGuarantorBankConsent(ctx context.Context, arg SetGuarantorBankConsentParams) error
+	SetGuarantorMention(ctx context.Context, arg SetGuarantorMentionParams) error
+	SetInvoiceDocument(ctx context.Context, arg SetInvoiceDocumentParams) (int64, error)
+	SetLeasePartyDeparture(ctx context.Context, arg SetLeasePartyDepartureParams) error
+	SetLeasePartySolidarityEnd(ctx context.Context, arg SetLeasePartySolidarityEndParams) error
+	SetPaymentTransactionEntity(ctx context.Context, arg SetPaymentTransactionEntityParams) error
+	SetPaymentTransactionStatus(ctx context.Context, arg SetPaymentTransactionStatusParams) error
+	SetPropertyMediaCover(ctx context.Context, arg SetPropertyMediaCoverParams) error
+	SetSolvencyCheckBankConnection(ctx context.Context, arg SetSolvencyCheckBankConnectionParams) error
+	SetSolvencyCheckBankConsent(ctx context.Context, arg SetSolvencyCheckBankConsentParams) error
+	SetSolvencyCheckDossierShare(ctx context.Context, arg SetSolvencyCheckDossierShareParams) error
+	SetSolvencyCheckSelection(ctx context.Context, arg SetSolvencyCheckSelectionParams) error
+	SetSubscriptionCancelAtPeriodEnd(ctx context.Context, arg SetSubscriptionCancelAtPeriodEndParams) error
+	SetSubscriptionPropertyLimit(ctx context.Context, arg SetSubscriptionPropertyLimitParams) error
+	SetSubscriptionStatus(ctx context.Context, arg SetSubscriptionStatusParams) error
+	SetUserPaymentCustomer(ctx context.Context, arg SetUserPaymentCustomerParams) error
+	SetUserSepaMandate(ctx context.Context, arg SetUserSepaMandateParams) error
+	SettleRefundTransaction(ctx context.Context, arg SettleRefundTransactionParams) (Transaction, error)
 	SoftDeleteProperty(ctx context.Context, arg SoftDeletePropertyParams) (int32, error)
+	// Reprend une clé restée 'processing' sans signe de vie depuis @stale_before (instance arrêtée pendant la
+	// requête) ; 0 ligne si la requête s'exécute encore ou si une autre l'a reprise entre-temps.
+	TakeOverIdempotencyKey(ctx context.Context, arg TakeOverIdempotencyKeyParams) (int64, error)
+	// Signe de vie de la requête qui détient la clé : elle ne peut pas être reprise tant qu'elle s'exécute.
+	TouchIdempotencyKey(ctx context.Context, arg TouchIdempotencyKeyParams) error
+	UpdateCatalogItem(ctx context.Context, arg UpdateCatalogItemParams) (CatalogItem, error)
+	UpdateGuarantorAnalysis(ctx context.Context, arg UpdateGuarantorAnalysisParams) error
+	UpdateGuarantorDocuments(ctx context.Context, arg UpdateGuarantorDocumentsParams) error
 	UpdateInvitationStatus(ctx context.Context, arg UpdateInvitationStatusParams) error
 	UpdateLastContext(ctx context.Context, arg UpdateLastContextParams) error
 	UpdateLeaseContractURL(ctx context.Context, arg UpdateLeaseContractURLParams) error
+	UpdateLeasePartyShare(ctx context.Context, arg UpdateLeasePartyShareParams) error
+	UpdateLeaseRentSplit(ctx context.Context, arg UpdateLeaseRentSplitParams) error
+	// Le premier locataire qui accepte reste le titulaire de leases.tenant_id, les suivants sont des colocataires
 	UpdateLeaseTenant(ctx context.Context, arg UpdateLeaseTenantParams) error
 	UpdateProperty(ctx context.Context, arg UpdatePropertyParams) (Property, error)
-	UpdateSolvencyCheckResult(ctx context.Context, arg UpdateSolvencyCheckResultParams) error
-	UpdateSubscriptionLimit(ctx context.Context, arg UpdateSubscriptionLimitParams) error
+	UpdatePropertyMediaPosition(ctx context.Context, arg UpdatePropertyMediaPositionParams) error
+	UpdateSolvencyCheckDocuments(ctx context.Context, arg UpdateSolvencyCheckDocumentsParams) error
+	UpdateSolvencyCheckProfile(ctx context.Context, arg UpdateSolvencyCheckProfileParams) error
+	// Only while the check is still in the status the decision was made from: a check cancelled or expired
+	// (and refunded) in the meantime is left as it is.
+	UpdateSolvencyCheckResult(ctx context.Context, arg UpdateSolvencyCheckResultParams) (int64, error)
+	// Applies the quantity and price of the period starting; a removal scheduled is done.
+	UpdateSubscriptionItem(ctx context.Context, arg UpdateSubscriptionItemParams) error
 	UpdateUserPromotion(ctx context.Context, arg UpdateUserPromotionParams) error
+	UpsertOwnerSolvencyPolicy(ctx context.Context, arg UpsertOwnerSolvencyPolicyParams) (SolvencyPolicy, error)
+	UpsertPropertySolvencyPolicy(ctx context.Context, arg UpsertPropertySolvencyPolicyParams) (SolvencyPolicy, error)
+	UpsertTenantDossier(ctx context.Context, arg UpsertTenantDossierParams) (TenantDossier, error)
+	VoidInvoice(ctx context.Context, id int32) error
 }
 
 var _ Querier = (*Queries)(nil)
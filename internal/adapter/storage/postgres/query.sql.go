@@ -11,25 +11,432 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-const cancelSolvencyCheck = `-- name: CancelSolvencyCheck :exec
+const activateLeaseParty = `-- name: ActivateLeaseParty :exec
+UPDATE lease_parties
+SET user_id = $2, status = 'active', joined_at = NOW()
+WHERE id = $1
+`
+
+type ActivateLeasePartyParams struct {
+	ID     int32       `json:"id"`
+	UserID pgtype.Int4 `json:"user_id"`
+}
+
+func (q *Queries) ActivateLeaseParty(ctx context.Context, arg ActivateLeasePartyParams) error {
+	_, err := q.db.Exec(ctx, activateLeaseParty, arg.ID, arg.UserID)
+	return err
+}
+
+const addSubscriptionItemQuantity = `-- name: AddSubscriptionItemQuantity :one
+INSERT INTO subscription_items (subscription_id, kind, quantity, unit_price_cents)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (subscription_id, kind) DO UPDATE
+SET quantity = subscription_items.quantity + EXCLUDED.quantity,
+    unit_price_cents = EXCLUDED.unit_price_cents,
+    scheduled_quantity = subscription_items.scheduled_quantity + EXCLUDED.quantity,
+    updated_at = NOW()
+RETURNING id, subscription_id, kind, quantity, unit_price_cents, scheduled_quantity, created_at, updated_at
+`
+
+type AddSubscriptionItemQuantityParams struct {
+	SubscriptionID int32  `json:"subscription_id"`
+	Kind           string `json:"kind"`
+	Quantity       int32  `json:"quantity"`
+	UnitPriceCents int32  `json:"unit_price_cents"`
+}
+
+// A removal scheduled at the period end keeps the quantity added.
+func (q *Queries) AddSubscriptionItemQuantity(ctx context.Context, arg AddSubscriptionItemQuantityParams) (SubscriptionItem, error) {
+	row := q.db.QueryRow(ctx, addSubscriptionItemQuantity,
+		arg.SubscriptionID,
+		arg.Kind,
+		arg.Quantity,
+		arg.UnitPriceCents,
+	)
+	var i SubscriptionItem
+	err := row.Scan(
+		&i.ID,
+		&i.SubscriptionID,
+		&i.Kind,
+		&i.Quantity,
+		&i.UnitPriceCents,
+		&i.ScheduledQuantity,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const advanceSubscriptionPeriod = `-- name: AdvanceSubscriptionPeriod :exec
+UPDATE subscriptions
+SET current_period_start = $1, current_period_end = $2,
+    end_date = $3, status = 'active'
+WHERE id = $4
+`
+
+type AdvanceSubscriptionPeriodParams struct {
+	PeriodStart pgtype.Date `json:"period_start"`
+	PeriodEnd   pgtype.Date `json:"period_end"`
+	EndDate     pgtype.Date `json:"end_date"`
+	ID          int32       `json:"id"`
+}
+
+// Back to 'active' once the period is paid; end_date follows the period for prepaid (yearly) plans.
+func (q *Queries) AdvanceSubscriptionPeriod(ctx context.Context, arg AdvanceSubscriptionPeriodParams) error {
+	_, err := q.db.Exec(ctx, advanceSubscriptionPeriod,
+		arg.PeriodStart,
+		arg.PeriodEnd,
+		arg.EndDate,
+		arg.ID,
+	)
+	return err
+}
+
+const anonymizeLease = `-- name: AnonymizeLease :exec
+UPDATE leases
+SET contract_url = NULL, anonymized_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) AnonymizeLease(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, anonymizeLease, id)
+	return err
+}
+
+const anonymizeLeaseGuarantors = `-- name: AnonymizeLeaseGuarantors :exec
+UPDATE solvency_guarantors
+SET email = 'anonymized-' || id || '@invalid', first_name = NULL, last_name = NULL, phone_number = NULL,
+    documents_json = NULL, bank_consent_id = NULL, bank_connection_id = NULL, analysis_json = NULL, mention_text = NULL
+WHERE lease_id = $1
+`
+
+func (q *Queries) AnonymizeLeaseGuarantors(ctx context.Context, leaseID pgtype.Int4) error {
+	_, err := q.db.Exec(ctx, anonymizeLeaseGuarantors, leaseID)
+	return err
+}
+
+const anonymizeLeaseInvitations = `-- name: AnonymizeLeaseInvitations :exec
+UPDATE lease_invitations
+SET tenant_email = 'anonymized-' || id || '@invalid'
+WHERE lease_id = $1
+`
+
+func (q *Queries) AnonymizeLeaseInvitations(ctx context.Context, leaseID pgtype.Int4) error {
+	_, err := q.db.Exec(ctx, anonymizeLeaseInvitations, leaseID)
+	return err
+}
+
+const anonymizeLeaseParties = `-- name: AnonymizeLeaseParties :exec
+UPDATE lease_parties
+SET email = 'anonymized-' || id || '@invalid', first_name = NULL, last_name = NULL
+WHERE lease_id = $1
+`
+
+func (q *Queries) AnonymizeLeaseParties(ctx context.Context, leaseID int32) error {
+	_, err := q.db.Exec(ctx, anonymizeLeaseParties, leaseID)
+	return err
+}
+
+const anonymizeSolvencyCheck = `-- name: AnonymizeSolvencyCheck :exec
+UPDATE solvency_checks
+SET candidate_id = NULL, token = NULL, analysis_json = NULL, documents_json = NULL, missing_documents = NULL,
+    report_url = NULL, bank_consent_id = NULL, bank_connection_id = NULL, employment_type = NULL,
+    guarantee_type = NULL, policy_results = NULL,
+    documents_purged_at = COALESCE(documents_purged_at, NOW()), anonymized_at = NOW()
+WHERE id = $1
+`
+
+// Keeps the status and scores for the owner's statistics.
+func (q *Queries) AnonymizeSolvencyCheck(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, anonymizeSolvencyCheck, id)
+	return err
+}
+
+const anonymizeUser = `-- name: AnonymizeUser :exec
+UPDATE users
+SET email = 'deleted-' || id || '@invalid', password_hash = NULL, first_name = NULL, last_name = NULL,
+    phone_number = NULL, is_verified = FALSE, deleted_at = NOW()
+WHERE id = $1
+`
+
+// The row stays for the financial records and contracts referring to it; the email is freed.
+func (q *Queries) AnonymizeUser(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, anonymizeUser, id)
+	return err
+}
+
+const archiveProperties = `-- name: ArchiveProperties :execrows
+UPDATE properties
+SET is_active = FALSE
+WHERE owner_id = $1 AND id = ANY($2::int[]) AND is_active = TRUE
+`
+
+type ArchivePropertiesParams struct {
+	OwnerID pgtype.Int4 `json:"owner_id"`
+	Ids     []int32     `json:"ids"`
+}
+
+func (q *Queries) ArchiveProperties(ctx context.Context, arg ArchivePropertiesParams) (int64, error) {
+	result, err := q.db.Exec(ctx, archiveProperties, arg.OwnerID, arg.Ids)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const attachGuarantorsToLease = `-- name: AttachGuarantorsToLease :many
+UPDATE solvency_guarantors g
+SET lease_id = $1
+WHERE g.lease_id IS NULL AND g.status = 'completed' AND g.check_id = (
+    SELECT sc.id FROM solvency_checks sc
+    WHERE sc.property_id = $2 AND sc.candidate_id = $3 AND sc.status = 'approved'
+    ORDER BY sc.created_at DESC
+    LIMIT 1
+)
+RETURNING g.id, g.check_id, g.email, g.first_name, g.last_name, g.phone_number, g.token, g.status, g.documents_json, g.bank_provider, g.bank_consent_id, g.bank_connection_id, g.monthly_income, g.analysis_json, g.lease_id, g.mention_text, g.mention_signed_at, g.created_at
+`
+
+type AttachGuarantorsToLeaseParams struct {
+	LeaseID    pgtype.Int4 `json:"lease_id"`
+	PropertyID pgtype.Int4 `json:"property_id"`
+	TenantID   pgtype.Int4 `json:"tenant_id"`
+}
+
+// Garants complétés (analyse bancaire ou pièces) du dernier dossier accepté du locataire pour ce bien
+func (q *Queries) AttachGuarantorsToLease(ctx context.Context, arg AttachGuarantorsToLeaseParams) ([]SolvencyGuarantor, error) {
+	rows, err := q.db.Query(ctx, attachGuarantorsToLease, arg.LeaseID, arg.PropertyID, arg.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SolvencyGuarantor
+	for rows.Next() {
+		var i SolvencyGuarantor
+		if err := rows.Scan(
+			&i.ID,
+			&i.CheckID,
+			&i.Email,
+			&i.FirstName,
+			&i.LastName,
+			&i.PhoneNumber,
+			&i.Token,
+			&i.Status,
+			&i.DocumentsJson,
+			&i.BankProvider,
+			&i.BankConsentID,
+			&i.BankConnectionID,
+			&i.MonthlyIncome,
+			&i.AnalysisJson,
+			&i.LeaseID,
+			&i.MentionText,
+			&i.MentionSignedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const cancelSolvencyCheck = `-- name: CancelSolvencyCheck :execrows
 UPDATE solvency_checks
 SET status = 'cancelled'
+WHERE id = $1 AND status IN ('pending', 'insufficient_docs')
+`
+
+// Only a check still waiting for the candidate is cancelled: a concurrent decision or expiry wins
+func (q *Queries) CancelSolvencyCheck(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.Exec(ctx, cancelSolvencyCheck, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const cancelUserSubscriptions = `-- name: CancelUserSubscriptions :exec
+UPDATE subscriptions
+SET status = 'cancelled'
+WHERE user_id = $1 AND status IN ('active', 'past_due', 'incomplete')
+`
+
+func (q *Queries) CancelUserSubscriptions(ctx context.Context, userID pgtype.Int4) error {
+	_, err := q.db.Exec(ctx, cancelUserSubscriptions, userID)
+	return err
+}
+
+const changeSubscriptionPlan = `-- name: ChangeSubscriptionPlan :exec
+UPDATE subscriptions
+SET catalog_item_id = $2, plan_type = $3, max_properties_limit = $4,
+    scheduled_catalog_item_id = NULL, scheduled_archived_property_ids = NULL
+WHERE id = $1
+`
+
+type ChangeSubscriptionPlanParams struct {
+	ID                 int32       `json:"id"`
+	CatalogItemID      pgtype.Int4 `json:"catalog_item_id"`
+	PlanType           SubPlan     `json:"plan_type"`
+	MaxPropertiesLimit pgtype.Int4 `json:"max_properties_limit"`
+}
+
+// Applies a plan change; any change scheduled for the period end is dropped.
+func (q *Queries) ChangeSubscriptionPlan(ctx context.Context, arg ChangeSubscriptionPlanParams) error {
+	_, err := q.db.Exec(ctx, changeSubscriptionPlan,
+		arg.ID,
+		arg.CatalogItemID,
+		arg.PlanType,
+		arg.MaxPropertiesLimit,
+	)
+	return err
+}
+
+const claimInvoiceForCollection = `-- name: ClaimInvoiceForCollection :one
+UPDATE invoices
+SET status = 'processing', last_attempt_at = NOW()
+WHERE id = $1 AND (status IN ('open', 'failed') OR (status = 'processing' AND last_attempt_at <= $2))
+RETURNING id, user_id, subscription_id, description, amount_cents, period_start, period_end, status, attempts, last_attempt_at, paid_at, created_at, kind, catalog_item_id, credited_invoice_id, number, issued_at, amount_excl_vat_cents, vat_rate_bps, vat_cents, buyer_name, buyer_email, document_id, slot_quantity, credit_property_id
+`
+
+type ClaimInvoiceForCollectionParams struct {
+	ID          int32            `json:"id"`
+	StaleBefore pgtype.Timestamp `json:"stale_before"`
+}
+
+// Reserves an invoice for the instance about to charge it. A claim older than @stale_before was left by
+// an instance that stopped mid-charge: it is taken over, with the same attempt number.
+func (q *Queries) ClaimInvoiceForCollection(ctx context.Context, arg ClaimInvoiceForCollectionParams) (Invoice, error) {
+	row := q.db.QueryRow(ctx, claimInvoiceForCollection, arg.ID, arg.StaleBefore)
+	var i Invoice
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.SubscriptionID,
+		&i.Description,
+		&i.AmountCents,
+		&i.PeriodStart,
+		&i.PeriodEnd,
+		&i.Status,
+		&i.Attempts,
+		&i.LastAttemptAt,
+		&i.PaidAt,
+		&i.CreatedAt,
+		&i.Kind,
+		&i.CatalogItemID,
+		&i.CreditedInvoiceID,
+		&i.Number,
+		&i.IssuedAt,
+		&i.AmountExclVatCents,
+		&i.VatRateBps,
+		&i.VatCents,
+		&i.BuyerName,
+		&i.BuyerEmail,
+		&i.DocumentID,
+		&i.SlotQuantity,
+		&i.CreditPropertyID,
+	)
+	return i, err
+}
+
+const cleanupProvisionalUsers = `-- name: CleanupProvisionalUsers :many
+DELETE FROM users u
+WHERE u.is_provisional = TRUE
+AND u.created_at < $1
+AND NOT EXISTS (SELECT 1 FROM solvency_checks sc WHERE sc.candidate_id = u.id OR sc.initiator_owner_id = u.id)
+AND NOT EXISTS (SELECT 1 FROM leases l WHERE l.tenant_id = u.id)
+AND NOT EXISTS (SELECT 1 FROM lease_parties lp WHERE lp.user_id = u.id)
+AND NOT EXISTS (SELECT 1 FROM lease_invitations li WHERE li.owner_id = u.id)
+AND NOT EXISTS (SELECT 1 FROM properties p WHERE p.owner_id = u.id)
+AND NOT EXISTS (SELECT 1 FROM subscriptions s WHERE s.user_id = u.id)
+AND NOT EXISTS (SELECT 1 FROM credit_transactions ct WHERE ct.user_id = u.id)
+AND NOT EXISTS (SELECT 1 FROM transactions t WHERE t.user_id = u.id)
+AND NOT EXISTS (SELECT 1 FROM seasonal_bookings sb WHERE sb.tenant_id = u.id)
+AND NOT EXISTS (SELECT 1 FROM document_links dl WHERE dl.user_id = u.id)
+AND NOT EXISTS (SELECT 1 FROM document_access_logs dal WHERE dal.user_id = u.id)
+AND NOT EXISTS (SELECT 1 FROM solvency_policies sp WHERE sp.owner_id = u.id)
+AND NOT EXISTS (SELECT 1 FROM tenant_dossiers td WHERE td.user_id = u.id)
+RETURNING u.id
+`
+
+// Provisional accounts nobody refers to any more (candidates are detached when anonymised).
+func (q *Queries) CleanupProvisionalUsers(ctx context.Context, createdAt pgtype.Timestamp) ([]int32, error) {
+	rows, err := q.db.Query(ctx, cleanupProvisionalUsers, createdAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const clearGuarantorDocumentsByCheck = `-- name: ClearGuarantorDocumentsByCheck :exec
+UPDATE solvency_guarantors
+SET documents_json = NULL
+WHERE check_id = $1
+`
+
+func (q *Queries) ClearGuarantorDocumentsByCheck(ctx context.Context, checkID int32) error {
+	_, err := q.db.Exec(ctx, clearGuarantorDocumentsByCheck, checkID)
+	return err
+}
+
+const clearPropertyCover = `-- name: ClearPropertyCover :exec
+UPDATE property_media
+SET is_cover = FALSE
+WHERE property_id = $1 AND is_cover
+`
+
+func (q *Queries) ClearPropertyCover(ctx context.Context, propertyID int32) error {
+	_, err := q.db.Exec(ctx, clearPropertyCover, propertyID)
+	return err
+}
+
+const completeGuarantorDocuments = `-- name: CompleteGuarantorDocuments :exec
+UPDATE solvency_guarantors
+SET status = 'completed'
 WHERE id = $1
 `
 
-func (q *Queries) CancelSolvencyCheck(ctx context.Context, id int32) error {
-	_, err := q.db.Exec(ctx, cancelSolvencyCheck, id)
+// Dossier de garant complété par pièces justificatives, sans analyse bancaire
+func (q *Queries) CompleteGuarantorDocuments(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, completeGuarantorDocuments, id)
 	return err
 }
 
-const cleanupProvisionalUsers = `-- name: CleanupProvisionalUsers :exec
-DELETE FROM users 
-WHERE is_provisional = TRUE 
-AND created_at < NOW() - INTERVAL '30 days'
+const completeIdempotencyKey = `-- name: CompleteIdempotencyKey :exec
+UPDATE idempotency_keys
+SET status = 'completed', response_code = $3, response_content_type = $4, response_body = $5, completed_at = NOW()
+WHERE user_id = $1 AND idempotency_key = $2
 `
 
-func (q *Queries) CleanupProvisionalUsers(ctx context.Context) error {
-	_, err := q.db.Exec(ctx, cleanupProvisionalUsers)
+type CompleteIdempotencyKeyParams struct {
+	UserID              int32       `json:"user_id"`
+	IdempotencyKey      string      `json:"idempotency_key"`
+	ResponseCode        pgtype.Int4 `json:"response_code"`
+	ResponseContentType pgtype.Text `json:"response_content_type"`
+	ResponseBody        []byte      `json:"response_body"`
+}
+
+func (q *Queries) CompleteIdempotencyKey(ctx context.Context, arg CompleteIdempotencyKeyParams) error {
+	_, err := q.db.Exec(ctx, completeIdempotencyKey,
+		arg.UserID,
+		arg.IdempotencyKey,
+		arg.ResponseCode,
+		arg.ResponseContentType,
+		arg.ResponseBody,
+	)
 	return err
 }
 
@@ -45,9 +452,56 @@ func (q *Queries) CountBookingsByTenant(ctx context.Context, tenantID pgtype.Int
 	return count, err
 }
 
+const countCreditTransactions = `-- name: CountCreditTransactions :one
+SELECT COUNT(*) FROM credit_transactions
+WHERE user_id = $1
+AND ($2::text IS NULL OR transaction_type = $2)
+`
+
+type CountCreditTransactionsParams struct {
+	UserID          pgtype.Int4 `json:"user_id"`
+	TransactionType pgtype.Text `json:"transaction_type"`
+}
+
+func (q *Queries) CountCreditTransactions(ctx context.Context, arg CountCreditTransactionsParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countCreditTransactions, arg.UserID, arg.TransactionType)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countGuarantorsByCheck = `-- name: CountGuarantorsByCheck :one
+SELECT COUNT(*) FROM solvency_guarantors
+WHERE check_id = $1
+`
+
+func (q *Queries) CountGuarantorsByCheck(ctx context.Context, checkID int32) (int64, error) {
+	row := q.db.QueryRow(ctx, countGuarantorsByCheck, checkID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countLeasesByOwner = `-- name: CountLeasesByOwner :one
+SELECT COUNT(*) FROM leases l
+JOIN properties p ON l.property_id = p.id
+WHERE p.owner_id = $1 AND l.lease_status IN ('signed_waiting_deposit', 'active')
+`
+
+// Signed leases still running; drafts do not bind the owner.
+func (q *Queries) CountLeasesByOwner(ctx context.Context, ownerID pgtype.Int4) (int64, error) {
+	row := q.db.QueryRow(ctx, countLeasesByOwner, ownerID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const countLeasesByTenant = `-- name: CountLeasesByTenant :one
-SELECT COUNT(*) FROM leases
-WHERE tenant_id = $1 AND lease_status != 'terminated'
+SELECT COUNT(*) FROM leases l
+WHERE l.lease_status != 'terminated' AND (
+    l.tenant_id = $1
+    OR EXISTS (SELECT 1 FROM lease_parties lp WHERE lp.lease_id = l.id AND lp.user_id = $1 AND lp.status = 'active')
+)
 `
 
 func (q *Queries) CountLeasesByTenant(ctx context.Context, tenantID pgtype.Int4) (int64, error) {
@@ -57,6 +511,33 @@ func (q *Queries) CountLeasesByTenant(ctx context.Context, tenantID pgtype.Int4)
 	return count, err
 }
 
+const countOverlappingCatalogItems = `-- name: CountOverlappingCatalogItems :one
+SELECT COUNT(*) FROM catalog_items
+WHERE code = $1 AND id != $2
+AND valid_from < COALESCE($3::timestamp, 'infinity')
+AND COALESCE(valid_until, 'infinity') > $4
+`
+
+type CountOverlappingCatalogItemsParams struct {
+	Code       string           `json:"code"`
+	ExcludeID  int32            `json:"exclude_id"`
+	ValidUntil pgtype.Timestamp `json:"valid_until"`
+	ValidFrom  pgtype.Timestamp `json:"valid_from"`
+}
+
+// Versions of the same offer whose validity period overlaps [valid_from, valid_until).
+func (q *Queries) CountOverlappingCatalogItems(ctx context.Context, arg CountOverlappingCatalogItemsParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countOverlappingCatalogItems,
+		arg.Code,
+		arg.ExcludeID,
+		arg.ValidUntil,
+		arg.ValidFrom,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const countPropertiesByOwner = `-- name: CountPropertiesByOwner :one
 SELECT COUNT(*) FROM properties
 WHERE owner_id = $1 AND is_active = true
@@ -86,28 +567,161 @@ func (q *Queries) CountPropertiesByOwnerAndType(ctx context.Context, arg CountPr
 	return count, err
 }
 
+const createCatalogItem = `-- name: CreateCatalogItem :one
+INSERT INTO catalog_items (
+    code, kind, name, plan_type, monthly_price_cents, yearly_price_cents, price_cents,
+    max_properties, included_credits, slot_price_cents, valid_from, valid_until
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
+)
+RETURNING id, code, kind, name, plan_type, monthly_price_cents, yearly_price_cents, price_cents, max_properties, included_credits, slot_price_cents, valid_from, valid_until, created_at, updated_at
+`
+
+type CreateCatalogItemParams struct {
+	Code              string           `json:"code"`
+	Kind              string           `json:"kind"`
+	Name              string           `json:"name"`
+	PlanType          NullSubPlan      `json:"plan_type"`
+	MonthlyPriceCents pgtype.Int4      `json:"monthly_price_cents"`
+	YearlyPriceCents  pgtype.Int4      `json:"yearly_price_cents"`
+	PriceCents        pgtype.Int4      `json:"price_cents"`
+	MaxProperties     int32            `json:"max_properties"`
+	IncludedCredits   int32            `json:"included_credits"`
+	SlotPriceCents    pgtype.Int4      `json:"slot_price_cents"`
+	ValidFrom         pgtype.Timestamp `json:"valid_from"`
+	ValidUntil        pgtype.Timestamp `json:"valid_until"`
+}
+
+func (q *Queries) CreateCatalogItem(ctx context.Context, arg CreateCatalogItemParams) (CatalogItem, error) {
+	row := q.db.QueryRow(ctx, createCatalogItem,
+		arg.Code,
+		arg.Kind,
+		arg.Name,
+		arg.PlanType,
+		arg.MonthlyPriceCents,
+		arg.YearlyPriceCents,
+		arg.PriceCents,
+		arg.MaxProperties,
+		arg.IncludedCredits,
+		arg.SlotPriceCents,
+		arg.ValidFrom,
+		arg.ValidUntil,
+	)
+	var i CatalogItem
+	err := row.Scan(
+		&i.ID,
+		&i.Code,
+		&i.Kind,
+		&i.Name,
+		&i.PlanType,
+		&i.MonthlyPriceCents,
+		&i.YearlyPriceCents,
+		&i.PriceCents,
+		&i.MaxProperties,
+		&i.IncludedCredits,
+		&i.SlotPriceCents,
+		&i.ValidFrom,
+		&i.ValidUntil,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const createCreditNote = `-- name: CreateCreditNote :one
+INSERT INTO invoices (
+    user_id, kind, credited_invoice_id, description, amount_cents, status, paid_at
+) VALUES (
+    $1, 'credit_note', $2, $3, $4, 'paid', NOW()
+)
+RETURNING id, user_id, subscription_id, description, amount_cents, period_start, period_end, status, attempts, last_attempt_at, paid_at, created_at, kind, catalog_item_id, credited_invoice_id, number, issued_at, amount_excl_vat_cents, vat_rate_bps, vat_cents, buyer_name, buyer_email, document_id, slot_quantity, credit_property_id
+`
+
+type CreateCreditNoteParams struct {
+	UserID            int32       `json:"user_id"`
+	CreditedInvoiceID pgtype.Int4 `json:"credited_invoice_id"`
+	Description       string      `json:"description"`
+	AmountCents       int32       `json:"amount_cents"`
+}
+
+func (q *Queries) CreateCreditNote(ctx context.Context, arg CreateCreditNoteParams) (Invoice, error) {
+	row := q.db.QueryRow(ctx, createCreditNote,
+		arg.UserID,
+		arg.CreditedInvoiceID,
+		arg.Description,
+		arg.AmountCents,
+	)
+	var i Invoice
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.SubscriptionID,
+		&i.Description,
+		&i.AmountCents,
+		&i.PeriodStart,
+		&i.PeriodEnd,
+		&i.Status,
+		&i.Attempts,
+		&i.LastAttemptAt,
+		&i.PaidAt,
+		&i.CreatedAt,
+		&i.Kind,
+		&i.CatalogItemID,
+		&i.CreditedInvoiceID,
+		&i.Number,
+		&i.IssuedAt,
+		&i.AmountExclVatCents,
+		&i.VatRateBps,
+		&i.VatCents,
+		&i.BuyerName,
+		&i.BuyerEmail,
+		&i.DocumentID,
+		&i.SlotQuantity,
+		&i.CreditPropertyID,
+	)
+	return i, err
+}
+
 const createCreditTransaction = `-- name: CreateCreditTransaction :one
+WITH system_line AS (
+    INSERT INTO credit_transactions (
+        entry_id, account_id, user_id, property_id, amount, transaction_type, description, refund_of
+    ) VALUES (
+        nextval('credit_entry_seq'), (SELECT id FROM credit_accounts WHERE kind = 'system'), NULL, NULL,
+        -$3::int, $4, $5, NULL
+    )
+    RETURNING entry_id
+)
 INSERT INTO credit_transactions (
-    user_id, amount, transaction_type, description
-) VALUES (
-    $1, $2, $3, $4
+    entry_id, account_id, user_id, property_id, amount, transaction_type, description, refund_of
 )
-RETURNING id, user_id, amount, transaction_type, description, created_at
+SELECT entry_id, NULL::int, $1::int, $2::int, $3::int,
+    $4, $5, $6::int
+FROM system_line
+RETURNING id, user_id, amount, transaction_type, description, refund_of, created_at, property_id, balance_after, account_id, entry_id
 `
 
 type CreateCreditTransactionParams struct {
 	UserID          pgtype.Int4 `json:"user_id"`
+	PropertyID      pgtype.Int4 `json:"property_id"`
 	Amount          int32       `json:"amount"`
 	TransactionType string      `json:"transaction_type"`
 	Description     pgtype.Text `json:"description"`
+	RefundOf        pgtype.Int4 `json:"refund_of"`
 }
 
+// Credits (positive amount) or debits a wallet against the system account, as one balanced entry: the
+// user's global wallet, or the wallet of property_id when set. The system line is inserted first and only
+// the wallet line is returned. A refund names the line it gives back in refund_of, which can be refunded
+// only once.
 func (q *Queries) CreateCreditTransaction(ctx context.Context, arg CreateCreditTransactionParams) (CreditTransaction, error) {
 	row := q.db.QueryRow(ctx, createCreditTransaction,
 		arg.UserID,
+		arg.PropertyID,
 		arg.Amount,
 		arg.TransactionType,
 		arg.Description,
+		arg.RefundOf,
 	)
 	var i CreditTransaction
 	err := row.Scan(
@@ -116,6 +730,179 @@ func (q *Queries) CreateCreditTransaction(ctx context.Context, arg CreateCreditT
 		&i.Amount,
 		&i.TransactionType,
 		&i.Description,
+		&i.RefundOf,
+		&i.CreatedAt,
+		&i.PropertyID,
+		&i.BalanceAfter,
+		&i.AccountID,
+		&i.EntryID,
+	)
+	return i, err
+}
+
+const createCreditTransfer = `-- name: CreateCreditTransfer :many
+INSERT INTO credit_transactions (
+    entry_id, account_id, amount, transaction_type, description
+) VALUES
+    (nextval('credit_entry_seq'), $1::int, -$2::int, 'transfer', $3),
+    (currval('credit_entry_seq'), $4::int, $2::int, 'transfer', $3)
+RETURNING id, user_id, amount, transaction_type, description, refund_of, created_at, property_id, balance_after, account_id, entry_id
+`
+
+type CreateCreditTransferParams struct {
+	FromAccountID int32       `json:"from_account_id"`
+	Amount        int32       `json:"amount"`
+	Description   pgtype.Text `json:"description"`
+	ToAccountID   int32       `json:"to_account_id"`
+}
+
+// Moves credits between two wallets as one balanced entry; the debited line comes first.
+func (q *Queries) CreateCreditTransfer(ctx context.Context, arg CreateCreditTransferParams) ([]CreditTransaction, error) {
+	rows, err := q.db.Query(ctx, createCreditTransfer,
+		arg.FromAccountID,
+		arg.Amount,
+		arg.Description,
+		arg.ToAccountID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CreditTransaction
+	for rows.Next() {
+		var i CreditTransaction
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Amount,
+			&i.TransactionType,
+			&i.Description,
+			&i.RefundOf,
+			&i.CreatedAt,
+			&i.PropertyID,
+			&i.BalanceAfter,
+			&i.AccountID,
+			&i.EntryID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const createDocument = `-- name: CreateDocument :one
+INSERT INTO documents (document_type, entity_id, version, storage_key, content_type, filename)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, document_type, entity_id, version, storage_key, content_type, filename, created_at
+`
+
+type CreateDocumentParams struct {
+	DocumentType string `json:"document_type"`
+	EntityID     int32  `json:"entity_id"`
+	Version      int32  `json:"version"`
+	StorageKey   string `json:"storage_key"`
+	ContentType  string `json:"content_type"`
+	Filename     string `json:"filename"`
+}
+
+func (q *Queries) CreateDocument(ctx context.Context, arg CreateDocumentParams) (Document, error) {
+	row := q.db.QueryRow(ctx, createDocument,
+		arg.DocumentType,
+		arg.EntityID,
+		arg.Version,
+		arg.StorageKey,
+		arg.ContentType,
+		arg.Filename,
+	)
+	var i Document
+	err := row.Scan(
+		&i.ID,
+		&i.DocumentType,
+		&i.EntityID,
+		&i.Version,
+		&i.StorageKey,
+		&i.ContentType,
+		&i.Filename,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createDocumentAccessLog = `-- name: CreateDocumentAccessLog :exec
+INSERT INTO document_access_logs (link_id, document_id, user_id, ip_address, user_agent)
+VALUES ($1, $2, $3, $4, $5)
+`
+
+type CreateDocumentAccessLogParams struct {
+	LinkID     pgtype.Int4 `json:"link_id"`
+	DocumentID int32       `json:"document_id"`
+	UserID     int32       `json:"user_id"`
+	IpAddress  pgtype.Text `json:"ip_address"`
+	UserAgent  pgtype.Text `json:"user_agent"`
+}
+
+func (q *Queries) CreateDocumentAccessLog(ctx context.Context, arg CreateDocumentAccessLogParams) error {
+	_, err := q.db.Exec(ctx, createDocumentAccessLog,
+		arg.LinkID,
+		arg.DocumentID,
+		arg.UserID,
+		arg.IpAddress,
+		arg.UserAgent,
+	)
+	return err
+}
+
+const createDocumentLink = `-- name: CreateDocumentLink :one
+INSERT INTO document_links (document_id, user_id, expires_at)
+VALUES ($1, $2, $3)
+RETURNING id, document_id, user_id, expires_at, revoked_at, created_at
+`
+
+type CreateDocumentLinkParams struct {
+	DocumentID int32            `json:"document_id"`
+	UserID     int32            `json:"user_id"`
+	ExpiresAt  pgtype.Timestamp `json:"expires_at"`
+}
+
+func (q *Queries) CreateDocumentLink(ctx context.Context, arg CreateDocumentLinkParams) (DocumentLink, error) {
+	row := q.db.QueryRow(ctx, createDocumentLink, arg.DocumentID, arg.UserID, arg.ExpiresAt)
+	var i DocumentLink
+	err := row.Scan(
+		&i.ID,
+		&i.DocumentID,
+		&i.UserID,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createDossierShare = `-- name: CreateDossierShare :one
+INSERT INTO dossier_shares (dossier_id, token, label)
+VALUES ($1, $2, $3)
+RETURNING id, dossier_id, token, label, revoked_at, created_at
+`
+
+type CreateDossierShareParams struct {
+	DossierID int32       `json:"dossier_id"`
+	Token     string      `json:"token"`
+	Label     pgtype.Text `json:"label"`
+}
+
+func (q *Queries) CreateDossierShare(ctx context.Context, arg CreateDossierShareParams) (DossierShare, error) {
+	row := q.db.QueryRow(ctx, createDossierShare, arg.DossierID, arg.Token, arg.Label)
+	var i DossierShare
+	err := row.Scan(
+		&i.ID,
+		&i.DossierID,
+		&i.Token,
+		&i.Label,
+		&i.RevokedAt,
 		&i.CreatedAt,
 	)
 	return i, err
@@ -123,22 +910,25 @@ func (q *Queries) CreateCreditTransaction(ctx context.Context, arg CreateCreditT
 
 const createDraftLease = `-- name: CreateDraftLease :one
 INSERT INTO leases (
-    property_id, start_date, end_date, rent_amount, charges_amount, deposit_amount, payment_day, special_clauses, lease_status
+    property_id, start_date, end_date, rent_amount, charges_amount, deposit_amount, payment_day, special_clauses,
+    joint_liability, individual_rent_shares, lease_status
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8, 'draft'
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, 'draft'
 )
-RETURNING id, property_id, tenant_id, start_date, end_date, rent_amount, charges_amount, deposit_amount, payment_day, special_clauses, lease_status, signature_status, signature_envelope_id, contract_url, escrow_deposit_status, created_at
+RETURNING id, property_id, tenant_id, start_date, end_date, rent_amount, charges_amount, deposit_amount, payment_day, special_clauses, lease_status, signature_status, signature_envelope_id, contract_url, escrow_deposit_status, joint_liability, individual_rent_shares, anonymized_at, created_at
 `
 
 type CreateDraftLeaseParams struct {
-	PropertyID     pgtype.Int4    `json:"property_id"`
-	StartDate      pgtype.Date    `json:"start_date"`
-	EndDate        pgtype.Date    `json:"end_date"`
-	RentAmount     pgtype.Numeric `json:"rent_amount"`
-	ChargesAmount  pgtype.Numeric `json:"charges_amount"`
-	DepositAmount  pgtype.Numeric `json:"deposit_amount"`
-	PaymentDay     pgtype.Int4    `json:"payment_day"`
-	SpecialClauses []byte         `json:"special_clauses"`
+	PropertyID           pgtype.Int4    `json:"property_id"`
+	StartDate            pgtype.Date    `json:"start_date"`
+	EndDate              pgtype.Date    `json:"end_date"`
+	RentAmount           pgtype.Numeric `json:"rent_amount"`
+	ChargesAmount        pgtype.Numeric `json:"charges_amount"`
+	DepositAmount        pgtype.Numeric `json:"deposit_amount"`
+	PaymentDay           pgtype.Int4    `json:"payment_day"`
+	SpecialClauses       []byte         `json:"special_clauses"`
+	JointLiability       pgtype.Bool    `json:"joint_liability"`
+	IndividualRentShares pgtype.Bool    `json:"individual_rent_shares"`
 }
 
 func (q *Queries) CreateDraftLease(ctx context.Context, arg CreateDraftLeaseParams) (Lease, error) {
@@ -151,6 +941,8 @@ func (q *Queries) CreateDraftLease(ctx context.Context, arg CreateDraftLeasePara
 		arg.DepositAmount,
 		arg.PaymentDay,
 		arg.SpecialClauses,
+		arg.JointLiability,
+		arg.IndividualRentShares,
 	)
 	var i Lease
 	err := row.Scan(
@@ -169,15 +961,39 @@ func (q *Queries) CreateDraftLease(ctx context.Context, arg CreateDraftLeasePara
 		&i.SignatureEnvelopeID,
 		&i.ContractUrl,
 		&i.EscrowDepositStatus,
+		&i.JointLiability,
+		&i.IndividualRentShares,
+		&i.AnonymizedAt,
 		&i.CreatedAt,
 	)
 	return i, err
 }
 
+const createIdempotencyKey = `-- name: CreateIdempotencyKey :execrows
+INSERT INTO idempotency_keys (user_id, idempotency_key, request_fingerprint)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, idempotency_key) DO NOTHING
+`
+
+type CreateIdempotencyKeyParams struct {
+	UserID             int32  `json:"user_id"`
+	IdempotencyKey     string `json:"idempotency_key"`
+	RequestFingerprint string `json:"request_fingerprint"`
+}
+
+// Réserve la clé ; 0 ligne quand elle existe déjà (requête rejouée ou concurrente).
+func (q *Queries) CreateIdempotencyKey(ctx context.Context, arg CreateIdempotencyKeyParams) (int64, error) {
+	result, err := q.db.Exec(ctx, createIdempotencyKey, arg.UserID, arg.IdempotencyKey, arg.RequestFingerprint)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const createInvitation = `-- name: CreateInvitation :one
 INSERT INTO lease_invitations (property_id, owner_id, tenant_email, token, expires_at)
 VALUES ($1, $2, $3, $4, $5)
-RETURNING id, property_id, lease_id, owner_id, tenant_email, token, status, expires_at, created_at
+RETURNING id, property_id, lease_id, party_id, owner_id, tenant_email, token, status, expires_at, created_at
 `
 
 type CreateInvitationParams struct {
@@ -201,6 +1017,7 @@ func (q *Queries) CreateInvitation(ctx context.Context, arg CreateInvitationPara
 		&i.ID,
 		&i.PropertyID,
 		&i.LeaseID,
+		&i.PartyID,
 		&i.OwnerID,
 		&i.TenantEmail,
 		&i.Token,
@@ -212,9 +1029,9 @@ func (q *Queries) CreateInvitation(ctx context.Context, arg CreateInvitationPara
 }
 
 const createInvitationWithLease = `-- name: CreateInvitationWithLease :one
-INSERT INTO lease_invitations (property_id, lease_id, owner_id, tenant_email, token, expires_at)
-VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, property_id, lease_id, owner_id, tenant_email, token, status, expires_at, created_at
+INSERT INTO lease_invitations (property_id, lease_id, owner_id, tenant_email, token, expires_at, party_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, property_id, lease_id, party_id, owner_id, tenant_email, token, status, expires_at, created_at
 `
 
 type CreateInvitationWithLeaseParams struct {
@@ -224,6 +1041,7 @@ type CreateInvitationWithLeaseParams struct {
 	TenantEmail string           `json:"tenant_email"`
 	Token       string           `json:"token"`
 	ExpiresAt   pgtype.Timestamp `json:"expires_at"`
+	PartyID     pgtype.Int4      `json:"party_id"`
 }
 
 func (q *Queries) CreateInvitationWithLease(ctx context.Context, arg CreateInvitationWithLeaseParams) (LeaseInvitation, error) {
@@ -234,12 +1052,14 @@ func (q *Queries) CreateInvitationWithLease(ctx context.Context, arg CreateInvit
 		arg.TenantEmail,
 		arg.Token,
 		arg.ExpiresAt,
+		arg.PartyID,
 	)
 	var i LeaseInvitation
 	err := row.Scan(
 		&i.ID,
 		&i.PropertyID,
 		&i.LeaseID,
+		&i.PartyID,
 		&i.OwnerID,
 		&i.TenantEmail,
 		&i.Token,
@@ -250,13 +1070,80 @@ func (q *Queries) CreateInvitationWithLease(ctx context.Context, arg CreateInvit
 	return i, err
 }
 
+const createInvoice = `-- name: CreateInvoice :one
+INSERT INTO invoices (
+    user_id, subscription_id, catalog_item_id, description, amount_cents, period_start, period_end, status,
+    slot_quantity, credit_property_id
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+)
+RETURNING id, user_id, subscription_id, description, amount_cents, period_start, period_end, status, attempts, last_attempt_at, paid_at, created_at, kind, catalog_item_id, credited_invoice_id, number, issued_at, amount_excl_vat_cents, vat_rate_bps, vat_cents, buyer_name, buyer_email, document_id, slot_quantity, credit_property_id
+`
+
+type CreateInvoiceParams struct {
+	UserID           int32       `json:"user_id"`
+	SubscriptionID   pgtype.Int4 `json:"subscription_id"`
+	CatalogItemID    pgtype.Int4 `json:"catalog_item_id"`
+	Description      string      `json:"description"`
+	AmountCents      int32       `json:"amount_cents"`
+	PeriodStart      pgtype.Date `json:"period_start"`
+	PeriodEnd        pgtype.Date `json:"period_end"`
+	Status           string      `json:"status"`
+	SlotQuantity     pgtype.Int4 `json:"slot_quantity"`
+	CreditPropertyID pgtype.Int4 `json:"credit_property_id"`
+}
+
+func (q *Queries) CreateInvoice(ctx context.Context, arg CreateInvoiceParams) (Invoice, error) {
+	row := q.db.QueryRow(ctx, createInvoice,
+		arg.UserID,
+		arg.SubscriptionID,
+		arg.CatalogItemID,
+		arg.Description,
+		arg.AmountCents,
+		arg.PeriodStart,
+		arg.PeriodEnd,
+		arg.Status,
+		arg.SlotQuantity,
+		arg.CreditPropertyID,
+	)
+	var i Invoice
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.SubscriptionID,
+		&i.Description,
+		&i.AmountCents,
+		&i.PeriodStart,
+		&i.PeriodEnd,
+		&i.Status,
+		&i.Attempts,
+		&i.LastAttemptAt,
+		&i.PaidAt,
+		&i.CreatedAt,
+		&i.Kind,
+		&i.CatalogItemID,
+		&i.CreditedInvoiceID,
+		&i.Number,
+		&i.IssuedAt,
+		&i.AmountExclVatCents,
+		&i.VatRateBps,
+		&i.VatCents,
+		&i.BuyerName,
+		&i.BuyerEmail,
+		&i.DocumentID,
+		&i.SlotQuantity,
+		&i.CreditPropertyID,
+	)
+	return i, err
+}
+
 const createLease = `-- name: CreateLease :one
 INSERT INTO leases (
     property_id, tenant_id, start_date, rent_amount, charges_amount, deposit_amount, lease_status
 ) VALUES (
     $1, $2, $3, $4, $5, $6, 'draft'
 )
-RETURNING id, property_id, tenant_id, start_date, end_date, rent_amount, charges_amount, deposit_amount, payment_day, special_clauses, lease_status, signature_status, signature_envelope_id, contract_url, escrow_deposit_status, created_at
+RETURNING id, property_id, tenant_id, start_date, end_date, rent_amount, charges_amount, deposit_amount, payment_day, special_clauses, lease_status, signature_status, signature_envelope_id, contract_url, escrow_deposit_status, joint_liability, individual_rent_shares, anonymized_at, created_at
 `
 
 type CreateLeaseParams struct {
@@ -294,6 +1181,113 @@ func (q *Queries) CreateLease(ctx context.Context, arg CreateLeaseParams) (Lease
 		&i.SignatureEnvelopeID,
 		&i.ContractUrl,
 		&i.EscrowDepositStatus,
+		&i.JointLiability,
+		&i.IndividualRentShares,
+		&i.AnonymizedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createLeaseParty = `-- name: CreateLeaseParty :one
+INSERT INTO lease_parties (lease_id, user_id, email, first_name, last_name, rent_share, status, joined_at, replaces_party_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id, lease_id, user_id, email, first_name, last_name, rent_share, status, joined_at, notice_date, left_at, solidarity_ends_at, replaces_party_id, created_at
+`
+
+type CreateLeasePartyParams struct {
+	LeaseID         int32            `json:"lease_id"`
+	UserID          pgtype.Int4      `json:"user_id"`
+	Email           string           `json:"email"`
+	FirstName       pgtype.Text      `json:"first_name"`
+	LastName        pgtype.Text      `json:"last_name"`
+	RentShare       pgtype.Numeric   `json:"rent_share"`
+	Status          string           `json:"status"`
+	JoinedAt        pgtype.Timestamp `json:"joined_at"`
+	ReplacesPartyID pgtype.Int4      `json:"replaces_party_id"`
+}
+
+func (q *Queries) CreateLeaseParty(ctx context.Context, arg CreateLeasePartyParams) (LeaseParty, error) {
+	row := q.db.QueryRow(ctx, createLeaseParty,
+		arg.LeaseID,
+		arg.UserID,
+		arg.Email,
+		arg.FirstName,
+		arg.LastName,
+		arg.RentShare,
+		arg.Status,
+		arg.JoinedAt,
+		arg.ReplacesPartyID,
+	)
+	var i LeaseParty
+	err := row.Scan(
+		&i.ID,
+		&i.LeaseID,
+		&i.UserID,
+		&i.Email,
+		&i.FirstName,
+		&i.LastName,
+		&i.RentShare,
+		&i.Status,
+		&i.JoinedAt,
+		&i.NoticeDate,
+		&i.LeftAt,
+		&i.SolidarityEndsAt,
+		&i.ReplacesPartyID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createPaymentTransaction = `-- name: CreatePaymentTransaction :one
+INSERT INTO transactions (
+    user_id, related_entity_type, related_entity_id, amount, direction,
+    stripe_payment_intent_id, stripe_refund_id, status, failure_reason
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9
+)
+ON CONFLICT (stripe_payment_intent_id) WHERE direction = 'inbound' DO UPDATE SET id = transactions.id
+RETURNING id, user_id, related_entity_type, related_entity_id, amount, currency, direction, stripe_payment_intent_id, stripe_refund_id, status, failure_reason, created_at
+`
+
+type CreatePaymentTransactionParams struct {
+	UserID                pgtype.Int4    `json:"user_id"`
+	RelatedEntityType     pgtype.Text    `json:"related_entity_type"`
+	RelatedEntityID       pgtype.Int4    `json:"related_entity_id"`
+	Amount                pgtype.Numeric `json:"amount"`
+	Direction             pgtype.Text    `json:"direction"`
+	StripePaymentIntentID pgtype.Text    `json:"stripe_payment_intent_id"`
+	StripeRefundID        pgtype.Text    `json:"stripe_refund_id"`
+	Status                pgtype.Text    `json:"status"`
+	FailureReason         pgtype.Text    `json:"failure_reason"`
+}
+
+// An intent replayed by the provider (same idempotency key) keeps the row of the interrupted attempt.
+func (q *Queries) CreatePaymentTransaction(ctx context.Context, arg CreatePaymentTransactionParams) (Transaction, error) {
+	row := q.db.QueryRow(ctx, createPaymentTransaction,
+		arg.UserID,
+		arg.RelatedEntityType,
+		arg.RelatedEntityID,
+		arg.Amount,
+		arg.Direction,
+		arg.StripePaymentIntentID,
+		arg.StripeRefundID,
+		arg.Status,
+		arg.FailureReason,
+	)
+	var i Transaction
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.RelatedEntityType,
+		&i.RelatedEntityID,
+		&i.Amount,
+		&i.Currency,
+		&i.Direction,
+		&i.StripePaymentIntentID,
+		&i.StripeRefundID,
+		&i.Status,
+		&i.FailureReason,
 		&i.CreatedAt,
 	)
 	return i, err
@@ -354,21 +1348,118 @@ func (q *Queries) CreateProperty(ctx context.Context, arg CreatePropertyParams)
 	return i, err
 }
 
+const createPropertyMedia = `-- name: CreatePropertyMedia :one
+INSERT INTO property_media (
+    property_id, media_kind, diagnostic_type, original_filename, storage_key, thumbnail_key, content_type, size_bytes, position, is_cover, expires_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+)
+RETURNING id, property_id, media_kind, diagnostic_type, original_filename, storage_key, thumbnail_key, content_type, size_bytes, position, is_cover, expires_at, reminder_sent_at, created_at
+`
+
+type CreatePropertyMediaParams struct {
+	PropertyID       int32       `json:"property_id"`
+	MediaKind        string      `json:"media_kind"`
+	DiagnosticType   pgtype.Text `json:"diagnostic_type"`
+	OriginalFilename string      `json:"original_filename"`
+	StorageKey       string      `json:"storage_key"`
+	ThumbnailKey     pgtype.Text `json:"thumbnail_key"`
+	ContentType      string      `json:"content_type"`
+	SizeBytes        int64       `json:"size_bytes"`
+	Position         int32       `json:"position"`
+	IsCover          bool        `json:"is_cover"`
+	ExpiresAt        pgtype.Date `json:"expires_at"`
+}
+
+func (q *Queries) CreatePropertyMedia(ctx context.Context, arg CreatePropertyMediaParams) (PropertyMedium, error) {
+	row := q.db.QueryRow(ctx, createPropertyMedia,
+		arg.PropertyID,
+		arg.MediaKind,
+		arg.DiagnosticType,
+		arg.OriginalFilename,
+		arg.StorageKey,
+		arg.ThumbnailKey,
+		arg.ContentType,
+		arg.SizeBytes,
+		arg.Position,
+		arg.IsCover,
+		arg.ExpiresAt,
+	)
+	var i PropertyMedium
+	err := row.Scan(
+		&i.ID,
+		&i.PropertyID,
+		&i.MediaKind,
+		&i.DiagnosticType,
+		&i.OriginalFilename,
+		&i.StorageKey,
+		&i.ThumbnailKey,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.Position,
+		&i.IsCover,
+		&i.ExpiresAt,
+		&i.ReminderSentAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createRetentionPurge = `-- name: CreateRetentionPurge :one
+INSERT INTO retention_purges (category, retention_days, cutoff, records, files, record_ids)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, category, retention_days, cutoff, records, files, record_ids, purged_at
+`
+
+type CreateRetentionPurgeParams struct {
+	Category      string           `json:"category"`
+	RetentionDays int32            `json:"retention_days"`
+	Cutoff        pgtype.Timestamp `json:"cutoff"`
+	Records       int32            `json:"records"`
+	Files         int32            `json:"files"`
+	RecordIds     []byte           `json:"record_ids"`
+}
+
+func (q *Queries) CreateRetentionPurge(ctx context.Context, arg CreateRetentionPurgeParams) (RetentionPurge, error) {
+	row := q.db.QueryRow(ctx, createRetentionPurge,
+		arg.Category,
+		arg.RetentionDays,
+		arg.Cutoff,
+		arg.Records,
+		arg.Files,
+		arg.RecordIds,
+	)
+	var i RetentionPurge
+	err := row.Scan(
+		&i.ID,
+		&i.Category,
+		&i.RetentionDays,
+		&i.Cutoff,
+		&i.Records,
+		&i.Files,
+		&i.RecordIds,
+		&i.PurgedAt,
+	)
+	return i, err
+}
+
 const createSolvencyCheck = `-- name: CreateSolvencyCheck :one
 INSERT INTO solvency_checks (
-    initiator_owner_id, candidate_id, token, property_id, status, credit_source
+    initiator_owner_id, candidate_id, token, property_id, status, credit_source, expires_at, credit_transaction_id
 ) VALUES (
-    $1, $2, $3, $4, 'pending', $5
+    $1, $2, $3, $4, 'pending', $5, $6, $7
 )
-RETURNING id, initiator_owner_id, candidate_id, token, property_id, status, credit_source, score_result, report_url, documents_json, created_at
+RETURNING id, initiator_owner_id, candidate_id, token, property_id, status, credit_source, score_result, analysis_json, report_url, documents_json, missing_documents, bank_provider, bank_consent_id, bank_connection_id, employment_type, guarantee_type, policy_results, combined_score, expires_at, reminders_sent, selection, selection_at, documents_purged_at, anonymized_at, created_at, dossier_share_id, credit_transaction_id
 `
 
 type CreateSolvencyCheckParams struct {
-	InitiatorOwnerID pgtype.Int4 `json:"initiator_owner_id"`
-	CandidateID      pgtype.Int4 `json:"candidate_id"`
-	Token            pgtype.Text `json:"token"`
-	PropertyID       pgtype.Int4 `json:"property_id"`
-	CreditSource     pgtype.Text `json:"credit_source"`
+	InitiatorOwnerID    pgtype.Int4      `json:"initiator_owner_id"`
+	CandidateID         pgtype.Int4      `json:"candidate_id"`
+	Token               pgtype.Text      `json:"token"`
+	PropertyID          pgtype.Int4      `json:"property_id"`
+	CreditSource        pgtype.Text      `json:"credit_source"`
+	ExpiresAt           pgtype.Timestamp `json:"expires_at"`
+	CreditTransactionID pgtype.Int4      `json:"credit_transaction_id"`
 }
 
 func (q *Queries) CreateSolvencyCheck(ctx context.Context, arg CreateSolvencyCheckParams) (SolvencyCheck, error) {
@@ -378,6 +1469,8 @@ func (q *Queries) CreateSolvencyCheck(ctx context.Context, arg CreateSolvencyChe
 		arg.Token,
 		arg.PropertyID,
 		arg.CreditSource,
+		arg.ExpiresAt,
+		arg.CreditTransactionID,
 	)
 	var i SolvencyCheck
 	err := row.Scan(
@@ -389,8 +1482,73 @@ func (q *Queries) CreateSolvencyCheck(ctx context.Context, arg CreateSolvencyChe
 		&i.Status,
 		&i.CreditSource,
 		&i.ScoreResult,
+		&i.AnalysisJson,
 		&i.ReportUrl,
 		&i.DocumentsJson,
+		&i.MissingDocuments,
+		&i.BankProvider,
+		&i.BankConsentID,
+		&i.BankConnectionID,
+		&i.EmploymentType,
+		&i.GuaranteeType,
+		&i.PolicyResults,
+		&i.CombinedScore,
+		&i.ExpiresAt,
+		&i.RemindersSent,
+		&i.Selection,
+		&i.SelectionAt,
+		&i.DocumentsPurgedAt,
+		&i.AnonymizedAt,
+		&i.CreatedAt,
+		&i.DossierShareID,
+		&i.CreditTransactionID,
+	)
+	return i, err
+}
+
+const createSolvencyGuarantor = `-- name: CreateSolvencyGuarantor :one
+INSERT INTO solvency_guarantors (check_id, email, first_name, last_name, phone_number, token)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, check_id, email, first_name, last_name, phone_number, token, status, documents_json, bank_provider, bank_consent_id, bank_connection_id, monthly_income, analysis_json, lease_id, mention_text, mention_signed_at, created_at
+`
+
+type CreateSolvencyGuarantorParams struct {
+	CheckID     int32       `json:"check_id"`
+	Email       string      `json:"email"`
+	FirstName   pgtype.Text `json:"first_name"`
+	LastName    pgtype.Text `json:"last_name"`
+	PhoneNumber pgtype.Text `json:"phone_number"`
+	Token       string      `json:"token"`
+}
+
+func (q *Queries) CreateSolvencyGuarantor(ctx context.Context, arg CreateSolvencyGuarantorParams) (SolvencyGuarantor, error) {
+	row := q.db.QueryRow(ctx, createSolvencyGuarantor,
+		arg.CheckID,
+		arg.Email,
+		arg.FirstName,
+		arg.LastName,
+		arg.PhoneNumber,
+		arg.Token,
+	)
+	var i SolvencyGuarantor
+	err := row.Scan(
+		&i.ID,
+		&i.CheckID,
+		&i.Email,
+		&i.FirstName,
+		&i.LastName,
+		&i.PhoneNumber,
+		&i.Token,
+		&i.Status,
+		&i.DocumentsJson,
+		&i.BankProvider,
+		&i.BankConsentID,
+		&i.BankConnectionID,
+		&i.MonthlyIncome,
+		&i.AnalysisJson,
+		&i.LeaseID,
+		&i.MentionText,
+		&i.MentionSignedAt,
 		&i.CreatedAt,
 	)
 	return i, err
@@ -398,11 +1556,12 @@ func (q *Queries) CreateSolvencyCheck(ctx context.Context, arg CreateSolvencyChe
 
 const createSubscription = `-- name: CreateSubscription :one
 INSERT INTO subscriptions (
-    user_id, plan_type, frequency, start_date, end_date, max_properties_limit
+    user_id, plan_type, frequency, start_date, end_date, max_properties_limit, catalog_item_id,
+    current_period_start, current_period_end, status
 ) VALUES (
-    $1, $2, $3, $4, $5, $6
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
 )
-RETURNING id, user_id, plan_type, frequency, status, start_date, end_date, max_properties_limit, created_at
+RETURNING id, user_id, plan_type, frequency, status, start_date, end_date, max_properties_limit, current_period_start, current_period_end, next_credit_grant, granted_credits, created_at, catalog_item_id, cancel_at_period_end, scheduled_catalog_item_id, scheduled_archived_property_ids, ended_at
 `
 
 type CreateSubscriptionParams struct {
@@ -412,6 +1571,10 @@ type CreateSubscriptionParams struct {
 	StartDate          pgtype.Date     `json:"start_date"`
 	EndDate            pgtype.Date     `json:"end_date"`
 	MaxPropertiesLimit pgtype.Int4     `json:"max_properties_limit"`
+	CatalogItemID      pgtype.Int4     `json:"catalog_item_id"`
+	CurrentPeriodStart pgtype.Date     `json:"current_period_start"`
+	CurrentPeriodEnd   pgtype.Date     `json:"current_period_end"`
+	Status             pgtype.Text     `json:"status"`
 }
 
 func (q *Queries) CreateSubscription(ctx context.Context, arg CreateSubscriptionParams) (Subscription, error) {
@@ -422,6 +1585,10 @@ func (q *Queries) CreateSubscription(ctx context.Context, arg CreateSubscription
 		arg.StartDate,
 		arg.EndDate,
 		arg.MaxPropertiesLimit,
+		arg.CatalogItemID,
+		arg.CurrentPeriodStart,
+		arg.CurrentPeriodEnd,
+		arg.Status,
 	)
 	var i Subscription
 	err := row.Scan(
@@ -433,7 +1600,70 @@ func (q *Queries) CreateSubscription(ctx context.Context, arg CreateSubscription
 		&i.StartDate,
 		&i.EndDate,
 		&i.MaxPropertiesLimit,
+		&i.CurrentPeriodStart,
+		&i.CurrentPeriodEnd,
+		&i.NextCreditGrant,
+		&i.GrantedCredits,
+		&i.CreatedAt,
+		&i.CatalogItemID,
+		&i.CancelAtPeriodEnd,
+		&i.ScheduledCatalogItemID,
+		&i.ScheduledArchivedPropertyIds,
+		&i.EndedAt,
+	)
+	return i, err
+}
+
+const createSubscriptionEvent = `-- name: CreateSubscriptionEvent :one
+INSERT INTO subscription_events (
+    subscription_id, user_id, event_type, from_catalog_item_id, to_catalog_item_id, effective_date,
+    amount_cents, invoice_id, archived_property_ids, slot_quantity
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+)
+RETURNING id, subscription_id, user_id, event_type, from_catalog_item_id, to_catalog_item_id, effective_date, amount_cents, invoice_id, archived_property_ids, created_at, slot_quantity
+`
+
+type CreateSubscriptionEventParams struct {
+	SubscriptionID      int32       `json:"subscription_id"`
+	UserID              int32       `json:"user_id"`
+	EventType           string      `json:"event_type"`
+	FromCatalogItemID   pgtype.Int4 `json:"from_catalog_item_id"`
+	ToCatalogItemID     pgtype.Int4 `json:"to_catalog_item_id"`
+	EffectiveDate       pgtype.Date `json:"effective_date"`
+	AmountCents         pgtype.Int4 `json:"amount_cents"`
+	InvoiceID           pgtype.Int4 `json:"invoice_id"`
+	ArchivedPropertyIds []int32     `json:"archived_property_ids"`
+	SlotQuantity        pgtype.Int4 `json:"slot_quantity"`
+}
+
+func (q *Queries) CreateSubscriptionEvent(ctx context.Context, arg CreateSubscriptionEventParams) (SubscriptionEvent, error) {
+	row := q.db.QueryRow(ctx, createSubscriptionEvent,
+		arg.SubscriptionID,
+		arg.UserID,
+		arg.EventType,
+		arg.FromCatalogItemID,
+		arg.ToCatalogItemID,
+		arg.EffectiveDate,
+		arg.AmountCents,
+		arg.InvoiceID,
+		arg.ArchivedPropertyIds,
+		arg.SlotQuantity,
+	)
+	var i SubscriptionEvent
+	err := row.Scan(
+		&i.ID,
+		&i.SubscriptionID,
+		&i.UserID,
+		&i.EventType,
+		&i.FromCatalogItemID,
+		&i.ToCatalogItemID,
+		&i.EffectiveDate,
+		&i.AmountCents,
+		&i.InvoiceID,
+		&i.ArchivedPropertyIds,
 		&i.CreatedAt,
+		&i.SlotQuantity,
 	)
 	return i, err
 }
@@ -449,7 +1679,7 @@ INSERT INTO users (
 ) VALUES (
   $1, $2, $3, $4, $5, $6
 )
-RETURNING id, email, password_hash, first_name, last_name, phone_number, is_verified, stripe_customer_id, is_provisional, last_context_used, created_at
+RETURNING id, email, password_hash, first_name, last_name, phone_number, is_verified, stripe_customer_id, sepa_mandate_id, is_provisional, last_context_used, role, deleted_at, created_at
 `
 
 type CreateUserParams struct {
@@ -480,654 +1710,4577 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.PhoneNumber,
 		&i.IsVerified,
 		&i.StripeCustomerID,
+		&i.SepaMandateID,
 		&i.IsProvisional,
 		&i.LastContextUsed,
+		&i.Role,
+		&i.DeletedAt,
 		&i.CreatedAt,
 	)
 	return i, err
 }
 
-const decreasePropertyCredits = `-- name: DecreasePropertyCredits :exec
+const deactivatePropertiesByOwner = `-- name: DeactivatePropertiesByOwner :exec
 UPDATE properties
-SET vacancy_credits = vacancy_credits - 1
-WHERE id = $1 AND vacancy_credits > 0
+SET is_active = FALSE
+WHERE owner_id = $1
 `
 
-func (q *Queries) DecreasePropertyCredits(ctx context.Context, id int32) error {
-	_, err := q.db.Exec(ctx, decreasePropertyCredits, id)
+func (q *Queries) DeactivatePropertiesByOwner(ctx context.Context, ownerID pgtype.Int4) error {
+	_, err := q.db.Exec(ctx, deactivatePropertiesByOwner, ownerID)
 	return err
 }
 
-const getInvitationByEmailAndProperty = `-- name: GetInvitationByEmailAndProperty :one
-SELECT id, property_id, lease_id, owner_id, tenant_email, token, status, expires_at, created_at FROM lease_invitations
-WHERE tenant_email = $1 AND property_id = $2 AND status = 'pending' LIMIT 1
+const deleteDocumentAccessLogsBefore = `-- name: DeleteDocumentAccessLogsBefore :execrows
+DELETE FROM document_access_logs
+WHERE accessed_at < $1
 `
 
-type GetInvitationByEmailAndPropertyParams struct {
-	TenantEmail string `json:"tenant_email"`
-	PropertyID  int32  `json:"property_id"`
+func (q *Queries) DeleteDocumentAccessLogsBefore(ctx context.Context, accessedAt pgtype.Timestamp) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteDocumentAccessLogsBefore, accessedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
-func (q *Queries) GetInvitationByEmailAndProperty(ctx context.Context, arg GetInvitationByEmailAndPropertyParams) (LeaseInvitation, error) {
-	row := q.db.QueryRow(ctx, getInvitationByEmailAndProperty, arg.TenantEmail, arg.PropertyID)
-	var i LeaseInvitation
-	err := row.Scan(
-		&i.ID,
-		&i.PropertyID,
-		&i.LeaseID,
-		&i.OwnerID,
-		&i.TenantEmail,
-		&i.Token,
-		&i.Status,
-		&i.ExpiresAt,
-		&i.CreatedAt,
-	)
-	return i, err
+const deleteDocumentAccessLogsByDocuments = `-- name: DeleteDocumentAccessLogsByDocuments :exec
+DELETE FROM document_access_logs
+WHERE document_id = ANY($1::int[])
+`
+
+func (q *Queries) DeleteDocumentAccessLogsByDocuments(ctx context.Context, documentIds []int32) error {
+	_, err := q.db.Exec(ctx, deleteDocumentAccessLogsByDocuments, documentIds)
+	return err
 }
 
-const getInvitationByLeaseID = `-- name: GetInvitationByLeaseID :one
-SELECT id, property_id, lease_id, owner_id, tenant_email, token, status, expires_at, created_at FROM lease_invitations
-WHERE lease_id = $1 LIMIT 1
+const deleteDocumentLinksBefore = `-- name: DeleteDocumentLinksBefore :execrows
+DELETE FROM document_links dl
+WHERE dl.expires_at < $1
+AND NOT EXISTS (SELECT 1 FROM document_access_logs dal WHERE dal.link_id = dl.id)
 `
 
-func (q *Queries) GetInvitationByLeaseID(ctx context.Context, leaseID pgtype.Int4) (LeaseInvitation, error) {
-	row := q.db.QueryRow(ctx, getInvitationByLeaseID, leaseID)
-	var i LeaseInvitation
-	err := row.Scan(
+// Links expired before the cutoff and no longer referenced by an access log.
+func (q *Queries) DeleteDocumentLinksBefore(ctx context.Context, expiresAt pgtype.Timestamp) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteDocumentLinksBefore, expiresAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteDocumentLinksByDocuments = `-- name: DeleteDocumentLinksByDocuments :exec
+DELETE FROM document_links
+WHERE document_id = ANY($1::int[])
+`
+
+func (q *Queries) DeleteDocumentLinksByDocuments(ctx context.Context, documentIds []int32) error {
+	_, err := q.db.Exec(ctx, deleteDocumentLinksByDocuments, documentIds)
+	return err
+}
+
+const deleteDocuments = `-- name: DeleteDocuments :exec
+DELETE FROM documents
+WHERE id = ANY($1::int[])
+`
+
+func (q *Queries) DeleteDocuments(ctx context.Context, documentIds []int32) error {
+	_, err := q.db.Exec(ctx, deleteDocuments, documentIds)
+	return err
+}
+
+const deleteExpiredTenantDossiers = `-- name: DeleteExpiredTenantDossiers :many
+DELETE FROM tenant_dossiers
+WHERE expires_at < $1
+RETURNING id, documents_json
+`
+
+type DeleteExpiredTenantDossiersRow struct {
+	ID            int32  `json:"id"`
+	DocumentsJson []byte `json:"documents_json"`
+}
+
+// Dossiers expirés avant la date limite ; leurs partages suivent (ON DELETE CASCADE)
+func (q *Queries) DeleteExpiredTenantDossiers(ctx context.Context, expiresAt pgtype.Timestamp) ([]DeleteExpiredTenantDossiersRow, error) {
+	rows, err := q.db.Query(ctx, deleteExpiredTenantDossiers, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DeleteExpiredTenantDossiersRow
+	for rows.Next() {
+		var i DeleteExpiredTenantDossiersRow
+		if err := rows.Scan(&i.ID, &i.DocumentsJson); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const deleteIdempotencyKey = `-- name: DeleteIdempotencyKey :exec
+DELETE FROM idempotency_keys
+WHERE user_id = $1 AND idempotency_key = $2
+`
+
+type DeleteIdempotencyKeyParams struct {
+	UserID         int32  `json:"user_id"`
+	IdempotencyKey string `json:"idempotency_key"`
+}
+
+func (q *Queries) DeleteIdempotencyKey(ctx context.Context, arg DeleteIdempotencyKeyParams) error {
+	_, err := q.db.Exec(ctx, deleteIdempotencyKey, arg.UserID, arg.IdempotencyKey)
+	return err
+}
+
+const deletePropertyMedia = `-- name: DeletePropertyMedia :exec
+DELETE FROM property_media
+WHERE id = $1 AND property_id = $2
+`
+
+type DeletePropertyMediaParams struct {
+	ID         int32 `json:"id"`
+	PropertyID int32 `json:"property_id"`
+}
+
+func (q *Queries) DeletePropertyMedia(ctx context.Context, arg DeletePropertyMediaParams) error {
+	_, err := q.db.Exec(ctx, deletePropertyMedia, arg.ID, arg.PropertyID)
+	return err
+}
+
+const deletePropertySolvencyPolicy = `-- name: DeletePropertySolvencyPolicy :execrows
+DELETE FROM solvency_policies
+WHERE property_id = $1 AND owner_id = $2
+`
+
+type DeletePropertySolvencyPolicyParams struct {
+	PropertyID pgtype.Int4 `json:"property_id"`
+	OwnerID    int32       `json:"owner_id"`
+}
+
+func (q *Queries) DeletePropertySolvencyPolicy(ctx context.Context, arg DeletePropertySolvencyPolicyParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deletePropertySolvencyPolicy, arg.PropertyID, arg.OwnerID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteSolvencyGuarantor = `-- name: DeleteSolvencyGuarantor :execrows
+DELETE FROM solvency_guarantors
+WHERE id = $1 AND check_id = $2
+`
+
+type DeleteSolvencyGuarantorParams struct {
+	ID      int32 `json:"id"`
+	CheckID int32 `json:"check_id"`
+}
+
+func (q *Queries) DeleteSolvencyGuarantor(ctx context.Context, arg DeleteSolvencyGuarantorParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteSolvencyGuarantor, arg.ID, arg.CheckID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteTenantDossier = `-- name: DeleteTenantDossier :exec
+DELETE FROM tenant_dossiers
+WHERE id = $1
+`
+
+func (q *Queries) DeleteTenantDossier(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, deleteTenantDossier, id)
+	return err
+}
+
+const deleteUnattachedGuarantorsByCheck = `-- name: DeleteUnattachedGuarantorsByCheck :exec
+DELETE FROM solvency_guarantors
+WHERE check_id = $1 AND lease_id IS NULL
+`
+
+func (q *Queries) DeleteUnattachedGuarantorsByCheck(ctx context.Context, checkID int32) error {
+	_, err := q.db.Exec(ctx, deleteUnattachedGuarantorsByCheck, checkID)
+	return err
+}
+
+const deleteWebhookEvent = `-- name: DeleteWebhookEvent :exec
+DELETE FROM webhook_events
+WHERE provider = $1 AND event_id = $2
+`
+
+type DeleteWebhookEventParams struct {
+	Provider string `json:"provider"`
+	EventID  string `json:"event_id"`
+}
+
+func (q *Queries) DeleteWebhookEvent(ctx context.Context, arg DeleteWebhookEventParams) error {
+	_, err := q.db.Exec(ctx, deleteWebhookEvent, arg.Provider, arg.EventID)
+	return err
+}
+
+const deleteWebhookEventsBefore = `-- name: DeleteWebhookEventsBefore :execrows
+DELETE FROM webhook_events
+WHERE received_at < $1
+`
+
+func (q *Queries) DeleteWebhookEventsBefore(ctx context.Context, receivedAt pgtype.Timestamp) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteWebhookEventsBefore, receivedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const endSubscription = `-- name: EndSubscription :exec
+UPDATE subscriptions
+SET status = 'cancelled', ended_at = $2, scheduled_catalog_item_id = NULL, scheduled_archived_property_ids = NULL
+WHERE id = $1
+`
+
+type EndSubscriptionParams struct {
+	ID      int32       `json:"id"`
+	EndedAt pgtype.Date `json:"ended_at"`
+}
+
+func (q *Queries) EndSubscription(ctx context.Context, arg EndSubscriptionParams) error {
+	_, err := q.db.Exec(ctx, endSubscription, arg.ID, arg.EndedAt)
+	return err
+}
+
+const expireSolvencyCheck = `-- name: ExpireSolvencyCheck :one
+UPDATE solvency_checks
+SET status = 'expired'
+WHERE id = $1 AND status IN ('pending', 'insufficient_docs') AND expires_at <= NOW()
+RETURNING id, initiator_owner_id, candidate_id, token, property_id, status, credit_source, score_result, analysis_json, report_url, documents_json, missing_documents, bank_provider, bank_consent_id, bank_connection_id, employment_type, guarantee_type, policy_results, combined_score, expires_at, reminders_sent, selection, selection_at, documents_purged_at, anonymized_at, created_at, dossier_share_id, credit_transaction_id
+`
+
+// Only a check still waiting for the candidate expires: a concurrent decision wins
+func (q *Queries) ExpireSolvencyCheck(ctx context.Context, id int32) (SolvencyCheck, error) {
+	row := q.db.QueryRow(ctx, expireSolvencyCheck, id)
+	var i SolvencyCheck
+	err := row.Scan(
 		&i.ID,
-		&i.PropertyID,
-		&i.LeaseID,
-		&i.OwnerID,
-		&i.TenantEmail,
+		&i.InitiatorOwnerID,
+		&i.CandidateID,
 		&i.Token,
+		&i.PropertyID,
 		&i.Status,
+		&i.CreditSource,
+		&i.ScoreResult,
+		&i.AnalysisJson,
+		&i.ReportUrl,
+		&i.DocumentsJson,
+		&i.MissingDocuments,
+		&i.BankProvider,
+		&i.BankConsentID,
+		&i.BankConnectionID,
+		&i.EmploymentType,
+		&i.GuaranteeType,
+		&i.PolicyResults,
+		&i.CombinedScore,
 		&i.ExpiresAt,
+		&i.RemindersSent,
+		&i.Selection,
+		&i.SelectionAt,
+		&i.DocumentsPurgedAt,
+		&i.AnonymizedAt,
 		&i.CreatedAt,
+		&i.DossierShareID,
+		&i.CreditTransactionID,
 	)
 	return i, err
 }
 
-const getInvitationByToken = `-- name: GetInvitationByToken :one
-SELECT id, property_id, lease_id, owner_id, tenant_email, token, status, expires_at, created_at FROM lease_invitations
-WHERE token = $1 LIMIT 1
+const getActiveCatalogItem = `-- name: GetActiveCatalogItem :one
+SELECT id, code, kind, name, plan_type, monthly_price_cents, yearly_price_cents, price_cents, max_properties, included_credits, slot_price_cents, valid_from, valid_until, created_at, updated_at FROM catalog_items
+WHERE kind = $1 AND code = $2
+AND valid_from <= NOW() AND (valid_until IS NULL OR valid_until > NOW())
+LIMIT 1
 `
 
-func (q *Queries) GetInvitationByToken(ctx context.Context, token string) (LeaseInvitation, error) {
-	row := q.db.QueryRow(ctx, getInvitationByToken, token)
-	var i LeaseInvitation
+type GetActiveCatalogItemParams struct {
+	Kind string `json:"kind"`
+	Code string `json:"code"`
+}
+
+func (q *Queries) GetActiveCatalogItem(ctx context.Context, arg GetActiveCatalogItemParams) (CatalogItem, error) {
+	row := q.db.QueryRow(ctx, getActiveCatalogItem, arg.Kind, arg.Code)
+	var i CatalogItem
 	err := row.Scan(
 		&i.ID,
-		&i.PropertyID,
-		&i.LeaseID,
-		&i.OwnerID,
-		&i.TenantEmail,
-		&i.Token,
-		&i.Status,
-		&i.ExpiresAt,
+		&i.Code,
+		&i.Kind,
+		&i.Name,
+		&i.PlanType,
+		&i.MonthlyPriceCents,
+		&i.YearlyPriceCents,
+		&i.PriceCents,
+		&i.MaxProperties,
+		&i.IncludedCredits,
+		&i.SlotPriceCents,
+		&i.ValidFrom,
+		&i.ValidUntil,
 		&i.CreatedAt,
+		&i.UpdatedAt,
 	)
 	return i, err
 }
 
-const getLease = `-- name: GetLease :one
-SELECT id, property_id, tenant_id, start_date, end_date, rent_amount, charges_amount, deposit_amount, payment_day, special_clauses, lease_status, signature_status, signature_envelope_id, contract_url, escrow_deposit_status, created_at FROM leases
+const getCatalogItem = `-- name: GetCatalogItem :one
+SELECT id, code, kind, name, plan_type, monthly_price_cents, yearly_price_cents, price_cents, max_properties, included_credits, slot_price_cents, valid_from, valid_until, created_at, updated_at FROM catalog_items
 WHERE id = $1 LIMIT 1
 `
 
-func (q *Queries) GetLease(ctx context.Context, id int32) (Lease, error) {
-	row := q.db.QueryRow(ctx, getLease, id)
-	var i Lease
+func (q *Queries) GetCatalogItem(ctx context.Context, id int32) (CatalogItem, error) {
+	row := q.db.QueryRow(ctx, getCatalogItem, id)
+	var i CatalogItem
 	err := row.Scan(
 		&i.ID,
-		&i.PropertyID,
-		&i.TenantID,
-		&i.StartDate,
-		&i.EndDate,
-		&i.RentAmount,
-		&i.ChargesAmount,
-		&i.DepositAmount,
-		&i.PaymentDay,
-		&i.SpecialClauses,
-		&i.LeaseStatus,
-		&i.SignatureStatus,
-		&i.SignatureEnvelopeID,
-		&i.ContractUrl,
-		&i.EscrowDepositStatus,
+		&i.Code,
+		&i.Kind,
+		&i.Name,
+		&i.PlanType,
+		&i.MonthlyPriceCents,
+		&i.YearlyPriceCents,
+		&i.PriceCents,
+		&i.MaxProperties,
+		&i.IncludedCredits,
+		&i.SlotPriceCents,
+		&i.ValidFrom,
+		&i.ValidUntil,
 		&i.CreatedAt,
+		&i.UpdatedAt,
 	)
 	return i, err
 }
 
-const getLeaseByPropertyAndStatus = `-- name: GetLeaseByPropertyAndStatus :one
-SELECT id, property_id, tenant_id, start_date, end_date, rent_amount, charges_amount, deposit_amount, payment_day, special_clauses, lease_status, signature_status, signature_envelope_id, contract_url, escrow_deposit_status, created_at FROM leases
-WHERE property_id = $1 AND lease_status = $2 LIMIT 1
+const getCatalogItemForUpdate = `-- name: GetCatalogItemForUpdate :one
+SELECT id, code, kind, name, plan_type, monthly_price_cents, yearly_price_cents, price_cents, max_properties, included_credits, slot_price_cents, valid_from, valid_until, created_at, updated_at FROM catalog_items
+WHERE id = $1 LIMIT 1
+FOR UPDATE
 `
 
-type GetLeaseByPropertyAndStatusParams struct {
-	PropertyID  pgtype.Int4 `json:"property_id"`
-	LeaseStatus pgtype.Text `json:"lease_status"`
+// FOR UPDATE (not NO KEY UPDATE) also waits for rows being inserted with a reference to this version.
+func (q *Queries) GetCatalogItemForUpdate(ctx context.Context, id int32) (CatalogItem, error) {
+	row := q.db.QueryRow(ctx, getCatalogItemForUpdate, id)
+	var i CatalogItem
+	err := row.Scan(
+		&i.ID,
+		&i.Code,
+		&i.Kind,
+		&i.Name,
+		&i.PlanType,
+		&i.MonthlyPriceCents,
+		&i.YearlyPriceCents,
+		&i.PriceCents,
+		&i.MaxProperties,
+		&i.IncludedCredits,
+		&i.SlotPriceCents,
+		&i.ValidFrom,
+		&i.ValidUntil,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
 }
 
-func (q *Queries) GetLeaseByPropertyAndStatus(ctx context.Context, arg GetLeaseByPropertyAndStatusParams) (Lease, error) {
-	row := q.db.QueryRow(ctx, getLeaseByPropertyAndStatus, arg.PropertyID, arg.LeaseStatus)
-	var i Lease
+const getCreditAccountForUpdate = `-- name: GetCreditAccountForUpdate :one
+SELECT a.id, a.kind, a.user_id, a.property_id, a.balance, a.last_transaction_id, a.created_at, a.updated_at, COALESCE(p.is_active, true)::boolean AS is_active FROM credit_accounts a
+LEFT JOIN properties p ON p.id = a.property_id
+WHERE a.id = $1
+FOR UPDATE OF a
+`
+
+type GetCreditAccountForUpdateRow struct {
+	ID                int32            `json:"id"`
+	Kind              string           `json:"kind"`
+	UserID            pgtype.Int4      `json:"user_id"`
+	PropertyID        pgtype.Int4      `json:"property_id"`
+	Balance           int32            `json:"balance"`
+	LastTransactionID pgtype.Int4      `json:"last_transaction_id"`
+	CreatedAt         pgtype.Timestamp `json:"created_at"`
+	UpdatedAt         pgtype.Timestamp `json:"updated_at"`
+	IsActive          bool             `json:"is_active"`
+}
+
+func (q *Queries) GetCreditAccountForUpdate(ctx context.Context, id int32) (GetCreditAccountForUpdateRow, error) {
+	row := q.db.QueryRow(ctx, getCreditAccountForUpdate, id)
+	var i GetCreditAccountForUpdateRow
 	err := row.Scan(
 		&i.ID,
+		&i.Kind,
+		&i.UserID,
 		&i.PropertyID,
-		&i.TenantID,
-		&i.StartDate,
-		&i.EndDate,
-		&i.RentAmount,
-		&i.ChargesAmount,
-		&i.DepositAmount,
-		&i.PaymentDay,
-		&i.SpecialClauses,
-		&i.LeaseStatus,
-		&i.SignatureStatus,
-		&i.SignatureEnvelopeID,
-		&i.ContractUrl,
-		&i.EscrowDepositStatus,
+		&i.Balance,
+		&i.LastTransactionID,
 		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.IsActive,
 	)
 	return i, err
 }
 
-const getProperty = `-- name: GetProperty :one
-SELECT id, owner_id, name, address, rental_type, details, rent_amount, rent_charges_amount, deposit_amount, is_furnished, seasonal_price_per_night, vacancy_credits, is_active, created_at FROM properties
+const getCreditAccountProperty = `-- name: GetCreditAccountProperty :one
+SELECT property_id FROM credit_accounts
+WHERE id = $1
+`
+
+// Property of a wallet (NULL for the global wallet); it never changes, no lock needed.
+func (q *Queries) GetCreditAccountProperty(ctx context.Context, id int32) (pgtype.Int4, error) {
+	row := q.db.QueryRow(ctx, getCreditAccountProperty, id)
+	var property_id pgtype.Int4
+	err := row.Scan(&property_id)
+	return property_id, err
+}
+
+const getCreditUsageSinceLastPlanGrant = `-- name: GetCreditUsageSinceLastPlanGrant :one
+SELECT COALESCE(-SUM(ct.amount), 0)::int FROM credit_transactions ct
+WHERE ct.user_id = $1 AND ct.transaction_type IN ('check_usage', 'refund') AND ct.property_id IS NULL
+AND ct.created_at >= (
+    SELECT MAX(g.created_at) FROM credit_transactions g
+    WHERE g.user_id = $1 AND g.transaction_type = 'plan_renewal'
+)
+`
+
+// Global credits spent (net of refunds) since the plan credits were last granted.
+func (q *Queries) GetCreditUsageSinceLastPlanGrant(ctx context.Context, userID pgtype.Int4) (int32, error) {
+	row := q.db.QueryRow(ctx, getCreditUsageSinceLastPlanGrant, userID)
+	var column_1 int32
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const getDocument = `-- name: GetDocument :one
+SELECT id, document_type, entity_id, version, storage_key, content_type, filename, created_at FROM documents
 WHERE id = $1 LIMIT 1
 `
 
-func (q *Queries) GetProperty(ctx context.Context, id int32) (Property, error) {
-	row := q.db.QueryRow(ctx, getProperty, id)
-	var i Property
+func (q *Queries) GetDocument(ctx context.Context, id int32) (Document, error) {
+	row := q.db.QueryRow(ctx, getDocument, id)
+	var i Document
 	err := row.Scan(
 		&i.ID,
-		&i.OwnerID,
-		&i.Name,
-		&i.Address,
-		&i.RentalType,
-		&i.Details,
-		&i.RentAmount,
-		&i.RentChargesAmount,
-		&i.DepositAmount,
-		&i.IsFurnished,
-		&i.SeasonalPricePerNight,
-		&i.VacancyCredits,
-		&i.IsActive,
+		&i.DocumentType,
+		&i.EntityID,
+		&i.Version,
+		&i.StorageKey,
+		&i.ContentType,
+		&i.Filename,
 		&i.CreatedAt,
 	)
 	return i, err
 }
 
-const getPropertyForUpdate = `-- name: GetPropertyForUpdate :one
-SELECT id, owner_id, name, address, rental_type, details, rent_amount, rent_charges_amount, deposit_amount, is_furnished, seasonal_price_per_night, vacancy_credits, is_active, created_at FROM properties
-WHERE id = $1 FOR UPDATE
+const getDocumentLink = `-- name: GetDocumentLink :one
+SELECT id, document_id, user_id, expires_at, revoked_at, created_at FROM document_links
+WHERE id = $1 LIMIT 1
 `
 
-func (q *Queries) GetPropertyForUpdate(ctx context.Context, id int32) (Property, error) {
-	row := q.db.QueryRow(ctx, getPropertyForUpdate, id)
-	var i Property
+func (q *Queries) GetDocumentLink(ctx context.Context, id int32) (DocumentLink, error) {
+	row := q.db.QueryRow(ctx, getDocumentLink, id)
+	var i DocumentLink
 	err := row.Scan(
 		&i.ID,
-		&i.OwnerID,
-		&i.Name,
-		&i.Address,
-		&i.RentalType,
-		&i.Details,
-		&i.RentAmount,
-		&i.RentChargesAmount,
-		&i.DepositAmount,
-		&i.IsFurnished,
-		&i.SeasonalPricePerNight,
-		&i.VacancyCredits,
-		&i.IsActive,
+		&i.DocumentID,
+		&i.UserID,
+		&i.ExpiresAt,
+		&i.RevokedAt,
 		&i.CreatedAt,
 	)
 	return i, err
 }
 
-const getSolvencyCheckByID = `-- name: GetSolvencyCheckByID :one
-SELECT id, initiator_owner_id, candidate_id, token, property_id, status, credit_source, score_result, report_url, documents_json, created_at FROM solvency_checks
-WHERE id = $1
+const getDossierShareByToken = `-- name: GetDossierShareByToken :one
+SELECT id, dossier_id, token, label, revoked_at, created_at FROM dossier_shares
+WHERE token = $1
 `
 
-func (q *Queries) GetSolvencyCheckByID(ctx context.Context, id int32) (SolvencyCheck, error) {
-	row := q.db.QueryRow(ctx, getSolvencyCheckByID, id)
-	var i SolvencyCheck
+func (q *Queries) GetDossierShareByToken(ctx context.Context, token string) (DossierShare, error) {
+	row := q.db.QueryRow(ctx, getDossierShareByToken, token)
+	var i DossierShare
 	err := row.Scan(
 		&i.ID,
-		&i.InitiatorOwnerID,
-		&i.CandidateID,
+		&i.DossierID,
 		&i.Token,
-		&i.PropertyID,
-		&i.Status,
-		&i.CreditSource,
-		&i.ScoreResult,
-		&i.ReportUrl,
-		&i.DocumentsJson,
+		&i.Label,
+		&i.RevokedAt,
 		&i.CreatedAt,
 	)
 	return i, err
 }
 
-const getSolvencyCheckByToken = `-- name: GetSolvencyCheckByToken :one
-SELECT 
-    sc.id, sc.initiator_owner_id, sc.candidate_id, sc.token, sc.property_id, sc.status, sc.created_at,
-    u.email as candidate_email, u.first_name as candidate_first_name, u.last_name as candidate_last_name,
-    p.address as property_address, p.rent_amount as property_rent_amount, p.name as property_name
-FROM solvency_checks sc
-JOIN users u ON sc.candidate_id = u.id
-JOIN properties p ON sc.property_id = p.id
-WHERE sc.token = $1
- LIMIT 1
+const getEffectiveSolvencyPolicy = `-- name: GetEffectiveSolvencyPolicy :one
+SELECT id, owner_id, property_id, income_multiplier, include_charges, count_guarantor_income, min_employment_type, accepted_guarantees, require_guarantee, created_at, updated_at FROM solvency_policies
+WHERE owner_id = $1 AND (property_id = $2 OR property_id IS NULL)
+ORDER BY property_id NULLS LAST
+LIMIT 1
 `
 
-type GetSolvencyCheckByTokenRow struct {
-	ID                 int32              `json:"id"`
-	InitiatorOwnerID   pgtype.Int4        `json:"initiator_owner_id"`
-	CandidateID        pgtype.Int4        `json:"candidate_id"`
-	Token              pgtype.Text        `json:"token"`
-	PropertyID         pgtype.Int4        `json:"property_id"`
-	Status             NullSolvencyStatus `json:"status"`
-	CreatedAt          pgtype.Timestamp   `json:"created_at"`
-	CandidateEmail     string             `json:"candidate_email"`
-	CandidateFirstName pgtype.Text        `json:"candidate_first_name"`
-	CandidateLastName  pgtype.Text        `json:"candidate_last_name"`
-	PropertyAddress    string             `json:"property_address"`
-	PropertyRentAmount pgtype.Numeric     `json:"property_rent_amount"`
-	PropertyName       pgtype.Text        `json:"property_name"`
+type GetEffectiveSolvencyPolicyParams struct {
+	OwnerID    int32       `json:"owner_id"`
+	PropertyID pgtype.Int4 `json:"property_id"`
 }
 
-func (q *Queries) GetSolvencyCheckByToken(ctx context.Context, token pgtype.Text) (GetSolvencyCheckByTokenRow, error) {
-	row := q.db.QueryRow(ctx, getSolvencyCheckByToken, token)
-	var i GetSolvencyCheckByTokenRow
+// La politique propre au bien l'emporte sur la politique par défaut du propriétaire
+func (q *Queries) GetEffectiveSolvencyPolicy(ctx context.Context, arg GetEffectiveSolvencyPolicyParams) (SolvencyPolicy, error) {
+	row := q.db.QueryRow(ctx, getEffectiveSolvencyPolicy, arg.OwnerID, arg.PropertyID)
+	var i SolvencyPolicy
 	err := row.Scan(
 		&i.ID,
-		&i.InitiatorOwnerID,
-		&i.CandidateID,
-		&i.Token,
+		&i.OwnerID,
 		&i.PropertyID,
-		&i.Status,
+		&i.IncomeMultiplier,
+		&i.IncludeCharges,
+		&i.CountGuarantorIncome,
+		&i.MinEmploymentType,
+		&i.AcceptedGuarantees,
+		&i.RequireGuarantee,
 		&i.CreatedAt,
-		&i.CandidateEmail,
-		&i.CandidateFirstName,
-		&i.CandidateLastName,
-		&i.PropertyAddress,
-		&i.PropertyRentAmount,
-		&i.PropertyName,
+		&i.UpdatedAt,
 	)
 	return i, err
 }
 
-const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, email, password_hash, first_name, last_name, phone_number, is_verified, stripe_customer_id, is_provisional, last_context_used, created_at FROM users
-WHERE email = $1 LIMIT 1
+const getGuarantorByBankConnection = `-- name: GetGuarantorByBankConnection :one
+SELECT id, check_id, email, first_name, last_name, phone_number, token, status, documents_json, bank_provider, bank_consent_id, bank_connection_id, monthly_income, analysis_json, lease_id, mention_text, mention_signed_at, created_at FROM solvency_guarantors
+WHERE bank_provider = $1 AND bank_connection_id = $2
+LIMIT 1
 `
 
-func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
-	row := q.db.QueryRow(ctx, getUserByEmail, email)
-	var i User
+type GetGuarantorByBankConnectionParams struct {
+	BankProvider     pgtype.Text `json:"bank_provider"`
+	BankConnectionID pgtype.Text `json:"bank_connection_id"`
+}
+
+func (q *Queries) GetGuarantorByBankConnection(ctx context.Context, arg GetGuarantorByBankConnectionParams) (SolvencyGuarantor, error) {
+	row := q.db.QueryRow(ctx, getGuarantorByBankConnection, arg.BankProvider, arg.BankConnectionID)
+	var i SolvencyGuarantor
 	err := row.Scan(
 		&i.ID,
+		&i.CheckID,
 		&i.Email,
-		&i.PasswordHash,
 		&i.FirstName,
 		&i.LastName,
 		&i.PhoneNumber,
-		&i.IsVerified,
-		&i.StripeCustomerID,
-		&i.IsProvisional,
-		&i.LastContextUsed,
+		&i.Token,
+		&i.Status,
+		&i.DocumentsJson,
+		&i.BankProvider,
+		&i.BankConsentID,
+		&i.BankConnectionID,
+		&i.MonthlyIncome,
+		&i.AnalysisJson,
+		&i.LeaseID,
+		&i.MentionText,
+		&i.MentionSignedAt,
 		&i.CreatedAt,
 	)
 	return i, err
 }
 
-const getUserById = `-- name: GetUserById :one
-SELECT id, email, password_hash, first_name, last_name, phone_number, is_verified, stripe_customer_id, is_provisional, last_context_used, created_at FROM users
-WHERE id = $1 LIMIT 1
+const getGuarantorByTokenForUpdate = `-- name: GetGuarantorByTokenForUpdate :one
+SELECT id, check_id, email, first_name, last_name, phone_number, token, status, documents_json, bank_provider, bank_consent_id, bank_connection_id, monthly_income, analysis_json, lease_id, mention_text, mention_signed_at, created_at FROM solvency_guarantors
+WHERE token = $1
+FOR UPDATE
 `
 
-func (q *Queries) GetUserById(ctx context.Context, id int32) (User, error) {
-	row := q.db.QueryRow(ctx, getUserById, id)
-	var i User
+func (q *Queries) GetGuarantorByTokenForUpdate(ctx context.Context, token string) (SolvencyGuarantor, error) {
+	row := q.db.QueryRow(ctx, getGuarantorByTokenForUpdate, token)
+	var i SolvencyGuarantor
 	err := row.Scan(
 		&i.ID,
+		&i.CheckID,
 		&i.Email,
-		&i.PasswordHash,
 		&i.FirstName,
 		&i.LastName,
 		&i.PhoneNumber,
-		&i.IsVerified,
-		&i.StripeCustomerID,
-		&i.IsProvisional,
-		&i.LastContextUsed,
+		&i.Token,
+		&i.Status,
+		&i.DocumentsJson,
+		&i.BankProvider,
+		&i.BankConsentID,
+		&i.BankConnectionID,
+		&i.MonthlyIncome,
+		&i.AnalysisJson,
+		&i.LeaseID,
+		&i.MentionText,
+		&i.MentionSignedAt,
 		&i.CreatedAt,
 	)
 	return i, err
 }
 
-const getUserCreditBalance = `-- name: GetUserCreditBalance :one
-SELECT current_balance::int FROM view_user_credit_balance
-WHERE user_id = $1
+const getIdempotencyKey = `-- name: GetIdempotencyKey :one
+SELECT user_id, idempotency_key, request_fingerprint, status, response_code, response_content_type, response_body, created_at, heartbeat_at, completed_at FROM idempotency_keys
+WHERE user_id = $1 AND idempotency_key = $2
 `
 
-func (q *Queries) GetUserCreditBalance(ctx context.Context, userID pgtype.Int4) (int32, error) {
-	row := q.db.QueryRow(ctx, getUserCreditBalance, userID)
-	var current_balance int32
-	err := row.Scan(&current_balance)
-	return current_balance, err
+type GetIdempotencyKeyParams struct {
+	UserID         int32  `json:"user_id"`
+	IdempotencyKey string `json:"idempotency_key"`
 }
 
-const getUserCreditBalanceForUpdate = `-- name: GetUserCreditBalanceForUpdate :one
-SELECT current_balance::int FROM view_user_credit_balance
-WHERE user_id = $1
+func (q *Queries) GetIdempotencyKey(ctx context.Context, arg GetIdempotencyKeyParams) (IdempotencyKey, error) {
+	row := q.db.QueryRow(ctx, getIdempotencyKey, arg.UserID, arg.IdempotencyKey)
+	var i IdempotencyKey
+	err := row.Scan(
+		&i.UserID,
+		&i.IdempotencyKey,
+		&i.RequestFingerprint,
+		&i.Status,
+		&i.ResponseCode,
+		&i.ResponseContentType,
+		&i.ResponseBody,
+		&i.CreatedAt,
+		&i.HeartbeatAt,
+		&i.CompletedAt,
+	)
+	return i, err
+}
+
+const getInvitationByEmailAndProperty = `-- name: GetInvitationByEmailAndProperty :one
+SELECT id, property_id, lease_id, party_id, owner_id, tenant_email, token, status, expires_at, created_at FROM lease_invitations
+WHERE tenant_email = $1 AND property_id = $2 AND status = 'pending' LIMIT 1
 `
 
-func (q *Queries) GetUserCreditBalanceForUpdate(ctx context.Context, userID pgtype.Int4) (int32, error) {
-	row := q.db.QueryRow(ctx, getUserCreditBalanceForUpdate, userID)
-	var current_balance int32
-	err := row.Scan(&current_balance)
-	return current_balance, err
+type GetInvitationByEmailAndPropertyParams struct {
+	TenantEmail string `json:"tenant_email"`
+	PropertyID  int32  `json:"property_id"`
 }
 
-const getUserForUpdate = `-- name: GetUserForUpdate :one
-SELECT id, email, password_hash, first_name, last_name, phone_number, is_verified, stripe_customer_id, is_provisional, last_context_used, created_at FROM users
-WHERE id = $1 FOR UPDATE
+func (q *Queries) GetInvitationByEmailAndProperty(ctx context.Context, arg GetInvitationByEmailAndPropertyParams) (LeaseInvitation, error) {
+	row := q.db.QueryRow(ctx, getInvitationByEmailAndProperty, arg.TenantEmail, arg.PropertyID)
+	var i LeaseInvitation
+	err := row.Scan(
+		&i.ID,
+		&i.PropertyID,
+		&i.LeaseID,
+		&i.PartyID,
+		&i.OwnerID,
+		&i.TenantEmail,
+		&i.Token,
+		&i.Status,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getInvitationByLeaseID = `-- name: GetInvitationByLeaseID :one
+SELECT id, property_id, lease_id, party_id, owner_id, tenant_email, token, status, expires_at, created_at FROM lease_invitations
+WHERE lease_id = $1 LIMIT 1
 `
 
-func (q *Queries) GetUserForUpdate(ctx context.Context, id int32) (User, error) {
-	row := q.db.QueryRow(ctx, getUserForUpdate, id)
-	var i User
+func (q *Queries) GetInvitationByLeaseID(ctx context.Context, leaseID pgtype.Int4) (LeaseInvitation, error) {
+	row := q.db.QueryRow(ctx, getInvitationByLeaseID, leaseID)
+	var i LeaseInvitation
 	err := row.Scan(
 		&i.ID,
-		&i.Email,
-		&i.PasswordHash,
-		&i.FirstName,
-		&i.LastName,
-		&i.PhoneNumber,
-		&i.IsVerified,
-		&i.StripeCustomerID,
-		&i.IsProvisional,
-		&i.LastContextUsed,
+		&i.PropertyID,
+		&i.LeaseID,
+		&i.PartyID,
+		&i.OwnerID,
+		&i.TenantEmail,
+		&i.Token,
+		&i.Status,
+		&i.ExpiresAt,
 		&i.CreatedAt,
 	)
 	return i, err
 }
 
-const getUserSubscription = `-- name: GetUserSubscription :one
-SELECT id, user_id, plan_type, frequency, status, start_date, end_date, max_properties_limit, created_at FROM subscriptions
-WHERE user_id = $1 AND status = 'active'
-ORDER BY created_at DESC
-LIMIT 1
+const getInvitationByToken = `-- name: GetInvitationByToken :one
+SELECT id, property_id, lease_id, party_id, owner_id, tenant_email, token, status, expires_at, created_at FROM lease_invitations
+WHERE token = $1 LIMIT 1
 `
 
-func (q *Queries) GetUserSubscription(ctx context.Context, userID pgtype.Int4) (Subscription, error) {
-	row := q.db.QueryRow(ctx, getUserSubscription, userID)
-	var i Subscription
+func (q *Queries) GetInvitationByToken(ctx context.Context, token string) (LeaseInvitation, error) {
+	row := q.db.QueryRow(ctx, getInvitationByToken, token)
+	var i LeaseInvitation
 	err := row.Scan(
 		&i.ID,
-		&i.UserID,
-		&i.PlanType,
-		&i.Frequency,
+		&i.PropertyID,
+		&i.LeaseID,
+		&i.PartyID,
+		&i.OwnerID,
+		&i.TenantEmail,
+		&i.Token,
 		&i.Status,
-		&i.StartDate,
-		&i.EndDate,
-		&i.MaxPropertiesLimit,
+		&i.ExpiresAt,
 		&i.CreatedAt,
 	)
 	return i, err
 }
 
-const hasReceivedInitialBonus = `-- name: HasReceivedInitialBonus :one
-SELECT EXISTS(
-    SELECT 1 FROM credit_transactions
-    WHERE user_id = $1 AND transaction_type = 'initial_free'
-)
+const getInvoice = `-- name: GetInvoice :one
+SELECT id, user_id, subscription_id, description, amount_cents, period_start, period_end, status, attempts, last_attempt_at, paid_at, created_at, kind, catalog_item_id, credited_invoice_id, number, issued_at, amount_excl_vat_cents, vat_rate_bps, vat_cents, buyer_name, buyer_email, document_id, slot_quantity, credit_property_id FROM invoices
+WHERE id = $1
 `
 
-func (q *Queries) HasReceivedInitialBonus(ctx context.Context, userID pgtype.Int4) (bool, error) {
-	row := q.db.QueryRow(ctx, hasReceivedInitialBonus, userID)
-	var exists bool
-	err := row.Scan(&exists)
-	return exists, err
+func (q *Queries) GetInvoice(ctx context.Context, id int32) (Invoice, error) {
+	row := q.db.QueryRow(ctx, getInvoice, id)
+	var i Invoice
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.SubscriptionID,
+		&i.Description,
+		&i.AmountCents,
+		&i.PeriodStart,
+		&i.PeriodEnd,
+		&i.Status,
+		&i.Attempts,
+		&i.LastAttemptAt,
+		&i.PaidAt,
+		&i.CreatedAt,
+		&i.Kind,
+		&i.CatalogItemID,
+		&i.CreditedInvoiceID,
+		&i.Number,
+		&i.IssuedAt,
+		&i.AmountExclVatCents,
+		&i.VatRateBps,
+		&i.VatCents,
+		&i.BuyerName,
+		&i.BuyerEmail,
+		&i.DocumentID,
+		&i.SlotQuantity,
+		&i.CreditPropertyID,
+	)
+	return i, err
 }
 
-const increasePropertyCredits = `-- name: IncreasePropertyCredits :exec
-UPDATE properties
-SET vacancy_credits = vacancy_credits + 1
-WHERE id = $1
+const getInvoiceForPeriod = `-- name: GetInvoiceForPeriod :one
+SELECT id, user_id, subscription_id, description, amount_cents, period_start, period_end, status, attempts, last_attempt_at, paid_at, created_at, kind, catalog_item_id, credited_invoice_id, number, issued_at, amount_excl_vat_cents, vat_rate_bps, vat_cents, buyer_name, buyer_email, document_id, slot_quantity, credit_property_id FROM invoices
+WHERE subscription_id = $1 AND period_start = $2
 `
 
-func (q *Queries) IncreasePropertyCredits(ctx context.Context, id int32) error {
-	_, err := q.db.Exec(ctx, increasePropertyCredits, id)
-	return err
+type GetInvoiceForPeriodParams struct {
+	SubscriptionID pgtype.Int4 `json:"subscription_id"`
+	PeriodStart    pgtype.Date `json:"period_start"`
 }
 
-const listLeasesByTenant = `-- name: ListLeasesByTenant :many
-SELECT 
-    l.id, l.property_id, l.tenant_id, l.start_date, l.end_date, l.rent_amount, l.charges_amount, l.deposit_amount, l.lease_status, l.signature_status, l.contract_url, l.created_at,
-    p.address as property_address, p.rental_type
-FROM leases l
-JOIN properties p ON l.property_id = p.id
-WHERE l.tenant_id = $1
-ORDER BY l.created_at DESC
+func (q *Queries) GetInvoiceForPeriod(ctx context.Context, arg GetInvoiceForPeriodParams) (Invoice, error) {
+	row := q.db.QueryRow(ctx, getInvoiceForPeriod, arg.SubscriptionID, arg.PeriodStart)
+	var i Invoice
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.SubscriptionID,
+		&i.Description,
+		&i.AmountCents,
+		&i.PeriodStart,
+		&i.PeriodEnd,
+		&i.Status,
+		&i.Attempts,
+		&i.LastAttemptAt,
+		&i.PaidAt,
+		&i.CreatedAt,
+		&i.Kind,
+		&i.CatalogItemID,
+		&i.CreditedInvoiceID,
+		&i.Number,
+		&i.IssuedAt,
+		&i.AmountExclVatCents,
+		&i.VatRateBps,
+		&i.VatCents,
+		&i.BuyerName,
+		&i.BuyerEmail,
+		&i.DocumentID,
+		&i.SlotQuantity,
+		&i.CreditPropertyID,
+	)
+	return i, err
+}
+
+const getInvoiceForUpdate = `-- name: GetInvoiceForUpdate :one
+SELECT id, user_id, subscription_id, description, amount_cents, period_start, period_end, status, attempts, last_attempt_at, paid_at, created_at, kind, catalog_item_id, credited_invoice_id, number, issued_at, amount_excl_vat_cents, vat_rate_bps, vat_cents, buyer_name, buyer_email, document_id, slot_quantity, credit_property_id FROM invoices
+WHERE id = $1
+FOR UPDATE
 `
 
-type ListLeasesByTenantRow struct {
-	ID              int32            `json:"id"`
-	PropertyID      pgtype.Int4      `json:"property_id"`
-	TenantID        pgtype.Int4      `json:"tenant_id"`
-	StartDate       pgtype.Date      `json:"start_date"`
-	EndDate         pgtype.Date      `json:"end_date"`
-	RentAmount      pgtype.Numeric   `json:"rent_amount"`
-	ChargesAmount   pgtype.Numeric   `json:"charges_amount"`
-	DepositAmount   pgtype.Numeric   `json:"deposit_amount"`
-	LeaseStatus     pgtype.Text      `json:"lease_status"`
-	SignatureStatus pgtype.Text      `json:"signature_status"`
-	ContractUrl     pgtype.Text      `json:"contract_url"`
-	CreatedAt       pgtype.Timestamp `json:"created_at"`
-	PropertyAddress string           `json:"property_address"`
-	RentalType      PropertyType     `json:"rental_type"`
+func (q *Queries) GetInvoiceForUpdate(ctx context.Context, id int32) (Invoice, error) {
+	row := q.db.QueryRow(ctx, getInvoiceForUpdate, id)
+	var i Invoice
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.SubscriptionID,
+		&i.Description,
+		&i.AmountCents,
+		&i.PeriodStart,
+		&i.PeriodEnd,
+		&i.Status,
+		&i.Attempts,
+		&i.LastAttemptAt,
+		&i.PaidAt,
+		&i.CreatedAt,
+		&i.Kind,
+		&i.CatalogItemID,
+		&i.CreditedInvoiceID,
+		&i.Number,
+		&i.IssuedAt,
+		&i.AmountExclVatCents,
+		&i.VatRateBps,
+		&i.VatCents,
+		&i.BuyerName,
+		&i.BuyerEmail,
+		&i.DocumentID,
+		&i.SlotQuantity,
+		&i.CreditPropertyID,
+	)
+	return i, err
 }
 
-func (q *Queries) ListLeasesByTenant(ctx context.Context, tenantID pgtype.Int4) ([]ListLeasesByTenantRow, error) {
-	rows, err := q.db.Query(ctx, listLeasesByTenant, tenantID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []ListLeasesByTenantRow
-	for rows.Next() {
-		var i ListLeasesByTenantRow
-		if err := rows.Scan(
-			&i.ID,
-			&i.PropertyID,
-			&i.TenantID,
-			&i.StartDate,
-			&i.EndDate,
-			&i.RentAmount,
-			&i.ChargesAmount,
-			&i.DepositAmount,
-			&i.LeaseStatus,
-			&i.SignatureStatus,
-			&i.ContractUrl,
-			&i.CreatedAt,
-			&i.PropertyAddress,
-			&i.RentalType,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+const getIssuedInvoice = `-- name: GetIssuedInvoice :one
+SELECT id, user_id, subscription_id, description, amount_cents, period_start, period_end, status, attempts, last_attempt_at, paid_at, created_at, kind, catalog_item_id, credited_invoice_id, number, issued_at, amount_excl_vat_cents, vat_rate_bps, vat_cents, buyer_name, buyer_email, document_id, slot_quantity, credit_property_id FROM invoices
+WHERE id = $1 AND number IS NOT NULL
+`
+
+func (q *Queries) GetIssuedInvoice(ctx context.Context, id int32) (Invoice, error) {
+	row := q.db.QueryRow(ctx, getIssuedInvoice, id)
+	var i Invoice
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.SubscriptionID,
+		&i.Description,
+		&i.AmountCents,
+		&i.PeriodStart,
+		&i.PeriodEnd,
+		&i.Status,
+		&i.Attempts,
+		&i.LastAttemptAt,
+		&i.PaidAt,
+		&i.CreatedAt,
+		&i.Kind,
+		&i.CatalogItemID,
+		&i.CreditedInvoiceID,
+		&i.Number,
+		&i.IssuedAt,
+		&i.AmountExclVatCents,
+		&i.VatRateBps,
+		&i.VatCents,
+		&i.BuyerName,
+		&i.BuyerEmail,
+		&i.DocumentID,
+		&i.SlotQuantity,
+		&i.CreditPropertyID,
+	)
+	return i, err
 }
 
-const listPropertiesByOwner = `-- name: ListPropertiesByOwner :many
-SELECT id, owner_id, name, address, rental_type, details, rent_amount, rent_charges_amount, deposit_amount, is_furnished, seasonal_price_per_night, vacancy_credits, is_active, created_at FROM properties
-WHERE owner_id = $1
-ORDER BY created_at DESC
+const getLatestDocument = `-- name: GetLatestDocument :one
+SELECT id, document_type, entity_id, version, storage_key, content_type, filename, created_at FROM documents
+WHERE document_type = $1 AND entity_id = $2
+ORDER BY version DESC
+LIMIT 1
+`
+
+type GetLatestDocumentParams struct {
+	DocumentType string `json:"document_type"`
+	EntityID     int32  `json:"entity_id"`
+}
+
+func (q *Queries) GetLatestDocument(ctx context.Context, arg GetLatestDocumentParams) (Document, error) {
+	row := q.db.QueryRow(ctx, getLatestDocument, arg.DocumentType, arg.EntityID)
+	var i Document
+	err := row.Scan(
+		&i.ID,
+		&i.DocumentType,
+		&i.EntityID,
+		&i.Version,
+		&i.StorageKey,
+		&i.ContentType,
+		&i.Filename,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getLease = `-- name: GetLease :one
+SELECT id, property_id, tenant_id, start_date, end_date, rent_amount, charges_amount, deposit_amount, payment_day, special_clauses, lease_status, signature_status, signature_envelope_id, contract_url, escrow_deposit_status, joint_liability, individual_rent_shares, anonymized_at, created_at FROM leases
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetLease(ctx context.Context, id int32) (Lease, error) {
+	row := q.db.QueryRow(ctx, getLease, id)
+	var i Lease
+	err := row.Scan(
+		&i.ID,
+		&i.PropertyID,
+		&i.TenantID,
+		&i.StartDate,
+		&i.EndDate,
+		&i.RentAmount,
+		&i.ChargesAmount,
+		&i.DepositAmount,
+		&i.PaymentDay,
+		&i.SpecialClauses,
+		&i.LeaseStatus,
+		&i.SignatureStatus,
+		&i.SignatureEnvelopeID,
+		&i.ContractUrl,
+		&i.EscrowDepositStatus,
+		&i.JointLiability,
+		&i.IndividualRentShares,
+		&i.AnonymizedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getLeaseByPropertyAndStatus = `-- name: GetLeaseByPropertyAndStatus :one
+SELECT id, property_id, tenant_id, start_date, end_date, rent_amount, charges_amount, deposit_amount, payment_day, special_clauses, lease_status, signature_status, signature_envelope_id, contract_url, escrow_deposit_status, joint_liability, individual_rent_shares, anonymized_at, created_at FROM leases
+WHERE property_id = $1 AND lease_status = $2 LIMIT 1
+`
+
+type GetLeaseByPropertyAndStatusParams struct {
+	PropertyID  pgtype.Int4 `json:"property_id"`
+	LeaseStatus pgtype.Text `json:"lease_status"`
+}
+
+func (q *Queries) GetLeaseByPropertyAndStatus(ctx context.Context, arg GetLeaseByPropertyAndStatusParams) (Lease, error) {
+	row := q.db.QueryRow(ctx, getLeaseByPropertyAndStatus, arg.PropertyID, arg.LeaseStatus)
+	var i Lease
+	err := row.Scan(
+		&i.ID,
+		&i.PropertyID,
+		&i.TenantID,
+		&i.StartDate,
+		&i.EndDate,
+		&i.RentAmount,
+		&i.ChargesAmount,
+		&i.DepositAmount,
+		&i.PaymentDay,
+		&i.SpecialClauses,
+		&i.LeaseStatus,
+		&i.SignatureStatus,
+		&i.SignatureEnvelopeID,
+		&i.ContractUrl,
+		&i.EscrowDepositStatus,
+		&i.JointLiability,
+		&i.IndividualRentShares,
+		&i.AnonymizedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getLeaseParty = `-- name: GetLeaseParty :one
+SELECT id, lease_id, user_id, email, first_name, last_name, rent_share, status, joined_at, notice_date, left_at, solidarity_ends_at, replaces_party_id, created_at FROM lease_parties
+WHERE id = $1 AND lease_id = $2
+LIMIT 1
+`
+
+type GetLeasePartyParams struct {
+	ID      int32 `json:"id"`
+	LeaseID int32 `json:"lease_id"`
+}
+
+func (q *Queries) GetLeaseParty(ctx context.Context, arg GetLeasePartyParams) (LeaseParty, error) {
+	row := q.db.QueryRow(ctx, getLeaseParty, arg.ID, arg.LeaseID)
+	var i LeaseParty
+	err := row.Scan(
+		&i.ID,
+		&i.LeaseID,
+		&i.UserID,
+		&i.Email,
+		&i.FirstName,
+		&i.LastName,
+		&i.RentShare,
+		&i.Status,
+		&i.JoinedAt,
+		&i.NoticeDate,
+		&i.LeftAt,
+		&i.SolidarityEndsAt,
+		&i.ReplacesPartyID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getLeasePartyByReplaced = `-- name: GetLeasePartyByReplaced :one
+SELECT id, lease_id, user_id, email, first_name, last_name, rent_share, status, joined_at, notice_date, left_at, solidarity_ends_at, replaces_party_id, created_at FROM lease_parties
+WHERE replaces_party_id = $1
+LIMIT 1
+`
+
+func (q *Queries) GetLeasePartyByReplaced(ctx context.Context, replacesPartyID pgtype.Int4) (LeaseParty, error) {
+	row := q.db.QueryRow(ctx, getLeasePartyByReplaced, replacesPartyID)
+	var i LeaseParty
+	err := row.Scan(
+		&i.ID,
+		&i.LeaseID,
+		&i.UserID,
+		&i.Email,
+		&i.FirstName,
+		&i.LastName,
+		&i.RentShare,
+		&i.Status,
+		&i.JoinedAt,
+		&i.NoticeDate,
+		&i.LeftAt,
+		&i.SolidarityEndsAt,
+		&i.ReplacesPartyID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getLeasePartyByUser = `-- name: GetLeasePartyByUser :one
+SELECT id, lease_id, user_id, email, first_name, last_name, rent_share, status, joined_at, notice_date, left_at, solidarity_ends_at, replaces_party_id, created_at FROM lease_parties
+WHERE lease_id = $1 AND user_id = $2 AND status != 'invited'
+LIMIT 1
+`
+
+type GetLeasePartyByUserParams struct {
+	LeaseID int32       `json:"lease_id"`
+	UserID  pgtype.Int4 `json:"user_id"`
+}
+
+// Colocataire ayant accepté son invitation (parti ou non)
+func (q *Queries) GetLeasePartyByUser(ctx context.Context, arg GetLeasePartyByUserParams) (LeaseParty, error) {
+	row := q.db.QueryRow(ctx, getLeasePartyByUser, arg.LeaseID, arg.UserID)
+	var i LeaseParty
+	err := row.Scan(
+		&i.ID,
+		&i.LeaseID,
+		&i.UserID,
+		&i.Email,
+		&i.FirstName,
+		&i.LastName,
+		&i.RentShare,
+		&i.Status,
+		&i.JoinedAt,
+		&i.NoticeDate,
+		&i.LeftAt,
+		&i.SolidarityEndsAt,
+		&i.ReplacesPartyID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getNextDocumentVersion = `-- name: GetNextDocumentVersion :one
+SELECT COALESCE(MAX(version) + 1, 1)::int FROM documents
+WHERE document_type = $1 AND entity_id = $2
+`
+
+type GetNextDocumentVersionParams struct {
+	DocumentType string `json:"document_type"`
+	EntityID     int32  `json:"entity_id"`
+}
+
+func (q *Queries) GetNextDocumentVersion(ctx context.Context, arg GetNextDocumentVersionParams) (int32, error) {
+	row := q.db.QueryRow(ctx, getNextDocumentVersion, arg.DocumentType, arg.EntityID)
+	var column_1 int32
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const getNextPhotoPosition = `-- name: GetNextPhotoPosition :one
+SELECT COALESCE(MAX(position) + 1, 0)::int FROM property_media
+WHERE property_id = $1 AND media_kind = 'photo'
+`
+
+func (q *Queries) GetNextPhotoPosition(ctx context.Context, propertyID int32) (int32, error) {
+	row := q.db.QueryRow(ctx, getNextPhotoPosition, propertyID)
+	var column_1 int32
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const getPaymentTransactionByIntentForUpdate = `-- name: GetPaymentTransactionByIntentForUpdate :one
+SELECT id, user_id, related_entity_type, related_entity_id, amount, currency, direction, stripe_payment_intent_id, stripe_refund_id, status, failure_reason, created_at FROM transactions
+WHERE stripe_payment_intent_id = $1 AND direction = 'inbound'
+FOR UPDATE
+`
+
+func (q *Queries) GetPaymentTransactionByIntentForUpdate(ctx context.Context, stripePaymentIntentID pgtype.Text) (Transaction, error) {
+	row := q.db.QueryRow(ctx, getPaymentTransactionByIntentForUpdate, stripePaymentIntentID)
+	var i Transaction
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.RelatedEntityType,
+		&i.RelatedEntityID,
+		&i.Amount,
+		&i.Currency,
+		&i.Direction,
+		&i.StripePaymentIntentID,
+		&i.StripeRefundID,
+		&i.Status,
+		&i.FailureReason,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getPaymentTransactionForUpdate = `-- name: GetPaymentTransactionForUpdate :one
+SELECT id, user_id, related_entity_type, related_entity_id, amount, currency, direction, stripe_payment_intent_id, stripe_refund_id, status, failure_reason, created_at FROM transactions
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetPaymentTransactionForUpdate(ctx context.Context, id int32) (Transaction, error) {
+	row := q.db.QueryRow(ctx, getPaymentTransactionForUpdate, id)
+	var i Transaction
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.RelatedEntityType,
+		&i.RelatedEntityID,
+		&i.Amount,
+		&i.Currency,
+		&i.Direction,
+		&i.StripePaymentIntentID,
+		&i.StripeRefundID,
+		&i.Status,
+		&i.FailureReason,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getPendingRefund = `-- name: GetPendingRefund :one
+SELECT id, user_id, related_entity_type, related_entity_id, amount, currency, direction, stripe_payment_intent_id, stripe_refund_id, status, failure_reason, created_at FROM transactions
+WHERE related_entity_type = 'refund' AND related_entity_id = $1 AND status = 'pending'
+ORDER BY id
+LIMIT 1
+`
+
+// Refund recorded but not confirmed by the provider yet, e.g. interrupted: resumed with its idempotency key.
+func (q *Queries) GetPendingRefund(ctx context.Context, relatedEntityID pgtype.Int4) (Transaction, error) {
+	row := q.db.QueryRow(ctx, getPendingRefund, relatedEntityID)
+	var i Transaction
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.RelatedEntityType,
+		&i.RelatedEntityID,
+		&i.Amount,
+		&i.Currency,
+		&i.Direction,
+		&i.StripePaymentIntentID,
+		&i.StripeRefundID,
+		&i.Status,
+		&i.FailureReason,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getProperty = `-- name: GetProperty :one
+SELECT id, owner_id, name, address, rental_type, details, rent_amount, rent_charges_amount, deposit_amount, is_furnished, seasonal_price_per_night, vacancy_credits, is_active, created_at FROM properties
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetProperty(ctx context.Context, id int32) (Property, error) {
+	row := q.db.QueryRow(ctx, getProperty, id)
+	var i Property
+	err := row.Scan(
+		&i.ID,
+		&i.OwnerID,
+		&i.Name,
+		&i.Address,
+		&i.RentalType,
+		&i.Details,
+		&i.RentAmount,
+		&i.RentChargesAmount,
+		&i.DepositAmount,
+		&i.IsFurnished,
+		&i.SeasonalPricePerNight,
+		&i.VacancyCredits,
+		&i.IsActive,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getPropertyDiagnosticByType = `-- name: GetPropertyDiagnosticByType :one
+SELECT id, property_id, media_kind, diagnostic_type, original_filename, storage_key, thumbnail_key, content_type, size_bytes, position, is_cover, expires_at, reminder_sent_at, created_at FROM property_media
+WHERE property_id = $1 AND media_kind = 'diagnostic' AND diagnostic_type = $2
+LIMIT 1
+`
+
+type GetPropertyDiagnosticByTypeParams struct {
+	PropertyID     int32       `json:"property_id"`
+	DiagnosticType pgtype.Text `json:"diagnostic_type"`
+}
+
+func (q *Queries) GetPropertyDiagnosticByType(ctx context.Context, arg GetPropertyDiagnosticByTypeParams) (PropertyMedium, error) {
+	row := q.db.QueryRow(ctx, getPropertyDiagnosticByType, arg.PropertyID, arg.DiagnosticType)
+	var i PropertyMedium
+	err := row.Scan(
+		&i.ID,
+		&i.PropertyID,
+		&i.MediaKind,
+		&i.DiagnosticType,
+		&i.OriginalFilename,
+		&i.StorageKey,
+		&i.ThumbnailKey,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.Position,
+		&i.IsCover,
+		&i.ExpiresAt,
+		&i.ReminderSentAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getPropertyForUpdate = `-- name: GetPropertyForUpdate :one
+SELECT id, owner_id, name, address, rental_type, details, rent_amount, rent_charges_amount, deposit_amount, is_furnished, seasonal_price_per_night, vacancy_credits, is_active, created_at FROM properties
+WHERE id = $1 FOR UPDATE
+`
+
+func (q *Queries) GetPropertyForUpdate(ctx context.Context, id int32) (Property, error) {
+	row := q.db.QueryRow(ctx, getPropertyForUpdate, id)
+	var i Property
+	err := row.Scan(
+		&i.ID,
+		&i.OwnerID,
+		&i.Name,
+		&i.Address,
+		&i.RentalType,
+		&i.Details,
+		&i.RentAmount,
+		&i.RentChargesAmount,
+		&i.DepositAmount,
+		&i.IsFurnished,
+		&i.SeasonalPricePerNight,
+		&i.VacancyCredits,
+		&i.IsActive,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getPropertyMedia = `-- name: GetPropertyMedia :one
+SELECT id, property_id, media_kind, diagnostic_type, original_filename, storage_key, thumbnail_key, content_type, size_bytes, position, is_cover, expires_at, reminder_sent_at, created_at FROM property_media
+WHERE id = $1 AND property_id = $2 LIMIT 1
+`
+
+type GetPropertyMediaParams struct {
+	ID         int32 `json:"id"`
+	PropertyID int32 `json:"property_id"`
+}
+
+func (q *Queries) GetPropertyMedia(ctx context.Context, arg GetPropertyMediaParams) (PropertyMedium, error) {
+	row := q.db.QueryRow(ctx, getPropertyMedia, arg.ID, arg.PropertyID)
+	var i PropertyMedium
+	err := row.Scan(
+		&i.ID,
+		&i.PropertyID,
+		&i.MediaKind,
+		&i.DiagnosticType,
+		&i.OriginalFilename,
+		&i.StorageKey,
+		&i.ThumbnailKey,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.Position,
+		&i.IsCover,
+		&i.ExpiresAt,
+		&i.ReminderSentAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getRefundedCents = `-- name: GetRefundedCents :one
+SELECT COALESCE(SUM(amount) * 100, 0)::int FROM transactions
+WHERE related_entity_type = 'refund' AND related_entity_id = $1 AND status = 'success'
+`
+
+func (q *Queries) GetRefundedCents(ctx context.Context, relatedEntityID pgtype.Int4) (int32, error) {
+	row := q.db.QueryRow(ctx, getRefundedCents, relatedEntityID)
+	var column_1 int32
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const getSolvencyCheckByBankConnection = `-- name: GetSolvencyCheckByBankConnection :one
+SELECT id, initiator_owner_id, candidate_id, token, property_id, status, credit_source, score_result, analysis_json, report_url, documents_json, missing_documents, bank_provider, bank_consent_id, bank_connection_id, employment_type, guarantee_type, policy_results, combined_score, expires_at, reminders_sent, selection, selection_at, documents_purged_at, anonymized_at, created_at, dossier_share_id, credit_transaction_id FROM solvency_checks
+WHERE bank_provider = $1 AND bank_connection_id = $2
+LIMIT 1
+`
+
+type GetSolvencyCheckByBankConnectionParams struct {
+	BankProvider     pgtype.Text `json:"bank_provider"`
+	BankConnectionID pgtype.Text `json:"bank_connection_id"`
+}
+
+func (q *Queries) GetSolvencyCheckByBankConnection(ctx context.Context, arg GetSolvencyCheckByBankConnectionParams) (SolvencyCheck, error) {
+	row := q.db.QueryRow(ctx, getSolvencyCheckByBankConnection, arg.BankProvider, arg.BankConnectionID)
+	var i SolvencyCheck
+	err := row.Scan(
+		&i.ID,
+		&i.InitiatorOwnerID,
+		&i.CandidateID,
+		&i.Token,
+		&i.PropertyID,
+		&i.Status,
+		&i.CreditSource,
+		&i.ScoreResult,
+		&i.AnalysisJson,
+		&i.ReportUrl,
+		&i.DocumentsJson,
+		&i.MissingDocuments,
+		&i.BankProvider,
+		&i.BankConsentID,
+		&i.BankConnectionID,
+		&i.EmploymentType,
+		&i.GuaranteeType,
+		&i.PolicyResults,
+		&i.CombinedScore,
+		&i.ExpiresAt,
+		&i.RemindersSent,
+		&i.Selection,
+		&i.SelectionAt,
+		&i.DocumentsPurgedAt,
+		&i.AnonymizedAt,
+		&i.CreatedAt,
+		&i.DossierShareID,
+		&i.CreditTransactionID,
+	)
+	return i, err
+}
+
+const getSolvencyCheckByID = `-- name: GetSolvencyCheckByID :one
+SELECT id, initiator_owner_id, candidate_id, token, property_id, status, credit_source, score_result, analysis_json, report_url, documents_json, missing_documents, bank_provider, bank_consent_id, bank_connection_id, employment_type, guarantee_type, policy_results, combined_score, expires_at, reminders_sent, selection, selection_at, documents_purged_at, anonymized_at, created_at, dossier_share_id, credit_transaction_id FROM solvency_checks
+WHERE id = $1
+`
+
+func (q *Queries) GetSolvencyCheckByID(ctx context.Context, id int32) (SolvencyCheck, error) {
+	row := q.db.QueryRow(ctx, getSolvencyCheckByID, id)
+	var i SolvencyCheck
+	err := row.Scan(
+		&i.ID,
+		&i.InitiatorOwnerID,
+		&i.CandidateID,
+		&i.Token,
+		&i.PropertyID,
+		&i.Status,
+		&i.CreditSource,
+		&i.ScoreResult,
+		&i.AnalysisJson,
+		&i.ReportUrl,
+		&i.DocumentsJson,
+		&i.MissingDocuments,
+		&i.BankProvider,
+		&i.BankConsentID,
+		&i.BankConnectionID,
+		&i.EmploymentType,
+		&i.GuaranteeType,
+		&i.PolicyResults,
+		&i.CombinedScore,
+		&i.ExpiresAt,
+		&i.RemindersSent,
+		&i.Selection,
+		&i.SelectionAt,
+		&i.DocumentsPurgedAt,
+		&i.AnonymizedAt,
+		&i.CreatedAt,
+		&i.DossierShareID,
+		&i.CreditTransactionID,
+	)
+	return i, err
+}
+
+const getSolvencyCheckByToken = `-- name: GetSolvencyCheckByToken :one
+SELECT 
+    sc.id, sc.initiator_owner_id, sc.candidate_id, sc.token, sc.property_id, sc.status, sc.created_at, sc.expires_at,
+    sc.documents_json, sc.missing_documents, sc.employment_type, sc.guarantee_type,
+    u.email as candidate_email, u.first_name as candidate_first_name, u.last_name as candidate_last_name,
+    p.address as property_address, p.rent_amount as property_rent_amount, p.name as property_name
+FROM solvency_checks sc
+JOIN users u ON sc.candidate_id = u.id
+JOIN properties p ON sc.property_id = p.id
+WHERE sc.token = $1
+ LIMIT 1
+`
+
+type GetSolvencyCheckByTokenRow struct {
+	ID                 int32              `json:"id"`
+	InitiatorOwnerID   pgtype.Int4        `json:"initiator_owner_id"`
+	CandidateID        pgtype.Int4        `json:"candidate_id"`
+	Token              pgtype.Text        `json:"token"`
+	PropertyID         pgtype.Int4        `json:"property_id"`
+	Status             NullSolvencyStatus `json:"status"`
+	CreatedAt          pgtype.Timestamp   `json:"created_at"`
+	ExpiresAt          pgtype.Timestamp   `json:"expires_at"`
+	DocumentsJson      []byte             `json:"documents_json"`
+	MissingDocuments   []byte             `json:"missing_documents"`
+	EmploymentType     pgtype.Text        `json:"employment_type"`
+	GuaranteeType      pgtype.Text        `json:"guarantee_type"`
+	CandidateEmail     string             `json:"candidate_email"`
+	CandidateFirstName pgtype.Text        `json:"candidate_first_name"`
+	CandidateLastName  pgtype.Text        `json:"candidate_last_name"`
+	PropertyAddress    string             `json:"property_address"`
+	PropertyRentAmount pgtype.Numeric     `json:"property_rent_amount"`
+	PropertyName       pgtype.Text        `json:"property_name"`
+}
+
+func (q *Queries) GetSolvencyCheckByToken(ctx context.Context, token pgtype.Text) (GetSolvencyCheckByTokenRow, error) {
+	row := q.db.QueryRow(ctx, getSolvencyCheckByToken, token)
+	var i GetSolvencyCheckByTokenRow
+	err := row.Scan(
+		&i.ID,
+		&i.InitiatorOwnerID,
+		&i.CandidateID,
+		&i.Token,
+		&i.PropertyID,
+		&i.Status,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.DocumentsJson,
+		&i.MissingDocuments,
+		&i.EmploymentType,
+		&i.GuaranteeType,
+		&i.CandidateEmail,
+		&i.CandidateFirstName,
+		&i.CandidateLastName,
+		&i.PropertyAddress,
+		&i.PropertyRentAmount,
+		&i.PropertyName,
+	)
+	return i, err
+}
+
+const getSolvencyCheckByTokenForUpdate = `-- name: GetSolvencyCheckByTokenForUpdate :one
+SELECT id, initiator_owner_id, candidate_id, token, property_id, status, credit_source, score_result, analysis_json, report_url, documents_json, missing_documents, bank_provider, bank_consent_id, bank_connection_id, employment_type, guarantee_type, policy_results, combined_score, expires_at, reminders_sent, selection, selection_at, documents_purged_at, anonymized_at, created_at, dossier_share_id, credit_transaction_id FROM solvency_checks
+WHERE token = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetSolvencyCheckByTokenForUpdate(ctx context.Context, token pgtype.Text) (SolvencyCheck, error) {
+	row := q.db.QueryRow(ctx, getSolvencyCheckByTokenForUpdate, token)
+	var i SolvencyCheck
+	err := row.Scan(
+		&i.ID,
+		&i.InitiatorOwnerID,
+		&i.CandidateID,
+		&i.Token,
+		&i.PropertyID,
+		&i.Status,
+		&i.CreditSource,
+		&i.ScoreResult,
+		&i.AnalysisJson,
+		&i.ReportUrl,
+		&i.DocumentsJson,
+		&i.MissingDocuments,
+		&i.BankProvider,
+		&i.BankConsentID,
+		&i.BankConnectionID,
+		&i.EmploymentType,
+		&i.GuaranteeType,
+		&i.PolicyResults,
+		&i.CombinedScore,
+		&i.ExpiresAt,
+		&i.RemindersSent,
+		&i.Selection,
+		&i.SelectionAt,
+		&i.DocumentsPurgedAt,
+		&i.AnonymizedAt,
+		&i.CreatedAt,
+		&i.DossierShareID,
+		&i.CreditTransactionID,
+	)
+	return i, err
+}
+
+const getSolvencyCheckForUpdate = `-- name: GetSolvencyCheckForUpdate :one
+SELECT id, initiator_owner_id, candidate_id, token, property_id, status, credit_source, score_result, analysis_json, report_url, documents_json, missing_documents, bank_provider, bank_consent_id, bank_connection_id, employment_type, guarantee_type, policy_results, combined_score, expires_at, reminders_sent, selection, selection_at, documents_purged_at, anonymized_at, created_at, dossier_share_id, credit_transaction_id FROM solvency_checks
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetSolvencyCheckForUpdate(ctx context.Context, id int32) (SolvencyCheck, error) {
+	row := q.db.QueryRow(ctx, getSolvencyCheckForUpdate, id)
+	var i SolvencyCheck
+	err := row.Scan(
+		&i.ID,
+		&i.InitiatorOwnerID,
+		&i.CandidateID,
+		&i.Token,
+		&i.PropertyID,
+		&i.Status,
+		&i.CreditSource,
+		&i.ScoreResult,
+		&i.AnalysisJson,
+		&i.ReportUrl,
+		&i.DocumentsJson,
+		&i.MissingDocuments,
+		&i.BankProvider,
+		&i.BankConsentID,
+		&i.BankConnectionID,
+		&i.EmploymentType,
+		&i.GuaranteeType,
+		&i.PolicyResults,
+		&i.CombinedScore,
+		&i.ExpiresAt,
+		&i.RemindersSent,
+		&i.Selection,
+		&i.SelectionAt,
+		&i.DocumentsPurgedAt,
+		&i.AnonymizedAt,
+		&i.CreatedAt,
+		&i.DossierShareID,
+		&i.CreditTransactionID,
+	)
+	return i, err
+}
+
+const getSolvencyGuarantor = `-- name: GetSolvencyGuarantor :one
+SELECT id, check_id, email, first_name, last_name, phone_number, token, status, documents_json, bank_provider, bank_consent_id, bank_connection_id, monthly_income, analysis_json, lease_id, mention_text, mention_signed_at, created_at FROM solvency_guarantors
+WHERE id = $1
+`
+
+func (q *Queries) GetSolvencyGuarantor(ctx context.Context, id int32) (SolvencyGuarantor, error) {
+	row := q.db.QueryRow(ctx, getSolvencyGuarantor, id)
+	var i SolvencyGuarantor
+	err := row.Scan(
+		&i.ID,
+		&i.CheckID,
+		&i.Email,
+		&i.FirstName,
+		&i.LastName,
+		&i.PhoneNumber,
+		&i.Token,
+		&i.Status,
+		&i.DocumentsJson,
+		&i.BankProvider,
+		&i.BankConsentID,
+		&i.BankConnectionID,
+		&i.MonthlyIncome,
+		&i.AnalysisJson,
+		&i.LeaseID,
+		&i.MentionText,
+		&i.MentionSignedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getSubscriptionForUpdate = `-- name: GetSubscriptionForUpdate :one
+SELECT id, user_id, plan_type, frequency, status, start_date, end_date, max_properties_limit, current_period_start, current_period_end, next_credit_grant, granted_credits, created_at, catalog_item_id, cancel_at_period_end, scheduled_catalog_item_id, scheduled_archived_property_ids, ended_at FROM subscriptions
+WHERE id = $1 FOR UPDATE
+`
+
+func (q *Queries) GetSubscriptionForUpdate(ctx context.Context, id int32) (Subscription, error) {
+	row := q.db.QueryRow(ctx, getSubscriptionForUpdate, id)
+	var i Subscription
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.PlanType,
+		&i.Frequency,
+		&i.Status,
+		&i.StartDate,
+		&i.EndDate,
+		&i.MaxPropertiesLimit,
+		&i.CurrentPeriodStart,
+		&i.CurrentPeriodEnd,
+		&i.NextCreditGrant,
+		&i.GrantedCredits,
+		&i.CreatedAt,
+		&i.CatalogItemID,
+		&i.CancelAtPeriodEnd,
+		&i.ScheduledCatalogItemID,
+		&i.ScheduledArchivedPropertyIds,
+		&i.EndedAt,
+	)
+	return i, err
+}
+
+const getSubscriptionItem = `-- name: GetSubscriptionItem :one
+SELECT id, subscription_id, kind, quantity, unit_price_cents, scheduled_quantity, created_at, updated_at FROM subscription_items
+WHERE subscription_id = $1 AND kind = $2
+`
+
+type GetSubscriptionItemParams struct {
+	SubscriptionID int32  `json:"subscription_id"`
+	Kind           string `json:"kind"`
+}
+
+func (q *Queries) GetSubscriptionItem(ctx context.Context, arg GetSubscriptionItemParams) (SubscriptionItem, error) {
+	row := q.db.QueryRow(ctx, getSubscriptionItem, arg.SubscriptionID, arg.Kind)
+	var i SubscriptionItem
+	err := row.Scan(
+		&i.ID,
+		&i.SubscriptionID,
+		&i.Kind,
+		&i.Quantity,
+		&i.UnitPriceCents,
+		&i.ScheduledQuantity,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getTenantDossier = `-- name: GetTenantDossier :one
+SELECT id, user_id, source_check_id, documents_json, analysis_json, employment_type, guarantee_type, verified_at, expires_at, created_at, updated_at FROM tenant_dossiers
+WHERE id = $1
+`
+
+func (q *Queries) GetTenantDossier(ctx context.Context, id int32) (TenantDossier, error) {
+	row := q.db.QueryRow(ctx, getTenantDossier, id)
+	var i TenantDossier
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.SourceCheckID,
+		&i.DocumentsJson,
+		&i.AnalysisJson,
+		&i.EmploymentType,
+		&i.GuaranteeType,
+		&i.VerifiedAt,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getTenantDossierByUser = `-- name: GetTenantDossierByUser :one
+SELECT id, user_id, source_check_id, documents_json, analysis_json, employment_type, guarantee_type, verified_at, expires_at, created_at, updated_at FROM tenant_dossiers
+WHERE user_id = $1
+`
+
+func (q *Queries) GetTenantDossierByUser(ctx context.Context, userID int32) (TenantDossier, error) {
+	row := q.db.QueryRow(ctx, getTenantDossierByUser, userID)
+	var i TenantDossier
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.SourceCheckID,
+		&i.DocumentsJson,
+		&i.AnalysisJson,
+		&i.EmploymentType,
+		&i.GuaranteeType,
+		&i.VerifiedAt,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getUserByEmail = `-- name: GetUserByEmail :one
+SELECT id, email, password_hash, first_name, last_name, phone_number, is_verified, stripe_customer_id, sepa_mandate_id, is_provisional, last_context_used, role, deleted_at, created_at FROM users
+WHERE email = $1 LIMIT 1
+`
+
+func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	row := q.db.QueryRow(ctx, getUserByEmail, email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.PasswordHash,
+		&i.FirstName,
+		&i.LastName,
+		&i.PhoneNumber,
+		&i.IsVerified,
+		&i.StripeCustomerID,
+		&i.SepaMandateID,
+		&i.IsProvisional,
+		&i.LastContextUsed,
+		&i.Role,
+		&i.DeletedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getUserById = `-- name: GetUserById :one
+SELECT id, email, password_hash, first_name, last_name, phone_number, is_verified, stripe_customer_id, sepa_mandate_id, is_provisional, last_context_used, role, deleted_at, created_at FROM users
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetUserById(ctx context.Context, id int32) (User, error) {
+	row := q.db.QueryRow(ctx, getUserById, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.PasswordHash,
+		&i.FirstName,
+		&i.LastName,
+		&i.PhoneNumber,
+		&i.IsVerified,
+		&i.StripeCustomerID,
+		&i.SepaMandateID,
+		&i.IsProvisional,
+		&i.LastContextUsed,
+		&i.Role,
+		&i.DeletedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getUserCreditBalance = `-- name: GetUserCreditBalance :one
+SELECT balance FROM credit_accounts
+WHERE kind = 'global' AND user_id = $1::int
+`
+
+func (q *Queries) GetUserCreditBalance(ctx context.Context, userID int32) (int32, error) {
+	row := q.db.QueryRow(ctx, getUserCreditBalance, userID)
+	var balance int32
+	err := row.Scan(&balance)
+	return balance, err
+}
+
+const getUserCreditBalanceForUpdate = `-- name: GetUserCreditBalanceForUpdate :one
+SELECT balance FROM credit_accounts
+WHERE kind = 'global' AND user_id = $1::int FOR UPDATE
+`
+
+func (q *Queries) GetUserCreditBalanceForUpdate(ctx context.Context, userID int32) (int32, error) {
+	row := q.db.QueryRow(ctx, getUserCreditBalanceForUpdate, userID)
+	var balance int32
+	err := row.Scan(&balance)
+	return balance, err
+}
+
+const getUserForUpdate = `-- name: GetUserForUpdate :one
+SELECT id, email, password_hash, first_name, last_name, phone_number, is_verified, stripe_customer_id, sepa_mandate_id, is_provisional, last_context_used, role, deleted_at, created_at FROM users
+WHERE id = $1 FOR UPDATE
+`
+
+func (q *Queries) GetUserForUpdate(ctx context.Context, id int32) (User, error) {
+	row := q.db.QueryRow(ctx, getUserForUpdate, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.PasswordHash,
+		&i.FirstName,
+		&i.LastName,
+		&i.PhoneNumber,
+		&i.IsVerified,
+		&i.StripeCustomerID,
+		&i.SepaMandateID,
+		&i.IsProvisional,
+		&i.LastContextUsed,
+		&i.Role,
+		&i.DeletedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getUserSubscription = `-- name: GetUserSubscription :one
+SELECT id, user_id, plan_type, frequency, status, start_date, end_date, max_properties_limit, current_period_start, current_period_end, next_credit_grant, granted_credits, created_at, catalog_item_id, cancel_at_period_end, scheduled_catalog_item_id, scheduled_archived_property_ids, ended_at FROM subscriptions
+WHERE user_id = $1 AND status IN ('incomplete', 'active', 'past_due')
+`
+
+// The current subscription: at most one per user is incomplete, active or past due (idx_subscriptions_current).
+func (q *Queries) GetUserSubscription(ctx context.Context, userID pgtype.Int4) (Subscription, error) {
+	row := q.db.QueryRow(ctx, getUserSubscription, userID)
+	var i Subscription
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.PlanType,
+		&i.Frequency,
+		&i.Status,
+		&i.StartDate,
+		&i.EndDate,
+		&i.MaxPropertiesLimit,
+		&i.CurrentPeriodStart,
+		&i.CurrentPeriodEnd,
+		&i.NextCreditGrant,
+		&i.GrantedCredits,
+		&i.CreatedAt,
+		&i.CatalogItemID,
+		&i.CancelAtPeriodEnd,
+		&i.ScheduledCatalogItemID,
+		&i.ScheduledArchivedPropertyIds,
+		&i.EndedAt,
+	)
+	return i, err
+}
+
+const getUserSubscriptionForUpdate = `-- name: GetUserSubscriptionForUpdate :one
+SELECT id, user_id, plan_type, frequency, status, start_date, end_date, max_properties_limit, current_period_start, current_period_end, next_credit_grant, granted_credits, created_at, catalog_item_id, cancel_at_period_end, scheduled_catalog_item_id, scheduled_archived_property_ids, ended_at FROM subscriptions
+WHERE user_id = $1 AND status IN ('incomplete', 'active', 'past_due')
+FOR UPDATE
+`
+
+func (q *Queries) GetUserSubscriptionForUpdate(ctx context.Context, userID pgtype.Int4) (Subscription, error) {
+	row := q.db.QueryRow(ctx, getUserSubscriptionForUpdate, userID)
+	var i Subscription
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.PlanType,
+		&i.Frequency,
+		&i.Status,
+		&i.StartDate,
+		&i.EndDate,
+		&i.MaxPropertiesLimit,
+		&i.CurrentPeriodStart,
+		&i.CurrentPeriodEnd,
+		&i.NextCreditGrant,
+		&i.GrantedCredits,
+		&i.CreatedAt,
+		&i.CatalogItemID,
+		&i.CancelAtPeriodEnd,
+		&i.ScheduledCatalogItemID,
+		&i.ScheduledArchivedPropertyIds,
+		&i.EndedAt,
+	)
+	return i, err
+}
+
+const hasReceivedInitialBonus = `-- name: HasReceivedInitialBonus :one
+SELECT EXISTS(
+    SELECT 1 FROM credit_transactions
+    WHERE user_id = $1 AND transaction_type = 'initial_free'
+)
+`
+
+func (q *Queries) HasReceivedInitialBonus(ctx context.Context, userID pgtype.Int4) (bool, error) {
+	row := q.db.QueryRow(ctx, hasReceivedInitialBonus, userID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const isCatalogItemReferenced = `-- name: IsCatalogItemReferenced :one
+SELECT (
+    EXISTS (SELECT 1 FROM subscriptions s WHERE s.catalog_item_id = $1::int OR s.scheduled_catalog_item_id = $1::int)
+    OR EXISTS (SELECT 1 FROM subscription_events e WHERE e.from_catalog_item_id = $1::int OR e.to_catalog_item_id = $1::int)
+    OR EXISTS (SELECT 1 FROM invoices i WHERE i.catalog_item_id = $1::int)
+    OR EXISTS (SELECT 1 FROM transactions t WHERE t.related_entity_type = 'pack_purchase' AND t.related_entity_id = $1::int)
+)::boolean AS referenced
+`
+
+// A version already sold: subscriptions (current or scheduled terms), their history, invoices or pack payments refer to it.
+func (q *Queries) IsCatalogItemReferenced(ctx context.Context, id int32) (bool, error) {
+	row := q.db.QueryRow(ctx, isCatalogItemReferenced, id)
+	var referenced bool
+	err := row.Scan(&referenced)
+	return referenced, err
+}
+
+const isUserActive = `-- name: IsUserActive :one
+SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL)
+`
+
+// An account deleted by its user (deleted_at) or gone no longer authenticates.
+func (q *Queries) IsUserActive(ctx context.Context, id int32) (bool, error) {
+	row := q.db.QueryRow(ctx, isUserActive, id)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const isUserAdmin = `-- name: IsUserAdmin :one
+SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL AND role = 'admin')
+`
+
+// The role is read at each request: a demoted administrator loses access without waiting for the token to expire.
+func (q *Queries) IsUserAdmin(ctx context.Context, id int32) (bool, error) {
+	row := q.db.QueryRow(ctx, isUserAdmin, id)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const issueInvoice = `-- name: IssueInvoice :one
+UPDATE invoices
+SET number = $2, issued_at = NOW(), amount_excl_vat_cents = $3, vat_rate_bps = $4, vat_cents = $5,
+    buyer_name = $6, buyer_email = $7
+WHERE id = $1 AND number IS NULL
+RETURNING id, user_id, subscription_id, description, amount_cents, period_start, period_end, status, attempts, last_attempt_at, paid_at, created_at, kind, catalog_item_id, credited_invoice_id, number, issued_at, amount_excl_vat_cents, vat_rate_bps, vat_cents, buyer_name, buyer_email, document_id, slot_quantity, credit_property_id
+`
+
+type IssueInvoiceParams struct {
+	ID                 int32       `json:"id"`
+	Number             pgtype.Text `json:"number"`
+	AmountExclVatCents pgtype.Int4 `json:"amount_excl_vat_cents"`
+	VatRateBps         pgtype.Int4 `json:"vat_rate_bps"`
+	VatCents           pgtype.Int4 `json:"vat_cents"`
+	BuyerName          pgtype.Text `json:"buyer_name"`
+	BuyerEmail         pgtype.Text `json:"buyer_email"`
+}
+
+func (q *Queries) IssueInvoice(ctx context.Context, arg IssueInvoiceParams) (Invoice, error) {
+	row := q.db.QueryRow(ctx, issueInvoice,
+		arg.ID,
+		arg.Number,
+		arg.AmountExclVatCents,
+		arg.VatRateBps,
+		arg.VatCents,
+		arg.BuyerName,
+		arg.BuyerEmail,
+	)
+	var i Invoice
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.SubscriptionID,
+		&i.Description,
+		&i.AmountCents,
+		&i.PeriodStart,
+		&i.PeriodEnd,
+		&i.Status,
+		&i.Attempts,
+		&i.LastAttemptAt,
+		&i.PaidAt,
+		&i.CreatedAt,
+		&i.Kind,
+		&i.CatalogItemID,
+		&i.CreditedInvoiceID,
+		&i.Number,
+		&i.IssuedAt,
+		&i.AmountExclVatCents,
+		&i.VatRateBps,
+		&i.VatCents,
+		&i.BuyerName,
+		&i.BuyerEmail,
+		&i.DocumentID,
+		&i.SlotQuantity,
+		&i.CreditPropertyID,
+	)
+	return i, err
+}
+
+const listActiveCatalogItems = `-- name: ListActiveCatalogItems :many
+SELECT id, code, kind, name, plan_type, monthly_price_cents, yearly_price_cents, price_cents, max_properties, included_credits, slot_price_cents, valid_from, valid_until, created_at, updated_at FROM catalog_items
+WHERE valid_from <= NOW() AND (valid_until IS NULL OR valid_until > NOW())
+ORDER BY kind DESC, COALESCE(monthly_price_cents, price_cents), id
+`
+
+func (q *Queries) ListActiveCatalogItems(ctx context.Context) ([]CatalogItem, error) {
+	rows, err := q.db.Query(ctx, listActiveCatalogItems)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CatalogItem
+	for rows.Next() {
+		var i CatalogItem
+		if err := rows.Scan(
+			&i.ID,
+			&i.Code,
+			&i.Kind,
+			&i.Name,
+			&i.PlanType,
+			&i.MonthlyPriceCents,
+			&i.YearlyPriceCents,
+			&i.PriceCents,
+			&i.MaxProperties,
+			&i.IncludedCredits,
+			&i.SlotPriceCents,
+			&i.ValidFrom,
+			&i.ValidUntil,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listActivePropertiesByOwnerAndType = `-- name: ListActivePropertiesByOwnerAndType :many
+SELECT id, name, address FROM properties
+WHERE owner_id = $1 AND rental_type = $2 AND is_active = true
+ORDER BY created_at DESC, id DESC
+`
+
+type ListActivePropertiesByOwnerAndTypeParams struct {
+	OwnerID    pgtype.Int4  `json:"owner_id"`
+	RentalType PropertyType `json:"rental_type"`
+}
+
+type ListActivePropertiesByOwnerAndTypeRow struct {
+	ID      int32       `json:"id"`
+	Name    pgtype.Text `json:"name"`
+	Address string      `json:"address"`
+}
+
+// Latest first: the first ones are archived when a downgrade leaves too many properties.
+func (q *Queries) ListActivePropertiesByOwnerAndType(ctx context.Context, arg ListActivePropertiesByOwnerAndTypeParams) ([]ListActivePropertiesByOwnerAndTypeRow, error) {
+	rows, err := q.db.Query(ctx, listActivePropertiesByOwnerAndType, arg.OwnerID, arg.RentalType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListActivePropertiesByOwnerAndTypeRow
+	for rows.Next() {
+		var i ListActivePropertiesByOwnerAndTypeRow
+		if err := rows.Scan(&i.ID, &i.Name, &i.Address); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCatalogItems = `-- name: ListCatalogItems :many
+SELECT id, code, kind, name, plan_type, monthly_price_cents, yearly_price_cents, price_cents, max_properties, included_credits, slot_price_cents, valid_from, valid_until, created_at, updated_at FROM catalog_items
+ORDER BY kind DESC, code, valid_from
+`
+
+func (q *Queries) ListCatalogItems(ctx context.Context) ([]CatalogItem, error) {
+	rows, err := q.db.Query(ctx, listCatalogItems)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CatalogItem
+	for rows.Next() {
+		var i CatalogItem
+		if err := rows.Scan(
+			&i.ID,
+			&i.Code,
+			&i.Kind,
+			&i.Name,
+			&i.PlanType,
+			&i.MonthlyPriceCents,
+			&i.YearlyPriceCents,
+			&i.PriceCents,
+			&i.MaxProperties,
+			&i.IncludedCredits,
+			&i.SlotPriceCents,
+			&i.ValidFrom,
+			&i.ValidUntil,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCreditAccountsByUser = `-- name: ListCreditAccountsByUser :many
+SELECT a.id, a.kind, a.property_id, a.balance, p.name AS property_name, p.address AS property_address,
+    COALESCE(p.is_active, true)::boolean AS is_active
+FROM credit_accounts a
+LEFT JOIN properties p ON p.id = a.property_id
+WHERE a.user_id = $1
+ORDER BY a.kind = 'property', a.id
+`
+
+type ListCreditAccountsByUserRow struct {
+	ID              int32       `json:"id"`
+	Kind            string      `json:"kind"`
+	PropertyID      pgtype.Int4 `json:"property_id"`
+	Balance         int32       `json:"balance"`
+	PropertyName    pgtype.Text `json:"property_name"`
+	PropertyAddress pgtype.Text `json:"property_address"`
+	IsActive        bool        `json:"is_active"`
+}
+
+// Wallets of a user: the global one first, then those of their properties.
+func (q *Queries) ListCreditAccountsByUser(ctx context.Context, userID pgtype.Int4) ([]ListCreditAccountsByUserRow, error) {
+	rows, err := q.db.Query(ctx, listCreditAccountsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListCreditAccountsByUserRow
+	for rows.Next() {
+		var i ListCreditAccountsByUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Kind,
+			&i.PropertyID,
+			&i.Balance,
+			&i.PropertyName,
+			&i.PropertyAddress,
+			&i.IsActive,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCreditBalanceDrifts = `-- name: ListCreditBalanceDrifts :many
+SELECT a.id AS account_id, a.kind, a.user_id, a.property_id, a.balance, COALESCE(SUM(ct.amount), 0)::int AS ledger_balance
+FROM credit_accounts a
+LEFT JOIN credit_transactions ct ON ct.account_id = a.id
+WHERE a.kind <> 'system'
+GROUP BY a.id
+HAVING a.balance <> COALESCE(SUM(ct.amount), 0)
+`
+
+type ListCreditBalanceDriftsRow struct {
+	AccountID     int32       `json:"account_id"`
+	Kind          string      `json:"kind"`
+	UserID        pgtype.Int4 `json:"user_id"`
+	PropertyID    pgtype.Int4 `json:"property_id"`
+	Balance       int32       `json:"balance"`
+	LedgerBalance int32       `json:"ledger_balance"`
+}
+
+// Wallets whose balance differs from the sum of their ledger lines.
+func (q *Queries) ListCreditBalanceDrifts(ctx context.Context) ([]ListCreditBalanceDriftsRow, error) {
+	rows, err := q.db.Query(ctx, listCreditBalanceDrifts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListCreditBalanceDriftsRow
+	for rows.Next() {
+		var i ListCreditBalanceDriftsRow
+		if err := rows.Scan(
+			&i.AccountID,
+			&i.Kind,
+			&i.UserID,
+			&i.PropertyID,
+			&i.Balance,
+			&i.LedgerBalance,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCreditTransactionsByUser = `-- name: ListCreditTransactionsByUser :many
+SELECT id, user_id, amount, transaction_type, description, refund_of, created_at, property_id, balance_after, account_id, entry_id FROM credit_transactions
+WHERE user_id = $1
+ORDER BY created_at ASC, id ASC
+`
+
+func (q *Queries) ListCreditTransactionsByUser(ctx context.Context, userID pgtype.Int4) ([]CreditTransaction, error) {
+	rows, err := q.db.Query(ctx, listCreditTransactionsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CreditTransaction
+	for rows.Next() {
+		var i CreditTransaction
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Amount,
+			&i.TransactionType,
+			&i.Description,
+			&i.RefundOf,
+			&i.CreatedAt,
+			&i.PropertyID,
+			&i.BalanceAfter,
+			&i.AccountID,
+			&i.EntryID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCreditTransactionsPage = `-- name: ListCreditTransactionsPage :many
+SELECT ct.id, ct.user_id, ct.amount, ct.transaction_type, ct.description, ct.refund_of, ct.created_at, ct.property_id, ct.balance_after, ct.account_id, ct.entry_id, p.name AS property_name FROM credit_transactions ct
+LEFT JOIN properties p ON p.id = ct.property_id
+WHERE ct.user_id = $1
+AND ($4::text IS NULL OR ct.transaction_type = $4)
+ORDER BY ct.id DESC
+LIMIT $2 OFFSET $3
+`
+
+type ListCreditTransactionsPageParams struct {
+	UserID          pgtype.Int4 `json:"user_id"`
+	Limit           int32       `json:"limit"`
+	Offset          int32       `json:"offset"`
+	TransactionType pgtype.Text `json:"transaction_type"`
+}
+
+type ListCreditTransactionsPageRow struct {
+	ID              int32            `json:"id"`
+	UserID          pgtype.Int4      `json:"user_id"`
+	Amount          int32            `json:"amount"`
+	TransactionType string           `json:"transaction_type"`
+	Description     pgtype.Text      `json:"description"`
+	RefundOf        pgtype.Int4      `json:"refund_of"`
+	CreatedAt       pgtype.Timestamp `json:"created_at"`
+	PropertyID      pgtype.Int4      `json:"property_id"`
+	BalanceAfter    pgtype.Int4      `json:"balance_after"`
+	AccountID       pgtype.Int4      `json:"account_id"`
+	EntryID         pgtype.Int4      `json:"entry_id"`
+	PropertyName    pgtype.Text      `json:"property_name"`
+}
+
+func (q *Queries) ListCreditTransactionsPage(ctx context.Context, arg ListCreditTransactionsPageParams) ([]ListCreditTransactionsPageRow, error) {
+	rows, err := q.db.Query(ctx, listCreditTransactionsPage,
+		arg.UserID,
+		arg.Limit,
+		arg.Offset,
+		arg.TransactionType,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListCreditTransactionsPageRow
+	for rows.Next() {
+		var i ListCreditTransactionsPageRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Amount,
+			&i.TransactionType,
+			&i.Description,
+			&i.RefundOf,
+			&i.CreatedAt,
+			&i.PropertyID,
+			&i.BalanceAfter,
+			&i.AccountID,
+			&i.EntryID,
+			&i.PropertyName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDocumentsByEntity = `-- name: ListDocumentsByEntity :many
+SELECT id, document_type, entity_id, version, storage_key, content_type, filename, created_at FROM documents
+WHERE document_type = $1 AND entity_id = $2
+ORDER BY version DESC
+`
+
+type ListDocumentsByEntityParams struct {
+	DocumentType string `json:"document_type"`
+	EntityID     int32  `json:"entity_id"`
+}
+
+func (q *Queries) ListDocumentsByEntity(ctx context.Context, arg ListDocumentsByEntityParams) ([]Document, error) {
+	rows, err := q.db.Query(ctx, listDocumentsByEntity, arg.DocumentType, arg.EntityID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Document
+	for rows.Next() {
+		var i Document
+		if err := rows.Scan(
+			&i.ID,
+			&i.DocumentType,
+			&i.EntityID,
+			&i.Version,
+			&i.StorageKey,
+			&i.ContentType,
+			&i.Filename,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDossierShares = `-- name: ListDossierShares :many
+SELECT id, dossier_id, token, label, revoked_at, created_at FROM dossier_shares
+WHERE dossier_id = $1
+ORDER BY created_at DESC
+`
+
+func (q *Queries) ListDossierShares(ctx context.Context, dossierID int32) ([]DossierShare, error) {
+	rows, err := q.db.Query(ctx, listDossierShares, dossierID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DossierShare
+	for rows.Next() {
+		var i DossierShare
+		if err := rows.Scan(
+			&i.ID,
+			&i.DossierID,
+			&i.Token,
+			&i.Label,
+			&i.RevokedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listExpiredSolvencyChecks = `-- name: ListExpiredSolvencyChecks :many
+SELECT id FROM solvency_checks
+WHERE status IN ('pending', 'insufficient_docs')
+AND expires_at <= NOW()
+ORDER BY expires_at
+`
+
+func (q *Queries) ListExpiredSolvencyChecks(ctx context.Context) ([]int32, error) {
+	rows, err := q.db.Query(ctx, listExpiredSolvencyChecks)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listExpiringDiagnostics = `-- name: ListExpiringDiagnostics :many
+SELECT pm.id, pm.property_id, pm.media_kind, pm.diagnostic_type, pm.original_filename, pm.storage_key, pm.thumbnail_key, pm.content_type, pm.size_bytes, pm.position, pm.is_cover, pm.expires_at, pm.reminder_sent_at, pm.created_at, p.address as property_address, u.email as owner_email
+FROM property_media pm
+JOIN properties p ON pm.property_id = p.id
+JOIN users u ON p.owner_id = u.id
+WHERE pm.media_kind = 'diagnostic'
+  AND pm.expires_at IS NOT NULL
+  AND pm.expires_at <= $1
+  AND pm.reminder_sent_at IS NULL
+  AND p.is_active = TRUE
+ORDER BY pm.expires_at ASC
+`
+
+type ListExpiringDiagnosticsRow struct {
+	ID               int32            `json:"id"`
+	PropertyID       int32            `json:"property_id"`
+	MediaKind        string           `json:"media_kind"`
+	DiagnosticType   pgtype.Text      `json:"diagnostic_type"`
+	OriginalFilename string           `json:"original_filename"`
+	StorageKey       string           `json:"storage_key"`
+	ThumbnailKey     pgtype.Text      `json:"thumbnail_key"`
+	ContentType      string           `json:"content_type"`
+	SizeBytes        int64            `json:"size_bytes"`
+	Position         int32            `json:"position"`
+	IsCover          bool             `json:"is_cover"`
+	ExpiresAt        pgtype.Date      `json:"expires_at"`
+	ReminderSentAt   pgtype.Timestamp `json:"reminder_sent_at"`
+	CreatedAt        pgtype.Timestamp `json:"created_at"`
+	PropertyAddress  string           `json:"property_address"`
+	OwnerEmail       string           `json:"owner_email"`
+}
+
+func (q *Queries) ListExpiringDiagnostics(ctx context.Context, expiresAt pgtype.Date) ([]ListExpiringDiagnosticsRow, error) {
+	rows, err := q.db.Query(ctx, listExpiringDiagnostics, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListExpiringDiagnosticsRow
+	for rows.Next() {
+		var i ListExpiringDiagnosticsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.PropertyID,
+			&i.MediaKind,
+			&i.DiagnosticType,
+			&i.OriginalFilename,
+			&i.StorageKey,
+			&i.ThumbnailKey,
+			&i.ContentType,
+			&i.SizeBytes,
+			&i.Position,
+			&i.IsCover,
+			&i.ExpiresAt,
+			&i.ReminderSentAt,
+			&i.CreatedAt,
+			&i.PropertyAddress,
+			&i.OwnerEmail,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listGuarantorsByCheck = `-- name: ListGuarantorsByCheck :many
+SELECT id, check_id, email, first_name, last_name, phone_number, token, status, documents_json, bank_provider, bank_consent_id, bank_connection_id, monthly_income, analysis_json, lease_id, mention_text, mention_signed_at, created_at FROM solvency_guarantors
+WHERE check_id = $1
+ORDER BY id
+`
+
+func (q *Queries) ListGuarantorsByCheck(ctx context.Context, checkID int32) ([]SolvencyGuarantor, error) {
+	rows, err := q.db.Query(ctx, listGuarantorsByCheck, checkID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SolvencyGuarantor
+	for rows.Next() {
+		var i SolvencyGuarantor
+		if err := rows.Scan(
+			&i.ID,
+			&i.CheckID,
+			&i.Email,
+			&i.FirstName,
+			&i.LastName,
+			&i.PhoneNumber,
+			&i.Token,
+			&i.Status,
+			&i.DocumentsJson,
+			&i.BankProvider,
+			&i.BankConsentID,
+			&i.BankConnectionID,
+			&i.MonthlyIncome,
+			&i.AnalysisJson,
+			&i.LeaseID,
+			&i.MentionText,
+			&i.MentionSignedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listGuarantorsByLease = `-- name: ListGuarantorsByLease :many
+SELECT id, check_id, email, first_name, last_name, phone_number, token, status, documents_json, bank_provider, bank_consent_id, bank_connection_id, monthly_income, analysis_json, lease_id, mention_text, mention_signed_at, created_at FROM solvency_guarantors
+WHERE lease_id = $1
+ORDER BY id
+`
+
+func (q *Queries) ListGuarantorsByLease(ctx context.Context, leaseID pgtype.Int4) ([]SolvencyGuarantor, error) {
+	rows, err := q.db.Query(ctx, listGuarantorsByLease, leaseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SolvencyGuarantor
+	for rows.Next() {
+		var i SolvencyGuarantor
+		if err := rows.Scan(
+			&i.ID,
+			&i.CheckID,
+			&i.Email,
+			&i.FirstName,
+			&i.LastName,
+			&i.PhoneNumber,
+			&i.Token,
+			&i.Status,
+			&i.DocumentsJson,
+			&i.BankProvider,
+			&i.BankConsentID,
+			&i.BankConnectionID,
+			&i.MonthlyIncome,
+			&i.AnalysisJson,
+			&i.LeaseID,
+			&i.MentionText,
+			&i.MentionSignedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listInvitationsByEmail = `-- name: ListInvitationsByEmail :many
+SELECT id, property_id, lease_id, party_id, owner_id, tenant_email, token, status, expires_at, created_at FROM lease_invitations
+WHERE tenant_email = $1
+ORDER BY created_at DESC
+`
+
+func (q *Queries) ListInvitationsByEmail(ctx context.Context, tenantEmail string) ([]LeaseInvitation, error) {
+	rows, err := q.db.Query(ctx, listInvitationsByEmail, tenantEmail)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []LeaseInvitation
+	for rows.Next() {
+		var i LeaseInvitation
+		if err := rows.Scan(
+			&i.ID,
+			&i.PropertyID,
+			&i.LeaseID,
+			&i.PartyID,
+			&i.OwnerID,
+			&i.TenantEmail,
+			&i.Token,
+			&i.Status,
+			&i.ExpiresAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listInvitationsByOwner = `-- name: ListInvitationsByOwner :many
+SELECT id, property_id, lease_id, party_id, owner_id, tenant_email, token, status, expires_at, created_at FROM lease_invitations
+WHERE owner_id = $1
+ORDER BY created_at DESC
+`
+
+func (q *Queries) ListInvitationsByOwner(ctx context.Context, ownerID int32) ([]LeaseInvitation, error) {
+	rows, err := q.db.Query(ctx, listInvitationsByOwner, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []LeaseInvitation
+	for rows.Next() {
+		var i LeaseInvitation
+		if err := rows.Scan(
+			&i.ID,
+			&i.PropertyID,
+			&i.LeaseID,
+			&i.PartyID,
+			&i.OwnerID,
+			&i.TenantEmail,
+			&i.Token,
+			&i.Status,
+			&i.ExpiresAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listInvoicesByUser = `-- name: ListInvoicesByUser :many
+SELECT id, user_id, subscription_id, description, amount_cents, period_start, period_end, status, attempts, last_attempt_at, paid_at, created_at, kind, catalog_item_id, credited_invoice_id, number, issued_at, amount_excl_vat_cents, vat_rate_bps, vat_cents, buyer_name, buyer_email, document_id, slot_quantity, credit_property_id FROM invoices
+WHERE user_id = $1
+ORDER BY created_at ASC, id ASC
+`
+
+func (q *Queries) ListInvoicesByUser(ctx context.Context, userID int32) ([]Invoice, error) {
+	rows, err := q.db.Query(ctx, listInvoicesByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Invoice
+	for rows.Next() {
+		var i Invoice
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.SubscriptionID,
+			&i.Description,
+			&i.AmountCents,
+			&i.PeriodStart,
+			&i.PeriodEnd,
+			&i.Status,
+			&i.Attempts,
+			&i.LastAttemptAt,
+			&i.PaidAt,
+			&i.CreatedAt,
+			&i.Kind,
+			&i.CatalogItemID,
+			&i.CreditedInvoiceID,
+			&i.Number,
+			&i.IssuedAt,
+			&i.AmountExclVatCents,
+			&i.VatRateBps,
+			&i.VatCents,
+			&i.BuyerName,
+			&i.BuyerEmail,
+			&i.DocumentID,
+			&i.SlotQuantity,
+			&i.CreditPropertyID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listInvoicesToArchive = `-- name: ListInvoicesToArchive :many
+SELECT id, user_id, subscription_id, description, amount_cents, period_start, period_end, status, attempts, last_attempt_at, paid_at, created_at, kind, catalog_item_id, credited_invoice_id, number, issued_at, amount_excl_vat_cents, vat_rate_bps, vat_cents, buyer_name, buyer_email, document_id, slot_quantity, credit_property_id FROM invoices
+WHERE number IS NOT NULL AND document_id IS NULL
+ORDER BY issued_at ASC, id ASC
+LIMIT $1
+`
+
+// Issued invoices and credit notes whose PDF is not archived yet.
+func (q *Queries) ListInvoicesToArchive(ctx context.Context, limit int32) ([]Invoice, error) {
+	rows, err := q.db.Query(ctx, listInvoicesToArchive, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Invoice
+	for rows.Next() {
+		var i Invoice
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.SubscriptionID,
+			&i.Description,
+			&i.AmountCents,
+			&i.PeriodStart,
+			&i.PeriodEnd,
+			&i.Status,
+			&i.Attempts,
+			&i.LastAttemptAt,
+			&i.PaidAt,
+			&i.CreatedAt,
+			&i.Kind,
+			&i.CatalogItemID,
+			&i.CreditedInvoiceID,
+			&i.Number,
+			&i.IssuedAt,
+			&i.AmountExclVatCents,
+			&i.VatRateBps,
+			&i.VatCents,
+			&i.BuyerName,
+			&i.BuyerEmail,
+			&i.DocumentID,
+			&i.SlotQuantity,
+			&i.CreditPropertyID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listInvoicesToRetry = `-- name: ListInvoicesToRetry :many
+SELECT i.id, i.user_id, i.subscription_id, i.description, i.amount_cents, i.period_start, i.period_end, i.status, i.attempts, i.last_attempt_at, i.paid_at, i.created_at, i.kind, i.catalog_item_id, i.credited_invoice_id, i.number, i.issued_at, i.amount_excl_vat_cents, i.vat_rate_bps, i.vat_cents, i.buyer_name, i.buyer_email, i.document_id, i.slot_quantity, i.credit_property_id FROM invoices i
+JOIN subscriptions s ON s.id = i.subscription_id
+WHERE s.status = 'past_due' AND (
+    (i.status = 'failed' AND i.last_attempt_at <= $1)
+    OR (i.status = 'processing' AND i.last_attempt_at <= $2)
+)
+ORDER BY i.last_attempt_at ASC, i.id ASC
+`
+
+type ListInvoicesToRetryParams struct {
+	RetryBefore pgtype.Timestamp `json:"retry_before"`
+	StaleBefore pgtype.Timestamp `json:"stale_before"`
+}
+
+// Failed renewals of subscriptions still past due, last attempted before @retry_before, and retries
+// abandoned mid-charge (claimed before @stale_before).
+func (q *Queries) ListInvoicesToRetry(ctx context.Context, arg ListInvoicesToRetryParams) ([]Invoice, error) {
+	rows, err := q.db.Query(ctx, listInvoicesToRetry, arg.RetryBefore, arg.StaleBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Invoice
+	for rows.Next() {
+		var i Invoice
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.SubscriptionID,
+			&i.Description,
+			&i.AmountCents,
+			&i.PeriodStart,
+			&i.PeriodEnd,
+			&i.Status,
+			&i.Attempts,
+			&i.LastAttemptAt,
+			&i.PaidAt,
+			&i.CreatedAt,
+			&i.Kind,
+			&i.CatalogItemID,
+			&i.CreditedInvoiceID,
+			&i.Number,
+			&i.IssuedAt,
+			&i.AmountExclVatCents,
+			&i.VatRateBps,
+			&i.VatCents,
+			&i.BuyerName,
+			&i.BuyerEmail,
+			&i.DocumentID,
+			&i.SlotQuantity,
+			&i.CreditPropertyID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listIssuedInvoicesByUser = `-- name: ListIssuedInvoicesByUser :many
+SELECT id, user_id, subscription_id, description, amount_cents, period_start, period_end, status, attempts, last_attempt_at, paid_at, created_at, kind, catalog_item_id, credited_invoice_id, number, issued_at, amount_excl_vat_cents, vat_rate_bps, vat_cents, buyer_name, buyer_email, document_id, slot_quantity, credit_property_id FROM invoices
+WHERE user_id = $1 AND number IS NOT NULL
+ORDER BY issued_at DESC, id DESC
+`
+
+func (q *Queries) ListIssuedInvoicesByUser(ctx context.Context, userID int32) ([]Invoice, error) {
+	rows, err := q.db.Query(ctx, listIssuedInvoicesByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Invoice
+	for rows.Next() {
+		var i Invoice
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.SubscriptionID,
+			&i.Description,
+			&i.AmountCents,
+			&i.PeriodStart,
+			&i.PeriodEnd,
+			&i.Status,
+			&i.Attempts,
+			&i.LastAttemptAt,
+			&i.PaidAt,
+			&i.CreatedAt,
+			&i.Kind,
+			&i.CatalogItemID,
+			&i.CreditedInvoiceID,
+			&i.Number,
+			&i.IssuedAt,
+			&i.AmountExclVatCents,
+			&i.VatRateBps,
+			&i.VatCents,
+			&i.BuyerName,
+			&i.BuyerEmail,
+			&i.DocumentID,
+			&i.SlotQuantity,
+			&i.CreditPropertyID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLeaseDocuments = `-- name: ListLeaseDocuments :many
+SELECT d.id, d.document_type, d.entity_id, d.version, d.storage_key, d.content_type, d.filename, d.created_at FROM documents d
+WHERE (d.document_type = 'lease' AND d.entity_id = $1)
+OR (d.document_type = 'guarantee_deed' AND d.entity_id IN (SELECT sg.id FROM solvency_guarantors sg WHERE sg.lease_id = $1))
+ORDER BY d.id
+`
+
+// Every generated document of a lease: contract versions and guarantee deeds.
+func (q *Queries) ListLeaseDocuments(ctx context.Context, entityID int32) ([]Document, error) {
+	rows, err := q.db.Query(ctx, listLeaseDocuments, entityID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Document
+	for rows.Next() {
+		var i Document
+		if err := rows.Scan(
+			&i.ID,
+			&i.DocumentType,
+			&i.EntityID,
+			&i.Version,
+			&i.StorageKey,
+			&i.ContentType,
+			&i.Filename,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLeaseParties = `-- name: ListLeaseParties :many
+SELECT id, lease_id, user_id, email, first_name, last_name, rent_share, status, joined_at, notice_date, left_at, solidarity_ends_at, replaces_party_id, created_at FROM lease_parties
+WHERE lease_id = $1
+ORDER BY id
+`
+
+func (q *Queries) ListLeaseParties(ctx context.Context, leaseID int32) ([]LeaseParty, error) {
+	rows, err := q.db.Query(ctx, listLeaseParties, leaseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []LeaseParty
+	for rows.Next() {
+		var i LeaseParty
+		if err := rows.Scan(
+			&i.ID,
+			&i.LeaseID,
+			&i.UserID,
+			&i.Email,
+			&i.FirstName,
+			&i.LastName,
+			&i.RentShare,
+			&i.Status,
+			&i.JoinedAt,
+			&i.NoticeDate,
+			&i.LeftAt,
+			&i.SolidarityEndsAt,
+			&i.ReplacesPartyID,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLeasesByOwner = `-- name: ListLeasesByOwner :many
+SELECT l.id, l.property_id, l.tenant_id, l.start_date, l.end_date, l.rent_amount, l.charges_amount, l.deposit_amount, l.payment_day, l.special_clauses, l.lease_status, l.signature_status, l.signature_envelope_id, l.contract_url, l.escrow_deposit_status, l.joint_liability, l.individual_rent_shares, l.anonymized_at, l.created_at, p.address as property_address, p.rental_type
+FROM leases l
+JOIN properties p ON l.property_id = p.id
+WHERE p.owner_id = $1
+ORDER BY l.created_at DESC
+`
+
+type ListLeasesByOwnerRow struct {
+	ID                   int32            `json:"id"`
+	PropertyID           pgtype.Int4      `json:"property_id"`
+	TenantID             pgtype.Int4      `json:"tenant_id"`
+	StartDate            pgtype.Date      `json:"start_date"`
+	EndDate              pgtype.Date      `json:"end_date"`
+	RentAmount           pgtype.Numeric   `json:"rent_amount"`
+	ChargesAmount        pgtype.Numeric   `json:"charges_amount"`
+	DepositAmount        pgtype.Numeric   `json:"deposit_amount"`
+	PaymentDay           pgtype.Int4      `json:"payment_day"`
+	SpecialClauses       []byte           `json:"special_clauses"`
+	LeaseStatus          pgtype.Text      `json:"lease_status"`
+	SignatureStatus      pgtype.Text      `json:"signature_status"`
+	SignatureEnvelopeID  pgtype.Text      `json:"signature_envelope_id"`
+	ContractUrl          pgtype.Text      `json:"contract_url"`
+	EscrowDepositStatus  NullEscrowStatus `json:"escrow_deposit_status"`
+	JointLiability       pgtype.Bool      `json:"joint_liability"`
+	IndividualRentShares pgtype.Bool      `json:"individual_rent_shares"`
+	AnonymizedAt         pgtype.Timestamp `json:"anonymized_at"`
+	CreatedAt            pgtype.Timestamp `json:"created_at"`
+	PropertyAddress      string           `json:"property_address"`
+	RentalType           PropertyType     `json:"rental_type"`
+}
+
+func (q *Queries) ListLeasesByOwner(ctx context.Context, ownerID pgtype.Int4) ([]ListLeasesByOwnerRow, error) {
+	rows, err := q.db.Query(ctx, listLeasesByOwner, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListLeasesByOwnerRow
+	for rows.Next() {
+		var i ListLeasesByOwnerRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.PropertyID,
+			&i.TenantID,
+			&i.StartDate,
+			&i.EndDate,
+			&i.RentAmount,
+			&i.ChargesAmount,
+			&i.DepositAmount,
+			&i.PaymentDay,
+			&i.SpecialClauses,
+			&i.LeaseStatus,
+			&i.SignatureStatus,
+			&i.SignatureEnvelopeID,
+			&i.ContractUrl,
+			&i.EscrowDepositStatus,
+			&i.JointLiability,
+			&i.IndividualRentShares,
+			&i.AnonymizedAt,
+			&i.CreatedAt,
+			&i.PropertyAddress,
+			&i.RentalType,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLeasesByTenant = `-- name: ListLeasesByTenant :many
+SELECT 
+    l.id, l.property_id, l.tenant_id, l.start_date, l.end_date, l.rent_amount, l.charges_amount, l.deposit_amount, l.lease_status, l.signature_status, l.contract_url, l.created_at,
+    p.address as property_address, p.rental_type
+FROM leases l
+JOIN properties p ON l.property_id = p.id
+WHERE l.tenant_id = $1
+   OR EXISTS (SELECT 1 FROM lease_parties lp WHERE lp.lease_id = l.id AND lp.user_id = $1 AND lp.status != 'invited')
+ORDER BY l.created_at DESC
+`
+
+type ListLeasesByTenantRow struct {
+	ID              int32            `json:"id"`
+	PropertyID      pgtype.Int4      `json:"property_id"`
+	TenantID        pgtype.Int4      `json:"tenant_id"`
+	StartDate       pgtype.Date      `json:"start_date"`
+	EndDate         pgtype.Date      `json:"end_date"`
+	RentAmount      pgtype.Numeric   `json:"rent_amount"`
+	ChargesAmount   pgtype.Numeric   `json:"charges_amount"`
+	DepositAmount   pgtype.Numeric   `json:"deposit_amount"`
+	LeaseStatus     pgtype.Text      `json:"lease_status"`
+	SignatureStatus pgtype.Text      `json:"signature_status"`
+	ContractUrl     pgtype.Text      `json:"contract_url"`
+	CreatedAt       pgtype.Timestamp `json:"created_at"`
+	PropertyAddress string           `json:"property_address"`
+	RentalType      PropertyType     `json:"rental_type"`
+}
+
+func (q *Queries) ListLeasesByTenant(ctx context.Context, tenantID pgtype.Int4) ([]ListLeasesByTenantRow, error) {
+	rows, err := q.db.Query(ctx, listLeasesByTenant, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListLeasesByTenantRow
+	for rows.Next() {
+		var i ListLeasesByTenantRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.PropertyID,
+			&i.TenantID,
+			&i.StartDate,
+			&i.EndDate,
+			&i.RentAmount,
+			&i.ChargesAmount,
+			&i.DepositAmount,
+			&i.LeaseStatus,
+			&i.SignatureStatus,
+			&i.ContractUrl,
+			&i.CreatedAt,
+			&i.PropertyAddress,
+			&i.RentalType,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLeasesForAnonymization = `-- name: ListLeasesForAnonymization :many
+SELECT id FROM leases
+WHERE lease_status = 'terminated'
+AND end_date < $1
+AND anonymized_at IS NULL
+ORDER BY id
+`
+
+func (q *Queries) ListLeasesForAnonymization(ctx context.Context, endDate pgtype.Date) ([]int32, error) {
+	rows, err := q.db.Query(ctx, listLeasesForAnonymization, endDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPaymentTransactionsByUser = `-- name: ListPaymentTransactionsByUser :many
+SELECT id, user_id, related_entity_type, related_entity_id, amount, currency, direction, stripe_payment_intent_id, stripe_refund_id, status, failure_reason, created_at FROM transactions
+WHERE user_id = $1
+ORDER BY created_at ASC, id ASC
+`
+
+func (q *Queries) ListPaymentTransactionsByUser(ctx context.Context, userID pgtype.Int4) ([]Transaction, error) {
+	rows, err := q.db.Query(ctx, listPaymentTransactionsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Transaction
+	for rows.Next() {
+		var i Transaction
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.RelatedEntityType,
+			&i.RelatedEntityID,
+			&i.Amount,
+			&i.Currency,
+			&i.Direction,
+			&i.StripePaymentIntentID,
+			&i.StripeRefundID,
+			&i.Status,
+			&i.FailureReason,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPropertiesByOwner = `-- name: ListPropertiesByOwner :many
+SELECT id, owner_id, name, address, rental_type, details, rent_amount, rent_charges_amount, deposit_amount, is_furnished, seasonal_price_per_night, vacancy_credits, is_active, created_at FROM properties
+WHERE owner_id = $1
+ORDER BY created_at DESC
 `
 
 func (q *Queries) ListPropertiesByOwner(ctx context.Context, ownerID pgtype.Int4) ([]Property, error) {
 	rows, err := q.db.Query(ctx, listPropertiesByOwner, ownerID)
 	if err != nil {
-		return nil, err
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Property
+	for rows.Next() {
+		var i Property
+		if err := rows.Scan(
+			&i.ID,
+			&i.OwnerID,
+			&i.Name,
+			&i.Address,
+			&i.RentalType,
+			&i.Details,
+			&i.RentAmount,
+			&i.RentChargesAmount,
+			&i.DepositAmount,
+			&i.IsFurnished,
+			&i.SeasonalPricePerNight,
+			&i.VacancyCredits,
+			&i.IsActive,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPropertyCreditDrifts = `-- name: ListPropertyCreditDrifts :many
+SELECT p.id AS property_id, p.owner_id, p.vacancy_credits, a.balance AS ledger_balance
+FROM properties p
+JOIN credit_accounts a ON a.property_id = p.id
+WHERE p.vacancy_credits <> a.balance
+`
+
+type ListPropertyCreditDriftsRow struct {
+	PropertyID     int32       `json:"property_id"`
+	OwnerID        pgtype.Int4 `json:"owner_id"`
+	VacancyCredits int32       `json:"vacancy_credits"`
+	LedgerBalance  int32       `json:"ledger_balance"`
+}
+
+// Properties whose vacancy credits differ from the balance of their wallet.
+func (q *Queries) ListPropertyCreditDrifts(ctx context.Context) ([]ListPropertyCreditDriftsRow, error) {
+	rows, err := q.db.Query(ctx, listPropertyCreditDrifts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListPropertyCreditDriftsRow
+	for rows.Next() {
+		var i ListPropertyCreditDriftsRow
+		if err := rows.Scan(
+			&i.PropertyID,
+			&i.OwnerID,
+			&i.VacancyCredits,
+			&i.LedgerBalance,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPropertyMedia = `-- name: ListPropertyMedia :many
+SELECT id, property_id, media_kind, diagnostic_type, original_filename, storage_key, thumbnail_key, content_type, size_bytes, position, is_cover, expires_at, reminder_sent_at, created_at FROM property_media
+WHERE property_id = $1
+ORDER BY media_kind DESC, position ASC, id ASC
+`
+
+func (q *Queries) ListPropertyMedia(ctx context.Context, propertyID int32) ([]PropertyMedium, error) {
+	rows, err := q.db.Query(ctx, listPropertyMedia, propertyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PropertyMedium
+	for rows.Next() {
+		var i PropertyMedium
+		if err := rows.Scan(
+			&i.ID,
+			&i.PropertyID,
+			&i.MediaKind,
+			&i.DiagnosticType,
+			&i.OriginalFilename,
+			&i.StorageKey,
+			&i.ThumbnailKey,
+			&i.ContentType,
+			&i.SizeBytes,
+			&i.Position,
+			&i.IsCover,
+			&i.ExpiresAt,
+			&i.ReminderSentAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRentPaymentsByLease = `-- name: ListRentPaymentsByLease :many
+SELECT id, lease_id, amount, due_date, payment_date, status, receipt_url, is_sepa_direct_debit FROM rent_payments
+WHERE lease_id = $1
+ORDER BY due_date ASC, id ASC
+`
+
+func (q *Queries) ListRentPaymentsByLease(ctx context.Context, leaseID pgtype.Int4) ([]RentPayment, error) {
+	rows, err := q.db.Query(ctx, listRentPaymentsByLease, leaseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RentPayment
+	for rows.Next() {
+		var i RentPayment
+		if err := rows.Scan(
+			&i.ID,
+			&i.LeaseID,
+			&i.Amount,
+			&i.DueDate,
+			&i.PaymentDate,
+			&i.Status,
+			&i.ReceiptUrl,
+			&i.IsSepaDirectDebit,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSolvencyChecksAwaitingCandidate = `-- name: ListSolvencyChecksAwaitingCandidate :many
+SELECT sc.id, sc.token, sc.created_at, sc.expires_at, sc.reminders_sent,
+    u.email as candidate_email, u.first_name as candidate_first_name, p.address as property_address
+FROM solvency_checks sc
+JOIN users u ON sc.candidate_id = u.id
+JOIN properties p ON sc.property_id = p.id
+WHERE sc.status IN ('pending', 'insufficient_docs')
+AND sc.expires_at > NOW()
+AND sc.reminders_sent < $1::int
+`
+
+type ListSolvencyChecksAwaitingCandidateRow struct {
+	ID                 int32            `json:"id"`
+	Token              pgtype.Text      `json:"token"`
+	CreatedAt          pgtype.Timestamp `json:"created_at"`
+	ExpiresAt          pgtype.Timestamp `json:"expires_at"`
+	RemindersSent      int32            `json:"reminders_sent"`
+	CandidateEmail     string           `json:"candidate_email"`
+	CandidateFirstName pgtype.Text      `json:"candidate_first_name"`
+	PropertyAddress    string           `json:"property_address"`
+}
+
+// Checks still waiting for the candidate, with reminders left to send
+func (q *Queries) ListSolvencyChecksAwaitingCandidate(ctx context.Context, maxReminders int32) ([]ListSolvencyChecksAwaitingCandidateRow, error) {
+	rows, err := q.db.Query(ctx, listSolvencyChecksAwaitingCandidate, maxReminders)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListSolvencyChecksAwaitingCandidateRow
+	for rows.Next() {
+		var i ListSolvencyChecksAwaitingCandidateRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Token,
+			&i.CreatedAt,
+			&i.ExpiresAt,
+			&i.RemindersSent,
+			&i.CandidateEmail,
+			&i.CandidateFirstName,
+			&i.PropertyAddress,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSolvencyChecksByCandidate = `-- name: ListSolvencyChecksByCandidate :many
+SELECT sc.id, sc.initiator_owner_id, sc.candidate_id, sc.token, sc.property_id, sc.status, sc.credit_source, sc.score_result, sc.analysis_json, sc.report_url, sc.documents_json, sc.missing_documents, sc.bank_provider, sc.bank_consent_id, sc.bank_connection_id, sc.employment_type, sc.guarantee_type, sc.policy_results, sc.combined_score, sc.expires_at, sc.reminders_sent, sc.selection, sc.selection_at, sc.documents_purged_at, sc.anonymized_at, sc.created_at, sc.dossier_share_id, sc.credit_transaction_id, p.address as property_address
+FROM solvency_checks sc
+LEFT JOIN properties p ON sc.property_id = p.id
+WHERE sc.candidate_id = $1
+ORDER BY sc.created_at DESC
+`
+
+type ListSolvencyChecksByCandidateRow struct {
+	ID                  int32              `json:"id"`
+	InitiatorOwnerID    pgtype.Int4        `json:"initiator_owner_id"`
+	CandidateID         pgtype.Int4        `json:"candidate_id"`
+	Token               pgtype.Text        `json:"token"`
+	PropertyID          pgtype.Int4        `json:"property_id"`
+	Status              NullSolvencyStatus `json:"status"`
+	CreditSource        pgtype.Text        `json:"credit_source"`
+	ScoreResult         pgtype.Int4        `json:"score_result"`
+	AnalysisJson        []byte             `json:"analysis_json"`
+	ReportUrl           pgtype.Text        `json:"report_url"`
+	DocumentsJson       []byte             `json:"documents_json"`
+	MissingDocuments    []byte             `json:"missing_documents"`
+	BankProvider        pgtype.Text        `json:"bank_provider"`
+	BankConsentID       pgtype.Text        `json:"bank_consent_id"`
+	BankConnectionID    pgtype.Text        `json:"bank_connection_id"`
+	EmploymentType      pgtype.Text        `json:"employment_type"`
+	GuaranteeType       pgtype.Text        `json:"guarantee_type"`
+	PolicyResults       []byte             `json:"policy_results"`
+	CombinedScore       pgtype.Int4        `json:"combined_score"`
+	ExpiresAt           pgtype.Timestamp   `json:"expires_at"`
+	RemindersSent       int32              `json:"reminders_sent"`
+	Selection           pgtype.Text        `json:"selection"`
+	SelectionAt         pgtype.Timestamp   `json:"selection_at"`
+	DocumentsPurgedAt   pgtype.Timestamp   `json:"documents_purged_at"`
+	AnonymizedAt        pgtype.Timestamp   `json:"anonymized_at"`
+	CreatedAt           pgtype.Timestamp   `json:"created_at"`
+	DossierShareID      pgtype.Int4        `json:"dossier_share_id"`
+	CreditTransactionID pgtype.Int4        `json:"credit_transaction_id"`
+	PropertyAddress     pgtype.Text        `json:"property_address"`
+}
+
+func (q *Queries) ListSolvencyChecksByCandidate(ctx context.Context, candidateID pgtype.Int4) ([]ListSolvencyChecksByCandidateRow, error) {
+	rows, err := q.db.Query(ctx, listSolvencyChecksByCandidate, candidateID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListSolvencyChecksByCandidateRow
+	for rows.Next() {
+		var i ListSolvencyChecksByCandidateRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.InitiatorOwnerID,
+			&i.CandidateID,
+			&i.Token,
+			&i.PropertyID,
+			&i.Status,
+			&i.CreditSource,
+			&i.ScoreResult,
+			&i.AnalysisJson,
+			&i.ReportUrl,
+			&i.DocumentsJson,
+			&i.MissingDocuments,
+			&i.BankProvider,
+			&i.BankConsentID,
+			&i.BankConnectionID,
+			&i.EmploymentType,
+			&i.GuaranteeType,
+			&i.PolicyResults,
+			&i.CombinedScore,
+			&i.ExpiresAt,
+			&i.RemindersSent,
+			&i.Selection,
+			&i.SelectionAt,
+			&i.DocumentsPurgedAt,
+			&i.AnonymizedAt,
+			&i.CreatedAt,
+			&i.DossierShareID,
+			&i.CreditTransactionID,
+			&i.PropertyAddress,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSolvencyChecksByLeaseTenants = `-- name: ListSolvencyChecksByLeaseTenants :many
+SELECT sc.id, sc.documents_json FROM solvency_checks sc
+JOIN leases l ON l.property_id = sc.property_id
+WHERE l.id = $1
+AND sc.anonymized_at IS NULL
+AND (sc.candidate_id = l.tenant_id OR EXISTS (
+    SELECT 1 FROM lease_parties lp WHERE lp.lease_id = l.id AND lp.user_id = sc.candidate_id
+))
+ORDER BY sc.id
+`
+
+type ListSolvencyChecksByLeaseTenantsRow struct {
+	ID            int32  `json:"id"`
+	DocumentsJson []byte `json:"documents_json"`
+}
+
+func (q *Queries) ListSolvencyChecksByLeaseTenants(ctx context.Context, id int32) ([]ListSolvencyChecksByLeaseTenantsRow, error) {
+	rows, err := q.db.Query(ctx, listSolvencyChecksByLeaseTenants, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListSolvencyChecksByLeaseTenantsRow
+	for rows.Next() {
+		var i ListSolvencyChecksByLeaseTenantsRow
+		if err := rows.Scan(&i.ID, &i.DocumentsJson); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSolvencyChecksByOwner = `-- name: ListSolvencyChecksByOwner :many
+SELECT sc.id, sc.initiator_owner_id, sc.candidate_id, sc.token, sc.property_id, sc.status, sc.credit_source, sc.score_result, sc.analysis_json, sc.report_url, sc.documents_json, sc.missing_documents, sc.bank_provider, sc.bank_consent_id, sc.bank_connection_id, sc.employment_type, sc.guarantee_type, sc.policy_results, sc.combined_score, sc.expires_at, sc.reminders_sent, sc.selection, sc.selection_at, sc.documents_purged_at, sc.anonymized_at, sc.created_at, sc.dossier_share_id, sc.credit_transaction_id, u.email as candidate_email, u.first_name as candidate_first_name, u.last_name as candidate_last_name, p.address as property_address
+FROM solvency_checks sc
+JOIN users u ON sc.candidate_id = u.id
+JOIN properties p ON sc.property_id = p.id
+WHERE sc.initiator_owner_id = $1
+ORDER BY sc.created_at DESC
+`
+
+type ListSolvencyChecksByOwnerRow struct {
+	ID                  int32              `json:"id"`
+	InitiatorOwnerID    pgtype.Int4        `json:"initiator_owner_id"`
+	CandidateID         pgtype.Int4        `json:"candidate_id"`
+	Token               pgtype.Text        `json:"token"`
+	PropertyID          pgtype.Int4        `json:"property_id"`
+	Status              NullSolvencyStatus `json:"status"`
+	CreditSource        pgtype.Text        `json:"credit_source"`
+	ScoreResult         pgtype.Int4        `json:"score_result"`
+	AnalysisJson        []byte             `json:"analysis_json"`
+	ReportUrl           pgtype.Text        `json:"report_url"`
+	DocumentsJson       []byte             `json:"documents_json"`
+	MissingDocuments    []byte             `json:"missing_documents"`
+	BankProvider        pgtype.Text        `json:"bank_provider"`
+	BankConsentID       pgtype.Text        `json:"bank_consent_id"`
+	BankConnectionID    pgtype.Text        `json:"bank_connection_id"`
+	EmploymentType      pgtype.Text        `json:"employment_type"`
+	GuaranteeType       pgtype.Text        `json:"guarantee_type"`
+	PolicyResults       []byte             `json:"policy_results"`
+	CombinedScore       pgtype.Int4        `json:"combined_score"`
+	ExpiresAt           pgtype.Timestamp   `json:"expires_at"`
+	RemindersSent       int32              `json:"reminders_sent"`
+	Selection           pgtype.Text        `json:"selection"`
+	SelectionAt         pgtype.Timestamp   `json:"selection_at"`
+	DocumentsPurgedAt   pgtype.Timestamp   `json:"documents_purged_at"`
+	AnonymizedAt        pgtype.Timestamp   `json:"anonymized_at"`
+	CreatedAt           pgtype.Timestamp   `json:"created_at"`
+	DossierShareID      pgtype.Int4        `json:"dossier_share_id"`
+	CreditTransactionID pgtype.Int4        `json:"credit_transaction_id"`
+	CandidateEmail      string             `json:"candidate_email"`
+	CandidateFirstName  pgtype.Text        `json:"candidate_first_name"`
+	CandidateLastName   pgtype.Text        `json:"candidate_last_name"`
+	PropertyAddress     string             `json:"property_address"`
+}
+
+func (q *Queries) ListSolvencyChecksByOwner(ctx context.Context, initiatorOwnerID pgtype.Int4) ([]ListSolvencyChecksByOwnerRow, error) {
+	rows, err := q.db.Query(ctx, listSolvencyChecksByOwner, initiatorOwnerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListSolvencyChecksByOwnerRow
+	for rows.Next() {
+		var i ListSolvencyChecksByOwnerRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.InitiatorOwnerID,
+			&i.CandidateID,
+			&i.Token,
+			&i.PropertyID,
+			&i.Status,
+			&i.CreditSource,
+			&i.ScoreResult,
+			&i.AnalysisJson,
+			&i.ReportUrl,
+			&i.DocumentsJson,
+			&i.MissingDocuments,
+			&i.BankProvider,
+			&i.BankConsentID,
+			&i.BankConnectionID,
+			&i.EmploymentType,
+			&i.GuaranteeType,
+			&i.PolicyResults,
+			&i.CombinedScore,
+			&i.ExpiresAt,
+			&i.RemindersSent,
+			&i.Selection,
+			&i.SelectionAt,
+			&i.DocumentsPurgedAt,
+			&i.AnonymizedAt,
+			&i.CreatedAt,
+			&i.DossierShareID,
+			&i.CreditTransactionID,
+			&i.CandidateEmail,
+			&i.CandidateFirstName,
+			&i.CandidateLastName,
+			&i.PropertyAddress,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSolvencyChecksByProperty = `-- name: ListSolvencyChecksByProperty :many
+SELECT sc.id, sc.initiator_owner_id, sc.candidate_id, sc.token, sc.property_id, sc.status, sc.credit_source, sc.score_result, sc.analysis_json, sc.report_url, sc.documents_json, sc.missing_documents, sc.bank_provider, sc.bank_consent_id, sc.bank_connection_id, sc.employment_type, sc.guarantee_type, sc.policy_results, sc.combined_score, sc.expires_at, sc.reminders_sent, sc.selection, sc.selection_at, sc.documents_purged_at, sc.anonymized_at, sc.created_at, sc.dossier_share_id, sc.credit_transaction_id, u.email as candidate_email, u.first_name as candidate_first_name, u.last_name as candidate_last_name
+FROM solvency_checks sc
+JOIN users u ON sc.candidate_id = u.id
+WHERE sc.property_id = $1
+ORDER BY sc.created_at DESC
+`
+
+type ListSolvencyChecksByPropertyRow struct {
+	ID                  int32              `json:"id"`
+	InitiatorOwnerID    pgtype.Int4        `json:"initiator_owner_id"`
+	CandidateID         pgtype.Int4        `json:"candidate_id"`
+	Token               pgtype.Text        `json:"token"`
+	PropertyID          pgtype.Int4        `json:"property_id"`
+	Status              NullSolvencyStatus `json:"status"`
+	CreditSource        pgtype.Text        `json:"credit_source"`
+	ScoreResult         pgtype.Int4        `json:"score_result"`
+	AnalysisJson        []byte             `json:"analysis_json"`
+	ReportUrl           pgtype.Text        `json:"report_url"`
+	DocumentsJson       []byte             `json:"documents_json"`
+	MissingDocuments    []byte             `json:"missing_documents"`
+	BankProvider        pgtype.Text        `json:"bank_provider"`
+	BankConsentID       pgtype.Text        `json:"bank_consent_id"`
+	BankConnectionID    pgtype.Text        `json:"bank_connection_id"`
+	EmploymentType      pgtype.Text        `json:"employment_type"`
+	GuaranteeType       pgtype.Text        `json:"guarantee_type"`
+	PolicyResults       []byte             `json:"policy_results"`
+	CombinedScore       pgtype.Int4        `json:"combined_score"`
+	ExpiresAt           pgtype.Timestamp   `json:"expires_at"`
+	RemindersSent       int32              `json:"reminders_sent"`
+	Selection           pgtype.Text        `json:"selection"`
+	SelectionAt         pgtype.Timestamp   `json:"selection_at"`
+	DocumentsPurgedAt   pgtype.Timestamp   `json:"documents_purged_at"`
+	AnonymizedAt        pgtype.Timestamp   `json:"anonymized_at"`
+	CreatedAt           pgtype.Timestamp   `json:"created_at"`
+	DossierShareID      pgtype.Int4        `json:"dossier_share_id"`
+	CreditTransactionID pgtype.Int4        `json:"credit_transaction_id"`
+	CandidateEmail      string             `json:"candidate_email"`
+	CandidateFirstName  pgtype.Text        `json:"candidate_first_name"`
+	CandidateLastName   pgtype.Text        `json:"candidate_last_name"`
+}
+
+func (q *Queries) ListSolvencyChecksByProperty(ctx context.Context, propertyID pgtype.Int4) ([]ListSolvencyChecksByPropertyRow, error) {
+	rows, err := q.db.Query(ctx, listSolvencyChecksByProperty, propertyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListSolvencyChecksByPropertyRow
+	for rows.Next() {
+		var i ListSolvencyChecksByPropertyRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.InitiatorOwnerID,
+			&i.CandidateID,
+			&i.Token,
+			&i.PropertyID,
+			&i.Status,
+			&i.CreditSource,
+			&i.ScoreResult,
+			&i.AnalysisJson,
+			&i.ReportUrl,
+			&i.DocumentsJson,
+			&i.MissingDocuments,
+			&i.BankProvider,
+			&i.BankConsentID,
+			&i.BankConnectionID,
+			&i.EmploymentType,
+			&i.GuaranteeType,
+			&i.PolicyResults,
+			&i.CombinedScore,
+			&i.ExpiresAt,
+			&i.RemindersSent,
+			&i.Selection,
+			&i.SelectionAt,
+			&i.DocumentsPurgedAt,
+			&i.AnonymizedAt,
+			&i.CreatedAt,
+			&i.DossierShareID,
+			&i.CreditTransactionID,
+			&i.CandidateEmail,
+			&i.CandidateFirstName,
+			&i.CandidateLastName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSolvencyChecksForAnonymization = `-- name: ListSolvencyChecksForAnonymization :many
+SELECT sc.id, sc.documents_json FROM solvency_checks sc
+WHERE sc.status NOT IN ('pending', 'insufficient_docs')
+AND sc.created_at < $1
+AND sc.anonymized_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM leases l
+    WHERE l.property_id = sc.property_id
+    AND (l.tenant_id = sc.candidate_id OR EXISTS (
+        SELECT 1 FROM lease_parties lp WHERE lp.lease_id = l.id AND lp.user_id = sc.candidate_id
+    ))
+)
+ORDER BY sc.id
+`
+
+type ListSolvencyChecksForAnonymizationRow struct {
+	ID            int32  `json:"id"`
+	DocumentsJson []byte `json:"documents_json"`
+}
+
+// Closed checks of candidates who were not retained: no lease with them on the property.
+func (q *Queries) ListSolvencyChecksForAnonymization(ctx context.Context, createdAt pgtype.Timestamp) ([]ListSolvencyChecksForAnonymizationRow, error) {
+	rows, err := q.db.Query(ctx, listSolvencyChecksForAnonymization, createdAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListSolvencyChecksForAnonymizationRow
+	for rows.Next() {
+		var i ListSolvencyChecksForAnonymizationRow
+		if err := rows.Scan(&i.ID, &i.DocumentsJson); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSolvencyChecksForDocumentPurge = `-- name: ListSolvencyChecksForDocumentPurge :many
+SELECT sc.id, sc.documents_json FROM solvency_checks sc
+WHERE sc.status NOT IN ('pending', 'insufficient_docs')
+AND sc.created_at < $1
+AND sc.documents_purged_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM leases l
+    WHERE l.property_id = sc.property_id
+    AND (l.tenant_id = sc.candidate_id OR EXISTS (
+        SELECT 1 FROM lease_parties lp WHERE lp.lease_id = l.id AND lp.user_id = sc.candidate_id
+    ))
+)
+ORDER BY sc.id
+`
+
+type ListSolvencyChecksForDocumentPurgeRow struct {
+	ID            int32  `json:"id"`
+	DocumentsJson []byte `json:"documents_json"`
+}
+
+// Closed checks whose candidate did not become a tenant of the property (the tenant's are purged with the lease).
+func (q *Queries) ListSolvencyChecksForDocumentPurge(ctx context.Context, createdAt pgtype.Timestamp) ([]ListSolvencyChecksForDocumentPurgeRow, error) {
+	rows, err := q.db.Query(ctx, listSolvencyChecksForDocumentPurge, createdAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListSolvencyChecksForDocumentPurgeRow
+	for rows.Next() {
+		var i ListSolvencyChecksForDocumentPurgeRow
+		if err := rows.Scan(&i.ID, &i.DocumentsJson); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSubscriptionEventsByUser = `-- name: ListSubscriptionEventsByUser :many
+SELECT e.id, e.subscription_id, e.user_id, e.event_type, e.from_catalog_item_id, e.to_catalog_item_id, e.effective_date, e.amount_cents, e.invoice_id, e.archived_property_ids, e.created_at, e.slot_quantity, f.code AS from_plan, t.code AS to_plan FROM subscription_events e
+LEFT JOIN catalog_items f ON f.id = e.from_catalog_item_id
+LEFT JOIN catalog_items t ON t.id = e.to_catalog_item_id
+WHERE e.user_id = $1
+ORDER BY e.id ASC
+`
+
+type ListSubscriptionEventsByUserRow struct {
+	ID                  int32            `json:"id"`
+	SubscriptionID      int32            `json:"subscription_id"`
+	UserID              int32            `json:"user_id"`
+	EventType           string           `json:"event_type"`
+	FromCatalogItemID   pgtype.Int4      `json:"from_catalog_item_id"`
+	ToCatalogItemID     pgtype.Int4      `json:"to_catalog_item_id"`
+	EffectiveDate       pgtype.Date      `json:"effective_date"`
+	AmountCents         pgtype.Int4      `json:"amount_cents"`
+	InvoiceID           pgtype.Int4      `json:"invoice_id"`
+	ArchivedPropertyIds []int32          `json:"archived_property_ids"`
+	CreatedAt           pgtype.Timestamp `json:"created_at"`
+	SlotQuantity        pgtype.Int4      `json:"slot_quantity"`
+	FromPlan            pgtype.Text      `json:"from_plan"`
+	ToPlan              pgtype.Text      `json:"to_plan"`
+}
+
+func (q *Queries) ListSubscriptionEventsByUser(ctx context.Context, userID int32) ([]ListSubscriptionEventsByUserRow, error) {
+	rows, err := q.db.Query(ctx, listSubscriptionEventsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListSubscriptionEventsByUserRow
+	for rows.Next() {
+		var i ListSubscriptionEventsByUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.SubscriptionID,
+			&i.UserID,
+			&i.EventType,
+			&i.FromCatalogItemID,
+			&i.ToCatalogItemID,
+			&i.EffectiveDate,
+			&i.AmountCents,
+			&i.InvoiceID,
+			&i.ArchivedPropertyIds,
+			&i.CreatedAt,
+			&i.SlotQuantity,
+			&i.FromPlan,
+			&i.ToPlan,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSubscriptionsByUser = `-- name: ListSubscriptionsByUser :many
+SELECT id, user_id, plan_type, frequency, status, start_date, end_date, max_properties_limit, current_period_start, current_period_end, next_credit_grant, granted_credits, created_at, catalog_item_id, cancel_at_period_end, scheduled_catalog_item_id, scheduled_archived_property_ids, ended_at FROM subscriptions
+WHERE user_id = $1
+ORDER BY created_at ASC, id ASC
+`
+
+func (q *Queries) ListSubscriptionsByUser(ctx context.Context, userID pgtype.Int4) ([]Subscription, error) {
+	rows, err := q.db.Query(ctx, listSubscriptionsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Subscription
+	for rows.Next() {
+		var i Subscription
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.PlanType,
+			&i.Frequency,
+			&i.Status,
+			&i.StartDate,
+			&i.EndDate,
+			&i.MaxPropertiesLimit,
+			&i.CurrentPeriodStart,
+			&i.CurrentPeriodEnd,
+			&i.NextCreditGrant,
+			&i.GrantedCredits,
+			&i.CreatedAt,
+			&i.CatalogItemID,
+			&i.CancelAtPeriodEnd,
+			&i.ScheduledCatalogItemID,
+			&i.ScheduledArchivedPropertyIds,
+			&i.EndedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSubscriptionsDueForCredits = `-- name: ListSubscriptionsDueForCredits :many
+SELECT id FROM subscriptions
+WHERE status = 'active' AND next_credit_grant <= $1::date
+AND (end_date IS NULL OR next_credit_grant < end_date)
+ORDER BY next_credit_grant ASC, id ASC
+`
+
+func (q *Queries) ListSubscriptionsDueForCredits(ctx context.Context, today pgtype.Date) ([]int32, error) {
+	rows, err := q.db.Query(ctx, listSubscriptionsDueForCredits, today)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSubscriptionsDueForRenewal = `-- name: ListSubscriptionsDueForRenewal :many
+SELECT id FROM subscriptions
+WHERE status = 'active' AND current_period_end <= $1::date
+ORDER BY current_period_end ASC, id ASC
+`
+
+func (q *Queries) ListSubscriptionsDueForRenewal(ctx context.Context, today pgtype.Date) ([]int32, error) {
+	rows, err := q.db.Query(ctx, listSubscriptionsDueForRenewal, today)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUnbalancedCreditEntries = `-- name: ListUnbalancedCreditEntries :many
+SELECT entry_id, SUM(amount)::int AS total FROM credit_transactions
+GROUP BY entry_id
+HAVING SUM(amount) <> 0
+`
+
+type ListUnbalancedCreditEntriesRow struct {
+	EntryID pgtype.Int4 `json:"entry_id"`
+	Total   int32       `json:"total"`
+}
+
+func (q *Queries) ListUnbalancedCreditEntries(ctx context.Context) ([]ListUnbalancedCreditEntriesRow, error) {
+	rows, err := q.db.Query(ctx, listUnbalancedCreditEntries)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUnbalancedCreditEntriesRow
+	for rows.Next() {
+		var i ListUnbalancedCreditEntriesRow
+		if err := rows.Scan(&i.EntryID, &i.Total); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockPropertiesByOwner = `-- name: LockPropertiesByOwner :exec
+SELECT id FROM properties
+WHERE owner_id = $1
+ORDER BY id
+FOR UPDATE
+`
+
+// Locks the owner's properties in id order: properties are locked before the user and its wallets.
+func (q *Queries) LockPropertiesByOwner(ctx context.Context, ownerID pgtype.Int4) error {
+	_, err := q.db.Exec(ctx, lockPropertiesByOwner, ownerID)
+	return err
+}
+
+const markDiagnosticReminderSent = `-- name: MarkDiagnosticReminderSent :exec
+UPDATE property_media
+SET reminder_sent_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) MarkDiagnosticReminderSent(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, markDiagnosticReminderSent, id)
+	return err
+}
+
+const markInvoiceFailed = `-- name: MarkInvoiceFailed :one
+UPDATE invoices
+SET status = 'failed', attempts = attempts + 1, last_attempt_at = NOW()
+WHERE id = $1 AND status IN ('open', 'processing', 'pending', 'failed')
+RETURNING id, user_id, subscription_id, description, amount_cents, period_start, period_end, status, attempts, last_attempt_at, paid_at, created_at, kind, catalog_item_id, credited_invoice_id, number, issued_at, amount_excl_vat_cents, vat_rate_bps, vat_cents, buyer_name, buyer_email, document_id, slot_quantity, credit_property_id
+`
+
+func (q *Queries) MarkInvoiceFailed(ctx context.Context, id int32) (Invoice, error) {
+	row := q.db.QueryRow(ctx, markInvoiceFailed, id)
+	var i Invoice
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.SubscriptionID,
+		&i.Description,
+		&i.AmountCents,
+		&i.PeriodStart,
+		&i.PeriodEnd,
+		&i.Status,
+		&i.Attempts,
+		&i.LastAttemptAt,
+		&i.PaidAt,
+		&i.CreatedAt,
+		&i.Kind,
+		&i.CatalogItemID,
+		&i.CreditedInvoiceID,
+		&i.Number,
+		&i.IssuedAt,
+		&i.AmountExclVatCents,
+		&i.VatRateBps,
+		&i.VatCents,
+		&i.BuyerName,
+		&i.BuyerEmail,
+		&i.DocumentID,
+		&i.SlotQuantity,
+		&i.CreditPropertyID,
+	)
+	return i, err
+}
+
+const markInvoicePaid = `-- name: MarkInvoicePaid :one
+UPDATE invoices
+SET status = 'paid', attempts = attempts + 1, last_attempt_at = NOW(), paid_at = NOW()
+WHERE id = $1 AND status IN ('open', 'processing', 'pending', 'failed')
+RETURNING id, user_id, subscription_id, description, amount_cents, period_start, period_end, status, attempts, last_attempt_at, paid_at, created_at, kind, catalog_item_id, credited_invoice_id, number, issued_at, amount_excl_vat_cents, vat_rate_bps, vat_cents, buyer_name, buyer_email, document_id, slot_quantity, credit_property_id
+`
+
+func (q *Queries) MarkInvoicePaid(ctx context.Context, id int32) (Invoice, error) {
+	row := q.db.QueryRow(ctx, markInvoicePaid, id)
+	var i Invoice
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.SubscriptionID,
+		&i.Description,
+		&i.AmountCents,
+		&i.PeriodStart,
+		&i.PeriodEnd,
+		&i.Status,
+		&i.Attempts,
+		&i.LastAttemptAt,
+		&i.PaidAt,
+		&i.CreatedAt,
+		&i.Kind,
+		&i.CatalogItemID,
+		&i.CreditedInvoiceID,
+		&i.Number,
+		&i.IssuedAt,
+		&i.AmountExclVatCents,
+		&i.VatRateBps,
+		&i.VatCents,
+		&i.BuyerName,
+		&i.BuyerEmail,
+		&i.DocumentID,
+		&i.SlotQuantity,
+		&i.CreditPropertyID,
+	)
+	return i, err
+}
+
+const markInvoicePending = `-- name: MarkInvoicePending :exec
+UPDATE invoices
+SET status = 'pending', last_attempt_at = NOW()
+WHERE id = $1 AND status IN ('open', 'processing', 'failed')
+`
+
+// Payment accepted by the provider but not confirmed yet (3DS, SEPA debit): settled by its webhook.
+func (q *Queries) MarkInvoicePending(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, markInvoicePending, id)
+	return err
+}
+
+const markSolvencyCheckDocumentsPurged = `-- name: MarkSolvencyCheckDocumentsPurged :exec
+UPDATE solvency_checks
+SET documents_json = NULL, missing_documents = NULL, report_url = NULL, documents_purged_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) MarkSolvencyCheckDocumentsPurged(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, markSolvencyCheckDocumentsPurged, id)
+	return err
+}
+
+const markSolvencyCheckReminderSent = `-- name: MarkSolvencyCheckReminderSent :exec
+UPDATE solvency_checks
+SET reminders_sent = reminders_sent + 1
+WHERE id = $1
+`
+
+func (q *Queries) MarkSolvencyCheckReminderSent(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, markSolvencyCheckReminderSent, id)
+	return err
+}
+
+const nextInvoiceNumber = `-- name: NextInvoiceNumber :one
+INSERT INTO invoice_sequences (series, year, last_number)
+VALUES ($1, $2, 1)
+ON CONFLICT (series, year) DO UPDATE SET last_number = invoice_sequences.last_number + 1
+RETURNING last_number
+`
+
+type NextInvoiceNumberParams struct {
+	Series string `json:"series"`
+	Year   int32  `json:"year"`
+}
+
+// Locks the counter of the series until the issuing transaction ends, so numbers have no gap.
+func (q *Queries) NextInvoiceNumber(ctx context.Context, arg NextInvoiceNumberParams) (int32, error) {
+	row := q.db.QueryRow(ctx, nextInvoiceNumber, arg.Series, arg.Year)
+	var last_number int32
+	err := row.Scan(&last_number)
+	return last_number, err
+}
+
+const purgeIdempotencyKeys = `-- name: PurgeIdempotencyKeys :execrows
+DELETE FROM idempotency_keys
+WHERE created_at < $1
+`
+
+func (q *Queries) PurgeIdempotencyKeys(ctx context.Context, createdBefore pgtype.Timestamp) (int64, error) {
+	result, err := q.db.Exec(ctx, purgeIdempotencyKeys, createdBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const recordSubscriptionCreditGrant = `-- name: RecordSubscriptionCreditGrant :exec
+UPDATE subscriptions
+SET next_credit_grant = $2, granted_credits = $3
+WHERE id = $1
+`
+
+type RecordSubscriptionCreditGrantParams struct {
+	ID              int32       `json:"id"`
+	NextCreditGrant pgtype.Date `json:"next_credit_grant"`
+	GrantedCredits  int32       `json:"granted_credits"`
+}
+
+func (q *Queries) RecordSubscriptionCreditGrant(ctx context.Context, arg RecordSubscriptionCreditGrantParams) error {
+	_, err := q.db.Exec(ctx, recordSubscriptionCreditGrant, arg.ID, arg.NextCreditGrant, arg.GrantedCredits)
+	return err
+}
+
+const recordWebhookEvent = `-- name: RecordWebhookEvent :execrows
+INSERT INTO webhook_events (provider, event_id, event_type)
+VALUES ($1, $2, $3)
+ON CONFLICT (provider, event_id) DO NOTHING
+`
+
+type RecordWebhookEventParams struct {
+	Provider  string `json:"provider"`
+	EventID   string `json:"event_id"`
+	EventType string `json:"event_type"`
+}
+
+func (q *Queries) RecordWebhookEvent(ctx context.Context, arg RecordWebhookEventParams) (int64, error) {
+	result, err := q.db.Exec(ctx, recordWebhookEvent, arg.Provider, arg.EventID, arg.EventType)
+	if err != nil {
+		return 0, err
 	}
-	defer rows.Close()
-	var items []Property
-	for rows.Next() {
-		var i Property
-		if err := rows.Scan(
-			&i.ID,
-			&i.OwnerID,
-			&i.Name,
-			&i.Address,
-			&i.RentalType,
-			&i.Details,
-			&i.RentAmount,
-			&i.RentChargesAmount,
-			&i.DepositAmount,
-			&i.IsFurnished,
-			&i.SeasonalPricePerNight,
-			&i.VacancyCredits,
-			&i.IsActive,
-			&i.CreatedAt,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
+	return result.RowsAffected(), nil
+}
+
+const retireCatalogItem = `-- name: RetireCatalogItem :one
+UPDATE catalog_items
+SET valid_until = NOW(), updated_at = NOW()
+WHERE id = $1 AND (valid_until IS NULL OR valid_until > NOW())
+RETURNING id, code, kind, name, plan_type, monthly_price_cents, yearly_price_cents, price_cents, max_properties, included_credits, slot_price_cents, valid_from, valid_until, created_at, updated_at
+`
+
+// Ends the validity now; subscriptions already taken keep referring to it.
+func (q *Queries) RetireCatalogItem(ctx context.Context, id int32) (CatalogItem, error) {
+	row := q.db.QueryRow(ctx, retireCatalogItem, id)
+	var i CatalogItem
+	err := row.Scan(
+		&i.ID,
+		&i.Code,
+		&i.Kind,
+		&i.Name,
+		&i.PlanType,
+		&i.MonthlyPriceCents,
+		&i.YearlyPriceCents,
+		&i.PriceCents,
+		&i.MaxProperties,
+		&i.IncludedCredits,
+		&i.SlotPriceCents,
+		&i.ValidFrom,
+		&i.ValidUntil,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const revokeDocumentLink = `-- name: RevokeDocumentLink :execrows
+UPDATE document_links
+SET revoked_at = NOW()
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+`
+
+type RevokeDocumentLinkParams struct {
+	ID     int32 `json:"id"`
+	UserID int32 `json:"user_id"`
+}
+
+func (q *Queries) RevokeDocumentLink(ctx context.Context, arg RevokeDocumentLinkParams) (int64, error) {
+	result, err := q.db.Exec(ctx, revokeDocumentLink, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
 	}
-	if err := rows.Err(); err != nil {
-		return nil, err
+	return result.RowsAffected(), nil
+}
+
+const revokeDocumentLinksByUser = `-- name: RevokeDocumentLinksByUser :exec
+UPDATE document_links
+SET revoked_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeDocumentLinksByUser(ctx context.Context, userID int32) error {
+	_, err := q.db.Exec(ctx, revokeDocumentLinksByUser, userID)
+	return err
+}
+
+const revokeDossierShare = `-- name: RevokeDossierShare :execrows
+UPDATE dossier_shares
+SET revoked_at = NOW()
+WHERE id = $1 AND dossier_id = $2 AND revoked_at IS NULL
+`
+
+type RevokeDossierShareParams struct {
+	ID        int32 `json:"id"`
+	DossierID int32 `json:"dossier_id"`
+}
+
+func (q *Queries) RevokeDossierShare(ctx context.Context, arg RevokeDossierShareParams) (int64, error) {
+	result, err := q.db.Exec(ctx, revokeDossierShare, arg.ID, arg.DossierID)
+	if err != nil {
+		return 0, err
 	}
-	return items, nil
+	return result.RowsAffected(), nil
 }
 
-const listSolvencyChecksByOwner = `-- name: ListSolvencyChecksByOwner :many
-SELECT sc.id, sc.initiator_owner_id, sc.candidate_id, sc.token, sc.property_id, sc.status, sc.credit_source, sc.score_result, sc.report_url, sc.documents_json, sc.created_at, u.email as candidate_email, u.first_name as candidate_first_name, u.last_name as candidate_last_name, p.address as property_address
-FROM solvency_checks sc
-JOIN users u ON sc.candidate_id = u.id
-JOIN properties p ON sc.property_id = p.id
-WHERE sc.initiator_owner_id = $1
-ORDER BY sc.created_at DESC
+const revokePendingInvitationsForUser = `-- name: RevokePendingInvitationsForUser :exec
+UPDATE lease_invitations
+SET status = 'revoked'
+WHERE status = 'pending' AND (owner_id = $1 OR tenant_email = $2)
 `
 
-type ListSolvencyChecksByOwnerRow struct {
-	ID                 int32              `json:"id"`
-	InitiatorOwnerID   pgtype.Int4        `json:"initiator_owner_id"`
-	CandidateID        pgtype.Int4        `json:"candidate_id"`
-	Token              pgtype.Text        `json:"token"`
-	PropertyID         pgtype.Int4        `json:"property_id"`
-	Status             NullSolvencyStatus `json:"status"`
-	CreditSource       pgtype.Text        `json:"credit_source"`
-	ScoreResult        pgtype.Int4        `json:"score_result"`
-	ReportUrl          pgtype.Text        `json:"report_url"`
-	DocumentsJson      []byte             `json:"documents_json"`
-	CreatedAt          pgtype.Timestamp   `json:"created_at"`
-	CandidateEmail     string             `json:"candidate_email"`
-	CandidateFirstName pgtype.Text        `json:"candidate_first_name"`
-	CandidateLastName  pgtype.Text        `json:"candidate_last_name"`
-	PropertyAddress    string             `json:"property_address"`
+type RevokePendingInvitationsForUserParams struct {
+	OwnerID     int32  `json:"owner_id"`
+	TenantEmail string `json:"tenant_email"`
+}
+
+func (q *Queries) RevokePendingInvitationsForUser(ctx context.Context, arg RevokePendingInvitationsForUserParams) error {
+	_, err := q.db.Exec(ctx, revokePendingInvitationsForUser, arg.OwnerID, arg.TenantEmail)
+	return err
+}
+
+const scheduleSubscriptionChange = `-- name: ScheduleSubscriptionChange :exec
+UPDATE subscriptions
+SET scheduled_catalog_item_id = $1, scheduled_archived_property_ids = $2::int[]
+WHERE id = $3
+`
+
+type ScheduleSubscriptionChangeParams struct {
+	CatalogItemID       pgtype.Int4 `json:"catalog_item_id"`
+	ArchivedPropertyIds []int32     `json:"archived_property_ids"`
+	ID                  int32       `json:"id"`
+}
+
+func (q *Queries) ScheduleSubscriptionChange(ctx context.Context, arg ScheduleSubscriptionChangeParams) error {
+	_, err := q.db.Exec(ctx, scheduleSubscriptionChange, arg.CatalogItemID, arg.ArchivedPropertyIds, arg.ID)
+	return err
+}
+
+const scheduleSubscriptionItemQuantity = `-- name: ScheduleSubscriptionItemQuantity :exec
+UPDATE subscription_items
+SET scheduled_quantity = $1, updated_at = NOW()
+WHERE id = $2
+`
+
+type ScheduleSubscriptionItemQuantityParams struct {
+	ScheduledQuantity pgtype.Int4 `json:"scheduled_quantity"`
+	ID                int32       `json:"id"`
+}
+
+func (q *Queries) ScheduleSubscriptionItemQuantity(ctx context.Context, arg ScheduleSubscriptionItemQuantityParams) error {
+	_, err := q.db.Exec(ctx, scheduleSubscriptionItemQuantity, arg.ScheduledQuantity, arg.ID)
+	return err
+}
+
+const setGuarantorBankConnection = `-- name: SetGuarantorBankConnection :exec
+UPDATE solvency_guarantors
+SET bank_connection_id = $2
+WHERE id = $1
+`
+
+type SetGuarantorBankConnectionParams struct {
+	ID               int32       `json:"id"`
+	BankConnectionID pgtype.Text `json:"bank_connection_id"`
+}
+
+func (q *Queries) SetGuarantorBankConnection(ctx context.Context, arg SetGuarantorBankConnectionParams) error {
+	_, err := q.db.Exec(ctx, setGuarantorBankConnection, arg.ID, arg.BankConnectionID)
+	return err
+}
+
+const setGuarantorBankConsent = `-- name: SetGuarantorBankConsent :exec
+UPDATE solvency_guarantors
+SET bank_provider = $2, bank_consent_id = $3, bank_connection_id = NULL
+WHERE id = $1
+`
+
+type SetGuarantorBankConsentParams struct {
+	ID            int32       `json:"id"`
+	BankProvider  pgtype.Text `json:"bank_provider"`
+	BankConsentID pgtype.Text `json:"bank_consent_id"`
+}
+
+func (q *Queries) SetGuarantorBankConsent(ctx context.Context, arg SetGuarantorBankConsentParams) error {
+	_, err := q.db.Exec(ctx, setGuarantorBankConsent, arg.ID, arg.BankProvider, arg.BankConsentID)
+	return err
+}
+
+const setGuarantorMention = `-- name: SetGuarantorMention :exec
+UPDATE solvency_guarantors
+SET mention_text = $2, mention_signed_at = NOW()
+WHERE id = $1
+`
+
+type SetGuarantorMentionParams struct {
+	ID          int32       `json:"id"`
+	MentionText pgtype.Text `json:"mention_text"`
+}
+
+func (q *Queries) SetGuarantorMention(ctx context.Context, arg SetGuarantorMentionParams) error {
+	_, err := q.db.Exec(ctx, setGuarantorMention, arg.ID, arg.MentionText)
+	return err
+}
+
+const setInvoiceDocument = `-- name: SetInvoiceDocument :execrows
+UPDATE invoices
+SET document_id = $2
+WHERE id = $1 AND document_id IS NULL
+`
+
+type SetInvoiceDocumentParams struct {
+	ID         int32       `json:"id"`
+	DocumentID pgtype.Int4 `json:"document_id"`
+}
+
+func (q *Queries) SetInvoiceDocument(ctx context.Context, arg SetInvoiceDocumentParams) (int64, error) {
+	result, err := q.db.Exec(ctx, setInvoiceDocument, arg.ID, arg.DocumentID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const setLeasePartyDeparture = `-- name: SetLeasePartyDeparture :exec
+UPDATE lease_parties
+SET status = 'left', notice_date = $2, left_at = $3, solidarity_ends_at = $4
+WHERE id = $1
+`
+
+type SetLeasePartyDepartureParams struct {
+	ID               int32       `json:"id"`
+	NoticeDate       pgtype.Date `json:"notice_date"`
+	LeftAt           pgtype.Date `json:"left_at"`
+	SolidarityEndsAt pgtype.Date `json:"solidarity_ends_at"`
+}
+
+func (q *Queries) SetLeasePartyDeparture(ctx context.Context, arg SetLeasePartyDepartureParams) error {
+	_, err := q.db.Exec(ctx, setLeasePartyDeparture,
+		arg.ID,
+		arg.NoticeDate,
+		arg.LeftAt,
+		arg.SolidarityEndsAt,
+	)
+	return err
+}
+
+const setLeasePartySolidarityEnd = `-- name: SetLeasePartySolidarityEnd :exec
+UPDATE lease_parties
+SET solidarity_ends_at = $2
+WHERE id = $1
+`
+
+type SetLeasePartySolidarityEndParams struct {
+	ID               int32       `json:"id"`
+	SolidarityEndsAt pgtype.Date `json:"solidarity_ends_at"`
+}
+
+func (q *Queries) SetLeasePartySolidarityEnd(ctx context.Context, arg SetLeasePartySolidarityEndParams) error {
+	_, err := q.db.Exec(ctx, setLeasePartySolidarityEnd, arg.ID, arg.SolidarityEndsAt)
+	return err
+}
+
+const setPaymentTransactionEntity = `-- name: SetPaymentTransactionEntity :exec
+UPDATE transactions
+SET related_entity_type = $2, related_entity_id = $3
+WHERE id = $1
+`
+
+type SetPaymentTransactionEntityParams struct {
+	ID                int32       `json:"id"`
+	RelatedEntityType pgtype.Text `json:"related_entity_type"`
+	RelatedEntityID   pgtype.Int4 `json:"related_entity_id"`
+}
+
+func (q *Queries) SetPaymentTransactionEntity(ctx context.Context, arg SetPaymentTransactionEntityParams) error {
+	_, err := q.db.Exec(ctx, setPaymentTransactionEntity, arg.ID, arg.RelatedEntityType, arg.RelatedEntityID)
+	return err
+}
+
+const setPaymentTransactionStatus = `-- name: SetPaymentTransactionStatus :exec
+UPDATE transactions
+SET status = $2, failure_reason = $3
+WHERE id = $1
+`
+
+type SetPaymentTransactionStatusParams struct {
+	ID            int32       `json:"id"`
+	Status        pgtype.Text `json:"status"`
+	FailureReason pgtype.Text `json:"failure_reason"`
+}
+
+func (q *Queries) SetPaymentTransactionStatus(ctx context.Context, arg SetPaymentTransactionStatusParams) error {
+	_, err := q.db.Exec(ctx, setPaymentTransactionStatus, arg.ID, arg.Status, arg.FailureReason)
+	return err
+}
+
+const setPropertyMediaCover = `-- name: SetPropertyMediaCover :exec
+UPDATE property_media
+SET is_cover = TRUE
+WHERE id = $1 AND property_id = $2 AND media_kind = 'photo'
+`
+
+type SetPropertyMediaCoverParams struct {
+	ID         int32 `json:"id"`
+	PropertyID int32 `json:"property_id"`
+}
+
+func (q *Queries) SetPropertyMediaCover(ctx context.Context, arg SetPropertyMediaCoverParams) error {
+	_, err := q.db.Exec(ctx, setPropertyMediaCover, arg.ID, arg.PropertyID)
+	return err
+}
+
+const setSolvencyCheckBankConnection = `-- name: SetSolvencyCheckBankConnection :exec
+UPDATE solvency_checks
+SET bank_connection_id = $2
+WHERE id = $1
+`
+
+type SetSolvencyCheckBankConnectionParams struct {
+	ID               int32       `json:"id"`
+	BankConnectionID pgtype.Text `json:"bank_connection_id"`
+}
+
+func (q *Queries) SetSolvencyCheckBankConnection(ctx context.Context, arg SetSolvencyCheckBankConnectionParams) error {
+	_, err := q.db.Exec(ctx, setSolvencyCheckBankConnection, arg.ID, arg.BankConnectionID)
+	return err
+}
+
+const setSolvencyCheckBankConsent = `-- name: SetSolvencyCheckBankConsent :exec
+UPDATE solvency_checks
+SET bank_provider = $2, bank_consent_id = $3, bank_connection_id = NULL
+WHERE id = $1
+`
+
+type SetSolvencyCheckBankConsentParams struct {
+	ID            int32       `json:"id"`
+	BankProvider  pgtype.Text `json:"bank_provider"`
+	BankConsentID pgtype.Text `json:"bank_consent_id"`
+}
+
+func (q *Queries) SetSolvencyCheckBankConsent(ctx context.Context, arg SetSolvencyCheckBankConsentParams) error {
+	_, err := q.db.Exec(ctx, setSolvencyCheckBankConsent, arg.ID, arg.BankProvider, arg.BankConsentID)
+	return err
+}
+
+const setSolvencyCheckDossierShare = `-- name: SetSolvencyCheckDossierShare :exec
+UPDATE solvency_checks
+SET dossier_share_id = $2
+WHERE id = $1
+`
+
+type SetSolvencyCheckDossierShareParams struct {
+	ID             int32       `json:"id"`
+	DossierShareID pgtype.Int4 `json:"dossier_share_id"`
+}
+
+func (q *Queries) SetSolvencyCheckDossierShare(ctx context.Context, arg SetSolvencyCheckDossierShareParams) error {
+	_, err := q.db.Exec(ctx, setSolvencyCheckDossierShare, arg.ID, arg.DossierShareID)
+	return err
+}
+
+const setSolvencyCheckSelection = `-- name: SetSolvencyCheckSelection :exec
+UPDATE solvency_checks
+SET selection = $2, selection_at = NOW()
+WHERE id = $1
+`
+
+type SetSolvencyCheckSelectionParams struct {
+	ID        int32       `json:"id"`
+	Selection pgtype.Text `json:"selection"`
+}
+
+func (q *Queries) SetSolvencyCheckSelection(ctx context.Context, arg SetSolvencyCheckSelectionParams) error {
+	_, err := q.db.Exec(ctx, setSolvencyCheckSelection, arg.ID, arg.Selection)
+	return err
+}
+
+const setSubscriptionCancelAtPeriodEnd = `-- name: SetSubscriptionCancelAtPeriodEnd :exec
+UPDATE subscriptions
+SET cancel_at_period_end = $2
+WHERE id = $1
+`
+
+type SetSubscriptionCancelAtPeriodEndParams struct {
+	ID                int32 `json:"id"`
+	CancelAtPeriodEnd bool  `json:"cancel_at_period_end"`
+}
+
+func (q *Queries) SetSubscriptionCancelAtPeriodEnd(ctx context.Context, arg SetSubscriptionCancelAtPeriodEndParams) error {
+	_, err := q.db.Exec(ctx, setSubscriptionCancelAtPeriodEnd, arg.ID, arg.CancelAtPeriodEnd)
+	return err
+}
+
+const setSubscriptionPropertyLimit = `-- name: SetSubscriptionPropertyLimit :exec
+UPDATE subscriptions
+SET max_properties_limit = $2
+WHERE id = $1
+`
+
+type SetSubscriptionPropertyLimitParams struct {
+	ID                 int32       `json:"id"`
+	MaxPropertiesLimit pgtype.Int4 `json:"max_properties_limit"`
+}
+
+func (q *Queries) SetSubscriptionPropertyLimit(ctx context.Context, arg SetSubscriptionPropertyLimitParams) error {
+	_, err := q.db.Exec(ctx, setSubscriptionPropertyLimit, arg.ID, arg.MaxPropertiesLimit)
+	return err
+}
+
+const setSubscriptionStatus = `-- name: SetSubscriptionStatus :exec
+UPDATE subscriptions
+SET status = $2
+WHERE id = $1
+`
+
+type SetSubscriptionStatusParams struct {
+	ID     int32       `json:"id"`
+	Status pgtype.Text `json:"status"`
+}
+
+func (q *Queries) SetSubscriptionStatus(ctx context.Context, arg SetSubscriptionStatusParams) error {
+	_, err := q.db.Exec(ctx, setSubscriptionStatus, arg.ID, arg.Status)
+	return err
+}
+
+const setUserPaymentCustomer = `-- name: SetUserPaymentCustomer :exec
+UPDATE users
+SET stripe_customer_id = $2
+WHERE id = $1
+`
+
+type SetUserPaymentCustomerParams struct {
+	ID               int32       `json:"id"`
+	StripeCustomerID pgtype.Text `json:"stripe_customer_id"`
+}
+
+func (q *Queries) SetUserPaymentCustomer(ctx context.Context, arg SetUserPaymentCustomerParams) error {
+	_, err := q.db.Exec(ctx, setUserPaymentCustomer, arg.ID, arg.StripeCustomerID)
+	return err
+}
+
+const setUserSepaMandate = `-- name: SetUserSepaMandate :exec
+UPDATE users
+SET sepa_mandate_id = $2
+WHERE id = $1
+`
+
+type SetUserSepaMandateParams struct {
+	ID            int32       `json:"id"`
+	SepaMandateID pgtype.Text `json:"sepa_mandate_id"`
 }
 
-func (q *Queries) ListSolvencyChecksByOwner(ctx context.Context, initiatorOwnerID pgtype.Int4) ([]ListSolvencyChecksByOwnerRow, error) {
-	rows, err := q.db.Query(ctx, listSolvencyChecksByOwner, initiatorOwnerID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []ListSolvencyChecksByOwnerRow
-	for rows.Next() {
-		var i ListSolvencyChecksByOwnerRow
-		if err := rows.Scan(
-			&i.ID,
-			&i.InitiatorOwnerID,
-			&i.CandidateID,
-			&i.Token,
-			&i.PropertyID,
-			&i.Status,
-			&i.CreditSource,
-			&i.ScoreResult,
-			&i.ReportUrl,
-			&i.DocumentsJson,
-			&i.CreatedAt,
-			&i.CandidateEmail,
-			&i.CandidateFirstName,
-			&i.CandidateLastName,
-			&i.PropertyAddress,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+func (q *Queries) SetUserSepaMandate(ctx context.Context, arg SetUserSepaMandateParams) error {
+	_, err := q.db.Exec(ctx, setUserSepaMandate, arg.ID, arg.SepaMandateID)
+	return err
 }
 
-const listSolvencyChecksByProperty = `-- name: ListSolvencyChecksByProperty :many
-SELECT sc.id, sc.initiator_owner_id, sc.candidate_id, sc.token, sc.property_id, sc.status, sc.credit_source, sc.score_result, sc.report_url, sc.documents_json, sc.created_at, u.email as candidate_email, u.first_name as candidate_first_name, u.last_name as candidate_last_name
-FROM solvency_checks sc
-JOIN users u ON sc.candidate_id = u.id
-WHERE sc.property_id = $1
-ORDER BY sc.created_at DESC
+const settleRefundTransaction = `-- name: SettleRefundTransaction :one
+UPDATE transactions
+SET status = 'success', stripe_refund_id = $2
+WHERE id = $1 AND status = 'pending'
+RETURNING id, user_id, related_entity_type, related_entity_id, amount, currency, direction, stripe_payment_intent_id, stripe_refund_id, status, failure_reason, created_at
 `
 
-type ListSolvencyChecksByPropertyRow struct {
-	ID                 int32              `json:"id"`
-	InitiatorOwnerID   pgtype.Int4        `json:"initiator_owner_id"`
-	CandidateID        pgtype.Int4        `json:"candidate_id"`
-	Token              pgtype.Text        `json:"token"`
-	PropertyID         pgtype.Int4        `json:"property_id"`
-	Status             NullSolvencyStatus `json:"status"`
-	CreditSource       pgtype.Text        `json:"credit_source"`
-	ScoreResult        pgtype.Int4        `json:"score_result"`
-	ReportUrl          pgtype.Text        `json:"report_url"`
-	DocumentsJson      []byte             `json:"documents_json"`
-	CreatedAt          pgtype.Timestamp   `json:"created_at"`
-	CandidateEmail     string             `json:"candidate_email"`
-	CandidateFirstName pgtype.Text        `json:"candidate_first_name"`
-	CandidateLastName  pgtype.Text        `json:"candidate_last_name"`
+type SettleRefundTransactionParams struct {
+	ID             int32       `json:"id"`
+	StripeRefundID pgtype.Text `json:"stripe_refund_id"`
 }
 
-func (q *Queries) ListSolvencyChecksByProperty(ctx context.Context, propertyID pgtype.Int4) ([]ListSolvencyChecksByPropertyRow, error) {
-	rows, err := q.db.Query(ctx, listSolvencyChecksByProperty, propertyID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []ListSolvencyChecksByPropertyRow
-	for rows.Next() {
-		var i ListSolvencyChecksByPropertyRow
-		if err := rows.Scan(
-			&i.ID,
-			&i.InitiatorOwnerID,
-			&i.CandidateID,
-			&i.Token,
-			&i.PropertyID,
-			&i.Status,
-			&i.CreditSource,
-			&i.ScoreResult,
-			&i.ReportUrl,
-			&i.DocumentsJson,
-			&i.CreatedAt,
-			&i.CandidateEmail,
-			&i.CandidateFirstName,
-			&i.CandidateLastName,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+func (q *Queries) SettleRefundTransaction(ctx context.Context, arg SettleRefundTransactionParams) (Transaction, error) {
+	row := q.db.QueryRow(ctx, settleRefundTransaction, arg.ID, arg.StripeRefundID)
+	var i Transaction
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.RelatedEntityType,
+		&i.RelatedEntityID,
+		&i.Amount,
+		&i.Currency,
+		&i.Direction,
+		&i.StripePaymentIntentID,
+		&i.StripeRefundID,
+		&i.Status,
+		&i.FailureReason,
+		&i.CreatedAt,
+	)
+	return i, err
 }
 
 const softDeleteProperty = `-- name: SoftDeleteProperty :one
@@ -1149,6 +6302,147 @@ func (q *Queries) SoftDeleteProperty(ctx context.Context, arg SoftDeleteProperty
 	return id, err
 }
 
+const takeOverIdempotencyKey = `-- name: TakeOverIdempotencyKey :execrows
+UPDATE idempotency_keys
+SET heartbeat_at = NOW()
+WHERE user_id = $1 AND idempotency_key = $2 AND request_fingerprint = $3
+  AND status = 'processing' AND heartbeat_at < $4
+`
+
+type TakeOverIdempotencyKeyParams struct {
+	UserID             int32            `json:"user_id"`
+	IdempotencyKey     string           `json:"idempotency_key"`
+	RequestFingerprint string           `json:"request_fingerprint"`
+	StaleBefore        pgtype.Timestamp `json:"stale_before"`
+}
+
+// Reprend une clé restée 'processing' sans signe de vie depuis @stale_before (instance arrêtée pendant la
+// requête) ; 0 ligne si la requête s'exécute encore ou si une autre l'a reprise entre-temps.
+func (q *Queries) TakeOverIdempotencyKey(ctx context.Context, arg TakeOverIdempotencyKeyParams) (int64, error) {
+	result, err := q.db.Exec(ctx, takeOverIdempotencyKey,
+		arg.UserID,
+		arg.IdempotencyKey,
+		arg.RequestFingerprint,
+		arg.StaleBefore,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const touchIdempotencyKey = `-- name: TouchIdempotencyKey :exec
+UPDATE idempotency_keys
+SET heartbeat_at = NOW()
+WHERE user_id = $1 AND idempotency_key = $2 AND status = 'processing'
+`
+
+type TouchIdempotencyKeyParams struct {
+	UserID         int32  `json:"user_id"`
+	IdempotencyKey string `json:"idempotency_key"`
+}
+
+// Signe de vie de la requête qui détient la clé : elle ne peut pas être reprise tant qu'elle s'exécute.
+func (q *Queries) TouchIdempotencyKey(ctx context.Context, arg TouchIdempotencyKeyParams) error {
+	_, err := q.db.Exec(ctx, touchIdempotencyKey, arg.UserID, arg.IdempotencyKey)
+	return err
+}
+
+const updateCatalogItem = `-- name: UpdateCatalogItem :one
+UPDATE catalog_items
+SET code = $2, kind = $3, name = $4, plan_type = $5, monthly_price_cents = $6, yearly_price_cents = $7,
+    price_cents = $8, max_properties = $9, included_credits = $10, slot_price_cents = $11,
+    valid_from = $12, valid_until = $13, updated_at = NOW()
+WHERE id = $1
+RETURNING id, code, kind, name, plan_type, monthly_price_cents, yearly_price_cents, price_cents, max_properties, included_credits, slot_price_cents, valid_from, valid_until, created_at, updated_at
+`
+
+type UpdateCatalogItemParams struct {
+	ID                int32            `json:"id"`
+	Code              string           `json:"code"`
+	Kind              string           `json:"kind"`
+	Name              string           `json:"name"`
+	PlanType          NullSubPlan      `json:"plan_type"`
+	MonthlyPriceCents pgtype.Int4      `json:"monthly_price_cents"`
+	YearlyPriceCents  pgtype.Int4      `json:"yearly_price_cents"`
+	PriceCents        pgtype.Int4      `json:"price_cents"`
+	MaxProperties     int32            `json:"max_properties"`
+	IncludedCredits   int32            `json:"included_credits"`
+	SlotPriceCents    pgtype.Int4      `json:"slot_price_cents"`
+	ValidFrom         pgtype.Timestamp `json:"valid_from"`
+	ValidUntil        pgtype.Timestamp `json:"valid_until"`
+}
+
+func (q *Queries) UpdateCatalogItem(ctx context.Context, arg UpdateCatalogItemParams) (CatalogItem, error) {
+	row := q.db.QueryRow(ctx, updateCatalogItem,
+		arg.ID,
+		arg.Code,
+		arg.Kind,
+		arg.Name,
+		arg.PlanType,
+		arg.MonthlyPriceCents,
+		arg.YearlyPriceCents,
+		arg.PriceCents,
+		arg.MaxProperties,
+		arg.IncludedCredits,
+		arg.SlotPriceCents,
+		arg.ValidFrom,
+		arg.ValidUntil,
+	)
+	var i CatalogItem
+	err := row.Scan(
+		&i.ID,
+		&i.Code,
+		&i.Kind,
+		&i.Name,
+		&i.PlanType,
+		&i.MonthlyPriceCents,
+		&i.YearlyPriceCents,
+		&i.PriceCents,
+		&i.MaxProperties,
+		&i.IncludedCredits,
+		&i.SlotPriceCents,
+		&i.ValidFrom,
+		&i.ValidUntil,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const updateGuarantorAnalysis = `-- name: UpdateGuarantorAnalysis :exec
+UPDATE solvency_guarantors
+SET status = 'completed', monthly_income = $2, analysis_json = $3
+WHERE id = $1
+`
+
+type UpdateGuarantorAnalysisParams struct {
+	ID            int32          `json:"id"`
+	MonthlyIncome pgtype.Numeric `json:"monthly_income"`
+	AnalysisJson  []byte         `json:"analysis_json"`
+}
+
+func (q *Queries) UpdateGuarantorAnalysis(ctx context.Context, arg UpdateGuarantorAnalysisParams) error {
+	_, err := q.db.Exec(ctx, updateGuarantorAnalysis, arg.ID, arg.MonthlyIncome, arg.AnalysisJson)
+	return err
+}
+
+const updateGuarantorDocuments = `-- name: UpdateGuarantorDocuments :exec
+UPDATE solvency_guarantors
+SET documents_json = $2
+WHERE id = $1
+`
+
+type UpdateGuarantorDocumentsParams struct {
+	ID            int32  `json:"id"`
+	DocumentsJson []byte `json:"documents_json"`
+}
+
+func (q *Queries) UpdateGuarantorDocuments(ctx context.Context, arg UpdateGuarantorDocumentsParams) error {
+	_, err := q.db.Exec(ctx, updateGuarantorDocuments, arg.ID, arg.DocumentsJson)
+	return err
+}
+
 const updateInvitationStatus = `-- name: UpdateInvitationStatus :exec
 UPDATE lease_invitations
 SET status = $2
@@ -1197,9 +6491,43 @@ func (q *Queries) UpdateLeaseContractURL(ctx context.Context, arg UpdateLeaseCon
 	return err
 }
 
+const updateLeasePartyShare = `-- name: UpdateLeasePartyShare :exec
+UPDATE lease_parties
+SET rent_share = $3
+WHERE id = $1 AND lease_id = $2
+`
+
+type UpdateLeasePartyShareParams struct {
+	ID        int32          `json:"id"`
+	LeaseID   int32          `json:"lease_id"`
+	RentShare pgtype.Numeric `json:"rent_share"`
+}
+
+func (q *Queries) UpdateLeasePartyShare(ctx context.Context, arg UpdateLeasePartyShareParams) error {
+	_, err := q.db.Exec(ctx, updateLeasePartyShare, arg.ID, arg.LeaseID, arg.RentShare)
+	return err
+}
+
+const updateLeaseRentSplit = `-- name: UpdateLeaseRentSplit :exec
+UPDATE leases
+SET joint_liability = $2, individual_rent_shares = $3
+WHERE id = $1
+`
+
+type UpdateLeaseRentSplitParams struct {
+	ID                   int32       `json:"id"`
+	JointLiability       pgtype.Bool `json:"joint_liability"`
+	IndividualRentShares pgtype.Bool `json:"individual_rent_shares"`
+}
+
+func (q *Queries) UpdateLeaseRentSplit(ctx context.Context, arg UpdateLeaseRentSplitParams) error {
+	_, err := q.db.Exec(ctx, updateLeaseRentSplit, arg.ID, arg.JointLiability, arg.IndividualRentShares)
+	return err
+}
+
 const updateLeaseTenant = `-- name: UpdateLeaseTenant :exec
 UPDATE leases
-SET tenant_id = $2, lease_status = 'active' -- Or 'pending_signature'
+SET tenant_id = COALESCE(tenant_id, $2), lease_status = 'active' -- Or 'pending_signature'
 WHERE id = $1
 `
 
@@ -1208,6 +6536,7 @@ type UpdateLeaseTenantParams struct {
 	TenantID pgtype.Int4 `json:"tenant_id"`
 }
 
+// Le premier locataire qui accepte reste le titulaire de leases.tenant_id, les suivants sont des colocataires
 func (q *Queries) UpdateLeaseTenant(ctx context.Context, arg UpdateLeaseTenantParams) error {
 	_, err := q.db.Exec(ctx, updateLeaseTenant, arg.ID, arg.TenantID)
 	return err
@@ -1277,42 +6606,116 @@ func (q *Queries) UpdateProperty(ctx context.Context, arg UpdatePropertyParams)
 	return i, err
 }
 
-const updateSolvencyCheckResult = `-- name: UpdateSolvencyCheckResult :exec
+const updatePropertyMediaPosition = `-- name: UpdatePropertyMediaPosition :exec
+UPDATE property_media
+SET position = $3
+WHERE id = $1 AND property_id = $2
+`
+
+type UpdatePropertyMediaPositionParams struct {
+	ID         int32 `json:"id"`
+	PropertyID int32 `json:"property_id"`
+	Position   int32 `json:"position"`
+}
+
+func (q *Queries) UpdatePropertyMediaPosition(ctx context.Context, arg UpdatePropertyMediaPositionParams) error {
+	_, err := q.db.Exec(ctx, updatePropertyMediaPosition, arg.ID, arg.PropertyID, arg.Position)
+	return err
+}
+
+const updateSolvencyCheckDocuments = `-- name: UpdateSolvencyCheckDocuments :exec
 UPDATE solvency_checks
-SET status = $2, score_result = $3, report_url = $4
+SET documents_json = $2, missing_documents = $3, status = $4
 WHERE id = $1
 `
 
-type UpdateSolvencyCheckResultParams struct {
-	ID          int32              `json:"id"`
-	Status      NullSolvencyStatus `json:"status"`
-	ScoreResult pgtype.Int4        `json:"score_result"`
-	ReportUrl   pgtype.Text        `json:"report_url"`
+type UpdateSolvencyCheckDocumentsParams struct {
+	ID               int32              `json:"id"`
+	DocumentsJson    []byte             `json:"documents_json"`
+	MissingDocuments []byte             `json:"missing_documents"`
+	Status           NullSolvencyStatus `json:"status"`
 }
 
-func (q *Queries) UpdateSolvencyCheckResult(ctx context.Context, arg UpdateSolvencyCheckResultParams) error {
-	_, err := q.db.Exec(ctx, updateSolvencyCheckResult,
+func (q *Queries) UpdateSolvencyCheckDocuments(ctx context.Context, arg UpdateSolvencyCheckDocumentsParams) error {
+	_, err := q.db.Exec(ctx, updateSolvencyCheckDocuments,
 		arg.ID,
+		arg.DocumentsJson,
+		arg.MissingDocuments,
+		arg.Status,
+	)
+	return err
+}
+
+const updateSolvencyCheckProfile = `-- name: UpdateSolvencyCheckProfile :exec
+UPDATE solvency_checks
+SET employment_type = $2, guarantee_type = $3
+WHERE id = $1
+`
+
+type UpdateSolvencyCheckProfileParams struct {
+	ID             int32       `json:"id"`
+	EmploymentType pgtype.Text `json:"employment_type"`
+	GuaranteeType  pgtype.Text `json:"guarantee_type"`
+}
+
+func (q *Queries) UpdateSolvencyCheckProfile(ctx context.Context, arg UpdateSolvencyCheckProfileParams) error {
+	_, err := q.db.Exec(ctx, updateSolvencyCheckProfile, arg.ID, arg.EmploymentType, arg.GuaranteeType)
+	return err
+}
+
+const updateSolvencyCheckResult = `-- name: UpdateSolvencyCheckResult :execrows
+UPDATE solvency_checks
+SET status = $1, score_result = $2, report_url = $3,
+    analysis_json = $4, policy_results = $5,
+    combined_score = $6
+WHERE id = $7 AND status = $8
+`
+
+type UpdateSolvencyCheckResultParams struct {
+	Status        NullSolvencyStatus `json:"status"`
+	ScoreResult   pgtype.Int4        `json:"score_result"`
+	ReportUrl     pgtype.Text        `json:"report_url"`
+	AnalysisJson  []byte             `json:"analysis_json"`
+	PolicyResults []byte             `json:"policy_results"`
+	CombinedScore pgtype.Int4        `json:"combined_score"`
+	ID            int32              `json:"id"`
+	CurrentStatus NullSolvencyStatus `json:"current_status"`
+}
+
+// Only while the check is still in the status the decision was made from: a check cancelled or expired
+// (and refunded) in the meantime is left as it is.
+func (q *Queries) UpdateSolvencyCheckResult(ctx context.Context, arg UpdateSolvencyCheckResultParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateSolvencyCheckResult,
 		arg.Status,
 		arg.ScoreResult,
 		arg.ReportUrl,
+		arg.AnalysisJson,
+		arg.PolicyResults,
+		arg.CombinedScore,
+		arg.ID,
+		arg.CurrentStatus,
 	)
-	return err
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
-const updateSubscriptionLimit = `-- name: UpdateSubscriptionLimit :exec
-UPDATE subscriptions
-SET max_properties_limit = max_properties_limit + $2
-WHERE user_id = $1 AND status = 'active'
+const updateSubscriptionItem = `-- name: UpdateSubscriptionItem :exec
+UPDATE subscription_items
+SET quantity = $2, unit_price_cents = $3, scheduled_quantity = NULL, updated_at = NOW()
+WHERE id = $1
 `
 
-type UpdateSubscriptionLimitParams struct {
-	UserID             pgtype.Int4 `json:"user_id"`
-	MaxPropertiesLimit pgtype.Int4 `json:"max_properties_limit"`
+type UpdateSubscriptionItemParams struct {
+	ID             int32 `json:"id"`
+	Quantity       int32 `json:"quantity"`
+	UnitPriceCents int32 `json:"unit_price_cents"`
 }
 
-func (q *Queries) UpdateSubscriptionLimit(ctx context.Context, arg UpdateSubscriptionLimitParams) error {
-	_, err := q.db.Exec(ctx, updateSubscriptionLimit, arg.UserID, arg.MaxPropertiesLimit)
+// Applies the quantity and price of the period starting; a removal scheduled is done.
+func (q *Queries) UpdateSubscriptionItem(ctx context.Context, arg UpdateSubscriptionItemParams) error {
+	_, err := q.db.Exec(ctx, updateSubscriptionItem, arg.ID, arg.Quantity, arg.UnitPriceCents)
 	return err
 }
 
@@ -1344,3 +6747,179 @@ func (q *Queries) UpdateUserPromotion(ctx context.Context, arg UpdateUserPromoti
 	)
 	return err
 }
+
+const upsertOwnerSolvencyPolicy = `-- name: UpsertOwnerSolvencyPolicy :one
+INSERT INTO solvency_policies (
+    owner_id, income_multiplier, include_charges, count_guarantor_income,
+    min_employment_type, accepted_guarantees, require_guarantee
+) VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (owner_id) WHERE property_id IS NULL DO UPDATE SET
+    income_multiplier = EXCLUDED.income_multiplier,
+    include_charges = EXCLUDED.include_charges,
+    count_guarantor_income = EXCLUDED.count_guarantor_income,
+    min_employment_type = EXCLUDED.min_employment_type,
+    accepted_guarantees = EXCLUDED.accepted_guarantees,
+    require_guarantee = EXCLUDED.require_guarantee,
+    updated_at = NOW()
+RETURNING id, owner_id, property_id, income_multiplier, include_charges, count_guarantor_income, min_employment_type, accepted_guarantees, require_guarantee, created_at, updated_at
+`
+
+type UpsertOwnerSolvencyPolicyParams struct {
+	OwnerID              int32          `json:"owner_id"`
+	IncomeMultiplier     pgtype.Numeric `json:"income_multiplier"`
+	IncludeCharges       bool           `json:"include_charges"`
+	CountGuarantorIncome bool           `json:"count_guarantor_income"`
+	MinEmploymentType    pgtype.Text    `json:"min_employment_type"`
+	AcceptedGuarantees   []byte         `json:"accepted_guarantees"`
+	RequireGuarantee     bool           `json:"require_guarantee"`
+}
+
+func (q *Queries) UpsertOwnerSolvencyPolicy(ctx context.Context, arg UpsertOwnerSolvencyPolicyParams) (SolvencyPolicy, error) {
+	row := q.db.QueryRow(ctx, upsertOwnerSolvencyPolicy,
+		arg.OwnerID,
+		arg.IncomeMultiplier,
+		arg.IncludeCharges,
+		arg.CountGuarantorIncome,
+		arg.MinEmploymentType,
+		arg.AcceptedGuarantees,
+		arg.RequireGuarantee,
+	)
+	var i SolvencyPolicy
+	err := row.Scan(
+		&i.ID,
+		&i.OwnerID,
+		&i.PropertyID,
+		&i.IncomeMultiplier,
+		&i.IncludeCharges,
+		&i.CountGuarantorIncome,
+		&i.MinEmploymentType,
+		&i.AcceptedGuarantees,
+		&i.RequireGuarantee,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const upsertPropertySolvencyPolicy = `-- name: UpsertPropertySolvencyPolicy :one
+INSERT INTO solvency_policies (
+    owner_id, property_id, income_multiplier, include_charges, count_guarantor_income,
+    min_employment_type, accepted_guarantees, require_guarantee
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (property_id) DO UPDATE SET
+    income_multiplier = EXCLUDED.income_multiplier,
+    include_charges = EXCLUDED.include_charges,
+    count_guarantor_income = EXCLUDED.count_guarantor_income,
+    min_employment_type = EXCLUDED.min_employment_type,
+    accepted_guarantees = EXCLUDED.accepted_guarantees,
+    require_guarantee = EXCLUDED.require_guarantee,
+    updated_at = NOW()
+RETURNING id, owner_id, property_id, income_multiplier, include_charges, count_guarantor_income, min_employment_type, accepted_guarantees, require_guarantee, created_at, updated_at
+`
+
+type UpsertPropertySolvencyPolicyParams struct {
+	OwnerID              int32          `json:"owner_id"`
+	PropertyID           pgtype.Int4    `json:"property_id"`
+	IncomeMultiplier     pgtype.Numeric `json:"income_multiplier"`
+	IncludeCharges       bool           `json:"include_charges"`
+	CountGuarantorIncome bool           `json:"count_guarantor_income"`
+	MinEmploymentType    pgtype.Text    `json:"min_employment_type"`
+	AcceptedGuarantees   []byte         `json:"accepted_guarantees"`
+	RequireGuarantee     bool           `json:"require_guarantee"`
+}
+
+func (q *Queries) UpsertPropertySolvencyPolicy(ctx context.Context, arg UpsertPropertySolvencyPolicyParams) (SolvencyPolicy, error) {
+	row := q.db.QueryRow(ctx, upsertPropertySolvencyPolicy,
+		arg.OwnerID,
+		arg.PropertyID,
+		arg.IncomeMultiplier,
+		arg.IncludeCharges,
+		arg.CountGuarantorIncome,
+		arg.MinEmploymentType,
+		arg.AcceptedGuarantees,
+		arg.RequireGuarantee,
+	)
+	var i SolvencyPolicy
+	err := row.Scan(
+		&i.ID,
+		&i.OwnerID,
+		&i.PropertyID,
+		&i.IncomeMultiplier,
+		&i.IncludeCharges,
+		&i.CountGuarantorIncome,
+		&i.MinEmploymentType,
+		&i.AcceptedGuarantees,
+		&i.RequireGuarantee,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const upsertTenantDossier = `-- name: UpsertTenantDossier :one
+INSERT INTO tenant_dossiers (
+    user_id, source_check_id, documents_json, analysis_json, employment_type, guarantee_type, verified_at, expires_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+)
+ON CONFLICT (user_id) DO UPDATE SET
+    source_check_id = EXCLUDED.source_check_id,
+    documents_json = EXCLUDED.documents_json,
+    analysis_json = EXCLUDED.analysis_json,
+    employment_type = EXCLUDED.employment_type,
+    guarantee_type = EXCLUDED.guarantee_type,
+    verified_at = EXCLUDED.verified_at,
+    expires_at = EXCLUDED.expires_at,
+    updated_at = NOW()
+RETURNING id, user_id, source_check_id, documents_json, analysis_json, employment_type, guarantee_type, verified_at, expires_at, created_at, updated_at
+`
+
+type UpsertTenantDossierParams struct {
+	UserID         int32            `json:"user_id"`
+	SourceCheckID  pgtype.Int4      `json:"source_check_id"`
+	DocumentsJson  []byte           `json:"documents_json"`
+	AnalysisJson   []byte           `json:"analysis_json"`
+	EmploymentType pgtype.Text      `json:"employment_type"`
+	GuaranteeType  pgtype.Text      `json:"guarantee_type"`
+	VerifiedAt     pgtype.Timestamp `json:"verified_at"`
+	ExpiresAt      pgtype.Timestamp `json:"expires_at"`
+}
+
+func (q *Queries) UpsertTenantDossier(ctx context.Context, arg UpsertTenantDossierParams) (TenantDossier, error) {
+	row := q.db.QueryRow(ctx, upsertTenantDossier,
+		arg.UserID,
+		arg.SourceCheckID,
+		arg.DocumentsJson,
+		arg.AnalysisJson,
+		arg.EmploymentType,
+		arg.GuaranteeType,
+		arg.VerifiedAt,
+		arg.ExpiresAt,
+	)
+	var i TenantDossier
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.SourceCheckID,
+		&i.DocumentsJson,
+		&i.AnalysisJson,
+		&i.EmploymentType,
+		&i.GuaranteeType,
+		&i.VerifiedAt,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const voidInvoice = `-- name: VoidInvoice :exec
+UPDATE invoices
+SET status = 'void'
+WHERE id = $1
+`
+
+func (q *Queries) VoidInvoice(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, voidInvoice, id)
+	return err
+}
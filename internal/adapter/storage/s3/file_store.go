@@ -0,0 +1,320 @@
+// Package s3 implements FileStorage on any S3-compatible object store (AWS S3, MinIO, Scaleway, OVH...).
+// It talks to the REST API directly (SigV4) to avoid pulling a full SDK for five calls.
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const defaultPresignTTL = 15 * time.Minute
+
+// Config describes the bucket to use. See ConfigFromEnv for the matching variables.
+type Config struct {
+	Endpoint        string // e.g. https://s3.eu-west-3.amazonaws.com or http://localhost:9000
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// PathStyle addresses objects as endpoint/bucket/key (MinIO) instead of bucket.endpoint/key.
+	PathStyle bool
+	// SSE is the server-side encryption mode: "" (bucket default), "AES256" or "aws:kms".
+	SSE      string
+	KMSKeyID string // Only with SSE "aws:kms"; empty uses the account default key
+	// PresignTTL is the lifetime of presigned download URLs.
+	PresignTTL time.Duration
+}
+
+// ConfigFromEnv reads the S3_* configuration variables.
+func ConfigFromEnv() Config {
+	return Config{
+		Endpoint:        viper.GetString("S3_ENDPOINT"),
+		Region:          viper.GetString("S3_REGION"),
+		Bucket:          viper.GetString("S3_BUCKET"),
+		AccessKeyID:     viper.GetString("S3_ACCESS_KEY_ID"),
+		SecretAccessKey: viper.GetString("S3_SECRET_ACCESS_KEY"),
+		PathStyle:       viper.GetBool("S3_PATH_STYLE"),
+		SSE:             viper.GetString("S3_SSE"),
+		KMSKeyID:        viper.GetString("S3_SSE_KMS_KEY_ID"),
+		PresignTTL:      viper.GetDuration("S3_PRESIGN_TTL"),
+	}
+}
+
+type FileStore struct {
+	cfg      Config
+	endpoint *url.URL
+	signer   signer
+	client   *http.Client
+	now      func() time.Time
+}
+
+func NewFileStore() (*FileStore, error) {
+	return New(ConfigFromEnv(), nil)
+}
+
+// New builds a store from an explicit configuration. A nil client uses a default one with a timeout.
+func New(cfg Config, client *http.Client) (*FileStore, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, fmt.Errorf("s3 storage requires an endpoint and a bucket")
+	}
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, fmt.Errorf("s3 storage requires credentials")
+	}
+	switch cfg.SSE {
+	case "", "AES256", "aws:kms":
+	default:
+		return nil, fmt.Errorf("unsupported s3 server-side encryption %q", cfg.SSE)
+	}
+	if cfg.KMSKeyID != "" && cfg.SSE != "aws:kms" {
+		return nil, fmt.Errorf("a KMS key id requires S3_SSE=aws:kms")
+	}
+
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint %q", cfg.Endpoint)
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.PresignTTL <= 0 {
+		cfg.PresignTTL = defaultPresignTTL
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second}
+	}
+
+	return &FileStore{
+		cfg:      cfg,
+		endpoint: endpoint,
+		signer:   signer{accessKeyID: cfg.AccessKeyID, secretAccessKey: cfg.SecretAccessKey, region: cfg.Region},
+		client:   client,
+		now:      time.Now,
+	}, nil
+}
+
+// objectURL returns the URL of a key (or of the bucket when key is empty).
+func (s *FileStore) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	if s.cfg.PathStyle {
+		u.Path = "/" + s.cfg.Bucket + "/" + key
+	} else {
+		u.Host = s.cfg.Bucket + "." + u.Host
+		u.Path = "/" + key
+	}
+	u.RawPath = uriEncode(u.Path, false)
+	return &u
+}
+
+func validKey(filename string) (string, error) {
+	key := strings.TrimPrefix(path.Clean("/"+filename), "/")
+	if key == "" || key != filename {
+		return "", fmt.Errorf("invalid file name %q", filename)
+	}
+	return key, nil
+}
+
+func (s *FileStore) do(method string, u *url.URL, body []byte, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(context.Background(), method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.ContentLength = int64(len(body))
+
+	payloadHash := emptyPayloadSHA
+	if len(body) > 0 {
+		payloadHash = sha256Hex(body)
+	}
+	s.signer.sign(req, payloadHash, s.now())
+
+	return s.client.Do(req)
+}
+
+// s3Error reads the XML error document returned by S3.
+func s3Error(op, key string, resp *http.Response) error {
+	var body struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if xml.Unmarshal(raw, &body) == nil && body.Code != "" {
+		return fmt.Errorf("s3 %s %s: %s (%s)", op, key, body.Code, body.Message)
+	}
+	return fmt.Errorf("s3 %s %s: unexpected status %s", op, key, resp.Status)
+}
+
+// contentType guesses the stored Content-Type from the extension, then from the content.
+func contentType(key string, content []byte) string {
+	if ct := mime.TypeByExtension(path.Ext(key)); ct != "" {
+		return ct
+	}
+	return http.DetectContentType(content)
+}
+
+// Save uploads the object with its Content-Type and the configured server-side encryption.
+// It returns the s3:// URI of the object.
+func (s *FileStore) Save(filename string, content []byte) (string, error) {
+	key, err := validKey(filename)
+	if err != nil {
+		return "", err
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", contentType(key, content))
+	if s.cfg.SSE != "" {
+		header.Set("X-Amz-Server-Side-Encryption", s.cfg.SSE)
+	}
+	if s.cfg.KMSKeyID != "" {
+		header.Set("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id", s.cfg.KMSKeyID)
+	}
+
+	resp, err := s.do(http.MethodPut, s.objectURL(key), content, header)
+	if err != nil {
+		return "", fmt.Errorf("failed to write file %s: %w", filename, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", s3Error("put", key, resp)
+	}
+	return "s3://" + s.cfg.Bucket + "/" + key, nil
+}
+
+func (s *FileStore) Get(filename string) ([]byte, error) {
+	reader, err := s.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// Open streams the object. The caller must close the reader.
+func (s *FileStore) Open(filename string) (io.ReadCloser, error) {
+	key, err := validKey(filename)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(http.MethodGet, s.objectURL(key), nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", filename, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, s3Error("get", key, resp)
+	}
+	return resp.Body, nil
+}
+
+func (s *FileStore) Exists(filename string) bool {
+	key, err := validKey(filename)
+	if err != nil {
+		return false
+	}
+	resp, err := s.do(http.MethodHead, s.objectURL(key), nil, nil)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+// Delete removes an object. S3 treats deleting a missing key as a success, like the local store.
+func (s *FileStore) Delete(filename string) error {
+	key, err := validKey(filename)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(http.MethodDelete, s.objectURL(key), nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete file %s: %w", filename, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return s3Error("delete", key, resp)
+	}
+	return nil
+}
+
+type listBucketResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List returns the keys starting with prefix, following ListObjectsV2 pagination.
+func (s *FileStore) List(prefix string) ([]string, error) {
+	var keys []string
+	token := ""
+	for {
+		u := s.objectURL("")
+		query := url.Values{}
+		query.Set("list-type", "2")
+		if prefix != "" {
+			query.Set("prefix", prefix)
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		u.RawQuery = canonicalQuery(query)
+
+		resp, err := s.do(http.MethodGet, u, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list files: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			err := s3Error("list", prefix, resp)
+			resp.Body.Close()
+			return nil, err
+		}
+
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode list response: %w", err)
+		}
+
+		for _, c := range result.Contents {
+			keys = append(keys, c.Key)
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// PresignGet returns a time-limited URL allowing a direct download of the object from the bucket.
+// ttl <= 0 uses the configured S3_PRESIGN_TTL.
+func (s *FileStore) PresignGet(filename string, ttl time.Duration) (string, error) {
+	key, err := validKey(filename)
+	if err != nil {
+		return "", err
+	}
+	if ttl <= 0 {
+		ttl = s.cfg.PresignTTL
+	}
+	// SigV4 caps presigned URLs at 7 days
+	if ttl > 7*24*time.Hour {
+		return "", fmt.Errorf("presigned url lifetime cannot exceed 7 days")
+	}
+	return s.signer.presign(s.objectURL(key), ttl, s.now()), nil
+}
@@ -0,0 +1,193 @@
+// Package s3test provides an in-process fake of the S3 object API, for tests that need a bucket
+// without running MinIO. It supports path-style PUT/GET/HEAD/DELETE object and ListObjectsV2.
+package s3test
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Object is a stored object with the metadata the client sent.
+type Object struct {
+	Data        []byte
+	ContentType string
+	SSE         string
+	KMSKeyID    string
+}
+
+type Server struct {
+	*httptest.Server
+
+	Bucket      string
+	AccessKeyID string
+	// PageSize limits ListObjectsV2 pages, to exercise pagination. Defaults to 1000 like S3.
+	PageSize int
+
+	mu      sync.Mutex
+	objects map[string]Object
+}
+
+// NewServer starts a fake serving a single bucket. Requests must be signed with accessKeyID.
+func NewServer(bucket, accessKeyID string) *Server {
+	s := &Server{Bucket: bucket, AccessKeyID: accessKeyID, PageSize: 1000, objects: make(map[string]Object)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Object returns a stored object.
+func (s *Server) Object(key string) (Object, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.objects[key]
+	return obj, ok
+}
+
+// Keys returns all stored keys, sorted.
+func (s *Server) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, 0, len(s.objects))
+	for k := range s.objects {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte("<Error><Code>" + code + "</Code><Message>" + message + "</Message></Error>"))
+}
+
+// authorized checks that the request carries SigV4 credentials for the expected key,
+// and that presigned URLs are not expired. Signatures themselves are not recomputed.
+func (s *Server) authorized(r *http.Request) (bool, string) {
+	credential := "Credential=" + s.AccessKeyID + "/"
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		return strings.HasPrefix(auth, "AWS4-HMAC-SHA256 ") && strings.Contains(auth, credential), "SignatureDoesNotMatch"
+	}
+
+	q := r.URL.Query()
+	if !strings.HasPrefix(q.Get("X-Amz-Credential"), s.AccessKeyID+"/") || q.Get("X-Amz-Signature") == "" {
+		return false, "AccessDenied"
+	}
+	date, err := time.Parse("20060102T150405Z", q.Get("X-Amz-Date"))
+	expires, err2 := strconv.Atoi(q.Get("X-Amz-Expires"))
+	if err != nil || err2 != nil || time.Now().After(date.Add(time.Duration(expires)*time.Second)) {
+		return false, "AccessDenied"
+	}
+	return true, ""
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	if ok, code := s.authorized(r); !ok {
+		writeError(w, http.StatusForbidden, code, "request is not authorized")
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	bucket, key, _ := strings.Cut(path, "/")
+	if bucket != s.Bucket {
+		writeError(w, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist")
+		return
+	}
+
+	if key == "" {
+		if r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2" {
+			s.list(w, r)
+			return
+		}
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "unsupported bucket operation")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "IncompleteBody", err.Error())
+			return
+		}
+		s.objects[key] = Object{
+			Data:        data,
+			ContentType: r.Header.Get("Content-Type"),
+			SSE:         r.Header.Get("X-Amz-Server-Side-Encryption"),
+			KMSKeyID:    r.Header.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"),
+		}
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet, http.MethodHead:
+		obj, ok := s.objects[key]
+		if !ok {
+			writeError(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+			return
+		}
+		w.Header().Set("Content-Type", obj.ContentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj.Data)))
+		if obj.SSE != "" {
+			w.Header().Set("X-Amz-Server-Side-Encryption", obj.SSE)
+		}
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(obj.Data)
+		}
+	case http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "unsupported object operation")
+	}
+}
+
+type listResult struct {
+	XMLName               xml.Name `xml:"ListBucketResult"`
+	Name                  string   `xml:"Name"`
+	Prefix                string   `xml:"Prefix"`
+	KeyCount              int      `xml:"KeyCount"`
+	IsTruncated           bool     `xml:"IsTruncated"`
+	NextContinuationToken string   `xml:"NextContinuationToken,omitempty"`
+	Contents              []struct {
+		Key  string `xml:"Key"`
+		Size int    `xml:"Size"`
+	} `xml:"Contents"`
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("prefix")
+	after := r.URL.Query().Get("continuation-token")
+
+	var keys []string
+	for _, k := range s.Keys() {
+		if strings.HasPrefix(k, prefix) && k > after {
+			keys = append(keys, k)
+		}
+	}
+
+	result := listResult{Name: s.Bucket, Prefix: prefix}
+	if len(keys) > s.PageSize {
+		keys = keys[:s.PageSize]
+		result.IsTruncated = true
+		result.NextContinuationToken = keys[len(keys)-1]
+	}
+	for _, k := range keys {
+		obj, _ := s.Object(k)
+		result.Contents = append(result.Contents, struct {
+			Key  string `xml:"Key"`
+			Size int    `xml:"Size"`
+		}{Key: k, Size: len(obj.Data)})
+	}
+	result.KeyCount = len(keys)
+
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(result)
+}
@@ -1,6 +1,8 @@
 package app
 
 import (
+	"context"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -15,10 +17,14 @@ import (
 
 	"seculoc-back/internal/adapter/http/handler"
 	"seculoc-back/internal/adapter/http/middleware"
-	"seculoc-back/internal/adapter/storage/local"
+	"seculoc-back/internal/adapter/openbanking/fake"
+	fakepayment "seculoc-back/internal/adapter/payment/fake"
+	"seculoc-back/internal/adapter/storage"
+	"seculoc-back/internal/adapter/storage/encrypted"
 	"seculoc-back/internal/adapter/storage/postgres"
 	"seculoc-back/internal/core/service"
 	"seculoc-back/internal/platform/email"
+	"seculoc-back/internal/platform/scheduler"
 
 	docs "seculoc-back/docs" // Swagger docs generated by swaggo
 
@@ -38,16 +44,43 @@ func NewServer(pool *pgxpool.Pool, log *zap.Logger) *gin.Engine {
 		frontendURL = "http://localhost:5173"
 	}
 
-	fileStore, err := local.NewFileStore()
+	fileStore, err := newFileStorage()
 	if err != nil {
 		log.Fatal("failed to initialize file store", zap.Error(err))
 	}
+	if _, ok := fileStore.(*encrypted.FileStore); !ok {
+		log.Warn("STORAGE_MASTER_KEYS is not set: candidate documents and contracts are stored unencrypted")
+	}
+	if err := checkDocumentLinkSecret(); err != nil {
+		log.Fatal("invalid document link configuration", zap.Error(err))
+	}
 	leaseService := service.NewLeaseService(txManager, log, fileStore)
 
 	userService := service.NewUserService(txManager, log, emailSender, frontendURL, leaseService)
 	propService := service.NewPropertyService(txManager, log)
-	subService := service.NewSubscriptionService(txManager, log)
-	solvService := service.NewSolvencyService(txManager, emailSender, log)
+	paymentProvider, err := newPaymentProvider()
+	if err != nil {
+		log.Fatal("failed to initialize payment provider", zap.Error(err))
+	}
+	paymentService := service.NewPaymentService(txManager, paymentProvider, emailSender, log)
+	subService := service.NewSubscriptionService(txManager, paymentService, log)
+	bankProvider, err := newOpenBankingProvider()
+	if err != nil {
+		log.Fatal("failed to initialize open banking provider", zap.Error(err))
+	}
+	solvService := service.NewSolvencyService(txManager, emailSender, log, fileStore, bankProvider)
+	// Raw transactions can only be trusted when they are fake anyway, which never happens in release mode
+	solvService.AllowRawTransactions = bankProvider.Name() == "fake" && !releaseMode()
+	mediaService := service.NewPropertyMediaService(txManager, fileStore, emailSender, log)
+	docService := service.NewDocumentService(txManager, fileStore, log)
+	retentionService := service.NewRetentionService(txManager, fileStore, log)
+	accountService := service.NewAccountService(txManager, fileStore, log)
+	catalogService := service.NewCatalogService(txManager, log)
+	billingService := service.NewBillingService(txManager, emailSender, paymentService, log)
+	billingService.ExpirePlanCredits = viper.GetBool("BILLING_EXPIRE_PLAN_CREDITS")
+	idempotencyService := service.NewIdempotencyService(txManager, log)
+	invoiceService := service.NewInvoiceService(txManager, fileStore, log)
+	creditService := service.NewCreditService(txManager, log)
 
 	// 3. Adapters (Handlers)
 	userHandler := handler.NewUserHandler(userService)
@@ -56,9 +89,32 @@ func NewServer(pool *pgxpool.Pool, log *zap.Logger) *gin.Engine {
 	solvHandler := handler.NewSolvencyHandler(solvService)
 	invHandler := handler.NewInvitationHandler(userService)
 	leaseHandler := handler.NewLeaseHandler(leaseService)
+	mediaHandler := handler.NewPropertyMediaHandler(mediaService)
+	docHandler := handler.NewDocumentHandler(docService, leaseService, frontendURL)
+	accountHandler := handler.NewAccountHandler(accountService)
+	catalogHandler := handler.NewCatalogHandler(catalogService)
+	paymentHandler := handler.NewPaymentHandler(paymentService)
+	invoiceHandler := handler.NewInvoiceHandler(invoiceService)
+	creditHandler := handler.NewCreditHandler(creditService)
+
+	// Background Jobs
+	jobs := scheduler.New(log)
+	// Several instances may run: each job runs on one at a time
+	jobs.Locker = postgres.NewAdvisoryLocker(pool)
+	jobs.Register("diagnostic_expiry_reminders", 24*time.Hour, mediaService.SendDiagnosticReminders)
+	jobs.Register("solvency_check_reminders", time.Hour, solvService.SendCheckReminders)
+	jobs.Register("solvency_check_expiry", time.Hour, solvService.ExpireStaleChecks)
+	jobs.Register("retention_purge", 24*time.Hour, retentionService.PurgeAll)
+	jobs.Register("subscription_renewals", time.Hour, billingService.RenewSubscriptions)
+	jobs.Register("subscription_payment_retries", time.Hour, billingService.RetryFailedRenewals)
+	jobs.Register("plan_credit_grants", time.Hour, billingService.GrantPlanCredits)
+	jobs.Register("idempotency_key_purge", time.Hour, idempotencyService.PurgeExpired)
+	jobs.Register("invoice_archive", 15*time.Minute, invoiceService.ArchiveIssuedInvoices)
+	jobs.Register("credit_balance_reconciliation", 24*time.Hour, creditService.ReconcileBalances)
+	startJobs(jobs)
 
 	// 4. HTTP Router (Gin)
-	if viper.GetString("GIN_MODE") == "release" {
+	if releaseMode() {
 		gin.SetMode(gin.ReleaseMode)
 	}
 	r := gin.New()
@@ -74,9 +130,29 @@ func NewServer(pool *pgxpool.Pool, log *zap.Logger) *gin.Engine {
 	// Public Routes
 	api := r.Group("/api/v1")
 	{
+		api.GET("/plans", catalogHandler.ListPlans)
 		api.GET("/invitations/:token", invHandler.GetInvitation)
 		api.GET("/solvency/public/check/:token", solvHandler.GetCheckByToken)
 		api.POST("/solvency/public/check/:token/callback", solvHandler.ProcessCallback)
+		api.POST("/solvency/public/check/:token/documents", solvHandler.UploadCandidateDocument)
+		api.POST("/solvency/public/check/:token/open-banking/consent", solvHandler.StartBankConsent)
+		api.POST("/solvency/public/check/:token/open-banking/complete", solvHandler.CompleteBankConsent)
+		api.PUT("/solvency/public/check/:token/profile", solvHandler.UpdateCandidateProfile)
+		api.POST("/solvency/public/check/:token/guarantors", solvHandler.AddGuarantorByToken)
+		api.GET("/solvency/public/guarantor/:token", solvHandler.GetGuarantorByToken)
+		api.POST("/solvency/public/guarantor/:token/documents", solvHandler.UploadGuarantorDocument)
+		api.DELETE("/solvency/public/guarantor/:token/documents/:docId", solvHandler.DeleteGuarantorDocument)
+		api.POST("/solvency/public/guarantor/:token/submit", solvHandler.SubmitGuarantorDocuments)
+		api.POST("/solvency/public/guarantor/:token/open-banking/consent", solvHandler.StartGuarantorBankConsent)
+		api.POST("/solvency/public/guarantor/:token/open-banking/complete", solvHandler.CompleteGuarantorBankConsent)
+		api.GET("/solvency/public/guarantor/:token/mention", solvHandler.GetGuaranteeMention)
+		api.POST("/solvency/public/guarantor/:token/mention", solvHandler.SignGuaranteeMention)
+		api.GET("/solvency/public/guarantor/:token/deed", solvHandler.DownloadGuaranteeDeed)
+		api.POST("/webhooks/open-banking", solvHandler.OpenBankingWebhook)
+		api.POST("/webhooks/payments", paymentHandler.Webhook)
+		api.DELETE("/solvency/public/check/:token/documents/:docId", solvHandler.DeleteCandidateDocument)
+		// Signed document links (the HMAC signature replaces the bearer token)
+		api.GET("/documents/links/:linkId", docHandler.OpenLink)
 
 		authGroup := api.Group("/auth")
 		{
@@ -86,35 +162,107 @@ func NewServer(pool *pgxpool.Pool, log *zap.Logger) *gin.Engine {
 
 		// Protected Routes
 		protected := api.Group("")
-		protected.Use(middleware.AuthMiddleware())
+		protected.Use(middleware.AuthMiddleware(accountService))
+		// Money-moving routes: retries with the same Idempotency-Key replay the first response
+		idempotent := middleware.Idempotency(idempotencyService)
 		{
 			protected.POST("/auth/switch-context", userHandler.SwitchContext)
+			// Account (RGPD)
+			protected.GET("/me/export", accountHandler.Export)
+			protected.DELETE("/me", accountHandler.Delete)
+			protected.POST("/me/payment-method/sepa", paymentHandler.SetupSepaMandate)
+			protected.GET("/me/invoices", invoiceHandler.List)
+			protected.GET("/me/invoices/:id/pdf", invoiceHandler.Download)
+			protected.GET("/me/credits/transactions", creditHandler.ListTransactions)
+			protected.GET("/me/credits/accounts", creditHandler.ListAccounts)
+			protected.POST("/me/credits/transfers", idempotent, creditHandler.TransferCredits)
 			// Properties
 			protected.POST("/properties", propHandler.Create)
 			protected.GET("/properties", propHandler.List)
 			protected.PUT("/properties/:id", propHandler.Update)
 			protected.DELETE("/properties/:id", propHandler.Delete)
 
+			// Property Media (photos & diagnostics)
+			protected.GET("/properties/:id/media", mediaHandler.List)
+			protected.POST("/properties/:id/media/photos", mediaHandler.UploadPhoto)
+			protected.POST("/properties/:id/media/diagnostics", mediaHandler.UploadDiagnostic)
+			protected.PUT("/properties/:id/media/order", mediaHandler.Reorder)
+			protected.GET("/properties/:id/media/:mediaId", mediaHandler.Download)
+			protected.PUT("/properties/:id/media/:mediaId/cover", mediaHandler.SetCover)
+			protected.DELETE("/properties/:id/media/:mediaId", mediaHandler.Delete)
+
 			// Leases
 			protected.GET("/leases", leaseHandler.List)
-			protected.POST("/leases/draft", leaseHandler.CreateDraft)
+			protected.POST("/leases/draft", idempotent, leaseHandler.CreateDraft)
 			protected.GET("/leases/:id/download", leaseHandler.Download)
 			protected.GET("/leases/:id/preview", leaseHandler.Preview)
+			protected.POST("/leases/:id/links", docHandler.CreateLeaseLink)
+			protected.GET("/leases/:id/guarantors", leaseHandler.ListGuarantors)
+			protected.GET("/leases/:id/parties", leaseHandler.ListParties)
+			protected.POST("/leases/:id/parties", invHandler.InviteCoTenant)
+			protected.POST("/leases/:id/parties/:partyId/leave", leaseHandler.RecordDeparture)
+			protected.PUT("/leases/:id/rent-split", leaseHandler.SetRentSplit)
+			protected.GET("/leases/:id/rent-schedule", leaseHandler.RentSchedule)
+
+			// Documents (versions & signed links)
+			protected.GET("/documents", docHandler.List)
+			protected.POST("/documents/links", docHandler.CreateLink)
+			protected.DELETE("/documents/links/:linkId", docHandler.RevokeLink)
 
 			// Subscriptions
-			protected.POST("/subscriptions", subHandler.Subscribe)
-			protected.POST("/subscriptions/upgrade", subHandler.IncreaseLimit)
+			protected.POST("/subscriptions", idempotent, subHandler.Subscribe)
+			protected.POST("/subscriptions/upgrade", idempotent, subHandler.IncreaseLimit)
+			protected.POST("/subscriptions/slots", idempotent, subHandler.IncreaseLimit)
+			protected.DELETE("/subscriptions/slots", subHandler.RemoveSlots)
+			protected.GET("/subscriptions/current", subHandler.GetCurrent)
+			protected.GET("/subscriptions/history", subHandler.History)
+			protected.POST("/subscriptions/change", idempotent, subHandler.ChangePlan)
+			protected.POST("/subscriptions/cancel", subHandler.Cancel)
+			protected.POST("/subscriptions/resume", subHandler.Resume)
+			protected.DELETE("/subscriptions/scheduled-change", subHandler.CancelScheduledChange)
 
 			// Solvency
-			protected.POST("/solvency/check", solvHandler.CreateCheck)
+			protected.POST("/solvency/check", idempotent, solvHandler.CreateCheck)
+			protected.POST("/solvency/check/from-dossier", idempotent, solvHandler.CreateCheckFromDossier)
 			protected.POST("/solvency/check/:id/cancel", solvHandler.CancelCheck)
+			protected.POST("/solvency/check/:id/insufficient-docs", solvHandler.RequestMissingDocuments)
+			protected.GET("/solvency/check/:id/documents/:docId", solvHandler.DownloadCandidateDocument)
+			protected.GET("/solvency/check/:id/report", solvHandler.DownloadReport)
+			protected.PUT("/solvency/check/:id/selection", solvHandler.SetCandidateSelection)
+			protected.POST("/solvency/check/:id/guarantors", solvHandler.AddGuarantor)
+			protected.GET("/solvency/check/:id/guarantors", solvHandler.ListGuarantors)
+			protected.DELETE("/solvency/check/:id/guarantors/:guarantorId", solvHandler.RemoveGuarantor)
+			protected.GET("/solvency/check/:id/guarantors/:guarantorId/documents/:docId", solvHandler.DownloadGuarantorDocument)
 			protected.GET("/solvency/checks", solvHandler.ListChecks)
-			protected.POST("/solvency/credits", solvHandler.BuyCredits)
+			protected.POST("/solvency/credits", idempotent, paymentHandler.BuyCredits)
+			protected.GET("/solvency/dossier", solvHandler.GetDossier)
+			protected.POST("/solvency/dossier", solvHandler.BuildDossier)
+			protected.DELETE("/solvency/dossier", solvHandler.DeleteDossier)
+			protected.POST("/solvency/dossier/shares", solvHandler.ShareDossier)
+			protected.DELETE("/solvency/dossier/shares/:shareId", solvHandler.RevokeDossierShare)
+			protected.GET("/solvency/policy", solvHandler.GetPolicy)
+			protected.PUT("/solvency/policy", solvHandler.SetPolicy)
+			protected.GET("/properties/:id/solvency-policy", solvHandler.GetPolicy)
+			protected.PUT("/properties/:id/solvency-policy", solvHandler.SetPolicy)
+			protected.DELETE("/properties/:id/solvency-policy", solvHandler.DeletePropertyPolicy)
+			protected.GET("/properties/:id/candidates", solvHandler.CompareCandidates)
 
 			// Invitations
 			protected.POST("/invitations", invHandler.InviteTenant)
 			protected.POST("/invitations/accept", invHandler.AcceptInvitation)
 		}
+
+		// Admin Routes (users.role = 'admin')
+		admin := api.Group("/admin")
+		admin.Use(middleware.AuthMiddleware(accountService), middleware.RequireAdmin(accountService))
+		{
+			// Catalog
+			admin.GET("/catalog", catalogHandler.List)
+			admin.POST("/catalog", catalogHandler.Create)
+			admin.PUT("/catalog/:id", catalogHandler.Update)
+			admin.DELETE("/catalog/:id", catalogHandler.Retire)
+			admin.POST("/transactions/:id/refund", paymentHandler.Refund)
+		}
 	}
 
 	// Health Check
@@ -129,6 +277,81 @@ func NewServer(pool *pgxpool.Pool, log *zap.Logger) *gin.Engine {
 	return r
 }
 
+func releaseMode() bool {
+	return viper.GetString("GIN_MODE") == "release"
+}
+
+// newFileStorage opens the document storage backend (STORAGE_DRIVER) and, when master keys are
+// configured (STORAGE_MASTER_KEYS), wraps it with envelope encryption at rest. Master keys are
+// required in release mode: identity documents and payslips are never stored in clear.
+func newFileStorage() (service.FileStorage, error) {
+	keyring, err := encrypted.KeyringFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if keyring == nil && releaseMode() {
+		return nil, fmt.Errorf("STORAGE_MASTER_KEYS is required in release mode")
+	}
+	backend, err := storage.BackendFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if keyring == nil {
+		return backend, nil
+	}
+	// Encrypted files cannot be served by presigned URLs: downloads go through the API
+	return encrypted.New(backend, keyring), nil
+}
+
+// checkDocumentLinkSecret requires DOCUMENT_LINK_SECRET in release mode: without it, no document link can
+// be signed. It must differ from JWT_SECRET.
+func checkDocumentLinkSecret() error {
+	secret := viper.GetString("DOCUMENT_LINK_SECRET")
+	if secret == "" {
+		if releaseMode() {
+			return fmt.Errorf("DOCUMENT_LINK_SECRET is required in release mode")
+		}
+		return nil
+	}
+	if secret == viper.GetString("JWT_SECRET") {
+		return fmt.Errorf("DOCUMENT_LINK_SECRET must differ from JWT_SECRET")
+	}
+	return nil
+}
+
+// newOpenBankingProvider selects the aggregator used for solvency checks (OPEN_BANKING_PROVIDER). There
+// is no default, and the fake aggregator, which lets candidates post their own transactions, is refused in
+// release mode.
+func newOpenBankingProvider() (service.OpenBankingProvider, error) {
+	switch driver := viper.GetString("OPEN_BANKING_PROVIDER"); driver {
+	case "":
+		return nil, fmt.Errorf("OPEN_BANKING_PROVIDER is required")
+	case "fake":
+		if releaseMode() {
+			return nil, fmt.Errorf("the fake open banking provider cannot be used in release mode")
+		}
+		return fake.NewFromEnv()
+	default:
+		return nil, fmt.Errorf("unknown OPEN_BANKING_PROVIDER %q", driver)
+	}
+}
+
+// newPaymentProvider selects the payment service provider (PAYMENT_PROVIDER). There is no default, and the
+// fake provider, which charges no one, is refused in release mode.
+func newPaymentProvider() (service.PaymentProvider, error) {
+	switch driver := viper.GetString("PAYMENT_PROVIDER"); driver {
+	case "":
+		return nil, fmt.Errorf("PAYMENT_PROVIDER is required")
+	case "fake":
+		if releaseMode() {
+			return nil, fmt.Errorf("the fake payment provider cannot be used in release mode")
+		}
+		return fakepayment.NewFromEnv()
+	default:
+		return nil, fmt.Errorf("unknown PAYMENT_PROVIDER %q", driver)
+	}
+}
+
 func configureCORS(r *gin.Engine) {
 	frontendURL := viper.GetString("FRONTEND_URL")
 	if frontendURL == "" {
@@ -137,8 +360,8 @@ func configureCORS(r *gin.Engine) {
 	corsConfig := cors.DefaultConfig()
 	corsConfig.AllowOrigins = []string{frontendURL}
 	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Authorization"}
-	corsConfig.ExposeHeaders = []string{"Content-Length", "Content-Disposition"}
+	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Authorization", middleware.IdempotencyKeyHeader}
+	corsConfig.ExposeHeaders = []string{"Content-Length", "Content-Disposition", middleware.IdempotentReplayedHeader}
 	corsConfig.AllowCredentials = true
 	r.Use(cors.New(corsConfig))
 }
@@ -164,3 +387,11 @@ func configureRateLimit(r *gin.Engine) {
 	// Middleware
 	r.Use(mgin.NewMiddleware(instance))
 }
+
+func startJobs(jobs *scheduler.Scheduler) {
+	// Skip in Test mode to keep E2E tests deterministic
+	if viper.GetString("GIN_MODE") == "test" {
+		return
+	}
+	jobs.Start(context.Background())
+}
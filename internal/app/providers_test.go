@@ -0,0 +1,84 @@
+package app
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"seculoc-back/internal/adapter/storage/encrypted"
+)
+
+func TestNewFileStorage_ReleaseRequiresMasterKeys(t *testing.T) {
+	t.Cleanup(viper.Reset)
+	viper.Set("STORAGE_DIR", t.TempDir())
+	viper.Set("GIN_MODE", "release")
+
+	_, err := newFileStorage()
+	assert.ErrorContains(t, err, "STORAGE_MASTER_KEYS")
+
+	viper.Set("STORAGE_MASTER_KEYS", "k1:"+base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32)))
+	store, err := newFileStorage()
+	require.NoError(t, err)
+	assert.IsType(t, &encrypted.FileStore{}, store)
+}
+
+func TestNewOpenBankingProvider(t *testing.T) {
+	t.Cleanup(viper.Reset)
+
+	_, err := newOpenBankingProvider()
+	assert.ErrorContains(t, err, "OPEN_BANKING_PROVIDER is required")
+
+	viper.Set("OPEN_BANKING_PROVIDER", "fake")
+	_, err = newOpenBankingProvider()
+	assert.ErrorContains(t, err, "OPEN_BANKING_WEBHOOK_SECRET is required")
+
+	viper.Set("OPEN_BANKING_WEBHOOK_SECRET", "secret")
+	provider, err := newOpenBankingProvider()
+	require.NoError(t, err)
+	assert.Equal(t, "fake", provider.Name())
+
+	// Candidates could post their own transactions
+	viper.Set("GIN_MODE", "release")
+	_, err = newOpenBankingProvider()
+	assert.ErrorContains(t, err, "release mode")
+}
+
+func TestNewPaymentProvider(t *testing.T) {
+	t.Cleanup(viper.Reset)
+
+	_, err := newPaymentProvider()
+	assert.ErrorContains(t, err, "PAYMENT_PROVIDER is required")
+
+	viper.Set("PAYMENT_PROVIDER", "fake")
+	_, err = newPaymentProvider()
+	assert.ErrorContains(t, err, "PAYMENT_WEBHOOK_SECRET is required")
+
+	viper.Set("PAYMENT_WEBHOOK_SECRET", "secret")
+	provider, err := newPaymentProvider()
+	require.NoError(t, err)
+	assert.Equal(t, "fake_stripe", provider.Name())
+
+	// Payments would go through without charging anyone
+	viper.Set("GIN_MODE", "release")
+	_, err = newPaymentProvider()
+	assert.ErrorContains(t, err, "release mode")
+}
+
+func TestCheckDocumentLinkSecret(t *testing.T) {
+	t.Cleanup(viper.Reset)
+	assert.NoError(t, checkDocumentLinkSecret(), "links are simply unavailable in development")
+
+	viper.Set("GIN_MODE", "release")
+	assert.ErrorContains(t, checkDocumentLinkSecret(), "DOCUMENT_LINK_SECRET")
+
+	viper.Set("JWT_SECRET", "shared")
+	viper.Set("DOCUMENT_LINK_SECRET", "shared")
+	assert.ErrorContains(t, checkDocumentLinkSecret(), "JWT_SECRET")
+
+	viper.Set("DOCUMENT_LINK_SECRET", "link-secret")
+	assert.NoError(t, checkDocumentLinkSecret())
+}
@@ -0,0 +1,600 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+)
+
+var (
+	ErrAccountNotFound = errors.New("account not found")
+	// ErrActiveLease: the account is owner or tenant of a lease that is not terminated yet
+	ErrActiveLease = errors.New("account has an ongoing lease")
+)
+
+// AccountService serves the data subject rights (RGPD): access to all the data held about a user and
+// erasure of the account.
+type AccountService struct {
+	txManager TxManager
+	storage   FileStorage
+	log       *zap.Logger
+}
+
+func NewAccountService(txManager TxManager, storage FileStorage, l *zap.Logger) *AccountService {
+	return &AccountService{
+		txManager: txManager,
+		storage:   storage,
+		log:       l,
+	}
+}
+
+// IsActive tells whether the user still has an account: tokens issued before a deletion are refused.
+func (s *AccountService) IsActive(ctx context.Context, userID int32) (bool, error) {
+	var active bool
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		var err error
+		active, err = q.IsUserActive(ctx, userID)
+		return err
+	})
+	return active, err
+}
+
+// IsAdmin tells whether the user is currently a platform administrator, whatever the role in their token.
+func (s *AccountService) IsAdmin(ctx context.Context, userID int32) (bool, error) {
+	var admin bool
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		var err error
+		admin, err = q.IsUserAdmin(ctx, userID)
+		return err
+	})
+	return admin, err
+}
+
+// cancelOpenCheck cancels a check of a deleted account still waiting for the candidate and refunds its credit
+// to the initiating owner, so that the candidate can no longer answer it.
+func cancelOpenCheck(ctx context.Context, q postgres.Querier, checkID int32) error {
+	check, err := q.GetSolvencyCheckForUpdate(ctx, checkID)
+	if err != nil {
+		return err
+	}
+	rows, err := q.CancelSolvencyCheck(ctx, checkID)
+	if err != nil || rows == 0 {
+		return err
+	}
+	return refundCheckCredit(ctx, q, check, fmt.Sprintf("Refund for solvency check #%d of a deleted account", checkID))
+}
+
+// ExportFile is a stored file included in the archive under Path.
+type ExportFile struct {
+	Path     string `json:"path"`
+	Type     string `json:"type"`
+	Filename string `json:"filename,omitempty"`
+
+	storageKey string
+}
+
+func exportFile(storageKey, fileType, filename string) ExportFile {
+	return ExportFile{Path: "files/" + storageKey, Type: fileType, Filename: filename, storageKey: storageKey}
+}
+
+type AccountProfileExport struct {
+	ID            int32     `json:"id"`
+	Email         string    `json:"email"`
+	FirstName     string    `json:"first_name,omitempty"`
+	LastName      string    `json:"last_name,omitempty"`
+	PhoneNumber   string    `json:"phone_number,omitempty"`
+	IsVerified    bool      `json:"is_verified"`
+	IsProvisional bool      `json:"is_provisional"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type PropertyExport struct {
+	postgres.Property
+	Media []ExportFile `json:"media"`
+}
+
+type LeaseExport struct {
+	LeaseDTO
+	Role         string                 `json:"role"` // "owner" or "tenant"
+	RentPayments []postgres.RentPayment `json:"rent_payments"`
+	Documents    []ExportFile           `json:"documents"`
+}
+
+// InitiatedCheckExport is a check ordered by the user as an owner. The candidate's data is theirs, not
+// the owner's, and is left out.
+type InitiatedCheckExport struct {
+	ID              int32     `json:"id"`
+	PropertyID      int32     `json:"property_id"`
+	PropertyAddress string    `json:"property_address"`
+	Status          string    `json:"status"`
+	Score           *int32    `json:"score,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// CandidateCheckExport is a check the user answered as a candidate, with their pieces and reports.
+type CandidateCheckExport struct {
+	ID              int32           `json:"id"`
+	PropertyAddress string          `json:"property_address,omitempty"`
+	Status          string          `json:"status"`
+	Score           *int32          `json:"score,omitempty"`
+	EmploymentType  string          `json:"employment_type,omitempty"`
+	GuaranteeType   string          `json:"guarantee_type,omitempty"`
+	Analysis        json.RawMessage `json:"analysis,omitempty"`
+	CreatedAt       time.Time       `json:"created_at"`
+	Documents       []ExportFile    `json:"documents"`
+}
+
+type InvitationExport struct {
+	ID          int32     `json:"id"`
+	PropertyID  int32     `json:"property_id"`
+	LeaseID     *int32    `json:"lease_id,omitempty"`
+	TenantEmail string    `json:"tenant_email"`
+	Status      string    `json:"status"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type DossierExport struct {
+	VerifiedAt time.Time       `json:"verified_at"`
+	ExpiresAt  time.Time       `json:"expires_at"`
+	Analysis   json.RawMessage `json:"analysis,omitempty"`
+	Documents  []ExportFile    `json:"documents"`
+}
+
+// AccountExport is everything held about a user. It is written as data.json next to the files it lists.
+type AccountExport struct {
+	GeneratedAt   time.Time                    `json:"generated_at"`
+	Profile       AccountProfileExport         `json:"profile"`
+	Subscriptions []postgres.Subscription      `json:"subscriptions"`
+	CreditLedger  []postgres.CreditTransaction `json:"credit_ledger"`
+	Payments      []postgres.Transaction       `json:"payments"`
+	Invoices      []postgres.Invoice           `json:"invoices"`
+	Properties    []PropertyExport             `json:"properties"`
+	Leases        []LeaseExport                `json:"leases"`
+	Checks        struct {
+		AsOwner     []InitiatedCheckExport `json:"as_owner"`
+		AsCandidate []CandidateCheckExport `json:"as_candidate"`
+	} `json:"solvency_checks"`
+	Invitations struct {
+		Sent     []InvitationExport `json:"sent"`
+		Received []InvitationExport `json:"received"`
+	} `json:"invitations"`
+	Dossier *DossierExport `json:"dossier,omitempty"`
+}
+
+func optionalInt32(v pgtype.Int4) *int32 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Int32
+}
+
+func invitationExports(invitations []postgres.LeaseInvitation) []InvitationExport {
+	out := make([]InvitationExport, 0, len(invitations))
+	for _, inv := range invitations {
+		out = append(out, InvitationExport{
+			ID:          inv.ID,
+			PropertyID:  inv.PropertyID,
+			LeaseID:     optionalInt32(inv.LeaseID),
+			TenantEmail: inv.TenantEmail,
+			Status:      inv.Status.String,
+			ExpiresAt:   inv.ExpiresAt.Time,
+			CreatedAt:   inv.CreatedAt.Time,
+		})
+	}
+	return out
+}
+
+func candidateDocumentFiles(docs []CandidateDocument) []ExportFile {
+	out := make([]ExportFile, 0, len(docs))
+	for _, d := range docs {
+		out = append(out, exportFile(d.StorageKey, d.Type, d.Filename))
+	}
+	return out
+}
+
+func documentFiles(docs []postgres.Document) []ExportFile {
+	out := make([]ExportFile, 0, len(docs))
+	for _, d := range docs {
+		out = append(out, exportFile(d.StorageKey, d.DocumentType, d.Filename))
+	}
+	return out
+}
+
+func leaseDTO(id int32, propertyID pgtype.Int4, address, rentalType string, start, end pgtype.Date, rent, charges, deposit pgtype.Numeric, status pgtype.Text) LeaseDTO {
+	rentValue, _ := rent.Float64Value()
+	chargesValue, _ := charges.Float64Value()
+	depositValue, _ := deposit.Float64Value()
+	dto := LeaseDTO{
+		ID:              id,
+		PropertyID:      propertyID.Int32,
+		PropertyAddress: address,
+		RentalType:      rentalType,
+		StartDate:       start.Time.Format("2006-01-02"),
+		RentAmount:      rentValue.Float64,
+		ChargesAmount:   chargesValue.Float64,
+		DepositAmount:   depositValue.Float64,
+		Status:          status.String,
+	}
+	if end.Valid {
+		dto.EndDate = end.Time.Format("2006-01-02")
+	}
+	return dto
+}
+
+// leaseExport completes a lease with its payments and generated documents.
+func leaseExport(ctx context.Context, q postgres.Querier, dto LeaseDTO, role string) (LeaseExport, error) {
+	payments, err := q.ListRentPaymentsByLease(ctx, pgtype.Int4{Int32: dto.ID, Valid: true})
+	if err != nil {
+		return LeaseExport{}, err
+	}
+	docs, err := q.ListLeaseDocuments(ctx, dto.ID)
+	if err != nil {
+		return LeaseExport{}, err
+	}
+	return LeaseExport{LeaseDTO: dto, Role: role, RentPayments: payments, Documents: documentFiles(docs)}, nil
+}
+
+// Export gathers the user's data. Files are only listed; WriteArchive reads them.
+func (s *AccountService) Export(ctx context.Context, userID int32) (*AccountExport, error) {
+	export := &AccountExport{GeneratedAt: time.Now()}
+	uid := pgtype.Int4{Int32: userID, Valid: true}
+
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		user, err := q.GetUserById(ctx, userID)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return ErrAccountNotFound
+			}
+			return err
+		}
+		if user.DeletedAt.Valid {
+			return ErrAccountNotFound
+		}
+		export.Profile = AccountProfileExport{
+			ID:            user.ID,
+			Email:         user.Email,
+			FirstName:     user.FirstName.String,
+			LastName:      user.LastName.String,
+			PhoneNumber:   user.PhoneNumber.String,
+			IsVerified:    user.IsVerified.Bool,
+			IsProvisional: user.IsProvisional.Bool,
+			CreatedAt:     user.CreatedAt.Time,
+		}
+
+		if export.Subscriptions, err = q.ListSubscriptionsByUser(ctx, uid); err != nil {
+			return err
+		}
+		if export.CreditLedger, err = q.ListCreditTransactionsByUser(ctx, uid); err != nil {
+			return err
+		}
+		if export.Payments, err = q.ListPaymentTransactionsByUser(ctx, uid); err != nil {
+			return err
+		}
+		if export.Invoices, err = q.ListInvoicesByUser(ctx, userID); err != nil {
+			return err
+		}
+
+		properties, err := q.ListPropertiesByOwner(ctx, uid)
+		if err != nil {
+			return err
+		}
+		for _, p := range properties {
+			media, err := q.ListPropertyMedia(ctx, p.ID)
+			if err != nil {
+				return err
+			}
+			files := make([]ExportFile, 0, len(media))
+			for _, m := range media {
+				files = append(files, exportFile(m.StorageKey, m.MediaKind, m.OriginalFilename))
+			}
+			export.Properties = append(export.Properties, PropertyExport{Property: p, Media: files})
+		}
+
+		owned, err := q.ListLeasesByOwner(ctx, uid)
+		if err != nil {
+			return err
+		}
+		for _, l := range owned {
+			dto := leaseDTO(l.ID, l.PropertyID, l.PropertyAddress, string(l.RentalType), l.StartDate, l.EndDate, l.RentAmount, l.ChargesAmount, l.DepositAmount, l.LeaseStatus)
+			lease, err := leaseExport(ctx, q, dto, "owner")
+			if err != nil {
+				return err
+			}
+			export.Leases = append(export.Leases, lease)
+		}
+		rented, err := q.ListLeasesByTenant(ctx, uid)
+		if err != nil {
+			return err
+		}
+		for _, l := range rented {
+			dto := leaseDTO(l.ID, l.PropertyID, l.PropertyAddress, string(l.RentalType), l.StartDate, l.EndDate, l.RentAmount, l.ChargesAmount, l.DepositAmount, l.LeaseStatus)
+			lease, err := leaseExport(ctx, q, dto, "tenant")
+			if err != nil {
+				return err
+			}
+			export.Leases = append(export.Leases, lease)
+		}
+
+		initiated, err := q.ListSolvencyChecksByOwner(ctx, uid)
+		if err != nil {
+			return err
+		}
+		for _, c := range initiated {
+			export.Checks.AsOwner = append(export.Checks.AsOwner, InitiatedCheckExport{
+				ID:              c.ID,
+				PropertyID:      c.PropertyID.Int32,
+				PropertyAddress: c.PropertyAddress,
+				Status:          string(c.Status.SolvencyStatus),
+				Score:           optionalInt32(c.ScoreResult),
+				CreatedAt:       c.CreatedAt.Time,
+			})
+		}
+		answered, err := q.ListSolvencyChecksByCandidate(ctx, uid)
+		if err != nil {
+			return err
+		}
+		for _, c := range answered {
+			reports, err := q.ListDocumentsByEntity(ctx, postgres.ListDocumentsByEntityParams{
+				DocumentType: DocumentTypeSolvencyReport,
+				EntityID:     c.ID,
+			})
+			if err != nil {
+				return err
+			}
+			check := CandidateCheckExport{
+				ID:              c.ID,
+				PropertyAddress: c.PropertyAddress.String,
+				Status:          string(c.Status.SolvencyStatus),
+				Score:           optionalInt32(c.ScoreResult),
+				EmploymentType:  c.EmploymentType.String,
+				GuaranteeType:   c.GuaranteeType.String,
+				CreatedAt:       c.CreatedAt.Time,
+				Documents:       append(candidateDocumentFiles(decodeCandidateDocuments(c.DocumentsJson)), documentFiles(reports)...),
+			}
+			if len(c.AnalysisJson) > 0 {
+				check.Analysis = c.AnalysisJson
+			}
+			export.Checks.AsCandidate = append(export.Checks.AsCandidate, check)
+		}
+
+		sent, err := q.ListInvitationsByOwner(ctx, userID)
+		if err != nil {
+			return err
+		}
+		export.Invitations.Sent = invitationExports(sent)
+		received, err := q.ListInvitationsByEmail(ctx, user.Email)
+		if err != nil {
+			return err
+		}
+		export.Invitations.Received = invitationExports(received)
+
+		dossier, err := q.GetTenantDossierByUser(ctx, userID)
+		if err != nil && err != pgx.ErrNoRows {
+			return err
+		}
+		if err == nil {
+			export.Dossier = &DossierExport{
+				VerifiedAt: dossier.VerifiedAt.Time,
+				ExpiresAt:  dossier.ExpiresAt.Time,
+				Documents:  candidateDocumentFiles(decodeCandidateDocuments(dossier.DocumentsJson)),
+			}
+			if len(dossier.AnalysisJson) > 0 {
+				export.Dossier.Analysis = dossier.AnalysisJson
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// files lists every stored file of the export, each once.
+func (e *AccountExport) files() []ExportFile {
+	var all []ExportFile
+	for _, p := range e.Properties {
+		all = append(all, p.Media...)
+	}
+	for _, l := range e.Leases {
+		all = append(all, l.Documents...)
+	}
+	for _, c := range e.Checks.AsCandidate {
+		all = append(all, c.Documents...)
+	}
+	if e.Dossier != nil {
+		all = append(all, e.Dossier.Documents...)
+	}
+
+	seen := map[string]bool{}
+	files := make([]ExportFile, 0, len(all))
+	for _, f := range all {
+		if f.storageKey == "" || seen[f.storageKey] {
+			continue
+		}
+		seen[f.storageKey] = true
+		files = append(files, f)
+	}
+	return files
+}
+
+// WriteArchive writes the export as a zip: data.json and the files under files/. A file missing from the
+// storage is skipped and logged rather than failing an archive already being sent.
+func (s *AccountService) WriteArchive(ctx context.Context, export *AccountExport, w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode export: %w", err)
+	}
+	entry, err := zw.Create("data.json")
+	if err != nil {
+		return err
+	}
+	if _, err := entry.Write(data); err != nil {
+		return err
+	}
+
+	for _, f := range export.files() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		reader, err := s.storage.Open(f.storageKey)
+		if err != nil {
+			s.log.Warn("export: stored file unavailable", zap.String("key", f.storageKey), zap.Error(err))
+			continue
+		}
+		entry, err := zw.Create(f.Path)
+		if err == nil {
+			_, err = io.Copy(entry, reader)
+		}
+		reader.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// listAccountChecks returns the checks of the user, as candidate or as owner, and, sorted, the ids of those
+// still waiting for the candidate.
+func listAccountChecks(ctx context.Context, q postgres.Querier, uid pgtype.Int4) ([]purgeCheck, []int32, error) {
+	var checks []purgeCheck
+	var open []int32
+	answered, err := q.ListSolvencyChecksByCandidate(ctx, uid)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, c := range answered {
+		checks = append(checks, purgeCheck{ID: c.ID, DocumentsJson: c.DocumentsJson})
+		if acceptsDocuments(c.Status.SolvencyStatus) {
+			open = append(open, c.ID)
+		}
+	}
+	initiated, err := q.ListSolvencyChecksByOwner(ctx, uid)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, c := range initiated {
+		checks = append(checks, purgeCheck{ID: c.ID, DocumentsJson: c.DocumentsJson})
+		if acceptsDocuments(c.Status.SolvencyStatus) {
+			open = append(open, c.ID)
+		}
+	}
+	// Two deletions sharing a check (its owner and its candidate) lock them in the same order
+	slices.Sort(open)
+	return checks, open, nil
+}
+
+// DeleteAccount erases the user's personal data. The row itself stays, anonymised, because the financial
+// records (credit ledger, payments, subscriptions) and signed leases must be kept for the legal periods.
+// Their solvency checks still waiting for the candidate are cancelled and refunded; pieces and reports of
+// all their checks, as candidate or as owner, and their dossier are deleted. The deletion is refused while
+// a signed lease of theirs, as owner or tenant, is not terminated.
+func (s *AccountService) DeleteAccount(ctx context.Context, userID int32) error {
+	uid := pgtype.Int4{Int32: userID, Valid: true}
+	var files []string
+
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		checks, open, err := listAccountChecks(ctx, q, uid)
+		if err != nil {
+			return err
+		}
+		// The open checks are cancelled and give their credit back to the properties: checks, then
+		// properties, then the user, in the order of CancelCheck and of the checks themselves
+		for _, id := range open {
+			if _, err := q.GetSolvencyCheckForUpdate(ctx, id); err != nil {
+				return err
+			}
+		}
+		if err := q.LockPropertiesByOwner(ctx, uid); err != nil {
+			return err
+		}
+		user, err := q.GetUserForUpdate(ctx, userID)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return ErrAccountNotFound
+			}
+			return err
+		}
+		if user.DeletedAt.Valid {
+			return ErrAccountNotFound
+		}
+
+		asTenant, err := q.CountLeasesByTenant(ctx, uid)
+		if err != nil {
+			return err
+		}
+		asOwner, err := q.CountLeasesByOwner(ctx, uid)
+		if err != nil {
+			return err
+		}
+		if asTenant > 0 || asOwner > 0 {
+			return ErrActiveLease
+		}
+
+		for _, id := range open {
+			if err := cancelOpenCheck(ctx, q, id); err != nil {
+				return fmt.Errorf("failed to cancel check %d: %w", id, err)
+			}
+		}
+		for _, c := range checks {
+			removed, err := anonymizeCheck(ctx, q, c)
+			if err != nil {
+				return err
+			}
+			files = append(files, removed...)
+		}
+
+		dossier, err := q.GetTenantDossierByUser(ctx, userID)
+		if err != nil && err != pgx.ErrNoRows {
+			return err
+		}
+		if err == nil {
+			files = append(files, storageKeys(decodeCandidateDocuments(dossier.DocumentsJson))...)
+			if err := q.DeleteTenantDossier(ctx, dossier.ID); err != nil {
+				return err
+			}
+		}
+
+		if err := q.RevokePendingInvitationsForUser(ctx, postgres.RevokePendingInvitationsForUserParams{
+			OwnerID:     userID,
+			TenantEmail: user.Email,
+		}); err != nil {
+			return err
+		}
+		if err := q.RevokeDocumentLinksByUser(ctx, userID); err != nil {
+			return err
+		}
+		if err := q.DeactivatePropertiesByOwner(ctx, uid); err != nil {
+			return err
+		}
+		if err := q.CancelUserSubscriptions(ctx, uid); err != nil {
+			return err
+		}
+		return q.AnonymizeUser(ctx, userID)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, key := range files {
+		if err := s.storage.Delete(key); err != nil {
+			s.log.Warn("failed to delete file of a deleted account", zap.String("key", key), zap.Error(err))
+		}
+	}
+	s.log.Info("account deleted", zap.Int32("user_id", userID), zap.Int("files", len(files)))
+	return nil
+}
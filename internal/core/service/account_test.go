@@ -0,0 +1,181 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+)
+
+func setupAccount() (*AccountService, *MockQuerier, *MockFileStorage) {
+	mockQuerier := new(MockQuerier)
+	mockFileStore := new(MockFileStorage)
+	return NewAccountService(passthroughTxManager{q: mockQuerier}, mockFileStore, zap.NewNop()), mockQuerier, mockFileStore
+}
+
+func TestExport_Archive(t *testing.T) {
+	svc, mockQuerier, mockFileStore := setupAccount()
+	uid := pgtype.Int4{Int32: 2, Valid: true}
+
+	mockQuerier.On("GetUserById", mock.Anything, int32(2)).Return(postgres.User{
+		ID:           2,
+		Email:        "cand@test.com",
+		PasswordHash: pgtype.Text{String: "secret-hash", Valid: true},
+		FirstName:    pgtype.Text{String: "Jane", Valid: true},
+	}, nil)
+	mockQuerier.On("ListSubscriptionsByUser", mock.Anything, uid).Return([]postgres.Subscription{}, nil)
+	mockQuerier.On("ListCreditTransactionsByUser", mock.Anything, uid).Return([]postgres.CreditTransaction{
+		{ID: 1, Amount: 3, TransactionType: "initial_free"},
+	}, nil)
+	mockQuerier.On("ListPaymentTransactionsByUser", mock.Anything, uid).Return([]postgres.Transaction{}, nil)
+	mockQuerier.On("ListInvoicesByUser", mock.Anything, uid.Int32).Return([]postgres.Invoice{}, nil)
+	mockQuerier.On("ListPropertiesByOwner", mock.Anything, uid).Return([]postgres.Property{}, nil)
+	mockQuerier.On("ListLeasesByOwner", mock.Anything, uid).Return([]postgres.ListLeasesByOwnerRow{}, nil)
+	mockQuerier.On("ListLeasesByTenant", mock.Anything, uid).Return([]postgres.ListLeasesByTenantRow{
+		{ID: 5, PropertyID: pgtype.Int4{Int32: 10, Valid: true}, PropertyAddress: "1 rue A", LeaseStatus: pgtype.Text{String: "terminated", Valid: true}},
+	}, nil)
+	mockQuerier.On("ListRentPaymentsByLease", mock.Anything, pgtype.Int4{Int32: 5, Valid: true}).Return([]postgres.RentPayment{}, nil)
+	mockQuerier.On("ListLeaseDocuments", mock.Anything, int32(5)).Return([]postgres.Document{
+		{ID: 1, DocumentType: DocumentTypeLease, StorageKey: "documents/lease/5/v1.pdf", Filename: "bail.pdf"},
+	}, nil)
+	mockQuerier.On("ListSolvencyChecksByOwner", mock.Anything, uid).Return([]postgres.ListSolvencyChecksByOwnerRow{}, nil)
+	mockQuerier.On("ListSolvencyChecksByCandidate", mock.Anything, uid).Return([]postgres.ListSolvencyChecksByCandidateRow{
+		{ID: 7, Status: postgres.NullSolvencyStatus{SolvencyStatus: postgres.SolvencyStatusApproved, Valid: true}, DocumentsJson: docsJSON(t, "solvency/7/payslip_a.pdf")},
+	}, nil)
+	mockQuerier.On("ListDocumentsByEntity", mock.Anything, postgres.ListDocumentsByEntityParams{DocumentType: DocumentTypeSolvencyReport, EntityID: 7}).
+		Return([]postgres.Document{}, nil)
+	mockQuerier.On("ListInvitationsByOwner", mock.Anything, int32(2)).Return([]postgres.LeaseInvitation{}, nil)
+	mockQuerier.On("ListInvitationsByEmail", mock.Anything, "cand@test.com").Return([]postgres.LeaseInvitation{
+		{ID: 4, PropertyID: 10, TenantEmail: "cand@test.com", Token: "invitation-token"},
+	}, nil)
+	mockQuerier.On("GetTenantDossierByUser", mock.Anything, int32(2)).Return(postgres.TenantDossier{}, pgx.ErrNoRows)
+
+	mockFileStore.On("Open", "documents/lease/5/v1.pdf").Return(io.NopCloser(bytes.NewReader(samplePDF)), nil)
+	mockFileStore.On("Open", "solvency/7/payslip_a.pdf").Return(nil, assert.AnError)
+
+	export, err := svc.Export(context.Background(), 2)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, svc.WriteArchive(context.Background(), export, &buf))
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	entries := map[string]*zip.File{}
+	for _, f := range zr.File {
+		entries[f.Name] = f
+	}
+	// A file missing from the storage is skipped, not fatal
+	assert.Len(t, entries, 2)
+	require.Contains(t, entries, "files/documents/lease/5/v1.pdf")
+	require.Contains(t, entries, "data.json")
+
+	rc, err := entries["data.json"].Open()
+	require.NoError(t, err)
+	data, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	rc.Close()
+	assert.NotContains(t, string(data), "secret-hash")
+	assert.NotContains(t, string(data), "invitation-token")
+	assert.NotContains(t, string(data), "storage_key")
+
+	var decoded AccountExport
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, "Jane", decoded.Profile.FirstName)
+	assert.Len(t, decoded.CreditLedger, 1)
+	require.Len(t, decoded.Leases, 1)
+	assert.Equal(t, "tenant", decoded.Leases[0].Role)
+	require.Len(t, decoded.Checks.AsCandidate, 1)
+	assert.Equal(t, "files/solvency/7/payslip_a.pdf", decoded.Checks.AsCandidate[0].Documents[0].Path)
+	assert.Len(t, decoded.Invitations.Received, 1)
+	assert.Nil(t, decoded.Dossier)
+}
+
+func TestDeleteAccount_RefusedWithOngoingLease(t *testing.T) {
+	svc, mockQuerier, _ := setupAccount()
+	uid := pgtype.Int4{Int32: 1, Valid: true}
+
+	mockQuerier.On("ListSolvencyChecksByCandidate", mock.Anything, uid).Return([]postgres.ListSolvencyChecksByCandidateRow{}, nil)
+	mockQuerier.On("ListSolvencyChecksByOwner", mock.Anything, uid).Return([]postgres.ListSolvencyChecksByOwnerRow{}, nil)
+	mockQuerier.On("LockPropertiesByOwner", mock.Anything, pgtype.Int4{Int32: 1, Valid: true}).Return(nil)
+	mockQuerier.On("GetUserForUpdate", mock.Anything, int32(1)).Return(postgres.User{ID: 1, Email: "owner@test.com"}, nil)
+	mockQuerier.On("CountLeasesByTenant", mock.Anything, uid).Return(int64(0), nil)
+	mockQuerier.On("CountLeasesByOwner", mock.Anything, uid).Return(int64(1), nil)
+
+	err := svc.DeleteAccount(context.Background(), 1)
+
+	assert.ErrorIs(t, err, ErrActiveLease)
+	mockQuerier.AssertNotCalled(t, "AnonymizeUser", mock.Anything, mock.Anything)
+}
+
+func TestDeleteAccount(t *testing.T) {
+	svc, mockQuerier, mockFileStore := setupAccount()
+	uid := pgtype.Int4{Int32: 2, Valid: true}
+
+	mockQuerier.On("CountLeasesByTenant", mock.Anything, uid).Return(int64(0), nil)
+	mockQuerier.On("CountLeasesByOwner", mock.Anything, uid).Return(int64(0), nil)
+	mockQuerier.On("ListSolvencyChecksByCandidate", mock.Anything, uid).Return([]postgres.ListSolvencyChecksByCandidateRow{
+		{ID: 7, DocumentsJson: docsJSON(t, "solvency/7/payslip_a.pdf")},
+	}, nil)
+	// A check they initiated is still waiting for its candidate: cancelled and refunded first
+	mockQuerier.On("ListSolvencyChecksByOwner", mock.Anything, uid).Return([]postgres.ListSolvencyChecksByOwnerRow{
+		{ID: 8, Status: postgres.NullSolvencyStatus{SolvencyStatus: postgres.SolvencyStatusPending, Valid: true}},
+	}, nil)
+	// The open check is locked before the properties, as by CancelCheck
+	mock.InOrder(
+		mockQuerier.On("GetSolvencyCheckForUpdate", mock.Anything, int32(8)).Return(postgres.SolvencyCheck{
+			ID:                  8,
+			InitiatorOwnerID:    uid,
+			Status:              postgres.NullSolvencyStatus{SolvencyStatus: postgres.SolvencyStatusPending, Valid: true},
+			CreditSource:        pgtype.Text{String: "global", Valid: true},
+			CreditTransactionID: pgtype.Int4{Int32: 30, Valid: true},
+		}, nil),
+		mockQuerier.On("LockPropertiesByOwner", mock.Anything, uid).Return(nil),
+		mockQuerier.On("GetUserForUpdate", mock.Anything, int32(2)).Return(postgres.User{ID: 2, Email: "cand@test.com"}, nil),
+	)
+	mockQuerier.On("CancelSolvencyCheck", mock.Anything, int32(8)).Return(int64(1), nil)
+	mockQuerier.On("CreateCreditTransaction", mock.Anything, mock.MatchedBy(func(p postgres.CreateCreditTransactionParams) bool {
+		return p.TransactionType == "refund" && p.RefundOf.Int32 == 30
+	})).Return(postgres.CreditTransaction{}, nil)
+	mockQuerier.On("ListGuarantorsByCheck", mock.Anything, int32(8)).Return([]postgres.SolvencyGuarantor{}, nil)
+	mockQuerier.On("ListDocumentsByEntity", mock.Anything, postgres.ListDocumentsByEntityParams{DocumentType: DocumentTypeSolvencyReport, EntityID: 8}).
+		Return([]postgres.Document{}, nil)
+	mockQuerier.On("DeleteUnattachedGuarantorsByCheck", mock.Anything, int32(8)).Return(nil)
+	mockQuerier.On("AnonymizeSolvencyCheck", mock.Anything, int32(8)).Return(nil)
+	mockQuerier.On("ListGuarantorsByCheck", mock.Anything, int32(7)).Return([]postgres.SolvencyGuarantor{}, nil)
+	mockQuerier.On("ListDocumentsByEntity", mock.Anything, postgres.ListDocumentsByEntityParams{DocumentType: DocumentTypeSolvencyReport, EntityID: 7}).
+		Return([]postgres.Document{}, nil)
+	mockQuerier.On("DeleteUnattachedGuarantorsByCheck", mock.Anything, int32(7)).Return(nil)
+	mockQuerier.On("AnonymizeSolvencyCheck", mock.Anything, int32(7)).Return(nil)
+	mockQuerier.On("GetTenantDossierByUser", mock.Anything, int32(2)).Return(postgres.TenantDossier{
+		ID: 3, UserID: 2, DocumentsJson: docsJSON(t, "dossiers/2/payslip_b.pdf"),
+	}, nil)
+	mockQuerier.On("DeleteTenantDossier", mock.Anything, int32(3)).Return(nil)
+	mockQuerier.On("RevokePendingInvitationsForUser", mock.Anything, postgres.RevokePendingInvitationsForUserParams{
+		OwnerID: 2, TenantEmail: "cand@test.com",
+	}).Return(nil)
+	mockQuerier.On("RevokeDocumentLinksByUser", mock.Anything, int32(2)).Return(nil)
+	mockQuerier.On("DeactivatePropertiesByOwner", mock.Anything, uid).Return(nil)
+	mockQuerier.On("CancelUserSubscriptions", mock.Anything, uid).Return(nil)
+	mockQuerier.On("AnonymizeUser", mock.Anything, int32(2)).Return(nil)
+	mockFileStore.On("Delete", "solvency/7/payslip_a.pdf").Return(nil).Once()
+	mockFileStore.On("Delete", "dossiers/2/payslip_b.pdf").Return(nil).Once()
+
+	err := svc.DeleteAccount(context.Background(), 2)
+
+	require.NoError(t, err)
+	mockQuerier.AssertExpectations(t)
+	mockFileStore.AssertExpectations(t)
+	// Financial records are kept
+	mockQuerier.AssertNotCalled(t, "ListCreditTransactionsByUser", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,561 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+	"seculoc-back/internal/platform/email"
+	"seculoc-back/internal/platform/logger"
+)
+
+const (
+	// maxRenewalAttempts is the number of failed payments after which a past due subscription is cancelled
+	maxRenewalAttempts = 3
+	// renewalRetryInterval is the delay between two payment attempts of a past due subscription
+	renewalRetryInterval = 24 * time.Hour
+	// invoiceClaimTimeout is how long an invoice stays reserved by the instance charging it. Past it, the
+	// instance is assumed stopped mid-charge and another one charges again with the same idempotency key.
+	invoiceClaimTimeout = 15 * time.Minute
+)
+
+// PaymentCollector charges an invoice to the user's payment method. An error means the payment failed,
+// except ErrPaymentPending: the outcome is then notified later and the invoice stays pending.
+type PaymentCollector interface {
+	Collect(ctx context.Context, invoice postgres.Invoice) error
+}
+
+// BillingService is the billing engine: it renews subscriptions at the end of each period, invoices them
+// and grants the credits included in the plans every month.
+type BillingService struct {
+	txManager   TxManager
+	emailSender email.EmailSender
+	// payments collects renewal invoices; without one they are settled as soon as they are issued
+	payments PaymentCollector
+
+	// ExpirePlanCredits makes the plan credits left unused at the end of a monthly cycle expire
+	// instead of rolling over. Checks consume the plan credits first, then the purchased ones.
+	ExpirePlanCredits bool
+}
+
+func NewBillingService(txManager TxManager, emailSender email.EmailSender, payments PaymentCollector, l *zap.Logger) *BillingService {
+	return &BillingService{
+		txManager:   txManager,
+		emailSender: emailSender,
+		payments:    payments,
+	}
+}
+
+// dateOf truncates t to its calendar day, as stored in DATE columns.
+func dateOf(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// addMonths adds n months to t, keeping the day of month when it exists (Jan 31 + 1 month = Feb 28).
+func addMonths(t time.Time, n int) time.Time {
+	y, m, d := t.Date()
+	first := time.Date(y, m+time.Month(n), 1, 0, 0, 0, 0, time.UTC)
+	if last := first.AddDate(0, 1, -1).Day(); d > last {
+		d = last
+	}
+	return time.Date(first.Year(), first.Month(), d, 0, 0, 0, 0, time.UTC)
+}
+
+// nextCycleDate returns the first cycle boundary after `after`, for cycles of `months` months counted
+// from anchor. Counting from the anchor keeps the day of month stable across short months.
+func nextCycleDate(anchor, after time.Time, months int) time.Time {
+	y1, m1, _ := anchor.Date()
+	y2, m2, _ := after.Date()
+	k := ((y2-y1)*12 + int(m2-m1)) / months
+	if k < 1 {
+		k = 1
+	}
+	next := addMonths(anchor, k*months)
+	for !next.After(after) {
+		k++
+		next = addMonths(anchor, k*months)
+	}
+	return next
+}
+
+// billingMonths is the length of a billing period.
+func billingMonths(freq postgres.BillingFreq) int {
+	if freq == postgres.BillingFreqYearly {
+		return 12
+	}
+	return 1
+}
+
+// prepaidEndDate is the end_date of a subscription paid until periodEnd: yearly plans are prepaid,
+// monthly ones renew without end date.
+func prepaidEndDate(freq postgres.BillingFreq, periodEnd time.Time) pgtype.Date {
+	if freq != postgres.BillingFreqYearly {
+		return pgtype.Date{}
+	}
+	return pgtype.Date{Time: periodEnd, Valid: true}
+}
+
+// grantPlanCredits credits the monthly credits included in a plan and records the grant on the
+// subscription. With expireUnused, the credits of the previous grant still unspent expire first.
+func grantPlanCredits(ctx context.Context, q postgres.Querier, sub postgres.Subscription, item postgres.CatalogItem, next time.Time, expireUnused bool) error {
+	userID := sub.UserID
+	if expireUnused && sub.GrantedCredits > 0 {
+		if _, err := q.GetUserForUpdate(ctx, userID.Int32); err != nil {
+			return fmt.Errorf("failed to lock user for credit expiry: %w", err)
+		}
+		balance, err := q.GetUserCreditBalanceForUpdate(ctx, userID.Int32)
+		if err != nil && err != pgx.ErrNoRows {
+			return err
+		}
+		// Plan credits are spent first: only what was not spent since the grant expires
+		used, err := q.GetCreditUsageSinceLastPlanGrant(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if unused := min(balance, sub.GrantedCredits-used); unused > 0 {
+			_, err := q.CreateCreditTransaction(ctx, postgres.CreateCreditTransactionParams{
+				UserID:          userID,
+				Amount:          -unused,
+				TransactionType: "plan_expiry",
+				Description:     pgtype.Text{String: fmt.Sprintf("Unused %s credits expired", item.Name), Valid: true},
+			})
+			if err != nil {
+				return fmt.Errorf("failed to expire plan credits: %w", err)
+			}
+		}
+	}
+
+	if item.IncludedCredits > 0 {
+		_, err := q.CreateCreditTransaction(ctx, postgres.CreateCreditTransactionParams{
+			UserID:          userID,
+			Amount:          item.IncludedCredits,
+			TransactionType: "plan_renewal",
+			Description:     pgtype.Text{String: fmt.Sprintf("Monthly %s credits", item.Name), Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to grant plan credits: %w", err)
+		}
+	}
+
+	return q.RecordSubscriptionCreditGrant(ctx, postgres.RecordSubscriptionCreditGrantParams{
+		ID:              sub.ID,
+		NextCreditGrant: pgtype.Date{Time: next, Valid: true},
+		GrantedCredits:  item.IncludedCredits,
+	})
+}
+
+// GrantPlanCredits grants the monthly credits of every active subscription whose grant is due.
+// Yearly plans are credited monthly too, up to their prepaid end date.
+func (s *BillingService) GrantPlanCredits(ctx context.Context) error {
+	log := logger.FromContext(ctx)
+	today := dateOf(time.Now())
+
+	var ids []int32
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		var err error
+		ids, err = q.ListSubscriptionsDueForCredits(ctx, pgtype.Date{Time: today, Valid: true})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list subscriptions due for credits: %w", err)
+	}
+
+	granted := 0
+	for _, id := range ids {
+		err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+			sub, err := q.GetSubscriptionForUpdate(ctx, id)
+			if err != nil {
+				return err
+			}
+			// Renewal failed or credits granted in the meantime
+			if sub.Status.String != "active" || !sub.NextCreditGrant.Valid || sub.NextCreditGrant.Time.After(today) {
+				return nil
+			}
+
+			item, err := subscriptionCatalogItem(ctx, q, sub)
+			if err != nil {
+				return err
+			}
+			// A missed month is not granted twice: the next grant is the next one after today
+			next := nextCycleDate(sub.StartDate.Time, today, 1)
+			if err := grantPlanCredits(ctx, q, sub, item, next, s.ExpirePlanCredits); err != nil {
+				return err
+			}
+			granted++
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to grant credits of subscription %d: %w", id, err)
+		}
+	}
+
+	if granted > 0 {
+		log.Info("plan credits granted", zap.Int("count", granted))
+	}
+	return nil
+}
+
+// RenewSubscriptions starts the next period of every active subscription whose period has ended:
+// the period is invoiced and charged, then the subscription moves on, or to 'past_due' if the payment
+// failed. Free plans move on without invoice.
+func (s *BillingService) RenewSubscriptions(ctx context.Context) error {
+	log := logger.FromContext(ctx)
+	today := dateOf(time.Now())
+
+	var ids []int32
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		var err error
+		ids, err = q.ListSubscriptionsDueForRenewal(ctx, pgtype.Date{Time: today, Valid: true})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list subscriptions due for renewal: %w", err)
+	}
+
+	renewed, failed, pending := 0, 0, 0
+	for _, id := range ids {
+		var invoice *postgres.Invoice
+		err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+			var err error
+			invoice, err = openRenewal(ctx, q, id, today)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to renew subscription %d: %w", id, err)
+		}
+		if invoice == nil {
+			renewed++
+			continue
+		}
+
+		outcome, err := s.collect(ctx, *invoice)
+		if err != nil {
+			return fmt.Errorf("failed to settle invoice %d: %w", invoice.ID, err)
+		}
+		switch outcome {
+		case invoicePaid:
+			renewed++
+		case invoiceFailed:
+			failed++
+		default:
+			pending++
+		}
+	}
+
+	if renewed+failed+pending > 0 {
+		log.Info("subscriptions renewed", zap.Int("renewed", renewed), zap.Int("payment_failed", failed), zap.Int("payment_pending", pending))
+	}
+	return nil
+}
+
+// openRenewal returns the invoice of the period following the current one, creating it if needed, with
+// the extra properties kept. A subscription cancelled at period end ends instead; a downgrade or a removal
+// of extra properties scheduled applies first. Free periods and subscriptions handled in the meantime
+// need no payment: nil is returned. The invoice returned is claimed (see claimInvoice).
+func openRenewal(ctx context.Context, q postgres.Querier, id int32, today time.Time) (*postgres.Invoice, error) {
+	sub, err := q.GetSubscriptionForUpdate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if sub.Status.String != "active" || !sub.CurrentPeriodEnd.Valid || sub.CurrentPeriodEnd.Time.After(today) {
+		return nil, nil
+	}
+	start := sub.CurrentPeriodEnd.Time
+	if sub.CancelAtPeriodEnd {
+		return nil, endSubscription(ctx, q, sub, start)
+	}
+
+	item, err := subscriptionCatalogItem(ctx, q, sub)
+	if err != nil {
+		return nil, err
+	}
+	slots, err := propertySlots(ctx, q, sub)
+	if err != nil {
+		return nil, err
+	}
+	if sub.ScheduledCatalogItemID.Valid {
+		sub, item, err = applyScheduledChange(ctx, q, sub, slots, start)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if slots, err = renewSlots(ctx, q, sub, item, slots, start); err != nil {
+		return nil, err
+	}
+	freq := sub.Frequency.BillingFreq
+	end := nextCycleDate(sub.StartDate.Time, start, billingMonths(freq))
+
+	amount := planPriceCents(item, freq) + slotsPriceCents(slots.UnitPriceCents, slots.Quantity, freq)
+	if amount == 0 {
+		return nil, advancePeriod(ctx, q, sub, start, end)
+	}
+
+	invoice, err := q.GetInvoiceForPeriod(ctx, postgres.GetInvoiceForPeriodParams{
+		SubscriptionID: pgtype.Int4{Int32: sub.ID, Valid: true},
+		PeriodStart:    pgtype.Date{Time: start, Valid: true},
+	})
+	if err == pgx.ErrNoRows {
+		invoice, err = q.CreateInvoice(ctx, postgres.CreateInvoiceParams{
+			UserID:         sub.UserID.Int32,
+			SubscriptionID: pgtype.Int4{Int32: sub.ID, Valid: true},
+			Description:    renewalDescription(item, freq, slots.Quantity),
+			AmountCents:    amount,
+			PeriodStart:    pgtype.Date{Time: start, Valid: true},
+			PeriodEnd:      pgtype.Date{Time: end, Valid: true},
+			Status:         "open",
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to invoice renewal: %w", err)
+	}
+	return claimInvoice(ctx, q, invoice.ID)
+}
+
+// claimInvoice reserves an invoice for collection so that a single instance charges it. nil is returned
+// when it is being charged elsewhere or waits for the provider's webhook.
+func claimInvoice(ctx context.Context, q postgres.Querier, id int32) (*postgres.Invoice, error) {
+	invoice, err := q.ClaimInvoiceForCollection(ctx, postgres.ClaimInvoiceForCollectionParams{
+		ID:          id,
+		StaleBefore: pgtype.Timestamp{Time: time.Now().Add(-invoiceClaimTimeout), Valid: true},
+	})
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim invoice %d: %w", id, err)
+	}
+	return &invoice, nil
+}
+
+func advancePeriod(ctx context.Context, q postgres.Querier, sub postgres.Subscription, start, end time.Time) error {
+	return q.AdvanceSubscriptionPeriod(ctx, postgres.AdvanceSubscriptionPeriodParams{
+		ID:          sub.ID,
+		PeriodStart: pgtype.Date{Time: start, Valid: true},
+		PeriodEnd:   pgtype.Date{Time: end, Valid: true},
+		EndDate:     prepaidEndDate(sub.Frequency.BillingFreq, end),
+	})
+}
+
+// RetryFailedRenewals charges again the renewals of past due subscriptions, once every
+// renewalRetryInterval. After maxRenewalAttempts failed payments the subscription is cancelled.
+func (s *BillingService) RetryFailedRenewals(ctx context.Context) error {
+	log := logger.FromContext(ctx)
+
+	var invoices []postgres.Invoice
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		var err error
+		now := time.Now()
+		invoices, err = q.ListInvoicesToRetry(ctx, postgres.ListInvoicesToRetryParams{
+			RetryBefore: pgtype.Timestamp{Time: now.Add(-renewalRetryInterval), Valid: true},
+			StaleBefore: pgtype.Timestamp{Time: now.Add(-invoiceClaimTimeout), Valid: true},
+		})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list invoices to retry: %w", err)
+	}
+
+	retried, recovered := 0, 0
+	for _, listed := range invoices {
+		var invoice *postgres.Invoice
+		err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+			var err error
+			invoice, err = claimInvoice(ctx, q, listed.ID)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if invoice == nil {
+			// Retried by another instance in the meantime
+			continue
+		}
+		retried++
+		outcome, err := s.collect(ctx, *invoice)
+		if err != nil {
+			return fmt.Errorf("failed to settle invoice %d: %w", invoice.ID, err)
+		}
+		if outcome == invoicePaid {
+			recovered++
+		}
+	}
+
+	if retried > 0 {
+		log.Info("failed renewals retried", zap.Int("count", retried), zap.Int("recovered", recovered))
+	}
+	return nil
+}
+
+// Invoice outcomes reported by collect.
+const (
+	invoicePaid    = "paid"
+	invoiceFailed  = "failed"
+	invoicePending = "pending"
+)
+
+// collect charges an invoice and records the outcome through settleInvoice. A payment waiting for
+// the bank or the customer leaves the invoice pending until the provider's webhook settles it.
+func (s *BillingService) collect(ctx context.Context, invoice postgres.Invoice) (string, error) {
+	log := logger.FromContext(ctx).With(zap.Int32("invoice_id", invoice.ID))
+
+	var payErr error
+	if s.payments != nil {
+		payErr = s.payments.Collect(ctx, invoice)
+	}
+
+	if errors.Is(payErr, ErrPaymentPending) {
+		err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+			return q.MarkInvoicePending(ctx, invoice.ID)
+		})
+		if err != nil {
+			return "", err
+		}
+		log.Info("renewal payment pending", zap.Int32("subscription_id", invoice.SubscriptionID.Int32))
+		return invoicePending, nil
+	}
+
+	var settled invoiceSettlement
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		var err error
+		settled, err = settleInvoice(ctx, q, invoice, payErr)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	if payErr == nil {
+		log.Info("renewal paid", zap.Int32("subscription_id", invoice.SubscriptionID.Int32), zap.Int32("amount_cents", invoice.AmountCents))
+		return invoicePaid, nil
+	}
+
+	log.Warn("renewal payment failed", zap.Int32("subscription_id", invoice.SubscriptionID.Int32), zap.Bool("cancelled", settled.cancelled), zap.Error(payErr))
+	notifyRenewalFailure(ctx, s.emailSender, settled)
+	return invoiceFailed, nil
+}
+
+// invoiceSettlement tells what settleInvoice did, to notify the user once the transaction committed.
+type invoiceSettlement struct {
+	invoice postgres.Invoice
+	email   string
+	failed  bool
+	// cancelled is set when the subscription ended because of the failure
+	cancelled bool
+	// first is set for the first payment of a subscription, which is never retried
+	first bool
+}
+
+// settleInvoice records the outcome of the payment of an invoice. A paid invoice is issued and starts its
+// period, or activates a subscription awaiting its first payment. A failed one leaves the subscription
+// past due, or cancels it after maxRenewalAttempts; a failed first payment cancels it right away.
+// Invoices without subscription are one-off purchases (see settlePurchase). Invoices already settled
+// are left as they are.
+func settleInvoice(ctx context.Context, q postgres.Querier, invoice postgres.Invoice, payErr error) (invoiceSettlement, error) {
+	settled := invoiceSettlement{invoice: invoice}
+	if !invoice.SubscriptionID.Valid {
+		return settled, settlePurchase(ctx, q, invoice, payErr)
+	}
+	sub, err := q.GetSubscriptionForUpdate(ctx, invoice.SubscriptionID.Int32)
+	if err != nil {
+		return settled, err
+	}
+	if invoice.CatalogItemID.Valid || invoice.SlotQuantity.Valid {
+		// Prorata of an upgrade or of extra properties, the only subscription invoices without period
+		return settled, settleProrata(ctx, q, sub, invoice, payErr)
+	}
+	settled.first = sub.Status.String == "incomplete"
+
+	if payErr == nil {
+		paid, err := q.MarkInvoicePaid(ctx, invoice.ID)
+		if err == pgx.ErrNoRows {
+			// Paid or voided in the meantime
+			return settled, nil
+		}
+		if err != nil {
+			return settled, err
+		}
+		if _, err := issueInvoice(ctx, q, paid, vatRateBps); err != nil {
+			return settled, err
+		}
+		if settled.first {
+			return settled, activateSubscription(ctx, q, sub)
+		}
+		return settled, advancePeriod(ctx, q, sub, invoice.PeriodStart.Time, invoice.PeriodEnd.Time)
+	}
+
+	failed, err := q.MarkInvoiceFailed(ctx, invoice.ID)
+	if err == pgx.ErrNoRows {
+		return settled, nil
+	}
+	if err != nil {
+		return settled, err
+	}
+	settled.failed = true
+	if settled.first || failed.Attempts >= maxRenewalAttempts {
+		// The period was never paid: nothing is owed and the access ends
+		settled.cancelled = true
+		if err := q.VoidInvoice(ctx, invoice.ID); err != nil {
+			return settled, err
+		}
+		if settled.first {
+			// Never started: no history
+			err = q.EndSubscription(ctx, postgres.EndSubscriptionParams{ID: sub.ID, EndedAt: sub.StartDate})
+		} else {
+			err = endSubscription(ctx, q, sub, dateOf(time.Now()))
+		}
+	} else {
+		err = q.SetSubscriptionStatus(ctx, postgres.SetSubscriptionStatusParams{
+			ID:     sub.ID,
+			Status: pgtype.Text{String: "past_due", Valid: true},
+		})
+	}
+	if err != nil {
+		return settled, err
+	}
+	user, err := q.GetUserById(ctx, invoice.UserID)
+	settled.email = user.Email
+	return settled, err
+}
+
+// activateSubscription starts a subscription whose first payment succeeded and grants its first month
+// of included credits.
+func activateSubscription(ctx context.Context, q postgres.Querier, sub postgres.Subscription) error {
+	item, err := subscriptionCatalogItem(ctx, q, sub)
+	if err != nil {
+		return err
+	}
+	if err := q.SetSubscriptionStatus(ctx, postgres.SetSubscriptionStatusParams{
+		ID:     sub.ID,
+		Status: pgtype.Text{String: "active", Valid: true},
+	}); err != nil {
+		return err
+	}
+	if err := recordSubscribed(ctx, q, sub); err != nil {
+		return err
+	}
+	return grantPlanCredits(ctx, q, sub, item, addMonths(sub.CurrentPeriodStart.Time, 1), false)
+}
+
+// notifyRenewalFailure tells the user by email that a subscription payment failed.
+func notifyRenewalFailure(ctx context.Context, sender email.EmailSender, settled invoiceSettlement) {
+	invoice := settled.invoice
+	amount := fmt.Sprintf("%.2f €", float64(invoice.AmountCents)/100)
+	subject := "Échec du paiement de votre abonnement"
+	body := fmt.Sprintf("Le paiement de %s pour votre abonnement %s n'a pas abouti. Une nouvelle tentative aura lieu dans 24 heures : pensez à vérifier votre moyen de paiement.", amount, invoice.Description)
+	switch {
+	case settled.first:
+		body = fmt.Sprintf("Le paiement de %s pour votre abonnement %s n'a pas abouti : votre abonnement n'a pas été activé.", amount, invoice.Description)
+	case settled.cancelled:
+		subject = "Votre abonnement a été résilié"
+		body = fmt.Sprintf("Après %d tentatives, le paiement de %s pour votre abonnement %s n'a pas pu être effectué. Votre abonnement a été résilié.", maxRenewalAttempts, amount, invoice.Description)
+	}
+	if err := sender.SendNotification(ctx, settled.email, subject, body); err != nil {
+		logger.FromContext(ctx).Warn("failed to notify user of failed subscription payment", zap.Int32("invoice_id", invoice.ID), zap.Error(err))
+	}
+}
@@ -0,0 +1,286 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+)
+
+type mockPaymentCollector struct {
+	mock.Mock
+}
+
+func (m *mockPaymentCollector) Collect(ctx context.Context, invoice postgres.Invoice) error {
+	return m.Called(ctx, invoice).Error(0)
+}
+
+func setupBilling() (*BillingService, *MockQuerier, *mockPaymentCollector, *mockEmailSender) {
+	mockQuerier := new(MockQuerier)
+	payments := new(mockPaymentCollector)
+	mockEmail := new(mockEmailSender)
+	svc := NewBillingService(passthroughTxManager{q: mockQuerier}, mockEmail, payments, zap.NewNop())
+	return svc, mockQuerier, payments, mockEmail
+}
+
+func pgDate(t time.Time) pgtype.Date { return pgtype.Date{Time: t, Valid: true} }
+
+// expectClaim lets the instance under test claim invoice and returns it as claimed.
+func expectClaim(mockQuerier *MockQuerier, invoice postgres.Invoice) postgres.Invoice {
+	claimed := invoice
+	claimed.Status = "processing"
+	mockQuerier.On("ClaimInvoiceForCollection", mock.Anything, mock.MatchedBy(func(p postgres.ClaimInvoiceForCollectionParams) bool {
+		return p.ID == invoice.ID
+	})).Return(claimed, nil).Once()
+	return claimed
+}
+
+func TestNextCycleDate(t *testing.T) {
+	anchor := day("2026-01-31")
+
+	assert.Equal(t, day("2026-02-28"), nextCycleDate(anchor, anchor, 1))
+	// Back to the 31st after the short month
+	assert.Equal(t, day("2026-03-31"), nextCycleDate(anchor, day("2026-02-28"), 1))
+	assert.Equal(t, day("2026-04-30"), nextCycleDate(anchor, day("2026-04-02"), 1))
+	assert.Equal(t, day("2027-01-31"), nextCycleDate(anchor, anchor, 12))
+	assert.Equal(t, day("2028-01-31"), nextCycleDate(anchor, day("2027-01-31"), 12))
+	// Leap year
+	assert.Equal(t, day("2029-02-28"), nextCycleDate(day("2028-02-29"), day("2028-02-29"), 12))
+}
+
+func TestRenewSubscriptions_PaidRenewalStartsNextPeriod(t *testing.T) {
+	svc, mockQuerier, payments, _ := setupBilling()
+	today := dateOf(time.Now())
+	start := addMonths(today, -12)
+	sub := postgres.Subscription{
+		ID: 3, UserID: pgtype.Int4{Int32: 1, Valid: true}, PlanType: postgres.SubPlanPremium,
+		Frequency: postgres.NullBillingFreq{BillingFreq: postgres.BillingFreqYearly, Valid: true},
+		Status:    pgtype.Text{String: "active", Valid: true}, StartDate: pgDate(start),
+		CurrentPeriodStart: pgDate(start), CurrentPeriodEnd: pgDate(today), CatalogItemID: pgtype.Int4{Int32: 3, Valid: true},
+	}
+	nextEnd := addMonths(start, 24)
+
+	mockQuerier.On("ListSubscriptionsDueForRenewal", mock.Anything, pgDate(today)).Return([]int32{3}, nil)
+	mockQuerier.On("GetSubscriptionForUpdate", mock.Anything, int32(3)).Return(sub, nil)
+	expectNoSlots(mockQuerier)
+	mockQuerier.On("GetCatalogItem", mock.Anything, int32(3)).Return(catalogPremium, nil)
+	mockQuerier.On("GetInvoiceForPeriod", mock.Anything, mock.Anything).Return(postgres.Invoice{}, pgx.ErrNoRows)
+	invoice := postgres.Invoice{ID: 11, UserID: 1, SubscriptionID: pgtype.Int4{Int32: 3, Valid: true}, AmountCents: 29900,
+		PeriodStart: pgDate(today), PeriodEnd: pgDate(nextEnd), Status: "open"}
+	mockQuerier.On("CreateInvoice", mock.Anything, mock.MatchedBy(func(p postgres.CreateInvoiceParams) bool {
+		return p.AmountCents == 29900 && p.PeriodStart.Time.Equal(today) && p.PeriodEnd.Time.Equal(nextEnd)
+	})).Return(invoice, nil)
+	payments.On("Collect", mock.Anything, expectClaim(mockQuerier, invoice)).Return(nil)
+	mockQuerier.On("MarkInvoicePaid", mock.Anything, int32(11)).Return(postgres.Invoice{ID: 11, UserID: 1, AmountCents: 29900, Status: "paid"}, nil)
+	mockQuerier.On("GetUserById", mock.Anything, int32(1)).Return(postgres.User{ID: 1, Email: "owner@test.com"}, nil)
+	expectInvoiceIssued(mockQuerier, invoiceSeries, 11, 1)
+	mockQuerier.On("AdvanceSubscriptionPeriod", mock.Anything, postgres.AdvanceSubscriptionPeriodParams{
+		ID: 3, PeriodStart: pgDate(today), PeriodEnd: pgDate(nextEnd),
+		// Yearly plans are prepaid until the end of the period
+		EndDate: pgDate(nextEnd),
+	}).Return(nil)
+
+	require.NoError(t, svc.RenewSubscriptions(context.Background()))
+	mockQuerier.AssertExpectations(t)
+	payments.AssertExpectations(t)
+}
+
+func TestRenewSubscriptions_FreePlanNeedsNoInvoice(t *testing.T) {
+	svc, mockQuerier, payments, _ := setupBilling()
+	today := dateOf(time.Now())
+	sub := postgres.Subscription{
+		ID: 4, UserID: pgtype.Int4{Int32: 1, Valid: true}, PlanType: postgres.SubPlanDiscovery,
+		Frequency: postgres.NullBillingFreq{BillingFreq: postgres.BillingFreqMonthly, Valid: true},
+		Status:    pgtype.Text{String: "active", Valid: true}, StartDate: pgDate(addMonths(today, -1)),
+		CurrentPeriodEnd: pgDate(today), CatalogItemID: pgtype.Int4{Int32: 1, Valid: true},
+	}
+
+	mockQuerier.On("ListSubscriptionsDueForRenewal", mock.Anything, mock.Anything).Return([]int32{4}, nil)
+	mockQuerier.On("GetSubscriptionForUpdate", mock.Anything, int32(4)).Return(sub, nil)
+	expectNoSlots(mockQuerier)
+	mockQuerier.On("GetCatalogItem", mock.Anything, int32(1)).Return(catalogDiscovery, nil)
+	mockQuerier.On("AdvanceSubscriptionPeriod", mock.Anything, mock.MatchedBy(func(p postgres.AdvanceSubscriptionPeriodParams) bool {
+		return p.ID == 4 && p.PeriodStart.Time.Equal(today) && !p.EndDate.Valid
+	})).Return(nil)
+
+	require.NoError(t, svc.RenewSubscriptions(context.Background()))
+	mockQuerier.AssertNotCalled(t, "CreateInvoice", mock.Anything, mock.Anything)
+	payments.AssertNotCalled(t, "Collect", mock.Anything, mock.Anything)
+}
+
+func TestRenewSubscriptions_FailedPaymentIsPastDue(t *testing.T) {
+	svc, mockQuerier, payments, mockEmail := setupBilling()
+	today := dateOf(time.Now())
+	sub := postgres.Subscription{
+		ID: 5, UserID: pgtype.Int4{Int32: 1, Valid: true}, PlanType: postgres.SubPlanSerenity,
+		Frequency: postgres.NullBillingFreq{BillingFreq: postgres.BillingFreqMonthly, Valid: true},
+		Status:    pgtype.Text{String: "active", Valid: true}, StartDate: pgDate(addMonths(today, -1)),
+		CurrentPeriodEnd: pgDate(today), CatalogItemID: pgtype.Int4{Int32: 2, Valid: true},
+	}
+	// Invoice left open by an interrupted run: not issued twice
+	invoice := postgres.Invoice{ID: 12, UserID: 1, SubscriptionID: pgtype.Int4{Int32: 5, Valid: true}, AmountCents: 990, Status: "open"}
+
+	mockQuerier.On("ListSubscriptionsDueForRenewal", mock.Anything, mock.Anything).Return([]int32{5}, nil)
+	mockQuerier.On("GetSubscriptionForUpdate", mock.Anything, int32(5)).Return(sub, nil)
+	expectNoSlots(mockQuerier)
+	mockQuerier.On("GetCatalogItem", mock.Anything, int32(2)).Return(catalogSerenity, nil)
+	mockQuerier.On("GetInvoiceForPeriod", mock.Anything, postgres.GetInvoiceForPeriodParams{
+		SubscriptionID: pgtype.Int4{Int32: 5, Valid: true}, PeriodStart: pgDate(today),
+	}).Return(invoice, nil)
+	payments.On("Collect", mock.Anything, expectClaim(mockQuerier, invoice)).Return(errors.New("card declined"))
+	mockQuerier.On("MarkInvoiceFailed", mock.Anything, int32(12)).Return(postgres.Invoice{ID: 12, Status: "failed", Attempts: 1}, nil)
+	mockQuerier.On("SetSubscriptionStatus", mock.Anything, postgres.SetSubscriptionStatusParams{
+		ID: 5, Status: pgtype.Text{String: "past_due", Valid: true},
+	}).Return(nil)
+	mockQuerier.On("GetUserById", mock.Anything, int32(1)).Return(postgres.User{ID: 1, Email: "owner@test.com"}, nil)
+	mockEmail.On("SendNotification", mock.Anything, "owner@test.com", "Échec du paiement de votre abonnement", mock.Anything).Return(nil)
+
+	require.NoError(t, svc.RenewSubscriptions(context.Background()))
+	mockQuerier.AssertNotCalled(t, "CreateInvoice", mock.Anything, mock.Anything)
+	mockQuerier.AssertNotCalled(t, "AdvanceSubscriptionPeriod", mock.Anything, mock.Anything)
+	mockEmail.AssertExpectations(t)
+}
+
+func TestRenewSubscriptions_PendingPaymentAwaitsWebhook(t *testing.T) {
+	svc, mockQuerier, payments, _ := setupBilling()
+	today := dateOf(time.Now())
+	sub := postgres.Subscription{
+		ID: 5, UserID: pgtype.Int4{Int32: 1, Valid: true}, PlanType: postgres.SubPlanSerenity,
+		Frequency: postgres.NullBillingFreq{BillingFreq: postgres.BillingFreqMonthly, Valid: true},
+		Status:    pgtype.Text{String: "active", Valid: true}, StartDate: pgDate(addMonths(today, -1)),
+		CurrentPeriodEnd: pgDate(today), CatalogItemID: pgtype.Int4{Int32: 2, Valid: true},
+	}
+	invoice := postgres.Invoice{ID: 12, UserID: 1, SubscriptionID: pgtype.Int4{Int32: 5, Valid: true}, AmountCents: 990, Status: "open"}
+
+	mockQuerier.On("ListSubscriptionsDueForRenewal", mock.Anything, mock.Anything).Return([]int32{5}, nil)
+	mockQuerier.On("GetSubscriptionForUpdate", mock.Anything, int32(5)).Return(sub, nil)
+	expectNoSlots(mockQuerier)
+	mockQuerier.On("GetCatalogItem", mock.Anything, int32(2)).Return(catalogSerenity, nil)
+	mockQuerier.On("GetInvoiceForPeriod", mock.Anything, mock.Anything).Return(invoice, nil).Once()
+	payments.On("Collect", mock.Anything, expectClaim(mockQuerier, invoice)).Return(ErrPaymentPending).Once()
+	mockQuerier.On("MarkInvoicePending", mock.Anything, int32(12)).Return(nil)
+
+	require.NoError(t, svc.RenewSubscriptions(context.Background()))
+
+	// Next run: the debit is still on its way, the pending invoice cannot be claimed to be charged again
+	pending := invoice
+	pending.Status = "pending"
+	mockQuerier.On("GetInvoiceForPeriod", mock.Anything, mock.Anything).Return(pending, nil)
+	mockQuerier.On("ClaimInvoiceForCollection", mock.Anything, mock.Anything).Return(postgres.Invoice{}, pgx.ErrNoRows)
+	require.NoError(t, svc.RenewSubscriptions(context.Background()))
+
+	payments.AssertNumberOfCalls(t, "Collect", 1)
+	mockQuerier.AssertNotCalled(t, "MarkInvoiceFailed", mock.Anything, mock.Anything)
+	mockQuerier.AssertNotCalled(t, "AdvanceSubscriptionPeriod", mock.Anything, mock.Anything)
+}
+
+func TestRetryFailedRenewals(t *testing.T) {
+	svc, mockQuerier, payments, mockEmail := setupBilling()
+	sub := postgres.Subscription{ID: 5, UserID: pgtype.Int4{Int32: 1, Valid: true},
+		Frequency: postgres.NullBillingFreq{BillingFreq: postgres.BillingFreqMonthly, Valid: true}}
+	recovered := postgres.Invoice{ID: 12, UserID: 1, SubscriptionID: pgtype.Int4{Int32: 5, Valid: true},
+		PeriodStart: pgDate(day("2026-05-03")), PeriodEnd: pgDate(day("2026-06-03")), Status: "failed", Attempts: 1}
+	lost := postgres.Invoice{ID: 13, UserID: 2, SubscriptionID: pgtype.Int4{Int32: 6, Valid: true}, Status: "failed", Attempts: 2}
+	// Retried by another instance since it was listed
+	taken := postgres.Invoice{ID: 14, UserID: 3, SubscriptionID: pgtype.Int4{Int32: 7, Valid: true}, Status: "failed", Attempts: 1}
+
+	mockQuerier.On("ListInvoicesToRetry", mock.Anything, mock.Anything).Return([]postgres.Invoice{recovered, lost, taken}, nil)
+	payments.On("Collect", mock.Anything, expectClaim(mockQuerier, recovered)).Return(nil)
+	payments.On("Collect", mock.Anything, expectClaim(mockQuerier, lost)).Return(errors.New("insufficient funds"))
+	mockQuerier.On("ClaimInvoiceForCollection", mock.Anything, mock.MatchedBy(func(p postgres.ClaimInvoiceForCollectionParams) bool {
+		return p.ID == 14
+	})).Return(postgres.Invoice{}, pgx.ErrNoRows)
+	mockQuerier.On("GetSubscriptionForUpdate", mock.Anything, int32(5)).Return(sub, nil)
+	mockQuerier.On("GetSubscriptionForUpdate", mock.Anything, int32(6)).Return(postgres.Subscription{ID: 6}, nil)
+
+	// Paid at last: back to active for the invoiced period
+	mockQuerier.On("MarkInvoicePaid", mock.Anything, int32(12)).Return(postgres.Invoice{ID: 12, UserID: 1, Status: "paid"}, nil)
+	mockQuerier.On("GetUserById", mock.Anything, int32(1)).Return(postgres.User{ID: 1, Email: "owner@test.com"}, nil)
+	expectInvoiceIssued(mockQuerier, invoiceSeries, 12, 1)
+	mockQuerier.On("AdvanceSubscriptionPeriod", mock.Anything, postgres.AdvanceSubscriptionPeriodParams{
+		ID: 5, PeriodStart: recovered.PeriodStart, PeriodEnd: recovered.PeriodEnd,
+	}).Return(nil)
+
+	// Third failure: cancelled
+	mockQuerier.On("MarkInvoiceFailed", mock.Anything, int32(13)).Return(postgres.Invoice{ID: 13, Status: "failed", Attempts: 3}, nil)
+	mockQuerier.On("VoidInvoice", mock.Anything, int32(13)).Return(nil)
+	mockQuerier.On("EndSubscription", mock.Anything, postgres.EndSubscriptionParams{ID: 6, EndedAt: pgDate(dateOf(time.Now()))}).Return(nil)
+	mockQuerier.On("CreateSubscriptionEvent", mock.Anything, mock.MatchedBy(func(p postgres.CreateSubscriptionEventParams) bool {
+		return p.SubscriptionID == 6 && p.EventType == SubscriptionEventCancelled
+	})).Return(postgres.SubscriptionEvent{}, nil)
+	mockQuerier.On("GetUserById", mock.Anything, int32(2)).Return(postgres.User{ID: 2, Email: "late@test.com"}, nil)
+	mockEmail.On("SendNotification", mock.Anything, "late@test.com", "Votre abonnement a été résilié", mock.Anything).Return(nil)
+
+	require.NoError(t, svc.RetryFailedRenewals(context.Background()))
+	mockQuerier.AssertExpectations(t)
+	mockEmail.AssertExpectations(t)
+	payments.AssertNumberOfCalls(t, "Collect", 2)
+}
+
+func TestGrantPlanCredits(t *testing.T) {
+	today := dateOf(time.Now())
+	start := addMonths(today, -1)
+	sub := postgres.Subscription{
+		ID: 3, UserID: pgtype.Int4{Int32: 1, Valid: true}, PlanType: postgres.SubPlanSerenity,
+		Status: pgtype.Text{String: "active", Valid: true}, StartDate: pgDate(start),
+		NextCreditGrant: pgDate(today), GrantedCredits: 20, CatalogItemID: pgtype.Int4{Int32: 2, Valid: true},
+	}
+
+	setup := func(expire bool) (*BillingService, *MockQuerier) {
+		svc, mockQuerier, _, _ := setupBilling()
+		svc.ExpirePlanCredits = expire
+		mockQuerier.On("ListSubscriptionsDueForCredits", mock.Anything, pgDate(today)).Return([]int32{3}, nil)
+		mockQuerier.On("GetSubscriptionForUpdate", mock.Anything, int32(3)).Return(sub, nil)
+		mockQuerier.On("GetCatalogItem", mock.Anything, int32(2)).Return(catalogSerenity, nil)
+		mockQuerier.On("CreateCreditTransaction", mock.Anything, mock.MatchedBy(func(p postgres.CreateCreditTransactionParams) bool {
+			return p.TransactionType == "plan_renewal" && p.Amount == 20
+		})).Return(postgres.CreditTransaction{}, nil).Once()
+		mockQuerier.On("RecordSubscriptionCreditGrant", mock.Anything, postgres.RecordSubscriptionCreditGrantParams{
+			ID: 3, NextCreditGrant: pgDate(addMonths(start, 2)), GrantedCredits: 20,
+		}).Return(nil)
+		return svc, mockQuerier
+	}
+
+	t.Run("unused credits roll over by default", func(t *testing.T) {
+		svc, mockQuerier := setup(false)
+
+		require.NoError(t, svc.GrantPlanCredits(context.Background()))
+		mockQuerier.AssertExpectations(t)
+		mockQuerier.AssertNotCalled(t, "GetCreditUsageSinceLastPlanGrant", mock.Anything, mock.Anything)
+	})
+
+	t.Run("unused plan credits expire, purchased ones stay", func(t *testing.T) {
+		svc, mockQuerier := setup(true)
+		uid := pgtype.Int4{Int32: 1, Valid: true}
+		mockQuerier.On("GetUserForUpdate", mock.Anything, int32(1)).Return(postgres.User{ID: 1}, nil)
+		// 20 plan credits + 20 from a pack, 5 spent since the last grant
+		mockQuerier.On("GetUserCreditBalanceForUpdate", mock.Anything, int32(1)).Return(int32(35), nil)
+		mockQuerier.On("GetCreditUsageSinceLastPlanGrant", mock.Anything, uid).Return(int32(5), nil)
+		mockQuerier.On("CreateCreditTransaction", mock.Anything, mock.MatchedBy(func(p postgres.CreateCreditTransactionParams) bool {
+			return p.TransactionType == "plan_expiry" && p.Amount == -15
+		})).Return(postgres.CreditTransaction{}, nil).Once()
+
+		require.NoError(t, svc.GrantPlanCredits(context.Background()))
+		mockQuerier.AssertExpectations(t)
+	})
+
+	t.Run("past due subscriptions are skipped", func(t *testing.T) {
+		svc, mockQuerier, _, _ := setupBilling()
+		pastDue := sub
+		pastDue.Status = pgtype.Text{String: "past_due", Valid: true}
+		mockQuerier.On("ListSubscriptionsDueForCredits", mock.Anything, mock.Anything).Return([]int32{3}, nil)
+		mockQuerier.On("GetSubscriptionForUpdate", mock.Anything, int32(3)).Return(pastDue, nil)
+
+		require.NoError(t, svc.GrantPlanCredits(context.Background()))
+		mockQuerier.AssertNotCalled(t, "CreateCreditTransaction", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,346 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+)
+
+// Kinds of catalog items
+const (
+	CatalogKindPlan = "plan"
+	CatalogKindPack = "pack"
+)
+
+var (
+	ErrCatalogItemNotFound = errors.New("catalog item not found")
+	ErrInvalidCatalogItem  = errors.New("invalid catalog item")
+	// ErrCatalogOverlap: two versions of an offer cannot be valid at the same time
+	ErrCatalogOverlap = errors.New("another version of this offer is valid over the same period")
+	// ErrCatalogItemInUse: a version already sold is never edited; retire it and create a new version
+	ErrCatalogItemInUse = errors.New("this version has been sold: retire it and create a new version")
+)
+
+var catalogCodePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// ValidCatalogCode tells whether code is a well-formed offer code (snake_case, e.g. "pack_20").
+func ValidCatalogCode(code string) bool {
+	return catalogCodePattern.MatchString(code)
+}
+
+// CatalogItem is a subscription plan or a credit pack. Prices are in cents. Successive versions of an
+// offer share its code and have disjoint validity periods.
+type CatalogItem struct {
+	ID                int32      `json:"id"`
+	Code              string     `json:"code"`
+	Kind              string     `json:"kind"` // plan or pack
+	Name              string     `json:"name"`
+	PlanType          string     `json:"plan_type,omitempty"` // plans: discovery, serenity or premium
+	MonthlyPriceCents *int32     `json:"monthly_price_cents,omitempty"`
+	YearlyPriceCents  *int32     `json:"yearly_price_cents,omitempty"`
+	PriceCents        *int32     `json:"price_cents,omitempty"` // packs
+	MaxProperties     int32      `json:"max_properties"`
+	IncludedCredits   int32      `json:"included_credits"`           // plans: per month; packs: in the pack
+	SlotPriceCents    *int32     `json:"slot_price_cents,omitempty"` // plans: monthly price of an extra property, nil if none can be added
+	ValidFrom         time.Time  `json:"valid_from"`
+	ValidUntil        *time.Time `json:"valid_until,omitempty"`
+}
+
+// CatalogService manages the offers catalog. Other services read it directly through activeCatalogItem.
+type CatalogService struct {
+	txManager TxManager
+	logger    *zap.Logger
+}
+
+func NewCatalogService(txManager TxManager, l *zap.Logger) *CatalogService {
+	return &CatalogService{txManager: txManager, logger: l}
+}
+
+func optionalCents(v *int32) pgtype.Int4 {
+	if v == nil {
+		return pgtype.Int4{}
+	}
+	return pgtype.Int4{Int32: *v, Valid: true}
+}
+
+func catalogItemFromRow(row postgres.CatalogItem) CatalogItem {
+	item := CatalogItem{
+		ID:                row.ID,
+		Code:              row.Code,
+		Kind:              row.Kind,
+		Name:              row.Name,
+		MonthlyPriceCents: optionalInt32(row.MonthlyPriceCents),
+		YearlyPriceCents:  optionalInt32(row.YearlyPriceCents),
+		PriceCents:        optionalInt32(row.PriceCents),
+		MaxProperties:     row.MaxProperties,
+		IncludedCredits:   row.IncludedCredits,
+		SlotPriceCents:    optionalInt32(row.SlotPriceCents),
+		ValidFrom:         row.ValidFrom.Time,
+	}
+	if row.PlanType.Valid {
+		item.PlanType = string(row.PlanType.SubPlan)
+	}
+	if row.ValidUntil.Valid {
+		until := row.ValidUntil.Time
+		item.ValidUntil = &until
+	}
+	return item
+}
+
+func catalogItemsFromRows(rows []postgres.CatalogItem) []CatalogItem {
+	items := make([]CatalogItem, 0, len(rows))
+	for _, r := range rows {
+		items = append(items, catalogItemFromRow(r))
+	}
+	return items
+}
+
+// activeCatalogItem returns the version of an offer valid now.
+func activeCatalogItem(ctx context.Context, q postgres.Querier, kind, code string) (postgres.CatalogItem, error) {
+	item, err := q.GetActiveCatalogItem(ctx, postgres.GetActiveCatalogItemParams{Kind: kind, Code: code})
+	if err == pgx.ErrNoRows {
+		return item, fmt.Errorf("%w: %s %s", ErrCatalogItemNotFound, kind, code)
+	}
+	return item, err
+}
+
+// planPriceCents is the price of a plan for a billing frequency.
+func planPriceCents(item postgres.CatalogItem, freq postgres.BillingFreq) int32 {
+	if freq == postgres.BillingFreqYearly {
+		return item.YearlyPriceCents.Int32
+	}
+	return item.MonthlyPriceCents.Int32
+}
+
+func validateCatalogItem(item CatalogItem) error {
+	if !ValidCatalogCode(item.Code) || item.Name == "" {
+		return fmt.Errorf("%w: a name and a snake_case code are required", ErrInvalidCatalogItem)
+	}
+	for _, cents := range []*int32{item.MonthlyPriceCents, item.YearlyPriceCents, item.PriceCents, item.SlotPriceCents} {
+		if cents != nil && *cents < 0 {
+			return fmt.Errorf("%w: prices cannot be negative", ErrInvalidCatalogItem)
+		}
+	}
+	if item.MaxProperties < 0 || item.IncludedCredits < 0 {
+		return fmt.Errorf("%w: limits cannot be negative", ErrInvalidCatalogItem)
+	}
+	switch item.Kind {
+	case CatalogKindPlan:
+		switch postgres.SubPlan(item.PlanType) {
+		case postgres.SubPlanDiscovery, postgres.SubPlanSerenity, postgres.SubPlanPremium:
+		default:
+			return fmt.Errorf("%w: invalid plan type %q", ErrInvalidCatalogItem, item.PlanType)
+		}
+		if item.MonthlyPriceCents == nil || item.YearlyPriceCents == nil {
+			return fmt.Errorf("%w: a plan needs a monthly and a yearly price", ErrInvalidCatalogItem)
+		}
+		if item.PriceCents != nil {
+			return fmt.Errorf("%w: a plan has no pack price", ErrInvalidCatalogItem)
+		}
+	case CatalogKindPack:
+		if item.PriceCents == nil || item.IncludedCredits == 0 {
+			return fmt.Errorf("%w: a pack needs a price and credits", ErrInvalidCatalogItem)
+		}
+		if item.PlanType != "" || item.MonthlyPriceCents != nil || item.YearlyPriceCents != nil || item.SlotPriceCents != nil || item.MaxProperties != 0 {
+			return fmt.Errorf("%w: a pack only has a price and credits", ErrInvalidCatalogItem)
+		}
+	default:
+		return fmt.Errorf("%w: invalid kind %q", ErrInvalidCatalogItem, item.Kind)
+	}
+	if item.ValidUntil != nil && !item.ValidUntil.After(item.ValidFrom) {
+		return fmt.Errorf("%w: valid_until must be after valid_from", ErrInvalidCatalogItem)
+	}
+	return nil
+}
+
+// ListActive returns the offers valid now: the public catalog.
+func (s *CatalogService) ListActive(ctx context.Context) ([]CatalogItem, error) {
+	var rows []postgres.CatalogItem
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		var err error
+		rows, err = q.ListActiveCatalogItems(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return catalogItemsFromRows(rows), nil
+}
+
+// List returns every version of every offer, past and future included.
+func (s *CatalogService) List(ctx context.Context) ([]CatalogItem, error) {
+	var rows []postgres.CatalogItem
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		var err error
+		rows, err = q.ListCatalogItems(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return catalogItemsFromRows(rows), nil
+}
+
+// checkOverlap refuses a validity period overlapping another version of the same offer.
+func checkOverlap(ctx context.Context, q postgres.Querier, id int32, item CatalogItem) error {
+	params := postgres.CountOverlappingCatalogItemsParams{
+		Code:      item.Code,
+		ExcludeID: id,
+		ValidFrom: pgtype.Timestamp{Time: item.ValidFrom, Valid: true},
+	}
+	if item.ValidUntil != nil {
+		params.ValidUntil = pgtype.Timestamp{Time: *item.ValidUntil, Valid: true}
+	}
+	n, err := q.CountOverlappingCatalogItems(ctx, params)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return ErrCatalogOverlap
+	}
+	return nil
+}
+
+// overlapViolation maps the exclusion constraint, which catches concurrent writes checkOverlap cannot see.
+func overlapViolation(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.ConstraintName == "catalog_items_no_overlap" {
+		return ErrCatalogOverlap
+	}
+	return err
+}
+
+// Create adds an offer or a new version of one. A zero ValidFrom means now.
+func (s *CatalogService) Create(ctx context.Context, item CatalogItem) (*CatalogItem, error) {
+	if item.ValidFrom.IsZero() {
+		item.ValidFrom = time.Now()
+	}
+	if err := validateCatalogItem(item); err != nil {
+		return nil, err
+	}
+
+	var row postgres.CatalogItem
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		if err := checkOverlap(ctx, q, 0, item); err != nil {
+			return err
+		}
+		params := postgres.CreateCatalogItemParams{
+			Code:              item.Code,
+			Kind:              item.Kind,
+			Name:              item.Name,
+			PlanType:          postgres.NullSubPlan{SubPlan: postgres.SubPlan(item.PlanType), Valid: item.PlanType != ""},
+			MonthlyPriceCents: optionalCents(item.MonthlyPriceCents),
+			YearlyPriceCents:  optionalCents(item.YearlyPriceCents),
+			PriceCents:        optionalCents(item.PriceCents),
+			MaxProperties:     item.MaxProperties,
+			IncludedCredits:   item.IncludedCredits,
+			SlotPriceCents:    optionalCents(item.SlotPriceCents),
+			ValidFrom:         pgtype.Timestamp{Time: item.ValidFrom, Valid: true},
+		}
+		if item.ValidUntil != nil {
+			params.ValidUntil = pgtype.Timestamp{Time: *item.ValidUntil, Valid: true}
+		}
+		var err error
+		row, err = q.CreateCatalogItem(ctx, params)
+		return overlapViolation(err)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("catalog item created", zap.Int32("id", row.ID), zap.String("code", row.Code))
+	created := catalogItemFromRow(row)
+	return &created, nil
+}
+
+// Update replaces an offer version that has not been sold yet, e.g. a scheduled price change. A version
+// referred to by a subscription, an invoice or a payment keeps its terms: it is retired and a new version
+// created instead.
+func (s *CatalogService) Update(ctx context.Context, id int32, item CatalogItem) (*CatalogItem, error) {
+	var row postgres.CatalogItem
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		current, err := q.GetCatalogItemForUpdate(ctx, id)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return ErrCatalogItemNotFound
+			}
+			return err
+		}
+		sold, err := q.IsCatalogItemReferenced(ctx, id)
+		if err != nil {
+			return err
+		}
+		if sold {
+			return ErrCatalogItemInUse
+		}
+		if item.ValidFrom.IsZero() {
+			item.ValidFrom = current.ValidFrom.Time
+		}
+		if err := validateCatalogItem(item); err != nil {
+			return err
+		}
+		if err := checkOverlap(ctx, q, id, item); err != nil {
+			return err
+		}
+
+		params := postgres.UpdateCatalogItemParams{
+			ID:                id,
+			Code:              item.Code,
+			Kind:              item.Kind,
+			Name:              item.Name,
+			PlanType:          postgres.NullSubPlan{SubPlan: postgres.SubPlan(item.PlanType), Valid: item.PlanType != ""},
+			MonthlyPriceCents: optionalCents(item.MonthlyPriceCents),
+			YearlyPriceCents:  optionalCents(item.YearlyPriceCents),
+			PriceCents:        optionalCents(item.PriceCents),
+			MaxProperties:     item.MaxProperties,
+			IncludedCredits:   item.IncludedCredits,
+			SlotPriceCents:    optionalCents(item.SlotPriceCents),
+			ValidFrom:         pgtype.Timestamp{Time: item.ValidFrom, Valid: true},
+		}
+		if item.ValidUntil != nil {
+			params.ValidUntil = pgtype.Timestamp{Time: *item.ValidUntil, Valid: true}
+		}
+		row, err = q.UpdateCatalogItem(ctx, params)
+		return overlapViolation(err)
+	})
+	if err != nil {
+		if errors.Is(err, ErrCatalogItemInUse) {
+			s.logger.Warn("refused to edit a sold catalog item", zap.Int32("id", id))
+		}
+		return nil, err
+	}
+
+	s.logger.Info("catalog item updated", zap.Int32("id", row.ID), zap.String("code", row.Code))
+	updated := catalogItemFromRow(row)
+	return &updated, nil
+}
+
+// Retire ends an offer's validity now. It can no longer be subscribed or bought; existing subscriptions
+// keep it.
+func (s *CatalogService) Retire(ctx context.Context, id int32) (*CatalogItem, error) {
+	var row postgres.CatalogItem
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		var err error
+		row, err = q.RetireCatalogItem(ctx, id)
+		if err == pgx.ErrNoRows {
+			return ErrCatalogItemNotFound
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("catalog item retired", zap.Int32("id", row.ID), zap.String("code", row.Code))
+	retired := catalogItemFromRow(row)
+	return &retired, nil
+}
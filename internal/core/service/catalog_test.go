@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+)
+
+func cents(v int32) pgtype.Int4 { return pgtype.Int4{Int32: v, Valid: true} }
+
+// Catalog rows as seeded by db/schemas.sql
+var (
+	catalogDiscovery = postgres.CatalogItem{
+		ID: 1, Code: "discovery", Kind: CatalogKindPlan, PlanType: postgres.NullSubPlan{SubPlan: postgres.SubPlanDiscovery, Valid: true},
+		MonthlyPriceCents: cents(0), YearlyPriceCents: cents(0), MaxProperties: 1,
+	}
+	catalogSerenity = postgres.CatalogItem{
+		ID: 2, Code: "serenity", Kind: CatalogKindPlan, PlanType: postgres.NullSubPlan{SubPlan: postgres.SubPlanSerenity, Valid: true},
+		MonthlyPriceCents: cents(990), YearlyPriceCents: cents(9900), MaxProperties: 1, IncludedCredits: 20, SlotPriceCents: cents(990),
+	}
+	catalogPremium = postgres.CatalogItem{
+		ID: 3, Code: "premium", Kind: CatalogKindPlan, PlanType: postgres.NullSubPlan{SubPlan: postgres.SubPlanPremium, Valid: true},
+		MonthlyPriceCents: cents(2990), YearlyPriceCents: cents(29900), MaxProperties: 5, IncludedCredits: 30, SlotPriceCents: cents(990),
+	}
+	catalogPack20 = postgres.CatalogItem{
+		ID: 4, Code: "pack_20", Kind: CatalogKindPack, PriceCents: cents(1990), IncludedCredits: 20,
+	}
+)
+
+func int32Ptr(v int32) *int32 { return &v }
+
+func TestValidateCatalogItem(t *testing.T) {
+	now := time.Now()
+	before := now.Add(-time.Hour)
+	plan := CatalogItem{Code: "premium_2027", Kind: CatalogKindPlan, Name: "Premium", PlanType: "premium",
+		MonthlyPriceCents: int32Ptr(3490), YearlyPriceCents: int32Ptr(34900), MaxProperties: 5, ValidFrom: now}
+	pack := CatalogItem{Code: "pack_50", Kind: CatalogKindPack, Name: "Pack 50", PriceCents: int32Ptr(3990), IncludedCredits: 50, ValidFrom: now}
+
+	assert.NoError(t, validateCatalogItem(plan))
+	assert.NoError(t, validateCatalogItem(pack))
+
+	invalid := map[string]func(i *CatalogItem){
+		"malformed code":       func(i *CatalogItem) { i.Code = "Premium 2027" },
+		"unknown plan type":    func(i *CatalogItem) { i.PlanType = "gold" },
+		"missing yearly":       func(i *CatalogItem) { i.YearlyPriceCents = nil },
+		"negative price":       func(i *CatalogItem) { i.MonthlyPriceCents = int32Ptr(-1) },
+		"plan with pack price": func(i *CatalogItem) { i.PriceCents = int32Ptr(100) },
+		"ends before start":    func(i *CatalogItem) { i.ValidUntil = &before },
+		"unknown kind":         func(i *CatalogItem) { i.Kind = "bundle" },
+	}
+	for name, change := range invalid {
+		item := plan
+		change(&item)
+		assert.ErrorIs(t, validateCatalogItem(item), ErrInvalidCatalogItem, name)
+	}
+
+	packWithSlots := pack
+	packWithSlots.SlotPriceCents = int32Ptr(990)
+	assert.ErrorIs(t, validateCatalogItem(packWithSlots), ErrInvalidCatalogItem)
+	packWithoutCredits := pack
+	packWithoutCredits.IncludedCredits = 0
+	assert.ErrorIs(t, validateCatalogItem(packWithoutCredits), ErrInvalidCatalogItem)
+}
+
+func TestCatalogCreate_RefusesOverlappingVersion(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	svc := NewCatalogService(passthroughTxManager{q: mockQuerier}, zap.NewNop())
+	from := time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)
+	item := CatalogItem{Code: "premium", Kind: CatalogKindPlan, Name: "Premium", PlanType: "premium",
+		MonthlyPriceCents: int32Ptr(3490), YearlyPriceCents: int32Ptr(34900), MaxProperties: 5, ValidFrom: from}
+
+	mockQuerier.On("CountOverlappingCatalogItems", mock.Anything, postgres.CountOverlappingCatalogItemsParams{
+		Code: "premium", ValidFrom: pgtype.Timestamp{Time: from, Valid: true},
+	}).Return(int64(1), nil).Once()
+
+	_, err := svc.Create(context.Background(), item)
+	assert.ErrorIs(t, err, ErrCatalogOverlap)
+
+	// Once the current version ends when the new one starts
+	mockQuerier.On("CountOverlappingCatalogItems", mock.Anything, mock.Anything).Return(int64(0), nil)
+	mockQuerier.On("CreateCatalogItem", mock.Anything, mock.MatchedBy(func(p postgres.CreateCatalogItemParams) bool {
+		return p.PlanType.SubPlan == postgres.SubPlanPremium && p.MonthlyPriceCents.Int32 == 3490 && !p.PriceCents.Valid && !p.ValidUntil.Valid
+	})).Return(postgres.CatalogItem{ID: 9, Code: "premium", Kind: CatalogKindPlan, MonthlyPriceCents: cents(3490)}, nil)
+
+	created, err := svc.Create(context.Background(), item)
+	require.NoError(t, err)
+	assert.Equal(t, int32(3490), *created.MonthlyPriceCents)
+	assert.Nil(t, created.PriceCents)
+}
+
+func TestCatalogCreate_ConcurrentOverlap(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	svc := NewCatalogService(passthroughTxManager{q: mockQuerier}, zap.NewNop())
+	item := CatalogItem{Code: "pack_50", Kind: CatalogKindPack, Name: "Pack 50", PriceCents: int32Ptr(3990), IncludedCredits: 50}
+
+	// Another version was created between the check and the insert: the exclusion constraint refuses it
+	mockQuerier.On("CountOverlappingCatalogItems", mock.Anything, mock.Anything).Return(int64(0), nil)
+	mockQuerier.On("CreateCatalogItem", mock.Anything, mock.Anything).
+		Return(postgres.CatalogItem{}, &pgconn.PgError{Code: "23P01", ConstraintName: "catalog_items_no_overlap"})
+
+	_, err := svc.Create(context.Background(), item)
+	assert.ErrorIs(t, err, ErrCatalogOverlap)
+}
+
+func TestCatalogUpdate_RefusesSoldVersion(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	svc := NewCatalogService(passthroughTxManager{q: mockQuerier}, zap.NewNop())
+	item := CatalogItem{Code: "premium", Kind: CatalogKindPlan, Name: "Premium", PlanType: "premium",
+		MonthlyPriceCents: int32Ptr(3490), YearlyPriceCents: int32Ptr(34900), MaxProperties: 5}
+
+	mockQuerier.On("GetCatalogItemForUpdate", mock.Anything, catalogPremium.ID).Return(catalogPremium, nil)
+	mockQuerier.On("IsCatalogItemReferenced", mock.Anything, catalogPremium.ID).Return(true, nil)
+
+	_, err := svc.Update(context.Background(), catalogPremium.ID, item)
+	assert.ErrorIs(t, err, ErrCatalogItemInUse)
+	mockQuerier.AssertNotCalled(t, "UpdateCatalogItem", mock.Anything, mock.Anything)
+
+	// A scheduled version nobody has bought yet can still be corrected
+	scheduled := catalogPremium
+	scheduled.ID = 9
+	scheduled.ValidFrom = pgtype.Timestamp{Time: time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), Valid: true}
+	mockQuerier.On("GetCatalogItemForUpdate", mock.Anything, int32(9)).Return(scheduled, nil)
+	mockQuerier.On("IsCatalogItemReferenced", mock.Anything, int32(9)).Return(false, nil)
+	mockQuerier.On("CountOverlappingCatalogItems", mock.Anything, mock.Anything).Return(int64(0), nil)
+	mockQuerier.On("UpdateCatalogItem", mock.Anything, mock.MatchedBy(func(p postgres.UpdateCatalogItemParams) bool {
+		return p.ID == 9 && p.MonthlyPriceCents.Int32 == 3490 && p.ValidFrom == scheduled.ValidFrom
+	})).Return(postgres.CatalogItem{ID: 9, Code: "premium", Kind: CatalogKindPlan, MonthlyPriceCents: cents(3490)}, nil)
+
+	updated, err := svc.Update(context.Background(), 9, item)
+	require.NoError(t, err)
+	assert.Equal(t, int32(3490), *updated.MonthlyPriceCents)
+}
+
+func TestSubscribeUser_UnknownPlan(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	svc := NewSubscriptionService(passthroughTxManager{q: mockQuerier}, nil, zap.NewNop())
+	mockQuerier.On("GetUserSubscription", mock.Anything, mock.Anything).Return(postgres.Subscription{}, pgx.ErrNoRows)
+	mockQuerier.On("GetActiveCatalogItem", mock.Anything, mock.Anything).Return(postgres.CatalogItem{}, pgx.ErrNoRows)
+
+	_, err := svc.SubscribeUser(context.Background(), 1, "platinum", "monthly", "")
+
+	assert.ErrorIs(t, err, ErrCatalogItemNotFound)
+	mockQuerier.AssertNotCalled(t, "CreateSubscription", mock.Anything, mock.Anything)
+}
+
+func TestIncreaseLimit_UsesSubscribedVersion(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	svc := NewSubscriptionService(passthroughTxManager{q: mockQuerier}, nil, zap.NewNop())
+	// Subscribed to a version of premium that did not allow extra slots
+	noSlots := catalogPremium
+	noSlots.ID, noSlots.SlotPriceCents = 7, pgtype.Int4{}
+	mockQuerier.On("GetUserSubscriptionForUpdate", mock.Anything, pgtype.Int4{Int32: 1, Valid: true}).Return(postgres.Subscription{
+		ID: 1, PlanType: postgres.SubPlanPremium, CatalogItemID: pgtype.Int4{Int32: 7, Valid: true},
+		Status: pgtype.Text{String: "active", Valid: true},
+	}, nil)
+	mockQuerier.On("GetCatalogItem", mock.Anything, int32(7)).Return(noSlots, nil)
+
+	_, err := svc.IncreaseLimit(context.Background(), 1, 2, "")
+
+	assert.ErrorIs(t, err, ErrSlotsNotAvailable)
+	assert.ErrorContains(t, err, "plan not eligible")
+	mockQuerier.AssertNotCalled(t, "GetActiveCatalogItem", mock.Anything, mock.Anything)
+	mockQuerier.AssertNotCalled(t, "AddSubscriptionItemQuantity", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,294 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+	"seculoc-back/internal/platform/logger"
+)
+
+const (
+	// Wallets of the credit ledger: the owner's global wallet or the vacancy credits of a property.
+	CreditWalletGlobal   = "global"
+	CreditWalletProperty = "property"
+
+	defaultCreditPageSize = 20
+	maxCreditPageSize     = 100
+)
+
+// creditTransactionTypes are the types of the credit ledger entries (see credit_transactions).
+var creditTransactionTypes = []string{
+	"plan_renewal", "plan_upgrade", "plan_expiry", "pack_purchase", "pack_refund", "check_usage", "initial_free", "refund",
+	"property_grant", "opening_balance", "transfer",
+}
+
+var (
+	ErrInvalidCreditTransactionType = errors.New("invalid credit transaction type")
+	ErrCreditAccountNotFound        = errors.New("credit wallet not found")
+	ErrInvalidCreditTransfer        = errors.New("credits can only be transferred between two different wallets, to an active property")
+	ErrNotEnoughWalletCredits       = errors.New("not enough credits in the wallet")
+	ErrInactivePropertyWallet       = errors.New("credits can only be added to the wallet of an active property")
+)
+
+// CreditService exposes the credit ledger to the users, moves credits between their wallets and checks
+// the running balances against the ledger.
+type CreditService struct {
+	txManager TxManager
+}
+
+func NewCreditService(txManager TxManager, l *zap.Logger) *CreditService {
+	return &CreditService{txManager: txManager}
+}
+
+type CreditTransactionDTO struct {
+	ID     int32  `json:"id"`
+	Type   string `json:"type"`
+	Amount int32  `json:"amount"`
+	// BalanceAfter is the balance of the wallet moved (global or property) after the entry
+	BalanceAfter int32  `json:"balance_after"`
+	Wallet       string `json:"wallet"`
+	PropertyID   *int32 `json:"property_id,omitempty"`
+	PropertyName string `json:"property_name,omitempty"`
+	Description  string `json:"description"`
+	CreatedAt    string `json:"created_at"`
+}
+
+type CreditTransactionPage struct {
+	Transactions []CreditTransactionDTO `json:"transactions"`
+	Page         int                    `json:"page"`
+	PageSize     int                    `json:"page_size"`
+	Total        int64                  `json:"total"`
+}
+
+// ListTransactions returns a page of the user's credit ledger, latest first, global and property wallets
+// together. txType filters on a transaction type when set; page starts at 1.
+func (s *CreditService) ListTransactions(ctx context.Context, userID int32, txType string, page, pageSize int) (*CreditTransactionPage, error) {
+	if txType != "" && !slices.Contains(creditTransactionTypes, txType) {
+		return nil, ErrInvalidCreditTransactionType
+	}
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = defaultCreditPageSize
+	}
+	pageSize = min(pageSize, maxCreditPageSize)
+
+	uid := pgtype.Int4{Int32: userID, Valid: true}
+	filter := pgtype.Text{String: txType, Valid: txType != ""}
+	result := &CreditTransactionPage{Transactions: []CreditTransactionDTO{}, Page: page, PageSize: pageSize}
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		rows, err := q.ListCreditTransactionsPage(ctx, postgres.ListCreditTransactionsPageParams{
+			UserID:          uid,
+			TransactionType: filter,
+			Limit:           int32(pageSize),
+			Offset:          int32((page - 1) * pageSize),
+		})
+		if err != nil {
+			return err
+		}
+		for _, r := range rows {
+			result.Transactions = append(result.Transactions, toCreditTransactionDTO(r))
+		}
+
+		result.Total, err = q.CountCreditTransactions(ctx, postgres.CountCreditTransactionsParams{UserID: uid, TransactionType: filter})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func toCreditTransactionDTO(r postgres.ListCreditTransactionsPageRow) CreditTransactionDTO {
+	dto := CreditTransactionDTO{
+		ID:           r.ID,
+		Type:         r.TransactionType,
+		Amount:       r.Amount,
+		BalanceAfter: r.BalanceAfter.Int32,
+		Wallet:       CreditWalletGlobal,
+		Description:  r.Description.String,
+		CreatedAt:    r.CreatedAt.Time.Format(time.RFC3339),
+	}
+	if r.PropertyID.Valid {
+		dto.Wallet = CreditWalletProperty
+		dto.PropertyID = &r.PropertyID.Int32
+		dto.PropertyName = r.PropertyName.String
+	}
+	return dto
+}
+
+type CreditAccountDTO struct {
+	ID              int32  `json:"id"`
+	Wallet          string `json:"wallet"`
+	Balance         int32  `json:"balance"`
+	PropertyID      *int32 `json:"property_id,omitempty"`
+	PropertyName    string `json:"property_name,omitempty"`
+	PropertyAddress string `json:"property_address,omitempty"`
+	// IsActive is false for the wallet of an archived property: it can only be emptied
+	IsActive bool `json:"is_active"`
+}
+
+// ListAccounts returns the wallets of the user: the global one, then one per property.
+func (s *CreditService) ListAccounts(ctx context.Context, userID int32) ([]CreditAccountDTO, error) {
+	var accounts []CreditAccountDTO
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		var err error
+		accounts, err = creditAccounts(ctx, q, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+func creditAccounts(ctx context.Context, q postgres.Querier, userID int32) ([]CreditAccountDTO, error) {
+	rows, err := q.ListCreditAccountsByUser(ctx, pgtype.Int4{Int32: userID, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+	accounts := make([]CreditAccountDTO, 0, len(rows))
+	for _, r := range rows {
+		dto := CreditAccountDTO{ID: r.ID, Wallet: r.Kind, Balance: r.Balance, IsActive: r.IsActive}
+		if r.PropertyID.Valid {
+			dto.PropertyID = &r.PropertyID.Int32
+			dto.PropertyName = r.PropertyName.String
+			dto.PropertyAddress = r.PropertyAddress.String
+		}
+		accounts = append(accounts, dto)
+	}
+	return accounts, nil
+}
+
+// TransferCredits moves credits between two wallets of the user, e.g. from the global wallet to a property,
+// as one balanced entry. It returns the user's wallets after the transfer.
+func (s *CreditService) TransferCredits(ctx context.Context, userID, fromAccountID, toAccountID, amount int32) ([]CreditAccountDTO, error) {
+	if amount <= 0 || fromAccountID == toAccountID {
+		return nil, ErrInvalidCreditTransfer
+	}
+
+	var accounts []CreditAccountDTO
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		// Properties are locked before wallets, as by checks and refunds (the posting trigger updates the
+		// property of a wallet): first the properties of both wallets, then the wallets, each in id order
+		ids := []int32{fromAccountID, toAccountID}
+		slices.Sort(ids)
+		var properties []int32
+		for _, id := range ids {
+			propertyID, err := q.GetCreditAccountProperty(ctx, id)
+			if err == pgx.ErrNoRows {
+				return ErrCreditAccountNotFound
+			}
+			if err != nil {
+				return err
+			}
+			if propertyID.Valid {
+				properties = append(properties, propertyID.Int32)
+			}
+		}
+		slices.Sort(properties)
+		for _, id := range properties {
+			if _, err := q.GetPropertyForUpdate(ctx, id); err != nil {
+				return err
+			}
+		}
+
+		locked := make(map[int32]postgres.GetCreditAccountForUpdateRow, len(ids))
+		for _, id := range ids {
+			account, err := q.GetCreditAccountForUpdate(ctx, id)
+			if err == pgx.ErrNoRows || (err == nil && account.UserID.Int32 != userID) {
+				return ErrCreditAccountNotFound
+			}
+			if err != nil {
+				return err
+			}
+			locked[id] = account
+		}
+		if !locked[toAccountID].IsActive {
+			return ErrInvalidCreditTransfer
+		}
+		if locked[fromAccountID].Balance < amount {
+			return ErrNotEnoughWalletCredits
+		}
+
+		_, err := q.CreateCreditTransfer(ctx, postgres.CreateCreditTransferParams{
+			FromAccountID: fromAccountID,
+			ToAccountID:   toAccountID,
+			Amount:        amount,
+			Description:   pgtype.Text{String: transferDescription(locked[fromAccountID], locked[toAccountID]), Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to transfer credits: %w", err)
+		}
+		accounts, err = creditAccounts(ctx, q, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx).Info("credits transferred", zap.Int32("user_id", userID),
+		zap.Int32("from_account_id", fromAccountID), zap.Int32("to_account_id", toAccountID), zap.Int32("amount", amount))
+	return accounts, nil
+}
+
+func transferDescription(from, to postgres.GetCreditAccountForUpdateRow) string {
+	wallet := func(a postgres.GetCreditAccountForUpdateRow) string {
+		if a.PropertyID.Valid {
+			return fmt.Sprintf("property #%d", a.PropertyID.Int32)
+		}
+		return "global wallet"
+	}
+	return fmt.Sprintf("Transfer from %s to %s", wallet(from), wallet(to))
+}
+
+// ReconcileBalances checks the running balances against the ledger: the balance of each wallet with the
+// sum of its lines, the vacancy credits of each property with the balance of its wallet, and that every
+// entry is balanced. Drifts are logged and reported as an error; they are not corrected, the ledger is
+// the reference.
+func (s *CreditService) ReconcileBalances(ctx context.Context) error {
+	var balances []postgres.ListCreditBalanceDriftsRow
+	var properties []postgres.ListPropertyCreditDriftsRow
+	var entries []postgres.ListUnbalancedCreditEntriesRow
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		var err error
+		if balances, err = q.ListCreditBalanceDrifts(ctx); err != nil {
+			return err
+		}
+		if properties, err = q.ListPropertyCreditDrifts(ctx); err != nil {
+			return err
+		}
+		entries, err = q.ListUnbalancedCreditEntries(ctx)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reconcile credit balances: %w", err)
+	}
+
+	log := logger.FromContext(ctx)
+	for _, d := range balances {
+		log.Error("credit balance drifted from the ledger", zap.Int32("account_id", d.AccountID),
+			zap.String("wallet", d.Kind), zap.Int32("user_id", d.UserID.Int32),
+			zap.Int32("balance", d.Balance), zap.Int32("ledger_balance", d.LedgerBalance))
+	}
+	for _, d := range properties {
+		log.Error("property credits drifted from the ledger", zap.Int32("property_id", d.PropertyID),
+			zap.Int32("vacancy_credits", d.VacancyCredits), zap.Int32("ledger_balance", d.LedgerBalance))
+	}
+	for _, e := range entries {
+		log.Error("credit entry is not balanced", zap.Int32("entry_id", e.EntryID.Int32), zap.Int32("total", e.Total))
+	}
+	if n := len(balances) + len(properties) + len(entries); n > 0 {
+		return fmt.Errorf("%d credit balances drifted from the ledger", n)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+)
+
+func TestListCreditTransactions(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	svc := NewCreditService(passthroughTxManager{q: mockQuerier}, zap.NewNop())
+	uid := pgtype.Int4{Int32: 1, Valid: true}
+	filter := pgtype.Text{String: "check_usage", Valid: true}
+
+	mockQuerier.On("ListCreditTransactionsPage", mock.Anything, postgres.ListCreditTransactionsPageParams{
+		UserID: uid, TransactionType: filter, Limit: 100, Offset: 100,
+	}).Return([]postgres.ListCreditTransactionsPageRow{
+		{ID: 9, UserID: uid, Amount: -1, TransactionType: "check_usage", BalanceAfter: pgtype.Int4{Int32: 19, Valid: true},
+			PropertyID: pgtype.Int4{Int32: 5, Valid: true}, PropertyName: pgtype.Text{String: "T2 Lyon", Valid: true}},
+		{ID: 7, UserID: uid, Amount: -1, TransactionType: "check_usage", BalanceAfter: pgtype.Int4{Int32: 2, Valid: true}},
+	}, nil)
+	mockQuerier.On("CountCreditTransactions", mock.Anything, postgres.CountCreditTransactionsParams{UserID: uid, TransactionType: filter}).Return(int64(102), nil)
+
+	page, err := svc.ListTransactions(context.Background(), 1, "check_usage", 2, 500)
+
+	require.NoError(t, err)
+	assert.Equal(t, 100, page.PageSize, "page size is capped")
+	assert.Equal(t, int64(102), page.Total)
+	require.Len(t, page.Transactions, 2)
+	assert.Equal(t, CreditWalletProperty, page.Transactions[0].Wallet)
+	assert.Equal(t, int32(5), *page.Transactions[0].PropertyID)
+	assert.Equal(t, "T2 Lyon", page.Transactions[0].PropertyName)
+	assert.Equal(t, CreditWalletGlobal, page.Transactions[1].Wallet)
+	assert.Nil(t, page.Transactions[1].PropertyID)
+
+	_, err = svc.ListTransactions(context.Background(), 1, "bonus", 1, 20)
+	assert.ErrorIs(t, err, ErrInvalidCreditTransactionType)
+}
+
+func TestReconcileBalances_ReportsDrift(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	svc := NewCreditService(passthroughTxManager{q: mockQuerier}, zap.NewNop())
+
+	mockQuerier.On("ListCreditBalanceDrifts", mock.Anything).Return([]postgres.ListCreditBalanceDriftsRow{
+		{AccountID: 3, Kind: CreditWalletGlobal, UserID: pgtype.Int4{Int32: 1, Valid: true}, Balance: 12, LedgerBalance: 10},
+	}, nil).Once()
+	mockQuerier.On("ListPropertyCreditDrifts", mock.Anything).Return([]postgres.ListPropertyCreditDriftsRow(nil), nil)
+	mockQuerier.On("ListUnbalancedCreditEntries", mock.Anything).Return([]postgres.ListUnbalancedCreditEntriesRow(nil), nil)
+
+	err := svc.ReconcileBalances(context.Background())
+	assert.ErrorContains(t, err, "1 credit balances drifted")
+
+	mockQuerier.On("ListCreditBalanceDrifts", mock.Anything).Return([]postgres.ListCreditBalanceDriftsRow(nil), nil)
+	assert.NoError(t, svc.ReconcileBalances(context.Background()))
+}
+
+func TestTransferCredits(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	svc := NewCreditService(passthroughTxManager{q: mockQuerier}, zap.NewNop())
+	owner := pgtype.Int4{Int32: 1, Valid: true}
+
+	for account, property := range map[int32]pgtype.Int4{3: {}, 8: {Int32: 5, Valid: true}, 9: {Int32: 6, Valid: true}, 12: {}} {
+		mockQuerier.On("GetCreditAccountProperty", mock.Anything, account).Return(property, nil)
+	}
+	mockQuerier.On("GetPropertyForUpdate", mock.Anything, int32(5)).Return(postgres.Property{ID: 5}, nil)
+	mockQuerier.On("GetPropertyForUpdate", mock.Anything, int32(6)).Return(postgres.Property{ID: 6}, nil)
+	mockQuerier.On("GetCreditAccountForUpdate", mock.Anything, int32(3)).Return(postgres.GetCreditAccountForUpdateRow{
+		ID: 3, Kind: CreditWalletGlobal, UserID: owner, Balance: 10, IsActive: true}, nil)
+	mockQuerier.On("GetCreditAccountForUpdate", mock.Anything, int32(8)).Return(postgres.GetCreditAccountForUpdateRow{
+		ID: 8, Kind: CreditWalletProperty, UserID: owner, PropertyID: pgtype.Int4{Int32: 5, Valid: true}, IsActive: true}, nil)
+	mockQuerier.On("GetCreditAccountForUpdate", mock.Anything, int32(9)).Return(postgres.GetCreditAccountForUpdateRow{
+		ID: 9, Kind: CreditWalletProperty, UserID: owner, PropertyID: pgtype.Int4{Int32: 6, Valid: true}, IsActive: false}, nil)
+	mockQuerier.On("GetCreditAccountForUpdate", mock.Anything, int32(12)).Return(postgres.GetCreditAccountForUpdateRow{
+		ID: 12, Kind: CreditWalletGlobal, UserID: pgtype.Int4{Int32: 2, Valid: true}, Balance: 50, IsActive: true}, nil)
+	mockQuerier.On("CreateCreditTransfer", mock.Anything, postgres.CreateCreditTransferParams{
+		FromAccountID: 3, ToAccountID: 8, Amount: 4,
+		Description: pgtype.Text{String: "Transfer from global wallet to property #5", Valid: true},
+	}).Return([]postgres.CreditTransaction{{ID: 20}, {ID: 21}}, nil).Once()
+	mockQuerier.On("ListCreditAccountsByUser", mock.Anything, owner).Return([]postgres.ListCreditAccountsByUserRow{
+		{ID: 3, Kind: CreditWalletGlobal, Balance: 6, IsActive: true},
+		{ID: 8, Kind: CreditWalletProperty, PropertyID: pgtype.Int4{Int32: 5, Valid: true}, Balance: 4, PropertyName: pgtype.Text{String: "T2 Lyon", Valid: true}, IsActive: true},
+	}, nil)
+
+	accounts, err := svc.TransferCredits(context.Background(), 1, 3, 8, 4)
+	require.NoError(t, err)
+	require.Len(t, accounts, 2)
+	assert.Equal(t, int32(6), accounts[0].Balance)
+	assert.Equal(t, int32(5), *accounts[1].PropertyID)
+	assert.Equal(t, int32(4), accounts[1].Balance)
+	// The property is locked before the wallets, as by checks
+	var locks []string
+	for _, c := range mockQuerier.Calls {
+		if strings.HasSuffix(c.Method, "ForUpdate") {
+			locks = append(locks, c.Method)
+		}
+	}
+	assert.Equal(t, []string{"GetPropertyForUpdate", "GetCreditAccountForUpdate", "GetCreditAccountForUpdate"}, locks)
+
+	_, err = svc.TransferCredits(context.Background(), 1, 3, 8, 11)
+	assert.ErrorIs(t, err, ErrNotEnoughWalletCredits)
+	_, err = svc.TransferCredits(context.Background(), 1, 3, 9, 1)
+	assert.ErrorIs(t, err, ErrInvalidCreditTransfer, "archived property")
+	_, err = svc.TransferCredits(context.Background(), 1, 3, 3, 1)
+	assert.ErrorIs(t, err, ErrInvalidCreditTransfer)
+	_, err = svc.TransferCredits(context.Background(), 1, 3, 8, 0)
+	assert.ErrorIs(t, err, ErrInvalidCreditTransfer)
+	_, err = svc.TransferCredits(context.Background(), 1, 12, 3, 1)
+	assert.ErrorIs(t, err, ErrCreditAccountNotFound, "wallet of another user")
+	mockQuerier.AssertExpectations(t)
+}
@@ -0,0 +1,347 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+	"seculoc-back/internal/platform/auth"
+	"seculoc-back/internal/platform/logger"
+)
+
+const (
+	DocumentTypeLease          = "lease"
+	DocumentTypeSolvencyReport = "solvency_report"
+	DocumentTypeGuaranteeDeed  = "guarantee_deed"
+	DocumentTypeInvoice        = "invoice"
+
+	defaultDocumentLinkTTL = 15 * time.Minute
+	maxDocumentLinkTTL     = 24 * time.Hour
+)
+
+var (
+	ErrDocumentNotFound     = errors.New("document not found")
+	ErrDocumentAccessDenied = errors.New("access denied to this document")
+	ErrInvalidDocumentLink  = errors.New("invalid document link")
+	ErrDocumentLinkExpired  = errors.New("document link expired or revoked")
+)
+
+// storeDocumentVersion saves content as the next immutable version of a document and records it.
+// It must run inside the caller's transaction so the version number stays consistent.
+func storeDocumentVersion(ctx context.Context, q postgres.Querier, storage FileStorage, docType string, entityID int32, ext, contentType, filename string, content []byte) (postgres.Document, error) {
+	version, err := q.GetNextDocumentVersion(ctx, postgres.GetNextDocumentVersionParams{DocumentType: docType, EntityID: entityID})
+	if err != nil {
+		return postgres.Document{}, err
+	}
+
+	storageKey := fmt.Sprintf("documents/%s/%d/v%d%s", docType, entityID, version, ext)
+	if _, err := storage.Save(storageKey, content); err != nil {
+		return postgres.Document{}, fmt.Errorf("failed to store document: %w", err)
+	}
+
+	return q.CreateDocument(ctx, postgres.CreateDocumentParams{
+		DocumentType: docType,
+		EntityID:     entityID,
+		Version:      version,
+		StorageKey:   storageKey,
+		ContentType:  contentType,
+		Filename:     filename,
+	})
+}
+
+// authorizeDocument checks that userID is a party to the entity the document belongs to.
+func authorizeDocument(ctx context.Context, q postgres.Querier, doc postgres.Document, userID int32) error {
+	leaseParty := func(leaseID int32) error {
+		lease, err := q.GetLease(ctx, leaseID)
+		if err != nil {
+			return ErrDocumentAccessDenied
+		}
+		if lease.TenantID.Valid && lease.TenantID.Int32 == userID {
+			return nil
+		}
+		prop, err := q.GetProperty(ctx, lease.PropertyID.Int32)
+		if err == nil && prop.OwnerID.Int32 == userID {
+			return nil
+		}
+		// Co-tenants who joined the lease
+		if tenant, err := isLeaseTenant(ctx, q, lease, userID); err != nil || !tenant {
+			return ErrDocumentAccessDenied
+		}
+		return nil
+	}
+
+	switch doc.DocumentType {
+	case DocumentTypeLease:
+		return leaseParty(doc.EntityID)
+	case DocumentTypeSolvencyReport:
+		check, err := q.GetSolvencyCheckByID(ctx, doc.EntityID)
+		if err != nil {
+			return ErrDocumentAccessDenied
+		}
+		if check.InitiatorOwnerID.Int32 != userID && check.CandidateID.Int32 != userID {
+			return ErrDocumentAccessDenied
+		}
+		return nil
+	case DocumentTypeGuaranteeDeed:
+		// The guarantor has no account: they read their deed through their own link
+		guarantor, err := q.GetSolvencyGuarantor(ctx, doc.EntityID)
+		if err != nil || !guarantor.LeaseID.Valid {
+			return ErrDocumentAccessDenied
+		}
+		return leaseParty(guarantor.LeaseID.Int32)
+	case DocumentTypeInvoice:
+		invoice, err := q.GetInvoice(ctx, doc.EntityID)
+		if err != nil || invoice.UserID != userID {
+			return ErrDocumentAccessDenied
+		}
+		return nil
+	default:
+		return ErrDocumentAccessDenied
+	}
+}
+
+type DocumentService struct {
+	txManager TxManager
+	storage   FileStorage
+	log       *zap.Logger
+	linkTTL   time.Duration
+}
+
+func NewDocumentService(txManager TxManager, storage FileStorage, l *zap.Logger) *DocumentService {
+	ttl := viper.GetDuration("DOCUMENT_LINK_TTL")
+	if ttl <= 0 {
+		ttl = defaultDocumentLinkTTL
+	}
+	return &DocumentService{txManager: txManager, storage: storage, log: l, linkTTL: ttl}
+}
+
+type DocumentDTO struct {
+	ID          int32  `json:"id"`
+	Type        string `json:"type"`
+	EntityID    int32  `json:"entity_id"`
+	Version     int32  `json:"version"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	CreatedAt   string `json:"created_at"`
+}
+
+func toDocumentDTO(d postgres.Document) DocumentDTO {
+	return DocumentDTO{
+		ID:          d.ID,
+		Type:        d.DocumentType,
+		EntityID:    d.EntityID,
+		Version:     d.Version,
+		Filename:    d.Filename,
+		ContentType: d.ContentType,
+		CreatedAt:   d.CreatedAt.Time.String(),
+	}
+}
+
+type DocumentLinkDTO struct {
+	LinkID     int32     `json:"link_id"`
+	DocumentID int32     `json:"document_id"`
+	Version    int32     `json:"version"`
+	URL        string    `json:"url"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// linkPayload is what the HMAC covers: the link, the document version, the user and the expiry.
+func linkPayload(linkID, documentID, userID int32, expires int64) string {
+	return fmt.Sprintf("%d:%d:%d:%d", linkID, documentID, userID, expires)
+}
+
+// ListDocuments returns the stored versions (newest first) of an entity's documents.
+func (s *DocumentService) ListDocuments(ctx context.Context, userID int32, docType string, entityID int32) ([]DocumentDTO, error) {
+	dtos := []DocumentDTO{}
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		// Authorization only depends on the entity, not on the version
+		probe := postgres.Document{DocumentType: docType, EntityID: entityID}
+		if err := authorizeDocument(ctx, q, probe, userID); err != nil {
+			return err
+		}
+		docs, err := q.ListDocumentsByEntity(ctx, postgres.ListDocumentsByEntityParams{DocumentType: docType, EntityID: entityID})
+		if err != nil {
+			return err
+		}
+		for _, d := range docs {
+			dtos = append(dtos, toDocumentDTO(d))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dtos, nil
+}
+
+// CreateLink issues a signed link to one document version, usable only by userID until it expires.
+// ttl <= 0 uses DOCUMENT_LINK_TTL; it is capped at 24 hours.
+func (s *DocumentService) CreateLink(ctx context.Context, userID, documentID int32, ttl time.Duration) (*DocumentLinkDTO, error) {
+	if ttl <= 0 {
+		ttl = s.linkTTL
+	}
+	if ttl > maxDocumentLinkTTL {
+		ttl = maxDocumentLinkTTL
+	}
+	// Truncated to the second: the expiry is carried in the URL as a unix timestamp
+	expiresAt := time.Now().UTC().Add(ttl).Truncate(time.Second)
+
+	var doc postgres.Document
+	var link postgres.DocumentLink
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		var err error
+		doc, err = q.GetDocument(ctx, documentID)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return ErrDocumentNotFound
+			}
+			return err
+		}
+		if err := authorizeDocument(ctx, q, doc, userID); err != nil {
+			return err
+		}
+
+		link, err = q.CreateDocumentLink(ctx, postgres.CreateDocumentLinkParams{
+			DocumentID: documentID,
+			UserID:     userID,
+			ExpiresAt:  pgtype.Timestamp{Time: expiresAt, Valid: true},
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := auth.SignLink(linkPayload(link.ID, doc.ID, userID, expiresAt.Unix()))
+	if err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx).Info("document link created",
+		zap.Int32("link_id", link.ID),
+		zap.Int32("document_id", doc.ID),
+		zap.Int32("user_id", userID),
+	)
+
+	return &DocumentLinkDTO{
+		LinkID:     link.ID,
+		DocumentID: doc.ID,
+		Version:    doc.Version,
+		URL:        fmt.Sprintf("/api/v1/documents/links/%d?expires=%d&signature=%s", link.ID, expiresAt.Unix(), signature),
+		ExpiresAt:  expiresAt,
+	}, nil
+}
+
+// CreateLatestLink issues a link to the latest version of an entity's document.
+func (s *DocumentService) CreateLatestLink(ctx context.Context, userID int32, docType string, entityID int32, ttl time.Duration) (*DocumentLinkDTO, error) {
+	var doc postgres.Document
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		var err error
+		doc, err = q.GetLatestDocument(ctx, postgres.GetLatestDocumentParams{DocumentType: docType, EntityID: entityID})
+		if err == pgx.ErrNoRows {
+			return ErrDocumentNotFound
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.CreateLink(ctx, userID, doc.ID, ttl)
+}
+
+// RevokeLink invalidates a link before its expiry. Only the user the link was issued to can revoke it.
+func (s *DocumentService) RevokeLink(ctx context.Context, userID, linkID int32) error {
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		rows, err := q.RevokeDocumentLink(ctx, postgres.RevokeDocumentLinkParams{ID: linkID, UserID: userID})
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return ErrInvalidDocumentLink
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	logger.FromContext(ctx).Info("document link revoked", zap.Int32("link_id", linkID), zap.Int32("user_id", userID))
+	return nil
+}
+
+type LinkAccess struct {
+	LinkID    int32
+	Expires   int64
+	Signature string
+	IPAddress string
+	UserAgent string
+}
+
+// OpenLink validates a signed link and streams the document it points to.
+// Every successful access is recorded in document_access_logs. The caller must close the reader.
+func (s *DocumentService) OpenLink(ctx context.Context, access LinkAccess) (io.ReadCloser, *DocumentDTO, error) {
+	log := logger.FromContext(ctx)
+
+	if time.Now().Unix() > access.Expires {
+		return nil, nil, ErrDocumentLinkExpired
+	}
+
+	var doc postgres.Document
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		link, err := q.GetDocumentLink(ctx, access.LinkID)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return ErrInvalidDocumentLink
+			}
+			return err
+		}
+
+		// The signature binds the URL to the link's document version, user and expiry
+		if err := auth.VerifyLink(linkPayload(link.ID, link.DocumentID, link.UserID, access.Expires), access.Signature); err != nil {
+			return ErrInvalidDocumentLink
+		}
+		if link.ExpiresAt.Time.Unix() != access.Expires {
+			return ErrInvalidDocumentLink
+		}
+		if link.RevokedAt.Valid {
+			return ErrDocumentLinkExpired
+		}
+
+		doc, err = q.GetDocument(ctx, link.DocumentID)
+		if err != nil {
+			return err
+		}
+		// The user may have lost access since the link was issued (e.g. lease terminated)
+		if err := authorizeDocument(ctx, q, doc, link.UserID); err != nil {
+			return err
+		}
+
+		return q.CreateDocumentAccessLog(ctx, postgres.CreateDocumentAccessLogParams{
+			LinkID:     pgtype.Int4{Int32: link.ID, Valid: true},
+			DocumentID: doc.ID,
+			UserID:     link.UserID,
+			IpAddress:  pgtype.Text{String: access.IPAddress, Valid: access.IPAddress != ""},
+			UserAgent:  pgtype.Text{String: access.UserAgent, Valid: access.UserAgent != ""},
+		})
+	})
+	if err != nil {
+		log.Warn("document link rejected", zap.Int32("link_id", access.LinkID), zap.Error(err))
+		return nil, nil, err
+	}
+
+	reader, err := s.storage.Open(doc.StorageKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open document: %w", err)
+	}
+
+	log.Info("document accessed via signed link", zap.Int32("link_id", access.LinkID), zap.Int32("document_id", doc.ID))
+	dto := toDocumentDTO(doc)
+	return reader, &dto, nil
+}
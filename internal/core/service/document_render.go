@@ -0,0 +1,105 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/launcher"
+	"github.com/go-rod/rod/lib/proto"
+	"github.com/spf13/viper"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// Document pipeline shared by leases and reports:
+// Markdown template (assets/templates/<kind>) -> text/template -> HTML (goldmark) -> PDF (headless browser).
+
+// readDocumentTemplate loads ASSETS_DIR/templates/<kind>/<name>.
+func readDocumentTemplate(kind, name string) ([]byte, error) {
+	assetsDir := viper.GetString("ASSETS_DIR")
+	if assetsDir == "" {
+		assetsDir = "assets"
+	}
+	content, err := os.ReadFile(filepath.Join(assetsDir, "templates", kind, name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template %s: %w", name, err)
+	}
+	return content, nil
+}
+
+// markdown renders tables in addition to CommonMark. Raw HTML stays disabled, so values filled in
+// the templates (names, addresses) cannot inject markup.
+var markdown = goldmark.New(goldmark.WithExtensions(extension.Table))
+
+// renderMarkdownTemplate fills a Markdown template with data and converts the result to an HTML fragment.
+func renderMarkdownTemplate(name string, content []byte, data any) ([]byte, error) {
+	tmpl, err := template.New(name).Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	var htmlBuf bytes.Buffer
+	if err := markdown.Convert(buf.Bytes(), &htmlBuf); err != nil {
+		return nil, fmt.Errorf("failed to convert markdown to html: %w", err)
+	}
+	return htmlBuf.Bytes(), nil
+}
+
+// htmlToPDF prints an HTML document to an A4 PDF with a headless browser.
+func htmlToPDF(html []byte) ([]byte, error) {
+	// We use a custom launcher to ensure it works in Docker/Dev envs
+	u, err := launcher.New().Launch()
+	if err != nil {
+		return nil, fmt.Errorf("failed to launch browser: %w", err)
+	}
+	browser := rod.New().ControlURL(u)
+	if err := browser.Connect(); err != nil {
+		return nil, fmt.Errorf("failed to connect to browser: %w", err)
+	}
+	defer browser.MustClose()
+
+	page, err := browser.Page(proto.TargetCreateTarget{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open page: %w", err)
+	}
+	if err := page.SetDocumentContent(string(html)); err != nil {
+		return nil, fmt.Errorf("failed to set page content: %w", err)
+	}
+	// Wait for network idle to ensure fonts/images loaded
+	if err := page.WaitLoad(); err != nil {
+		return nil, fmt.Errorf("failed to load page: %w", err)
+	}
+
+	// page.PDF() returns a stream
+	pdfStream, err := page.PDF(&proto.PagePrintToPDF{
+		PaperWidth:      floatPtr(8.27),
+		PaperHeight:     floatPtr(11.69),
+		MarginTop:       floatPtr(0.5),
+		MarginBottom:    floatPtr(0.5),
+		MarginLeft:      floatPtr(0.5),
+		MarginRight:     floatPtr(0.5),
+		PrintBackground: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate PDF stream: %w", err)
+	}
+
+	pdfBytes, err := io.ReadAll(pdfStream)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF stream: %w", err)
+	}
+	return pdfBytes, nil
+}
+
+// Helper to convert float pointer for Rod
+func floatPtr(v float64) *float64 { return &v }
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+)
+
+func setupDocumentService(t *testing.T) (*DocumentService, *MockQuerier, *MockFileStorage) {
+	viper.Set("DOCUMENT_LINK_SECRET", "test-link-secret")
+	t.Cleanup(func() { viper.Set("DOCUMENT_LINK_SECRET", "") })
+
+	mockQuerier := new(MockQuerier)
+	mockFileStore := new(MockFileStorage)
+	return NewDocumentService(passthroughTxManager{q: mockQuerier}, mockFileStore, zap.NewNop()), mockQuerier, mockFileStore
+}
+
+// mockLeaseParties sets up lease 5 on property 10, owned by user 1 and rented by user 2 without co-tenants.
+func mockLeaseParties(q *MockQuerier) {
+	q.On("GetLeasePartyByUser", mock.Anything, mock.Anything).Return(postgres.LeaseParty{}, pgx.ErrNoRows).Maybe()
+	q.On("GetLease", mock.Anything, int32(5)).Return(postgres.Lease{
+		ID:         5,
+		PropertyID: pgtype.Int4{Int32: 10, Valid: true},
+		TenantID:   pgtype.Int4{Int32: 2, Valid: true},
+	}, nil)
+	q.On("GetProperty", mock.Anything, int32(10)).Return(postgres.Property{ID: 10, OwnerID: pgtype.Int4{Int32: 1, Valid: true}}, nil).Maybe()
+}
+
+var leaseDocV2 = postgres.Document{ID: 42, DocumentType: DocumentTypeLease, EntityID: 5, Version: 2, StorageKey: "documents/lease/5/v2.html", ContentType: "text/html; charset=utf-8", Filename: "contract.html"}
+
+// issueLink creates a link for user 2 and returns it with its parsed query.
+func issueLink(t *testing.T, svc *DocumentService, q *MockQuerier, expiresAt *time.Time) (*DocumentLinkDTO, url.Values) {
+	q.On("GetDocument", mock.Anything, int32(42)).Return(leaseDocV2, nil)
+	q.On("CreateDocumentLink", mock.Anything, mock.MatchedBy(func(p postgres.CreateDocumentLinkParams) bool {
+		*expiresAt = p.ExpiresAt.Time
+		return p.DocumentID == 42 && p.UserID == 2
+	})).Return(postgres.DocumentLink{ID: 7, DocumentID: 42, UserID: 2}, nil).Once()
+
+	link, err := svc.CreateLink(context.Background(), 2, 42, 10*time.Minute)
+	require.NoError(t, err)
+
+	u, err := url.Parse(link.URL)
+	require.NoError(t, err)
+	return link, u.Query()
+}
+
+func TestCreateLink_Success(t *testing.T) {
+	svc, q, _ := setupDocumentService(t)
+	mockLeaseParties(q)
+
+	var expiresAt time.Time
+	link, query := issueLink(t, svc, q, &expiresAt)
+
+	assert.Equal(t, int32(7), link.LinkID)
+	assert.Equal(t, int32(2), link.Version)
+	assert.True(t, strings.HasPrefix(link.URL, "/api/v1/documents/links/7?"))
+	assert.Equal(t, strconv.FormatInt(expiresAt.Unix(), 10), query.Get("expires"))
+	assert.NotEmpty(t, query.Get("signature"))
+	assert.WithinDuration(t, time.Now().Add(10*time.Minute), expiresAt, 2*time.Second)
+}
+
+func TestCreateLink_AccessDenied(t *testing.T) {
+	svc, q, _ := setupDocumentService(t)
+	mockLeaseParties(q)
+	q.On("GetDocument", mock.Anything, int32(42)).Return(leaseDocV2, nil)
+
+	_, err := svc.CreateLink(context.Background(), 99, 42, 0)
+
+	assert.ErrorIs(t, err, ErrDocumentAccessDenied)
+	q.AssertNotCalled(t, "CreateDocumentLink", mock.Anything, mock.Anything)
+}
+
+func TestOpenLink_RecordsAccess(t *testing.T) {
+	svc, q, store := setupDocumentService(t)
+	mockLeaseParties(q)
+
+	var expiresAt time.Time
+	_, query := issueLink(t, svc, q, &expiresAt)
+
+	q.On("GetDocumentLink", mock.Anything, int32(7)).Return(postgres.DocumentLink{
+		ID: 7, DocumentID: 42, UserID: 2, ExpiresAt: pgtype.Timestamp{Time: expiresAt, Valid: true},
+	}, nil)
+	q.On("CreateDocumentAccessLog", mock.Anything, postgres.CreateDocumentAccessLogParams{
+		LinkID:     pgtype.Int4{Int32: 7, Valid: true},
+		DocumentID: 42,
+		UserID:     2,
+		IpAddress:  pgtype.Text{String: "203.0.113.9", Valid: true},
+		UserAgent:  pgtype.Text{String: "pdf-viewer", Valid: true},
+	}).Return(nil)
+	store.On("Open", "documents/lease/5/v2.html").Return(io.NopCloser(strings.NewReader("<html>")), nil)
+
+	expires, _ := strconv.ParseInt(query.Get("expires"), 10, 64)
+	reader, doc, err := svc.OpenLink(context.Background(), LinkAccess{
+		LinkID:    7,
+		Expires:   expires,
+		Signature: query.Get("signature"),
+		IPAddress: "203.0.113.9",
+		UserAgent: "pdf-viewer",
+	})
+
+	require.NoError(t, err)
+	defer reader.Close()
+	assert.Equal(t, int32(2), doc.Version)
+	q.AssertExpectations(t)
+}
+
+func TestOpenLink_Rejections(t *testing.T) {
+	svc, q, _ := setupDocumentService(t)
+	mockLeaseParties(q)
+
+	var expiresAt time.Time
+	_, query := issueLink(t, svc, q, &expiresAt)
+	expires, _ := strconv.ParseInt(query.Get("expires"), 10, 64)
+	signature := query.Get("signature")
+
+	link := postgres.DocumentLink{ID: 7, DocumentID: 42, UserID: 2, ExpiresAt: pgtype.Timestamp{Time: expiresAt, Valid: true}}
+	q.On("GetDocumentLink", mock.Anything, int32(7)).Return(link, nil).Once()
+
+	// Extending the expiry in the URL breaks the signature
+	_, _, err := svc.OpenLink(context.Background(), LinkAccess{LinkID: 7, Expires: expires + 3600, Signature: signature})
+	assert.ErrorIs(t, err, ErrInvalidDocumentLink)
+
+	// Past expiry is refused before touching the database
+	_, _, err = svc.OpenLink(context.Background(), LinkAccess{LinkID: 7, Expires: time.Now().Add(-time.Minute).Unix(), Signature: signature})
+	assert.ErrorIs(t, err, ErrDocumentLinkExpired)
+
+	// Revoked
+	link.RevokedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+	q.On("GetDocumentLink", mock.Anything, int32(7)).Return(link, nil).Once()
+	_, _, err = svc.OpenLink(context.Background(), LinkAccess{LinkID: 7, Expires: expires, Signature: signature})
+	assert.ErrorIs(t, err, ErrDocumentLinkExpired)
+
+	q.AssertNotCalled(t, "CreateDocumentAccessLog", mock.Anything, mock.Anything)
+}
+
+func TestRevokeLink_OnlyOwnLinks(t *testing.T) {
+	svc, q, _ := setupDocumentService(t)
+	q.On("RevokeDocumentLink", mock.Anything, postgres.RevokeDocumentLinkParams{ID: 7, UserID: 3}).Return(int64(0), nil)
+	q.On("RevokeDocumentLink", mock.Anything, postgres.RevokeDocumentLinkParams{ID: 7, UserID: 2}).Return(int64(1), nil)
+
+	assert.ErrorIs(t, svc.RevokeLink(context.Background(), 3, 7), ErrInvalidDocumentLink)
+	assert.NoError(t, svc.RevokeLink(context.Background(), 2, 7))
+}
+
+func TestAuthorizeDocument_SolvencyReport(t *testing.T) {
+	q := new(MockQuerier)
+	q.On("GetSolvencyCheckByID", mock.Anything, int32(3)).Return(postgres.SolvencyCheck{
+		ID:               3,
+		InitiatorOwnerID: pgtype.Int4{Int32: 1, Valid: true},
+		CandidateID:      pgtype.Int4{Int32: 4, Valid: true},
+	}, nil)
+	doc := postgres.Document{DocumentType: DocumentTypeSolvencyReport, EntityID: 3}
+
+	assert.NoError(t, authorizeDocument(context.Background(), q, doc, 1))
+	assert.NoError(t, authorizeDocument(context.Background(), q, doc, 4))
+	assert.True(t, errors.Is(authorizeDocument(context.Background(), q, doc, 2), ErrDocumentAccessDenied))
+}
@@ -0,0 +1,410 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+	"seculoc-back/internal/platform/logger"
+)
+
+const (
+	guaranteeDeedTemplate = "acte_cautionnement.md"
+
+	guaranteeRevisionClause = "révisé chaque année à la date anniversaire du bail selon la variation de l'indice de référence des loyers (IRL) publié par l'INSEE"
+	// Second-to-last paragraph of article 22-1, which the guarantor must reproduce
+	guaranteeTerminationReminder = "Lorsque le cautionnement d'obligations résultant d'un contrat de location conclu en application du présent titre ne comporte aucune indication de durée ou lorsque la durée du cautionnement est stipulée indéterminée, la caution peut le résilier unilatéralement. La résiliation prend effet au terme du contrat de location, qu'il s'agisse du contrat initial ou d'un contrat reconduit ou renouvelé, au cours duquel le bailleur reçoit notification de la résiliation."
+)
+
+var (
+	ErrGuaranteeDeedNotReady    = errors.New("no signed lease to guarantee yet")
+	ErrGuaranteeMentionMismatch = errors.New("the reproduced mention does not match the required text")
+	ErrGuaranteeMentionSigned   = errors.New("the mention has already been reproduced")
+)
+
+// GuaranteeDeedData fills the guarantee deed (acte de cautionnement) template.
+type GuaranteeDeedData struct {
+	DateActe string
+
+	CautionNom       string
+	CautionEmail     string
+	CautionTelephone string
+
+	BailleurNom   string
+	BailleurEmail string
+	LocataireNom  string
+
+	AdresseLogement string
+	DateBail        string
+	DureeBail       string
+	Loyer           string
+	Charges         string
+	TotalMensuel    string
+	Revision        string
+
+	DureeEngagement string
+	MontantMaximal  string
+
+	// Mention is the text the guarantor must reproduce; MentionSignee is their reproduction once done
+	Mention       string
+	MentionSignee string
+	DateMention   string
+}
+
+// LeaseGuarantorDTO lists a guarantor of a signed lease with the latest version of their deed.
+type LeaseGuarantorDTO struct {
+	GuarantorID   int32        `json:"guarantor_id"`
+	Name          string       `json:"name"`
+	Email         string       `json:"email"`
+	MentionSigned bool         `json:"mention_signed"`
+	Deed          *DocumentDTO `json:"deed,omitempty"`
+}
+
+// GuaranteeMentionDTO is the mention the guarantor must reproduce before the deed is complete.
+type GuaranteeMentionDTO struct {
+	Text     string `json:"text"`
+	Signed   bool   `json:"signed"`
+	SignedAt string `json:"signed_at,omitempty"`
+}
+
+// guaranteeMonths is the length of the guarantor's commitment: the lease term when it has an end date,
+// otherwise the legal minimum term (3 years unfurnished, 1 year furnished).
+func guaranteeMonths(lease postgres.Lease, prop postgres.Property) int {
+	if lease.EndDate.Valid && lease.StartDate.Valid {
+		start, end := lease.StartDate.Time, lease.EndDate.Time
+		months := (end.Year()-start.Year())*12 + int(end.Month()-start.Month())
+		if end.Day() > start.Day() {
+			months++
+		}
+		return max(months, 1)
+	}
+	if prop.IsFurnished.Bool {
+		return 12
+	}
+	return 36
+}
+
+func durationLabel(months int) string {
+	switch {
+	case months%12 == 0 && months == 12:
+		return "1 an"
+	case months%12 == 0:
+		return fmt.Sprintf("%d ans", months/12)
+	default:
+		return fmt.Sprintf("%d mois", months)
+	}
+}
+
+// guaranteeMention is the text required by article 22-1 of the law of 6 July 1989: rent and its revision
+// as stated in the lease, explicit awareness of the nature and extent of the commitment, and the
+// reproduction of the article's second-to-last paragraph.
+func guaranteeMention(d GuaranteeDeedData) string {
+	return fmt.Sprintf("Je soussigné(e) %s me porte caution solidaire de %s, en renonçant au bénéfice de discussion et de division, "+
+		"pour le paiement du loyer de %s euros par mois, %s, et des charges de %s euros par mois, "+
+		"ainsi que des réparations locatives, indemnités d'occupation et frais dus au titre du bail du logement situé %s, "+
+		"pour une durée de %s et dans la limite de %s euros. "+
+		"J'ai parfaite connaissance de la nature et de l'étendue de mon engagement. "+
+		"%s",
+		d.CautionNom, d.LocataireNom, d.Loyer, d.Revision, d.Charges, d.AdresseLogement,
+		d.DureeEngagement, d.MontantMaximal, guaranteeTerminationReminder)
+}
+
+// buildGuaranteeDeedData gathers the deed of one guarantor of a signed lease.
+func buildGuaranteeDeedData(lease postgres.Lease, prop postgres.Property, owner, tenant postgres.User, g postgres.SolvencyGuarantor, now time.Time) GuaranteeDeedData {
+	rent, _ := lease.RentAmount.Float64Value()
+	charges, _ := lease.ChargesAmount.Float64Value()
+	total := rent.Float64 + charges.Float64
+	months := guaranteeMonths(lease, prop)
+
+	data := GuaranteeDeedData{
+		DateActe: now.Format("02/01/2006"),
+
+		CautionNom:       strings.TrimSpace(fmt.Sprintf("%s %s", g.LastName.String, g.FirstName.String)),
+		CautionEmail:     g.Email,
+		CautionTelephone: g.PhoneNumber.String,
+
+		BailleurNom:   fmt.Sprintf("%s %s", owner.LastName.String, owner.FirstName.String),
+		BailleurEmail: owner.Email,
+		LocataireNom:  fmt.Sprintf("%s %s", tenant.LastName.String, tenant.FirstName.String),
+
+		AdresseLogement: prop.Address,
+		DateBail:        lease.StartDate.Time.Format("02/01/2006"),
+		DureeBail:       durationLabel(months),
+		Loyer:           euros(rent.Float64),
+		Charges:         euros(charges.Float64),
+		TotalMensuel:    euros(total),
+		Revision:        guaranteeRevisionClause,
+
+		DureeEngagement: durationLabel(months),
+		MontantMaximal:  euros(round2(total * float64(months))),
+	}
+	if data.CautionNom == "" {
+		data.CautionNom = g.Email
+	}
+	if data.CautionTelephone == "" {
+		data.CautionTelephone = "Non renseigné"
+	}
+	data.Mention = guaranteeMention(data)
+	if g.MentionText.Valid {
+		data.MentionSignee = g.MentionText.String
+		data.DateMention = g.MentionSignedAt.Time.Format("02/01/2006 à 15:04")
+	}
+	return data
+}
+
+// renderGuaranteeDeedHTML renders the deed template as a standalone HTML page, like the lease it annexes.
+func renderGuaranteeDeedHTML(data GuaranteeDeedData) ([]byte, error) {
+	content, err := readDocumentTemplate("leases", guaranteeDeedTemplate)
+	if err != nil {
+		return nil, err
+	}
+	body, err := renderMarkdownTemplate("guarantee_deed", content, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(fmt.Sprintf(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="UTF-8">
+<title>Acte de cautionnement</title>
+<style>
+body { font-family: 'Helvetica', 'Arial', sans-serif; max-width: 800px; margin: 40px auto; padding: 20px; line-height: 1.6; color: #333; }
+h1, h2, h3 { color: #000; border-bottom: 2px solid #333; padding-bottom: 10px; margin-top: 30px; }
+h1 { font-size: 22px; text-align: center; border: none; text-transform: uppercase; letter-spacing: 2px; }
+blockquote { border-left: 3px solid #333; margin: 12px 0; padding: 4px 14px; font-style: italic; }
+p { margin-bottom: 0.8em; text-align: justify; }
+</style>
+</head>
+<body>
+%s
+</body>
+</html>`, body)), nil
+}
+
+// storeGuaranteeDeed renders the deed of a guarantor bound to a lease and stores it as a new document version.
+func storeGuaranteeDeed(ctx context.Context, q postgres.Querier, storage FileStorage, g postgres.SolvencyGuarantor) (postgres.Document, error) {
+	if !g.LeaseID.Valid {
+		return postgres.Document{}, ErrGuaranteeDeedNotReady
+	}
+	data, err := guaranteeDeedDataFor(ctx, q, g)
+	if err != nil {
+		return postgres.Document{}, err
+	}
+
+	content, err := renderGuaranteeDeedHTML(data)
+	if err != nil {
+		return postgres.Document{}, err
+	}
+	filename := fmt.Sprintf("acte_cautionnement_%d.html", g.ID)
+	return storeDocumentVersion(ctx, q, storage, DocumentTypeGuaranteeDeed, g.ID, ".html", "text/html; charset=utf-8", filename, content)
+}
+
+// GenerateGuaranteeDeeds binds the completed guarantors of the tenant's approved check to a signed lease
+// and stores a guarantee deed for each of them as a lease annex. It returns the number of deeds generated.
+func (s *LeaseService) GenerateGuaranteeDeeds(ctx context.Context, leaseID, tenantID int32) (int, error) {
+	var generated int
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		lease, err := q.GetLease(ctx, leaseID)
+		if err != nil {
+			return fmt.Errorf("lease not found: %w", err)
+		}
+
+		guarantors, err := q.AttachGuarantorsToLease(ctx, postgres.AttachGuarantorsToLeaseParams{
+			LeaseID:    pgtype.Int4{Int32: lease.ID, Valid: true},
+			PropertyID: lease.PropertyID,
+			TenantID:   pgtype.Int4{Int32: tenantID, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		for _, g := range guarantors {
+			if _, err := storeGuaranteeDeed(ctx, q, s.storage, g); err != nil {
+				return fmt.Errorf("failed to generate guarantee deed: %w", err)
+			}
+			generated++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if generated > 0 {
+		s.logger.Info("guarantee deeds generated", zap.Int32("lease_id", leaseID), zap.Int("count", generated))
+	}
+	return generated, nil
+}
+
+// ListLeaseGuarantors returns the guarantors of a lease and their latest deed, for the owner and the tenant.
+func (s *LeaseService) ListLeaseGuarantors(ctx context.Context, userID, leaseID int32) ([]LeaseGuarantorDTO, error) {
+	dtos := []LeaseGuarantorDTO{}
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		if err := authorizeDocument(ctx, q, postgres.Document{DocumentType: DocumentTypeLease, EntityID: leaseID}, userID); err != nil {
+			return err
+		}
+		guarantors, err := q.ListGuarantorsByLease(ctx, pgtype.Int4{Int32: leaseID, Valid: true})
+		if err != nil {
+			return err
+		}
+		for _, g := range guarantors {
+			dto := LeaseGuarantorDTO{
+				GuarantorID:   g.ID,
+				Name:          strings.TrimSpace(g.FirstName.String + " " + g.LastName.String),
+				Email:         g.Email,
+				MentionSigned: g.MentionSignedAt.Valid,
+			}
+			doc, err := q.GetLatestDocument(ctx, postgres.GetLatestDocumentParams{DocumentType: DocumentTypeGuaranteeDeed, EntityID: g.ID})
+			if err == nil {
+				deed := toDocumentDTO(doc)
+				dto.Deed = &deed
+			} else if err != pgx.ErrNoRows {
+				return err
+			}
+			dtos = append(dtos, dto)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dtos, nil
+}
+
+// boundGuarantorByToken locks a guarantor already bound to a signed lease.
+func boundGuarantorByToken(ctx context.Context, q postgres.Querier, token string) (postgres.SolvencyGuarantor, error) {
+	g, err := guarantorByToken(ctx, q, token)
+	if err != nil {
+		return g, err
+	}
+	if !g.LeaseID.Valid {
+		return g, ErrGuaranteeDeedNotReady
+	}
+	return g, nil
+}
+
+// GetGuaranteeMention returns the mention the guarantor must reproduce on their deed.
+func (s *SolvencyService) GetGuaranteeMention(ctx context.Context, token string) (*GuaranteeMentionDTO, error) {
+	var mention GuaranteeMentionDTO
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		g, err := boundGuarantorByToken(ctx, q, token)
+		if err != nil {
+			return err
+		}
+		data, err := guaranteeDeedDataFor(ctx, q, g)
+		if err != nil {
+			return err
+		}
+		mention = GuaranteeMentionDTO{Text: data.Mention, Signed: g.MentionSignedAt.Valid}
+		if g.MentionSignedAt.Valid {
+			mention.SignedAt = g.MentionSignedAt.Time.Format(time.RFC3339)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &mention, nil
+}
+
+// SignGuaranteeMention records the mention typed by the guarantor, the electronic equivalent of the
+// handwritten mention, and stores a new version of the deed that includes it. Case, accents and
+// punctuation are not compared; every word must be there.
+func (s *SolvencyService) SignGuaranteeMention(ctx context.Context, token, text string) error {
+	text = strings.Join(strings.Fields(text), " ")
+
+	var guarantorID int32
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		g, err := boundGuarantorByToken(ctx, q, token)
+		if err != nil {
+			return err
+		}
+		if g.MentionSignedAt.Valid {
+			return ErrGuaranteeMentionSigned
+		}
+		data, err := guaranteeDeedDataFor(ctx, q, g)
+		if err != nil {
+			return err
+		}
+		if normalizeLabel(text) != normalizeLabel(data.Mention) {
+			return ErrGuaranteeMentionMismatch
+		}
+
+		if err := q.SetGuarantorMention(ctx, postgres.SetGuarantorMentionParams{ID: g.ID, MentionText: pgtype.Text{String: text, Valid: true}}); err != nil {
+			return err
+		}
+		signed, err := q.GetSolvencyGuarantor(ctx, g.ID)
+		if err != nil {
+			return err
+		}
+		guarantorID = g.ID
+		_, err = storeGuaranteeDeed(ctx, q, s.storage, signed)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.FromContext(ctx).Info("guarantee mention signed", zap.Int32("guarantor_id", guarantorID))
+	return nil
+}
+
+// OpenGuaranteeDeed streams the latest deed to the guarantor. The caller must close the reader.
+func (s *SolvencyService) OpenGuaranteeDeed(ctx context.Context, token string) (io.ReadCloser, *DocumentDTO, error) {
+	var doc postgres.Document
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		g, err := boundGuarantorByToken(ctx, q, token)
+		if err != nil {
+			return err
+		}
+		doc, err = q.GetLatestDocument(ctx, postgres.GetLatestDocumentParams{DocumentType: DocumentTypeGuaranteeDeed, EntityID: g.ID})
+		if err == pgx.ErrNoRows {
+			return ErrDocumentNotFound
+		}
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reader, err := s.storage.Open(doc.StorageKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open document: %w", err)
+	}
+	dto := toDocumentDTO(doc)
+	return reader, &dto, nil
+}
+
+// guaranteeDeedDataFor loads what the deed of a bound guarantor is built from.
+func guaranteeDeedDataFor(ctx context.Context, q postgres.Querier, g postgres.SolvencyGuarantor) (GuaranteeDeedData, error) {
+	lease, err := q.GetLease(ctx, g.LeaseID.Int32)
+	if err != nil {
+		return GuaranteeDeedData{}, fmt.Errorf("lease not found: %w", err)
+	}
+	prop, err := q.GetProperty(ctx, lease.PropertyID.Int32)
+	if err != nil {
+		return GuaranteeDeedData{}, fmt.Errorf("property not found: %w", err)
+	}
+	owner, err := q.GetUserById(ctx, prop.OwnerID.Int32)
+	if err != nil {
+		return GuaranteeDeedData{}, fmt.Errorf("owner not found: %w", err)
+	}
+	// With co-tenants, the guarantor stands surety for the tenant whose check they joined
+	check, err := q.GetSolvencyCheckByID(ctx, g.CheckID)
+	if err != nil {
+		return GuaranteeDeedData{}, fmt.Errorf("check not found: %w", err)
+	}
+	tenant, err := q.GetUserById(ctx, check.CandidateID.Int32)
+	if err != nil {
+		return GuaranteeDeedData{}, fmt.Errorf("tenant not found: %w", err)
+	}
+	return buildGuaranteeDeedData(lease, prop, owner, tenant, g, time.Now()), nil
+}
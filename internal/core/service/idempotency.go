@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+	"seculoc-back/internal/platform/logger"
+)
+
+const (
+	// idempotencyKeyTTL is how long a response is kept for replay.
+	idempotencyKeyTTL = 24 * time.Hour
+	// idempotencyProcessingTimeout is how long a key stays reserved without a sign of life from the request
+	// holding it (instance stopped mid-request); past it, a retry takes the key over.
+	idempotencyProcessingTimeout = 5 * time.Minute
+	// IdempotencyKeepAliveInterval is how often a running request refreshes its key (see KeepAlive), well
+	// within idempotencyProcessingTimeout: a slow request is never taken over while it runs.
+	IdempotencyKeepAliveInterval = time.Minute
+)
+
+var (
+	// ErrIdempotencyKeyReused is returned when a key comes back with another request.
+	ErrIdempotencyKeyReused = errors.New("idempotency key already used for another request")
+	// ErrIdempotencyInProgress is returned while the first request with the key is still running.
+	ErrIdempotencyInProgress = errors.New("a request with this idempotency key is in progress")
+)
+
+// StoredResponse is the response recorded for an idempotency key.
+type StoredResponse struct {
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+// IdempotencyService records the responses of requests sent with an Idempotency-Key, so that a client
+// retrying after a timeout gets the original response instead of being charged twice.
+type IdempotencyService struct {
+	txManager TxManager
+}
+
+func NewIdempotencyService(txManager TxManager, l *zap.Logger) *IdempotencyService {
+	return &IdempotencyService{txManager: txManager}
+}
+
+// Begin reserves key for the request identified by fingerprint. It returns the stored response when the
+// request was already processed; nil means the caller must process it, then Complete or Release the key.
+// A key left processing without sign of life for idempotencyProcessingTimeout is taken over by the caller.
+func (s *IdempotencyService) Begin(ctx context.Context, userID int32, key, fingerprint string) (*StoredResponse, error) {
+	var stored *StoredResponse
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		rows, err := q.CreateIdempotencyKey(ctx, postgres.CreateIdempotencyKeyParams{
+			UserID:             userID,
+			IdempotencyKey:     key,
+			RequestFingerprint: fingerprint,
+		})
+		if err != nil || rows == 1 {
+			return err
+		}
+
+		existing, err := q.GetIdempotencyKey(ctx, postgres.GetIdempotencyKeyParams{UserID: userID, IdempotencyKey: key})
+		if err != nil {
+			return err
+		}
+		if existing.RequestFingerprint != fingerprint {
+			return ErrIdempotencyKeyReused
+		}
+		if existing.Status != "completed" {
+			taken, err := q.TakeOverIdempotencyKey(ctx, postgres.TakeOverIdempotencyKeyParams{
+				UserID:             userID,
+				IdempotencyKey:     key,
+				RequestFingerprint: fingerprint,
+				StaleBefore:        pgtype.Timestamp{Time: time.Now().Add(-idempotencyProcessingTimeout), Valid: true},
+			})
+			if err != nil {
+				return err
+			}
+			if taken == 0 {
+				return ErrIdempotencyInProgress
+			}
+			return nil
+		}
+		stored = &StoredResponse{
+			StatusCode:  int(existing.ResponseCode.Int32),
+			ContentType: existing.ResponseContentType.String,
+			Body:        existing.ResponseBody,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// KeepAlive tells that the request which reserved key is still running.
+func (s *IdempotencyService) KeepAlive(ctx context.Context, userID int32, key string) error {
+	return s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		return q.TouchIdempotencyKey(ctx, postgres.TouchIdempotencyKeyParams{UserID: userID, IdempotencyKey: key})
+	})
+}
+
+// Complete records the response of the request which reserved key, whatever its status: a request which
+// failed may have changed something, and is replayed rather than run again.
+func (s *IdempotencyService) Complete(ctx context.Context, userID int32, key string, resp StoredResponse) error {
+	return s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		return q.CompleteIdempotencyKey(ctx, postgres.CompleteIdempotencyKeyParams{
+			UserID:              userID,
+			IdempotencyKey:      key,
+			ResponseCode:        pgtype.Int4{Int32: int32(resp.StatusCode), Valid: true},
+			ResponseContentType: pgtype.Text{String: resp.ContentType, Valid: resp.ContentType != ""},
+			ResponseBody:        resp.Body,
+		})
+	})
+}
+
+// Release frees key after a failure known to have changed nothing, so that the request can be retried.
+func (s *IdempotencyService) Release(ctx context.Context, userID int32, key string) error {
+	return s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		return q.DeleteIdempotencyKey(ctx, postgres.DeleteIdempotencyKeyParams{UserID: userID, IdempotencyKey: key})
+	})
+}
+
+// PurgeExpired deletes the keys older than idempotencyKeyTTL.
+func (s *IdempotencyService) PurgeExpired(ctx context.Context) error {
+	var purged int64
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		var err error
+		purged, err = q.PurgeIdempotencyKeys(ctx, pgtype.Timestamp{Time: time.Now().Add(-idempotencyKeyTTL), Valid: true})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to purge idempotency keys: %w", err)
+	}
+	if purged > 0 {
+		logger.FromContext(ctx).Info("idempotency keys purged", zap.Int64("count", purged))
+	}
+	return nil
+}
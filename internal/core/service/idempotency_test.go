@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+)
+
+func TestIdempotencyBegin(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	svc := NewIdempotencyService(passthroughTxManager{q: mockQuerier}, zap.NewNop())
+	ctx := context.Background()
+	keyParams := func(key string) postgres.GetIdempotencyKeyParams {
+		return postgres.GetIdempotencyKeyParams{UserID: 1, IdempotencyKey: key}
+	}
+
+	mockQuerier.On("CreateIdempotencyKey", mock.Anything, postgres.CreateIdempotencyKeyParams{UserID: 1, IdempotencyKey: "new", RequestFingerprint: "abc"}).
+		Return(int64(1), nil)
+	mockQuerier.On("CreateIdempotencyKey", mock.Anything, mock.Anything).Return(int64(0), nil)
+	mockQuerier.On("GetIdempotencyKey", mock.Anything, keyParams("done")).Return(postgres.IdempotencyKey{
+		RequestFingerprint: "abc", Status: "completed", ResponseCode: pgtype.Int4{Int32: 200, Valid: true},
+		ResponseContentType: pgtype.Text{String: "application/json", Valid: true}, ResponseBody: []byte(`{"added":20}`),
+	}, nil)
+	mockQuerier.On("GetIdempotencyKey", mock.Anything, keyParams("running")).Return(postgres.IdempotencyKey{
+		RequestFingerprint: "abc", Status: "processing",
+	}, nil)
+	mockQuerier.On("GetIdempotencyKey", mock.Anything, keyParams("stuck")).Return(postgres.IdempotencyKey{
+		RequestFingerprint: "abc", Status: "processing",
+	}, nil)
+	// Only a key without sign of life for the processing timeout can be taken over
+	staleBefore := func(key string) interface{} {
+		return mock.MatchedBy(func(arg postgres.TakeOverIdempotencyKeyParams) bool {
+			return arg.IdempotencyKey == key && arg.RequestFingerprint == "abc" &&
+				time.Since(arg.StaleBefore.Time) >= idempotencyProcessingTimeout
+		})
+	}
+	mockQuerier.On("TakeOverIdempotencyKey", mock.Anything, staleBefore("running")).Return(int64(0), nil)
+	mockQuerier.On("TakeOverIdempotencyKey", mock.Anything, staleBefore("stuck")).Return(int64(1), nil)
+
+	stored, err := svc.Begin(ctx, 1, "new", "abc")
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	stored, err = svc.Begin(ctx, 1, "done", "abc")
+	require.NoError(t, err)
+	assert.Equal(t, &StoredResponse{StatusCode: 200, ContentType: "application/json", Body: []byte(`{"added":20}`)}, stored)
+
+	_, err = svc.Begin(ctx, 1, "done", "other")
+	assert.ErrorIs(t, err, ErrIdempotencyKeyReused)
+
+	_, err = svc.Begin(ctx, 1, "running", "abc")
+	assert.ErrorIs(t, err, ErrIdempotencyInProgress)
+
+	stored, err = svc.Begin(ctx, 1, "stuck", "abc")
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+}
@@ -0,0 +1,578 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	FlowSalary      = "salary"
+	FlowBenefits    = "benefits"
+	FlowPension     = "pension"
+	FlowOtherIncome = "other_income"
+	FlowRent        = "rent"
+	FlowLoan        = "loan"
+	FlowOtherDebit  = "other"
+
+	defaultMinSolvencyScore = 60
+
+	daysPerMonth = 30.4375
+	// A credit mirrored by a debit of the same counterparty and amount within this window is a transfer between own accounts
+	mirrorWindow = 2 * 24 * time.Hour
+	// A credit repaying an earlier debit of the same counterparty and amount within this window is a refund
+	refundWindow = 90 * 24 * time.Hour
+)
+
+// Keywords are matched on whole words of the normalized label (upper case, no accents).
+var (
+	internalTransferKeywords = []string{"VIR INTERNE", "VIREMENT INTERNE", "COMPTE A COMPTE", "LIVRET", "EPARGNE", "LDDS", "PEL", "TRANSFERT"}
+	refundKeywords           = []string{"REMBOURSEMENT", "REMB", "AVOIR", "REFUND", "RETOUR", "ANNULATION"}
+	salaryKeywords           = []string{"SALAIRE", "PAIE", "PAYE", "SALARY", "REMUNERATION"}
+	benefitsKeywords         = []string{"CAF", "APL", "ALLOCATION", "FRANCE TRAVAIL", "POLE EMPLOI", "CPAM"}
+	pensionKeywords          = []string{"PENSION", "RETRAITE", "CARSAT", "AGIRC", "ARRCO"}
+	rentKeywords             = []string{"LOYER", "BAILLEUR", "FONCIA", "NEXITY", "CITYA", "ORPI"}
+	loanKeywords             = []string{"PRET", "CREDIT", "EMPRUNT", "COFIDIS", "CETELEM", "SOFINCO"}
+
+	// Words that describe the payment method rather than the counterparty
+	labelNoiseWords = map[string]bool{
+		"VIR": true, "VIREMENT": true, "SEPA": true, "RECU": true, "EMIS": true, "INST": true, "DE": true, "DU": true,
+		"PRLV": true, "PRELEVEMENT": true, "CB": true, "CARTE": true, "FACT": true, "FACTURE": true, "REF": true,
+		"MOTIF": true, "ECHEANCE": true, "PAIEMENT": true, "PAR": true, "LE": true, "LA": true,
+	}
+	// Month names vary from one occurrence to the next ("SALAIRE JANVIER", "Salary Feb")
+	labelMonthWords = strings.Fields("JAN JANV JANVIER JANUARY FEB FEV FEVR FEVRIER FEBRUARY MAR MARS MARCH APR AVR AVRIL APRIL " +
+		"MAY MAI JUN JUIN JUNE JUL JUIL JUILLET JULY AUG AOU AOUT AUGUST SEP SEPT SEPTEMBRE SEPTEMBER " +
+		"OCT OCTOBRE OCTOBER NOV NOVEMBRE NOVEMBER DEC DECEMBRE DECEMBER")
+
+	accentReplacer = strings.NewReplacer(
+		"É", "E", "È", "E", "Ê", "E", "Ë", "E", "À", "A", "Â", "A", "Ä", "A",
+		"Î", "I", "Ï", "I", "Ô", "O", "Ö", "O", "Ù", "U", "Û", "U", "Ü", "U", "Ç", "C",
+	)
+)
+
+// RecurringFlow is a recurring credit (income source) or debit detected in the transactions.
+type RecurringFlow struct {
+	Counterparty  string  `json:"counterparty"`
+	Category      string  `json:"category"`
+	MonthlyAmount float64 `json:"monthly_amount"`
+	Occurrences   int     `json:"occurrences"`
+	IntervalDays  int     `json:"interval_days"`
+}
+
+// MonthlyIncome is the income actually received in a calendar month, transfers and refunds excluded.
+type MonthlyIncome struct {
+	Month     string  `json:"month"` // YYYY-MM
+	Recurring float64 `json:"recurring"`
+	Other     float64 `json:"other"`
+}
+
+// ScoreFactor explains how many points a criterion contributed to the score.
+type ScoreFactor struct {
+	Code      string `json:"code"`
+	Label     string `json:"label"`
+	Points    int    `json:"points"`
+	MaxPoints int    `json:"max_points"`
+	Detail    string `json:"detail"`
+}
+
+// IncomeAnalysis is the structured result of a solvency check, stored in solvency_checks.analysis_json.
+type IncomeAnalysis struct {
+	Score         int     `json:"score"`
+	PeriodStart   string  `json:"period_start,omitempty"`
+	PeriodEnd     string  `json:"period_end,omitempty"`
+	MonthsCovered float64 `json:"months_covered"`
+
+	MonthlyRecurringIncome float64 `json:"monthly_recurring_income"`
+	MonthlyOtherIncome     float64 `json:"monthly_other_income"`
+	MonthlyIncome          float64 `json:"monthly_income"`
+
+	CurrentRent            float64 `json:"current_rent"`
+	MonthlyLoanRepayments  float64 `json:"monthly_loan_repayments"`
+	MonthlyOtherRecurrings float64 `json:"monthly_other_recurring_debits"`
+
+	RentAmount float64 `json:"rent_amount"`
+	EffortRate float64 `json:"effort_rate"` // rent / monthly income
+	DebtRatio  float64 `json:"debt_ratio"`  // (rent + loans) / monthly income
+
+	IncomeSources     []RecurringFlow `json:"income_sources"`
+	IncomeByMonth     []MonthlyIncome `json:"income_by_month"`
+	RecurringDebits   []RecurringFlow `json:"recurring_debits"`
+	ExcludedTransfers int             `json:"excluded_transfers"`
+	ExcludedRefunds   int             `json:"excluded_refunds"`
+	IgnoredCount      int             `json:"ignored_transactions"`
+
+	Factors []ScoreFactor `json:"factors"`
+}
+
+// IncomeAnalysisFromJSON decodes analysis_json for API responses (nil when the check was not processed).
+func IncomeAnalysisFromJSON(raw []byte) *IncomeAnalysis {
+	if len(raw) == 0 {
+		return nil
+	}
+	var a IncomeAnalysis
+	if err := json.Unmarshal(raw, &a); err != nil {
+		return nil
+	}
+	return &a
+}
+
+type analyzedTx struct {
+	TransactionData
+	label        string
+	counterparty string
+}
+
+func normalizeLabel(s string) string {
+	s = accentReplacer.Replace(strings.ToUpper(s))
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return ' '
+	}, s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func hasKeyword(label string, keywords []string) bool {
+	padded := " " + label + " "
+	for _, kw := range keywords {
+		if strings.Contains(padded, " "+kw+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// counterpartyOf uses the provider's counterparty when present, otherwise the meaningful words of the label
+// (payment method words and references containing digits are dropped).
+func counterpartyOf(tx TransactionData, label string) string {
+	if tx.Counterparty != "" {
+		return normalizeLabel(tx.Counterparty)
+	}
+	var words []string
+	for _, w := range strings.Fields(label) {
+		if labelNoiseWords[w] || slices.Contains(labelMonthWords, w) || strings.ContainsAny(w, "0123456789") {
+			continue
+		}
+		words = append(words, w)
+		if len(words) == 3 {
+			break
+		}
+	}
+	if len(words) == 0 {
+		return label
+	}
+	return strings.Join(words, " ")
+}
+
+func round2(f float64) float64 {
+	return math.Round(f*100) / 100
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+func sameAmount(a, b float64) bool {
+	return math.Abs(math.Abs(a)-math.Abs(b)) < 0.01
+}
+
+// detectRecurring checks whether the transactions of one counterparty follow a weekly, fortnightly or
+// monthly rhythm with stable amounts. It returns the monthly equivalent of the median amount.
+func detectRecurring(group []analyzedTx) (RecurringFlow, bool) {
+	// Several payments on the same day count as one occurrence
+	var dates []time.Time
+	var amounts []float64
+	for _, tx := range group {
+		day := tx.Date.Truncate(24 * time.Hour)
+		if n := len(dates); n > 0 && dates[n-1].Equal(day) {
+			amounts[n-1] += math.Abs(tx.Amount)
+			continue
+		}
+		dates = append(dates, day)
+		amounts = append(amounts, math.Abs(tx.Amount))
+	}
+	if len(dates) < 2 {
+		return RecurringFlow{}, false
+	}
+
+	intervals := make([]float64, 0, len(dates)-1)
+	for i := 1; i < len(dates); i++ {
+		intervals = append(intervals, dates[i].Sub(dates[i-1]).Hours()/24)
+	}
+	m := median(intervals)
+	var period float64
+	switch {
+	case m >= 6 && m <= 8:
+		period = 7
+	case m >= 12 && m <= 16:
+		period = 14
+	case m >= 26 && m <= 35:
+		period = daysPerMonth
+	default:
+		return RecurringFlow{}, false
+	}
+
+	// At least two thirds of the intervals and amounts must be regular
+	regular := func(values []float64, ref, tolerance float64) bool {
+		ok := 0
+		for _, v := range values {
+			if math.Abs(v-ref) <= ref*tolerance {
+				ok++
+			}
+		}
+		return ok*3 >= len(values)*2
+	}
+	medianAmount := median(amounts)
+	if !regular(intervals, m, 0.4) || !regular(amounts, medianAmount, 0.3) {
+		return RecurringFlow{}, false
+	}
+
+	return RecurringFlow{
+		MonthlyAmount: round2(medianAmount * daysPerMonth / period),
+		Occurrences:   len(dates),
+		IntervalDays:  int(math.Round(m)),
+	}, true
+}
+
+func classifyCredit(labels []string) string {
+	for _, l := range labels {
+		switch {
+		case hasKeyword(l, salaryKeywords):
+			return FlowSalary
+		case hasKeyword(l, pensionKeywords):
+			return FlowPension
+		case hasKeyword(l, benefitsKeywords):
+			return FlowBenefits
+		}
+	}
+	return FlowOtherIncome
+}
+
+func classifyDebit(labels []string) string {
+	for _, l := range labels {
+		switch {
+		case hasKeyword(l, rentKeywords):
+			return FlowRent
+		case hasKeyword(l, loanKeywords):
+			return FlowLoan
+		}
+	}
+	return FlowOtherDebit
+}
+
+// AnalyzeIncome turns raw bank transactions into the structured solvency analysis:
+// internal transfers and refunds are excluded, recurring credits and debits are detected per
+// counterparty, amounts are normalised per month over the actual period covered, and a 0–100
+// score is computed for rentAmount.
+func AnalyzeIncome(transactions []TransactionData, rentAmount float64) IncomeAnalysis {
+	a := IncomeAnalysis{
+		RentAmount:      round2(rentAmount),
+		IncomeSources:   []RecurringFlow{},
+		IncomeByMonth:   []MonthlyIncome{},
+		RecurringDebits: []RecurringFlow{},
+	}
+
+	var txs []analyzedTx
+	for _, tx := range transactions {
+		if tx.Date.IsZero() || tx.Amount == 0 {
+			a.IgnoredCount++
+			continue
+		}
+		label := normalizeLabel(tx.Description)
+		txs = append(txs, analyzedTx{TransactionData: tx, label: label, counterparty: counterpartyOf(tx, label)})
+	}
+	if len(txs) == 0 {
+		scoreIncomeAnalysis(&a)
+		return a
+	}
+
+	sort.SliceStable(txs, func(i, j int) bool { return txs[i].Date.Before(txs[j].Date) })
+	start, end := txs[0].Date, txs[len(txs)-1].Date
+	a.PeriodStart = start.Format("2006-01-02")
+	a.PeriodEnd = end.Format("2006-01-02")
+	// Inclusive span; less than a month of history is not extrapolated
+	months := math.Max((end.Sub(start).Hours()/24+1)/daysPerMonth, 1)
+	a.MonthsCovered = round2(months)
+
+	excluded := make([]bool, len(txs))
+
+	// 1. Transfers between the candidate's own accounts
+	for i, tx := range txs {
+		if hasKeyword(tx.label, internalTransferKeywords) {
+			excluded[i] = true
+			a.ExcludedTransfers++
+		}
+	}
+	for i, credit := range txs {
+		if excluded[i] || credit.Amount <= 0 {
+			continue
+		}
+		for j, debit := range txs {
+			if excluded[j] || debit.Amount >= 0 || debit.counterparty != credit.counterparty || !sameAmount(credit.Amount, debit.Amount) {
+				continue
+			}
+			if d := credit.Date.Sub(debit.Date); d <= mirrorWindow && d >= -mirrorWindow {
+				excluded[i], excluded[j] = true, true
+				a.ExcludedTransfers += 2
+				break
+			}
+		}
+	}
+
+	// 2. Refunds are not income
+	for i, credit := range txs {
+		if excluded[i] || credit.Amount <= 0 {
+			continue
+		}
+		refund := hasKeyword(credit.label, refundKeywords)
+		for j := 0; j < i && !refund; j++ {
+			debit := txs[j]
+			refund = debit.Amount < 0 && debit.counterparty == credit.counterparty &&
+				sameAmount(credit.Amount, debit.Amount) && credit.Date.Sub(debit.Date) <= refundWindow
+		}
+		if refund {
+			excluded[i] = true
+			a.ExcludedRefunds++
+		}
+	}
+
+	// 3. Recurring flows per counterparty
+	credits := make(map[string][]analyzedTx)
+	debits := make(map[string][]analyzedTx)
+	for i, tx := range txs {
+		if excluded[i] {
+			continue
+		}
+		if tx.Amount > 0 {
+			credits[tx.counterparty] = append(credits[tx.counterparty], tx)
+		} else {
+			debits[tx.counterparty] = append(debits[tx.counterparty], tx)
+		}
+	}
+
+	labelsOf := func(group []analyzedTx) []string {
+		labels := make([]string, len(group))
+		for i, tx := range group {
+			labels[i] = tx.label
+		}
+		return labels
+	}
+
+	var recurringIncome, otherIncome float64
+	recurringSources := make(map[string]bool)
+	for counterparty, group := range credits {
+		flow, ok := detectRecurring(group)
+		if !ok {
+			for _, tx := range group {
+				otherIncome += tx.Amount
+			}
+			continue
+		}
+		recurringSources[counterparty] = true
+		flow.Counterparty = counterparty
+		flow.Category = classifyCredit(labelsOf(group))
+		a.IncomeSources = append(a.IncomeSources, flow)
+		recurringIncome += flow.MonthlyAmount
+	}
+	for counterparty, group := range debits {
+		flow, ok := detectRecurring(group)
+		if !ok {
+			continue
+		}
+		flow.Counterparty = counterparty
+		flow.Category = classifyDebit(labelsOf(group))
+		a.RecurringDebits = append(a.RecurringDebits, flow)
+		switch flow.Category {
+		case FlowRent:
+			a.CurrentRent += flow.MonthlyAmount
+		case FlowLoan:
+			a.MonthlyLoanRepayments += flow.MonthlyAmount
+		default:
+			a.MonthlyOtherRecurrings += flow.MonthlyAmount
+		}
+	}
+
+	byAmount := func(flows []RecurringFlow) {
+		sort.Slice(flows, func(i, j int) bool {
+			if flows[i].MonthlyAmount != flows[j].MonthlyAmount {
+				return flows[i].MonthlyAmount > flows[j].MonthlyAmount
+			}
+			return flows[i].Counterparty < flows[j].Counterparty
+		})
+	}
+	byAmount(a.IncomeSources)
+	byAmount(a.RecurringDebits)
+
+	// 4. Month by month breakdown (txs are sorted, so months come in order)
+	for i, tx := range txs {
+		if excluded[i] || tx.Amount <= 0 {
+			continue
+		}
+		month := tx.Date.Format("2006-01")
+		if n := len(a.IncomeByMonth); n == 0 || a.IncomeByMonth[n-1].Month != month {
+			a.IncomeByMonth = append(a.IncomeByMonth, MonthlyIncome{Month: month})
+		}
+		m := &a.IncomeByMonth[len(a.IncomeByMonth)-1]
+		if recurringSources[tx.counterparty] {
+			m.Recurring = round2(m.Recurring + tx.Amount)
+		} else {
+			m.Other = round2(m.Other + tx.Amount)
+		}
+	}
+
+	a.MonthlyRecurringIncome = round2(recurringIncome)
+	a.MonthlyOtherIncome = round2(otherIncome / months)
+	a.MonthlyIncome = round2(recurringIncome + otherIncome/months)
+	a.CurrentRent = round2(a.CurrentRent)
+	a.MonthlyLoanRepayments = round2(a.MonthlyLoanRepayments)
+	a.MonthlyOtherRecurrings = round2(a.MonthlyOtherRecurrings)
+
+	scoreIncomeAnalysis(&a)
+	return a
+}
+
+// scoreIncomeAnalysis computes the 0–100 score:
+//   - effort rate (40): share of the income taken by the rent, the usual "3x the rent" rule being 33%
+//   - stability (25): share of the income coming from recurring sources
+//   - debt ratio (20): rent plus existing loans, against the 35% lending threshold
+//   - history (15): at least three months of transactions
+//
+// The current rent is reported for information: it stops when the candidate moves.
+func scoreIncomeAnalysis(a *IncomeAnalysis) {
+	income := a.MonthlyIncome
+	var factors []ScoreFactor
+
+	effort := ScoreFactor{Code: "effort_rate", Label: "Taux d'effort (loyer / revenus)", MaxPoints: 40}
+	switch {
+	case a.RentAmount <= 0:
+		// Without a rent the effort rate cannot be assessed: no points rather than all of them
+		effort.Detail = "Loyer du logement non renseigné : taux d'effort non évaluable"
+	case income <= 0:
+		effort.Detail = "Aucun revenu détecté"
+	default:
+		a.EffortRate = round2(a.RentAmount / income)
+		switch {
+		case a.EffortRate <= 0.25:
+			effort.Points = 40
+		case a.EffortRate <= 0.33:
+			effort.Points = 32
+		case a.EffortRate <= 0.40:
+			effort.Points = 20
+		case a.EffortRate <= 0.50:
+			effort.Points = 8
+		}
+		effort.Detail = fmt.Sprintf("Loyer de %.2f € pour %.2f € de revenus mensuels (%.0f %%)", a.RentAmount, income, a.EffortRate*100)
+	}
+	factors = append(factors, effort)
+
+	stability := ScoreFactor{Code: "income_stability", Label: "Stabilité des revenus", MaxPoints: 25}
+	if income > 0 {
+		share := a.MonthlyRecurringIncome / income
+		stability.Points = int(math.Round(25 * share))
+		stability.Detail = fmt.Sprintf("%.0f %% des revenus proviennent de %d source(s) récurrente(s)", share*100, len(a.IncomeSources))
+	} else {
+		stability.Detail = "Aucun revenu récurrent détecté"
+	}
+	factors = append(factors, stability)
+
+	debt := ScoreFactor{Code: "debt_ratio", Label: "Endettement (loyer + crédits)", MaxPoints: 20}
+	if income > 0 {
+		a.DebtRatio = round2((a.RentAmount + a.MonthlyLoanRepayments) / income)
+		switch {
+		case a.DebtRatio <= 0.35:
+			debt.Points = 20
+		case a.DebtRatio <= 0.45:
+			debt.Points = 10
+		case a.DebtRatio <= 0.55:
+			debt.Points = 5
+		}
+		debt.Detail = fmt.Sprintf("%.2f € de crédits en cours, taux d'endettement de %.0f %%", a.MonthlyLoanRepayments, a.DebtRatio*100)
+	} else {
+		debt.Detail = "Aucun revenu détecté"
+	}
+	factors = append(factors, debt)
+
+	history := ScoreFactor{Code: "history", Label: "Historique analysé", MaxPoints: 15}
+	if a.PeriodStart != "" {
+		history.Points = int(math.Round(15 * math.Min(a.MonthsCovered, 3) / 3))
+		history.Detail = fmt.Sprintf("%.1f mois de transactions (du %s au %s)", a.MonthsCovered, a.PeriodStart, a.PeriodEnd)
+	} else {
+		history.Detail = "Aucune transaction exploitable"
+	}
+	factors = append(factors, history)
+
+	if a.CurrentRent > 0 {
+		factors = append(factors, ScoreFactor{
+			Code:   "current_rent",
+			Label:  "Loyer actuel",
+			Detail: fmt.Sprintf("Loyer actuel de %.2f € par mois payé régulièrement", a.CurrentRent),
+		})
+	}
+
+	score := 0
+	for _, f := range factors {
+		score += f.Points
+	}
+	a.Score = min(max(score, 0), 100)
+	a.Factors = factors
+}
+
+// CombineIncomeAnalyses scores the candidate together with their guarantors: incomes and loan
+// repayments add up, the history is the shortest one analyzed. The rent stays the candidate's.
+func CombineIncomeAnalyses(candidate IncomeAnalysis, guarantors []IncomeAnalysis) IncomeAnalysis {
+	c := IncomeAnalysis{
+		PeriodStart:            candidate.PeriodStart,
+		PeriodEnd:              candidate.PeriodEnd,
+		MonthsCovered:          candidate.MonthsCovered,
+		MonthlyRecurringIncome: candidate.MonthlyRecurringIncome,
+		MonthlyOtherIncome:     candidate.MonthlyOtherIncome,
+		MonthlyIncome:          candidate.MonthlyIncome,
+		CurrentRent:            candidate.CurrentRent,
+		MonthlyLoanRepayments:  candidate.MonthlyLoanRepayments,
+		RentAmount:             candidate.RentAmount,
+		IncomeSources:          append([]RecurringFlow{}, candidate.IncomeSources...),
+	}
+	for _, g := range guarantors {
+		c.MonthlyRecurringIncome += g.MonthlyRecurringIncome
+		c.MonthlyOtherIncome += g.MonthlyOtherIncome
+		c.MonthlyIncome += g.MonthlyIncome
+		c.MonthlyLoanRepayments += g.MonthlyLoanRepayments
+		c.IncomeSources = append(c.IncomeSources, g.IncomeSources...)
+		if g.PeriodStart != "" && (c.PeriodStart == "" || g.MonthsCovered < c.MonthsCovered) {
+			c.PeriodStart, c.PeriodEnd, c.MonthsCovered = g.PeriodStart, g.PeriodEnd, g.MonthsCovered
+		}
+	}
+	c.MonthlyRecurringIncome = round2(c.MonthlyRecurringIncome)
+	c.MonthlyOtherIncome = round2(c.MonthlyOtherIncome)
+	c.MonthlyIncome = round2(c.MonthlyIncome)
+	c.MonthlyLoanRepayments = round2(c.MonthlyLoanRepayments)
+
+	scoreIncomeAnalysis(&c)
+	return c
+}
+
+// RescoreForRent re-scores an analysis against another rent, e.g. when a dossier verified for one
+// property is presented for another. Incomes and debits are unchanged.
+func RescoreForRent(a IncomeAnalysis, rent float64) IncomeAnalysis {
+	a.RentAmount = rent
+	a.EffortRate = 0
+	a.DebtRatio = 0
+	scoreIncomeAnalysis(&a)
+	return a
+}
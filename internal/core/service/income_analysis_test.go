@@ -0,0 +1,135 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func day(s string) time.Time {
+	t, _ := time.Parse("2006-01-02", s)
+	return t
+}
+
+func TestAnalyzeIncome_RecurringSalaryAndExclusions(t *testing.T) {
+	txs := []TransactionData{
+		{Amount: 2500, Description: "VIR SEPA RECU ACME SAS SALAIRE JANVIER", Date: day("2024-01-28")},
+		{Amount: 2550, Description: "VIR SEPA RECU ACME SAS SALAIRE FEVRIER", Date: day("2024-02-28")},
+		{Amount: 2500, Description: "VIR SEPA RECU ACME SAS SALAIRE MARS", Date: day("2024-03-28")},
+		// CAF, received monthly
+		{Amount: 150, Description: "VIR CAF 123456", Date: day("2024-01-05")},
+		{Amount: 150, Description: "VIR CAF 123999", Date: day("2024-02-05")},
+		{Amount: 150, Description: "VIR CAF 124321", Date: day("2024-03-05")},
+		// Savings transfer and money moved back and forth between own accounts
+		{Amount: 5000, Description: "VIREMENT DEPUIS LIVRET A", Date: day("2024-02-10")},
+		{Amount: -800, Description: "VIR M DUPONT", Date: day("2024-03-01")},
+		{Amount: 800, Description: "VIR M DUPONT", Date: day("2024-03-02")},
+		// Refund of a purchase
+		{Amount: -120, Description: "CB DARTY 12/01", Date: day("2024-01-12")},
+		{Amount: 120, Description: "DARTY", Date: day("2024-01-20")},
+		{Amount: 45, Description: "REMBOURSEMENT CPAM SOINS", Date: day("2024-02-14")},
+		// Current rent and a consumer loan
+		{Amount: -700, Description: "PRLV LOYER FONCIA", Date: day("2024-01-03")},
+		{Amount: -700, Description: "PRLV LOYER FONCIA", Date: day("2024-02-03")},
+		{Amount: -700, Description: "PRLV LOYER FONCIA", Date: day("2024-03-03")},
+		{Amount: -200, Description: "ECHEANCE PRET COFIDIS", Date: day("2024-01-10")},
+		{Amount: -200, Description: "ECHEANCE PRET COFIDIS", Date: day("2024-02-10")},
+		{Amount: -200, Description: "ECHEANCE PRET COFIDIS", Date: day("2024-03-10")},
+		// Undated lines cannot be placed in the period
+		{Amount: 10000, Description: "BONUS"},
+	}
+
+	a := AnalyzeIncome(txs, 800)
+
+	assert.Equal(t, "2024-01-03", a.PeriodStart)
+	assert.Equal(t, "2024-03-28", a.PeriodEnd)
+	assert.InDelta(t, 2.83, a.MonthsCovered, 0.01)
+	assert.Equal(t, 3, a.ExcludedTransfers)
+	assert.Equal(t, 2, a.ExcludedRefunds)
+	assert.Equal(t, 1, a.IgnoredCount)
+
+	require.Len(t, a.IncomeSources, 2)
+	assert.Equal(t, RecurringFlow{Counterparty: "ACME SAS SALAIRE", Category: FlowSalary, MonthlyAmount: 2500, Occurrences: 3, IntervalDays: 30}, a.IncomeSources[0])
+	assert.Equal(t, FlowBenefits, a.IncomeSources[1].Category)
+	assert.Equal(t, 2650.0, a.MonthlyRecurringIncome)
+	assert.Equal(t, 0.0, a.MonthlyOtherIncome)
+	assert.Equal(t, []MonthlyIncome{
+		{Month: "2024-01", Recurring: 2650},
+		{Month: "2024-02", Recurring: 2700},
+		{Month: "2024-03", Recurring: 2650},
+	}, a.IncomeByMonth)
+
+	assert.Equal(t, 700.0, a.CurrentRent)
+	assert.Equal(t, 200.0, a.MonthlyLoanRepayments)
+	assert.InDelta(t, 0.30, a.EffortRate, 0.001)
+	assert.InDelta(t, 0.38, a.DebtRatio, 0.001)
+
+	// effort 32 + stability 25 + debt 10 + history 14
+	assert.Equal(t, 81, a.Score)
+	codes := []string{}
+	for _, f := range a.Factors {
+		codes = append(codes, f.Code)
+	}
+	assert.Equal(t, []string{"effort_rate", "income_stability", "debt_ratio", "history", "current_rent"}, codes)
+}
+
+func TestAnalyzeIncome_IrregularIncomeOverActualPeriod(t *testing.T) {
+	txs := []TransactionData{
+		{Amount: 1200, Description: "Facture client A", Date: day("2024-01-15")},
+		{Amount: 3000, Description: "Facture client B", Date: day("2024-03-01")},
+		{Amount: 600, Description: "Facture client C", Date: day("2024-04-14")},
+	}
+
+	a := AnalyzeIncome(txs, 1000)
+
+	// 91 days: not the assumed three months of the former heuristic
+	assert.InDelta(t, 2.99, a.MonthsCovered, 0.01)
+	assert.Empty(t, a.IncomeSources)
+	assert.InDelta(t, 4800/2.99, a.MonthlyIncome, 1)
+	assert.Equal(t, 0, a.Factors[1].Points, "no recurring income")
+}
+
+func TestAnalyzeIncome_NoRent(t *testing.T) {
+	txs := []TransactionData{
+		{Amount: 2500, Description: "VIR SALAIRE ACME", Date: day("2024-01-28")},
+		{Amount: 2500, Description: "VIR SALAIRE ACME", Date: day("2024-02-28")},
+		{Amount: 2500, Description: "VIR SALAIRE ACME", Date: day("2024-03-28")},
+	}
+
+	for _, rent := range []float64{0, -500} {
+		a := AnalyzeIncome(txs, rent)
+		assert.Equal(t, "effort_rate", a.Factors[0].Code)
+		assert.Equal(t, 0, a.Factors[0].Points, "rent %v", rent)
+		assert.Equal(t, 0.0, a.EffortRate)
+	}
+}
+
+func TestAnalyzeIncome_NoTransactions(t *testing.T) {
+	a := AnalyzeIncome(nil, 900)
+
+	assert.Equal(t, 0, a.Score)
+	assert.Equal(t, 0.0, a.MonthlyIncome)
+	assert.Len(t, a.Factors, 4)
+}
+
+func TestDetectRecurring_Periodicity(t *testing.T) {
+	group := func(dates ...string) []analyzedTx {
+		var txs []analyzedTx
+		for _, d := range dates {
+			txs = append(txs, analyzedTx{TransactionData: TransactionData{Amount: 100, Date: day(d)}})
+		}
+		return txs
+	}
+
+	weekly, ok := detectRecurring(group("2024-01-01", "2024-01-08", "2024-01-15", "2024-01-22"))
+	require.True(t, ok)
+	assert.InDelta(t, 434.82, weekly.MonthlyAmount, 0.01)
+
+	_, ok = detectRecurring(group("2024-01-01", "2024-01-20"))
+	assert.False(t, ok, "19 days is neither fortnightly nor monthly")
+
+	_, ok = detectRecurring(group("2024-01-01"))
+	assert.False(t, ok)
+}
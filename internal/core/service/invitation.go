@@ -5,6 +5,7 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -84,6 +85,97 @@ func (s *UserService) InviteTenant(ctx context.Context, ownerID int32, propertyI
 	return &invitation, nil
 }
 
+// InviteCoTenant adds a co-tenant to a lease and sends them their own invitation. A co-tenant may replace
+// one who gave notice, which ends the latter's solidarity once the invitation is accepted.
+func (s *UserService) InviteCoTenant(ctx context.Context, ownerID, leaseID int32, p CoTenantParams) (*LeasePartyDTO, error) {
+	token, err := GenerateSecureToken()
+	if err != nil {
+		return nil, err
+	}
+
+	var party postgres.LeaseParty
+	err = s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		lease, prop, err := ownedLease(ctx, q, leaseID, ownerID)
+		if err != nil {
+			return err
+		}
+		if prop.RentalType == postgres.PropertyTypeSeasonal || lease.LeaseStatus.String == "terminated" {
+			return ErrLeaseClosedToParties
+		}
+
+		parties, err := q.ListLeaseParties(ctx, leaseID)
+		if err != nil {
+			return err
+		}
+		for _, existing := range parties {
+			if strings.EqualFold(existing.Email, p.Email) {
+				return ErrCoTenantAlreadyInvited
+			}
+		}
+		if p.ReplacesPartyID != 0 {
+			departing, err := q.GetLeaseParty(ctx, postgres.GetLeasePartyParams{ID: p.ReplacesPartyID, LeaseID: leaseID})
+			if err == pgx.ErrNoRows {
+				return ErrLeasePartyNotFound
+			}
+			if err != nil {
+				return err
+			}
+			if departing.Status != LeasePartyStatusLeft {
+				return ErrInvalidReplacement
+			}
+			if _, err := q.GetLeasePartyByReplaced(ctx, pgtype.Int4{Int32: departing.ID, Valid: true}); err == nil {
+				return ErrInvalidReplacement
+			} else if err != pgx.ErrNoRows {
+				return err
+			}
+		}
+
+		share := pgtype.Numeric{}
+		if p.RentShare > 0 {
+			share = numeric(p.RentShare)
+		}
+		party, err = q.CreateLeaseParty(ctx, postgres.CreateLeasePartyParams{
+			LeaseID:         leaseID,
+			Email:           p.Email,
+			FirstName:       pgtype.Text{String: p.FirstName, Valid: p.FirstName != ""},
+			LastName:        pgtype.Text{String: p.LastName, Valid: p.LastName != ""},
+			RentShare:       share,
+			Status:          LeasePartyStatusInvited,
+			ReplacesPartyID: pgtype.Int4{Int32: p.ReplacesPartyID, Valid: p.ReplacesPartyID != 0},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to add co-tenant: %w", err)
+		}
+
+		_, err = q.CreateInvitationWithLease(ctx, postgres.CreateInvitationWithLeaseParams{
+			PropertyID:  prop.ID,
+			LeaseID:     pgtype.Int4{Int32: leaseID, Valid: true},
+			OwnerID:     ownerID,
+			TenantEmail: p.Email,
+			Token:       token,
+			ExpiresAt:   pgtype.Timestamp{Time: time.Now().Add(7 * 24 * time.Hour), Valid: true},
+			PartyID:     pgtype.Int4{Int32: party.ID, Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create invitation: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log := logger.FromContext(ctx)
+	inviteLink := fmt.Sprintf("%s/register?token=%s", s.frontendURL, token)
+	if err := s.emailSender.SendInvitation(ctx, p.Email, inviteLink); err != nil {
+		log.Warn("failed to send co-tenant invitation email", zap.Error(err))
+	}
+	log.Info("co-tenant invited", zap.Int32("lease_id", leaseID), zap.Int32("party_id", party.ID))
+
+	dto := toLeasePartyDTO(party)
+	return &dto, nil
+}
+
 // AcceptInvitation allows a user to accept an invitation using a token.
 func (s *UserService) AcceptInvitation(ctx context.Context, token string, userID int32) error {
 
@@ -140,6 +232,10 @@ func (s *UserService) AcceptInvitation(ctx context.Context, token string, userID
 			leaseID = lease.ID
 		}
 
+		if err := joinLeaseParty(ctx, q, inv, leaseID, userID); err != nil {
+			return fmt.Errorf("failed to join lease: %w", err)
+		}
+
 		// 5. Update Invitation Status
 		err = q.UpdateInvitationStatus(ctx, postgres.UpdateInvitationStatusParams{
 			ID:     inv.ID,
@@ -159,6 +255,10 @@ func (s *UserService) AcceptInvitation(ctx context.Context, token string, userID
 			logger.FromContext(ctx).Error("failed to generate lease document after accept", zap.Error(err))
 			// Non-critical: User can still access lease via fallback or we can retry later.
 		}
+		// The guarantors of the tenant's approved check stand surety for this lease (one tenant at a time for co-tenants)
+		if _, err := s.leaseService.GenerateGuaranteeDeeds(ctx, leaseID, userID); err != nil {
+			logger.FromContext(ctx).Error("failed to generate guarantee deeds after accept", zap.Error(err))
+		}
 	}
 
 	return nil
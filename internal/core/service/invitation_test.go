@@ -92,6 +92,12 @@ func TestAcceptInvitation_Success(t *testing.T) {
 	// Mock CreateLease
 	mockQuerier.On("CreateLease", mock.Anything, mock.Anything).Return(postgres.Lease{ID: 1}, nil)
 
+	// Mock Lease Party (invitation sent before co-tenancy)
+	mockQuerier.On("GetUserById", mock.Anything, userID).Return(postgres.User{ID: userID, Email: "tenant@example.com"}, nil)
+	mockQuerier.On("CreateLeaseParty", mock.Anything, mock.MatchedBy(func(arg postgres.CreateLeasePartyParams) bool {
+		return arg.LeaseID == 1 && arg.UserID.Int32 == userID && arg.Status == LeasePartyStatusActive
+	})).Return(postgres.LeaseParty{ID: 1}, nil)
+
 	// Mock Update Status
 	mockQuerier.On("UpdateInvitationStatus", mock.Anything, postgres.UpdateInvitationStatusParams{
 		ID:     1,
@@ -100,6 +106,7 @@ func TestAcceptInvitation_Success(t *testing.T) {
 
 	// Expect Lease Generation
 	mockLeaseService.On("GenerateAndSave", mock.Anything, int32(1), userID).Return(nil)
+	mockLeaseService.On("GenerateGuaranteeDeeds", mock.Anything, int32(1), userID).Return(0, nil)
 
 	// Execute
 	err := svc.AcceptInvitation(context.Background(), token, userID)
@@ -137,3 +144,47 @@ func TestAcceptInvitation_Expired(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "expired")
 }
+
+func TestAcceptInvitation_CoTenantReplacesDeparted(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	mockLeaseService := new(MockLeaseService)
+	svc := NewUserService(passthroughTxManager{q: mockQuerier}, zap.NewNop(), email.NewMockEmailSender(zap.NewNop()), "http://test.com", mockLeaseService)
+
+	userID := int32(56)
+	mockQuerier.On("GetInvitationByToken", mock.Anything, "cotenant-token").Return(postgres.LeaseInvitation{
+		ID:         2,
+		PropertyID: 100,
+		LeaseID:    pgtype.Int4{Int32: 4, Valid: true},
+		PartyID:    pgtype.Int4{Int32: 8, Valid: true},
+		Status:     pgtype.Text{String: "pending", Valid: true},
+		ExpiresAt:  pgtype.Timestamp{Time: time.Now().Add(24 * time.Hour), Valid: true},
+	}, nil)
+	mockQuerier.On("GetProperty", mock.Anything, int32(100)).Return(postgres.Property{ID: 100}, nil)
+	// The first tenant stays the lease's tenant_id
+	mockQuerier.On("UpdateLeaseTenant", mock.Anything, postgres.UpdateLeaseTenantParams{ID: 4, TenantID: pgtype.Int4{Int32: userID, Valid: true}}).Return(nil)
+	mockQuerier.On("GetLeaseParty", mock.Anything, postgres.GetLeasePartyParams{ID: 8, LeaseID: 4}).Return(postgres.LeaseParty{
+		ID: 8, LeaseID: 4, Status: LeasePartyStatusInvited, ReplacesPartyID: pgtype.Int4{Int32: 3, Valid: true},
+	}, nil)
+	mockQuerier.On("ActivateLeaseParty", mock.Anything, postgres.ActivateLeasePartyParams{ID: 8, UserID: pgtype.Int4{Int32: userID, Valid: true}}).Return(nil)
+
+	// The departing co-tenant left last month: their six months of solidarity end with the replacement
+	leftAt := time.Now().AddDate(0, -1, 0)
+	mockQuerier.On("GetLeaseParty", mock.Anything, postgres.GetLeasePartyParams{ID: 3, LeaseID: 4}).Return(postgres.LeaseParty{
+		ID:               3,
+		LeaseID:          4,
+		Status:           LeasePartyStatusLeft,
+		LeftAt:           pgtype.Date{Time: leftAt, Valid: true},
+		SolidarityEndsAt: pgtype.Date{Time: leftAt.AddDate(0, 6, 0), Valid: true},
+	}, nil)
+	mockQuerier.On("SetLeasePartySolidarityEnd", mock.Anything, mock.MatchedBy(func(arg postgres.SetLeasePartySolidarityEndParams) bool {
+		return arg.ID == 3 && arg.SolidarityEndsAt.Time.After(leftAt) && arg.SolidarityEndsAt.Time.Before(time.Now().Add(time.Minute))
+	})).Return(nil)
+	mockQuerier.On("UpdateInvitationStatus", mock.Anything, mock.Anything).Return(nil)
+	mockLeaseService.On("GenerateAndSave", mock.Anything, int32(4), userID).Return(nil)
+	mockLeaseService.On("GenerateGuaranteeDeeds", mock.Anything, int32(4), userID).Return(0, nil)
+
+	err := svc.AcceptInvitation(context.Background(), "cotenant-token", userID)
+
+	assert.NoError(t, err)
+	mockQuerier.AssertExpectations(t)
+}
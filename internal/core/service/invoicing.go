@@ -0,0 +1,429 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+	"seculoc-back/internal/platform/logger"
+)
+
+// Kinds of invoices. A credit note cancels all or part of an invoice after a refund.
+const (
+	InvoiceKindInvoice    = "invoice"
+	InvoiceKindCreditNote = "credit_note"
+)
+
+const (
+	// Numbering series: each one is continuous within a calendar year.
+	invoiceSeries    = "F"
+	creditNoteSeries = "AV"
+
+	// vatRateBps is the VAT rate applied to the services sold (taux normal, 20 %), in basis points.
+	// Prices in the catalog include VAT.
+	vatRateBps = 2000
+
+	invoiceTemplate = "facture.md"
+	// invoiceArchiveBatch is the number of PDFs rendered per run of ArchiveIssuedInvoices.
+	invoiceArchiveBatch = 50
+)
+
+var ErrInvoiceNotFound = errors.New("invoice not found")
+
+// invoiceNumber formats the number of an invoice, e.g. F-2026-000042.
+func invoiceNumber(series string, year int, n int32) string {
+	return fmt.Sprintf("%s-%d-%06d", series, year, n)
+}
+
+// splitVAT breaks a price including VAT down into the amount excluding VAT and the VAT.
+func splitVAT(amountCents, rateBps int32) (exclVAT, vat int32) {
+	exclVAT = int32(math.Round(float64(amountCents) * 10000 / float64(10000+rateBps)))
+	return exclVAT, amountCents - exclVAT
+}
+
+// buyerName is the name printed on the invoices of a user: the account name, or the email without one.
+func buyerName(user postgres.User) string {
+	if name := strings.TrimSpace(user.FirstName.String + " " + user.LastName.String); name != "" {
+		return name
+	}
+	return user.Email
+}
+
+// issueInvoice numbers a paid invoice or credit note and freezes its legal mentions: VAT breakdown at
+// rateBps and buyer identity. The number is taken from the counter of its series in the caller's
+// transaction: if the transaction rolls back, so does the counter, and numbers never have gaps.
+func issueInvoice(ctx context.Context, q postgres.Querier, invoice postgres.Invoice, rateBps int32) (postgres.Invoice, error) {
+	if invoice.Number.Valid {
+		return invoice, nil
+	}
+	user, err := q.GetUserById(ctx, invoice.UserID)
+	if err != nil {
+		return invoice, err
+	}
+
+	series := invoiceSeries
+	if invoice.Kind == InvoiceKindCreditNote {
+		series = creditNoteSeries
+	}
+	year := time.Now().Year()
+	n, err := q.NextInvoiceNumber(ctx, postgres.NextInvoiceNumberParams{Series: series, Year: int32(year)})
+	if err != nil {
+		return invoice, fmt.Errorf("failed to number invoice: %w", err)
+	}
+
+	exclVAT, vat := splitVAT(invoice.AmountCents, rateBps)
+	issued, err := q.IssueInvoice(ctx, postgres.IssueInvoiceParams{
+		ID:                 invoice.ID,
+		Number:             pgtype.Text{String: invoiceNumber(series, year, n), Valid: true},
+		AmountExclVatCents: pgtype.Int4{Int32: exclVAT, Valid: true},
+		VatRateBps:         pgtype.Int4{Int32: rateBps, Valid: true},
+		VatCents:           pgtype.Int4{Int32: vat, Valid: true},
+		BuyerName:          pgtype.Text{String: buyerName(user), Valid: true},
+		BuyerEmail:         pgtype.Text{String: user.Email, Valid: true},
+	})
+	if err != nil {
+		return invoice, fmt.Errorf("failed to issue invoice %d: %w", invoice.ID, err)
+	}
+	logger.FromContext(ctx).Info("invoice issued", zap.Int32("invoice_id", issued.ID), zap.String("number", issued.Number.String))
+	return issued, nil
+}
+
+// creditInvoice issues a credit note for amountCents refunded on an invoice, at the VAT rate of the
+// invoice. Invoices never issued (paid before invoicing existed) get none.
+func creditInvoice(ctx context.Context, q postgres.Querier, invoiceID, amountCents int32) error {
+	invoice, err := q.GetInvoice(ctx, invoiceID)
+	if err != nil {
+		return err
+	}
+	if !invoice.Number.Valid {
+		return nil
+	}
+	note, err := q.CreateCreditNote(ctx, postgres.CreateCreditNoteParams{
+		UserID:            invoice.UserID,
+		CreditedInvoiceID: pgtype.Int4{Int32: invoice.ID, Valid: true},
+		Description:       fmt.Sprintf("Avoir sur la facture %s : %s", invoice.Number.String, invoice.Description),
+		AmountCents:       amountCents,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create credit note: %w", err)
+	}
+	_, err = issueInvoice(ctx, q, note, invoice.VatRateBps.Int32)
+	return err
+}
+
+// Seller identifies the company issuing the invoices, as printed on them.
+type Seller struct {
+	Name      string
+	Address   string
+	SIRET     string
+	VATNumber string
+}
+
+// InvoiceService lists the invoices and credit notes of the users and archives them as PDF documents.
+// Once archived, the PDF of an invoice is never rendered again.
+type InvoiceService struct {
+	txManager TxManager
+	storage   FileStorage
+	seller    Seller
+	// renderPDF converts the HTML invoice to PDF; replaced in tests
+	renderPDF func(html []byte) ([]byte, error)
+}
+
+func NewInvoiceService(txManager TxManager, storage FileStorage, l *zap.Logger) *InvoiceService {
+	return &InvoiceService{
+		txManager: txManager,
+		storage:   storage,
+		seller: Seller{
+			Name:      viper.GetString("INVOICE_SELLER_NAME"),
+			Address:   viper.GetString("INVOICE_SELLER_ADDRESS"),
+			SIRET:     viper.GetString("INVOICE_SELLER_SIRET"),
+			VATNumber: viper.GetString("INVOICE_SELLER_VAT_NUMBER"),
+		},
+		renderPDF: htmlToPDF,
+	}
+}
+
+type InvoiceDTO struct {
+	ID                 int32  `json:"id"`
+	Number             string `json:"number"`
+	Kind               string `json:"kind"`
+	Description        string `json:"description"`
+	IssuedAt           string `json:"issued_at"`
+	PeriodStart        string `json:"period_start,omitempty"`
+	PeriodEnd          string `json:"period_end,omitempty"`
+	AmountExclVatCents int32  `json:"amount_excl_vat_cents"`
+	VatRateBps         int32  `json:"vat_rate_bps"`
+	VatCents           int32  `json:"vat_cents"`
+	AmountCents        int32  `json:"amount_cents"`
+	CreditedInvoiceID  *int32 `json:"credited_invoice_id,omitempty"`
+	// DocumentID is the archived PDF, once rendered
+	DocumentID *int32 `json:"document_id,omitempty"`
+}
+
+func toInvoiceDTO(i postgres.Invoice) InvoiceDTO {
+	dto := InvoiceDTO{
+		ID:                 i.ID,
+		Number:             i.Number.String,
+		Kind:               i.Kind,
+		Description:        i.Description,
+		IssuedAt:           i.IssuedAt.Time.Format(time.RFC3339),
+		AmountExclVatCents: i.AmountExclVatCents.Int32,
+		VatRateBps:         i.VatRateBps.Int32,
+		VatCents:           i.VatCents.Int32,
+		AmountCents:        i.AmountCents,
+	}
+	if i.PeriodStart.Valid {
+		dto.PeriodStart = i.PeriodStart.Time.Format("2006-01-02")
+	}
+	if i.PeriodEnd.Valid {
+		dto.PeriodEnd = i.PeriodEnd.Time.Format("2006-01-02")
+	}
+	if i.CreditedInvoiceID.Valid {
+		dto.CreditedInvoiceID = &i.CreditedInvoiceID.Int32
+	}
+	if i.DocumentID.Valid {
+		dto.DocumentID = &i.DocumentID.Int32
+	}
+	return dto
+}
+
+// ListInvoices returns the invoices and credit notes issued to the user, latest first.
+func (s *InvoiceService) ListInvoices(ctx context.Context, userID int32) ([]InvoiceDTO, error) {
+	var invoices []postgres.Invoice
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		var err error
+		invoices, err = q.ListIssuedInvoicesByUser(ctx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	dtos := make([]InvoiceDTO, 0, len(invoices))
+	for _, i := range invoices {
+		dtos = append(dtos, toInvoiceDTO(i))
+	}
+	return dtos, nil
+}
+
+// InvoiceData fills the invoice template.
+type InvoiceData struct {
+	Titre          string
+	Numero         string
+	DateEmission   string
+	VendeurNom     string
+	VendeurAdresse string
+	VendeurSiret   string
+	VendeurTVA     string
+	AcheteurNom    string
+	AcheteurEmail  string
+	Designation    string
+	Periode        string
+	MontantHT      string
+	TauxTVA        string
+	MontantTVA     string
+	MontantTTC     string
+	// FactureOrigine is the number of the invoice cancelled by a credit note
+	FactureOrigine string
+	DatePaiement   string
+}
+
+func formatCents(cents int32) string {
+	return fmt.Sprintf("%.2f", float64(cents)/100)
+}
+
+func buildInvoiceData(invoice postgres.Invoice, credited *postgres.Invoice, seller Seller) InvoiceData {
+	data := InvoiceData{
+		Titre:          "FACTURE",
+		Numero:         invoice.Number.String,
+		DateEmission:   invoice.IssuedAt.Time.Format("02/01/2006"),
+		VendeurNom:     cell(seller.Name),
+		VendeurAdresse: cell(seller.Address),
+		VendeurSiret:   cell(seller.SIRET),
+		VendeurTVA:     cell(seller.VATNumber),
+		AcheteurNom:    cell(invoice.BuyerName.String),
+		AcheteurEmail:  cell(invoice.BuyerEmail.String),
+		Designation:    cell(invoice.Description),
+		MontantHT:      formatCents(invoice.AmountExclVatCents.Int32),
+		TauxTVA:        fmt.Sprintf("%.2f %%", float64(invoice.VatRateBps.Int32)/100),
+		MontantTVA:     formatCents(invoice.VatCents.Int32),
+		MontantTTC:     formatCents(invoice.AmountCents),
+	}
+	if invoice.PeriodStart.Valid && invoice.PeriodEnd.Valid {
+		data.Periode = fmt.Sprintf("du %s au %s", invoice.PeriodStart.Time.Format("02/01/2006"), invoice.PeriodEnd.Time.Format("02/01/2006"))
+	}
+	if invoice.PaidAt.Valid {
+		data.DatePaiement = invoice.PaidAt.Time.Format("02/01/2006")
+	}
+	if invoice.Kind == InvoiceKindCreditNote {
+		data.Titre = "AVOIR"
+		if credited != nil {
+			data.FactureOrigine = credited.Number.String
+		}
+	}
+	return data
+}
+
+// renderInvoiceHTML renders the invoice template as a standalone HTML page.
+func renderInvoiceHTML(data InvoiceData) ([]byte, error) {
+	content, err := readDocumentTemplate("invoices", invoiceTemplate)
+	if err != nil {
+		return nil, err
+	}
+	body, err := renderMarkdownTemplate("invoice", content, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(fmt.Sprintf(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="UTF-8">
+<title>%s %s</title>
+<style>
+body { font-family: 'Helvetica', 'Arial', sans-serif; max-width: 800px; margin: 40px auto; padding: 20px; line-height: 1.5; color: #333; font-size: 13px; }
+h1 { font-size: 22px; text-align: center; text-transform: uppercase; letter-spacing: 2px; color: #000; }
+h3 { color: #000; border-bottom: 2px solid #333; padding-bottom: 6px; margin-top: 24px; }
+table { width: 100%%; border-collapse: collapse; margin: 10px 0 16px; page-break-inside: avoid; }
+th, td { border: 1px solid #ccc; padding: 6px 8px; text-align: left; vertical-align: top; }
+th { background: #f2f2f2; }
+</style>
+</head>
+<body>
+%s
+</body>
+</html>`, data.Titre, data.Numero, body)), nil
+}
+
+// archiveInvoice renders the PDF of an issued invoice and stores it as its document, unless it was
+// archived in the meantime. The archived document is final: when the PDF cannot be rendered, nothing is
+// stored and the error is returned, so that the invoice is archived by a later run.
+func (s *InvoiceService) archiveInvoice(ctx context.Context, invoice postgres.Invoice) (postgres.Document, error) {
+	var credited *postgres.Invoice
+	if invoice.CreditedInvoiceID.Valid {
+		err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+			i, err := q.GetInvoice(ctx, invoice.CreditedInvoiceID.Int32)
+			credited = &i
+			return err
+		})
+		if err != nil {
+			return postgres.Document{}, err
+		}
+	}
+
+	html, err := renderInvoiceHTML(buildInvoiceData(invoice, credited, s.seller))
+	if err != nil {
+		return postgres.Document{}, err
+	}
+	pdf, err := s.renderPDF(html)
+	if err != nil {
+		return postgres.Document{}, fmt.Errorf("failed to render invoice %d: %w", invoice.ID, err)
+	}
+
+	var doc postgres.Document
+	err = s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		locked, err := q.GetInvoiceForUpdate(ctx, invoice.ID)
+		if err != nil {
+			return err
+		}
+		if locked.DocumentID.Valid {
+			doc, err = q.GetDocument(ctx, locked.DocumentID.Int32)
+			return err
+		}
+		doc, err = storeDocumentVersion(ctx, q, s.storage, DocumentTypeInvoice, invoice.ID, ".pdf", "application/pdf", strings.ToLower(invoice.Number.String)+".pdf", pdf)
+		if err != nil {
+			return err
+		}
+		_, err = q.SetInvoiceDocument(ctx, postgres.SetInvoiceDocumentParams{
+			ID:         invoice.ID,
+			DocumentID: pgtype.Int4{Int32: doc.ID, Valid: true},
+		})
+		return err
+	})
+	if err != nil {
+		return postgres.Document{}, fmt.Errorf("failed to archive invoice %d: %w", invoice.ID, err)
+	}
+	return doc, nil
+}
+
+// ArchiveIssuedInvoices renders and stores the PDF of the invoices and credit notes issued since the
+// last run.
+func (s *InvoiceService) ArchiveIssuedInvoices(ctx context.Context) error {
+	var invoices []postgres.Invoice
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		var err error
+		invoices, err = q.ListInvoicesToArchive(ctx, invoiceArchiveBatch)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list invoices to archive: %w", err)
+	}
+
+	for _, invoice := range invoices {
+		if _, err := s.archiveInvoice(ctx, invoice); err != nil {
+			return err
+		}
+	}
+	if len(invoices) > 0 {
+		logger.FromContext(ctx).Info("invoices archived", zap.Int("count", len(invoices)))
+	}
+	return nil
+}
+
+// OpenInvoice streams the archived PDF of one of the user's invoices, archiving it first if the job
+// did not run yet. Each access is audited. The caller must close the reader.
+func (s *InvoiceService) OpenInvoice(ctx context.Context, userID, invoiceID int32, ipAddress, userAgent string) (io.ReadCloser, *DocumentDTO, error) {
+	var invoice postgres.Invoice
+	var doc postgres.Document
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		var err error
+		invoice, err = q.GetIssuedInvoice(ctx, invoiceID)
+		if err == pgx.ErrNoRows || (err == nil && invoice.UserID != userID) {
+			return ErrInvoiceNotFound
+		}
+		if err != nil || !invoice.DocumentID.Valid {
+			return err
+		}
+		doc, err = q.GetDocument(ctx, invoice.DocumentID.Int32)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !invoice.DocumentID.Valid {
+		doc, err = s.archiveInvoice(ctx, invoice)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	err = s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		return q.CreateDocumentAccessLog(ctx, postgres.CreateDocumentAccessLogParams{
+			DocumentID: doc.ID,
+			UserID:     userID,
+			IpAddress:  pgtype.Text{String: ipAddress, Valid: ipAddress != ""},
+			UserAgent:  pgtype.Text{String: userAgent, Valid: userAgent != ""},
+		})
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reader, err := s.storage.Open(doc.StorageKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open document: %w", err)
+	}
+	dto := toDocumentDTO(doc)
+	return reader, &dto, nil
+}
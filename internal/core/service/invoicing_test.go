@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+)
+
+// expectInvoiceIssued expects invoice invoiceID to get number n of series this year.
+func expectInvoiceIssued(q *MockQuerier, series string, invoiceID, n int32) {
+	year := time.Now().Year()
+	q.On("NextInvoiceNumber", mock.Anything, postgres.NextInvoiceNumberParams{Series: series, Year: int32(year)}).Return(n, nil).Once()
+	q.On("IssueInvoice", mock.Anything, mock.MatchedBy(func(p postgres.IssueInvoiceParams) bool {
+		return p.ID == invoiceID && p.Number.String == invoiceNumber(series, year, n)
+	})).Return(postgres.Invoice{ID: invoiceID, Number: pgtype.Text{String: invoiceNumber(series, year, n), Valid: true}}, nil).Once()
+}
+
+func TestSplitVAT(t *testing.T) {
+	for _, c := range []struct{ ttc, ht, vat int32 }{
+		{990, 825, 165},
+		{2990, 2492, 498},
+		{1990, 1658, 332},
+		{0, 0, 0},
+	} {
+		ht, vat := splitVAT(c.ttc, vatRateBps)
+		assert.Equal(t, c.ht, ht, "excluding VAT of %d", c.ttc)
+		assert.Equal(t, c.vat, vat, "VAT of %d", c.ttc)
+	}
+	assert.Equal(t, "F-2026-000042", invoiceNumber(invoiceSeries, 2026, 42))
+}
+
+func TestIssueInvoice_FreezesLegalMentions(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	year := time.Now().Year()
+	mockQuerier.On("GetUserById", mock.Anything, int32(1)).Return(postgres.User{
+		ID: 1, Email: "owner@test.com", FirstName: pgtype.Text{String: "Jeanne", Valid: true}, LastName: pgtype.Text{String: "Martin", Valid: true},
+	}, nil)
+	mockQuerier.On("NextInvoiceNumber", mock.Anything, postgres.NextInvoiceNumberParams{Series: "F", Year: int32(year)}).Return(int32(7), nil)
+	mockQuerier.On("IssueInvoice", mock.Anything, postgres.IssueInvoiceParams{
+		ID:                 11,
+		Number:             pgtype.Text{String: invoiceNumber("F", year, 7), Valid: true},
+		AmountExclVatCents: pgtype.Int4{Int32: 2492, Valid: true},
+		VatRateBps:         pgtype.Int4{Int32: 2000, Valid: true},
+		VatCents:           pgtype.Int4{Int32: 498, Valid: true},
+		BuyerName:          pgtype.Text{String: "Jeanne Martin", Valid: true},
+		BuyerEmail:         pgtype.Text{String: "owner@test.com", Valid: true},
+	}).Return(postgres.Invoice{ID: 11, Number: pgtype.Text{String: "F-1", Valid: true}}, nil)
+
+	issued, err := issueInvoice(context.Background(), mockQuerier, postgres.Invoice{ID: 11, UserID: 1, Kind: InvoiceKindInvoice, AmountCents: 2990}, vatRateBps)
+	require.NoError(t, err)
+	assert.True(t, issued.Number.Valid)
+
+	// Already issued: the number is never taken twice
+	_, err = issueInvoice(context.Background(), mockQuerier, issued, vatRateBps)
+	require.NoError(t, err)
+	mockQuerier.AssertNumberOfCalls(t, "NextInvoiceNumber", 1)
+}
+
+func TestRefund_IssuesCreditNote(t *testing.T) {
+	svc, mockQuerier, provider := setupPayments()
+	payment := postgres.Transaction{
+		ID: 9, UserID: pgtype.Int4{Int32: 1, Valid: true}, Amount: centsToNumeric(2990),
+		RelatedEntityType: pgtype.Text{String: paymentForInvoice, Valid: true}, RelatedEntityID: pgtype.Int4{Int32: 11, Valid: true},
+		Direction: pgtype.Text{String: "inbound", Valid: true}, Status: pgtype.Text{String: "success", Valid: true},
+		StripePaymentIntentID: pgtype.Text{String: "pi_1", Valid: true},
+	}
+	mockQuerier.On("GetPaymentTransactionForUpdate", mock.Anything, int32(9)).Return(payment, nil)
+	mockQuerier.On("GetPendingRefund", mock.Anything, mock.Anything).Return(postgres.Transaction{}, pgx.ErrNoRows)
+	mockQuerier.On("GetRefundedCents", mock.Anything, mock.Anything).Return(int32(0), nil)
+	expectRefundRecorded(mockQuerier, provider, 1000)
+	mockQuerier.On("GetInvoice", mock.Anything, int32(11)).Return(postgres.Invoice{
+		ID: 11, UserID: 1, Description: "Premium (monthly)", AmountCents: 2990,
+		Number: pgtype.Text{String: "F-2026-000001", Valid: true}, VatRateBps: pgtype.Int4{Int32: 550, Valid: true},
+	}, nil)
+	mockQuerier.On("CreateCreditNote", mock.Anything, postgres.CreateCreditNoteParams{
+		UserID: 1, CreditedInvoiceID: pgtype.Int4{Int32: 11, Valid: true},
+		Description: "Avoir sur la facture F-2026-000001 : Premium (monthly)", AmountCents: 1000,
+	}).Return(postgres.Invoice{ID: 12, UserID: 1, Kind: InvoiceKindCreditNote, AmountCents: 1000}, nil)
+	mockQuerier.On("GetUserById", mock.Anything, int32(1)).Return(payingUser, nil)
+	expectInvoiceIssued(mockQuerier, creditNoteSeries, 12, 1)
+
+	_, err := svc.Refund(context.Background(), 9, 1000)
+
+	require.NoError(t, err)
+	mockQuerier.AssertExpectations(t)
+	// The credit note keeps the VAT rate of the invoice it cancels
+	mockQuerier.AssertCalled(t, "IssueInvoice", mock.Anything, mock.MatchedBy(func(p postgres.IssueInvoiceParams) bool {
+		return p.VatRateBps.Int32 == 550
+	}))
+}
+
+func setupInvoices() (*InvoiceService, *MockQuerier, *MockFileStorage) {
+	mockQuerier := new(MockQuerier)
+	mockFileStore := new(MockFileStorage)
+	svc := NewInvoiceService(passthroughTxManager{q: mockQuerier}, mockFileStore, zap.NewNop())
+	svc.seller = Seller{Name: "Seculoc SAS", Address: "10 rue de Rivoli, 75001 Paris", SIRET: "123 456 789 00012", VATNumber: "FR12123456789"}
+	return svc, mockQuerier, mockFileStore
+}
+
+var issuedInvoice = postgres.Invoice{
+	ID: 11, UserID: 1, Kind: InvoiceKindInvoice, Description: "Premium (monthly)", AmountCents: 2990, Status: "paid",
+	PeriodStart: pgDate(day("2026-05-03")), PeriodEnd: pgDate(day("2026-06-03")),
+	Number: pgtype.Text{String: "F-2026-000042", Valid: true}, IssuedAt: pgtype.Timestamp{Time: day("2026-05-03"), Valid: true},
+	PaidAt:             pgtype.Timestamp{Time: day("2026-05-03"), Valid: true},
+	AmountExclVatCents: pgtype.Int4{Int32: 2492, Valid: true}, VatRateBps: pgtype.Int4{Int32: 2000, Valid: true},
+	VatCents: pgtype.Int4{Int32: 498, Valid: true}, BuyerName: pgtype.Text{String: "Jeanne <i>Martin</i>", Valid: true},
+	BuyerEmail: pgtype.Text{String: "owner@test.com", Valid: true},
+}
+
+func TestArchiveIssuedInvoices_StoresPDFOnce(t *testing.T) {
+	viper.Set("ASSETS_DIR", "../../../assets")
+	defer viper.Set("ASSETS_DIR", "")
+
+	svc, mockQuerier, mockFileStore := setupInvoices()
+	var html string
+	svc.renderPDF = func(b []byte) ([]byte, error) {
+		html = string(b)
+		return []byte("%PDF-1.7"), nil
+	}
+	archived := issuedInvoice
+	archived.ID = 12
+	archived.DocumentID = pgtype.Int4{Int32: 8, Valid: true}
+
+	mockQuerier.On("ListInvoicesToArchive", mock.Anything, int32(invoiceArchiveBatch)).Return([]postgres.Invoice{issuedInvoice, archived}, nil)
+	mockQuerier.On("GetInvoiceForUpdate", mock.Anything, int32(11)).Return(issuedInvoice, nil)
+	// Archived by a download in the meantime: kept as it is
+	mockQuerier.On("GetInvoiceForUpdate", mock.Anything, int32(12)).Return(archived, nil)
+	mockQuerier.On("GetDocument", mock.Anything, int32(8)).Return(postgres.Document{ID: 8}, nil)
+	mockQuerier.On("GetNextDocumentVersion", mock.Anything, postgres.GetNextDocumentVersionParams{DocumentType: DocumentTypeInvoice, EntityID: 11}).Return(int32(1), nil)
+	mockFileStore.On("Save", "documents/invoice/11/v1.pdf", []byte("%PDF-1.7")).Return("documents/invoice/11/v1.pdf", nil)
+	mockQuerier.On("CreateDocument", mock.Anything, mock.MatchedBy(func(p postgres.CreateDocumentParams) bool {
+		return p.ContentType == "application/pdf" && p.Filename == "f-2026-000042.pdf"
+	})).Return(postgres.Document{ID: 5, Version: 1}, nil)
+	mockQuerier.On("SetInvoiceDocument", mock.Anything, postgres.SetInvoiceDocumentParams{
+		ID: 11, DocumentID: pgtype.Int4{Int32: 5, Valid: true},
+	}).Return(int64(1), nil)
+
+	require.NoError(t, svc.ArchiveIssuedInvoices(context.Background()))
+
+	mockQuerier.AssertExpectations(t)
+	mockFileStore.AssertNumberOfCalls(t, "Save", 1)
+	assert.Contains(t, html, "FACTURE N° F-2026-000042")
+	assert.Contains(t, html, "Seculoc SAS")
+	assert.Contains(t, html, "FR12123456789")
+	assert.Contains(t, html, "24.92 €")
+	assert.Contains(t, html, "4.98 €")
+	assert.Contains(t, html, "29.90 €")
+	assert.Contains(t, html, "20.00 %")
+	assert.Contains(t, html, "du 03/05/2026 au 03/06/2026")
+	assert.NotContains(t, html, "<i>Martin</i>", "buyer input must not inject markup")
+}
+
+func TestArchiveIssuedInvoices_RenderFailureStoresNothing(t *testing.T) {
+	viper.Set("ASSETS_DIR", "../../../assets")
+	defer viper.Set("ASSETS_DIR", "")
+
+	svc, mockQuerier, mockFileStore := setupInvoices()
+	svc.renderPDF = func([]byte) ([]byte, error) { return nil, errors.New("no headless browser") }
+	mockQuerier.On("ListInvoicesToArchive", mock.Anything, int32(invoiceArchiveBatch)).Return([]postgres.Invoice{issuedInvoice}, nil)
+
+	// The invoice stays without document and is archived by the next run
+	require.Error(t, svc.ArchiveIssuedInvoices(context.Background()))
+
+	mockQuerier.AssertNotCalled(t, "SetInvoiceDocument", mock.Anything, mock.Anything)
+	mockFileStore.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestBuildInvoiceData_CreditNote(t *testing.T) {
+	note := issuedInvoice
+	note.Kind = InvoiceKindCreditNote
+	note.Number = pgtype.Text{String: "AV-2026-000001", Valid: true}
+	note.PeriodStart, note.PeriodEnd = pgtype.Date{}, pgtype.Date{}
+
+	data := buildInvoiceData(note, &issuedInvoice, Seller{})
+
+	assert.Equal(t, "AVOIR", data.Titre)
+	assert.Equal(t, "F-2026-000042", data.FactureOrigine)
+	assert.Empty(t, data.Periode)
+}
+
+func TestOpenInvoice(t *testing.T) {
+	svc, mockQuerier, mockFileStore := setupInvoices()
+	archived := issuedInvoice
+	archived.DocumentID = pgtype.Int4{Int32: 5, Valid: true}
+	mockQuerier.On("GetIssuedInvoice", mock.Anything, int32(11)).Return(archived, nil)
+	mockQuerier.On("GetDocument", mock.Anything, int32(5)).Return(postgres.Document{
+		ID: 5, DocumentType: DocumentTypeInvoice, EntityID: 11, StorageKey: "documents/invoice/11/v1.pdf", ContentType: "application/pdf",
+	}, nil)
+	mockQuerier.On("CreateDocumentAccessLog", mock.Anything, mock.MatchedBy(func(p postgres.CreateDocumentAccessLogParams) bool {
+		return p.DocumentID == 5 && p.UserID == 1
+	})).Return(nil)
+	mockFileStore.On("Open", "documents/invoice/11/v1.pdf").Return(io.NopCloser(strings.NewReader("%PDF-1.7")), nil)
+
+	reader, doc, err := svc.OpenInvoice(context.Background(), 1, 11, "127.0.0.1", "test")
+	require.NoError(t, err)
+	defer reader.Close()
+	assert.Equal(t, "application/pdf", doc.ContentType)
+
+	// Someone else's invoice
+	_, _, err = svc.OpenInvoice(context.Background(), 2, 11, "", "")
+	assert.True(t, errors.Is(err, ErrInvoiceNotFound))
+}
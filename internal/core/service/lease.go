@@ -6,6 +6,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgtype"
@@ -18,6 +19,16 @@ type FileStorage interface {
 	Save(filename string, content []byte) (string, error)
 	Get(filename string) ([]byte, error)
 	Exists(filename string) bool
+	Delete(filename string) error
+	// List returns the stored names starting with prefix (e.g. "properties/12/").
+	List(prefix string) ([]string, error)
+	// Open streams a stored file. The caller must close the reader.
+	Open(filename string) (io.ReadCloser, error)
+}
+
+// URLPresigner is implemented by storages able to serve files directly (e.g. S3 presigned URLs).
+type URLPresigner interface {
+	PresignGet(filename string, ttl time.Duration) (string, error)
 }
 
 type LeaseService struct {
@@ -98,10 +109,11 @@ type DraftLeaseRequest struct {
 }
 
 type TenantDraft struct {
-	FirstName string `json:"first_name" binding:"required"`
-	LastName  string `json:"last_name" binding:"required"`
-	Email     string `json:"email" binding:"required,email"`
-	Phone     string `json:"phone"`
+	FirstName string  `json:"first_name" binding:"required"`
+	LastName  string  `json:"last_name" binding:"required"`
+	Email     string  `json:"email" binding:"required,email"`
+	Phone     string  `json:"phone"`
+	RentShare float64 `json:"rent_share" binding:"gte=0"` // Co-tenancy: monthly share, rent and charges included
 }
 
 type LeaseTerms struct {
@@ -111,6 +123,9 @@ type LeaseTerms struct {
 	ChargesAmount float64 `json:"charges_amount"`
 	DepositAmount float64 `json:"deposit_amount" binding:"required"`
 	PaymentDay    int     `json:"payment_day" binding:"required,min=1,max=31"`
+	// Co-tenancy: joint liability clause (default true) and one payment per co-tenant
+	JointLiability       *bool `json:"joint_liability"`
+	IndividualRentShares bool  `json:"individual_rent_shares"`
 }
 
 func (s *LeaseService) CreateDraft(ctx context.Context, req DraftLeaseRequest, ownerID int32) (int32, string, error) {
@@ -147,21 +162,41 @@ func (s *LeaseService) CreateDraft(ctx context.Context, req DraftLeaseRequest, o
 		}
 
 		// 4. Create Draft Lease
+		jointLiability := req.Terms.JointLiability == nil || *req.Terms.JointLiability
 		lease, err := q.CreateDraftLease(ctx, postgres.CreateDraftLeaseParams{
-			PropertyID:     pgtype.Int4{Int32: req.PropertyID, Valid: true},
-			StartDate:      pgtype.Date{Time: start, Valid: true},
-			EndDate:        pgtype.Date{Time: end, Valid: !end.IsZero()},
-			RentAmount:     numeric(req.Terms.RentAmount),
-			ChargesAmount:  numeric(req.Terms.ChargesAmount),
-			DepositAmount:  numeric(req.Terms.DepositAmount),
-			PaymentDay:     pgtype.Int4{Int32: int32(req.Terms.PaymentDay), Valid: true},
-			SpecialClauses: clausesJSON,
+			PropertyID:           pgtype.Int4{Int32: req.PropertyID, Valid: true},
+			StartDate:            pgtype.Date{Time: start, Valid: true},
+			EndDate:              pgtype.Date{Time: end, Valid: !end.IsZero()},
+			RentAmount:           numeric(req.Terms.RentAmount),
+			ChargesAmount:        numeric(req.Terms.ChargesAmount),
+			DepositAmount:        numeric(req.Terms.DepositAmount),
+			PaymentDay:           pgtype.Int4{Int32: int32(req.Terms.PaymentDay), Valid: true},
+			SpecialClauses:       clausesJSON,
+			JointLiability:       pgtype.Bool{Bool: jointLiability, Valid: true},
+			IndividualRentShares: pgtype.Bool{Bool: req.Terms.IndividualRentShares, Valid: true},
 		})
 		if err != nil {
 			return fmt.Errorf("failed to create draft lease: %w", err)
 		}
 		leaseID = lease.ID
 
+		// The first tenant is a lease party like the co-tenants invited later
+		share := pgtype.Numeric{}
+		if req.TenantInfo.RentShare > 0 {
+			share = numeric(req.TenantInfo.RentShare)
+		}
+		party, err := q.CreateLeaseParty(ctx, postgres.CreateLeasePartyParams{
+			LeaseID:   leaseID,
+			Email:     req.TenantInfo.Email,
+			FirstName: pgtype.Text{String: req.TenantInfo.FirstName, Valid: true},
+			LastName:  pgtype.Text{String: req.TenantInfo.LastName, Valid: true},
+			RentShare: share,
+			Status:    LeasePartyStatusInvited,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create lease party: %w", err)
+		}
+
 		// 5. Create Invitation
 		token = generateToken()
 		expiresAt := time.Now().Add(7 * 24 * time.Hour)
@@ -173,6 +208,7 @@ func (s *LeaseService) CreateDraft(ctx context.Context, req DraftLeaseRequest, o
 			TenantEmail: req.TenantInfo.Email,
 			Token:       token,
 			ExpiresAt:   pgtype.Timestamp{Time: expiresAt, Valid: true},
+			PartyID:     pgtype.Int4{Int32: party.ID, Valid: true},
 		})
 		if err != nil {
 			return fmt.Errorf("failed to create invitation: %w", err)
@@ -1,22 +1,14 @@
 package service
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"os"
-	"path/filepath"
-	"text/template"
+	"strings"
 	"time"
 
-	"github.com/go-rod/rod"
-	"github.com/go-rod/rod/lib/launcher"
-	"github.com/go-rod/rod/lib/proto"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
-	"github.com/spf13/viper"
-	"github.com/yuin/goldmark"
 	"go.uber.org/zap"
 
 	"seculoc-back/internal/adapter/storage/postgres"
@@ -31,6 +23,11 @@ type LeaseTemplateData struct {
 
 	LocataireNom   string
 	LocataireEmail string
+	// Co-tenancy: every tenant signs the lease
+	Locataires         []LeaseTenantLine
+	Colocation         bool
+	Solidarite         bool
+	PartsIndividuelles bool
 
 	AdresseLogement string
 	Surface         string
@@ -53,40 +50,80 @@ type LeaseTemplateData struct {
 	DateSignature  string
 }
 
-// GenerateAndSave generates the lease document and persists it to storage.
+type LeaseTenantLine struct {
+	Nom   string
+	Email string
+	Part  string // Monthly share when each co-tenant pays their own
+}
+
+// GenerateAndSave generates the lease document and stores it as a new immutable version.
 func (s *LeaseService) GenerateAndSave(ctx context.Context, leaseID int32, userID int32) error {
+	_, err := s.generateAndStore(ctx, leaseID, userID)
+	return err
+}
+
+func (s *LeaseService) generateAndStore(ctx context.Context, leaseID int32, userID int32) (postgres.Document, error) {
 	content, _, err := s.GenerateLeaseDocument(ctx, leaseID, userID)
 	if err != nil {
-		return err
-	}
-
-	storageName := fmt.Sprintf("lease_%d.html", leaseID)
-	if _, err := s.storage.Save(storageName, content); err != nil {
-		return fmt.Errorf("failed to save lease document: %w", err)
+		return postgres.Document{}, err
 	}
 
 	// Update DB with the download URL (which serves the stored file)
 	downloadURL := fmt.Sprintf("/api/v1/leases/%d/download", leaseID)
 
+	var doc postgres.Document
 	err = s.txManager.WithTx(ctx, func(q postgres.Querier) error {
-		return q.UpdateLeaseContractURL(ctx, postgres.UpdateLeaseContractURLParams{
+		var err error
+		doc, err = storeDocumentVersion(ctx, q, s.storage, DocumentTypeLease, leaseID, ".html", "text/html; charset=utf-8", "contract.html", content)
+		if err != nil {
+			return fmt.Errorf("failed to save lease document: %w", err)
+		}
+
+		if err := q.UpdateLeaseContractURL(ctx, postgres.UpdateLeaseContractURLParams{
 			ID:          leaseID,
 			ContractUrl: pgtype.Text{String: downloadURL, Valid: true},
-		})
+		}); err != nil {
+			return fmt.Errorf("failed to update lease contract url: %w", err)
+		}
+		return nil
 	})
 
 	if err != nil {
-		return fmt.Errorf("failed to update lease contract url: %w", err)
+		return postgres.Document{}, err
+	}
+
+	s.logger.Info("lease document generated and saved", zap.Int("lease_id", int(leaseID)), zap.Int32("version", doc.Version), zap.String("url", downloadURL))
+	return doc, nil
+}
+
+// EnsureLeaseDocument returns the latest stored version of the lease document,
+// generating the first one if the lease has none yet (e.g. a draft only previewed so far).
+func (s *LeaseService) EnsureLeaseDocument(ctx context.Context, leaseID int32, userID int32) (int32, error) {
+	var doc postgres.Document
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		var err error
+		doc, err = q.GetLatestDocument(ctx, postgres.GetLatestDocumentParams{DocumentType: DocumentTypeLease, EntityID: leaseID})
+		return err
+	})
+	if err == nil {
+		return doc.ID, nil
+	}
+	if err != pgx.ErrNoRows {
+		return 0, err
 	}
 
-	s.logger.Info("lease document generated and saved", zap.Int("lease_id", int(leaseID)), zap.String("url", downloadURL))
-	return nil
+	doc, err = s.generateAndStore(ctx, leaseID, userID)
+	if err != nil {
+		return 0, err
+	}
+	return doc.ID, nil
 }
 
-// GetLeaseDocumentContent retrieves the lease document from storage if available,
+// GetLeaseDocumentContent retrieves the latest stored lease document if available,
 // otherwise generates it on the fly (legacy/fallback).
 func (s *LeaseService) GetLeaseDocumentContent(ctx context.Context, leaseID int32, userID int32) ([]byte, string, error) {
-	storageName := fmt.Sprintf("lease_%d.html", leaseID)
+	// Files saved before documents were versioned
+	legacyName := fmt.Sprintf("lease_%d.html", leaseID)
 
 	// 1. Try Storage
 	// IMPORTANT: Even if we get it from storage, we MUST verify access.
@@ -94,6 +131,7 @@ func (s *LeaseService) GetLeaseDocumentContent(ctx context.Context, leaseID int3
 	// But if we return stored content, we skip that check.
 	// We must perform a lightweight access check first if we want to serve stored content.
 
+	storageName := ""
 	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
 		l, err := q.GetLease(ctx, leaseID)
 		if err != nil {
@@ -104,8 +142,21 @@ func (s *LeaseService) GetLeaseDocumentContent(ctx context.Context, leaseID int3
 			return fmt.Errorf("property not found: %w", err)
 		}
 
-		if l.TenantID.Int32 != userID && prop.OwnerID.Int32 != userID {
-			return fmt.Errorf("access denied: user %d is not a party to this lease", userID)
+		if prop.OwnerID.Int32 != userID {
+			tenant, err := isLeaseTenant(ctx, q, l, userID)
+			if err != nil {
+				return err
+			}
+			if !tenant {
+				return fmt.Errorf("access denied: user %d is not a party to this lease", userID)
+			}
+		}
+
+		doc, err := q.GetLatestDocument(ctx, postgres.GetLatestDocumentParams{DocumentType: DocumentTypeLease, EntityID: leaseID})
+		if err == nil {
+			storageName = doc.StorageKey
+		} else if err != pgx.ErrNoRows {
+			return err
 		}
 		return nil
 	})
@@ -113,7 +164,10 @@ func (s *LeaseService) GetLeaseDocumentContent(ctx context.Context, leaseID int3
 		return nil, "", err
 	}
 
-	if s.storage.Exists(storageName) {
+	if storageName == "" && s.storage.Exists(legacyName) {
+		storageName = legacyName
+	}
+	if storageName != "" {
 		content, err := s.storage.Get(storageName)
 		if err == nil {
 			return content, "contract.html", nil
@@ -132,6 +186,7 @@ func (s *LeaseService) GenerateLeaseDocument(ctx context.Context, leaseID int32,
 	var lease postgres.Lease
 	var prop postgres.Property
 	var tenant, owner postgres.User
+	var parties []postgres.LeaseParty
 
 	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
 		// Get Lease
@@ -150,8 +205,14 @@ func (s *LeaseService) GenerateLeaseDocument(ctx context.Context, leaseID int32,
 		// Authorization Check
 		// If tenant is nil, only owner can view. If tenant is set, check match.
 		if l.TenantID.Valid {
-			if l.TenantID.Int32 != userID && prop.OwnerID.Int32 != userID {
-				return fmt.Errorf("access denied: user %d is not a party to this lease", userID)
+			if prop.OwnerID.Int32 != userID {
+				tenant, err := isLeaseTenant(ctx, q, l, userID)
+				if err != nil {
+					return err
+				}
+				if !tenant {
+					return fmt.Errorf("access denied: user %d is not a party to this lease", userID)
+				}
 			}
 		} else {
 			// Draft mode: Only owner can view (for now), or invited user if we had a way to verify token context
@@ -198,6 +259,12 @@ func (s *LeaseService) GenerateLeaseDocument(ctx context.Context, leaseID int32,
 			return fmt.Errorf("owner not found: %w", err)
 		}
 
+		// Get Co-tenants (invited ones are named in the draft too)
+		parties, err = q.ListLeaseParties(ctx, leaseID)
+		if err != nil {
+			return fmt.Errorf("failed to list lease parties: %w", err)
+		}
+
 		return nil
 	})
 
@@ -218,14 +285,9 @@ func (s *LeaseService) GenerateLeaseDocument(ctx context.Context, leaseID int32,
 		}
 	}
 
-	assetsDir := viper.GetString("ASSETS_DIR")
-	if assetsDir == "" {
-		assetsDir = "assets"
-	}
-	templatePath := filepath.Join(assetsDir, "templates", "leases", templateName)
-	content, err := os.ReadFile(templatePath)
+	content, err := readDocumentTemplate("leases", templateName)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to read template %s: %w", templateName, err)
+		return nil, "", err
 	}
 
 	// 3. Prepare Data
@@ -291,21 +353,12 @@ func (s *LeaseService) GenerateLeaseDocument(ctx context.Context, leaseID int32,
 		DateSignature:  time.Now().Format("02/01/2006"),
 	}
 
-	// 4. Execute Template (Fill variables)
-	tmpl, err := template.New("lease").Parse(string(content))
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to parse template: %w", err)
-	}
+	applyLeaseParties(&data, lease, currentLeaseParties(parties), total)
 
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, data); err != nil {
-		return nil, "", fmt.Errorf("failed to execute template: %w", err)
-	}
-
-	// 5. Convert Markdown to HTML (Goldmark)
-	var htmlBuf bytes.Buffer
-	if err := goldmark.Convert(buf.Bytes(), &htmlBuf); err != nil {
-		return nil, "", fmt.Errorf("failed to convert markdown to html: %w", err)
+	// 4. Execute Template and convert Markdown to HTML
+	body, err := renderMarkdownTemplate("lease", content, data)
+	if err != nil {
+		return nil, "", err
 	}
 
 	// 6. Wrap in Styled HTML Container
@@ -340,7 +393,7 @@ p { margin-bottom: 0.8em; text-align: justify; }
 	</div>
 </div>
 </body>
-</html>`, htmlBuf.String(), data.BailleurNom, data.LocataireNom)
+</html>`, body, data.BailleurNom, data.LocataireNom)
 
 	return []byte(finalHTML), "contract.html", nil
 }
@@ -353,48 +406,39 @@ func (s *LeaseService) GenerateLeasePDF(ctx context.Context, leaseID int32, user
 		return nil, "", err
 	}
 
-	// 2. Setup Rod (Headless Browser)
-	// We use a custom launcher to ensure it works in Docker/Dev envs
-	l := launcher.New()
-	u := l.MustLaunch()
-	browser := rod.New().ControlURL(u).MustConnect()
-	defer browser.MustClose()
-
-	// 3. Create Page & Set Content
-	page := browser.MustPage()
-
-	// Set content safely
-	if err := page.SetDocumentContent(string(htmlBytes)); err != nil {
-		return nil, "", fmt.Errorf("failed to set page content: %w", err)
-	}
-
-	// Wait for network idle to ensure fonts/images loaded
-	page.MustWaitLoad()
-
-	// 4. Print to PDF
-	// page.PDF() returns a StreamReader in recent versions, or []byte in older ones.
-	// Based on the error "cannot use *rod.StreamReader as []byte", it returns a stream.
-	pdfStream, err := page.PDF(&proto.PagePrintToPDF{
-		PaperWidth:      floatPtr(8.27),
-		PaperHeight:     floatPtr(11.69),
-		MarginTop:       floatPtr(0.5),
-		MarginBottom:    floatPtr(0.5),
-		MarginLeft:      floatPtr(0.5),
-		MarginRight:     floatPtr(0.5),
-		PrintBackground: true,
-	})
+	// 2. Print to PDF (Headless Browser)
+	pdfBytes, err := htmlToPDF(htmlBytes)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to generate PDF stream: %w", err)
-	}
-
-	// Read the stream to []byte
-	pdfBytes, err := io.ReadAll(pdfStream)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to read PDF stream: %w", err)
+		return nil, "", err
 	}
 
 	return pdfBytes, "contract.pdf", nil
 }
 
-// Helper to convert float pointer for Rod
-func floatPtr(v float64) *float64 { return &v }
+// applyLeaseParties lists every current tenant of the lease in the contract. Leases without parties
+// (created before co-tenancy) keep their single tenant.
+func applyLeaseParties(data *LeaseTemplateData, lease postgres.Lease, parties []postgres.LeaseParty, total float64) {
+	if len(parties) == 0 {
+		data.Locataires = []LeaseTenantLine{{Nom: data.LocataireNom, Email: data.LocataireEmail}}
+		return
+	}
+
+	shares := splitRent(total, parties)
+	names := make([]string, 0, len(parties))
+	emails := make([]string, 0, len(parties))
+	data.Locataires = make([]LeaseTenantLine, 0, len(parties))
+	for i, p := range parties {
+		names = append(names, partyName(p))
+		emails = append(emails, p.Email)
+		data.Locataires = append(data.Locataires, LeaseTenantLine{
+			Nom:   partyName(p),
+			Email: p.Email,
+			Part:  fmt.Sprintf("%.2f", shares[i].Amount),
+		})
+	}
+	data.LocataireNom = strings.Join(names, ", ")
+	data.LocataireEmail = strings.Join(emails, ", ")
+	data.Colocation = len(parties) > 1
+	data.Solidarite = data.Colocation && lease.JointLiability.Bool
+	data.PartsIndividuelles = data.Colocation && lease.IndividualRentShares.Bool
+}
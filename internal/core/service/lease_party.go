@@ -0,0 +1,537 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+)
+
+// Lease party statuses
+const (
+	LeasePartyStatusInvited = "invited"
+	LeasePartyStatusActive  = "active"
+	LeasePartyStatusLeft    = "left"
+)
+
+// solidarityMonths is how long a departing co-tenant remains jointly liable when nobody replaces them
+// (article 8-1 of the law of 6 July 1989).
+const solidarityMonths = 6
+
+var (
+	ErrLeaseNotFound          = errors.New("lease not found")
+	ErrLeaseAccessDenied      = errors.New("access denied to this lease")
+	ErrLeasePartyNotFound     = errors.New("lease party not found")
+	ErrLeasePartyNotActive    = errors.New("only an active co-tenant can give notice")
+	ErrCoTenantAlreadyInvited = errors.New("this email is already a party to the lease")
+	ErrInvalidReplacement     = errors.New("only a co-tenant who gave notice and is not replaced yet can be replaced")
+	ErrLeaseClosedToParties   = errors.New("co-tenants can only be added to a long-term lease that is not terminated")
+	ErrInvalidRentShares      = errors.New("rent shares must be positive and cover the monthly rent and charges")
+)
+
+// CoTenantParams identifies a co-tenant invited to join a lease.
+type CoTenantParams struct {
+	Email     string
+	FirstName string
+	LastName  string
+	RentShare float64
+	// ReplacesPartyID is the departing co-tenant this one takes over from (0 when none).
+	ReplacesPartyID int32
+}
+
+// PartyShare is the monthly amount (rent and charges) paid by one co-tenant.
+type PartyShare struct {
+	PartyID int32   `json:"party_id" binding:"required"`
+	Amount  float64 `json:"amount" binding:"gt=0"`
+}
+
+// RentSplit is how the rent of a co-tenancy is owed.
+type RentSplit struct {
+	JointLiability   bool
+	IndividualShares bool
+	// Shares may be empty: the rent is then split in equal parts.
+	Shares []PartyShare
+}
+
+type LeasePartyDTO struct {
+	ID               int32   `json:"id"`
+	Email            string  `json:"email"`
+	FirstName        string  `json:"first_name"`
+	LastName         string  `json:"last_name"`
+	Status           string  `json:"status"`
+	RentShare        float64 `json:"rent_share,omitempty"`
+	JoinedAt         string  `json:"joined_at,omitempty"`
+	NoticeDate       string  `json:"notice_date,omitempty"`
+	LeftAt           string  `json:"left_at,omitempty"`
+	SolidarityEndsAt string  `json:"solidarity_ends_at,omitempty"`
+	ReplacesPartyID  int32   `json:"replaces_party_id,omitempty"`
+}
+
+type LeasePartiesDTO struct {
+	LeaseID              int32           `json:"lease_id"`
+	JointLiability       bool            `json:"joint_liability"`
+	IndividualRentShares bool            `json:"individual_rent_shares"`
+	MonthlyTotal         float64         `json:"monthly_total"`
+	Parties              []LeasePartyDTO `json:"parties"`
+}
+
+// RentScheduleLine is what one payer owes at a due date. PartyID is 0 for a single payment by all co-tenants.
+type RentScheduleLine struct {
+	PartyID int32   `json:"party_id,omitempty"`
+	Tenant  string  `json:"tenant"`
+	Amount  float64 `json:"amount"`
+}
+
+type RentScheduleMonth struct {
+	DueDate string             `json:"due_date"`
+	Total   float64            `json:"total"`
+	Lines   []RentScheduleLine `json:"lines"`
+	// SolidaryParties left the flat but still answer for the whole rent (six-month rule).
+	SolidaryParties []string `json:"solidary_parties,omitempty"`
+}
+
+func toLeasePartyDTO(p postgres.LeaseParty) LeasePartyDTO {
+	share, _ := p.RentShare.Float64Value()
+	dto := LeasePartyDTO{
+		ID:              p.ID,
+		Email:           p.Email,
+		FirstName:       p.FirstName.String,
+		LastName:        p.LastName.String,
+		Status:          p.Status,
+		RentShare:       share.Float64,
+		ReplacesPartyID: p.ReplacesPartyID.Int32,
+	}
+	if p.JoinedAt.Valid {
+		dto.JoinedAt = p.JoinedAt.Time.Format(time.RFC3339)
+	}
+	if p.NoticeDate.Valid {
+		dto.NoticeDate = p.NoticeDate.Time.Format("2006-01-02")
+	}
+	if p.LeftAt.Valid {
+		dto.LeftAt = p.LeftAt.Time.Format("2006-01-02")
+	}
+	if p.SolidarityEndsAt.Valid {
+		dto.SolidarityEndsAt = p.SolidarityEndsAt.Time.Format("2006-01-02")
+	}
+	return dto
+}
+
+func partyName(p postgres.LeaseParty) string {
+	name := strings.TrimSpace(fmt.Sprintf("%s %s", p.LastName.String, p.FirstName.String))
+	if name == "" {
+		return p.Email
+	}
+	return name
+}
+
+func monthlyTotal(lease postgres.Lease) float64 {
+	rent, _ := lease.RentAmount.Float64Value()
+	charges, _ := lease.ChargesAmount.Float64Value()
+	return round2(rent.Float64 + charges.Float64)
+}
+
+// currentLeaseParties drops the co-tenants who left: the others sign the contract and share the rent.
+func currentLeaseParties(parties []postgres.LeaseParty) []postgres.LeaseParty {
+	current := []postgres.LeaseParty{}
+	for _, p := range parties {
+		if p.Status != LeasePartyStatusLeft {
+			current = append(current, p)
+		}
+	}
+	return current
+}
+
+// isLeaseTenant tells whether the user signed the lease, as its first tenant or as a co-tenant.
+func isLeaseTenant(ctx context.Context, q postgres.Querier, lease postgres.Lease, userID int32) (bool, error) {
+	if lease.TenantID.Valid && lease.TenantID.Int32 == userID {
+		return true, nil
+	}
+	_, err := q.GetLeasePartyByUser(ctx, postgres.GetLeasePartyByUserParams{
+		LeaseID: lease.ID,
+		UserID:  pgtype.Int4{Int32: userID, Valid: true},
+	})
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// leaseParticipant loads a lease the user owns or signed. isOwner tells which.
+func leaseParticipant(ctx context.Context, q postgres.Querier, leaseID, userID int32) (lease postgres.Lease, prop postgres.Property, isOwner bool, err error) {
+	lease, err = q.GetLease(ctx, leaseID)
+	if err == pgx.ErrNoRows {
+		return lease, prop, false, ErrLeaseNotFound
+	}
+	if err != nil {
+		return lease, prop, false, err
+	}
+	prop, err = q.GetProperty(ctx, lease.PropertyID.Int32)
+	if err != nil {
+		return lease, prop, false, fmt.Errorf("property not found: %w", err)
+	}
+	if prop.OwnerID.Int32 == userID {
+		return lease, prop, true, nil
+	}
+	tenant, err := isLeaseTenant(ctx, q, lease, userID)
+	if err != nil {
+		return lease, prop, false, err
+	}
+	if !tenant {
+		return lease, prop, false, ErrLeaseAccessDenied
+	}
+	return lease, prop, false, nil
+}
+
+// ownedLease loads a lease of one of the owner's properties.
+func ownedLease(ctx context.Context, q postgres.Querier, leaseID, ownerID int32) (postgres.Lease, postgres.Property, error) {
+	lease, prop, isOwner, err := leaseParticipant(ctx, q, leaseID, ownerID)
+	if err != nil {
+		return lease, prop, err
+	}
+	if !isOwner {
+		return lease, prop, ErrLeaseAccessDenied
+	}
+	return lease, prop, nil
+}
+
+func leasePartiesDTO(lease postgres.Lease, parties []postgres.LeaseParty) *LeasePartiesDTO {
+	dto := &LeasePartiesDTO{
+		LeaseID:              lease.ID,
+		JointLiability:       lease.JointLiability.Bool,
+		IndividualRentShares: lease.IndividualRentShares.Bool,
+		MonthlyTotal:         monthlyTotal(lease),
+		Parties:              make([]LeasePartyDTO, 0, len(parties)),
+	}
+	for _, p := range parties {
+		dto.Parties = append(dto.Parties, toLeasePartyDTO(p))
+	}
+	return dto
+}
+
+// ListParties returns the tenants of a lease, for its owner and its tenants.
+func (s *LeaseService) ListParties(ctx context.Context, userID, leaseID int32) (*LeasePartiesDTO, error) {
+	var dto *LeasePartiesDTO
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		lease, _, _, err := leaseParticipant(ctx, q, leaseID, userID)
+		if err != nil {
+			return err
+		}
+		parties, err := q.ListLeaseParties(ctx, leaseID)
+		if err != nil {
+			return err
+		}
+		dto = leasePartiesDTO(lease, parties)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dto, nil
+}
+
+// SetRentSplit sets the joint liability clause and how the rent is shared between the current co-tenants.
+// Individual shares must add up to the monthly rent and charges.
+func (s *LeaseService) SetRentSplit(ctx context.Context, ownerID, leaseID int32, split RentSplit) (*LeasePartiesDTO, error) {
+	var dto *LeasePartiesDTO
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		lease, _, err := ownedLease(ctx, q, leaseID, ownerID)
+		if err != nil {
+			return err
+		}
+		parties, err := q.ListLeaseParties(ctx, leaseID)
+		if err != nil {
+			return err
+		}
+		current := currentLeaseParties(parties)
+
+		shares := make(map[int32]float64, len(split.Shares))
+		sum := 0.0
+		for _, share := range split.Shares {
+			if share.Amount <= 0 {
+				return ErrInvalidRentShares
+			}
+			shares[share.PartyID] = share.Amount
+			sum += share.Amount
+		}
+		for id := range shares {
+			found := false
+			for _, p := range current {
+				found = found || p.ID == id
+			}
+			if !found {
+				return ErrLeasePartyNotFound
+			}
+		}
+		if len(shares) > 0 && (len(shares) != len(current) || math.Abs(sum-monthlyTotal(lease)) >= 0.01) {
+			return ErrInvalidRentShares
+		}
+
+		if err := q.UpdateLeaseRentSplit(ctx, postgres.UpdateLeaseRentSplitParams{
+			ID:                   leaseID,
+			JointLiability:       pgtype.Bool{Bool: split.JointLiability, Valid: true},
+			IndividualRentShares: pgtype.Bool{Bool: split.IndividualShares, Valid: true},
+		}); err != nil {
+			return err
+		}
+		// Without shares the rent is split in equal parts
+		for _, p := range current {
+			share := pgtype.Numeric{}
+			if amount, ok := shares[p.ID]; ok {
+				share = numeric(amount)
+			}
+			if err := q.UpdateLeasePartyShare(ctx, postgres.UpdateLeasePartyShareParams{ID: p.ID, LeaseID: leaseID, RentShare: share}); err != nil {
+				return err
+			}
+		}
+
+		lease.JointLiability = pgtype.Bool{Bool: split.JointLiability, Valid: true}
+		lease.IndividualRentShares = pgtype.Bool{Bool: split.IndividualShares, Valid: true}
+		parties, err = q.ListLeaseParties(ctx, leaseID)
+		if err != nil {
+			return err
+		}
+		dto = leasePartiesDTO(lease, parties)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dto, nil
+}
+
+// noticeMonths is the tenant's notice period: one month for furnished flats or when the law reduces it
+// (tight housing zone, job change, health...), three months otherwise.
+func noticeMonths(prop postgres.Property, reduced bool) int {
+	if reduced || prop.IsFurnished.Bool {
+		return 1
+	}
+	return 3
+}
+
+// RecordDeparture registers the notice given by a co-tenant, by the owner or by the co-tenant themselves.
+// Under a joint liability clause the co-tenant remains liable until replaced, and at most six months after
+// the notice takes effect.
+func (s *LeaseService) RecordDeparture(ctx context.Context, userID, leaseID, partyID int32, noticeDate time.Time, reducedNotice bool) (*LeasePartyDTO, error) {
+	var dto LeasePartyDTO
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		lease, prop, isOwner, err := leaseParticipant(ctx, q, leaseID, userID)
+		if err != nil {
+			return err
+		}
+		party, err := q.GetLeaseParty(ctx, postgres.GetLeasePartyParams{ID: partyID, LeaseID: leaseID})
+		if err == pgx.ErrNoRows {
+			return ErrLeasePartyNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if !isOwner && party.UserID.Int32 != userID {
+			return ErrLeaseAccessDenied
+		}
+		if party.Status != LeasePartyStatusActive {
+			return ErrLeasePartyNotActive
+		}
+
+		leftAt := noticeDate.AddDate(0, noticeMonths(prop, reducedNotice), 0)
+		solidarityEnd := leftAt
+		if lease.JointLiability.Bool {
+			solidarityEnd = leftAt.AddDate(0, solidarityMonths, 0)
+		}
+		if err := q.SetLeasePartyDeparture(ctx, postgres.SetLeasePartyDepartureParams{
+			ID:               party.ID,
+			NoticeDate:       pgtype.Date{Time: noticeDate, Valid: true},
+			LeftAt:           pgtype.Date{Time: leftAt, Valid: true},
+			SolidarityEndsAt: pgtype.Date{Time: solidarityEnd, Valid: true},
+		}); err != nil {
+			return err
+		}
+
+		party.Status = LeasePartyStatusLeft
+		party.NoticeDate = pgtype.Date{Time: noticeDate, Valid: true}
+		party.LeftAt = pgtype.Date{Time: leftAt, Valid: true}
+		party.SolidarityEndsAt = pgtype.Date{Time: solidarityEnd, Valid: true}
+		dto = toLeasePartyDTO(party)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("co-tenant departure recorded", zap.Int32("lease_id", leaseID), zap.Int32("party_id", partyID), zap.String("solidarity_ends_at", dto.SolidarityEndsAt))
+	return &dto, nil
+}
+
+// endSolidarityOnReplacement applies article 8-1: the departing co-tenant's solidarity ends once their
+// replacement is on the lease, but not before their notice takes effect.
+func endSolidarityOnReplacement(ctx context.Context, q postgres.Querier, leaseID, departingID int32, joined time.Time) error {
+	departing, err := q.GetLeaseParty(ctx, postgres.GetLeasePartyParams{ID: departingID, LeaseID: leaseID})
+	if err != nil {
+		return err
+	}
+	end := joined
+	if departing.LeftAt.Valid && departing.LeftAt.Time.After(end) {
+		end = departing.LeftAt.Time
+	}
+	if departing.SolidarityEndsAt.Valid && !end.Before(departing.SolidarityEndsAt.Time) {
+		return nil
+	}
+	return q.SetLeasePartySolidarityEnd(ctx, postgres.SetLeasePartySolidarityEndParams{
+		ID:               departing.ID,
+		SolidarityEndsAt: pgtype.Date{Time: end, Valid: true},
+	})
+}
+
+// joinLeaseParty records the accepting user as a tenant of the lease: the co-tenant slot their invitation
+// was issued for, or a new one for invitations sent before co-tenancy.
+func joinLeaseParty(ctx context.Context, q postgres.Querier, inv postgres.LeaseInvitation, leaseID, userID int32) error {
+	now := time.Now()
+	if !inv.PartyID.Valid {
+		user, err := q.GetUserById(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("user not found: %w", err)
+		}
+		_, err = q.CreateLeaseParty(ctx, postgres.CreateLeasePartyParams{
+			LeaseID:   leaseID,
+			UserID:    pgtype.Int4{Int32: userID, Valid: true},
+			Email:     user.Email,
+			FirstName: user.FirstName,
+			LastName:  user.LastName,
+			Status:    LeasePartyStatusActive,
+			JoinedAt:  pgtype.Timestamp{Time: now, Valid: true},
+		})
+		return err
+	}
+
+	party, err := q.GetLeaseParty(ctx, postgres.GetLeasePartyParams{ID: inv.PartyID.Int32, LeaseID: leaseID})
+	if err != nil {
+		return fmt.Errorf("lease party not found: %w", err)
+	}
+	if err := q.ActivateLeaseParty(ctx, postgres.ActivateLeasePartyParams{ID: party.ID, UserID: pgtype.Int4{Int32: userID, Valid: true}}); err != nil {
+		return err
+	}
+	if party.ReplacesPartyID.Valid {
+		return endSolidarityOnReplacement(ctx, q, leaseID, party.ReplacesPartyID.Int32, now)
+	}
+	return nil
+}
+
+// RentSchedule lists the next due dates of a lease from the given date. With individual shares each
+// co-tenant has their own line; otherwise the co-tenants owe a single payment.
+func (s *LeaseService) RentSchedule(ctx context.Context, userID, leaseID int32, from time.Time, months int) ([]RentScheduleMonth, error) {
+	var schedule []RentScheduleMonth
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		lease, _, _, err := leaseParticipant(ctx, q, leaseID, userID)
+		if err != nil {
+			return err
+		}
+		parties, err := q.ListLeaseParties(ctx, leaseID)
+		if err != nil {
+			return err
+		}
+		schedule = buildRentSchedule(lease, parties, from, months)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// dueDate is the payment day of a month, moved to the last day for short months.
+func dueDate(month time.Time, day int) time.Time {
+	first := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+func buildRentSchedule(lease postgres.Lease, parties []postgres.LeaseParty, from time.Time, months int) []RentScheduleMonth {
+	total := monthlyTotal(lease)
+	payDay := 5
+	if lease.PaymentDay.Valid && lease.PaymentDay.Int32 > 0 {
+		payDay = int(lease.PaymentDay.Int32)
+	}
+	start := lease.StartDate.Time
+	if from.After(start) {
+		start = from
+	}
+	start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	first := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	schedule := []RentScheduleMonth{}
+	for i := 0; len(schedule) < months && i <= months; i++ {
+		due := dueDate(first.AddDate(0, i, 0), payDay)
+		if due.Before(start) {
+			continue
+		}
+		if lease.EndDate.Valid && due.After(lease.EndDate.Time) {
+			break
+		}
+		schedule = append(schedule, scheduleMonth(lease, total, parties, due))
+	}
+	return schedule
+}
+
+func scheduleMonth(lease postgres.Lease, total float64, parties []postgres.LeaseParty, due time.Time) RentScheduleMonth {
+	month := RentScheduleMonth{DueDate: due.Format("2006-01-02"), Total: total}
+
+	present := []postgres.LeaseParty{}
+	for _, p := range parties {
+		switch {
+		case p.Status == LeasePartyStatusInvited:
+		case p.LeftAt.Valid && !due.Before(p.LeftAt.Time):
+			if lease.JointLiability.Bool && p.SolidarityEndsAt.Valid && due.Before(p.SolidarityEndsAt.Time) {
+				month.SolidaryParties = append(month.SolidaryParties, partyName(p))
+			}
+		default:
+			present = append(present, p)
+		}
+	}
+
+	if !lease.IndividualRentShares.Bool || len(present) < 2 {
+		names := make([]string, 0, len(present))
+		for _, p := range present {
+			names = append(names, partyName(p))
+		}
+		tenant := strings.Join(names, ", ")
+		if tenant == "" {
+			tenant = "Locataire"
+		}
+		month.Lines = []RentScheduleLine{{Tenant: tenant, Amount: total}}
+		return month
+	}
+	month.Lines = splitRent(total, present)
+	return month
+}
+
+// splitRent uses the co-tenants' shares when they cover the total (they stop doing so once someone
+// leaves), equal parts otherwise. The first co-tenant takes the rounding cents.
+func splitRent(total float64, parties []postgres.LeaseParty) []RentScheduleLine {
+	lines := make([]RentScheduleLine, 0, len(parties))
+	sum, complete := 0.0, true
+	for _, p := range parties {
+		share, _ := p.RentShare.Float64Value()
+		complete = complete && p.RentShare.Valid
+		sum += share.Float64
+		lines = append(lines, RentScheduleLine{PartyID: p.ID, Tenant: partyName(p), Amount: share.Float64})
+	}
+	if len(lines) == 0 || (complete && math.Abs(sum-total) < 0.01) {
+		return lines
+	}
+
+	part := math.Floor(total/float64(len(lines))*100) / 100
+	for i := range lines {
+		lines[i].Amount = part
+	}
+	lines[0].Amount = round2(total - part*float64(len(lines)-1))
+	return lines
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+)
+
+// coTenancy is lease 4 (800 € + 100 € on the 31st) shared by Alice and Bob.
+func coTenancy() (postgres.Lease, []postgres.LeaseParty) {
+	lease := postgres.Lease{
+		ID:                   4,
+		PropertyID:           pgtype.Int4{Int32: 10, Valid: true},
+		TenantID:             pgtype.Int4{Int32: 2, Valid: true},
+		StartDate:            pgtype.Date{Time: day("2024-01-01"), Valid: true},
+		RentAmount:           numeric(800),
+		ChargesAmount:        numeric(100),
+		PaymentDay:           pgtype.Int4{Int32: 31, Valid: true},
+		JointLiability:       pgtype.Bool{Bool: true, Valid: true},
+		IndividualRentShares: pgtype.Bool{Bool: true, Valid: true},
+	}
+	parties := []postgres.LeaseParty{
+		{ID: 1, LeaseID: 4, UserID: pgtype.Int4{Int32: 2, Valid: true}, Email: "alice@example.com", LastName: pgtype.Text{String: "Martin", Valid: true}, FirstName: pgtype.Text{String: "Alice", Valid: true}, Status: LeasePartyStatusActive, RentShare: numeric(500)},
+		{ID: 2, LeaseID: 4, UserID: pgtype.Int4{Int32: 3, Valid: true}, Email: "bob@example.com", LastName: pgtype.Text{String: "Durand", Valid: true}, FirstName: pgtype.Text{String: "Bob", Valid: true}, Status: LeasePartyStatusActive, RentShare: numeric(400)},
+	}
+	return lease, parties
+}
+
+func TestBuildRentSchedule_IndividualShares(t *testing.T) {
+	lease, parties := coTenancy()
+
+	schedule := buildRentSchedule(lease, parties, day("2024-01-15"), 3)
+
+	require.Len(t, schedule, 3)
+	assert.Equal(t, "2024-01-31", schedule[0].DueDate)
+	assert.Equal(t, "2024-02-29", schedule[1].DueDate, "short months are due on their last day")
+	assert.Equal(t, []RentScheduleLine{
+		{PartyID: 1, Tenant: "Martin Alice", Amount: 500},
+		{PartyID: 2, Tenant: "Durand Bob", Amount: 400},
+	}, schedule[0].Lines)
+
+	// A single payment when the co-tenants pay together
+	lease.IndividualRentShares = pgtype.Bool{Bool: false, Valid: true}
+	schedule = buildRentSchedule(lease, parties, day("2024-01-15"), 1)
+	assert.Equal(t, []RentScheduleLine{{Tenant: "Martin Alice, Durand Bob", Amount: 900}}, schedule[0].Lines)
+}
+
+func TestBuildRentSchedule_SixMonthSolidarity(t *testing.T) {
+	lease, parties := coTenancy()
+	parties = append(parties, postgres.LeaseParty{
+		ID: 3, LeaseID: 4, Email: "chloe@example.com", Status: LeasePartyStatusActive, UserID: pgtype.Int4{Int32: 5, Valid: true},
+	})
+	// Bob's notice took effect on 15 March: he stays liable until 15 September
+	parties[1].Status = LeasePartyStatusLeft
+	parties[1].LeftAt = pgtype.Date{Time: day("2024-03-15"), Valid: true}
+	parties[1].SolidarityEndsAt = pgtype.Date{Time: day("2024-09-15"), Valid: true}
+
+	schedule := buildRentSchedule(lease, parties, day("2024-02-01"), 8)
+
+	require.Len(t, schedule, 8)
+	// February: everybody pays; Chloé has no share yet, so the rent is split in equal parts
+	assert.Len(t, schedule[0].Lines, 3)
+	assert.Equal(t, 300.0, schedule[0].Lines[0].Amount)
+	assert.Empty(t, schedule[0].SolidaryParties)
+	// March to August: Bob no longer pays but answers for the rent
+	assert.Len(t, schedule[1].Lines, 2)
+	assert.Equal(t, 450.0, schedule[1].Lines[1].Amount)
+	assert.Equal(t, []string{"Durand Bob"}, schedule[1].SolidaryParties)
+	assert.Equal(t, []string{"Durand Bob"}, schedule[6].SolidaryParties, "2024-08-31")
+	// September 30: solidarity is over
+	assert.Empty(t, schedule[7].SolidaryParties)
+}
+
+func TestSplitRent_RoundingCents(t *testing.T) {
+	_, parties := coTenancy()
+	parties = append(parties, postgres.LeaseParty{ID: 3, Email: "chloe@example.com"})
+
+	lines := splitRent(1000, parties)
+
+	assert.Equal(t, 333.34, lines[0].Amount)
+	assert.Equal(t, 333.33, lines[1].Amount)
+	assert.Equal(t, 333.33, lines[2].Amount)
+}
+
+func TestRecordDeparture(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	svc := NewLeaseService(passthroughTxManager{q: mockQuerier}, zap.NewNop(), nil)
+	lease, parties := coTenancy()
+
+	mockQuerier.On("GetLease", mock.Anything, int32(4)).Return(lease, nil)
+	mockQuerier.On("GetProperty", mock.Anything, int32(10)).Return(postgres.Property{ID: 10, OwnerID: pgtype.Int4{Int32: 1, Valid: true}}, nil)
+	mockQuerier.On("GetLeasePartyByUser", mock.Anything, postgres.GetLeasePartyByUserParams{LeaseID: 4, UserID: pgtype.Int4{Int32: 3, Valid: true}}).Return(parties[1], nil)
+	mockQuerier.On("GetLeaseParty", mock.Anything, postgres.GetLeasePartyParams{ID: 1, LeaseID: 4}).Return(parties[0], nil)
+	mockQuerier.On("GetLeaseParty", mock.Anything, postgres.GetLeasePartyParams{ID: 2, LeaseID: 4}).Return(parties[1], nil)
+
+	// A co-tenant cannot give notice for another one
+	_, err := svc.RecordDeparture(context.Background(), 3, 4, 1, day("2024-03-01"), false)
+	assert.ErrorIs(t, err, ErrLeaseAccessDenied)
+
+	// Unfurnished flat: three months of notice, then six months of solidarity
+	mockQuerier.On("SetLeasePartyDeparture", mock.Anything, postgres.SetLeasePartyDepartureParams{
+		ID:               2,
+		NoticeDate:       pgtype.Date{Time: day("2024-03-01"), Valid: true},
+		LeftAt:           pgtype.Date{Time: day("2024-06-01"), Valid: true},
+		SolidarityEndsAt: pgtype.Date{Time: day("2024-12-01"), Valid: true},
+	}).Return(nil)
+
+	party, err := svc.RecordDeparture(context.Background(), 3, 4, 2, day("2024-03-01"), false)
+
+	require.NoError(t, err)
+	assert.Equal(t, LeasePartyStatusLeft, party.Status)
+	assert.Equal(t, "2024-12-01", party.SolidarityEndsAt)
+	mockQuerier.AssertExpectations(t)
+}
+
+func TestSetRentSplit_SharesMustCoverTotal(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	svc := NewLeaseService(passthroughTxManager{q: mockQuerier}, zap.NewNop(), nil)
+	lease, parties := coTenancy()
+
+	mockQuerier.On("GetLease", mock.Anything, int32(4)).Return(lease, nil)
+	mockQuerier.On("GetProperty", mock.Anything, int32(10)).Return(postgres.Property{ID: 10, OwnerID: pgtype.Int4{Int32: 1, Valid: true}}, nil)
+	mockQuerier.On("ListLeaseParties", mock.Anything, int32(4)).Return(parties, nil)
+
+	_, err := svc.SetRentSplit(context.Background(), 1, 4, RentSplit{
+		JointLiability:   true,
+		IndividualShares: true,
+		Shares:           []PartyShare{{PartyID: 1, Amount: 500}, {PartyID: 2, Amount: 300}},
+	})
+	assert.ErrorIs(t, err, ErrInvalidRentShares)
+
+	_, err = svc.SetRentSplit(context.Background(), 1, 4, RentSplit{Shares: []PartyShare{{PartyID: 9, Amount: 900}}})
+	assert.ErrorIs(t, err, ErrLeasePartyNotFound)
+	mockQuerier.AssertNotCalled(t, "UpdateLeaseRentSplit", mock.Anything, mock.Anything)
+}
+
+func TestGenerateLeaseDocument_ListsCoTenants(t *testing.T) {
+	viper.Set("ASSETS_DIR", "../../../assets")
+	defer viper.Set("ASSETS_DIR", "")
+
+	mockQuerier := new(MockQuerier)
+	svc := NewLeaseService(passthroughTxManager{q: mockQuerier}, zap.NewNop(), nil)
+	lease, parties := coTenancy()
+
+	mockQuerier.On("GetLease", mock.Anything, int32(4)).Return(lease, nil)
+	mockQuerier.On("GetProperty", mock.Anything, int32(10)).Return(postgres.Property{ID: 10, Address: "1 rue de la Paix", OwnerID: pgtype.Int4{Int32: 1, Valid: true}}, nil)
+	mockQuerier.On("GetUserById", mock.Anything, int32(1)).Return(postgres.User{ID: 1, LastName: pgtype.Text{String: "Bailleur", Valid: true}}, nil)
+	mockQuerier.On("GetUserById", mock.Anything, int32(2)).Return(postgres.User{ID: 2, Email: "alice@example.com"}, nil)
+	mockQuerier.On("ListLeaseParties", mock.Anything, int32(4)).Return(parties, nil)
+	// Bob is a co-tenant but not the lease's tenant_id
+	mockQuerier.On("GetLeasePartyByUser", mock.Anything, postgres.GetLeasePartyByUserParams{LeaseID: 4, UserID: pgtype.Int4{Int32: 3, Valid: true}}).Return(parties[1], nil)
+	mockQuerier.On("GetLeasePartyByUser", mock.Anything, mock.Anything).Return(postgres.LeaseParty{}, pgx.ErrNoRows)
+
+	content, _, err := svc.GenerateLeaseDocument(context.Background(), 4, 3)
+
+	require.NoError(t, err)
+	html := string(content)
+	assert.Contains(t, html, "Martin Alice — Email : alice@example.com")
+	assert.Contains(t, html, "Durand Bob — Email : bob@example.com")
+	assert.Contains(t, html, "solidairement et indivisiblement")
+	assert.Contains(t, html, "Durand Bob : 400.00 €")
+
+	_, _, err = svc.GenerateLeaseDocument(context.Background(), 4, 99)
+	assert.ErrorContains(t, err, "access denied")
+}
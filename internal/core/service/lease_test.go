@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"errors"
+	"io"
 	"math/big"
 	"testing"
 	"time"
@@ -142,12 +143,17 @@ func TestCreateDraft_Success(t *testing.T) {
 	mockQuerier.On("CreateDraftLease", mock.Anything, mock.MatchedBy(func(p postgres.CreateDraftLeaseParams) bool {
 		rent, _ := p.RentAmount.Float64Value()
 		charges, _ := p.ChargesAmount.Float64Value()
-		return rent.Float64 == 800 && charges.Float64 == 100
+		return rent.Float64 == 800 && charges.Float64 == 100 && p.JointLiability.Bool && !p.IndividualRentShares.Bool
 	})).Return(postgres.Lease{ID: 100}, nil)
 
-	// 3. Mock CreateInvitationWithLease
+	// 3. Mock CreateLeaseParty (first tenant)
+	mockQuerier.On("CreateLeaseParty", mock.Anything, mock.MatchedBy(func(p postgres.CreateLeasePartyParams) bool {
+		return p.LeaseID == 100 && p.Email == "jean@example.com" && p.Status == LeasePartyStatusInvited
+	})).Return(postgres.LeaseParty{ID: 7}, nil)
+
+	// 4. Mock CreateInvitationWithLease
 	mockQuerier.On("CreateInvitationWithLease", mock.Anything, mock.MatchedBy(func(p postgres.CreateInvitationWithLeaseParams) bool {
-		return p.LeaseID.Int32 == 100 && p.TenantEmail == "jean@example.com"
+		return p.LeaseID.Int32 == 100 && p.TenantEmail == "jean@example.com" && p.PartyID.Int32 == 7
 	})).Return(postgres.LeaseInvitation{}, nil)
 
 	// Execute
@@ -182,3 +188,24 @@ func (m *MockFileStorage) Exists(filename string) bool {
 	args := m.Called(filename)
 	return args.Bool(0)
 }
+
+func (m *MockFileStorage) Delete(filename string) error {
+	args := m.Called(filename)
+	return args.Error(0)
+}
+
+func (m *MockFileStorage) List(prefix string) ([]string, error) {
+	args := m.Called(prefix)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockFileStorage) Open(filename string) (io.ReadCloser, error) {
+	args := m.Called(filename)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(io.ReadCloser), args.Error(1)
+}
@@ -17,18 +17,22 @@ func (m *MockTxManager) WithTx(ctx context.Context, fn func(postgres.Querier) er
 	return args.Error(0)
 }
 
+// passthroughTxManager runs fn on the querier and returns its error, for tests asserting on errors raised inside the transaction.
+type passthroughTxManager struct {
+	q postgres.Querier
+}
+
+func (m passthroughTxManager) WithTx(ctx context.Context, fn func(postgres.Querier) error) error {
+	return fn(m.q)
+}
+
 type MockQuerier struct {
 	mock.Mock
 }
 
-func (m *MockQuerier) CancelSolvencyCheck(ctx context.Context, id int32) error {
+func (m *MockQuerier) CancelSolvencyCheck(ctx context.Context, id int32) (int64, error) {
 	args := m.Called(ctx, id)
-	return args.Error(0)
-}
-
-func (m *MockQuerier) CleanupProvisionalUsers(ctx context.Context) error {
-	args := m.Called(ctx)
-	return args.Error(0)
+	return args.Get(0).(int64), args.Error(1)
 }
 
 func (m *MockQuerier) CountBookingsByTenant(ctx context.Context, tenantID pgtype.Int4) (int64, error) {
@@ -89,16 +93,6 @@ func (m *MockQuerier) CreateUser(ctx context.Context, arg postgres.CreateUserPar
 	return args.Get(0).(postgres.User), args.Error(1)
 }
 
-func (m *MockQuerier) DecreasePropertyCredits(ctx context.Context, id int32) error {
-	args := m.Called(ctx, id)
-	return args.Error(0)
-}
-
-func (m *MockQuerier) IncreasePropertyCredits(ctx context.Context, id int32) error {
-	args := m.Called(ctx, id)
-	return args.Error(0)
-}
-
 func (m *MockQuerier) GetInvitationByToken(ctx context.Context, token string) (postgres.LeaseInvitation, error) {
 	args := m.Called(ctx, token)
 	return args.Get(0).(postgres.LeaseInvitation), args.Error(1)
@@ -139,12 +133,12 @@ func (m *MockQuerier) GetUserForUpdate(ctx context.Context, id int32) (postgres.
 	return args.Get(0).(postgres.User), args.Error(1)
 }
 
-func (m *MockQuerier) GetUserCreditBalance(ctx context.Context, userID pgtype.Int4) (int32, error) {
+func (m *MockQuerier) GetUserCreditBalance(ctx context.Context, userID int32) (int32, error) {
 	args := m.Called(ctx, userID)
 	return args.Get(0).(int32), args.Error(1)
 }
 
-func (m *MockQuerier) GetUserCreditBalanceForUpdate(ctx context.Context, userID pgtype.Int4) (int32, error) {
+func (m *MockQuerier) GetUserCreditBalanceForUpdate(ctx context.Context, userID int32) (int32, error) {
 	args := m.Called(ctx, userID)
 	return args.Get(0).(int32), args.Error(1)
 }
@@ -211,14 +205,9 @@ func (m *MockQuerier) UpdateProperty(ctx context.Context, arg postgres.UpdatePro
 	return args.Get(0).(postgres.Property), args.Error(1)
 }
 
-func (m *MockQuerier) UpdateSolvencyCheckResult(ctx context.Context, arg postgres.UpdateSolvencyCheckResultParams) error {
-	args := m.Called(ctx, arg)
-	return args.Error(0)
-}
-
-func (m *MockQuerier) UpdateSubscriptionLimit(ctx context.Context, arg postgres.UpdateSubscriptionLimitParams) error {
+func (m *MockQuerier) UpdateSolvencyCheckResult(ctx context.Context, arg postgres.UpdateSolvencyCheckResultParams) (int64, error) {
 	args := m.Called(ctx, arg)
-	return args.Error(0)
+	return args.Get(0).(int64), args.Error(1)
 }
 
 func (m *MockQuerier) GetLease(ctx context.Context, id int32) (postgres.Lease, error) {
@@ -266,11 +255,1069 @@ func (m *MockQuerier) GetLeaseByPropertyAndStatus(ctx context.Context, arg postg
 	return args.Get(0).(postgres.Lease), args.Error(1)
 }
 
-type MockLeaseService struct {
-	mock.Mock
+func (m *MockQuerier) ClearPropertyCover(ctx context.Context, propertyID int32) error {
+	args := m.Called(ctx, propertyID)
+	return args.Error(0)
 }
 
-func (m *MockLeaseService) GenerateAndSave(ctx context.Context, leaseID int32, userID int32) error {
-	args := m.Called(ctx, leaseID, userID)
+func (m *MockQuerier) CreatePropertyMedia(ctx context.Context, arg postgres.CreatePropertyMediaParams) (postgres.PropertyMedium, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(postgres.PropertyMedium), args.Error(1)
+}
+
+func (m *MockQuerier) DeletePropertyMedia(ctx context.Context, arg postgres.DeletePropertyMediaParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) GetNextPhotoPosition(ctx context.Context, propertyID int32) (int32, error) {
+	args := m.Called(ctx, propertyID)
+	return args.Get(0).(int32), args.Error(1)
+}
+
+func (m *MockQuerier) GetPropertyDiagnosticByType(ctx context.Context, arg postgres.GetPropertyDiagnosticByTypeParams) (postgres.PropertyMedium, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(postgres.PropertyMedium), args.Error(1)
+}
+
+func (m *MockQuerier) GetPropertyMedia(ctx context.Context, arg postgres.GetPropertyMediaParams) (postgres.PropertyMedium, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(postgres.PropertyMedium), args.Error(1)
+}
+
+func (m *MockQuerier) ListExpiringDiagnostics(ctx context.Context, expiresAt pgtype.Date) ([]postgres.ListExpiringDiagnosticsRow, error) {
+	args := m.Called(ctx, expiresAt)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]postgres.ListExpiringDiagnosticsRow), args.Error(1)
+}
+
+func (m *MockQuerier) ListPropertyMedia(ctx context.Context, propertyID int32) ([]postgres.PropertyMedium, error) {
+	args := m.Called(ctx, propertyID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]postgres.PropertyMedium), args.Error(1)
+}
+
+func (m *MockQuerier) MarkDiagnosticReminderSent(ctx context.Context, id int32) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) SetPropertyMediaCover(ctx context.Context, arg postgres.SetPropertyMediaCoverParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) UpdatePropertyMediaPosition(ctx context.Context, arg postgres.UpdatePropertyMediaPositionParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) CreateDocument(ctx context.Context, arg postgres.CreateDocumentParams) (postgres.Document, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(postgres.Document), args.Error(1)
+}
+
+func (m *MockQuerier) CreateDocumentAccessLog(ctx context.Context, arg postgres.CreateDocumentAccessLogParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) CreateDocumentLink(ctx context.Context, arg postgres.CreateDocumentLinkParams) (postgres.DocumentLink, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(postgres.DocumentLink), args.Error(1)
+}
+
+func (m *MockQuerier) GetDocument(ctx context.Context, id int32) (postgres.Document, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(postgres.Document), args.Error(1)
+}
+
+func (m *MockQuerier) GetDocumentLink(ctx context.Context, id int32) (postgres.DocumentLink, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(postgres.DocumentLink), args.Error(1)
+}
+
+func (m *MockQuerier) GetLatestDocument(ctx context.Context, arg postgres.GetLatestDocumentParams) (postgres.Document, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(postgres.Document), args.Error(1)
+}
+
+func (m *MockQuerier) GetNextDocumentVersion(ctx context.Context, arg postgres.GetNextDocumentVersionParams) (int32, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(int32), args.Error(1)
+}
+
+func (m *MockQuerier) ListDocumentsByEntity(ctx context.Context, arg postgres.ListDocumentsByEntityParams) ([]postgres.Document, error) {
+	args := m.Called(ctx, arg)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]postgres.Document), args.Error(1)
+}
+
+func (m *MockQuerier) RevokeDocumentLink(ctx context.Context, arg postgres.RevokeDocumentLinkParams) (int64, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) GetSolvencyCheckByTokenForUpdate(ctx context.Context, token pgtype.Text) (postgres.SolvencyCheck, error) {
+	args := m.Called(ctx, token)
+	return args.Get(0).(postgres.SolvencyCheck), args.Error(1)
+}
+
+func (m *MockQuerier) GetSolvencyCheckForUpdate(ctx context.Context, id int32) (postgres.SolvencyCheck, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(postgres.SolvencyCheck), args.Error(1)
+}
+
+func (m *MockQuerier) UpdateSolvencyCheckDocuments(ctx context.Context, arg postgres.UpdateSolvencyCheckDocumentsParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) GetSolvencyCheckByBankConnection(ctx context.Context, arg postgres.GetSolvencyCheckByBankConnectionParams) (postgres.SolvencyCheck, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(postgres.SolvencyCheck), args.Error(1)
+}
+
+func (m *MockQuerier) RecordWebhookEvent(ctx context.Context, arg postgres.RecordWebhookEventParams) (int64, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) SetSolvencyCheckBankConnection(ctx context.Context, arg postgres.SetSolvencyCheckBankConnectionParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) SetSolvencyCheckBankConsent(ctx context.Context, arg postgres.SetSolvencyCheckBankConsentParams) error {
+	args := m.Called(ctx, arg)
 	return args.Error(0)
 }
+
+func (m *MockQuerier) DeleteWebhookEvent(ctx context.Context, arg postgres.DeleteWebhookEventParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) DeletePropertySolvencyPolicy(ctx context.Context, arg postgres.DeletePropertySolvencyPolicyParams) (int64, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) GetEffectiveSolvencyPolicy(ctx context.Context, arg postgres.GetEffectiveSolvencyPolicyParams) (postgres.SolvencyPolicy, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(postgres.SolvencyPolicy), args.Error(1)
+}
+
+func (m *MockQuerier) UpdateSolvencyCheckProfile(ctx context.Context, arg postgres.UpdateSolvencyCheckProfileParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) UpsertOwnerSolvencyPolicy(ctx context.Context, arg postgres.UpsertOwnerSolvencyPolicyParams) (postgres.SolvencyPolicy, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(postgres.SolvencyPolicy), args.Error(1)
+}
+
+func (m *MockQuerier) UpsertPropertySolvencyPolicy(ctx context.Context, arg postgres.UpsertPropertySolvencyPolicyParams) (postgres.SolvencyPolicy, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(postgres.SolvencyPolicy), args.Error(1)
+}
+
+func (m *MockQuerier) AttachGuarantorsToLease(ctx context.Context, arg postgres.AttachGuarantorsToLeaseParams) ([]postgres.SolvencyGuarantor, error) {
+	args := m.Called(ctx, arg)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]postgres.SolvencyGuarantor), args.Error(1)
+}
+
+func (m *MockQuerier) CountGuarantorsByCheck(ctx context.Context, checkID int32) (int64, error) {
+	args := m.Called(ctx, checkID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) CreateSolvencyGuarantor(ctx context.Context, arg postgres.CreateSolvencyGuarantorParams) (postgres.SolvencyGuarantor, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(postgres.SolvencyGuarantor), args.Error(1)
+}
+
+func (m *MockQuerier) DeleteSolvencyGuarantor(ctx context.Context, arg postgres.DeleteSolvencyGuarantorParams) (int64, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) GetGuarantorByBankConnection(ctx context.Context, arg postgres.GetGuarantorByBankConnectionParams) (postgres.SolvencyGuarantor, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(postgres.SolvencyGuarantor), args.Error(1)
+}
+
+func (m *MockQuerier) GetGuarantorByTokenForUpdate(ctx context.Context, token string) (postgres.SolvencyGuarantor, error) {
+	args := m.Called(ctx, token)
+	return args.Get(0).(postgres.SolvencyGuarantor), args.Error(1)
+}
+
+func (m *MockQuerier) ListGuarantorsByCheck(ctx context.Context, checkID int32) ([]postgres.SolvencyGuarantor, error) {
+	args := m.Called(ctx, checkID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]postgres.SolvencyGuarantor), args.Error(1)
+}
+
+func (m *MockQuerier) ListGuarantorsByLease(ctx context.Context, leaseID pgtype.Int4) ([]postgres.SolvencyGuarantor, error) {
+	args := m.Called(ctx, leaseID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]postgres.SolvencyGuarantor), args.Error(1)
+}
+
+func (m *MockQuerier) SetGuarantorBankConnection(ctx context.Context, arg postgres.SetGuarantorBankConnectionParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) SetGuarantorBankConsent(ctx context.Context, arg postgres.SetGuarantorBankConsentParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) SetGuarantorMention(ctx context.Context, arg postgres.SetGuarantorMentionParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) UpdateGuarantorAnalysis(ctx context.Context, arg postgres.UpdateGuarantorAnalysisParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) UpdateGuarantorDocuments(ctx context.Context, arg postgres.UpdateGuarantorDocumentsParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) GetSolvencyGuarantor(ctx context.Context, id int32) (postgres.SolvencyGuarantor, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(postgres.SolvencyGuarantor), args.Error(1)
+}
+
+func (m *MockQuerier) ActivateLeaseParty(ctx context.Context, arg postgres.ActivateLeasePartyParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) CreateLeaseParty(ctx context.Context, arg postgres.CreateLeasePartyParams) (postgres.LeaseParty, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(postgres.LeaseParty), args.Error(1)
+}
+
+func (m *MockQuerier) GetLeaseParty(ctx context.Context, arg postgres.GetLeasePartyParams) (postgres.LeaseParty, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(postgres.LeaseParty), args.Error(1)
+}
+
+func (m *MockQuerier) GetLeasePartyByReplaced(ctx context.Context, replacesPartyID pgtype.Int4) (postgres.LeaseParty, error) {
+	args := m.Called(ctx, replacesPartyID)
+	return args.Get(0).(postgres.LeaseParty), args.Error(1)
+}
+
+func (m *MockQuerier) GetLeasePartyByUser(ctx context.Context, arg postgres.GetLeasePartyByUserParams) (postgres.LeaseParty, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(postgres.LeaseParty), args.Error(1)
+}
+
+func (m *MockQuerier) ListLeaseParties(ctx context.Context, leaseID int32) ([]postgres.LeaseParty, error) {
+	args := m.Called(ctx, leaseID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]postgres.LeaseParty), args.Error(1)
+}
+
+func (m *MockQuerier) SetLeasePartyDeparture(ctx context.Context, arg postgres.SetLeasePartyDepartureParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) SetLeasePartySolidarityEnd(ctx context.Context, arg postgres.SetLeasePartySolidarityEndParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) UpdateLeasePartyShare(ctx context.Context, arg postgres.UpdateLeasePartyShareParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) UpdateLeaseRentSplit(ctx context.Context, arg postgres.UpdateLeaseRentSplitParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) ExpireSolvencyCheck(ctx context.Context, id int32) (postgres.SolvencyCheck, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(postgres.SolvencyCheck), args.Error(1)
+}
+
+func (m *MockQuerier) ListExpiredSolvencyChecks(ctx context.Context) ([]int32, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]int32), args.Error(1)
+}
+
+func (m *MockQuerier) ListSolvencyChecksAwaitingCandidate(ctx context.Context, maxReminders int32) ([]postgres.ListSolvencyChecksAwaitingCandidateRow, error) {
+	args := m.Called(ctx, maxReminders)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]postgres.ListSolvencyChecksAwaitingCandidateRow), args.Error(1)
+}
+
+func (m *MockQuerier) MarkSolvencyCheckReminderSent(ctx context.Context, id int32) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) SetSolvencyCheckSelection(ctx context.Context, arg postgres.SetSolvencyCheckSelectionParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) CreateDossierShare(ctx context.Context, arg postgres.CreateDossierShareParams) (postgres.DossierShare, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(postgres.DossierShare), args.Error(1)
+}
+
+func (m *MockQuerier) DeleteTenantDossier(ctx context.Context, id int32) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) GetDossierShareByToken(ctx context.Context, token string) (postgres.DossierShare, error) {
+	args := m.Called(ctx, token)
+	return args.Get(0).(postgres.DossierShare), args.Error(1)
+}
+
+func (m *MockQuerier) GetTenantDossier(ctx context.Context, id int32) (postgres.TenantDossier, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(postgres.TenantDossier), args.Error(1)
+}
+
+func (m *MockQuerier) GetTenantDossierByUser(ctx context.Context, userID int32) (postgres.TenantDossier, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(postgres.TenantDossier), args.Error(1)
+}
+
+func (m *MockQuerier) ListDossierShares(ctx context.Context, dossierID int32) ([]postgres.DossierShare, error) {
+	args := m.Called(ctx, dossierID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]postgres.DossierShare), args.Error(1)
+}
+
+func (m *MockQuerier) RevokeDossierShare(ctx context.Context, arg postgres.RevokeDossierShareParams) (int64, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) SetSolvencyCheckDossierShare(ctx context.Context, arg postgres.SetSolvencyCheckDossierShareParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) UpsertTenantDossier(ctx context.Context, arg postgres.UpsertTenantDossierParams) (postgres.TenantDossier, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(postgres.TenantDossier), args.Error(1)
+}
+
+func (m *MockQuerier) AnonymizeLease(ctx context.Context, id int32) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) AnonymizeLeaseGuarantors(ctx context.Context, leaseID pgtype.Int4) error {
+	args := m.Called(ctx, leaseID)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) AnonymizeLeaseInvitations(ctx context.Context, leaseID pgtype.Int4) error {
+	args := m.Called(ctx, leaseID)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) AnonymizeLeaseParties(ctx context.Context, leaseID int32) error {
+	args := m.Called(ctx, leaseID)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) AnonymizeSolvencyCheck(ctx context.Context, id int32) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) ClearGuarantorDocumentsByCheck(ctx context.Context, checkID int32) error {
+	args := m.Called(ctx, checkID)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) CreateRetentionPurge(ctx context.Context, arg postgres.CreateRetentionPurgeParams) (postgres.RetentionPurge, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(postgres.RetentionPurge), args.Error(1)
+}
+
+func (m *MockQuerier) DeleteDocumentAccessLogsBefore(ctx context.Context, accessedAt pgtype.Timestamp) (int64, error) {
+	args := m.Called(ctx, accessedAt)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) DeleteDocumentAccessLogsByDocuments(ctx context.Context, documentIds []int32) error {
+	args := m.Called(ctx, documentIds)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) DeleteDocumentLinksBefore(ctx context.Context, expiresAt pgtype.Timestamp) (int64, error) {
+	args := m.Called(ctx, expiresAt)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) DeleteDocumentLinksByDocuments(ctx context.Context, documentIds []int32) error {
+	args := m.Called(ctx, documentIds)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) DeleteDocuments(ctx context.Context, documentIds []int32) error {
+	args := m.Called(ctx, documentIds)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) DeleteUnattachedGuarantorsByCheck(ctx context.Context, checkID int32) error {
+	args := m.Called(ctx, checkID)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) DeleteWebhookEventsBefore(ctx context.Context, receivedAt pgtype.Timestamp) (int64, error) {
+	args := m.Called(ctx, receivedAt)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) ListLeaseDocuments(ctx context.Context, entityID int32) ([]postgres.Document, error) {
+	args := m.Called(ctx, entityID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]postgres.Document), args.Error(1)
+}
+
+func (m *MockQuerier) ListLeasesForAnonymization(ctx context.Context, endDate pgtype.Date) ([]int32, error) {
+	args := m.Called(ctx, endDate)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]int32), args.Error(1)
+}
+
+func (m *MockQuerier) ListSolvencyChecksByLeaseTenants(ctx context.Context, id int32) ([]postgres.ListSolvencyChecksByLeaseTenantsRow, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]postgres.ListSolvencyChecksByLeaseTenantsRow), args.Error(1)
+}
+
+func (m *MockQuerier) ListSolvencyChecksForAnonymization(ctx context.Context, createdAt pgtype.Timestamp) ([]postgres.ListSolvencyChecksForAnonymizationRow, error) {
+	args := m.Called(ctx, createdAt)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]postgres.ListSolvencyChecksForAnonymizationRow), args.Error(1)
+}
+
+func (m *MockQuerier) ListSolvencyChecksForDocumentPurge(ctx context.Context, createdAt pgtype.Timestamp) ([]postgres.ListSolvencyChecksForDocumentPurgeRow, error) {
+	args := m.Called(ctx, createdAt)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]postgres.ListSolvencyChecksForDocumentPurgeRow), args.Error(1)
+}
+
+func (m *MockQuerier) MarkSolvencyCheckDocumentsPurged(ctx context.Context, id int32) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) CleanupProvisionalUsers(ctx context.Context, createdAt pgtype.Timestamp) ([]int32, error) {
+	args := m.Called(ctx, createdAt)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]int32), args.Error(1)
+}
+
+func (m *MockQuerier) AnonymizeUser(ctx context.Context, id int32) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) CancelUserSubscriptions(ctx context.Context, userID pgtype.Int4) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) CountLeasesByOwner(ctx context.Context, ownerID pgtype.Int4) (int64, error) {
+	args := m.Called(ctx, ownerID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) DeactivatePropertiesByOwner(ctx context.Context, ownerID pgtype.Int4) error {
+	args := m.Called(ctx, ownerID)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) ListCreditTransactionsByUser(ctx context.Context, userID pgtype.Int4) ([]postgres.CreditTransaction, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]postgres.CreditTransaction), args.Error(1)
+}
+
+func (m *MockQuerier) ListInvitationsByEmail(ctx context.Context, tenantEmail string) ([]postgres.LeaseInvitation, error) {
+	args := m.Called(ctx, tenantEmail)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]postgres.LeaseInvitation), args.Error(1)
+}
+
+func (m *MockQuerier) ListInvitationsByOwner(ctx context.Context, ownerID int32) ([]postgres.LeaseInvitation, error) {
+	args := m.Called(ctx, ownerID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]postgres.LeaseInvitation), args.Error(1)
+}
+
+func (m *MockQuerier) ListLeasesByOwner(ctx context.Context, ownerID pgtype.Int4) ([]postgres.ListLeasesByOwnerRow, error) {
+	args := m.Called(ctx, ownerID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]postgres.ListLeasesByOwnerRow), args.Error(1)
+}
+
+func (m *MockQuerier) ListPaymentTransactionsByUser(ctx context.Context, userID pgtype.Int4) ([]postgres.Transaction, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]postgres.Transaction), args.Error(1)
+}
+
+func (m *MockQuerier) ListRentPaymentsByLease(ctx context.Context, leaseID pgtype.Int4) ([]postgres.RentPayment, error) {
+	args := m.Called(ctx, leaseID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]postgres.RentPayment), args.Error(1)
+}
+
+func (m *MockQuerier) ListSolvencyChecksByCandidate(ctx context.Context, candidateID pgtype.Int4) ([]postgres.ListSolvencyChecksByCandidateRow, error) {
+	args := m.Called(ctx, candidateID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]postgres.ListSolvencyChecksByCandidateRow), args.Error(1)
+}
+
+func (m *MockQuerier) ListSubscriptionsByUser(ctx context.Context, userID pgtype.Int4) ([]postgres.Subscription, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]postgres.Subscription), args.Error(1)
+}
+
+func (m *MockQuerier) RevokeDocumentLinksByUser(ctx context.Context, userID int32) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) RevokePendingInvitationsForUser(ctx context.Context, arg postgres.RevokePendingInvitationsForUserParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) CountOverlappingCatalogItems(ctx context.Context, arg postgres.CountOverlappingCatalogItemsParams) (int64, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) CreateCatalogItem(ctx context.Context, arg postgres.CreateCatalogItemParams) (postgres.CatalogItem, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(postgres.CatalogItem), args.Error(1)
+}
+
+func (m *MockQuerier) GetActiveCatalogItem(ctx context.Context, arg postgres.GetActiveCatalogItemParams) (postgres.CatalogItem, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(postgres.CatalogItem), args.Error(1)
+}
+
+func (m *MockQuerier) GetCatalogItem(ctx context.Context, id int32) (postgres.CatalogItem, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(postgres.CatalogItem), args.Error(1)
+}
+
+func (m *MockQuerier) ListActiveCatalogItems(ctx context.Context) ([]postgres.CatalogItem, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]postgres.CatalogItem), args.Error(1)
+}
+
+func (m *MockQuerier) ListCatalogItems(ctx context.Context) ([]postgres.CatalogItem, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]postgres.CatalogItem), args.Error(1)
+}
+
+func (m *MockQuerier) RetireCatalogItem(ctx context.Context, id int32) (postgres.CatalogItem, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(postgres.CatalogItem), args.Error(1)
+}
+
+func (m *MockQuerier) UpdateCatalogItem(ctx context.Context, arg postgres.UpdateCatalogItemParams) (postgres.CatalogItem, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(postgres.CatalogItem), args.Error(1)
+}
+
+func (m *MockQuerier) AdvanceSubscriptionPeriod(ctx context.Context, arg postgres.AdvanceSubscriptionPeriodParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) CreateInvoice(ctx context.Context, arg postgres.CreateInvoiceParams) (postgres.Invoice, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(postgres.Invoice), args.Error(1)
+}
+
+func (m *MockQuerier) GetInvoiceForPeriod(ctx context.Context, arg postgres.GetInvoiceForPeriodParams) (postgres.Invoice, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(postgres.Invoice), args.Error(1)
+}
+
+func (m *MockQuerier) GetSubscriptionForUpdate(ctx context.Context, id int32) (postgres.Subscription, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(postgres.Subscription), args.Error(1)
+}
+
+func (m *MockQuerier) ListInvoicesByUser(ctx context.Context, userID int32) ([]postgres.Invoice, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]postgres.Invoice), args.Error(1)
+}
+
+func (m *MockQuerier) ListInvoicesToRetry(ctx context.Context, arg postgres.ListInvoicesToRetryParams) ([]postgres.Invoice, error) {
+	args := m.Called(ctx, arg)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]postgres.Invoice), args.Error(1)
+}
+
+func (m *MockQuerier) ListSubscriptionsDueForCredits(ctx context.Context, today pgtype.Date) ([]int32, error) {
+	args := m.Called(ctx, today)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]int32), args.Error(1)
+}
+
+func (m *MockQuerier) ListSubscriptionsDueForRenewal(ctx context.Context, today pgtype.Date) ([]int32, error) {
+	args := m.Called(ctx, today)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]int32), args.Error(1)
+}
+
+func (m *MockQuerier) MarkInvoiceFailed(ctx context.Context, id int32) (postgres.Invoice, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(postgres.Invoice), args.Error(1)
+}
+
+func (m *MockQuerier) MarkInvoicePaid(ctx context.Context, id int32) (postgres.Invoice, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(postgres.Invoice), args.Error(1)
+}
+
+func (m *MockQuerier) RecordSubscriptionCreditGrant(ctx context.Context, arg postgres.RecordSubscriptionCreditGrantParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) SetSubscriptionStatus(ctx context.Context, arg postgres.SetSubscriptionStatusParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) VoidInvoice(ctx context.Context, id int32) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) GetCreditUsageSinceLastPlanGrant(ctx context.Context, userID pgtype.Int4) (int32, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(int32), args.Error(1)
+}
+
+func (m *MockQuerier) CreatePaymentTransaction(ctx context.Context, arg postgres.CreatePaymentTransactionParams) (postgres.Transaction, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(postgres.Transaction), args.Error(1)
+}
+
+func (m *MockQuerier) GetInvoice(ctx context.Context, id int32) (postgres.Invoice, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(postgres.Invoice), args.Error(1)
+}
+
+func (m *MockQuerier) GetPaymentTransactionByIntentForUpdate(ctx context.Context, stripePaymentIntentID pgtype.Text) (postgres.Transaction, error) {
+	args := m.Called(ctx, stripePaymentIntentID)
+	return args.Get(0).(postgres.Transaction), args.Error(1)
+}
+
+func (m *MockQuerier) GetPaymentTransactionForUpdate(ctx context.Context, id int32) (postgres.Transaction, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(postgres.Transaction), args.Error(1)
+}
+
+func (m *MockQuerier) MarkInvoicePending(ctx context.Context, id int32) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) SetPaymentTransactionStatus(ctx context.Context, arg postgres.SetPaymentTransactionStatusParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) SetUserPaymentCustomer(ctx context.Context, arg postgres.SetUserPaymentCustomerParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) SetUserSepaMandate(ctx context.Context, arg postgres.SetUserSepaMandateParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) GetRefundedCents(ctx context.Context, relatedEntityID pgtype.Int4) (int32, error) {
+	args := m.Called(ctx, relatedEntityID)
+	return args.Get(0).(int32), args.Error(1)
+}
+
+func (m *MockQuerier) CompleteIdempotencyKey(ctx context.Context, arg postgres.CompleteIdempotencyKeyParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) CreateIdempotencyKey(ctx context.Context, arg postgres.CreateIdempotencyKeyParams) (int64, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) DeleteIdempotencyKey(ctx context.Context, arg postgres.DeleteIdempotencyKeyParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) GetIdempotencyKey(ctx context.Context, arg postgres.GetIdempotencyKeyParams) (postgres.IdempotencyKey, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(postgres.IdempotencyKey), args.Error(1)
+}
+
+func (m *MockQuerier) PurgeIdempotencyKeys(ctx context.Context, createdBefore pgtype.Timestamp) (int64, error) {
+	args := m.Called(ctx, createdBefore)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) CreateCreditNote(ctx context.Context, arg postgres.CreateCreditNoteParams) (postgres.Invoice, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(postgres.Invoice), args.Error(1)
+}
+
+func (m *MockQuerier) GetIssuedInvoice(ctx context.Context, id int32) (postgres.Invoice, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(postgres.Invoice), args.Error(1)
+}
+
+func (m *MockQuerier) IssueInvoice(ctx context.Context, arg postgres.IssueInvoiceParams) (postgres.Invoice, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(postgres.Invoice), args.Error(1)
+}
+
+func (m *MockQuerier) ListInvoicesToArchive(ctx context.Context, limit int32) ([]postgres.Invoice, error) {
+	args := m.Called(ctx, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]postgres.Invoice), args.Error(1)
+}
+
+func (m *MockQuerier) ListIssuedInvoicesByUser(ctx context.Context, userID int32) ([]postgres.Invoice, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]postgres.Invoice), args.Error(1)
+}
+
+func (m *MockQuerier) NextInvoiceNumber(ctx context.Context, arg postgres.NextInvoiceNumberParams) (int32, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(int32), args.Error(1)
+}
+
+func (m *MockQuerier) SetInvoiceDocument(ctx context.Context, arg postgres.SetInvoiceDocumentParams) (int64, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) GetInvoiceForUpdate(ctx context.Context, id int32) (postgres.Invoice, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(postgres.Invoice), args.Error(1)
+}
+
+func (m *MockQuerier) ArchiveProperties(ctx context.Context, arg postgres.ArchivePropertiesParams) (int64, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) ChangeSubscriptionPlan(ctx context.Context, arg postgres.ChangeSubscriptionPlanParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) CreateSubscriptionEvent(ctx context.Context, arg postgres.CreateSubscriptionEventParams) (postgres.SubscriptionEvent, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(postgres.SubscriptionEvent), args.Error(1)
+}
+
+func (m *MockQuerier) EndSubscription(ctx context.Context, arg postgres.EndSubscriptionParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) GetUserSubscriptionForUpdate(ctx context.Context, userID pgtype.Int4) (postgres.Subscription, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(postgres.Subscription), args.Error(1)
+}
+
+func (m *MockQuerier) ListActivePropertiesByOwnerAndType(ctx context.Context, arg postgres.ListActivePropertiesByOwnerAndTypeParams) ([]postgres.ListActivePropertiesByOwnerAndTypeRow, error) {
+	args := m.Called(ctx, arg)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]postgres.ListActivePropertiesByOwnerAndTypeRow), args.Error(1)
+}
+
+func (m *MockQuerier) ListSubscriptionEventsByUser(ctx context.Context, userID int32) ([]postgres.ListSubscriptionEventsByUserRow, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]postgres.ListSubscriptionEventsByUserRow), args.Error(1)
+}
+
+func (m *MockQuerier) ScheduleSubscriptionChange(ctx context.Context, arg postgres.ScheduleSubscriptionChangeParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) SetSubscriptionCancelAtPeriodEnd(ctx context.Context, arg postgres.SetSubscriptionCancelAtPeriodEndParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) AddSubscriptionItemQuantity(ctx context.Context, arg postgres.AddSubscriptionItemQuantityParams) (postgres.SubscriptionItem, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(postgres.SubscriptionItem), args.Error(1)
+}
+
+func (m *MockQuerier) GetSubscriptionItem(ctx context.Context, arg postgres.GetSubscriptionItemParams) (postgres.SubscriptionItem, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(postgres.SubscriptionItem), args.Error(1)
+}
+
+func (m *MockQuerier) ScheduleSubscriptionItemQuantity(ctx context.Context, arg postgres.ScheduleSubscriptionItemQuantityParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) SetSubscriptionPropertyLimit(ctx context.Context, arg postgres.SetSubscriptionPropertyLimitParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) UpdateSubscriptionItem(ctx context.Context, arg postgres.UpdateSubscriptionItemParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) CountCreditTransactions(ctx context.Context, arg postgres.CountCreditTransactionsParams) (int64, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) ListCreditBalanceDrifts(ctx context.Context) ([]postgres.ListCreditBalanceDriftsRow, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]postgres.ListCreditBalanceDriftsRow), args.Error(1)
+}
+
+func (m *MockQuerier) ListCreditTransactionsPage(ctx context.Context, arg postgres.ListCreditTransactionsPageParams) ([]postgres.ListCreditTransactionsPageRow, error) {
+	args := m.Called(ctx, arg)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]postgres.ListCreditTransactionsPageRow), args.Error(1)
+}
+
+func (m *MockQuerier) ListPropertyCreditDrifts(ctx context.Context) ([]postgres.ListPropertyCreditDriftsRow, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]postgres.ListPropertyCreditDriftsRow), args.Error(1)
+}
+
+func (m *MockQuerier) CreateCreditTransfer(ctx context.Context, arg postgres.CreateCreditTransferParams) ([]postgres.CreditTransaction, error) {
+	args := m.Called(ctx, arg)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]postgres.CreditTransaction), args.Error(1)
+}
+
+func (m *MockQuerier) GetCreditAccountForUpdate(ctx context.Context, id int32) (postgres.GetCreditAccountForUpdateRow, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(postgres.GetCreditAccountForUpdateRow), args.Error(1)
+}
+
+func (m *MockQuerier) ListCreditAccountsByUser(ctx context.Context, userID pgtype.Int4) ([]postgres.ListCreditAccountsByUserRow, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]postgres.ListCreditAccountsByUserRow), args.Error(1)
+}
+
+func (m *MockQuerier) ListUnbalancedCreditEntries(ctx context.Context) ([]postgres.ListUnbalancedCreditEntriesRow, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]postgres.ListUnbalancedCreditEntriesRow), args.Error(1)
+}
+
+func (m *MockQuerier) CompleteGuarantorDocuments(ctx context.Context, id int32) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) DeleteExpiredTenantDossiers(ctx context.Context, expiresAt pgtype.Timestamp) ([]postgres.DeleteExpiredTenantDossiersRow, error) {
+	args := m.Called(ctx, expiresAt)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]postgres.DeleteExpiredTenantDossiersRow), args.Error(1)
+}
+
+func (m *MockQuerier) IsUserActive(ctx context.Context, id int32) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(bool), args.Error(1)
+}
+
+func (m *MockQuerier) GetCatalogItemForUpdate(ctx context.Context, id int32) (postgres.CatalogItem, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(postgres.CatalogItem), args.Error(1)
+}
+
+func (m *MockQuerier) IsCatalogItemReferenced(ctx context.Context, id int32) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(bool), args.Error(1)
+}
+
+func (m *MockQuerier) ClaimInvoiceForCollection(ctx context.Context, arg postgres.ClaimInvoiceForCollectionParams) (postgres.Invoice, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(postgres.Invoice), args.Error(1)
+}
+
+func (m *MockQuerier) SetPaymentTransactionEntity(ctx context.Context, arg postgres.SetPaymentTransactionEntityParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) TakeOverIdempotencyKey(ctx context.Context, arg postgres.TakeOverIdempotencyKeyParams) (int64, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) GetCreditAccountProperty(ctx context.Context, id int32) (pgtype.Int4, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(pgtype.Int4), args.Error(1)
+}
+
+func (m *MockQuerier) LockPropertiesByOwner(ctx context.Context, ownerID pgtype.Int4) error {
+	args := m.Called(ctx, ownerID)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) GetPendingRefund(ctx context.Context, relatedEntityID pgtype.Int4) (postgres.Transaction, error) {
+	args := m.Called(ctx, relatedEntityID)
+	return args.Get(0).(postgres.Transaction), args.Error(1)
+}
+
+func (m *MockQuerier) SettleRefundTransaction(ctx context.Context, arg postgres.SettleRefundTransactionParams) (postgres.Transaction, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(postgres.Transaction), args.Error(1)
+}
+
+func (m *MockQuerier) TouchIdempotencyKey(ctx context.Context, arg postgres.TouchIdempotencyKeyParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) IsUserAdmin(ctx context.Context, id int32) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(bool), args.Error(1)
+}
+
+type MockLeaseService struct {
+	mock.Mock
+}
+
+func (m *MockLeaseService) GenerateAndSave(ctx context.Context, leaseID int32, userID int32) error {
+	args := m.Called(ctx, leaseID, userID)
+	return args.Error(0)
+}
+
+func (m *MockLeaseService) GenerateGuaranteeDeeds(ctx context.Context, leaseID, tenantID int32) (int, error) {
+	args := m.Called(ctx, leaseID, tenantID)
+	return args.Int(0), args.Error(1)
+}
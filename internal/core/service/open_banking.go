@@ -0,0 +1,302 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+	"seculoc-back/internal/platform/logger"
+)
+
+// Open banking webhook event types understood by the solvency flow.
+const (
+	WebhookTransactionsReady = "transactions.ready"
+	WebhookConsentRevoked    = "consent.revoked"
+)
+
+// bankHistoryWindow is the transaction history fetched for the income analysis.
+const bankHistoryWindow = 3 * 30 * 24 * time.Hour
+
+var (
+	ErrOpenBankingUnavailable  = errors.New("open banking provider not configured")
+	ErrBankDataPending         = errors.New("bank data not available yet")
+	ErrBankConsentMismatch     = errors.New("bank consent does not belong to this check")
+	ErrBankConsentMissing      = errors.New("no bank consent started for this check")
+	ErrRawTransactionsDisabled = errors.New("raw transactions callback disabled")
+	ErrCheckAlreadyProcessed   = errors.New("check already processed")
+)
+
+// ConsentRequest describes the consent session opened for a candidate.
+type ConsentRequest struct {
+	// Reference is an opaque identifier echoed back by the provider (never the check token)
+	Reference   string
+	RedirectURL string
+}
+
+// ConsentSession is the provider-hosted page where the candidate authorizes access to their accounts.
+type ConsentSession struct {
+	ID        string
+	URL       string
+	ExpiresAt time.Time
+}
+
+// BankConnection is the result of a successful consent: a handle on the candidate's bank data.
+type BankConnection struct {
+	ID        string
+	ConsentID string
+}
+
+type BankAccount struct {
+	ID       string
+	Name     string
+	IBAN     string
+	Currency string
+}
+
+// WebhookEvent is a provider notification, already authenticated by ParseWebhook.
+type WebhookEvent struct {
+	ID           string
+	Type         string
+	ConnectionID string
+	ConsentID    string
+}
+
+// OpenBankingProvider abstracts an aggregator (consent, accounts, transactions, webhooks).
+// Implementations keep their own credentials: no bank access token is stored by the service.
+type OpenBankingProvider interface {
+	Name() string
+	StartConsent(ctx context.Context, req ConsentRequest) (*ConsentSession, error)
+	ExchangeCode(ctx context.Context, code string) (*BankConnection, error)
+	ListAccounts(ctx context.Context, connectionID string) ([]BankAccount, error)
+	ListTransactions(ctx context.Context, connectionID, accountID string, from, to time.Time) ([]TransactionData, error)
+	// ParseWebhook verifies the signature of a notification and decodes it.
+	ParseWebhook(payload []byte, headers http.Header) (*WebhookEvent, error)
+}
+
+// bankReference identifies a check towards the provider without exposing its token.
+func bankReference(checkID int32) string {
+	return fmt.Sprintf("check-%d", checkID)
+}
+
+// pendingCheckByToken locks a pending check for the public bank flow.
+func pendingCheckByToken(ctx context.Context, q postgres.Querier, token string) (postgres.SolvencyCheck, error) {
+	check, err := q.GetSolvencyCheckByTokenForUpdate(ctx, pgtype.Text{String: token, Valid: true})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return check, fmt.Errorf("check not found")
+		}
+		return check, err
+	}
+	if check.Status.SolvencyStatus != postgres.SolvencyStatusPending {
+		return check, ErrCheckAlreadyProcessed
+	}
+	return check, nil
+}
+
+// readPendingCheck reads a pending check of the public bank flow without keeping it locked: the provider is
+// called outside any transaction, then the check is locked again (pendingCheckByToken) to store the outcome.
+func (s *SolvencyService) readPendingCheck(ctx context.Context, token string) (postgres.SolvencyCheck, error) {
+	var check postgres.SolvencyCheck
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		var err error
+		check, err = pendingCheckByToken(ctx, q, token)
+		return err
+	})
+	return check, err
+}
+
+// StartBankConsent opens a consent session with the provider and remembers its identifier on the check.
+func (s *SolvencyService) StartBankConsent(ctx context.Context, token, redirectURL string) (*ConsentSession, error) {
+	if s.bank == nil {
+		return nil, ErrOpenBankingUnavailable
+	}
+
+	check, err := s.readPendingCheck(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	session, err := s.bank.StartConsent(ctx, ConsentRequest{
+		Reference:   bankReference(check.ID),
+		RedirectURL: redirectURL,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start bank consent: %w", err)
+	}
+
+	err = s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		if _, err := pendingCheckByToken(ctx, q, token); err != nil {
+			return err
+		}
+		return q.SetSolvencyCheckBankConsent(ctx, postgres.SetSolvencyCheckBankConsentParams{
+			ID:            check.ID,
+			BankProvider:  pgtype.Text{String: s.bank.Name(), Valid: true},
+			BankConsentID: pgtype.Text{String: session.ID, Valid: true},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// CompleteBankConsent exchanges the authorization code returned to the candidate, checks it was issued
+// for this check's consent, then fetches and analyzes the transactions. When the provider is still
+// aggregating, the check stays pending and the transactions.ready webhook finishes the job.
+func (s *SolvencyService) CompleteBankConsent(ctx context.Context, token, code string) error {
+	if s.bank == nil {
+		return ErrOpenBankingUnavailable
+	}
+
+	check, err := s.readPendingCheck(ctx, token)
+	if err != nil {
+		return err
+	}
+	if !check.BankConsentID.Valid || check.BankProvider.String != s.bank.Name() {
+		return ErrBankConsentMissing
+	}
+	conn, err := s.bank.ExchangeCode(ctx, code)
+	if err != nil {
+		return fmt.Errorf("failed to exchange bank code: %w", err)
+	}
+
+	err = s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		// Still pending, and the code was issued for the consent the check holds now
+		check, err := pendingCheckByToken(ctx, q, token)
+		if err != nil {
+			return err
+		}
+		if conn.ConsentID != check.BankConsentID.String {
+			return ErrBankConsentMismatch
+		}
+
+		return q.SetSolvencyCheckBankConnection(ctx, postgres.SetSolvencyCheckBankConnectionParams{
+			ID:               check.ID,
+			BankConnectionID: pgtype.Text{String: conn.ID, Valid: true},
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.analyzeConnection(ctx, check.ID, conn.ID)
+	if errors.Is(err, ErrBankDataPending) {
+		logger.FromContext(ctx).Info("bank data pending, awaiting webhook", zap.Int("check_id", int(check.ID)))
+		return nil
+	}
+	return err
+}
+
+// HandleOpenBankingWebhook authenticates a provider notification, drops replays and completes
+// the pending check, or the guarantor, bound to the notified connection.
+func (s *SolvencyService) HandleOpenBankingWebhook(ctx context.Context, payload []byte, headers http.Header) error {
+	if s.bank == nil {
+		return ErrOpenBankingUnavailable
+	}
+	log := logger.FromContext(ctx)
+
+	event, err := s.bank.ParseWebhook(payload, headers)
+	if err != nil {
+		return err
+	}
+
+	var check postgres.SolvencyCheck
+	var guarantor postgres.SolvencyGuarantor
+	var found bool
+	err = s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		rows, err := q.RecordWebhookEvent(ctx, postgres.RecordWebhookEventParams{
+			Provider:  s.bank.Name(),
+			EventID:   event.ID,
+			EventType: event.Type,
+		})
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			log.Info("webhook replay ignored", zap.String("event_id", event.ID))
+			return nil
+		}
+		if event.Type != WebhookTransactionsReady {
+			return nil
+		}
+
+		check, err = q.GetSolvencyCheckByBankConnection(ctx, postgres.GetSolvencyCheckByBankConnectionParams{
+			BankProvider:     pgtype.Text{String: s.bank.Name(), Valid: true},
+			BankConnectionID: pgtype.Text{String: event.ConnectionID, Valid: true},
+		})
+		if err == nil {
+			found = check.Status.SolvencyStatus == postgres.SolvencyStatusPending
+			return nil
+		}
+		if err != pgx.ErrNoRows {
+			return err
+		}
+
+		// Not a candidate's connection: it may be a guarantor's
+		guarantor, err = q.GetGuarantorByBankConnection(ctx, postgres.GetGuarantorByBankConnectionParams{
+			BankProvider:     pgtype.Text{String: s.bank.Name(), Valid: true},
+			BankConnectionID: pgtype.Text{String: event.ConnectionID, Valid: true},
+		})
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				log.Warn("webhook for unknown bank connection", zap.String("connection_id", event.ConnectionID))
+				return nil
+			}
+			return err
+		}
+		found = guarantor.Status == GuarantorStatusInvited
+		return nil
+	})
+	if err != nil || !found {
+		return err
+	}
+
+	if guarantor.ID != 0 {
+		err = s.analyzeGuarantorConnection(ctx, guarantor, event.ConnectionID)
+	} else {
+		err = s.analyzeConnection(ctx, check.ID, event.ConnectionID)
+	}
+	if err != nil {
+		// Forget the event so that the provider's retry is not mistaken for a replay
+		_ = s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+			return q.DeleteWebhookEvent(ctx, postgres.DeleteWebhookEventParams{Provider: s.bank.Name(), EventID: event.ID})
+		})
+		return err
+	}
+	return nil
+}
+
+// analyzeConnection fetches the recent transactions of every account behind a connection and scores them.
+func (s *SolvencyService) analyzeConnection(ctx context.Context, checkID int32, connectionID string) error {
+	transactions, err := s.fetchBankTransactions(ctx, connectionID)
+	if err != nil {
+		return err
+	}
+	return s.analyzeTransactions(ctx, checkID, transactions)
+}
+
+// fetchBankTransactions returns the transactions of the last bankHistoryWindow on every account of a connection.
+func (s *SolvencyService) fetchBankTransactions(ctx context.Context, connectionID string) ([]TransactionData, error) {
+	accounts, err := s.bank.ListAccounts(ctx, connectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	to := time.Now()
+	from := to.Add(-bankHistoryWindow)
+	var transactions []TransactionData
+	for _, acc := range accounts {
+		txs, err := s.bank.ListTransactions(ctx, connectionID, acc.ID, from, to)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, txs...)
+	}
+	return transactions, nil
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+)
+
+// stubBankProvider returns a connection bound to consent "consent-1" and three monthly salaries.
+type stubBankProvider struct {
+	pending bool
+	event   *WebhookEvent
+}
+
+func (p *stubBankProvider) Name() string { return "stub" }
+
+func (p *stubBankProvider) StartConsent(ctx context.Context, req ConsentRequest) (*ConsentSession, error) {
+	return &ConsentSession{ID: "consent-1", URL: req.RedirectURL + "?code=c1"}, nil
+}
+
+func (p *stubBankProvider) ExchangeCode(ctx context.Context, code string) (*BankConnection, error) {
+	if code == "c1" {
+		return &BankConnection{ID: "conn-1", ConsentID: "consent-1"}, nil
+	}
+	return &BankConnection{ID: "conn-2", ConsentID: "consent-2"}, nil
+}
+
+func (p *stubBankProvider) ListAccounts(ctx context.Context, connectionID string) ([]BankAccount, error) {
+	if p.pending {
+		return nil, ErrBankDataPending
+	}
+	return []BankAccount{{ID: "acc-1"}}, nil
+}
+
+func (p *stubBankProvider) ListTransactions(ctx context.Context, connectionID, accountID string, from, to time.Time) ([]TransactionData, error) {
+	var txs []TransactionData
+	for i := 1; i <= 3; i++ {
+		txs = append(txs, TransactionData{Amount: 3000, Description: "SALAIRE ACME", Date: to.AddDate(0, -i, 0)})
+	}
+	return txs, nil
+}
+
+func (p *stubBankProvider) ParseWebhook(payload []byte, headers http.Header) (*WebhookEvent, error) {
+	if p.event == nil {
+		return nil, errors.New("invalid signature")
+	}
+	return p.event, nil
+}
+
+func pendingCheck() postgres.SolvencyCheck {
+	return postgres.SolvencyCheck{
+		ID:            7,
+		PropertyID:    pgtype.Int4{Int32: 10, Valid: true},
+		Status:        postgres.NullSolvencyStatus{SolvencyStatus: postgres.SolvencyStatusPending, Valid: true},
+		BankProvider:  pgtype.Text{String: "stub", Valid: true},
+		BankConsentID: pgtype.Text{String: "consent-1", Valid: true},
+	}
+}
+
+func TestStartBankConsent_StoresConsent(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	svc := NewSolvencyService(passthroughTxManager{q: mockQuerier}, nil, zap.NewNop(), nil, &stubBankProvider{})
+
+	mockQuerier.On("GetSolvencyCheckByTokenForUpdate", mock.Anything, pgtype.Text{String: "tok", Valid: true}).Return(pendingCheck(), nil)
+	mockQuerier.On("SetSolvencyCheckBankConsent", mock.Anything, postgres.SetSolvencyCheckBankConsentParams{
+		ID:            7,
+		BankProvider:  pgtype.Text{String: "stub", Valid: true},
+		BankConsentID: pgtype.Text{String: "consent-1", Valid: true},
+	}).Return(nil)
+
+	session, err := svc.StartBankConsent(context.Background(), "tok", "http://front/check/tok/bank")
+	assert.NoError(t, err)
+	assert.Equal(t, "http://front/check/tok/bank?code=c1", session.URL)
+	mockQuerier.AssertExpectations(t)
+}
+
+func TestCompleteBankConsent_AnalyzesTransactions(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	svc := NewSolvencyService(passthroughTxManager{q: mockQuerier}, nil, zap.NewNop(), nil, &stubBankProvider{})
+
+	mockQuerier.On("GetSolvencyCheckByTokenForUpdate", mock.Anything, mock.Anything).Return(pendingCheck(), nil)
+	mockQuerier.On("SetSolvencyCheckBankConnection", mock.Anything, postgres.SetSolvencyCheckBankConnectionParams{
+		ID:               7,
+		BankConnectionID: pgtype.Text{String: "conn-1", Valid: true},
+	}).Return(nil)
+	expectAnalysisLookups(mockQuerier, pendingCheck())
+	var stored postgres.UpdateSolvencyCheckResultParams
+	mockQuerier.On("UpdateSolvencyCheckResult", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(postgres.UpdateSolvencyCheckResultParams)
+	}).Return(int64(1), nil)
+
+	err := svc.CompleteBankConsent(context.Background(), "tok", "c1")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(7), stored.ID)
+	assert.Equal(t, postgres.SolvencyStatusApproved, stored.Status.SolvencyStatus)
+}
+
+func TestCompleteBankConsent_CheckCancelledMeanwhile(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	svc := NewSolvencyService(passthroughTxManager{q: mockQuerier}, nil, zap.NewNop(), nil, &stubBankProvider{})
+
+	mockQuerier.On("GetSolvencyCheckByTokenForUpdate", mock.Anything, mock.Anything).Return(pendingCheck(), nil)
+	mockQuerier.On("SetSolvencyCheckBankConnection", mock.Anything, mock.Anything).Return(nil)
+	expectAnalysisLookups(mockQuerier, pendingCheck())
+	// Cancelled and refunded while the bank data was fetched: the late result is not stored
+	mockQuerier.On("UpdateSolvencyCheckResult", mock.Anything, mock.MatchedBy(func(p postgres.UpdateSolvencyCheckResultParams) bool {
+		return p.CurrentStatus.SolvencyStatus == postgres.SolvencyStatusPending
+	})).Return(int64(0), nil)
+
+	err := svc.CompleteBankConsent(context.Background(), "tok", "c1")
+	assert.ErrorIs(t, err, ErrCheckAlreadyProcessed)
+}
+
+func TestCompleteBankConsent_RejectsForeignCode(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	svc := NewSolvencyService(passthroughTxManager{q: mockQuerier}, nil, zap.NewNop(), nil, &stubBankProvider{})
+
+	mockQuerier.On("GetSolvencyCheckByTokenForUpdate", mock.Anything, mock.Anything).Return(pendingCheck(), nil)
+
+	// A code obtained for another check's consent must not complete this one
+	err := svc.CompleteBankConsent(context.Background(), "tok", "c2")
+	assert.ErrorIs(t, err, ErrBankConsentMismatch)
+	mockQuerier.AssertNotCalled(t, "SetSolvencyCheckBankConnection", mock.Anything, mock.Anything)
+}
+
+func TestCompleteBankConsent_PendingData(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	svc := NewSolvencyService(passthroughTxManager{q: mockQuerier}, nil, zap.NewNop(), nil, &stubBankProvider{pending: true})
+
+	mockQuerier.On("GetSolvencyCheckByTokenForUpdate", mock.Anything, mock.Anything).Return(pendingCheck(), nil)
+	mockQuerier.On("SetSolvencyCheckBankConnection", mock.Anything, mock.Anything).Return(nil)
+
+	err := svc.CompleteBankConsent(context.Background(), "tok", "c1")
+	assert.NoError(t, err)
+	mockQuerier.AssertNotCalled(t, "UpdateSolvencyCheckResult", mock.Anything, mock.Anything)
+}
+
+func TestHandleOpenBankingWebhook_IgnoresReplay(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	event := &WebhookEvent{ID: "evt-1", Type: WebhookTransactionsReady, ConnectionID: "conn-1"}
+	svc := NewSolvencyService(passthroughTxManager{q: mockQuerier}, nil, zap.NewNop(), nil, &stubBankProvider{event: event})
+
+	mockQuerier.On("RecordWebhookEvent", mock.Anything, postgres.RecordWebhookEventParams{
+		Provider: "stub", EventID: "evt-1", EventType: WebhookTransactionsReady,
+	}).Return(int64(0), nil)
+
+	err := svc.HandleOpenBankingWebhook(context.Background(), []byte(`{}`), http.Header{})
+	assert.NoError(t, err)
+	mockQuerier.AssertNotCalled(t, "GetSolvencyCheckByBankConnection", mock.Anything, mock.Anything)
+}
+
+func TestHandleOpenBankingWebhook_ProcessesPendingCheck(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	event := &WebhookEvent{ID: "evt-1", Type: WebhookTransactionsReady, ConnectionID: "conn-1"}
+	svc := NewSolvencyService(passthroughTxManager{q: mockQuerier}, nil, zap.NewNop(), nil, &stubBankProvider{event: event})
+
+	mockQuerier.On("RecordWebhookEvent", mock.Anything, mock.Anything).Return(int64(1), nil)
+	mockQuerier.On("GetSolvencyCheckByBankConnection", mock.Anything, postgres.GetSolvencyCheckByBankConnectionParams{
+		BankProvider:     pgtype.Text{String: "stub", Valid: true},
+		BankConnectionID: pgtype.Text{String: "conn-1", Valid: true},
+	}).Return(pendingCheck(), nil)
+	expectAnalysisLookups(mockQuerier, pendingCheck())
+	mockQuerier.On("UpdateSolvencyCheckResult", mock.Anything, mock.Anything).Return(int64(1), nil)
+
+	err := svc.HandleOpenBankingWebhook(context.Background(), []byte(`{}`), http.Header{})
+	assert.NoError(t, err)
+	mockQuerier.AssertCalled(t, "UpdateSolvencyCheckResult", mock.Anything, mock.Anything)
+}
+
+func TestHandleOpenBankingWebhook_InvalidSignature(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	svc := NewSolvencyService(passthroughTxManager{q: mockQuerier}, nil, zap.NewNop(), nil, &stubBankProvider{})
+
+	err := svc.HandleOpenBankingWebhook(context.Background(), []byte(`{}`), http.Header{})
+	assert.Error(t, err)
+	mockQuerier.AssertNotCalled(t, "RecordWebhookEvent", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,809 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net/http"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+	"seculoc-back/internal/platform/email"
+	"seculoc-back/internal/platform/logger"
+)
+
+// Payment intent statuses. Anything else than succeeded or failed waits for the provider's webhook.
+const (
+	PaymentSucceeded      = "succeeded"
+	PaymentFailed         = "failed"
+	PaymentProcessing     = "processing"      // SEPA debit submitted to the bank
+	PaymentRequiresAction = "requires_action" // 3-D Secure authentication by the customer
+)
+
+// Payment webhook event types understood by PaymentService.
+const (
+	WebhookPaymentSucceeded = "payment_intent.succeeded"
+	WebhookPaymentFailed    = "payment_intent.payment_failed"
+)
+
+// Related entities of a payment in the transactions table.
+const (
+	paymentForInvoice = "invoice"
+	paymentForPack    = "pack_purchase" // before packs were invoiced
+	paymentRefund     = "refund"
+)
+
+var (
+	// ErrPaymentUnavailable is returned before anything is recorded: the request can be retried as is.
+	ErrPaymentUnavailable = errors.New("payment provider not configured")
+	ErrPaymentFailed      = errors.New("payment failed")
+	ErrPaymentPending     = errors.New("payment pending")
+	ErrPaymentNotFound    = errors.New("payment not found")
+	ErrRefundNotAllowed   = errors.New("payment cannot be refunded")
+)
+
+type CustomerRequest struct {
+	Email string
+	Name  string
+	// Reference is an opaque identifier of the user, echoed back by the provider
+	Reference string
+}
+
+type PaymentCustomer struct {
+	ID string
+}
+
+// PaymentIntentRequest asks the provider to charge a customer. Without PaymentMethodID, the customer's
+// SEPA mandate (MandateID) or default payment method is charged off-session.
+type PaymentIntentRequest struct {
+	CustomerID      string
+	AmountCents     int32
+	Currency        string
+	Description     string
+	Reference       string
+	PaymentMethodID string
+	MandateID       string
+	// IdempotencyKey makes the provider return the intent already created for the same key instead of
+	// charging again, e.g. after a crash between the charge and its recording.
+	IdempotencyKey string
+}
+
+type PaymentIntent struct {
+	ID     string
+	Status string
+	// ClientSecret and NextActionURL let the frontend complete a 3-D Secure authentication
+	ClientSecret  string
+	NextActionURL string
+	FailureReason string
+}
+
+type MandateRequest struct {
+	CustomerID    string
+	IBAN          string
+	AccountHolder string
+}
+
+type Mandate struct {
+	ID    string
+	Last4 string
+}
+
+// RefundRequest asks the provider to give back part or all of a payment.
+type RefundRequest struct {
+	PaymentIntentID string
+	AmountCents     int32
+	// IdempotencyKey makes the provider return the refund already made for the same key instead of
+	// refunding again, e.g. when a refund interrupted before its recording is resumed.
+	IdempotencyKey string
+}
+
+type Refund struct {
+	ID          string
+	AmountCents int32
+}
+
+// PaymentEvent is a provider notification, already authenticated by ParseWebhook.
+type PaymentEvent struct {
+	ID              string
+	Type            string
+	PaymentIntentID string
+	FailureReason   string
+}
+
+// PaymentProvider abstracts a payment service provider (Stripe or compatible): customers, payment
+// intents, SEPA mandates, refunds and signed webhooks. No card or IBAN is stored by the service.
+type PaymentProvider interface {
+	Name() string
+	CreateCustomer(ctx context.Context, req CustomerRequest) (*PaymentCustomer, error)
+	CreatePaymentIntent(ctx context.Context, req PaymentIntentRequest) (*PaymentIntent, error)
+	CreateSepaMandate(ctx context.Context, req MandateRequest) (*Mandate, error)
+	Refund(ctx context.Context, req RefundRequest) (*Refund, error)
+	// ParseWebhook verifies the signature of a notification and decodes it.
+	ParseWebhook(payload []byte, headers http.Header) (*PaymentEvent, error)
+}
+
+// PaymentResult is the outcome of a charge as shown to the customer.
+type PaymentResult struct {
+	TransactionID int32  `json:"transaction_id"`
+	Status        string `json:"status"`
+	AmountCents   int32  `json:"amount_cents"`
+	ClientSecret  string `json:"client_secret,omitempty"`
+	NextActionURL string `json:"next_action_url,omitempty"`
+	FailureReason string `json:"failure_reason,omitempty"`
+}
+
+// Pending tells whether the payment waits for the customer or the bank.
+func (r *PaymentResult) Pending() bool {
+	return r.Status != PaymentSucceeded && r.Status != PaymentFailed
+}
+
+// charge describes what is paid for.
+type charge struct {
+	amountCents     int32
+	description     string
+	entityType      string
+	entityID        int32
+	paymentMethodID string
+	idempotencyKey  string
+}
+
+// invoiceCharge is the charge of an invoice. Its idempotency key changes with each payment attempt, so
+// that a retry after a failure is a new charge but an interrupted attempt is never charged twice.
+func invoiceCharge(invoice postgres.Invoice, paymentMethodID string) charge {
+	return charge{
+		amountCents:     invoice.AmountCents,
+		description:     invoice.Description,
+		entityType:      paymentForInvoice,
+		entityID:        invoice.ID,
+		paymentMethodID: paymentMethodID,
+		idempotencyKey:  fmt.Sprintf("invoice-%d-%d", invoice.ID, invoice.Attempts+1),
+	}
+}
+
+// PaymentService charges customers through the provider and records every payment in transactions.
+// What is paid for (credits, a subscription period) is granted only once the payment succeeded,
+// immediately or when the provider's webhook confirms it.
+type PaymentService struct {
+	txManager   TxManager
+	provider    PaymentProvider
+	emailSender email.EmailSender
+}
+
+func NewPaymentService(txManager TxManager, provider PaymentProvider, emailSender email.EmailSender, l *zap.Logger) *PaymentService {
+	return &PaymentService{
+		txManager:   txManager,
+		provider:    provider,
+		emailSender: emailSender,
+	}
+}
+
+func transactionStatus(paymentStatus string) string {
+	switch paymentStatus {
+	case PaymentSucceeded:
+		return "success"
+	case PaymentFailed:
+		return "failed"
+	default:
+		return "pending"
+	}
+}
+
+func centsToNumeric(cents int32) pgtype.Numeric {
+	return pgtype.Numeric{Int: big.NewInt(int64(cents)), Exp: -2, Valid: true}
+}
+
+// numericToCents converts an amount in euros to cents.
+func numericToCents(n pgtype.Numeric) (int32, error) {
+	f, err := n.Float64Value()
+	if err != nil {
+		return 0, err
+	}
+	return int32(math.Round(f.Float64 * 100)), nil
+}
+
+// paymentCustomer returns the provider customer of a user, creating it on first payment.
+func (s *PaymentService) paymentCustomer(ctx context.Context, q postgres.Querier, user postgres.User) (string, error) {
+	if user.StripeCustomerID.Valid && user.StripeCustomerID.String != "" {
+		return user.StripeCustomerID.String, nil
+	}
+	customer, err := s.provider.CreateCustomer(ctx, CustomerRequest{
+		Email:     user.Email,
+		Name:      fmt.Sprintf("%s %s", user.FirstName.String, user.LastName.String),
+		Reference: fmt.Sprintf("user-%d", user.ID),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create payment customer: %w", err)
+	}
+	err = q.SetUserPaymentCustomer(ctx, postgres.SetUserPaymentCustomerParams{
+		ID:               user.ID,
+		StripeCustomerID: pgtype.Text{String: customer.ID, Valid: true},
+	})
+	return customer.ID, err
+}
+
+// available tells whether payments can be taken. Callers check it before recording what is to be charged,
+// so that ErrPaymentUnavailable leaves nothing behind.
+func (s *PaymentService) available() bool {
+	return s != nil && s.provider != nil
+}
+
+// charge creates a payment intent and records it. The caller grants what is paid for when the
+// result succeeded; pending payments are completed by HandleWebhook.
+func (s *PaymentService) charge(ctx context.Context, q postgres.Querier, userID int32, c charge) (*PaymentResult, error) {
+	if !s.available() {
+		return nil, ErrPaymentUnavailable
+	}
+
+	user, err := q.GetUserById(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	customerID, err := s.paymentCustomer(ctx, q, user)
+	if err != nil {
+		return nil, err
+	}
+
+	req := PaymentIntentRequest{
+		CustomerID:      customerID,
+		AmountCents:     c.amountCents,
+		Currency:        "EUR",
+		Description:     c.description,
+		Reference:       fmt.Sprintf("%s-%d", c.entityType, c.entityID),
+		PaymentMethodID: c.paymentMethodID,
+		IdempotencyKey:  c.idempotencyKey,
+	}
+	if req.PaymentMethodID == "" {
+		req.MandateID = user.SepaMandateID.String
+	}
+	intent, err := s.provider.CreatePaymentIntent(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create payment intent: %w", err)
+	}
+
+	tx, err := q.CreatePaymentTransaction(ctx, postgres.CreatePaymentTransactionParams{
+		UserID:                pgtype.Int4{Int32: userID, Valid: true},
+		RelatedEntityType:     pgtype.Text{String: c.entityType, Valid: true},
+		RelatedEntityID:       pgtype.Int4{Int32: c.entityID, Valid: true},
+		Amount:                centsToNumeric(c.amountCents),
+		Direction:             pgtype.Text{String: "inbound", Valid: true},
+		StripePaymentIntentID: pgtype.Text{String: intent.ID, Valid: true},
+		Status:                pgtype.Text{String: transactionStatus(intent.Status), Valid: true},
+		FailureReason:         pgtype.Text{String: intent.FailureReason, Valid: intent.FailureReason != ""},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record payment: %w", err)
+	}
+
+	logger.FromContext(ctx).Info("payment created",
+		zap.Int32("user_id", userID),
+		zap.Int32("transaction_id", tx.ID),
+		zap.String("status", intent.Status),
+		zap.Int32("amount_cents", c.amountCents),
+	)
+	return &PaymentResult{
+		TransactionID: tx.ID,
+		Status:        intent.Status,
+		AmountCents:   c.amountCents,
+		ClientSecret:  intent.ClientSecret,
+		NextActionURL: intent.NextActionURL,
+		FailureReason: intent.FailureReason,
+	}, nil
+}
+
+// grantPackCredits credits the user with the credits of a pack, on the wallet of propertyID when set
+// (a top-up of its vacancy credits) or on the global wallet.
+func grantPackCredits(ctx context.Context, q postgres.Querier, userID int32, propertyID pgtype.Int4, pack postgres.CatalogItem) error {
+	if propertyID.Valid {
+		// The property before its wallet
+		if _, err := q.GetPropertyForUpdate(ctx, propertyID.Int32); err != nil {
+			return err
+		}
+	}
+	_, err := q.CreateCreditTransaction(ctx, postgres.CreateCreditTransactionParams{
+		UserID:          pgtype.Int4{Int32: userID, Valid: true},
+		PropertyID:      propertyID,
+		Amount:          pack.IncludedCredits,
+		TransactionType: "pack_purchase",
+		Description:     pgtype.Text{String: fmt.Sprintf("Purchase %s", pack.Code), Valid: true},
+	})
+	return err
+}
+
+// reversePackCredits takes back from the wallet they were granted to the credits of a pack paid
+// paidCents, in proportion to the amountCents refunded after refundedCents already were. Credits
+// spent in the meantime cannot be taken back: the reversal stops at the wallet's balance.
+func reversePackCredits(ctx context.Context, q postgres.Querier, userID int32, propertyID pgtype.Int4, packID, paidCents, refundedCents, amountCents int32) error {
+	pack, err := q.GetCatalogItem(ctx, packID)
+	if err != nil {
+		return err
+	}
+	credits := pack.IncludedCredits*(refundedCents+amountCents)/paidCents - pack.IncludedCredits*refundedCents/paidCents
+
+	var balance int32
+	if propertyID.Valid {
+		prop, err := q.GetPropertyForUpdate(ctx, propertyID.Int32)
+		if err != nil {
+			return err
+		}
+		balance = prop.VacancyCredits
+	} else {
+		if _, err := q.GetUserForUpdate(ctx, userID); err != nil {
+			return err
+		}
+		balance, err = q.GetUserCreditBalanceForUpdate(ctx, userID)
+		if err != nil && err != pgx.ErrNoRows {
+			return err
+		}
+	}
+	reversed := min(credits, balance)
+	if reversed < credits {
+		logger.FromContext(ctx).Warn("refunded pack credits already spent",
+			zap.Int32("user_id", userID), zap.Int32("credits", credits), zap.Int32("reversed", reversed))
+	}
+	if reversed <= 0 {
+		return nil
+	}
+	_, err = q.CreateCreditTransaction(ctx, postgres.CreateCreditTransactionParams{
+		UserID:          pgtype.Int4{Int32: userID, Valid: true},
+		PropertyID:      propertyID,
+		Amount:          -reversed,
+		TransactionType: "pack_refund",
+		Description:     pgtype.Text{String: fmt.Sprintf("Refund %s", pack.Code), Valid: true},
+	})
+	return err
+}
+
+// settlePurchase records the outcome of the payment of a one-off purchase (a credit pack): once paid,
+// the invoice is issued and the credits are granted. A failed purchase is not retried.
+func settlePurchase(ctx context.Context, q postgres.Querier, invoice postgres.Invoice, payErr error) error {
+	if payErr != nil {
+		_, err := q.MarkInvoiceFailed(ctx, invoice.ID)
+		if err == pgx.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+
+	paid, err := q.MarkInvoicePaid(ctx, invoice.ID)
+	if err == pgx.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := issueInvoice(ctx, q, paid, vatRateBps); err != nil {
+		return err
+	}
+	pack, err := q.GetCatalogItem(ctx, invoice.CatalogItemID.Int32)
+	if err != nil {
+		return err
+	}
+	return grantPackCredits(ctx, q, invoice.UserID, invoice.CreditPropertyID, pack)
+}
+
+// invoiceLegacyPack invoices a pack paid before packs were invoiced, and points the payment to the
+// invoice: the purchase is then settled, and later refunded, like any other (see settlePurchase). Such
+// payments predate property top-ups: the credits go to the global wallet.
+func invoiceLegacyPack(ctx context.Context, q postgres.Querier, payment postgres.Transaction) (postgres.Invoice, error) {
+	pack, err := q.GetCatalogItem(ctx, payment.RelatedEntityID.Int32)
+	if err != nil {
+		return postgres.Invoice{}, err
+	}
+	amount, err := numericToCents(payment.Amount)
+	if err != nil {
+		return postgres.Invoice{}, err
+	}
+	invoice, err := q.CreateInvoice(ctx, postgres.CreateInvoiceParams{
+		UserID:        payment.UserID.Int32,
+		CatalogItemID: pgtype.Int4{Int32: pack.ID, Valid: true},
+		Description:   pack.Name,
+		AmountCents:   amount,
+		Status:        "pending",
+	})
+	if err != nil {
+		return invoice, fmt.Errorf("failed to invoice pack: %w", err)
+	}
+	err = q.SetPaymentTransactionEntity(ctx, postgres.SetPaymentTransactionEntityParams{
+		ID:                payment.ID,
+		RelatedEntityType: pgtype.Text{String: paymentForInvoice, Valid: true},
+		RelatedEntityID:   pgtype.Int4{Int32: invoice.ID, Valid: true},
+	})
+	return invoice, err
+}
+
+// BuyPack invoices and charges a credit pack of the catalog (e.g. pack_20). The credits are added and
+// the invoice issued when the payment succeeds: right away, or once the customer completed 3-D Secure.
+// With a propertyID, the credits top up the wallet of that property instead of the global wallet.
+func (s *PaymentService) BuyPack(ctx context.Context, userID int32, packType, paymentMethodID string, propertyID int32) (int32, *PaymentResult, error) {
+	if s.provider == nil {
+		return 0, nil, ErrPaymentUnavailable
+	}
+	log := logger.FromContext(ctx)
+
+	// The invoice is committed, and claimed, before the provider is called: an interrupted purchase
+	// is never charged without a trace
+	var pack postgres.CatalogItem
+	var invoice *postgres.Invoice
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		var err error
+		pack, err = activeCatalogItem(ctx, q, CatalogKindPack, packType)
+		if err != nil {
+			return err
+		}
+		var wallet pgtype.Int4
+		if propertyID != 0 {
+			prop, err := q.GetProperty(ctx, propertyID)
+			if err == pgx.ErrNoRows || (err == nil && prop.OwnerID.Int32 != userID) {
+				return ErrCreditAccountNotFound
+			}
+			if err != nil {
+				return err
+			}
+			if !prop.IsActive.Bool {
+				return ErrInactivePropertyWallet
+			}
+			wallet = pgtype.Int4{Int32: propertyID, Valid: true}
+		}
+		created, err := q.CreateInvoice(ctx, postgres.CreateInvoiceParams{
+			UserID:           userID,
+			CatalogItemID:    pgtype.Int4{Int32: pack.ID, Valid: true},
+			Description:      pack.Name,
+			AmountCents:      pack.PriceCents.Int32,
+			Status:           "open",
+			CreditPropertyID: wallet,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to invoice pack: %w", err)
+		}
+		invoice, err = claimInvoice(ctx, q, created.ID)
+		return err
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	result, err := s.payInvoice(ctx, *invoice, paymentMethodID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	log.Info("credit pack purchase",
+		zap.Int("user_id", int(userID)),
+		zap.String("pack", packType),
+		zap.Int32("property_id", propertyID),
+		zap.String("payment_status", result.Status),
+		zap.Int("cost_cents", int(pack.PriceCents.Int32)),
+	)
+	return pack.IncludedCredits, result, nil
+}
+
+// payInvoice charges an invoice committed and claimed beforehand (see claimInvoice) with paymentMethodID
+// (or the saved payment method), then records the outcome through settleInvoice. Each step has its own
+// transaction: none is held open while the provider is called. A payment awaiting 3-D Secure or the
+// bank leaves the invoice pending until the provider's webhook settles it; a charge that could not be
+// made fails the invoice and its error is returned.
+func (s *PaymentService) payInvoice(ctx context.Context, invoice postgres.Invoice, paymentMethodID string) (*PaymentResult, error) {
+	var result *PaymentResult
+	chargeErr := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		var err error
+		result, err = s.charge(ctx, q, invoice.UserID, invoiceCharge(invoice, paymentMethodID))
+		return err
+	})
+
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		payErr := chargeErr
+		switch {
+		case chargeErr != nil:
+		case result.Status == PaymentFailed:
+			payErr = fmt.Errorf("%w: %s", ErrPaymentFailed, result.FailureReason)
+		case result.Status != PaymentSucceeded:
+			return q.MarkInvoicePending(ctx, invoice.ID)
+		}
+		_, err := settleInvoice(ctx, q, invoice, payErr)
+		return err
+	})
+	if chargeErr != nil {
+		if err != nil {
+			logger.FromContext(ctx).Error("failed to settle invoice", zap.Int32("invoice_id", invoice.ID), zap.Error(err))
+		}
+		return nil, chargeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to settle invoice %d: %w", invoice.ID, err)
+	}
+	return result, nil
+}
+
+// Collect charges a renewal invoice off-session, with the user's SEPA mandate or default payment method.
+// It implements PaymentCollector: ErrPaymentPending means the webhook will settle the invoice.
+func (s *PaymentService) Collect(ctx context.Context, invoice postgres.Invoice) error {
+	var result *PaymentResult
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		var err error
+		result, err = s.charge(ctx, q, invoice.UserID, invoiceCharge(invoice, ""))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	switch result.Status {
+	case PaymentSucceeded:
+		return nil
+	case PaymentFailed:
+		return fmt.Errorf("%w: %s", ErrPaymentFailed, result.FailureReason)
+	default:
+		return ErrPaymentPending
+	}
+}
+
+// SetupSepaMandate registers the user's IBAN with the provider. Renewals are then debited from it.
+func (s *PaymentService) SetupSepaMandate(ctx context.Context, userID int32, iban, accountHolder string) (*Mandate, error) {
+	if s.provider == nil {
+		return nil, ErrPaymentUnavailable
+	}
+
+	var mandate *Mandate
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		user, err := q.GetUserForUpdate(ctx, userID)
+		if err != nil {
+			return err
+		}
+		customerID, err := s.paymentCustomer(ctx, q, user)
+		if err != nil {
+			return err
+		}
+		mandate, err = s.provider.CreateSepaMandate(ctx, MandateRequest{CustomerID: customerID, IBAN: iban, AccountHolder: accountHolder})
+		if err != nil {
+			return fmt.Errorf("failed to create sepa mandate: %w", err)
+		}
+		return q.SetUserSepaMandate(ctx, postgres.SetUserSepaMandateParams{
+			ID:            userID,
+			SepaMandateID: pgtype.Text{String: mandate.ID, Valid: true},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mandate, nil
+}
+
+// Refund gives back amountCents (the whole remaining amount when 0) of a successful payment and issues
+// a credit note on the invoice paid. The credits of a refunded pack are taken back in proportion (see
+// reversePackCredits); subscription periods already granted are left as they are.
+//
+// The refund is recorded as pending before the provider is called, with an idempotency key derived from
+// its row, and settled afterwards. A refund left pending (provider unreachable, instance stopped) is
+// resumed by the next call for the same payment rather than made twice.
+func (s *PaymentService) Refund(ctx context.Context, transactionID, amountCents int32) (*postgres.Transaction, error) {
+	if s.provider == nil {
+		return nil, ErrPaymentUnavailable
+	}
+
+	var refundTx postgres.Transaction
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		payment, err := refundablePayment(ctx, q, transactionID)
+		if err != nil {
+			return err
+		}
+
+		refundTx, err = q.GetPendingRefund(ctx, pgtype.Int4{Int32: payment.ID, Valid: true})
+		if err == nil {
+			pending, err := numericToCents(refundTx.Amount)
+			if err != nil {
+				return err
+			}
+			if amountCents != 0 && amountCents != pending {
+				return fmt.Errorf("%w: a refund of %d cents is pending", ErrRefundNotAllowed, pending)
+			}
+			return nil
+		}
+		if err != pgx.ErrNoRows {
+			return err
+		}
+
+		paid, err := numericToCents(payment.Amount)
+		if err != nil {
+			return err
+		}
+		refunded, err := q.GetRefundedCents(ctx, pgtype.Int4{Int32: payment.ID, Valid: true})
+		if err != nil {
+			return err
+		}
+		remaining := paid - refunded
+		if amountCents == 0 {
+			amountCents = remaining
+		}
+		if amountCents <= 0 || amountCents > remaining {
+			return fmt.Errorf("%w: %d cents left to refund", ErrRefundNotAllowed, remaining)
+		}
+
+		refundTx, err = q.CreatePaymentTransaction(ctx, postgres.CreatePaymentTransactionParams{
+			UserID:                payment.UserID,
+			RelatedEntityType:     pgtype.Text{String: paymentRefund, Valid: true},
+			RelatedEntityID:       pgtype.Int4{Int32: payment.ID, Valid: true},
+			Amount:                centsToNumeric(amountCents),
+			Direction:             pgtype.Text{String: "outbound", Valid: true},
+			StripePaymentIntentID: payment.StripePaymentIntentID,
+			Status:                pgtype.Text{String: "pending", Valid: true},
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	amountCents, err = numericToCents(refundTx.Amount)
+	if err != nil {
+		return nil, err
+	}
+	refund, err := s.provider.Refund(ctx, RefundRequest{
+		PaymentIntentID: refundTx.StripePaymentIntentID.String,
+		AmountCents:     amountCents,
+		IdempotencyKey:  fmt.Sprintf("refund-%d", refundTx.ID),
+	})
+	if err != nil {
+		// Left pending: the next refund of the payment retries it with the same key
+		return nil, fmt.Errorf("failed to refund payment: %w", err)
+	}
+
+	refundID := refundTx.ID
+	err = s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		payment, err := refundablePayment(ctx, q, transactionID)
+		if err != nil {
+			return err
+		}
+		paid, err := numericToCents(payment.Amount)
+		if err != nil {
+			return err
+		}
+		refunded, err := q.GetRefundedCents(ctx, pgtype.Int4{Int32: payment.ID, Valid: true})
+		if err != nil {
+			return err
+		}
+		refundTx, err = q.SettleRefundTransaction(ctx, postgres.SettleRefundTransactionParams{
+			ID:             refundID,
+			StripeRefundID: pgtype.Text{String: refund.ID, Valid: true},
+		})
+		if err == pgx.ErrNoRows {
+			// Settled by a concurrent call resuming the same refund
+			refundTx, err = q.GetPaymentTransactionForUpdate(ctx, refundID)
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		entityID := payment.RelatedEntityID.Int32
+		switch payment.RelatedEntityType.String {
+		case paymentForInvoice:
+			if err := creditInvoice(ctx, q, entityID, amountCents); err != nil {
+				return err
+			}
+			invoice, err := q.GetInvoice(ctx, entityID)
+			if err != nil || invoice.SubscriptionID.Valid || !invoice.CatalogItemID.Valid {
+				return err
+			}
+			return reversePackCredits(ctx, q, invoice.UserID, invoice.CreditPropertyID, invoice.CatalogItemID.Int32, paid, refunded, amountCents)
+		case paymentForPack:
+			return reversePackCredits(ctx, q, payment.UserID.Int32, pgtype.Int4{}, entityID, paid, refunded, amountCents)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to settle refund %d: %w", refundID, err)
+	}
+
+	logger.FromContext(ctx).Info("payment refunded", zap.Int32("transaction_id", transactionID), zap.Int32("amount_cents", amountCents))
+	return &refundTx, nil
+}
+
+// refundablePayment locks a successful inbound payment.
+func refundablePayment(ctx context.Context, q postgres.Querier, transactionID int32) (postgres.Transaction, error) {
+	payment, err := q.GetPaymentTransactionForUpdate(ctx, transactionID)
+	if err == pgx.ErrNoRows {
+		return payment, ErrPaymentNotFound
+	}
+	if err != nil {
+		return payment, err
+	}
+	if payment.Direction.String != "inbound" || payment.Status.String != "success" || !payment.StripePaymentIntentID.Valid {
+		return payment, fmt.Errorf("%w: only successful payments can be refunded", ErrRefundNotAllowed)
+	}
+	return payment, nil
+}
+
+// HandleWebhook authenticates a provider notification and settles the pending payment it is about:
+// the credits or the subscription period paid for are granted on success. Each event is processed once.
+func (s *PaymentService) HandleWebhook(ctx context.Context, payload []byte, headers http.Header) error {
+	if s.provider == nil {
+		return ErrPaymentUnavailable
+	}
+	log := logger.FromContext(ctx)
+
+	event, err := s.provider.ParseWebhook(payload, headers)
+	if err != nil {
+		return err
+	}
+
+	var renewal invoiceSettlement
+	err = s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		rows, err := q.RecordWebhookEvent(ctx, postgres.RecordWebhookEventParams{
+			Provider:  s.provider.Name(),
+			EventID:   event.ID,
+			EventType: event.Type,
+		})
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			log.Info("webhook replay ignored", zap.String("event_id", event.ID))
+			return nil
+		}
+		if event.Type != WebhookPaymentSucceeded && event.Type != WebhookPaymentFailed {
+			return nil
+		}
+
+		payment, err := q.GetPaymentTransactionByIntentForUpdate(ctx, pgtype.Text{String: event.PaymentIntentID, Valid: true})
+		if err == pgx.ErrNoRows {
+			log.Warn("webhook for unknown payment", zap.String("payment_intent_id", event.PaymentIntentID))
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if payment.Status.String != "pending" {
+			// Settled synchronously or by an earlier event
+			return nil
+		}
+
+		var payErr error
+		status := "success"
+		if event.Type == WebhookPaymentFailed {
+			payErr = fmt.Errorf("%w: %s", ErrPaymentFailed, event.FailureReason)
+			status = "failed"
+		}
+		err = q.SetPaymentTransactionStatus(ctx, postgres.SetPaymentTransactionStatusParams{
+			ID:            payment.ID,
+			Status:        pgtype.Text{String: status, Valid: true},
+			FailureReason: pgtype.Text{String: event.FailureReason, Valid: event.FailureReason != ""},
+		})
+		if err != nil {
+			return err
+		}
+
+		entityID := payment.RelatedEntityID.Int32
+		switch payment.RelatedEntityType.String {
+		case paymentForPack:
+			if payErr != nil {
+				return nil
+			}
+			invoice, err := invoiceLegacyPack(ctx, q, payment)
+			if err != nil {
+				return err
+			}
+			return settlePurchase(ctx, q, invoice, nil)
+		case paymentForInvoice:
+			invoice, err := q.GetInvoice(ctx, entityID)
+			if err != nil {
+				return err
+			}
+			renewal, err = settleInvoice(ctx, q, invoice, payErr)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if renewal.failed {
+		notifyRenewalFailure(ctx, s.emailSender, renewal)
+	}
+	return nil
+}
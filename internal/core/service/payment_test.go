@@ -0,0 +1,489 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+)
+
+type mockPaymentProvider struct {
+	mock.Mock
+}
+
+func (m *mockPaymentProvider) Name() string { return "mock" }
+
+func (m *mockPaymentProvider) CreateCustomer(ctx context.Context, req CustomerRequest) (*PaymentCustomer, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*PaymentCustomer), args.Error(1)
+}
+
+func (m *mockPaymentProvider) CreatePaymentIntent(ctx context.Context, req PaymentIntentRequest) (*PaymentIntent, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*PaymentIntent), args.Error(1)
+}
+
+func (m *mockPaymentProvider) CreateSepaMandate(ctx context.Context, req MandateRequest) (*Mandate, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Mandate), args.Error(1)
+}
+
+func (m *mockPaymentProvider) Refund(ctx context.Context, req RefundRequest) (*Refund, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Refund), args.Error(1)
+}
+
+func (m *mockPaymentProvider) ParseWebhook(payload []byte, headers http.Header) (*PaymentEvent, error) {
+	args := m.Called(payload, headers)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*PaymentEvent), args.Error(1)
+}
+
+func setupPayments() (*PaymentService, *MockQuerier, *mockPaymentProvider) {
+	mockQuerier := new(MockQuerier)
+	provider := new(mockPaymentProvider)
+	svc := NewPaymentService(passthroughTxManager{q: mockQuerier}, provider, new(mockEmailSender), zap.NewNop())
+	return svc, mockQuerier, provider
+}
+
+// payingUser is a user already known by the provider.
+var payingUser = postgres.User{ID: 1, Email: "owner@test.com", StripeCustomerID: pgtype.Text{String: "cus_1", Valid: true}}
+
+// packInvoice is the invoice of a pack_20 bought by payingUser.
+var packInvoice = postgres.Invoice{
+	ID: 21, UserID: 1, CatalogItemID: pgtype.Int4{Int32: catalogPack20.ID, Valid: true},
+	Description: catalogPack20.Name, AmountCents: 1990, Status: "open",
+}
+
+// expectPackInvoice expects packInvoice to be created, then claimed before it is charged.
+func expectPackInvoice(q *MockQuerier) {
+	q.On("CreateInvoice", mock.Anything, postgres.CreateInvoiceParams{
+		UserID: 1, CatalogItemID: pgtype.Int4{Int32: catalogPack20.ID, Valid: true},
+		Description: catalogPack20.Name, AmountCents: 1990, Status: "open",
+	}).Return(packInvoice, nil)
+	expectClaim(q, packInvoice)
+}
+
+func TestBuyPack_Success(t *testing.T) {
+	svc, mockQuerier, provider := setupPayments()
+	mockQuerier.On("GetActiveCatalogItem", mock.Anything, postgres.GetActiveCatalogItemParams{Kind: CatalogKindPack, Code: "pack_20"}).
+		Return(catalogPack20, nil)
+	// First payment: the provider customer is created and kept
+	mockQuerier.On("GetUserById", mock.Anything, int32(1)).Return(postgres.User{ID: 1, Email: "owner@test.com"}, nil)
+	provider.On("CreateCustomer", mock.Anything, mock.MatchedBy(func(req CustomerRequest) bool {
+		return req.Email == "owner@test.com" && req.Reference == "user-1"
+	})).Return(&PaymentCustomer{ID: "cus_1"}, nil)
+	mockQuerier.On("SetUserPaymentCustomer", mock.Anything, postgres.SetUserPaymentCustomerParams{
+		ID: 1, StripeCustomerID: pgtype.Text{String: "cus_1", Valid: true},
+	}).Return(nil)
+	expectPackInvoice(mockQuerier)
+	provider.On("CreatePaymentIntent", mock.Anything, PaymentIntentRequest{
+		CustomerID: "cus_1", AmountCents: 1990, Currency: "EUR", Description: catalogPack20.Name,
+		Reference: "invoice-21", PaymentMethodID: "pm_card_visa", IdempotencyKey: "invoice-21-1",
+	}).Return(&PaymentIntent{ID: "pi_1", Status: PaymentSucceeded}, nil)
+	mockQuerier.On("CreatePaymentTransaction", mock.Anything, mock.MatchedBy(func(p postgres.CreatePaymentTransactionParams) bool {
+		amount, _ := numericToCents(p.Amount)
+		return p.UserID.Int32 == 1 && amount == 1990 && p.Direction.String == "inbound" &&
+			p.RelatedEntityType.String == "invoice" && p.RelatedEntityID.Int32 == 21 &&
+			p.StripePaymentIntentID.String == "pi_1" && p.Status.String == "success"
+	})).Return(postgres.Transaction{ID: 9}, nil)
+	// Paid: invoice issued, then credits granted
+	mockQuerier.On("MarkInvoicePaid", mock.Anything, int32(21)).Return(postgres.Invoice{ID: 21, UserID: 1, AmountCents: 1990, Status: "paid"}, nil)
+	expectInvoiceIssued(mockQuerier, invoiceSeries, 21, 1)
+	mockQuerier.On("GetCatalogItem", mock.Anything, catalogPack20.ID).Return(catalogPack20, nil)
+	mockQuerier.On("CreateCreditTransaction", mock.Anything, mock.MatchedBy(func(arg postgres.CreateCreditTransactionParams) bool {
+		return arg.UserID.Int32 == 1 && arg.Amount == 20 && arg.TransactionType == "pack_purchase"
+	})).Return(postgres.CreditTransaction{}, nil)
+
+	amount, payment, err := svc.BuyPack(context.Background(), 1, "pack_20", "pm_card_visa", 0)
+
+	require.NoError(t, err)
+	assert.Equal(t, int32(20), amount)
+	assert.Equal(t, int32(9), payment.TransactionID)
+	assert.Equal(t, PaymentSucceeded, payment.Status)
+	mockQuerier.AssertExpectations(t)
+}
+
+func TestBuyPack_ReadsCatalog(t *testing.T) {
+	svc, mockQuerier, provider := setupPayments()
+	bigger := catalogPack20
+	bigger.IncludedCredits = 25
+	mockQuerier.On("GetActiveCatalogItem", mock.Anything, postgres.GetActiveCatalogItemParams{Kind: CatalogKindPack, Code: "pack_20"}).Return(bigger, nil)
+	mockQuerier.On("GetActiveCatalogItem", mock.Anything, postgres.GetActiveCatalogItemParams{Kind: CatalogKindPack, Code: "invalid"}).Return(postgres.CatalogItem{}, pgx.ErrNoRows)
+	mockQuerier.On("GetUserById", mock.Anything, int32(1)).Return(payingUser, nil)
+	mockQuerier.On("CreateInvoice", mock.Anything, mock.Anything).Return(packInvoice, nil)
+	expectClaim(mockQuerier, packInvoice)
+	provider.On("CreatePaymentIntent", mock.Anything, mock.Anything).Return(&PaymentIntent{ID: "pi_1", Status: PaymentSucceeded}, nil)
+	mockQuerier.On("CreatePaymentTransaction", mock.Anything, mock.Anything).Return(postgres.Transaction{ID: 9}, nil)
+	mockQuerier.On("MarkInvoicePaid", mock.Anything, int32(21)).Return(postgres.Invoice{ID: 21, UserID: 1, Status: "paid"}, nil)
+	expectInvoiceIssued(mockQuerier, invoiceSeries, 21, 1)
+	// The credits of the pack as invoiced
+	mockQuerier.On("GetCatalogItem", mock.Anything, catalogPack20.ID).Return(bigger, nil)
+	mockQuerier.On("CreateCreditTransaction", mock.Anything, mock.MatchedBy(func(p postgres.CreateCreditTransactionParams) bool {
+		return p.Amount == 25
+	})).Return(postgres.CreditTransaction{}, nil).Once()
+
+	amount, _, err := svc.BuyPack(context.Background(), 1, "pack_20", "", 0)
+	require.NoError(t, err)
+	assert.Equal(t, int32(25), amount)
+
+	_, _, err = svc.BuyPack(context.Background(), 1, "invalid", "", 0)
+	assert.ErrorIs(t, err, ErrCatalogItemNotFound)
+	mockQuerier.AssertExpectations(t)
+}
+
+func TestBuyPack_NoCreditsUntilPaid(t *testing.T) {
+	for _, intent := range []PaymentIntent{
+		{ID: "pi_1", Status: PaymentFailed, FailureReason: "card_declined"},
+		{ID: "pi_1", Status: PaymentRequiresAction, ClientSecret: "pi_1_secret"},
+	} {
+		t.Run(intent.Status, func(t *testing.T) {
+			svc, mockQuerier, provider := setupPayments()
+			mockQuerier.On("GetActiveCatalogItem", mock.Anything, mock.Anything).Return(catalogPack20, nil)
+			mockQuerier.On("GetUserById", mock.Anything, int32(1)).Return(payingUser, nil)
+			expectPackInvoice(mockQuerier)
+			provider.On("CreatePaymentIntent", mock.Anything, mock.Anything).Return(&intent, nil)
+			mockQuerier.On("CreatePaymentTransaction", mock.Anything, mock.MatchedBy(func(p postgres.CreatePaymentTransactionParams) bool {
+				return p.Status.String == transactionStatus(intent.Status) && p.FailureReason.String == intent.FailureReason
+			})).Return(postgres.Transaction{ID: 9}, nil)
+			// Declined: not retried. Waiting for 3-D Secure: settled by the webhook
+			mockQuerier.On("MarkInvoiceFailed", mock.Anything, int32(21)).Return(postgres.Invoice{ID: 21, Status: "failed"}, nil).Maybe()
+			mockQuerier.On("MarkInvoicePending", mock.Anything, int32(21)).Return(nil).Maybe()
+
+			_, payment, err := svc.BuyPack(context.Background(), 1, "pack_20", "pm_card", 0)
+
+			require.NoError(t, err)
+			assert.Equal(t, intent.Status, payment.Status)
+			assert.Equal(t, intent.ClientSecret, payment.ClientSecret)
+			mockQuerier.AssertNotCalled(t, "CreateCreditTransaction", mock.Anything, mock.Anything)
+			mockQuerier.AssertNotCalled(t, "IssueInvoice", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestBuyPack_TopsUpPropertyWallet(t *testing.T) {
+	svc, mockQuerier, provider := setupPayments()
+	mockQuerier.On("GetActiveCatalogItem", mock.Anything, mock.Anything).Return(catalogPack20, nil)
+	mockQuerier.On("GetProperty", mock.Anything, int32(5)).Return(postgres.Property{ID: 5, OwnerID: pgtype.Int4{Int32: 1, Valid: true}, IsActive: pgtype.Bool{Bool: true, Valid: true}}, nil)
+	mockQuerier.On("GetProperty", mock.Anything, int32(6)).Return(postgres.Property{ID: 6, OwnerID: pgtype.Int4{Int32: 2, Valid: true}, IsActive: pgtype.Bool{Bool: true, Valid: true}}, nil)
+	mockQuerier.On("GetProperty", mock.Anything, int32(7)).Return(postgres.Property{ID: 7, OwnerID: pgtype.Int4{Int32: 1, Valid: true}, IsActive: pgtype.Bool{Bool: false, Valid: true}}, nil)
+	mockQuerier.On("GetUserById", mock.Anything, int32(1)).Return(payingUser, nil)
+	// The wallet to top up is kept on the invoice until it is paid
+	topUp := packInvoice
+	topUp.CreditPropertyID = pgtype.Int4{Int32: 5, Valid: true}
+	mockQuerier.On("CreateInvoice", mock.Anything, mock.MatchedBy(func(p postgres.CreateInvoiceParams) bool {
+		return p.CreditPropertyID == topUp.CreditPropertyID
+	})).Return(topUp, nil).Once()
+	expectClaim(mockQuerier, topUp)
+	provider.On("CreatePaymentIntent", mock.Anything, mock.Anything).Return(&PaymentIntent{ID: "pi_1", Status: PaymentSucceeded}, nil)
+	mockQuerier.On("CreatePaymentTransaction", mock.Anything, mock.Anything).Return(postgres.Transaction{ID: 9}, nil)
+	mockQuerier.On("MarkInvoicePaid", mock.Anything, int32(21)).Return(topUp, nil)
+	expectInvoiceIssued(mockQuerier, invoiceSeries, 21, 1)
+	mockQuerier.On("GetCatalogItem", mock.Anything, catalogPack20.ID).Return(catalogPack20, nil)
+	mockQuerier.On("GetPropertyForUpdate", mock.Anything, int32(5)).Return(postgres.Property{ID: 5}, nil).Once()
+	mockQuerier.On("CreateCreditTransaction", mock.Anything, mock.MatchedBy(func(arg postgres.CreateCreditTransactionParams) bool {
+		return arg.UserID.Int32 == 1 && arg.PropertyID.Int32 == 5 && arg.Amount == 20 && arg.TransactionType == "pack_purchase"
+	})).Return(postgres.CreditTransaction{}, nil).Once()
+
+	amount, _, err := svc.BuyPack(context.Background(), 1, "pack_20", "", 5)
+	require.NoError(t, err)
+	assert.Equal(t, int32(20), amount)
+
+	_, _, err = svc.BuyPack(context.Background(), 1, "pack_20", "", 6)
+	assert.ErrorIs(t, err, ErrCreditAccountNotFound)
+	_, _, err = svc.BuyPack(context.Background(), 1, "pack_20", "", 7)
+	assert.ErrorIs(t, err, ErrInactivePropertyWallet)
+	mockQuerier.AssertExpectations(t)
+}
+
+func TestBuyPack_Unavailable(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	svc := NewPaymentService(passthroughTxManager{q: mockQuerier}, nil, nil, zap.NewNop())
+
+	_, _, err := svc.BuyPack(context.Background(), 1, "pack_20", "", 0)
+
+	assert.ErrorIs(t, err, ErrPaymentUnavailable)
+	// Nothing invoiced without a provider to charge it
+	mockQuerier.AssertNotCalled(t, "CreateInvoice", mock.Anything, mock.Anything)
+}
+
+func TestBuyPack_ChargeErrorFailsInvoice(t *testing.T) {
+	svc, mockQuerier, provider := setupPayments()
+	mockQuerier.On("GetActiveCatalogItem", mock.Anything, mock.Anything).Return(catalogPack20, nil)
+	mockQuerier.On("GetUserById", mock.Anything, int32(1)).Return(payingUser, nil)
+	expectPackInvoice(mockQuerier)
+	provider.On("CreatePaymentIntent", mock.Anything, mock.Anything).Return(nil, errors.New("provider unreachable"))
+	// The invoice, committed before the charge, is not left claimed
+	mockQuerier.On("MarkInvoiceFailed", mock.Anything, int32(21)).Return(postgres.Invoice{ID: 21, Status: "failed"}, nil).Once()
+
+	_, _, err := svc.BuyPack(context.Background(), 1, "pack_20", "pm_card", 0)
+
+	require.Error(t, err)
+	mockQuerier.AssertExpectations(t)
+	mockQuerier.AssertNotCalled(t, "CreateCreditTransaction", mock.Anything, mock.Anything)
+}
+
+func TestCollect_UsesSepaMandate(t *testing.T) {
+	svc, mockQuerier, provider := setupPayments()
+	user := payingUser
+	user.SepaMandateID = pgtype.Text{String: "mandate_1", Valid: true}
+	invoice := postgres.Invoice{ID: 11, UserID: 1, AmountCents: 2990, Description: "Premium (monthly)"}
+	mockQuerier.On("GetUserById", mock.Anything, int32(1)).Return(user, nil)
+	provider.On("CreatePaymentIntent", mock.Anything, mock.MatchedBy(func(req PaymentIntentRequest) bool {
+		return req.MandateID == "mandate_1" && req.Reference == "invoice-11"
+	})).Return(&PaymentIntent{ID: "pi_1", Status: PaymentProcessing}, nil).Once()
+	provider.On("CreatePaymentIntent", mock.Anything, mock.Anything).Return(&PaymentIntent{ID: "pi_2", Status: PaymentFailed, FailureReason: "insufficient_funds"}, nil)
+	mockQuerier.On("CreatePaymentTransaction", mock.Anything, mock.Anything).Return(postgres.Transaction{ID: 9}, nil)
+
+	// SEPA debits are confirmed by webhook
+	assert.ErrorIs(t, svc.Collect(context.Background(), invoice), ErrPaymentPending)
+	assert.ErrorIs(t, svc.Collect(context.Background(), invoice), ErrPaymentFailed)
+}
+
+func pendingPayment(entityType string, entityID int32) postgres.Transaction {
+	return postgres.Transaction{
+		ID: 9, UserID: pgtype.Int4{Int32: 1, Valid: true},
+		RelatedEntityType: pgtype.Text{String: entityType, Valid: true}, RelatedEntityID: pgtype.Int4{Int32: entityID, Valid: true},
+		StripePaymentIntentID: pgtype.Text{String: "pi_1", Valid: true}, Status: pgtype.Text{String: "pending", Valid: true},
+	}
+}
+
+func TestHandleWebhook_GrantsPackOnce(t *testing.T) {
+	svc, mockQuerier, provider := setupPayments()
+	payload := []byte(`{}`)
+	provider.On("ParseWebhook", payload, mock.Anything).Return(&PaymentEvent{ID: "evt_1", Type: WebhookPaymentSucceeded, PaymentIntentID: "pi_1"}, nil)
+	mockQuerier.On("RecordWebhookEvent", mock.Anything, postgres.RecordWebhookEventParams{Provider: "mock", EventID: "evt_1", EventType: WebhookPaymentSucceeded}).
+		Return(int64(1), nil).Once()
+	mockQuerier.On("RecordWebhookEvent", mock.Anything, mock.Anything).Return(int64(0), nil)
+	// A pack paid before packs were invoiced
+	legacy := pendingPayment(paymentForPack, catalogPack20.ID)
+	legacy.Amount = centsToNumeric(1990)
+	mockQuerier.On("GetPaymentTransactionByIntentForUpdate", mock.Anything, pgtype.Text{String: "pi_1", Valid: true}).Return(legacy, nil)
+	mockQuerier.On("SetPaymentTransactionStatus", mock.Anything, postgres.SetPaymentTransactionStatusParams{
+		ID: 9, Status: pgtype.Text{String: "success", Valid: true},
+	}).Return(nil)
+	mockQuerier.On("GetCatalogItem", mock.Anything, catalogPack20.ID).Return(catalogPack20, nil)
+	// It is invoiced, then settled like the purchases invoiced upfront
+	invoice := packInvoice
+	invoice.Status = "pending"
+	mockQuerier.On("CreateInvoice", mock.Anything, postgres.CreateInvoiceParams{
+		UserID: 1, CatalogItemID: pgtype.Int4{Int32: catalogPack20.ID, Valid: true}, Description: catalogPack20.Name,
+		AmountCents: 1990, Status: "pending",
+	}).Return(invoice, nil).Once()
+	mockQuerier.On("SetPaymentTransactionEntity", mock.Anything, postgres.SetPaymentTransactionEntityParams{
+		ID: 9, RelatedEntityType: pgtype.Text{String: paymentForInvoice, Valid: true}, RelatedEntityID: pgtype.Int4{Int32: 21, Valid: true},
+	}).Return(nil).Once()
+	mockQuerier.On("MarkInvoicePaid", mock.Anything, int32(21)).Return(invoice, nil).Once()
+	mockQuerier.On("GetUserById", mock.Anything, int32(1)).Return(payingUser, nil)
+	expectInvoiceIssued(mockQuerier, invoiceSeries, 21, 4)
+	mockQuerier.On("CreateCreditTransaction", mock.Anything, mock.MatchedBy(func(arg postgres.CreateCreditTransactionParams) bool {
+		return arg.UserID.Int32 == 1 && !arg.PropertyID.Valid && arg.Amount == 20 && arg.TransactionType == "pack_purchase"
+	})).Return(postgres.CreditTransaction{}, nil).Once()
+
+	require.NoError(t, svc.HandleWebhook(context.Background(), payload, http.Header{}))
+	// Replayed delivery
+	require.NoError(t, svc.HandleWebhook(context.Background(), payload, http.Header{}))
+	mockQuerier.AssertExpectations(t)
+}
+
+func TestHandleWebhook_IssuesPackInvoice(t *testing.T) {
+	svc, mockQuerier, provider := setupPayments()
+	pending := packInvoice
+	pending.Status = "pending"
+	provider.On("ParseWebhook", mock.Anything, mock.Anything).Return(&PaymentEvent{ID: "evt_3", Type: WebhookPaymentSucceeded, PaymentIntentID: "pi_1"}, nil)
+	mockQuerier.On("RecordWebhookEvent", mock.Anything, mock.Anything).Return(int64(1), nil)
+	mockQuerier.On("GetPaymentTransactionByIntentForUpdate", mock.Anything, mock.Anything).Return(pendingPayment(paymentForInvoice, 21), nil)
+	mockQuerier.On("SetPaymentTransactionStatus", mock.Anything, mock.Anything).Return(nil)
+	mockQuerier.On("GetInvoice", mock.Anything, int32(21)).Return(pending, nil)
+	mockQuerier.On("MarkInvoicePaid", mock.Anything, int32(21)).Return(postgres.Invoice{ID: 21, UserID: 1, Status: "paid"}, nil)
+	mockQuerier.On("GetUserById", mock.Anything, int32(1)).Return(payingUser, nil)
+	expectInvoiceIssued(mockQuerier, invoiceSeries, 21, 3)
+	mockQuerier.On("GetCatalogItem", mock.Anything, catalogPack20.ID).Return(catalogPack20, nil)
+	mockQuerier.On("CreateCreditTransaction", mock.Anything, mock.MatchedBy(func(arg postgres.CreateCreditTransactionParams) bool {
+		return arg.UserID.Int32 == 1 && arg.Amount == 20 && arg.TransactionType == "pack_purchase"
+	})).Return(postgres.CreditTransaction{}, nil).Once()
+
+	require.NoError(t, svc.HandleWebhook(context.Background(), []byte(`{}`), http.Header{}))
+	mockQuerier.AssertExpectations(t)
+	mockQuerier.AssertNotCalled(t, "GetSubscriptionForUpdate", mock.Anything, mock.Anything)
+}
+
+func TestHandleWebhook_FailedFirstPaymentCancelsSubscription(t *testing.T) {
+	svc, mockQuerier, provider := setupPayments()
+	mockEmail := svc.emailSender.(*mockEmailSender)
+	invoice := postgres.Invoice{ID: 7, UserID: 1, SubscriptionID: pgtype.Int4{Int32: 2, Valid: true}, AmountCents: 2990, Status: "pending"}
+	provider.On("ParseWebhook", mock.Anything, mock.Anything).Return(&PaymentEvent{
+		ID: "evt_2", Type: WebhookPaymentFailed, PaymentIntentID: "pi_1", FailureReason: "authentication_failed",
+	}, nil)
+	mockQuerier.On("RecordWebhookEvent", mock.Anything, mock.Anything).Return(int64(1), nil)
+	mockQuerier.On("GetPaymentTransactionByIntentForUpdate", mock.Anything, mock.Anything).Return(pendingPayment(paymentForInvoice, 7), nil)
+	mockQuerier.On("SetPaymentTransactionStatus", mock.Anything, postgres.SetPaymentTransactionStatusParams{
+		ID: 9, Status: pgtype.Text{String: "failed", Valid: true}, FailureReason: pgtype.Text{String: "authentication_failed", Valid: true},
+	}).Return(nil)
+	mockQuerier.On("GetInvoice", mock.Anything, int32(7)).Return(invoice, nil)
+	mockQuerier.On("GetSubscriptionForUpdate", mock.Anything, int32(2)).Return(postgres.Subscription{
+		ID: 2, Status: pgtype.Text{String: "incomplete", Valid: true},
+	}, nil)
+	mockQuerier.On("MarkInvoiceFailed", mock.Anything, int32(7)).Return(postgres.Invoice{ID: 7, Attempts: 1}, nil)
+	mockQuerier.On("VoidInvoice", mock.Anything, int32(7)).Return(nil)
+	// Never started: ended without history
+	mockQuerier.On("EndSubscription", mock.Anything, mock.MatchedBy(func(p postgres.EndSubscriptionParams) bool { return p.ID == 2 })).Return(nil)
+	mockQuerier.On("GetUserById", mock.Anything, int32(1)).Return(payingUser, nil)
+	mockEmail.On("SendNotification", mock.Anything, "owner@test.com", "Échec du paiement de votre abonnement", mock.Anything).Return(nil)
+
+	require.NoError(t, svc.HandleWebhook(context.Background(), []byte(`{}`), http.Header{}))
+	mockQuerier.AssertExpectations(t)
+	mockEmail.AssertExpectations(t)
+}
+
+// expectRefundRecorded expects a pending refund of amountCents of payment 9 to be recorded as
+// transaction 10, then settled with the provider's refund re_1.
+func expectRefundRecorded(q *MockQuerier, provider *mockPaymentProvider, amountCents int32) {
+	pending := postgres.Transaction{
+		ID: 10, UserID: pgtype.Int4{Int32: 1, Valid: true}, Amount: centsToNumeric(amountCents),
+		RelatedEntityType: pgtype.Text{String: paymentRefund, Valid: true}, RelatedEntityID: pgtype.Int4{Int32: 9, Valid: true},
+		Direction: pgtype.Text{String: "outbound", Valid: true}, Status: pgtype.Text{String: "pending", Valid: true},
+		StripePaymentIntentID: pgtype.Text{String: "pi_1", Valid: true},
+	}
+	q.On("CreatePaymentTransaction", mock.Anything, mock.MatchedBy(func(p postgres.CreatePaymentTransactionParams) bool {
+		amount, _ := numericToCents(p.Amount)
+		return amount == amountCents && p.Direction.String == "outbound" && p.RelatedEntityType.String == "refund" &&
+			p.RelatedEntityID.Int32 == 9 && p.Status.String == "pending" && !p.StripeRefundID.Valid
+	})).Return(pending, nil).Once()
+	provider.On("Refund", mock.Anything, RefundRequest{PaymentIntentID: "pi_1", AmountCents: amountCents, IdempotencyKey: "refund-10"}).
+		Return(&Refund{ID: "re_1", AmountCents: amountCents}, nil).Once()
+	settled := pending
+	settled.Status = pgtype.Text{String: "success", Valid: true}
+	settled.StripeRefundID = pgtype.Text{String: "re_1", Valid: true}
+	q.On("SettleRefundTransaction", mock.Anything, postgres.SettleRefundTransactionParams{
+		ID: 10, StripeRefundID: pgtype.Text{String: "re_1", Valid: true},
+	}).Return(settled, nil).Once()
+}
+
+func TestRefund_ReversesPackCredits(t *testing.T) {
+	svc, mockQuerier, provider := setupPayments()
+	payment := postgres.Transaction{
+		ID: 9, UserID: pgtype.Int4{Int32: 1, Valid: true}, Amount: centsToNumeric(1990),
+		RelatedEntityType: pgtype.Text{String: paymentForInvoice, Valid: true}, RelatedEntityID: pgtype.Int4{Int32: 21, Valid: true},
+		Direction: pgtype.Text{String: "inbound", Valid: true}, Status: pgtype.Text{String: "success", Valid: true},
+		StripePaymentIntentID: pgtype.Text{String: "pi_1", Valid: true},
+	}
+	topUp := packInvoice
+	topUp.Status = "paid"
+	topUp.CreditPropertyID = pgtype.Int4{Int32: 5, Valid: true}
+	mockQuerier.On("GetPaymentTransactionForUpdate", mock.Anything, int32(9)).Return(payment, nil)
+	mockQuerier.On("GetPendingRefund", mock.Anything, pgtype.Int4{Int32: 9, Valid: true}).Return(postgres.Transaction{}, pgx.ErrNoRows)
+	mockQuerier.On("GetRefundedCents", mock.Anything, mock.Anything).Return(int32(0), nil).Twice()
+	expectRefundRecorded(mockQuerier, provider, 995)
+	// Not issued: no credit note
+	mockQuerier.On("GetInvoice", mock.Anything, int32(21)).Return(topUp, nil)
+	mockQuerier.On("GetCatalogItem", mock.Anything, catalogPack20.ID).Return(catalogPack20, nil)
+
+	// Half the pack refunded: half its credits leave the property wallet they topped up
+	mockQuerier.On("GetPropertyForUpdate", mock.Anything, int32(5)).Return(postgres.Property{ID: 5, VacancyCredits: 25}, nil).Once()
+	mockQuerier.On("CreateCreditTransaction", mock.Anything, postgres.CreateCreditTransactionParams{
+		UserID: pgtype.Int4{Int32: 1, Valid: true}, PropertyID: topUp.CreditPropertyID, Amount: -10,
+		TransactionType: "pack_refund", Description: pgtype.Text{String: "Refund pack_20", Valid: true},
+	}).Return(postgres.CreditTransaction{}, nil).Once()
+	_, err := svc.Refund(context.Background(), 9, 995)
+	require.NoError(t, err)
+
+	// The other half: the wallet only has 4 credits left, the others were spent
+	mockQuerier.On("GetRefundedCents", mock.Anything, mock.Anything).Return(int32(995), nil).Twice()
+	expectRefundRecorded(mockQuerier, provider, 995)
+	mockQuerier.On("GetPropertyForUpdate", mock.Anything, int32(5)).Return(postgres.Property{ID: 5, VacancyCredits: 4}, nil).Once()
+	mockQuerier.On("CreateCreditTransaction", mock.Anything, mock.MatchedBy(func(p postgres.CreateCreditTransactionParams) bool {
+		return p.PropertyID.Int32 == 5 && p.Amount == -4 && p.TransactionType == "pack_refund"
+	})).Return(postgres.CreditTransaction{}, nil).Once()
+	_, err = svc.Refund(context.Background(), 9, 995)
+	require.NoError(t, err)
+	mockQuerier.AssertExpectations(t)
+	provider.AssertExpectations(t)
+}
+
+func TestRefund_CappedByPayment(t *testing.T) {
+	svc, mockQuerier, provider := setupPayments()
+	payment := postgres.Transaction{
+		ID: 9, UserID: pgtype.Int4{Int32: 1, Valid: true}, Amount: centsToNumeric(1990),
+		Direction: pgtype.Text{String: "inbound", Valid: true}, Status: pgtype.Text{String: "success", Valid: true},
+		StripePaymentIntentID: pgtype.Text{String: "pi_1", Valid: true},
+	}
+	mockQuerier.On("GetPaymentTransactionForUpdate", mock.Anything, int32(9)).Return(payment, nil)
+	mockQuerier.On("GetPaymentTransactionForUpdate", mock.Anything, int32(404)).Return(postgres.Transaction{}, pgx.ErrNoRows)
+	mockQuerier.On("GetPendingRefund", mock.Anything, pgtype.Int4{Int32: 9, Valid: true}).Return(postgres.Transaction{}, pgx.ErrNoRows)
+	mockQuerier.On("GetRefundedCents", mock.Anything, pgtype.Int4{Int32: 9, Valid: true}).Return(int32(1000), nil)
+	expectRefundRecorded(mockQuerier, provider, 990)
+
+	_, err := svc.Refund(context.Background(), 9, 1000)
+	assert.ErrorIs(t, err, ErrRefundNotAllowed)
+
+	// The remaining amount by default
+	refund, err := svc.Refund(context.Background(), 9, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int32(10), refund.ID)
+	assert.Equal(t, "re_1", refund.StripeRefundID.String)
+
+	_, err = svc.Refund(context.Background(), 404, 0)
+	assert.ErrorIs(t, err, ErrPaymentNotFound)
+}
+
+func TestRefund_ResumesPendingRefund(t *testing.T) {
+	svc, mockQuerier, provider := setupPayments()
+	payment := postgres.Transaction{
+		ID: 9, UserID: pgtype.Int4{Int32: 1, Valid: true}, Amount: centsToNumeric(1990),
+		Direction: pgtype.Text{String: "inbound", Valid: true}, Status: pgtype.Text{String: "success", Valid: true},
+		StripePaymentIntentID: pgtype.Text{String: "pi_1", Valid: true},
+	}
+	mockQuerier.On("GetPaymentTransactionForUpdate", mock.Anything, int32(9)).Return(payment, nil)
+	mockQuerier.On("GetPendingRefund", mock.Anything, pgtype.Int4{Int32: 9, Valid: true}).Return(postgres.Transaction{}, pgx.ErrNoRows).Once()
+	mockQuerier.On("GetRefundedCents", mock.Anything, pgtype.Int4{Int32: 9, Valid: true}).Return(int32(0), nil)
+	pending := postgres.Transaction{
+		ID: 10, Amount: centsToNumeric(500), Status: pgtype.Text{String: "pending", Valid: true},
+		StripePaymentIntentID: pgtype.Text{String: "pi_1", Valid: true},
+	}
+	mockQuerier.On("CreatePaymentTransaction", mock.Anything, mock.Anything).Return(pending, nil).Once()
+	request := RefundRequest{PaymentIntentID: "pi_1", AmountCents: 500, IdempotencyKey: "refund-10"}
+	provider.On("Refund", mock.Anything, request).Return(nil, errors.New("provider unreachable")).Once()
+
+	// The provider did not answer: the refund stays pending, nothing is settled
+	_, err := svc.Refund(context.Background(), 9, 500)
+	require.Error(t, err)
+	mockQuerier.AssertNotCalled(t, "SettleRefundTransaction", mock.Anything, mock.Anything)
+
+	// Another amount cannot be refunded meanwhile; the pending refund is resumed with the same key
+	mockQuerier.On("GetPendingRefund", mock.Anything, pgtype.Int4{Int32: 9, Valid: true}).Return(pending, nil)
+	_, err = svc.Refund(context.Background(), 9, 700)
+	assert.ErrorIs(t, err, ErrRefundNotAllowed)
+
+	provider.On("Refund", mock.Anything, request).Return(&Refund{ID: "re_1", AmountCents: 500}, nil).Once()
+	mockQuerier.On("SettleRefundTransaction", mock.Anything, postgres.SettleRefundTransactionParams{
+		ID: 10, StripeRefundID: pgtype.Text{String: "re_1", Valid: true},
+	}).Return(pending, nil).Once()
+	_, err = svc.Refund(context.Background(), 9, 0)
+	require.NoError(t, err)
+	mockQuerier.AssertNumberOfCalls(t, "CreatePaymentTransaction", 1)
+	provider.AssertExpectations(t)
+}
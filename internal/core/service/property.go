@@ -13,6 +13,9 @@ import (
 	"seculoc-back/internal/platform/logger"
 )
 
+// propertyVacancyCredits are the credits granted to the wallet of each new property.
+const propertyVacancyCredits = 20
+
 type PropertyService struct {
 	txManager TxManager
 	log       *zap.Logger
@@ -32,10 +35,10 @@ func (s *PropertyService) CreateProperty(ctx context.Context, userID int32, name
 	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
 		// 1. Get User Subscription
 		sub, err := q.GetUserSubscription(ctx, pgtype.Int4{Int32: userID, Valid: true})
+		if err == pgx.ErrNoRows || (err == nil && sub.Status.String == "incomplete") {
+			return fmt.Errorf("user has no active subscription")
+		}
 		if err != nil {
-			if err == pgx.ErrNoRows {
-				return fmt.Errorf("user has no active subscription")
-			}
 			return err
 		}
 
@@ -99,6 +102,18 @@ func (s *PropertyService) CreateProperty(ctx context.Context, userID int32, name
 		prop = p
 		log.Info("property created", zap.Int32("property_id", p.ID), zap.Int32("user_id", userID))
 
+		grant, err := q.CreateCreditTransaction(ctx, postgres.CreateCreditTransactionParams{
+			UserID:          pgtype.Int4{Int32: userID, Valid: true},
+			PropertyID:      pgtype.Int4{Int32: p.ID, Valid: true},
+			Amount:          propertyVacancyCredits,
+			TransactionType: "property_grant",
+			Description:     pgtype.Text{String: fmt.Sprintf("Vacancy credits: %d credits", propertyVacancyCredits), Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to grant property credits: %w", err)
+		}
+		prop.VacancyCredits = grant.BalanceAfter.Int32
+
 		// 5. Initial Bonus for first property
 		// Check if it's the very first property
 		if sub.PlanType == postgres.SubPlanDiscovery && pType == postgres.PropertyTypeLongTerm {
@@ -38,6 +38,7 @@ func TestCreateProperty_Discovery_GrantsBonus(t *testing.T) {
 	// Mock 3: Create Property
 	expectedProp := postgres.Property{ID: 1, RentalType: postgres.PropertyTypeLongTerm}
 	mockQuerier.On("CreateProperty", mock.Anything, mock.Anything).Return(expectedProp, nil)
+	expectPropertyGrant(mockQuerier, 1)
 
 	// Mock 4: HasReceivedInitialBonus (False)
 	mockQuerier.On("HasReceivedInitialBonus", mock.Anything, pgtype.Int4{Int32: userID, Valid: true}).Return(false, nil)
@@ -84,11 +85,12 @@ func TestCreateProperty_Discovery_BonusAlreadyReceived(t *testing.T) {
 
 	// Mock 3: Create Property
 	mockQuerier.On("CreateProperty", mock.Anything, mock.Anything).Return(postgres.Property{ID: 2, RentalType: postgres.PropertyTypeLongTerm}, nil)
+	expectPropertyGrant(mockQuerier, 2)
 
 	// Mock 4: HasReceivedInitialBonus (True)
 	mockQuerier.On("HasReceivedInitialBonus", mock.Anything, pgtype.Int4{Int32: userID, Valid: true}).Return(true, nil)
 
-	// Expect NO Credit Transaction
+	// Expect NO Welcome Bonus, only the property's vacancy credits
 
 	// WithTx
 	mockTx.On("WithTx", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
@@ -101,4 +103,5 @@ func TestCreateProperty_Discovery_BonusAlreadyReceived(t *testing.T) {
 
 	// Assert
 	assert.NoError(t, err)
+	mockQuerier.AssertNumberOfCalls(t, "CreateCreditTransaction", 1)
 }
@@ -0,0 +1,603 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+	"seculoc-back/internal/platform/email"
+	"seculoc-back/internal/platform/imaging"
+	"seculoc-back/internal/platform/logger"
+)
+
+const (
+	MediaKindPhoto      = "photo"
+	MediaKindDiagnostic = "diagnostic"
+
+	defaultMaxPhotoBytes    = 10 << 20 // 10 MB
+	defaultMaxDocumentBytes = 20 << 20 // 20 MB
+	defaultThumbnailSize    = 400      // px, longest side
+	defaultReminderDays     = 30
+)
+
+var (
+	ErrMediaTooLarge        = errors.New("file exceeds the maximum allowed size")
+	ErrUnsupportedMediaType = errors.New("unsupported file type")
+	ErrMediaNotFound        = errors.New("media not found")
+)
+
+// DiagnosticType describes a mandatory diagnostic ("Dossier de Diagnostic Technique").
+type DiagnosticType struct {
+	Label string
+	// Validity is the legal validity of the diagnostic, used when only the issue date is known.
+	// Zero means unlimited.
+	Validity time.Duration
+}
+
+const year = 365 * 24 * time.Hour
+
+// DiagnosticTypes lists the diagnostics an owner can attach to a property.
+var DiagnosticTypes = map[string]DiagnosticType{
+	"dpe":         {Label: "Diagnostic de performance énergétique (DPE)", Validity: 10 * year},
+	"erp":         {Label: "État des risques et pollutions (ERP)", Validity: year / 2},
+	"lead":        {Label: "Constat de risque d'exposition au plomb (CREP)", Validity: 6 * year},
+	"electricity": {Label: "État de l'installation intérieure d'électricité", Validity: 6 * year},
+	"gas":         {Label: "État de l'installation intérieure de gaz", Validity: 6 * year},
+}
+
+var photoContentTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+var documentContentTypes = map[string]string{
+	"application/pdf": ".pdf",
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+}
+
+type PropertyMediaService struct {
+	txManager   TxManager
+	storage     FileStorage
+	emailSender email.EmailSender
+	log         *zap.Logger
+
+	MaxPhotoBytes    int64
+	MaxDocumentBytes int64
+	ThumbnailSize    int
+	ReminderDays     int
+}
+
+func NewPropertyMediaService(txManager TxManager, storage FileStorage, emailSender email.EmailSender, l *zap.Logger) *PropertyMediaService {
+	s := &PropertyMediaService{
+		txManager:        txManager,
+		storage:          storage,
+		emailSender:      emailSender,
+		log:              l,
+		MaxPhotoBytes:    viper.GetInt64("MEDIA_MAX_PHOTO_BYTES"),
+		MaxDocumentBytes: viper.GetInt64("MEDIA_MAX_DOCUMENT_BYTES"),
+		ThumbnailSize:    defaultThumbnailSize,
+		ReminderDays:     viper.GetInt("DIAGNOSTIC_REMINDER_DAYS"),
+	}
+	if s.MaxPhotoBytes <= 0 {
+		s.MaxPhotoBytes = defaultMaxPhotoBytes
+	}
+	if s.MaxDocumentBytes <= 0 {
+		s.MaxDocumentBytes = defaultMaxDocumentBytes
+	}
+	if s.ReminderDays <= 0 {
+		s.ReminderDays = defaultReminderDays
+	}
+	return s
+}
+
+// MaxUploadBytes is the largest file accepted by any upload endpoint.
+func (s *PropertyMediaService) MaxUploadBytes() int64 {
+	return max(s.MaxPhotoBytes, s.MaxDocumentBytes)
+}
+
+type PropertyMediaDTO struct {
+	ID             int32  `json:"id"`
+	Kind           string `json:"kind"`
+	DiagnosticType string `json:"diagnostic_type,omitempty"`
+	Filename       string `json:"filename"`
+	ContentType    string `json:"content_type"`
+	SizeBytes      int64  `json:"size_bytes"`
+	Position       int32  `json:"position"`
+	IsCover        bool   `json:"is_cover"`
+	ExpiresAt      string `json:"expires_at,omitempty"`
+	IsExpired      bool   `json:"is_expired"`
+	URL            string `json:"url"`
+	ThumbnailURL   string `json:"thumbnail_url,omitempty"`
+	CreatedAt      string `json:"created_at"`
+}
+
+func toPropertyMediaDTO(m postgres.PropertyMedium) PropertyMediaDTO {
+	url := fmt.Sprintf("/api/v1/properties/%d/media/%d", m.PropertyID, m.ID)
+	dto := PropertyMediaDTO{
+		ID:             m.ID,
+		Kind:           m.MediaKind,
+		DiagnosticType: m.DiagnosticType.String,
+		Filename:       m.OriginalFilename,
+		ContentType:    m.ContentType,
+		SizeBytes:      m.SizeBytes,
+		Position:       m.Position,
+		IsCover:        m.IsCover,
+		URL:            url,
+		CreatedAt:      m.CreatedAt.Time.String(),
+	}
+	if m.ThumbnailKey.Valid {
+		dto.ThumbnailURL = url + "?variant=thumbnail"
+	}
+	if m.ExpiresAt.Valid {
+		dto.ExpiresAt = m.ExpiresAt.Time.Format("2006-01-02")
+		dto.IsExpired = m.ExpiresAt.Time.Before(time.Now())
+	}
+	return dto
+}
+
+// sniffContentType detects the real type of an upload, ignoring the name and the declared header.
+func sniffContentType(content []byte, allowed map[string]string) (string, string, error) {
+	contentType := http.DetectContentType(content)
+	// DetectContentType may append parameters (e.g. "; charset=utf-8")
+	contentType, _, _ = strings.Cut(contentType, ";")
+	ext, ok := allowed[contentType]
+	if !ok {
+		return "", "", fmt.Errorf("%w: %s", ErrUnsupportedMediaType, contentType)
+	}
+	return contentType, ext, nil
+}
+
+// checkPropertyOwner ensures the property exists, is active and belongs to ownerID.
+func checkPropertyOwner(ctx context.Context, q postgres.Querier, ownerID, propertyID int32) (postgres.Property, error) {
+	prop, err := q.GetProperty(ctx, propertyID)
+	return ownedActiveProperty(prop, err, ownerID)
+}
+
+// lockPropertyOwner is checkPropertyOwner with the property locked until the end of the transaction.
+func lockPropertyOwner(ctx context.Context, q postgres.Querier, ownerID, propertyID int32) (postgres.Property, error) {
+	prop, err := q.GetPropertyForUpdate(ctx, propertyID)
+	return ownedActiveProperty(prop, err, ownerID)
+}
+
+func ownedActiveProperty(prop postgres.Property, err error, ownerID int32) (postgres.Property, error) {
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return prop, fmt.Errorf("property not found or access denied")
+		}
+		return prop, err
+	}
+	if prop.OwnerID.Int32 != ownerID || !prop.IsActive.Bool {
+		return prop, fmt.Errorf("property not found or access denied")
+	}
+	return prop, nil
+}
+
+// UploadPhoto stores a property photo and its thumbnail.
+// The first photo of a property automatically becomes its cover.
+func (s *PropertyMediaService) UploadPhoto(ctx context.Context, ownerID, propertyID int32, filename string, content []byte) (*PropertyMediaDTO, error) {
+	log := logger.FromContext(ctx)
+
+	if len(content) == 0 {
+		return nil, fmt.Errorf("empty file")
+	}
+	if int64(len(content)) > s.MaxPhotoBytes {
+		return nil, ErrMediaTooLarge
+	}
+	contentType, ext, err := sniffContentType(content, photoContentTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	thumb, err := imaging.Thumbnail(content, s.ThumbnailSize)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedMediaType, err)
+	}
+
+	base := fmt.Sprintf("properties/%d/photos/%s", propertyID, generateToken())
+	storageKey := base + ext
+	thumbKey := base + "_thumb.jpg"
+
+	var media postgres.PropertyMedium
+	err = s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		// Concurrent uploads take their position (and the cover) one at a time
+		if _, err := lockPropertyOwner(ctx, q, ownerID, propertyID); err != nil {
+			return err
+		}
+
+		position, err := q.GetNextPhotoPosition(ctx, propertyID)
+		if err != nil {
+			return err
+		}
+
+		if _, err := s.storage.Save(storageKey, content); err != nil {
+			return fmt.Errorf("failed to store photo: %w", err)
+		}
+		if _, err := s.storage.Save(thumbKey, thumb); err != nil {
+			return fmt.Errorf("failed to store thumbnail: %w", err)
+		}
+
+		media, err = q.CreatePropertyMedia(ctx, postgres.CreatePropertyMediaParams{
+			PropertyID:       propertyID,
+			MediaKind:        MediaKindPhoto,
+			OriginalFilename: filepath.Base(filename),
+			StorageKey:       storageKey,
+			ThumbnailKey:     pgtype.Text{String: thumbKey, Valid: true},
+			ContentType:      contentType,
+			SizeBytes:        int64(len(content)),
+			Position:         position,
+			IsCover:          position == 0,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save photo: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		s.removeFiles(ctx, storageKey, thumbKey)
+		return nil, err
+	}
+
+	log.Info("property photo uploaded", zap.Int32("property_id", propertyID), zap.Int32("media_id", media.ID), zap.Int("size", len(content)))
+	dto := toPropertyMediaDTO(media)
+	return &dto, nil
+}
+
+type UploadDiagnosticParams struct {
+	OwnerID        int32
+	PropertyID     int32
+	DiagnosticType string
+	Filename       string
+	Content        []byte
+	// ExpiresAt takes precedence. Otherwise the expiry is derived from IssuedAt and the legal validity.
+	ExpiresAt *time.Time
+	IssuedAt  *time.Time
+}
+
+// UploadDiagnostic stores a diagnostic file. It replaces any previous diagnostic of the same type.
+func (s *PropertyMediaService) UploadDiagnostic(ctx context.Context, params UploadDiagnosticParams) (*PropertyMediaDTO, error) {
+	log := logger.FromContext(ctx)
+
+	diag, ok := DiagnosticTypes[params.DiagnosticType]
+	if !ok {
+		return nil, fmt.Errorf("invalid diagnostic type: %s", params.DiagnosticType)
+	}
+	if len(params.Content) == 0 {
+		return nil, fmt.Errorf("empty file")
+	}
+	if int64(len(params.Content)) > s.MaxDocumentBytes {
+		return nil, ErrMediaTooLarge
+	}
+	contentType, ext, err := sniffContentType(params.Content, documentContentTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	var expiresAt pgtype.Date
+	switch {
+	case params.ExpiresAt != nil:
+		expiresAt = pgtype.Date{Time: *params.ExpiresAt, Valid: true}
+	case params.IssuedAt != nil && diag.Validity > 0:
+		expiresAt = pgtype.Date{Time: params.IssuedAt.Add(diag.Validity), Valid: true}
+	}
+
+	storageKey := fmt.Sprintf("properties/%d/diagnostics/%s_%s%s", params.PropertyID, params.DiagnosticType, generateToken(), ext)
+
+	var media postgres.PropertyMedium
+	var replaced *postgres.PropertyMedium
+	err = s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		// Locked: two uploads of the same type replace one another instead of both finding no previous version
+		if _, err := lockPropertyOwner(ctx, q, params.OwnerID, params.PropertyID); err != nil {
+			return err
+		}
+
+		previous, err := q.GetPropertyDiagnosticByType(ctx, postgres.GetPropertyDiagnosticByTypeParams{
+			PropertyID:     params.PropertyID,
+			DiagnosticType: pgtype.Text{String: params.DiagnosticType, Valid: true},
+		})
+		if err == nil {
+			replaced = &previous
+			if err := q.DeletePropertyMedia(ctx, postgres.DeletePropertyMediaParams{ID: previous.ID, PropertyID: params.PropertyID}); err != nil {
+				return err
+			}
+		} else if err != pgx.ErrNoRows {
+			return err
+		}
+
+		if _, err := s.storage.Save(storageKey, params.Content); err != nil {
+			return fmt.Errorf("failed to store diagnostic: %w", err)
+		}
+
+		media, err = q.CreatePropertyMedia(ctx, postgres.CreatePropertyMediaParams{
+			PropertyID:       params.PropertyID,
+			MediaKind:        MediaKindDiagnostic,
+			DiagnosticType:   pgtype.Text{String: params.DiagnosticType, Valid: true},
+			OriginalFilename: filepath.Base(params.Filename),
+			StorageKey:       storageKey,
+			ContentType:      contentType,
+			SizeBytes:        int64(len(params.Content)),
+			ExpiresAt:        expiresAt,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save diagnostic: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		s.removeFiles(ctx, storageKey)
+		return nil, err
+	}
+
+	// The previous version is only removed once the new one is committed.
+	if replaced != nil {
+		s.removeFiles(ctx, replaced.StorageKey)
+	}
+
+	log.Info("property diagnostic uploaded",
+		zap.Int32("property_id", params.PropertyID),
+		zap.Int32("media_id", media.ID),
+		zap.String("diagnostic_type", params.DiagnosticType),
+	)
+	dto := toPropertyMediaDTO(media)
+	return &dto, nil
+}
+
+// ListMedia returns the photos (in display order) followed by the diagnostics of a property.
+func (s *PropertyMediaService) ListMedia(ctx context.Context, ownerID, propertyID int32) ([]PropertyMediaDTO, error) {
+	dtos := []PropertyMediaDTO{}
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		if _, err := checkPropertyOwner(ctx, q, ownerID, propertyID); err != nil {
+			return err
+		}
+		media, err := q.ListPropertyMedia(ctx, propertyID)
+		if err != nil {
+			return err
+		}
+		for _, m := range media {
+			dtos = append(dtos, toPropertyMediaDTO(m))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dtos, nil
+}
+
+// ReorderPhotos sets the display order of the photos. mediaIDs must contain every photo of the property.
+func (s *PropertyMediaService) ReorderPhotos(ctx context.Context, ownerID, propertyID int32, mediaIDs []int32) error {
+	return s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		if _, err := checkPropertyOwner(ctx, q, ownerID, propertyID); err != nil {
+			return err
+		}
+
+		media, err := q.ListPropertyMedia(ctx, propertyID)
+		if err != nil {
+			return err
+		}
+		photos := make(map[int32]bool)
+		for _, m := range media {
+			if m.MediaKind == MediaKindPhoto {
+				photos[m.ID] = true
+			}
+		}
+
+		if len(mediaIDs) != len(photos) {
+			return fmt.Errorf("order must list all %d photos of the property", len(photos))
+		}
+		seen := make(map[int32]bool)
+		for _, id := range mediaIDs {
+			if !photos[id] || seen[id] {
+				return fmt.Errorf("invalid photo id in order: %d", id)
+			}
+			seen[id] = true
+		}
+
+		for i, id := range mediaIDs {
+			err := q.UpdatePropertyMediaPosition(ctx, postgres.UpdatePropertyMediaPositionParams{
+				ID:         id,
+				PropertyID: propertyID,
+				Position:   int32(i),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SetCover selects the cover photo of a property.
+func (s *PropertyMediaService) SetCover(ctx context.Context, ownerID, propertyID, mediaID int32) error {
+	return s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		if _, err := checkPropertyOwner(ctx, q, ownerID, propertyID); err != nil {
+			return err
+		}
+
+		media, err := q.GetPropertyMedia(ctx, postgres.GetPropertyMediaParams{ID: mediaID, PropertyID: propertyID})
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return ErrMediaNotFound
+			}
+			return err
+		}
+		if media.MediaKind != MediaKindPhoto {
+			return fmt.Errorf("only photos can be used as cover")
+		}
+
+		if err := q.ClearPropertyCover(ctx, propertyID); err != nil {
+			return err
+		}
+		return q.SetPropertyMediaCover(ctx, postgres.SetPropertyMediaCoverParams{ID: mediaID, PropertyID: propertyID})
+	})
+}
+
+// DeleteMedia removes a media and its files. Deleting the cover promotes the next photo.
+func (s *PropertyMediaService) DeleteMedia(ctx context.Context, ownerID, propertyID, mediaID int32) error {
+	var deleted postgres.PropertyMedium
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		if _, err := checkPropertyOwner(ctx, q, ownerID, propertyID); err != nil {
+			return err
+		}
+
+		var err error
+		deleted, err = q.GetPropertyMedia(ctx, postgres.GetPropertyMediaParams{ID: mediaID, PropertyID: propertyID})
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return ErrMediaNotFound
+			}
+			return err
+		}
+
+		if err := q.DeletePropertyMedia(ctx, postgres.DeletePropertyMediaParams{ID: mediaID, PropertyID: propertyID}); err != nil {
+			return err
+		}
+
+		if deleted.IsCover {
+			remaining, err := q.ListPropertyMedia(ctx, propertyID)
+			if err != nil {
+				return err
+			}
+			for _, m := range remaining {
+				if m.MediaKind == MediaKindPhoto {
+					return q.SetPropertyMediaCover(ctx, postgres.SetPropertyMediaCoverParams{ID: m.ID, PropertyID: propertyID})
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.removeFiles(ctx, deleted.StorageKey, deleted.ThumbnailKey.String)
+	logger.FromContext(ctx).Info("property media deleted", zap.Int32("property_id", propertyID), zap.Int32("media_id", mediaID))
+	return nil
+}
+
+// getMedia loads a media after checking the caller owns the property.
+func (s *PropertyMediaService) getMedia(ctx context.Context, ownerID, propertyID, mediaID int32) (postgres.PropertyMedium, error) {
+	var media postgres.PropertyMedium
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		if _, err := checkPropertyOwner(ctx, q, ownerID, propertyID); err != nil {
+			return err
+		}
+		var err error
+		media, err = q.GetPropertyMedia(ctx, postgres.GetPropertyMediaParams{ID: mediaID, PropertyID: propertyID})
+		if err == pgx.ErrNoRows {
+			return ErrMediaNotFound
+		}
+		return err
+	})
+	return media, err
+}
+
+func mediaFileKey(media postgres.PropertyMedium, thumbnail bool) string {
+	if thumbnail && media.ThumbnailKey.Valid {
+		return media.ThumbnailKey.String
+	}
+	return media.StorageKey
+}
+
+// OpenMedia streams a media file (or its thumbnail). The caller must close the reader.
+func (s *PropertyMediaService) OpenMedia(ctx context.Context, ownerID, propertyID, mediaID int32, thumbnail bool) (io.ReadCloser, *PropertyMediaDTO, error) {
+	media, err := s.getMedia(ctx, ownerID, propertyID, mediaID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dto := toPropertyMediaDTO(media)
+	if thumbnail && media.ThumbnailKey.Valid {
+		dto.ContentType = "image/jpeg"
+	}
+
+	reader, err := s.storage.Open(mediaFileKey(media, thumbnail))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open media file: %w", err)
+	}
+	return reader, &dto, nil
+}
+
+// MediaDownloadURL returns a short-lived direct URL to the file when the storage supports it
+// (S3), or "" when the file must be streamed through OpenMedia.
+func (s *PropertyMediaService) MediaDownloadURL(ctx context.Context, ownerID, propertyID, mediaID int32, thumbnail bool) (string, error) {
+	presigner, ok := s.storage.(URLPresigner)
+	if !ok {
+		return "", nil
+	}
+	media, err := s.getMedia(ctx, ownerID, propertyID, mediaID)
+	if err != nil {
+		return "", err
+	}
+	return presigner.PresignGet(mediaFileKey(media, thumbnail), 0)
+}
+
+// SendDiagnosticReminders emails owners whose diagnostics expire within ReminderDays.
+// Each diagnostic is reminded once; uploading a new version resets the reminder.
+func (s *PropertyMediaService) SendDiagnosticReminders(ctx context.Context) error {
+	limit := time.Now().AddDate(0, 0, s.ReminderDays)
+
+	var expiring []postgres.ListExpiringDiagnosticsRow
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		var err error
+		expiring, err = q.ListExpiringDiagnostics(ctx, pgtype.Date{Time: limit, Valid: true})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list expiring diagnostics: %w", err)
+	}
+
+	sent := 0
+	for _, d := range expiring {
+		label := DiagnosticTypes[d.DiagnosticType.String].Label
+		if label == "" {
+			label = d.DiagnosticType.String
+		}
+		subject := fmt.Sprintf("Diagnostic à renouveler : %s", label)
+		body := fmt.Sprintf("Le diagnostic « %s » du logement situé %s expire le %s. Pensez à le renouveler et à déposer la nouvelle version sur Séculoc.",
+			label, d.PropertyAddress, d.ExpiresAt.Time.Format("02/01/2006"))
+
+		if err := s.emailSender.SendNotification(ctx, d.OwnerEmail, subject, body); err != nil {
+			// Not marked as sent: it will be retried on the next run.
+			s.log.Warn("failed to send diagnostic reminder", zap.Int32("media_id", d.ID), zap.Error(err))
+			continue
+		}
+
+		err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+			return q.MarkDiagnosticReminderSent(ctx, d.ID)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to mark reminder sent: %w", err)
+		}
+		sent++
+	}
+
+	if sent > 0 {
+		s.log.Info("diagnostic expiry reminders sent", zap.Int("count", sent))
+	}
+	return nil
+}
+
+// removeFiles deletes stored files, logging failures (orphans are harmless but should be visible).
+func (s *PropertyMediaService) removeFiles(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if err := s.storage.Delete(key); err != nil {
+			logger.FromContext(ctx).Warn("failed to delete media file", zap.String("key", key), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+)
+
+// setupMediaService wires the service with mocks. txErr is what WithTx returns (the mock does not propagate fn's error).
+func setupMediaService(txErr error) (*PropertyMediaService, *MockQuerier, *MockFileStorage, *mockEmailSender) {
+	mockQuerier := new(MockQuerier)
+	mockTx := new(MockTxManager)
+	mockTx.On("WithTx", mock.Anything, mock.Anything).Return(txErr).Run(func(args mock.Arguments) {
+		fn := args.Get(1).(func(postgres.Querier) error)
+		_ = fn(mockQuerier)
+	})
+	mockFileStore := new(MockFileStorage)
+	mockEmail := new(mockEmailSender)
+	return NewPropertyMediaService(mockTx, mockFileStore, mockEmail, zap.NewNop()), mockQuerier, mockFileStore, mockEmail
+}
+
+func ownedProperty(ownerID, propertyID int32) postgres.Property {
+	return postgres.Property{
+		ID:       propertyID,
+		OwnerID:  pgtype.Int4{Int32: ownerID, Valid: true},
+		IsActive: pgtype.Bool{Bool: true, Valid: true},
+	}
+}
+
+func samplePNG(t *testing.T) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 20, 10))))
+	return buf.Bytes()
+}
+
+func TestUploadPhoto_FirstPhotoBecomesCover(t *testing.T) {
+	svc, mockQuerier, mockFileStore, _ := setupMediaService(nil)
+	content := samplePNG(t)
+
+	mockQuerier.On("GetPropertyForUpdate", mock.Anything, int32(10)).Return(ownedProperty(1, 10), nil)
+	mockQuerier.On("GetNextPhotoPosition", mock.Anything, int32(10)).Return(int32(0), nil)
+	mockFileStore.On("Save", mock.MatchedBy(func(k string) bool { return len(k) > 0 }), mock.Anything).Return("", nil).Twice()
+	mockQuerier.On("CreatePropertyMedia", mock.Anything, mock.MatchedBy(func(p postgres.CreatePropertyMediaParams) bool {
+		return p.MediaKind == MediaKindPhoto && p.ContentType == "image/png" && p.IsCover && p.ThumbnailKey.Valid && p.OriginalFilename == "salon.png"
+	})).Return(postgres.PropertyMedium{ID: 3, PropertyID: 10, MediaKind: MediaKindPhoto, IsCover: true, ContentType: "image/png", ThumbnailKey: pgtype.Text{String: "t", Valid: true}}, nil)
+
+	dto, err := svc.UploadPhoto(context.Background(), 1, 10, "../../salon.png", content)
+
+	require.NoError(t, err)
+	assert.True(t, dto.IsCover)
+	assert.Equal(t, "/api/v1/properties/10/media/3", dto.URL)
+	assert.Equal(t, "/api/v1/properties/10/media/3?variant=thumbnail", dto.ThumbnailURL)
+	mockQuerier.AssertExpectations(t)
+	mockFileStore.AssertExpectations(t)
+}
+
+func TestUploadPhoto_RejectsSpoofedType(t *testing.T) {
+	svc, _, _, _ := setupMediaService(nil)
+
+	// A PDF renamed to .jpg must be rejected: the type is sniffed from the content
+	_, err := svc.UploadPhoto(context.Background(), 1, 10, "photo.jpg", []byte("%PDF-1.4 fake"))
+
+	assert.ErrorIs(t, err, ErrUnsupportedMediaType)
+}
+
+func TestUploadPhoto_TooLarge(t *testing.T) {
+	svc, _, _, _ := setupMediaService(nil)
+	svc.MaxPhotoBytes = 10
+
+	_, err := svc.UploadPhoto(context.Background(), 1, 10, "photo.png", samplePNG(t))
+
+	assert.ErrorIs(t, err, ErrMediaTooLarge)
+}
+
+func TestUploadPhoto_AccessDenied(t *testing.T) {
+	svc, mockQuerier, mockFileStore, _ := setupMediaService(errors.New("property not found or access denied"))
+
+	mockQuerier.On("GetPropertyForUpdate", mock.Anything, int32(10)).Return(ownedProperty(2, 10), nil)
+	mockFileStore.On("Delete", mock.Anything).Return(nil)
+
+	_, err := svc.UploadPhoto(context.Background(), 1, 10, "photo.png", samplePNG(t))
+
+	assert.EqualError(t, err, "property not found or access denied")
+	mockFileStore.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestUploadDiagnostic_DefaultExpiryAndReplace(t *testing.T) {
+	svc, mockQuerier, mockFileStore, _ := setupMediaService(nil)
+	issued := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	// The property is locked while its previous diagnostic is replaced
+	mockQuerier.On("GetPropertyForUpdate", mock.Anything, int32(10)).Return(ownedProperty(1, 10), nil)
+	mockQuerier.On("GetPropertyDiagnosticByType", mock.Anything, mock.Anything).
+		Return(postgres.PropertyMedium{ID: 7, StorageKey: "properties/10/diagnostics/old.pdf"}, nil)
+	mockQuerier.On("DeletePropertyMedia", mock.Anything, postgres.DeletePropertyMediaParams{ID: 7, PropertyID: 10}).Return(nil)
+	mockFileStore.On("Save", mock.Anything, mock.Anything).Return("", nil)
+	mockQuerier.On("CreatePropertyMedia", mock.Anything, mock.MatchedBy(func(p postgres.CreatePropertyMediaParams) bool {
+		return p.DiagnosticType.String == "erp" && p.ContentType == "application/pdf" &&
+			p.ExpiresAt.Valid && p.ExpiresAt.Time.Equal(issued.Add(DiagnosticTypes["erp"].Validity))
+	})).Return(postgres.PropertyMedium{ID: 8, PropertyID: 10, MediaKind: MediaKindDiagnostic}, nil)
+	mockFileStore.On("Delete", "properties/10/diagnostics/old.pdf").Return(nil)
+
+	_, err := svc.UploadDiagnostic(context.Background(), UploadDiagnosticParams{
+		OwnerID:        1,
+		PropertyID:     10,
+		DiagnosticType: "erp",
+		Filename:       "erp.pdf",
+		Content:        []byte("%PDF-1.4 content"),
+		IssuedAt:       &issued,
+	})
+
+	require.NoError(t, err)
+	mockQuerier.AssertExpectations(t)
+	mockFileStore.AssertExpectations(t)
+}
+
+func TestUploadDiagnostic_InvalidType(t *testing.T) {
+	svc, _, _, _ := setupMediaService(nil)
+
+	_, err := svc.UploadDiagnostic(context.Background(), UploadDiagnosticParams{
+		DiagnosticType: "asbestos-ish",
+		Content:        []byte("%PDF-1.4"),
+	})
+
+	assert.Error(t, err)
+}
+
+func TestReorderPhotos(t *testing.T) {
+	svc, mockQuerier, _, _ := setupMediaService(nil)
+
+	mockQuerier.On("GetProperty", mock.Anything, int32(10)).Return(ownedProperty(1, 10), nil)
+	mockQuerier.On("ListPropertyMedia", mock.Anything, int32(10)).Return([]postgres.PropertyMedium{
+		{ID: 1, MediaKind: MediaKindPhoto},
+		{ID: 2, MediaKind: MediaKindPhoto},
+		{ID: 3, MediaKind: MediaKindDiagnostic},
+	}, nil)
+
+	// Diagnostic id instead of a photo: nothing is reordered
+	_ = svc.ReorderPhotos(context.Background(), 1, 10, []int32{2, 3})
+	mockQuerier.AssertNotCalled(t, "UpdatePropertyMediaPosition", mock.Anything, mock.Anything)
+
+	mockQuerier.On("UpdatePropertyMediaPosition", mock.Anything, postgres.UpdatePropertyMediaPositionParams{ID: 2, PropertyID: 10, Position: 0}).Return(nil)
+	mockQuerier.On("UpdatePropertyMediaPosition", mock.Anything, postgres.UpdatePropertyMediaPositionParams{ID: 1, PropertyID: 10, Position: 1}).Return(nil)
+
+	err := svc.ReorderPhotos(context.Background(), 1, 10, []int32{2, 1})
+	require.NoError(t, err)
+	mockQuerier.AssertExpectations(t)
+}
+
+func TestSetCover_NotFound(t *testing.T) {
+	svc, mockQuerier, _, _ := setupMediaService(ErrMediaNotFound)
+
+	mockQuerier.On("GetProperty", mock.Anything, int32(10)).Return(ownedProperty(1, 10), nil)
+	mockQuerier.On("GetPropertyMedia", mock.Anything, mock.Anything).Return(postgres.PropertyMedium{}, pgx.ErrNoRows)
+
+	err := svc.SetCover(context.Background(), 1, 10, 99)
+
+	assert.ErrorIs(t, err, ErrMediaNotFound)
+}
+
+func TestDeleteMedia_PromotesNextCover(t *testing.T) {
+	svc, mockQuerier, mockFileStore, _ := setupMediaService(nil)
+
+	mockQuerier.On("GetProperty", mock.Anything, int32(10)).Return(ownedProperty(1, 10), nil)
+	mockQuerier.On("GetPropertyMedia", mock.Anything, postgres.GetPropertyMediaParams{ID: 1, PropertyID: 10}).
+		Return(postgres.PropertyMedium{ID: 1, PropertyID: 10, MediaKind: MediaKindPhoto, IsCover: true, StorageKey: "a.jpg", ThumbnailKey: pgtype.Text{String: "a_thumb.jpg", Valid: true}}, nil)
+	mockQuerier.On("DeletePropertyMedia", mock.Anything, postgres.DeletePropertyMediaParams{ID: 1, PropertyID: 10}).Return(nil)
+	mockQuerier.On("ListPropertyMedia", mock.Anything, int32(10)).Return([]postgres.PropertyMedium{{ID: 2, MediaKind: MediaKindPhoto}}, nil)
+	mockQuerier.On("SetPropertyMediaCover", mock.Anything, postgres.SetPropertyMediaCoverParams{ID: 2, PropertyID: 10}).Return(nil)
+	mockFileStore.On("Delete", "a.jpg").Return(nil)
+	mockFileStore.On("Delete", "a_thumb.jpg").Return(nil)
+
+	err := svc.DeleteMedia(context.Background(), 1, 10, 1)
+
+	require.NoError(t, err)
+	mockQuerier.AssertExpectations(t)
+	mockFileStore.AssertExpectations(t)
+}
+
+func TestSendDiagnosticReminders(t *testing.T) {
+	svc, mockQuerier, _, mockEmail := setupMediaService(nil)
+
+	mockQuerier.On("ListExpiringDiagnostics", mock.Anything, mock.Anything).Return([]postgres.ListExpiringDiagnosticsRow{
+		{ID: 1, DiagnosticType: pgtype.Text{String: "dpe", Valid: true}, OwnerEmail: "ok@test.com", PropertyAddress: "1 rue A"},
+		{ID: 2, DiagnosticType: pgtype.Text{String: "gas", Valid: true}, OwnerEmail: "ko@test.com", PropertyAddress: "2 rue B"},
+	}, nil)
+	mockEmail.On("SendNotification", mock.Anything, "ok@test.com", mock.Anything, mock.Anything).Return(nil)
+	mockEmail.On("SendNotification", mock.Anything, "ko@test.com", mock.Anything, mock.Anything).Return(errors.New("smtp down"))
+	mockQuerier.On("MarkDiagnosticReminderSent", mock.Anything, int32(1)).Return(nil)
+
+	err := svc.SendDiagnosticReminders(context.Background())
+
+	require.NoError(t, err)
+	// Failed sends stay unmarked so they are retried on the next run
+	mockQuerier.AssertNotCalled(t, "MarkDiagnosticReminderSent", mock.Anything, int32(2))
+	mockQuerier.AssertExpectations(t)
+}
+
+type presigningFileStorage struct {
+	MockFileStorage
+}
+
+func (m *presigningFileStorage) PresignGet(filename string, ttl time.Duration) (string, error) {
+	args := m.Called(filename, ttl)
+	return args.String(0), args.Error(1)
+}
+
+func TestMediaDownloadURL(t *testing.T) {
+	// Local storage: no direct URL, the handler streams the file
+	svc, _, _, _ := setupMediaService(nil)
+	url, err := svc.MediaDownloadURL(context.Background(), 1, 10, 3, false)
+	require.NoError(t, err)
+	assert.Empty(t, url)
+
+	// Object storage: presigned URL of the thumbnail
+	svc, mockQuerier, _, _ := setupMediaService(nil)
+	store := new(presigningFileStorage)
+	svc.storage = store
+	mockQuerier.On("GetProperty", mock.Anything, int32(10)).Return(ownedProperty(1, 10), nil)
+	mockQuerier.On("GetPropertyMedia", mock.Anything, postgres.GetPropertyMediaParams{ID: 3, PropertyID: 10}).
+		Return(postgres.PropertyMedium{ID: 3, StorageKey: "a.jpg", ThumbnailKey: pgtype.Text{String: "a_thumb.jpg", Valid: true}}, nil)
+	store.On("PresignGet", "a_thumb.jpg", time.Duration(0)).Return("https://bucket/a_thumb.jpg?X-Amz-Signature=x", nil)
+
+	url, err = svc.MediaDownloadURL(context.Background(), 1, 10, 3, true)
+	require.NoError(t, err)
+	assert.Equal(t, "https://bucket/a_thumb.jpg?X-Amz-Signature=x", url)
+}
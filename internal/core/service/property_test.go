@@ -15,6 +15,13 @@ import (
 	"seculoc-back/internal/adapter/storage/postgres"
 )
 
+// expectPropertyGrant expects the vacancy credits granted to the wallet of a new property.
+func expectPropertyGrant(q *MockQuerier, propertyID int32) {
+	q.On("CreateCreditTransaction", mock.Anything, mock.MatchedBy(func(arg postgres.CreateCreditTransactionParams) bool {
+		return arg.PropertyID.Int32 == propertyID && arg.Amount == propertyVacancyCredits && arg.TransactionType == "property_grant"
+	})).Return(postgres.CreditTransaction{BalanceAfter: pgtype.Int4{Int32: propertyVacancyCredits, Valid: true}}, nil).Once()
+}
+
 func TestCreateProperty_Success(t *testing.T) {
 	// Setup
 	mockQuerier := new(MockQuerier)
@@ -65,6 +72,7 @@ func TestCreateProperty_Success(t *testing.T) {
 			d.Float64 == 2000.0 &&
 			arg.IsFurnished.Bool == true
 	})).Return(expectedProp, nil)
+	expectPropertyGrant(mockQuerier, 1)
 
 	// Execute
 	prop, err := svc.CreateProperty(ctx, userID, "", "123 Main St", "long_term", `{"rooms":3}`, 1000, 150, 2000, true, 0)
@@ -73,6 +81,7 @@ func TestCreateProperty_Success(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, int32(1), prop.ID)
 	assert.True(t, prop.IsFurnished.Bool)
+	assert.Equal(t, int32(propertyVacancyCredits), prop.VacancyCredits)
 }
 
 func TestCreateProperty_Seasonal_AlwaysAllowed(t *testing.T) {
@@ -105,6 +114,7 @@ func TestCreateProperty_Seasonal_AlwaysAllowed(t *testing.T) {
 		RentalType: postgres.PropertyTypeSeasonal,
 	}
 	mockQuerier.On("CreateProperty", mock.Anything, mock.Anything).Return(expectedProp, nil)
+	expectPropertyGrant(mockQuerier, 1)
 
 	// Execute
 	prop, err := svc.CreateProperty(ctx, 1, "", "123 St", "seasonal", "{}", 100, 0, 200, true, 50)
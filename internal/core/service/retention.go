@@ -0,0 +1,482 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+)
+
+// Data categories with their own retention period (RGPD)
+const (
+	// RetentionSolvencyDocuments: pieces, guarantor pieces and report of closed checks whose candidate did not
+	// become a tenant of the property. Status and score remain.
+	RetentionSolvencyDocuments = "solvency_documents"
+	// RetentionRejectedCandidates: everything else about candidates who were not retained; the check is
+	// detached from their account so that a provisional account can then be deleted.
+	RetentionRejectedCandidates = "rejected_candidates"
+	// RetentionEndedLeases: parties, guarantors, documents and the tenants' checks of terminated leases.
+	// The lease itself and its payments are kept, anonymised, for the owner's accounts.
+	RetentionEndedLeases = "ended_leases"
+	// RetentionExpiredDossiers: portable tenant dossiers expired for longer than the period, with their shares and
+	// their own copy of the pieces (dossiers/<user>/). Checks created from them keep their own copy.
+	RetentionExpiredDossiers = "expired_dossiers"
+	// RetentionLogs: document access logs, expired download links and received webhook events.
+	RetentionLogs = "logs"
+	// RetentionProvisionalUsers: provisional accounts nothing refers to any more.
+	RetentionProvisionalUsers = "provisional_users"
+)
+
+// RetentionCategories are purged in this order: candidates are detached from their checks before
+// provisional accounts are deleted.
+var RetentionCategories = []string{
+	RetentionSolvencyDocuments,
+	RetentionRejectedCandidates,
+	RetentionEndedLeases,
+	RetentionExpiredDossiers,
+	RetentionLogs,
+	RetentionProvisionalUsers,
+}
+
+// defaultRetentionDays follow the CNIL recommendations: pieces of a rental application are only needed for
+// the decision, candidates who were not retained are forgotten after three months, and a lease's personal
+// data is kept for the three years during which rent claims can be brought (loi du 6 juillet 1989, art. 7-1).
+var defaultRetentionDays = map[string]int{
+	RetentionSolvencyDocuments:  30,
+	RetentionRejectedCandidates: 90,
+	RetentionEndedLeases:        3 * 365,
+	RetentionExpiredDossiers:    30,
+	RetentionLogs:               365,
+	RetentionProvisionalUsers:   30,
+}
+
+var ErrUnknownRetentionCategory = errors.New("unknown retention category")
+
+// errRetentionDryRun rolls back the transactions of a dry run.
+var errRetentionDryRun = errors.New("retention dry run")
+
+type RetentionService struct {
+	txManager TxManager
+	storage   FileStorage
+	log       *zap.Logger
+
+	// Days is the retention period of each category (RETENTION_<CATEGORY>_DAYS)
+	Days map[string]int
+}
+
+func NewRetentionService(txManager TxManager, storage FileStorage, l *zap.Logger) *RetentionService {
+	s := &RetentionService{
+		txManager: txManager,
+		storage:   storage,
+		log:       l,
+		Days:      map[string]int{},
+	}
+	for _, category := range RetentionCategories {
+		days := viper.GetInt("RETENTION_" + strings.ToUpper(category) + "_DAYS")
+		if days <= 0 {
+			days = defaultRetentionDays[category]
+		}
+		s.Days[category] = days
+	}
+	return s
+}
+
+// RetentionReport is what a purge deleted or anonymised, or would have in a dry run.
+type RetentionReport struct {
+	Category      string    `json:"category"`
+	RetentionDays int       `json:"retention_days"`
+	Cutoff        time.Time `json:"cutoff"`
+	DryRun        bool      `json:"dry_run"`
+	Records       int       `json:"records"`
+	Files         int       `json:"files"`
+	RecordIDs     []int32   `json:"record_ids"`
+}
+
+// PurgeAll purges every category. It is the scheduled job.
+func (s *RetentionService) PurgeAll(ctx context.Context) error {
+	_, err := s.Run(ctx, false)
+	return err
+}
+
+// Run purges every category in order. A failing category does not prevent the next ones.
+func (s *RetentionService) Run(ctx context.Context, dryRun bool) ([]RetentionReport, error) {
+	var reports []RetentionReport
+	var errs []error
+	for _, category := range RetentionCategories {
+		report, err := s.Purge(ctx, category, dryRun)
+		if report != nil {
+			reports = append(reports, *report)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", category, err))
+		}
+	}
+	return reports, errors.Join(errs...)
+}
+
+// Purge deletes or anonymises the data of a category older than its retention period and records the
+// purge in retention_purges. A dry run performs the same statements in transactions that are rolled back
+// and deletes no file, so its report is exactly what a purge would do.
+func (s *RetentionService) Purge(ctx context.Context, category string, dryRun bool) (*RetentionReport, error) {
+	days, ok := s.Days[category]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownRetentionCategory, category)
+	}
+	report := &RetentionReport{
+		Category:      category,
+		RetentionDays: days,
+		Cutoff:        time.Now().AddDate(0, 0, -days).Truncate(time.Second),
+		DryRun:        dryRun,
+		RecordIDs:     []int32{},
+	}
+
+	var err error
+	switch category {
+	case RetentionSolvencyDocuments:
+		err = s.purgeSolvencyDocuments(ctx, report)
+	case RetentionRejectedCandidates:
+		err = s.anonymizeRejectedCandidates(ctx, report)
+	case RetentionEndedLeases:
+		err = s.anonymizeEndedLeases(ctx, report)
+	case RetentionExpiredDossiers:
+		err = s.purgeExpiredDossiers(ctx, report)
+	case RetentionLogs:
+		err = s.purgeLogs(ctx, report)
+	case RetentionProvisionalUsers:
+		err = s.purgeProvisionalUsers(ctx, report)
+	}
+
+	// What was done before a failure is recorded too
+	if !dryRun && (report.Records > 0 || report.Files > 0) {
+		if auditErr := s.recordPurge(ctx, report); auditErr != nil {
+			err = errors.Join(err, auditErr)
+		}
+	}
+
+	s.log.Info("retention purge",
+		zap.String("category", category),
+		zap.Bool("dry_run", dryRun),
+		zap.Time("cutoff", report.Cutoff),
+		zap.Int("records", report.Records),
+		zap.Int("files", report.Files),
+		zap.Error(err))
+	return report, err
+}
+
+func (s *RetentionService) recordPurge(ctx context.Context, report *RetentionReport) error {
+	ids, err := json.Marshal(report.RecordIDs)
+	if err != nil {
+		return err
+	}
+	return s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		_, err := q.CreateRetentionPurge(ctx, postgres.CreateRetentionPurgeParams{
+			Category:      report.Category,
+			RetentionDays: int32(report.RetentionDays),
+			Cutoff:        pgtype.Timestamp{Time: report.Cutoff, Valid: true},
+			Records:       int32(report.Records),
+			Files:         int32(report.Files),
+			RecordIds:     ids,
+		})
+		return err
+	})
+}
+
+// step runs fn in its own transaction: fn returns the stored files to delete once committed and the
+// number of records it deleted or anonymised. In a dry run the transaction is rolled back.
+func (s *RetentionService) step(ctx context.Context, report *RetentionReport, fn func(q postgres.Querier) ([]string, int, error)) error {
+	var files []string
+	var records int
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		var err error
+		files, records, err = fn(q)
+		if err == nil && report.DryRun {
+			return errRetentionDryRun
+		}
+		return err
+	})
+	if err != nil && !errors.Is(err, errRetentionDryRun) {
+		return err
+	}
+
+	report.Records += records
+	report.Files += len(files)
+	if !report.DryRun {
+		for _, key := range files {
+			if err := s.storage.Delete(key); err != nil {
+				s.log.Warn("failed to delete purged file", zap.String("key", key), zap.Error(err))
+			}
+		}
+	}
+	return nil
+}
+
+// purgeCheck is a check selected for a purge.
+type purgeCheck struct {
+	ID            int32
+	DocumentsJson []byte
+}
+
+func (s *RetentionService) listChecks(ctx context.Context, list func(q postgres.Querier) ([]purgeCheck, error)) ([]purgeCheck, error) {
+	var checks []purgeCheck
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		var err error
+		checks, err = list(q)
+		return err
+	})
+	return checks, err
+}
+
+// deleteDocuments deletes generated documents with their download links and access logs, which refer
+// to them, and returns their storage keys.
+func deleteDocuments(ctx context.Context, q postgres.Querier, docs []postgres.Document) ([]string, error) {
+	if len(docs) == 0 {
+		return nil, nil
+	}
+	ids := make([]int32, 0, len(docs))
+	keys := make([]string, 0, len(docs))
+	for _, d := range docs {
+		ids = append(ids, d.ID)
+		keys = append(keys, d.StorageKey)
+	}
+	if err := q.DeleteDocumentAccessLogsByDocuments(ctx, ids); err != nil {
+		return nil, err
+	}
+	if err := q.DeleteDocumentLinksByDocuments(ctx, ids); err != nil {
+		return nil, err
+	}
+	if err := q.DeleteDocuments(ctx, ids); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// checkFiles removes the reports of a check and returns the files of the check: candidate pieces,
+// guarantor pieces and report versions.
+func checkFiles(ctx context.Context, q postgres.Querier, check purgeCheck) ([]string, error) {
+	files := storageKeys(decodeCandidateDocuments(check.DocumentsJson))
+
+	guarantors, err := q.ListGuarantorsByCheck(ctx, check.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range guarantors {
+		files = append(files, storageKeys(decodeCandidateDocuments(g.DocumentsJson))...)
+	}
+
+	reports, err := q.ListDocumentsByEntity(ctx, postgres.ListDocumentsByEntityParams{
+		DocumentType: DocumentTypeSolvencyReport,
+		EntityID:     check.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	reportFiles, err := deleteDocuments(ctx, q, reports)
+	if err != nil {
+		return nil, err
+	}
+	return append(files, reportFiles...), nil
+}
+
+// anonymizeCheck erases the candidate's data from a check and deletes its guarantors not bound to a lease.
+func anonymizeCheck(ctx context.Context, q postgres.Querier, check purgeCheck) ([]string, error) {
+	files, err := checkFiles(ctx, q, check)
+	if err != nil {
+		return nil, err
+	}
+	if err := q.DeleteUnattachedGuarantorsByCheck(ctx, check.ID); err != nil {
+		return nil, err
+	}
+	if err := q.AnonymizeSolvencyCheck(ctx, check.ID); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+func (s *RetentionService) purgeSolvencyDocuments(ctx context.Context, report *RetentionReport) error {
+	checks, err := s.listChecks(ctx, func(q postgres.Querier) ([]purgeCheck, error) {
+		rows, err := q.ListSolvencyChecksForDocumentPurge(ctx, pgtype.Timestamp{Time: report.Cutoff, Valid: true})
+		checks := make([]purgeCheck, 0, len(rows))
+		for _, r := range rows {
+			checks = append(checks, purgeCheck{ID: r.ID, DocumentsJson: r.DocumentsJson})
+		}
+		return checks, err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list checks: %w", err)
+	}
+
+	for _, check := range checks {
+		err := s.step(ctx, report, func(q postgres.Querier) ([]string, int, error) {
+			files, err := checkFiles(ctx, q, check)
+			if err != nil {
+				return nil, 0, err
+			}
+			if err := q.ClearGuarantorDocumentsByCheck(ctx, check.ID); err != nil {
+				return nil, 0, err
+			}
+			if err := q.MarkSolvencyCheckDocumentsPurged(ctx, check.ID); err != nil {
+				return nil, 0, err
+			}
+			return files, 1, nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to purge documents of check %d: %w", check.ID, err)
+		}
+		report.RecordIDs = append(report.RecordIDs, check.ID)
+	}
+	return nil
+}
+
+func (s *RetentionService) anonymizeRejectedCandidates(ctx context.Context, report *RetentionReport) error {
+	checks, err := s.listChecks(ctx, func(q postgres.Querier) ([]purgeCheck, error) {
+		rows, err := q.ListSolvencyChecksForAnonymization(ctx, pgtype.Timestamp{Time: report.Cutoff, Valid: true})
+		checks := make([]purgeCheck, 0, len(rows))
+		for _, r := range rows {
+			checks = append(checks, purgeCheck{ID: r.ID, DocumentsJson: r.DocumentsJson})
+		}
+		return checks, err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list checks: %w", err)
+	}
+
+	for _, check := range checks {
+		err := s.step(ctx, report, func(q postgres.Querier) ([]string, int, error) {
+			files, err := anonymizeCheck(ctx, q, check)
+			return files, 1, err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to anonymise check %d: %w", check.ID, err)
+		}
+		report.RecordIDs = append(report.RecordIDs, check.ID)
+	}
+	return nil
+}
+
+func (s *RetentionService) anonymizeEndedLeases(ctx context.Context, report *RetentionReport) error {
+	var leaseIDs []int32
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		var err error
+		leaseIDs, err = q.ListLeasesForAnonymization(ctx, pgtype.Date{Time: report.Cutoff, Valid: true})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list leases: %w", err)
+	}
+
+	for _, leaseID := range leaseIDs {
+		err := s.step(ctx, report, func(q postgres.Querier) ([]string, int, error) {
+			docs, err := q.ListLeaseDocuments(ctx, leaseID)
+			if err != nil {
+				return nil, 0, err
+			}
+			files, err := deleteDocuments(ctx, q, docs)
+			if err != nil {
+				return nil, 0, err
+			}
+
+			guarantors, err := q.ListGuarantorsByLease(ctx, pgtype.Int4{Int32: leaseID, Valid: true})
+			if err != nil {
+				return nil, 0, err
+			}
+			for _, g := range guarantors {
+				files = append(files, storageKeys(decodeCandidateDocuments(g.DocumentsJson))...)
+			}
+			if err := q.AnonymizeLeaseGuarantors(ctx, pgtype.Int4{Int32: leaseID, Valid: true}); err != nil {
+				return nil, 0, err
+			}
+			if err := q.AnonymizeLeaseParties(ctx, leaseID); err != nil {
+				return nil, 0, err
+			}
+			if err := q.AnonymizeLeaseInvitations(ctx, pgtype.Int4{Int32: leaseID, Valid: true}); err != nil {
+				return nil, 0, err
+			}
+
+			// The solvency checks that led to the lease
+			checks, err := q.ListSolvencyChecksByLeaseTenants(ctx, leaseID)
+			if err != nil {
+				return nil, 0, err
+			}
+			for _, c := range checks {
+				more, err := anonymizeCheck(ctx, q, purgeCheck{ID: c.ID, DocumentsJson: c.DocumentsJson})
+				if err != nil {
+					return nil, 0, err
+				}
+				files = append(files, more...)
+			}
+
+			if err := q.AnonymizeLease(ctx, leaseID); err != nil {
+				return nil, 0, err
+			}
+			return files, 1, nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to anonymise lease %d: %w", leaseID, err)
+		}
+		report.RecordIDs = append(report.RecordIDs, leaseID)
+	}
+	return nil
+}
+
+func (s *RetentionService) purgeExpiredDossiers(ctx context.Context, report *RetentionReport) error {
+	var ids []int32
+	err := s.step(ctx, report, func(q postgres.Querier) ([]string, int, error) {
+		dossiers, err := q.DeleteExpiredTenantDossiers(ctx, pgtype.Timestamp{Time: report.Cutoff, Valid: true})
+		if err != nil {
+			return nil, 0, err
+		}
+		var files []string
+		for _, d := range dossiers {
+			ids = append(ids, d.ID)
+			files = append(files, storageKeys(decodeCandidateDocuments(d.DocumentsJson))...)
+		}
+		return files, len(dossiers), nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete expired dossiers: %w", err)
+	}
+	report.RecordIDs = append(report.RecordIDs, ids...)
+	return nil
+}
+
+func (s *RetentionService) purgeLogs(ctx context.Context, report *RetentionReport) error {
+	cutoff := pgtype.Timestamp{Time: report.Cutoff, Valid: true}
+	return s.step(ctx, report, func(q postgres.Querier) ([]string, int, error) {
+		// Access logs first: they refer to the links
+		logs, err := q.DeleteDocumentAccessLogsBefore(ctx, cutoff)
+		if err != nil {
+			return nil, 0, err
+		}
+		links, err := q.DeleteDocumentLinksBefore(ctx, cutoff)
+		if err != nil {
+			return nil, 0, err
+		}
+		events, err := q.DeleteWebhookEventsBefore(ctx, cutoff)
+		if err != nil {
+			return nil, 0, err
+		}
+		return nil, int(logs + links + events), nil
+	})
+}
+
+func (s *RetentionService) purgeProvisionalUsers(ctx context.Context, report *RetentionReport) error {
+	var ids []int32
+	err := s.step(ctx, report, func(q postgres.Querier) ([]string, int, error) {
+		var err error
+		ids, err = q.CleanupProvisionalUsers(ctx, pgtype.Timestamp{Time: report.Cutoff, Valid: true})
+		return nil, len(ids), err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete provisional users: %w", err)
+	}
+	report.RecordIDs = append(report.RecordIDs, ids...)
+	return nil
+}
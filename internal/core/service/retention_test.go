@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+)
+
+func setupRetention() (*RetentionService, *MockQuerier, *MockFileStorage) {
+	mockQuerier := new(MockQuerier)
+	mockFileStore := new(MockFileStorage)
+	return NewRetentionService(passthroughTxManager{q: mockQuerier}, mockFileStore, zap.NewNop()), mockQuerier, mockFileStore
+}
+
+func docsJSON(t *testing.T, keys ...string) []byte {
+	var docs []CandidateDocument
+	for _, k := range keys {
+		docs = append(docs, CandidateDocument{ID: k, Type: "payslip", StorageKey: k})
+	}
+	raw, err := json.Marshal(docs)
+	require.NoError(t, err)
+	return raw
+}
+
+// mockRejectedCheck sets up check 7 with one piece, a guarantor with one piece and a report.
+func mockRejectedCheck(t *testing.T, q *MockQuerier) {
+	q.On("ListSolvencyChecksForAnonymization", mock.Anything, mock.Anything).Return([]postgres.ListSolvencyChecksForAnonymizationRow{
+		{ID: 7, DocumentsJson: docsJSON(t, "solvency/7/payslip_a.pdf")},
+	}, nil)
+	q.On("ListGuarantorsByCheck", mock.Anything, int32(7)).Return([]postgres.SolvencyGuarantor{
+		{ID: 3, CheckID: 7, DocumentsJson: docsJSON(t, "solvency/7/guarantors/3/payslip_b.pdf")},
+	}, nil)
+	q.On("ListDocumentsByEntity", mock.Anything, postgres.ListDocumentsByEntityParams{DocumentType: DocumentTypeSolvencyReport, EntityID: 7}).
+		Return([]postgres.Document{{ID: 40, StorageKey: "documents/solvency_report/7/v1.pdf"}}, nil)
+	q.On("DeleteDocumentAccessLogsByDocuments", mock.Anything, []int32{40}).Return(nil)
+	q.On("DeleteDocumentLinksByDocuments", mock.Anything, []int32{40}).Return(nil)
+	q.On("DeleteDocuments", mock.Anything, []int32{40}).Return(nil)
+	q.On("DeleteUnattachedGuarantorsByCheck", mock.Anything, int32(7)).Return(nil)
+	q.On("AnonymizeSolvencyCheck", mock.Anything, int32(7)).Return(nil)
+}
+
+func TestNewRetentionService_Days(t *testing.T) {
+	viper.Set("RETENTION_ENDED_LEASES_DAYS", 1825)
+	t.Cleanup(func() { viper.Set("RETENTION_ENDED_LEASES_DAYS", "") })
+
+	svc, _, _ := setupRetention()
+
+	assert.Equal(t, 1825, svc.Days[RetentionEndedLeases])
+	assert.Equal(t, 90, svc.Days[RetentionRejectedCandidates])
+	_, err := svc.Purge(context.Background(), "everything", false)
+	assert.ErrorIs(t, err, ErrUnknownRetentionCategory)
+}
+
+func TestPurgeRejectedCandidates(t *testing.T) {
+	svc, mockQuerier, mockFileStore := setupRetention()
+	mockRejectedCheck(t, mockQuerier)
+	for _, key := range []string{"solvency/7/payslip_a.pdf", "solvency/7/guarantors/3/payslip_b.pdf", "documents/solvency_report/7/v1.pdf"} {
+		mockFileStore.On("Delete", key).Return(nil).Once()
+	}
+	mockQuerier.On("CreateRetentionPurge", mock.Anything, mock.MatchedBy(func(p postgres.CreateRetentionPurgeParams) bool {
+		return p.Category == RetentionRejectedCandidates && p.RetentionDays == 90 && p.Records == 1 && p.Files == 3 && string(p.RecordIds) == "[7]"
+	})).Return(postgres.RetentionPurge{}, nil).Once()
+
+	report, err := svc.Purge(context.Background(), RetentionRejectedCandidates, false)
+
+	require.NoError(t, err)
+	assert.Equal(t, []int32{7}, report.RecordIDs)
+	mockQuerier.AssertExpectations(t)
+	mockFileStore.AssertExpectations(t)
+}
+
+func TestPurge_DryRunChangesNothing(t *testing.T) {
+	svc, mockQuerier, mockFileStore := setupRetention()
+	mockRejectedCheck(t, mockQuerier)
+
+	report, err := svc.Purge(context.Background(), RetentionRejectedCandidates, true)
+
+	require.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, 1, report.Records)
+	assert.Equal(t, 3, report.Files)
+	// The statements ran in a transaction that was rolled back; no file deleted, no audit record
+	mockFileStore.AssertNotCalled(t, "Delete", mock.Anything)
+	mockQuerier.AssertNotCalled(t, "CreateRetentionPurge", mock.Anything, mock.Anything)
+}
+
+func TestPurgeEndedLeases(t *testing.T) {
+	svc, mockQuerier, mockFileStore := setupRetention()
+	lease := pgtype.Int4{Int32: 5, Valid: true}
+
+	mockQuerier.On("ListLeasesForAnonymization", mock.Anything, mock.Anything).Return([]int32{5}, nil)
+	mockQuerier.On("ListLeaseDocuments", mock.Anything, int32(5)).Return([]postgres.Document{
+		{ID: 1, StorageKey: "documents/lease/5/v1.pdf"},
+		{ID: 2, StorageKey: "documents/guarantee_deed/9/v1.pdf"},
+	}, nil)
+	mockQuerier.On("DeleteDocumentAccessLogsByDocuments", mock.Anything, []int32{1, 2}).Return(nil)
+	mockQuerier.On("DeleteDocumentLinksByDocuments", mock.Anything, []int32{1, 2}).Return(nil)
+	mockQuerier.On("DeleteDocuments", mock.Anything, []int32{1, 2}).Return(nil)
+	mockQuerier.On("ListGuarantorsByLease", mock.Anything, lease).Return([]postgres.SolvencyGuarantor{}, nil)
+	mockQuerier.On("AnonymizeLeaseGuarantors", mock.Anything, lease).Return(nil)
+	mockQuerier.On("AnonymizeLeaseParties", mock.Anything, int32(5)).Return(nil)
+	mockQuerier.On("AnonymizeLeaseInvitations", mock.Anything, lease).Return(nil)
+	mockQuerier.On("ListSolvencyChecksByLeaseTenants", mock.Anything, int32(5)).Return([]postgres.ListSolvencyChecksByLeaseTenantsRow{{ID: 8}}, nil)
+	mockQuerier.On("ListGuarantorsByCheck", mock.Anything, int32(8)).Return([]postgres.SolvencyGuarantor{}, nil)
+	mockQuerier.On("ListDocumentsByEntity", mock.Anything, mock.Anything).Return([]postgres.Document{}, nil)
+	mockQuerier.On("DeleteUnattachedGuarantorsByCheck", mock.Anything, int32(8)).Return(nil)
+	mockQuerier.On("AnonymizeSolvencyCheck", mock.Anything, int32(8)).Return(nil)
+	mockQuerier.On("AnonymizeLease", mock.Anything, int32(5)).Return(nil)
+	mockFileStore.On("Delete", mock.Anything).Return(nil)
+	mockQuerier.On("CreateRetentionPurge", mock.Anything, mock.Anything).Return(postgres.RetentionPurge{}, nil)
+
+	report, err := svc.Purge(context.Background(), RetentionEndedLeases, false)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Records)
+	assert.Equal(t, 2, report.Files)
+	mockQuerier.AssertExpectations(t)
+}
+
+func TestPurgeExpiredDossiers(t *testing.T) {
+	svc, mockQuerier, mockFileStore := setupRetention()
+
+	mockQuerier.On("DeleteExpiredTenantDossiers", mock.Anything, mock.MatchedBy(func(cutoff pgtype.Timestamp) bool {
+		return cutoff.Time.Before(time.Now().AddDate(0, 0, -29))
+	})).Return([]postgres.DeleteExpiredTenantDossiersRow{
+		{ID: 4, DocumentsJson: docsJSON(t, "dossiers/2/payslip_a.pdf", "dossiers/2/identity_b.pdf")},
+		{ID: 6},
+	}, nil)
+	for _, key := range []string{"dossiers/2/payslip_a.pdf", "dossiers/2/identity_b.pdf"} {
+		mockFileStore.On("Delete", key).Return(nil).Once()
+	}
+	mockQuerier.On("CreateRetentionPurge", mock.Anything, mock.MatchedBy(func(p postgres.CreateRetentionPurgeParams) bool {
+		return p.Category == RetentionExpiredDossiers && p.RetentionDays == 30 && p.Records == 2 && p.Files == 2 && string(p.RecordIds) == "[4,6]"
+	})).Return(postgres.RetentionPurge{}, nil).Once()
+
+	report, err := svc.Purge(context.Background(), RetentionExpiredDossiers, false)
+
+	require.NoError(t, err)
+	assert.Equal(t, []int32{4, 6}, report.RecordIDs)
+	mockQuerier.AssertExpectations(t)
+	mockFileStore.AssertExpectations(t)
+}
+
+func TestRun_ContinuesAfterFailure(t *testing.T) {
+	svc, mockQuerier, _ := setupRetention()
+	mockQuerier.On("ListSolvencyChecksForDocumentPurge", mock.Anything, mock.Anything).Return([]postgres.ListSolvencyChecksForDocumentPurgeRow{}, assert.AnError)
+	mockQuerier.On("ListSolvencyChecksForAnonymization", mock.Anything, mock.Anything).Return([]postgres.ListSolvencyChecksForAnonymizationRow{}, nil)
+	mockQuerier.On("ListLeasesForAnonymization", mock.Anything, mock.Anything).Return([]int32{}, nil)
+	mockQuerier.On("DeleteExpiredTenantDossiers", mock.Anything, mock.Anything).Return([]postgres.DeleteExpiredTenantDossiersRow{}, nil)
+	mockQuerier.On("DeleteDocumentAccessLogsBefore", mock.Anything, mock.Anything).Return(int64(0), nil)
+	mockQuerier.On("DeleteDocumentLinksBefore", mock.Anything, mock.Anything).Return(int64(0), nil)
+	mockQuerier.On("DeleteWebhookEventsBefore", mock.Anything, mock.Anything).Return(int64(2), nil)
+	mockQuerier.On("CreateRetentionPurge", mock.Anything, mock.MatchedBy(func(p postgres.CreateRetentionPurgeParams) bool {
+		return p.Category == RetentionLogs && p.Records == 2
+	})).Return(postgres.RetentionPurge{}, nil).Once()
+	mockQuerier.On("CleanupProvisionalUsers", mock.Anything, mock.Anything).Return([]int32{}, nil)
+
+	reports, err := svc.Run(context.Background(), false)
+
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Len(t, reports, len(RetentionCategories))
+	mockQuerier.AssertExpectations(t)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -20,6 +21,23 @@ import (
 type SolvencyService struct {
 	txManager   TxManager
 	emailSender email.EmailSender
+	storage     FileStorage
+	bank        OpenBankingProvider
+	renderPDF   func(html []byte) ([]byte, error)
+
+	MaxCandidateDocumentBytes int64
+	// MinScore is the income analysis score from which a check is approved
+	MinScore int
+	// AllowRawTransactions enables the unauthenticated transactions callback (fake provider only)
+	AllowRawTransactions bool
+	// CheckExpiryDays is how long a candidate has to complete a check unless the owner chose otherwise
+	CheckExpiryDays int
+	// ReminderDays are the days after creation on which the candidate is reminded
+	ReminderDays []int
+	// DossierValidityDays is how long a portable tenant dossier can be shared after its bank data
+	DossierValidityDays int
+	// DossierConsumesCredit makes a check created from a shared dossier consume a credit like any other
+	DossierConsumesCredit bool
 }
 
 // ErrInsufficientCredits is returned when no credits are available.
@@ -32,11 +50,36 @@ func (e *ErrInsufficientCredits) Error() string {
 	return "insufficient credits for solvency check"
 }
 
-func NewSolvencyService(txManager TxManager, emailSender email.EmailSender, l *zap.Logger) *SolvencyService {
-	return &SolvencyService{
-		txManager:   txManager,
-		emailSender: emailSender,
+func NewSolvencyService(txManager TxManager, emailSender email.EmailSender, l *zap.Logger, storage FileStorage, bank OpenBankingProvider) *SolvencyService {
+	s := &SolvencyService{
+		txManager:                 txManager,
+		emailSender:               emailSender,
+		storage:                   storage,
+		bank:                      bank,
+		renderPDF:                 htmlToPDF,
+		MaxCandidateDocumentBytes: viper.GetInt64("SOLVENCY_MAX_DOCUMENT_BYTES"),
+		MinScore:                  viper.GetInt("SOLVENCY_MIN_SCORE"),
+		CheckExpiryDays:           viper.GetInt("SOLVENCY_CHECK_EXPIRY_DAYS"),
+		ReminderDays:              parseReminderDays(viper.GetString("SOLVENCY_REMINDER_DAYS")),
+		DossierValidityDays:       viper.GetInt("SOLVENCY_DOSSIER_VALIDITY_DAYS"),
+		DossierConsumesCredit:     viper.GetBool("SOLVENCY_DOSSIER_CONSUMES_CREDIT"),
 	}
+	if s.MaxCandidateDocumentBytes <= 0 {
+		s.MaxCandidateDocumentBytes = defaultMaxCandidateDocumentBytes
+	}
+	if s.MinScore <= 0 {
+		s.MinScore = defaultMinSolvencyScore
+	}
+	if s.CheckExpiryDays <= 0 {
+		s.CheckExpiryDays = defaultCheckExpiryDays
+	}
+	if s.ReminderDays == nil {
+		s.ReminderDays = defaultCheckReminderDays
+	}
+	if s.DossierValidityDays <= 0 {
+		s.DossierValidityDays = defaultDossierValidityDays
+	}
+	return s
 }
 
 type InitiateCheckParams struct {
@@ -46,6 +89,8 @@ type InitiateCheckParams struct {
 	CandidateLastName  string
 	CandidatePhone     string
 	PropertyID         int32
+	// ExpiresInDays overrides CheckExpiryDays for this check (0 keeps the default)
+	ExpiresInDays int
 }
 
 func (s *SolvencyService) InitiateCheck(ctx context.Context, params InitiateCheckParams) (*postgres.SolvencyCheck, error) {
@@ -68,48 +113,9 @@ func (s *SolvencyService) InitiateCheck(ctx context.Context, params InitiateChec
 		}
 
 		// 2. Hybrid Credit Deduction
-		usedSource := "global"
-		var globalBalance int32
-		if prop.VacancyCredits > 0 {
-			err = q.DecreasePropertyCredits(ctx, params.PropertyID)
-			if err != nil {
-				return fmt.Errorf("failed to deduct property credit: %w", err)
-			}
-			usedSource = "property"
-		} else {
-			// Fallback to Global Credits (with Lock on User)
-			_, err = q.GetUserForUpdate(ctx, params.UserID)
-			if err != nil {
-				return fmt.Errorf("failed to lock user for credit deduction: %w", err)
-			}
-
-			balance, err := q.GetUserCreditBalanceForUpdate(ctx, pgtype.Int4{Int32: params.UserID, Valid: true})
-			if err != nil {
-				if err == pgx.ErrNoRows {
-					balance = 0
-				} else {
-					return err
-				}
-			}
-			globalBalance = balance
-
-			if balance <= 0 {
-				return &ErrInsufficientCredits{
-					GlobalBalance:   globalBalance,
-					PropertyBalance: prop.VacancyCredits,
-				}
-			}
-
-			// Consume Global Credit
-			_, err = q.CreateCreditTransaction(ctx, postgres.CreateCreditTransactionParams{
-				UserID:          pgtype.Int4{Int32: params.UserID, Valid: true},
-				Amount:          -1,
-				TransactionType: "check_usage",
-				Description:     pgtype.Text{String: "Solvency Check Request (Global Wallet)", Valid: true},
-			})
-			if err != nil {
-				return fmt.Errorf("failed to deduct global credit: %w", err)
-			}
+		usedSource, usage, err := consumeCheckCredit(ctx, q, prop, params.UserID)
+		if err != nil {
+			return err
 		}
 
 		// 3. Find or Create Candidate (User)
@@ -142,12 +148,18 @@ func (s *SolvencyService) InitiateCheck(ctx context.Context, params InitiateChec
 		tokenValue := hex.EncodeToString(bytes)
 
 		// 5. Create Solvency Check
+		expiryDays := s.CheckExpiryDays
+		if params.ExpiresInDays > 0 {
+			expiryDays = params.ExpiresInDays
+		}
 		check, err = q.CreateSolvencyCheck(ctx, postgres.CreateSolvencyCheckParams{
-			InitiatorOwnerID: pgtype.Int4{Int32: params.UserID, Valid: true},
-			CandidateID:      pgtype.Int4{Int32: candidateID, Valid: true},
-			Token:            pgtype.Text{String: tokenValue, Valid: true},
-			PropertyID:       pgtype.Int4{Int32: params.PropertyID, Valid: true},
-			CreditSource:     pgtype.Text{String: usedSource, Valid: true},
+			InitiatorOwnerID:    pgtype.Int4{Int32: params.UserID, Valid: true},
+			CandidateID:         pgtype.Int4{Int32: candidateID, Valid: true},
+			Token:               pgtype.Text{String: tokenValue, Valid: true},
+			PropertyID:          pgtype.Int4{Int32: params.PropertyID, Valid: true},
+			CreditSource:        pgtype.Text{String: usedSource, Valid: true},
+			ExpiresAt:           pgtype.Timestamp{Time: time.Now().AddDate(0, 0, expiryDays), Valid: true},
+			CreditTransactionID: pgtype.Int4{Int32: usage.ID, Valid: true},
 		})
 		if err != nil {
 			return fmt.Errorf("failed to create solvency check: %w", err)
@@ -186,50 +198,10 @@ func (s *SolvencyService) InitiateCheck(ctx context.Context, params InitiateChec
 	return &check, nil
 }
 
-// PurchaseCredits allows users to buy credit packs (e.g., 20 checks).
-func (s *SolvencyService) PurchaseCredits(ctx context.Context, userID int32, packType string) (int32, error) {
-	log := logger.FromContext(ctx)
-
-	var amount int32
-	var cost float64
-
-	switch packType {
-	case "pack_20":
-		amount = 20
-		cost = 19.90 // Assigning arbitrary price for logic completeness, e.g. 1€/unit approx
-	default:
-		return 0, fmt.Errorf("invalid pack type: %s", packType)
-	}
-
-	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
-		_, err := q.CreateCreditTransaction(ctx, postgres.CreateCreditTransactionParams{
-			UserID:          pgtype.Int4{Int32: userID, Valid: true},
-			Amount:          amount,
-			TransactionType: "pack_purchase",
-			Description:     pgtype.Text{String: fmt.Sprintf("Purchase %s", packType), Valid: true},
-		})
-		if err != nil {
-			return err
-		}
-
-		log.Info("credit pack purchased",
-			zap.Int("user_id", int(userID)),
-			zap.String("pack", packType),
-			zap.Int("amount_added", int(amount)),
-			zap.Float64("cost", cost),
-		)
-		return nil
-	})
-
-	if err != nil {
-		return 0, err
-	}
-
-	return amount, nil
-}
-
 type SolvencyCheckEnriched struct {
 	postgres.SolvencyCheck
+	Documents          []CandidateDocumentDTO
+	Missing            []MissingDocument
 	CandidateEmail     string
 	CandidateFirstName string
 	CandidateLastName  string
@@ -254,7 +226,14 @@ func (s *SolvencyService) GetCheckByToken(ctx context.Context, token string) (*S
 			PropertyID:       row.PropertyID,
 			Status:           row.Status,
 			CreatedAt:        row.CreatedAt,
+			ExpiresAt:        row.ExpiresAt,
+			DocumentsJson:    row.DocumentsJson,
+			MissingDocuments: row.MissingDocuments,
+			EmploymentType:   row.EmploymentType,
+			GuaranteeType:    row.GuaranteeType,
 		}
+		check.Documents = CandidateDocumentsFromJSON(row.DocumentsJson)
+		check.Missing = MissingDocumentsFromJSON(row.MissingDocuments)
 		check.CandidateEmail = row.CandidateEmail
 		check.CandidateFirstName = row.CandidateFirstName.String
 		check.CandidateLastName = row.CandidateLastName.String
@@ -303,11 +282,17 @@ type TransactionData struct {
 	Amount      float64   `json:"amount"`
 	Description string    `json:"description"`
 	Date        time.Time `json:"date"`
+	// Counterparty is optional: when the provider does not send it, it is derived from the description
+	Counterparty string `json:"counterparty,omitempty"`
 }
 
-// ProcessOpenBankingResult analyzes transactions and updates the solvency check status.
+// ProcessOpenBankingResult analyzes transactions posted directly through the public callback.
+// Only honoured when AllowRawTransactions is set (fake provider, local and test environments):
+// otherwise anyone holding the token could submit forged transactions.
 func (s *SolvencyService) ProcessOpenBankingResult(ctx context.Context, token string, transactions []TransactionData) error {
-	log := logger.FromContext(ctx)
+	if !s.AllowRawTransactions {
+		return ErrRawTransactionsDisabled
+	}
 
 	check, err := s.GetCheckByToken(ctx, token)
 	if err != nil {
@@ -315,70 +300,182 @@ func (s *SolvencyService) ProcessOpenBankingResult(ctx context.Context, token st
 	}
 
 	if check.Status.SolvencyStatus != postgres.SolvencyStatusPending {
-		return fmt.Errorf("check already processed")
+		return ErrCheckAlreadyProcessed
 	}
 
-	// 1. Fetch Property to get Rent Amount
-	var rentAmount float64
-	err = s.txManager.WithTx(ctx, func(q postgres.Querier) error {
-		prop, err := q.GetProperty(ctx, check.PropertyID.Int32)
+	return s.analyzeTransactions(ctx, check.ID, transactions)
+}
+
+// decisionInputs is what a check decision depends on besides the candidate's own analysis.
+type decisionInputs struct {
+	check      postgres.SolvencyCheck
+	rent       float64
+	charges    float64
+	policy     SolvencyPolicy
+	guarantors []IncomeAnalysis
+}
+
+// loadDecisionInputs fetches the check, its property (rent and charges), the owner's acceptance policy
+// and the analyses of the guarantors who already connected their bank.
+func (s *SolvencyService) loadDecisionInputs(ctx context.Context, checkID int32) (*decisionInputs, error) {
+	in := &decisionInputs{policy: defaultSolvencyPolicy()}
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		var err error
+		in.check, err = q.GetSolvencyCheckByID(ctx, checkID)
+		if err != nil {
+			return err
+		}
+		prop, err := q.GetProperty(ctx, in.check.PropertyID.Int32)
 		if err != nil {
 			return err
 		}
 		if prop.RentAmount.Valid {
 			f, _ := prop.RentAmount.Float64Value()
-			rentAmount = f.Float64
+			in.rent = f.Float64
+		}
+		if prop.RentChargesAmount.Valid {
+			f, _ := prop.RentChargesAmount.Float64Value()
+			in.charges = f.Float64
+		}
+
+		row, err := q.GetEffectiveSolvencyPolicy(ctx, postgres.GetEffectiveSolvencyPolicyParams{
+			OwnerID:    in.check.InitiatorOwnerID.Int32,
+			PropertyID: in.check.PropertyID,
+		})
+		if err == nil {
+			in.policy = policyFromRow(row)
+		} else if err != pgx.ErrNoRows {
+			return err
+		}
+
+		guarantors, err := q.ListGuarantorsByCheck(ctx, checkID)
+		if err != nil {
+			return err
+		}
+		for _, g := range guarantors {
+			if a := IncomeAnalysisFromJSON(g.AnalysisJson); a != nil {
+				in.guarantors = append(in.guarantors, *a)
+			}
 		}
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+	return in, nil
+}
+
+// analyzeTransactions runs the income analysis against the property rent and stores the decision.
+func (s *SolvencyService) analyzeTransactions(ctx context.Context, checkID int32, transactions []TransactionData) error {
+	in, err := s.loadDecisionInputs(ctx, checkID)
 	if err != nil {
 		return err
 	}
 
-	// 2. Simple Salary Detection (Heuristic: regular inbound amounts)
-	// In a real app, this would be much more sophisticated (looking for "SALARY", "VIR", etc.)
-	var totalMonthlyIncome float64
-	// Simple mock logic: sum positive transactions and divide by months (let's assume 3 months of data)
-	for _, tx := range transactions {
-		if tx.Amount > 0 {
-			totalMonthlyIncome += tx.Amount
-		}
+	// Income analysis: recurring income and debits, exclusions, score with explained factors
+	return s.decide(ctx, in, AnalyzeIncome(transactions, in.rent))
+}
+
+// refreshDecision re-evaluates a processed check, e.g. once a guarantor's income has been analyzed.
+// Checks still waiting for the candidate's bank data are decided when it arrives.
+func (s *SolvencyService) refreshDecision(ctx context.Context, checkID int32) error {
+	in, err := s.loadDecisionInputs(ctx, checkID)
+	if err != nil {
+		return err
+	}
+	status := in.check.Status.SolvencyStatus
+	analysis := IncomeAnalysisFromJSON(in.check.AnalysisJson)
+	if analysis == nil || (status != postgres.SolvencyStatusApproved && status != postgres.SolvencyStatusRejected) {
+		return nil
+	}
+	return s.decide(ctx, in, *analysis)
+}
+
+// decide applies the owner's policy to the analysis, stores the result and regenerates the report.
+func (s *SolvencyService) decide(ctx context.Context, in *decisionInputs, analysis IncomeAnalysis) error {
+	log := logger.FromContext(ctx)
+	checkID := in.check.ID
+
+	analysisJSON, err := json.Marshal(analysis)
+	if err != nil {
+		return fmt.Errorf("failed to encode analysis: %w", err)
+	}
+
+	// Candidate and guarantors together
+	var combinedScore pgtype.Int4
+	var guarantorIncome float64
+	if len(in.guarantors) > 0 {
+		combined := CombineIncomeAnalyses(analysis, in.guarantors)
+		combinedScore = pgtype.Int4{Int32: int32(combined.Score), Valid: true}
+		guarantorIncome = round2(combined.MonthlyIncome - analysis.MonthlyIncome)
 	}
-	// Assume 3 months of data provided
-	avgMonthlyIncome := totalMonthlyIncome / 3.0
 
-	// 3. Apply 3x Rent Rule
+	// Decision: every rule of the owner's policy must pass
+	accepted, results := EvaluatePolicy(in.policy, PolicyInput{
+		Score:           analysis.Score,
+		MinScore:        s.MinScore,
+		CombinedScore:   int(combinedScore.Int32),
+		MonthlyIncome:   analysis.MonthlyIncome,
+		GuarantorIncome: guarantorIncome,
+		Rent:            in.rent,
+		Charges:         in.charges,
+		EmploymentType:  in.check.EmploymentType.String,
+		GuaranteeType:   in.check.GuaranteeType.String,
+	})
+	resultsJSON, err := json.Marshal(results)
+	if err != nil {
+		return fmt.Errorf("failed to encode policy results: %w", err)
+	}
 	status := postgres.SolvencyStatusRejected
-	if avgMonthlyIncome >= (rentAmount * 3.0) {
+	if accepted {
 		status = postgres.SolvencyStatusApproved
 	}
 
-	// 4. Update Result
 	err = s.txManager.WithTx(ctx, func(q postgres.Querier) error {
-		return q.UpdateSolvencyCheckResult(ctx, postgres.UpdateSolvencyCheckResultParams{
-			ID:          check.ID,
-			Status:      postgres.NullSolvencyStatus{SolvencyStatus: status, Valid: true},
-			ScoreResult: pgtype.Int4{Int32: int32(avgMonthlyIncome), Valid: true},
-			ReportUrl:   pgtype.Text{String: "http://report-url.pdf", Valid: true}, // Mock
+		rows, err := q.UpdateSolvencyCheckResult(ctx, postgres.UpdateSolvencyCheckResultParams{
+			ID:            checkID,
+			CurrentStatus: in.check.Status,
+			Status:        postgres.NullSolvencyStatus{SolvencyStatus: status, Valid: true},
+			ScoreResult:   pgtype.Int4{Int32: int32(analysis.Score), Valid: true},
+			ReportUrl:     pgtype.Text{String: solvencyReportURL(checkID), Valid: true},
+			AnalysisJson:  analysisJSON,
+			PolicyResults: resultsJSON,
+			CombinedScore: combinedScore,
 		})
+		if err == nil && rows == 0 {
+			// Cancelled or expired since the inputs were read
+			return ErrCheckAlreadyProcessed
+		}
+		return err
 	})
+	if err != nil {
+		return err
+	}
 
 	log.Info("solvency check processed",
-		zap.Int("check_id", int(check.ID)),
+		zap.Int("check_id", int(checkID)),
 		zap.String("status", string(status)),
-		zap.Float64("income", avgMonthlyIncome),
-		zap.Float64("rent", rentAmount))
-
-	return err
+		zap.Int("score", analysis.Score),
+		zap.Int("guarantors", len(in.guarantors)),
+		zap.Float64("income", analysis.MonthlyIncome),
+		zap.Float64("rent", in.rent),
+		zap.String("policy", in.policy.Scope))
+
+	// The decision stands even if the report cannot be produced now: it is generated on first download
+	if s.storage != nil {
+		if _, err := s.generateReport(ctx, checkID); err != nil {
+			log.Error("failed to generate solvency report", zap.Int32("check_id", checkID), zap.Error(err))
+		}
+	}
+	return nil
 }
 
-// CancelCheck cancels a pending solvency check and refunds the credit
+// CancelCheck cancels a solvency check still waiting for the candidate (pending, or stuck on insufficient
+// documents) and refunds the credit
 func (s *SolvencyService) CancelCheck(ctx context.Context, checkID int32, ownerID int32) error {
-	log := logger.FromContext(ctx).With(zap.Int32("check_id", checkID))
-
 	return s.txManager.WithTx(ctx, func(q postgres.Querier) error {
-		// 1. Fetch check and verify ownership/status
-		check, err := q.GetSolvencyCheckByID(ctx, checkID)
+		// 1. Lock check and verify ownership/status (the expiry job may be expiring and refunding it)
+		check, err := q.GetSolvencyCheckForUpdate(ctx, checkID)
 		if err != nil {
 			return fmt.Errorf("failed to fetch check: %w", err)
 		}
@@ -387,37 +484,112 @@ func (s *SolvencyService) CancelCheck(ctx context.Context, checkID int32, ownerI
 			return fmt.Errorf("unauthorized: you don't own this check")
 		}
 
-		if check.Status.SolvencyStatus != postgres.SolvencyStatusPending {
+		switch check.Status.SolvencyStatus {
+		case postgres.SolvencyStatusPending, postgres.SolvencyStatusInsufficientDocs:
+		default:
 			return fmt.Errorf("cannot cancel check with status: %s", check.Status.SolvencyStatus)
 		}
 
 		// 2. Mark as cancelled
-		err = q.CancelSolvencyCheck(ctx, checkID)
+		rows, err := q.CancelSolvencyCheck(ctx, checkID)
 		if err != nil {
 			return fmt.Errorf("failed to cancel check: %w", err)
 		}
+		if rows == 0 {
+			return ErrCheckAlreadyProcessed
+		}
 
 		// 3. Refund credit
-		source := check.CreditSource.String
-		if source == "property" {
-			err = q.IncreasePropertyCredits(ctx, check.PropertyID.Int32)
-			if err != nil {
-				return fmt.Errorf("failed to refund property credit: %w", err)
-			}
-			log.Info("refunded property credit", zap.Int32("property_id", check.PropertyID.Int32))
-		} else if source == "global" {
-			_, err = q.CreateCreditTransaction(ctx, postgres.CreateCreditTransactionParams{
-				UserID:          check.InitiatorOwnerID,
-				Amount:          1,
-				TransactionType: "refund",
-				Description:     pgtype.Text{String: fmt.Sprintf("Refund for cancelled solvency check #%d", checkID), Valid: true},
-			})
-			if err != nil {
-				return fmt.Errorf("failed to refund global credit: %w", err)
-			}
-			log.Info("refunded global credit")
+		return refundCheckCredit(ctx, q, check, fmt.Sprintf("Refund for cancelled solvency check #%d", checkID))
+	})
+}
+
+// consumeCheckCredit takes the credit of a new check from the property's wallet, else from the owner's
+// global wallet, and returns the source used so that refundCheckCredit can give it back, with the ledger
+// line debited. The property must be locked by the caller.
+func consumeCheckCredit(ctx context.Context, q postgres.Querier, prop postgres.Property, ownerID int32) (string, postgres.CreditTransaction, error) {
+	if prop.VacancyCredits > 0 {
+		usage, err := q.CreateCreditTransaction(ctx, postgres.CreateCreditTransactionParams{
+			UserID:          pgtype.Int4{Int32: ownerID, Valid: true},
+			PropertyID:      pgtype.Int4{Int32: prop.ID, Valid: true},
+			Amount:          -1,
+			TransactionType: "check_usage",
+			Description:     pgtype.Text{String: "Solvency Check Request (Property Wallet)", Valid: true},
+		})
+		if err != nil {
+			return "", usage, fmt.Errorf("failed to deduct property credit: %w", err)
 		}
+		return "property", usage, nil
+	}
 
-		return nil
+	// Fallback to Global Credits (with Lock on User)
+	if _, err := q.GetUserForUpdate(ctx, ownerID); err != nil {
+		return "", postgres.CreditTransaction{}, fmt.Errorf("failed to lock user for credit deduction: %w", err)
+	}
+
+	balance, err := q.GetUserCreditBalanceForUpdate(ctx, ownerID)
+	if err != nil {
+		if err != pgx.ErrNoRows {
+			return "", postgres.CreditTransaction{}, err
+		}
+		balance = 0
+	}
+	if balance <= 0 {
+		return "", postgres.CreditTransaction{}, &ErrInsufficientCredits{
+			GlobalBalance:   balance,
+			PropertyBalance: prop.VacancyCredits,
+		}
+	}
+
+	// Consume Global Credit
+	usage, err := q.CreateCreditTransaction(ctx, postgres.CreateCreditTransactionParams{
+		UserID:          pgtype.Int4{Int32: ownerID, Valid: true},
+		Amount:          -1,
+		TransactionType: "check_usage",
+		Description:     pgtype.Text{String: "Solvency Check Request (Global Wallet)", Valid: true},
 	})
+	if err != nil {
+		return "", usage, fmt.Errorf("failed to deduct global credit: %w", err)
+	}
+	return "global", usage, nil
+}
+
+// refundCheckCredit returns the credit consumed by a check to where it was taken from:
+// the property's vacancy credits or the owner's global wallet. The refund names the consumed line,
+// which the ledger refunds only once.
+func refundCheckCredit(ctx context.Context, q postgres.Querier, check postgres.SolvencyCheck, description string) error {
+	log := logger.FromContext(ctx).With(zap.Int32("check_id", check.ID))
+
+	switch check.CreditSource.String {
+	case "property":
+		// The property before its wallet, as when the credit was consumed
+		if _, err := q.GetPropertyForUpdate(ctx, check.PropertyID.Int32); err != nil {
+			return fmt.Errorf("failed to refund property credit: %w", err)
+		}
+		_, err := q.CreateCreditTransaction(ctx, postgres.CreateCreditTransactionParams{
+			UserID:          check.InitiatorOwnerID,
+			PropertyID:      check.PropertyID,
+			Amount:          1,
+			TransactionType: "refund",
+			Description:     pgtype.Text{String: description, Valid: true},
+			RefundOf:        check.CreditTransactionID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to refund property credit: %w", err)
+		}
+		log.Info("refunded property credit", zap.Int32("property_id", check.PropertyID.Int32))
+	case "global":
+		_, err := q.CreateCreditTransaction(ctx, postgres.CreateCreditTransactionParams{
+			UserID:          check.InitiatorOwnerID,
+			Amount:          1,
+			TransactionType: "refund",
+			Description:     pgtype.Text{String: description, Valid: true},
+			RefundOf:        check.CreditTransactionID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to refund global credit: %w", err)
+		}
+		log.Info("refunded global credit")
+	}
+	return nil
 }
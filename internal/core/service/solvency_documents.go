@@ -0,0 +1,380 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+	"seculoc-back/internal/platform/logger"
+)
+
+const defaultMaxCandidateDocumentBytes = 10 << 20 // 10 MB
+
+var (
+	ErrCheckClosedForDocuments    = errors.New("this check no longer accepts documents")
+	ErrCandidateDocumentLimit     = errors.New("maximum number of documents reached for this type")
+	ErrCandidateDocumentNotFound  = errors.New("candidate document not found")
+	ErrInvalidCandidateDocType    = errors.New("invalid document type")
+	ErrCheckNotOwned              = errors.New("unauthorized: you don't own this check")
+	ErrCannotRequestMoreDocuments = errors.New("documents can only be requested on a pending check")
+)
+
+// CandidateDocumentType describes a piece of the rental application ("dossier locataire").
+type CandidateDocumentType struct {
+	Label    string
+	MaxFiles int
+}
+
+// CandidateDocumentTypes lists the documents a candidate can upload, with the number of files allowed
+// (e.g. front and back of an ID card, the last three payslips).
+var CandidateDocumentTypes = map[string]CandidateDocumentType{
+	"identity":            {Label: "Pièce d'identité", MaxFiles: 2},
+	"payslip":             {Label: "Bulletin de salaire", MaxFiles: 3},
+	"tax_notice":          {Label: "Avis d'imposition", MaxFiles: 2},
+	"employment_contract": {Label: "Contrat de travail", MaxFiles: 2},
+}
+
+// CandidateDocument is an entry of solvency_checks.documents_json.
+type CandidateDocument struct {
+	ID          string    `json:"id"`
+	Type        string    `json:"type"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	SizeBytes   int64     `json:"size_bytes"`
+	StorageKey  string    `json:"storage_key"`
+	UploadedAt  time.Time `json:"uploaded_at"`
+}
+
+// CandidateDocumentDTO is the public view of a CandidateDocument (the storage key is never exposed).
+type CandidateDocumentDTO struct {
+	ID          string `json:"id"`
+	Type        string `json:"type"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	SizeBytes   int64  `json:"size_bytes"`
+	UploadedAt  string `json:"uploaded_at"`
+}
+
+// MissingDocument is an entry of solvency_checks.missing_documents.
+type MissingDocument struct {
+	Type    string `json:"type" binding:"required"`
+	Comment string `json:"comment,omitempty" binding:"max=500"`
+}
+
+func (d CandidateDocument) toDTO() CandidateDocumentDTO {
+	return CandidateDocumentDTO{
+		ID:          d.ID,
+		Type:        d.Type,
+		Filename:    d.Filename,
+		ContentType: d.ContentType,
+		SizeBytes:   d.SizeBytes,
+		UploadedAt:  d.UploadedAt.Format(time.RFC3339),
+	}
+}
+
+func decodeCandidateDocuments(raw []byte) []CandidateDocument {
+	docs := []CandidateDocument{}
+	if len(raw) > 0 {
+		_ = json.Unmarshal(raw, &docs)
+	}
+	return docs
+}
+
+// CandidateDocumentsFromJSON decodes documents_json for API responses.
+func CandidateDocumentsFromJSON(raw []byte) []CandidateDocumentDTO {
+	dtos := []CandidateDocumentDTO{}
+	for _, d := range decodeCandidateDocuments(raw) {
+		dtos = append(dtos, d.toDTO())
+	}
+	return dtos
+}
+
+// MissingDocumentsFromJSON decodes missing_documents for API responses.
+func MissingDocumentsFromJSON(raw []byte) []MissingDocument {
+	missing := []MissingDocument{}
+	if len(raw) > 0 {
+		_ = json.Unmarshal(raw, &missing)
+	}
+	return missing
+}
+
+func acceptsDocuments(status postgres.SolvencyStatus) bool {
+	return status == postgres.SolvencyStatusPending || status == postgres.SolvencyStatusInsufficientDocs
+}
+
+// saveCheckDocuments writes both JSON columns. Once every missing piece has been provided,
+// the check goes back to 'pending' so it can be processed.
+func saveCheckDocuments(ctx context.Context, q postgres.Querier, check postgres.SolvencyCheck, docs []CandidateDocument, missing []MissingDocument) error {
+	docsJSON, err := json.Marshal(docs)
+	if err != nil {
+		return err
+	}
+	status := check.Status.SolvencyStatus
+	var missingJSON []byte
+	if len(missing) > 0 {
+		if missingJSON, err = json.Marshal(missing); err != nil {
+			return err
+		}
+	} else if status == postgres.SolvencyStatusInsufficientDocs {
+		status = postgres.SolvencyStatusPending
+	}
+
+	return q.UpdateSolvencyCheckDocuments(ctx, postgres.UpdateSolvencyCheckDocumentsParams{
+		ID:               check.ID,
+		DocumentsJson:    docsJSON,
+		MissingDocuments: missingJSON,
+		Status:           postgres.NullSolvencyStatus{SolvencyStatus: status, Valid: true},
+	})
+}
+
+func getCheckByTokenForUpdate(ctx context.Context, q postgres.Querier, token string) (postgres.SolvencyCheck, error) {
+	check, err := q.GetSolvencyCheckByTokenForUpdate(ctx, pgtype.Text{String: token, Valid: true})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return check, fmt.Errorf("check not found")
+		}
+		return check, err
+	}
+	return check, nil
+}
+
+// UploadCandidateDocument stores a document uploaded by the candidate through the check token.
+// Files go through the storage layer, which encrypts them at rest when master keys are configured.
+func (s *SolvencyService) UploadCandidateDocument(ctx context.Context, token, docType, filename string, content []byte) (*CandidateDocumentDTO, error) {
+	log := logger.FromContext(ctx)
+
+	spec, ok := CandidateDocumentTypes[docType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidCandidateDocType, docType)
+	}
+	if len(content) == 0 {
+		return nil, fmt.Errorf("empty file")
+	}
+	if int64(len(content)) > s.MaxCandidateDocumentBytes {
+		return nil, ErrMediaTooLarge
+	}
+	contentType, ext, err := sniffContentType(content, documentContentTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := CandidateDocument{
+		ID:          generateToken()[:16],
+		Type:        docType,
+		Filename:    filepath.Base(filename),
+		ContentType: contentType,
+		SizeBytes:   int64(len(content)),
+		UploadedAt:  time.Now().UTC().Truncate(time.Second),
+	}
+
+	var checkID int32
+	err = s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		check, err := getCheckByTokenForUpdate(ctx, q, token)
+		if err != nil {
+			return err
+		}
+		if !acceptsDocuments(check.Status.SolvencyStatus) {
+			return ErrCheckClosedForDocuments
+		}
+		checkID = check.ID
+
+		docs := decodeCandidateDocuments(check.DocumentsJson)
+		count := 0
+		for _, d := range docs {
+			if d.Type == docType {
+				count++
+			}
+		}
+		if count >= spec.MaxFiles {
+			return fmt.Errorf("%w (%s: %d)", ErrCandidateDocumentLimit, docType, spec.MaxFiles)
+		}
+
+		// A document of a requested type satisfies the request
+		var missing []MissingDocument
+		for _, m := range MissingDocumentsFromJSON(check.MissingDocuments) {
+			if m.Type != docType {
+				missing = append(missing, m)
+			}
+		}
+
+		doc.StorageKey = fmt.Sprintf("solvency/%d/%s_%s%s", check.ID, docType, doc.ID, ext)
+		if _, err := s.storage.Save(doc.StorageKey, content); err != nil {
+			return fmt.Errorf("failed to store document: %w", err)
+		}
+
+		return saveCheckDocuments(ctx, q, check, append(docs, doc), missing)
+	})
+	if err != nil {
+		if doc.StorageKey != "" {
+			s.removeFiles(ctx, doc.StorageKey)
+		}
+		return nil, err
+	}
+
+	log.Info("candidate document uploaded", zap.Int32("check_id", checkID), zap.String("type", docType), zap.Int("size", len(content)))
+	dto := doc.toDTO()
+	return &dto, nil
+}
+
+// DeleteCandidateDocument lets the candidate remove a document while the check is still open.
+func (s *SolvencyService) DeleteCandidateDocument(ctx context.Context, token, documentID string) error {
+	var removed CandidateDocument
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		check, err := getCheckByTokenForUpdate(ctx, q, token)
+		if err != nil {
+			return err
+		}
+		if !acceptsDocuments(check.Status.SolvencyStatus) {
+			return ErrCheckClosedForDocuments
+		}
+
+		docs := decodeCandidateDocuments(check.DocumentsJson)
+		kept := make([]CandidateDocument, 0, len(docs))
+		for _, d := range docs {
+			if d.ID == documentID {
+				removed = d
+				continue
+			}
+			kept = append(kept, d)
+		}
+		if removed.ID == "" {
+			return ErrCandidateDocumentNotFound
+		}
+		return saveCheckDocuments(ctx, q, check, kept, MissingDocumentsFromJSON(check.MissingDocuments))
+	})
+	if err != nil {
+		return err
+	}
+
+	s.removeFiles(ctx, removed.StorageKey)
+	return nil
+}
+
+// OpenCandidateDocument streams a candidate document to the owner who initiated the check.
+// The caller must close the reader.
+func (s *SolvencyService) OpenCandidateDocument(ctx context.Context, ownerID, checkID int32, documentID string) (io.ReadCloser, *CandidateDocumentDTO, error) {
+	var doc CandidateDocument
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		check, err := q.GetSolvencyCheckByID(ctx, checkID)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return fmt.Errorf("check not found")
+			}
+			return err
+		}
+		if check.InitiatorOwnerID.Int32 != ownerID {
+			return ErrCheckNotOwned
+		}
+		for _, d := range decodeCandidateDocuments(check.DocumentsJson) {
+			if d.ID == documentID {
+				doc = d
+				return nil
+			}
+		}
+		return ErrCandidateDocumentNotFound
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reader, err := s.storage.Open(doc.StorageKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open document: %w", err)
+	}
+	logger.FromContext(ctx).Info("candidate document accessed", zap.Int32("check_id", checkID), zap.Int32("owner_id", ownerID), zap.String("document_id", documentID))
+	dto := doc.toDTO()
+	return reader, &dto, nil
+}
+
+// RequestMissingDocuments moves a check to 'insufficient_docs' and emails the candidate the list of missing pieces.
+// The check returns to 'pending' once the candidate has uploaded a document of each requested type.
+func (s *SolvencyService) RequestMissingDocuments(ctx context.Context, ownerID, checkID int32, missing []MissingDocument) error {
+	log := logger.FromContext(ctx).With(zap.Int32("check_id", checkID))
+
+	if len(missing) == 0 {
+		return fmt.Errorf("at least one missing document is required")
+	}
+	seen := make(map[string]bool)
+	for i, m := range missing {
+		if _, ok := CandidateDocumentTypes[m.Type]; !ok {
+			return fmt.Errorf("%w: %s", ErrInvalidCandidateDocType, m.Type)
+		}
+		if seen[m.Type] {
+			return fmt.Errorf("document type listed twice: %s", m.Type)
+		}
+		seen[m.Type] = true
+		missing[i].Comment = strings.TrimSpace(m.Comment)
+	}
+
+	var candidate postgres.User
+	var token string
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		check, err := q.GetSolvencyCheckForUpdate(ctx, checkID)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return fmt.Errorf("check not found")
+			}
+			return err
+		}
+		if check.InitiatorOwnerID.Int32 != ownerID {
+			return ErrCheckNotOwned
+		}
+		if !acceptsDocuments(check.Status.SolvencyStatus) {
+			return ErrCannotRequestMoreDocuments
+		}
+
+		candidate, err = q.GetUserById(ctx, check.CandidateID.Int32)
+		if err != nil {
+			return fmt.Errorf("failed to load candidate: %w", err)
+		}
+		token = check.Token.String
+
+		check.Status.SolvencyStatus = postgres.SolvencyStatusInsufficientDocs
+		return saveCheckDocuments(ctx, q, check, decodeCandidateDocuments(check.DocumentsJson), missing)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Info("missing documents requested", zap.Int("count", len(missing)))
+
+	// Email OUTSIDE the transaction: the status change stands even if the email fails
+	baseURL := viper.GetString("FRONTEND_URL")
+	if baseURL == "" {
+		baseURL = "https://seculoc.com"
+	}
+	var lines []string
+	for _, m := range missing {
+		line := "- " + CandidateDocumentTypes[m.Type].Label
+		if m.Comment != "" {
+			line += " : " + m.Comment
+		}
+		lines = append(lines, line)
+	}
+	body := fmt.Sprintf("Bonjour,\n\nVotre dossier de location est incomplet. Merci de déposer les pièces suivantes :\n%s\n\nDéposer mes documents : %s/check/%s",
+		strings.Join(lines, "\n"), baseURL, token)
+	if err := s.emailSender.SendNotification(ctx, candidate.Email, "Pièces manquantes dans votre dossier", body); err != nil {
+		log.Error("failed to send missing documents email", zap.Error(err), zap.String("email", candidate.Email))
+	}
+	return nil
+}
+
+// removeFiles deletes stored files, logging failures (orphans are harmless but should be visible).
+func (s *SolvencyService) removeFiles(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		if err := s.storage.Delete(key); err != nil {
+			logger.FromContext(ctx).Warn("failed to delete candidate document", zap.String("key", key), zap.Error(err))
+		}
+	}
+}
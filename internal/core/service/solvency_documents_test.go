@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+)
+
+func setupSolvencyDocuments() (*SolvencyService, *MockQuerier, *MockFileStorage, *mockEmailSender) {
+	mockQuerier := new(MockQuerier)
+	mockFileStore := new(MockFileStorage)
+	mockEmail := new(mockEmailSender)
+	svc := NewSolvencyService(passthroughTxManager{q: mockQuerier}, mockEmail, zap.NewNop(), mockFileStore, nil)
+	return svc, mockQuerier, mockFileStore, mockEmail
+}
+
+func checkWithDocuments(t *testing.T, status postgres.SolvencyStatus, docs []CandidateDocument, missing []MissingDocument) postgres.SolvencyCheck {
+	check := postgres.SolvencyCheck{
+		ID:               7,
+		InitiatorOwnerID: pgtype.Int4{Int32: 1, Valid: true},
+		CandidateID:      pgtype.Int4{Int32: 2, Valid: true},
+		Token:            pgtype.Text{String: "tok", Valid: true},
+		Status:           postgres.NullSolvencyStatus{SolvencyStatus: status, Valid: true},
+	}
+	if docs != nil {
+		raw, err := json.Marshal(docs)
+		require.NoError(t, err)
+		check.DocumentsJson = raw
+	}
+	if missing != nil {
+		raw, err := json.Marshal(missing)
+		require.NoError(t, err)
+		check.MissingDocuments = raw
+	}
+	return check
+}
+
+var samplePDF = []byte("%PDF-1.4 bulletin")
+
+func TestUploadCandidateDocument_ResolvesMissingPieces(t *testing.T) {
+	svc, mockQuerier, mockFileStore, _ := setupSolvencyDocuments()
+
+	check := checkWithDocuments(t, postgres.SolvencyStatusInsufficientDocs, nil, []MissingDocument{{Type: "payslip", Comment: "mars"}})
+	mockQuerier.On("GetSolvencyCheckByTokenForUpdate", mock.Anything, pgtype.Text{String: "tok", Valid: true}).Return(check, nil)
+	mockFileStore.On("Save", mock.MatchedBy(func(k string) bool {
+		return strings.HasPrefix(k, "solvency/7/payslip_") && strings.HasSuffix(k, ".pdf")
+	}), samplePDF).Return("", nil)
+	mockQuerier.On("UpdateSolvencyCheckDocuments", mock.Anything, mock.MatchedBy(func(p postgres.UpdateSolvencyCheckDocumentsParams) bool {
+		docs := decodeCandidateDocuments(p.DocumentsJson)
+		return p.ID == 7 && len(docs) == 1 && docs[0].Type == "payslip" && docs[0].StorageKey != "" &&
+			p.MissingDocuments == nil && p.Status.SolvencyStatus == postgres.SolvencyStatusPending
+	})).Return(nil)
+
+	dto, err := svc.UploadCandidateDocument(context.Background(), "tok", "payslip", "../mars.pdf", samplePDF)
+
+	require.NoError(t, err)
+	assert.Equal(t, "mars.pdf", dto.Filename)
+	assert.Equal(t, "application/pdf", dto.ContentType)
+	mockQuerier.AssertExpectations(t)
+	mockFileStore.AssertExpectations(t)
+}
+
+func TestUploadCandidateDocument_Validation(t *testing.T) {
+	svc, mockQuerier, _, _ := setupSolvencyDocuments()
+
+	_, err := svc.UploadCandidateDocument(context.Background(), "tok", "selfie", "a.pdf", samplePDF)
+	assert.ErrorIs(t, err, ErrInvalidCandidateDocType)
+
+	_, err = svc.UploadCandidateDocument(context.Background(), "tok", "identity", "a.pdf", []byte("<html>not a document</html>"))
+	assert.ErrorIs(t, err, ErrUnsupportedMediaType)
+
+	svc.MaxCandidateDocumentBytes = 4
+	_, err = svc.UploadCandidateDocument(context.Background(), "tok", "identity", "a.pdf", samplePDF)
+	assert.ErrorIs(t, err, ErrMediaTooLarge)
+
+	mockQuerier.AssertNotCalled(t, "GetSolvencyCheckByTokenForUpdate", mock.Anything, mock.Anything)
+}
+
+func TestUploadCandidateDocument_LimitAndClosedCheck(t *testing.T) {
+	svc, mockQuerier, mockFileStore, _ := setupSolvencyDocuments()
+
+	full := checkWithDocuments(t, postgres.SolvencyStatusPending, []CandidateDocument{{ID: "a", Type: "employment_contract"}, {ID: "b", Type: "employment_contract"}}, nil)
+	mockQuerier.On("GetSolvencyCheckByTokenForUpdate", mock.Anything, pgtype.Text{String: "full", Valid: true}).Return(full, nil)
+	_, err := svc.UploadCandidateDocument(context.Background(), "full", "employment_contract", "c.pdf", samplePDF)
+	assert.ErrorIs(t, err, ErrCandidateDocumentLimit)
+
+	closed := checkWithDocuments(t, postgres.SolvencyStatusApproved, nil, nil)
+	mockQuerier.On("GetSolvencyCheckByTokenForUpdate", mock.Anything, pgtype.Text{String: "closed", Valid: true}).Return(closed, nil)
+	_, err = svc.UploadCandidateDocument(context.Background(), "closed", "payslip", "c.pdf", samplePDF)
+	assert.ErrorIs(t, err, ErrCheckClosedForDocuments)
+
+	mockFileStore.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestRequestMissingDocuments_EmailsCandidate(t *testing.T) {
+	svc, mockQuerier, _, mockEmail := setupSolvencyDocuments()
+
+	check := checkWithDocuments(t, postgres.SolvencyStatusPending, []CandidateDocument{{ID: "a", Type: "identity"}}, nil)
+	mockQuerier.On("GetSolvencyCheckForUpdate", mock.Anything, int32(7)).Return(check, nil)
+	mockQuerier.On("GetUserById", mock.Anything, int32(2)).Return(postgres.User{ID: 2, Email: "cand@example.com"}, nil)
+	mockQuerier.On("UpdateSolvencyCheckDocuments", mock.Anything, mock.MatchedBy(func(p postgres.UpdateSolvencyCheckDocumentsParams) bool {
+		missing := MissingDocumentsFromJSON(p.MissingDocuments)
+		return p.Status.SolvencyStatus == postgres.SolvencyStatusInsufficientDocs &&
+			len(missing) == 2 && len(decodeCandidateDocuments(p.DocumentsJson)) == 1
+	})).Return(nil)
+	mockEmail.On("SendNotification", mock.Anything, "cand@example.com", mock.Anything, mock.MatchedBy(func(body string) bool {
+		return strings.Contains(body, "Avis d'imposition : 2024") && strings.Contains(body, "Bulletin de salaire") && strings.Contains(body, "/check/tok")
+	})).Return(nil)
+
+	err := svc.RequestMissingDocuments(context.Background(), 1, 7, []MissingDocument{{Type: "tax_notice", Comment: " 2024 "}, {Type: "payslip"}})
+
+	require.NoError(t, err)
+	mockQuerier.AssertExpectations(t)
+	mockEmail.AssertExpectations(t)
+}
+
+func TestRequestMissingDocuments_Errors(t *testing.T) {
+	svc, mockQuerier, _, _ := setupSolvencyDocuments()
+
+	err := svc.RequestMissingDocuments(context.Background(), 1, 7, []MissingDocument{{Type: "unknown"}})
+	assert.ErrorIs(t, err, ErrInvalidCandidateDocType)
+
+	mockQuerier.On("GetSolvencyCheckForUpdate", mock.Anything, int32(7)).Return(checkWithDocuments(t, postgres.SolvencyStatusPending, nil, nil), nil)
+	err = svc.RequestMissingDocuments(context.Background(), 99, 7, []MissingDocument{{Type: "payslip"}})
+	assert.ErrorIs(t, err, ErrCheckNotOwned)
+
+	mockQuerier.On("GetSolvencyCheckForUpdate", mock.Anything, int32(8)).Return(checkWithDocuments(t, postgres.SolvencyStatusRejected, nil, nil), nil)
+	err = svc.RequestMissingDocuments(context.Background(), 1, 8, []MissingDocument{{Type: "payslip"}})
+	assert.ErrorIs(t, err, ErrCannotRequestMoreDocuments)
+}
+
+func TestOpenCandidateDocument_OwnerOnly(t *testing.T) {
+	svc, mockQuerier, mockFileStore, _ := setupSolvencyDocuments()
+
+	check := checkWithDocuments(t, postgres.SolvencyStatusPending, []CandidateDocument{{ID: "a", Type: "identity", StorageKey: "solvency/7/identity_a.pdf", ContentType: "application/pdf"}}, nil)
+	mockQuerier.On("GetSolvencyCheckByID", mock.Anything, int32(7)).Return(check, nil)
+
+	_, _, err := svc.OpenCandidateDocument(context.Background(), 99, 7, "a")
+	assert.ErrorIs(t, err, ErrCheckNotOwned)
+
+	_, _, err = svc.OpenCandidateDocument(context.Background(), 1, 7, "missing")
+	assert.ErrorIs(t, err, ErrCandidateDocumentNotFound)
+
+	mockFileStore.On("Open", "solvency/7/identity_a.pdf").Return(nil, nil)
+	_, dto, err := svc.OpenCandidateDocument(context.Background(), 1, 7, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "application/pdf", dto.ContentType)
+}
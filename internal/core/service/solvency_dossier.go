@@ -0,0 +1,462 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+	"seculoc-back/internal/platform/logger"
+)
+
+const defaultDossierValidityDays = 90
+
+// creditSourceDossier marks a check created from a shared dossier without consuming any credit:
+// refundCheckCredit has nothing to give back.
+const creditSourceDossier = "dossier"
+
+// dossierDecisionGrace is the expiry of a check created from a dossier. It is decided right after creation;
+// if that never happens (crash between the two steps), the expiry job refunds it.
+const dossierDecisionGrace = time.Hour
+
+var (
+	ErrDossierNotFound      = errors.New("tenant dossier not found")
+	ErrDossierExpired       = errors.New("tenant dossier has expired, a new solvency check is needed")
+	ErrDossierSourceInvalid = errors.New("the dossier can only be built from one of your completed solvency checks")
+	ErrDossierShareInvalid  = errors.New("dossier share not found or revoked")
+	ErrDossierShareNotFound = errors.New("dossier share not found")
+)
+
+// TenantDossierDTO is the candidate's view of their portable dossier.
+type TenantDossierDTO struct {
+	ID             int32                  `json:"id"`
+	SourceCheckID  int32                  `json:"source_check_id,omitempty"`
+	Documents      []CandidateDocumentDTO `json:"documents"`
+	Analysis       *IncomeAnalysis        `json:"analysis"`
+	EmploymentType string                 `json:"employment_type,omitempty"`
+	GuaranteeType  string                 `json:"guarantee_type,omitempty"`
+	VerifiedAt     string                 `json:"verified_at"`
+	ExpiresAt      string                 `json:"expires_at"`
+	Expired        bool                   `json:"expired"`
+	Shares         []DossierShareDTO      `json:"shares"`
+}
+
+// DossierShareDTO is a share of the dossier. The token is what the candidate hands to an owner.
+type DossierShareDTO struct {
+	ID        int32  `json:"id"`
+	Token     string `json:"token"`
+	Label     string `json:"label,omitempty"`
+	ShareURL  string `json:"share_url"`
+	Revoked   bool   `json:"revoked"`
+	CreatedAt string `json:"created_at"`
+}
+
+func dossierShareToDTO(sh postgres.DossierShare) DossierShareDTO {
+	return DossierShareDTO{
+		ID:        sh.ID,
+		Token:     sh.Token,
+		Label:     sh.Label.String,
+		ShareURL:  fmt.Sprintf("%s/dossier/%s", frontendBaseURL(), sh.Token),
+		Revoked:   sh.RevokedAt.Valid,
+		CreatedAt: sh.CreatedAt.Time.Format(time.RFC3339),
+	}
+}
+
+func dossierToDTO(d postgres.TenantDossier, shares []postgres.DossierShare, now time.Time) *TenantDossierDTO {
+	dto := &TenantDossierDTO{
+		ID:             d.ID,
+		SourceCheckID:  d.SourceCheckID.Int32,
+		Documents:      CandidateDocumentsFromJSON(d.DocumentsJson),
+		Analysis:       IncomeAnalysisFromJSON(d.AnalysisJson),
+		EmploymentType: d.EmploymentType.String,
+		GuaranteeType:  d.GuaranteeType.String,
+		VerifiedAt:     d.VerifiedAt.Time.Format(time.RFC3339),
+		ExpiresAt:      d.ExpiresAt.Time.Format(time.RFC3339),
+		Expired:        !now.Before(d.ExpiresAt.Time),
+		Shares:         []DossierShareDTO{},
+	}
+	for _, sh := range shares {
+		dto.Shares = append(dto.Shares, dossierShareToDTO(sh))
+	}
+	return dto
+}
+
+// dossierVerifiedAt is the date the dossier's financial data dates from: the end of the analyzed bank
+// history, or the creation of the check when the period is unknown.
+func dossierVerifiedAt(check postgres.SolvencyCheck, analysis *IncomeAnalysis) time.Time {
+	if t, err := time.Parse("2006-01-02", analysis.PeriodEnd); err == nil {
+		return t
+	}
+	return check.CreatedAt.Time
+}
+
+// copyDocuments copies stored candidate documents under prefix, with new ids so that the copies never
+// share a storage key with the originals. On error the copies already made are returned for cleanup.
+func (s *SolvencyService) copyDocuments(docs []CandidateDocument, prefix string) ([]CandidateDocument, error) {
+	copies := make([]CandidateDocument, 0, len(docs))
+	for _, d := range docs {
+		content, err := s.storage.Get(d.StorageKey)
+		if err != nil {
+			return copies, fmt.Errorf("failed to read document %s: %w", d.ID, err)
+		}
+		c := d
+		c.ID = generateToken()[:16]
+		c.StorageKey = fmt.Sprintf("%s%s_%s%s", prefix, d.Type, c.ID, filepath.Ext(d.StorageKey))
+		if _, err := s.storage.Save(c.StorageKey, content); err != nil {
+			return copies, fmt.Errorf("failed to store document: %w", err)
+		}
+		copies = append(copies, c)
+	}
+	return copies, nil
+}
+
+func storageKeys(docs []CandidateDocument) []string {
+	keys := make([]string, 0, len(docs))
+	for _, d := range docs {
+		keys = append(keys, d.StorageKey)
+	}
+	return keys
+}
+
+// BuildDossier turns one of the candidate's completed checks into their portable dossier, replacing the
+// previous one. The dossier keeps its own copy of the documents and the candidate's own income analysis
+// (guarantors are specific to a check and are not carried over). It is valid DossierValidityDays from
+// the date of the bank data.
+func (s *SolvencyService) BuildDossier(ctx context.Context, userID, checkID int32) (*TenantDossierDTO, error) {
+	now := time.Now()
+	var dossier postgres.TenantDossier
+	var shares []postgres.DossierShare
+	var copies, previous []CandidateDocument
+
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		check, err := q.GetSolvencyCheckByID(ctx, checkID)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return ErrDossierSourceInvalid
+			}
+			return err
+		}
+		analysis := IncomeAnalysisFromJSON(check.AnalysisJson)
+		if check.CandidateID.Int32 != userID || !checkCompleted(check.Status.SolvencyStatus) || analysis == nil {
+			return ErrDossierSourceInvalid
+		}
+
+		verifiedAt := dossierVerifiedAt(check, analysis)
+		expiresAt := verifiedAt.AddDate(0, 0, s.DossierValidityDays)
+		if !now.Before(expiresAt) {
+			return ErrDossierExpired
+		}
+
+		existing, err := q.GetTenantDossierByUser(ctx, userID)
+		if err == nil {
+			previous = decodeCandidateDocuments(existing.DocumentsJson)
+		} else if err != pgx.ErrNoRows {
+			return err
+		}
+
+		copies, err = s.copyDocuments(decodeCandidateDocuments(check.DocumentsJson), fmt.Sprintf("dossiers/%d/", userID))
+		if err != nil {
+			return err
+		}
+		docsJSON, err := json.Marshal(copies)
+		if err != nil {
+			return fmt.Errorf("failed to encode documents: %w", err)
+		}
+
+		dossier, err = q.UpsertTenantDossier(ctx, postgres.UpsertTenantDossierParams{
+			UserID:         userID,
+			SourceCheckID:  pgtype.Int4{Int32: check.ID, Valid: true},
+			DocumentsJson:  docsJSON,
+			AnalysisJson:   check.AnalysisJson,
+			EmploymentType: check.EmploymentType,
+			GuaranteeType:  check.GuaranteeType,
+			VerifiedAt:     pgtype.Timestamp{Time: verifiedAt, Valid: true},
+			ExpiresAt:      pgtype.Timestamp{Time: expiresAt, Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save dossier: %w", err)
+		}
+		shares, err = q.ListDossierShares(ctx, dossier.ID)
+		return err
+	})
+	if err != nil {
+		s.removeFiles(ctx, storageKeys(copies)...)
+		return nil, err
+	}
+	s.removeFiles(ctx, storageKeys(previous)...)
+
+	logger.FromContext(ctx).Info("tenant dossier built",
+		zap.Int32("user_id", userID), zap.Int32("check_id", checkID), zap.Int("documents", len(copies)))
+	return dossierToDTO(dossier, shares, now), nil
+}
+
+// GetDossier returns the candidate's dossier with its shares.
+func (s *SolvencyService) GetDossier(ctx context.Context, userID int32) (*TenantDossierDTO, error) {
+	var dossier postgres.TenantDossier
+	var shares []postgres.DossierShare
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		var err error
+		dossier, err = q.GetTenantDossierByUser(ctx, userID)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return ErrDossierNotFound
+			}
+			return err
+		}
+		shares, err = q.ListDossierShares(ctx, dossier.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dossierToDTO(dossier, shares, time.Now()), nil
+}
+
+// DeleteDossier deletes the candidate's dossier, its shares and its documents. Checks already created
+// from it keep their own copy.
+func (s *SolvencyService) DeleteDossier(ctx context.Context, userID int32) error {
+	var docs []CandidateDocument
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		dossier, err := q.GetTenantDossierByUser(ctx, userID)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return ErrDossierNotFound
+			}
+			return err
+		}
+		docs = decodeCandidateDocuments(dossier.DocumentsJson)
+		return q.DeleteTenantDossier(ctx, dossier.ID)
+	})
+	if err != nil {
+		return err
+	}
+	s.removeFiles(ctx, storageKeys(docs)...)
+	return nil
+}
+
+// ShareDossier creates a share token for an owner. label is a free reminder of who it was given to.
+func (s *SolvencyService) ShareDossier(ctx context.Context, userID int32, label string) (*DossierShareDTO, error) {
+	var share postgres.DossierShare
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		dossier, err := q.GetTenantDossierByUser(ctx, userID)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return ErrDossierNotFound
+			}
+			return err
+		}
+		if !time.Now().Before(dossier.ExpiresAt.Time) {
+			return ErrDossierExpired
+		}
+
+		token, err := GenerateSecureToken()
+		if err != nil {
+			return fmt.Errorf("failed to generate secure token: %w", err)
+		}
+		share, err = q.CreateDossierShare(ctx, postgres.CreateDossierShareParams{
+			DossierID: dossier.ID,
+			Token:     token,
+			Label:     pgtype.Text{String: label, Valid: label != ""},
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	dto := dossierShareToDTO(share)
+	return &dto, nil
+}
+
+// RevokeDossierShare revokes a share: owners can no longer use its token. Checks already created with it
+// are kept.
+func (s *SolvencyService) RevokeDossierShare(ctx context.Context, userID, shareID int32) error {
+	return s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		dossier, err := q.GetTenantDossierByUser(ctx, userID)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return ErrDossierNotFound
+			}
+			return err
+		}
+		rows, err := q.RevokeDossierShare(ctx, postgres.RevokeDossierShareParams{ID: shareID, DossierID: dossier.ID})
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return ErrDossierShareNotFound
+		}
+		return nil
+	})
+}
+
+// abandonCheck cancels a check created from a dossier whose decision failed and gives its credit back,
+// unless it was decided in the meantime.
+func (s *SolvencyService) abandonCheck(ctx context.Context, checkID int32) {
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		check, err := q.GetSolvencyCheckForUpdate(ctx, checkID)
+		if err != nil {
+			return err
+		}
+		rows, err := q.CancelSolvencyCheck(ctx, checkID)
+		if err != nil || rows == 0 {
+			return err
+		}
+		return refundCheckCredit(ctx, q, check, fmt.Sprintf("Refund for failed solvency check #%d", checkID))
+	})
+	if err != nil {
+		// The expiry job refunds it once dossierDecisionGrace has passed
+		logger.FromContext(ctx).Error("failed to abandon undecided check", zap.Int32("check_id", checkID), zap.Error(err))
+	}
+}
+
+// InitiateCheckFromDossier creates a check for one of the owner's properties from a dossier shared with
+// them. The check is completed at once: the dossier's analysis is re-scored against the property's rent
+// and the owner's policy applied, with no action from the candidate. It consumes a credit only when
+// DossierConsumesCredit is set.
+func (s *SolvencyService) InitiateCheckFromDossier(ctx context.Context, ownerID, propertyID int32, shareToken string) (*postgres.SolvencyCheck, error) {
+	log := logger.FromContext(ctx)
+
+	var check postgres.SolvencyCheck
+	var analysis *IncomeAnalysis
+	var docs []CandidateDocument
+	var candidate postgres.User
+	var prop postgres.Property
+
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		share, err := q.GetDossierShareByToken(ctx, shareToken)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return ErrDossierShareInvalid
+			}
+			return err
+		}
+		if share.RevokedAt.Valid {
+			return ErrDossierShareInvalid
+		}
+		dossier, err := q.GetTenantDossier(ctx, share.DossierID)
+		if err != nil {
+			return err
+		}
+		if !time.Now().Before(dossier.ExpiresAt.Time) {
+			return ErrDossierExpired
+		}
+		analysis = IncomeAnalysisFromJSON(dossier.AnalysisJson)
+		if analysis == nil {
+			return ErrDossierSourceInvalid
+		}
+		docs = decodeCandidateDocuments(dossier.DocumentsJson)
+
+		prop, err = q.GetPropertyForUpdate(ctx, propertyID)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return fmt.Errorf("property not found or access denied")
+			}
+			return err
+		}
+		if !prop.OwnerID.Valid || prop.OwnerID.Int32 != ownerID {
+			return fmt.Errorf("property not found or access denied")
+		}
+
+		usedSource := creditSourceDossier
+		var usage postgres.CreditTransaction
+		if s.DossierConsumesCredit {
+			if usedSource, usage, err = consumeCheckCredit(ctx, q, prop, ownerID); err != nil {
+				return err
+			}
+		}
+
+		candidate, err = q.GetUserById(ctx, dossier.UserID)
+		if err != nil {
+			return err
+		}
+		token, err := GenerateSecureToken()
+		if err != nil {
+			return fmt.Errorf("failed to generate secure token: %w", err)
+		}
+		check, err = q.CreateSolvencyCheck(ctx, postgres.CreateSolvencyCheckParams{
+			InitiatorOwnerID: pgtype.Int4{Int32: ownerID, Valid: true},
+			CandidateID:      pgtype.Int4{Int32: dossier.UserID, Valid: true},
+			Token:            pgtype.Text{String: token, Valid: true},
+			PropertyID:       pgtype.Int4{Int32: propertyID, Valid: true},
+			CreditSource:     pgtype.Text{String: usedSource, Valid: true},
+			ExpiresAt:        pgtype.Timestamp{Time: time.Now().Add(dossierDecisionGrace), Valid: true},
+			// Zero when the dossier did not consume a credit
+			CreditTransactionID: pgtype.Int4{Int32: usage.ID, Valid: usage.ID != 0},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create solvency check: %w", err)
+		}
+		if err := q.SetSolvencyCheckDossierShare(ctx, postgres.SetSolvencyCheckDossierShareParams{
+			ID:             check.ID,
+			DossierShareID: pgtype.Int4{Int32: share.ID, Valid: true},
+		}); err != nil {
+			return err
+		}
+		if err := q.UpdateSolvencyCheckProfile(ctx, postgres.UpdateSolvencyCheckProfileParams{
+			ID:             check.ID,
+			EmploymentType: dossier.EmploymentType,
+			GuaranteeType:  dossier.GuaranteeType,
+		}); err != nil {
+			return err
+		}
+
+		log.Info("solvency check initiated from dossier",
+			zap.Int32("user_id", ownerID),
+			zap.Int32("check_id", check.ID),
+			zap.Int32("dossier_id", dossier.ID),
+			zap.String("credit_source", usedSource),
+		)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The check gets its own copy: it outlives a deleted or rebuilt dossier. The files are copied once the
+	// credit is committed, so that the property and wallet stay unlocked during the storage round trips.
+	copies, err := s.copyDocuments(docs, fmt.Sprintf("solvency/%d/", check.ID))
+	if err == nil {
+		err = s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+			return saveCheckDocuments(ctx, q, check, copies, nil)
+		})
+	}
+	if err != nil {
+		s.removeFiles(ctx, storageKeys(copies)...)
+		s.abandonCheck(ctx, check.ID)
+		return nil, err
+	}
+
+	in, err := s.loadDecisionInputs(ctx, check.ID)
+	if err == nil {
+		err = s.decide(ctx, in, RescoreForRent(*analysis, in.rent))
+	}
+	if err != nil {
+		s.abandonCheck(ctx, check.ID)
+		return nil, err
+	}
+	err = s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		check, err = q.GetSolvencyCheckByID(ctx, check.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	greeting := "Bonjour"
+	if candidate.FirstName.String != "" {
+		greeting += " " + candidate.FirstName.String
+	}
+	body := fmt.Sprintf("%s,\n\nUn propriétaire a étudié votre dossier locataire pour le logement situé %s grâce au lien de partage que vous lui avez transmis.\n\n"+
+		"Vous pouvez révoquer vos liens de partage à tout moment depuis votre espace.", greeting, prop.Address)
+	if err := s.emailSender.SendNotification(ctx, candidate.Email, "Votre dossier locataire a été consulté", body); err != nil {
+		log.Warn("failed to notify candidate of dossier use", zap.Int32("check_id", check.ID), zap.Error(err))
+	}
+	return &check, nil
+}
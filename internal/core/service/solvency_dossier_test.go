@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+)
+
+func TestRescoreForRent(t *testing.T) {
+	a := IncomeAnalysis{MonthlyIncome: 3000, MonthlyRecurringIncome: 3000, RentAmount: 600}
+	scoreIncomeAnalysis(&a)
+	require.Equal(t, 0.2, a.EffortRate)
+
+	b := RescoreForRent(a, 1200)
+
+	assert.Equal(t, 0.4, b.EffortRate)
+	assert.Equal(t, 0.4, b.DebtRatio)
+	assert.Less(t, b.Score, a.Score)
+	assert.Equal(t, 600.0, a.RentAmount, "the original analysis is left untouched")
+}
+
+func TestBuildDossier(t *testing.T) {
+	svc, mockQuerier, mockFileStore, _ := setupSolvencyDocuments()
+	periodEnd := time.Now().AddDate(0, 0, -10).Format("2006-01-02")
+	analysis, err := json.Marshal(IncomeAnalysis{Score: 80, MonthlyIncome: 3000, PeriodEnd: periodEnd})
+	require.NoError(t, err)
+	check := checkWithDocuments(t, postgres.SolvencyStatusApproved, []CandidateDocument{
+		{ID: "d1", Type: "payslip", StorageKey: "solvency/7/payslip_d1.pdf"},
+	}, nil)
+	check.AnalysisJson = analysis
+	check.GuaranteeType = pgtype.Text{String: "visale", Valid: true}
+
+	mockQuerier.On("GetSolvencyCheckByID", mock.Anything, int32(7)).Return(check, nil)
+	previous, err := json.Marshal([]CandidateDocument{{ID: "old", Type: "identity", StorageKey: "dossiers/2/identity_old.pdf"}})
+	require.NoError(t, err)
+	mockQuerier.On("GetTenantDossierByUser", mock.Anything, int32(2)).Return(postgres.TenantDossier{ID: 3, UserID: 2, DocumentsJson: previous}, nil)
+	mockFileStore.On("Get", "solvency/7/payslip_d1.pdf").Return(samplePDF, nil)
+	mockFileStore.On("Save", mock.MatchedBy(func(k string) bool {
+		return strings.HasPrefix(k, "dossiers/2/payslip_") && strings.HasSuffix(k, ".pdf")
+	}), samplePDF).Return("", nil)
+	var saved postgres.UpsertTenantDossierParams
+	mockQuerier.On("UpsertTenantDossier", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		saved = args.Get(1).(postgres.UpsertTenantDossierParams)
+	}).Return(postgres.TenantDossier{ID: 3, UserID: 2, AnalysisJson: analysis}, nil)
+	mockQuerier.On("ListDossierShares", mock.Anything, int32(3)).Return([]postgres.DossierShare{}, nil)
+	mockFileStore.On("Delete", "dossiers/2/identity_old.pdf").Return(nil)
+
+	_, err = svc.BuildDossier(context.Background(), 2, 7)
+
+	require.NoError(t, err)
+	assert.Equal(t, periodEnd, saved.VerifiedAt.Time.Format("2006-01-02"))
+	assert.Equal(t, saved.VerifiedAt.Time.AddDate(0, 0, 90), saved.ExpiresAt.Time)
+	assert.Equal(t, "visale", saved.GuaranteeType.String)
+	docs := decodeCandidateDocuments(saved.DocumentsJson)
+	require.Len(t, docs, 1)
+	assert.NotEqual(t, "d1", docs[0].ID, "the copy gets its own id")
+	mockFileStore.AssertExpectations(t)
+
+	// Someone else's check cannot be used
+	_, err = svc.BuildDossier(context.Background(), 5, 7)
+	assert.ErrorIs(t, err, ErrDossierSourceInvalid)
+
+	// Bank data too old
+	svc.DossierValidityDays = 5
+	_, err = svc.BuildDossier(context.Background(), 2, 7)
+	assert.ErrorIs(t, err, ErrDossierExpired)
+}
+
+func TestInitiateCheckFromDossier_RescoresWithoutCredit(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	mockEmail := new(mockEmailSender)
+	svc := NewSolvencyService(passthroughTxManager{q: mockQuerier}, mockEmail, zap.NewNop(), nil, nil)
+	analysis, err := json.Marshal(IncomeAnalysis{MonthlyIncome: 3000, MonthlyRecurringIncome: 3000, RentAmount: 600})
+	require.NoError(t, err)
+
+	mockQuerier.On("GetDossierShareByToken", mock.Anything, "revoked").Return(postgres.DossierShare{ID: 1, DossierID: 3, RevokedAt: pgtype.Timestamp{Time: time.Now(), Valid: true}}, nil)
+	mockQuerier.On("GetDossierShareByToken", mock.Anything, "unknown").Return(postgres.DossierShare{}, pgx.ErrNoRows)
+	mockQuerier.On("GetDossierShareByToken", mock.Anything, "old").Return(postgres.DossierShare{ID: 2, DossierID: 4}, nil)
+	mockQuerier.On("GetTenantDossier", mock.Anything, int32(4)).Return(postgres.TenantDossier{ID: 4, ExpiresAt: pgtype.Timestamp{Time: time.Now().Add(-time.Hour), Valid: true}}, nil)
+	mockQuerier.On("GetDossierShareByToken", mock.Anything, "share").Return(postgres.DossierShare{ID: 5, DossierID: 3}, nil)
+	mockQuerier.On("GetTenantDossier", mock.Anything, int32(3)).Return(postgres.TenantDossier{
+		ID:             3,
+		UserID:         2,
+		AnalysisJson:   analysis,
+		EmploymentType: pgtype.Text{String: "cdi", Valid: true},
+		ExpiresAt:      pgtype.Timestamp{Time: time.Now().AddDate(0, 1, 0), Valid: true},
+	}, nil)
+	prop := postgres.Property{ID: 10, OwnerID: pgtype.Int4{Int32: 1, Valid: true}, Address: "1 rue A", RentAmount: numeric(1200)}
+	mockQuerier.On("GetPropertyForUpdate", mock.Anything, int32(10)).Return(prop, nil)
+	mockQuerier.On("GetUserById", mock.Anything, int32(2)).Return(postgres.User{ID: 2, Email: "cand@test.com"}, nil)
+	mockQuerier.On("CreateSolvencyCheck", mock.Anything, mock.MatchedBy(func(p postgres.CreateSolvencyCheckParams) bool {
+		return p.CandidateID.Int32 == 2 && p.CreditSource.String == creditSourceDossier && p.ExpiresAt.Time.Before(time.Now().Add(dossierDecisionGrace+time.Minute))
+	})).Return(postgres.SolvencyCheck{ID: 20}, nil)
+	mockQuerier.On("SetSolvencyCheckDossierShare", mock.Anything, postgres.SetSolvencyCheckDossierShareParams{
+		ID: 20, DossierShareID: pgtype.Int4{Int32: 5, Valid: true},
+	}).Return(nil)
+	mockQuerier.On("UpdateSolvencyCheckProfile", mock.Anything, postgres.UpdateSolvencyCheckProfileParams{
+		ID: 20, EmploymentType: pgtype.Text{String: "cdi", Valid: true},
+	}).Return(nil)
+	mockQuerier.On("UpdateSolvencyCheckDocuments", mock.Anything, mock.Anything).Return(nil)
+	decided := postgres.SolvencyCheck{
+		ID:               20,
+		InitiatorOwnerID: pgtype.Int4{Int32: 1, Valid: true},
+		PropertyID:       pgtype.Int4{Int32: 10, Valid: true},
+	}
+	mockQuerier.On("GetSolvencyCheckByID", mock.Anything, int32(20)).Return(decided, nil)
+	mockQuerier.On("GetProperty", mock.Anything, int32(10)).Return(prop, nil)
+	mockQuerier.On("GetEffectiveSolvencyPolicy", mock.Anything, mock.Anything).Return(postgres.SolvencyPolicy{}, pgx.ErrNoRows)
+	mockQuerier.On("ListGuarantorsByCheck", mock.Anything, int32(20)).Return([]postgres.SolvencyGuarantor{}, nil)
+	var stored postgres.UpdateSolvencyCheckResultParams
+	mockQuerier.On("UpdateSolvencyCheckResult", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(postgres.UpdateSolvencyCheckResultParams)
+	}).Return(int64(1), nil)
+	mockEmail.On("SendNotification", mock.Anything, "cand@test.com", "Votre dossier locataire a été consulté", mock.MatchedBy(func(body string) bool {
+		return strings.Contains(body, "1 rue A")
+	})).Return(nil).Once()
+
+	_, err = svc.InitiateCheckFromDossier(context.Background(), 1, 10, "share")
+
+	require.NoError(t, err)
+	// Re-scored against this property's rent, not the one of the original check
+	analysisStored := IncomeAnalysisFromJSON(stored.AnalysisJson)
+	require.NotNil(t, analysisStored)
+	assert.Equal(t, 1200.0, analysisStored.RentAmount)
+	assert.Equal(t, 0.4, analysisStored.EffortRate)
+	mockQuerier.AssertNotCalled(t, "CreateCreditTransaction", mock.Anything, mock.Anything)
+	mockEmail.AssertExpectations(t)
+
+	_, err = svc.InitiateCheckFromDossier(context.Background(), 1, 10, "revoked")
+	assert.ErrorIs(t, err, ErrDossierShareInvalid)
+	_, err = svc.InitiateCheckFromDossier(context.Background(), 1, 10, "unknown")
+	assert.ErrorIs(t, err, ErrDossierShareInvalid)
+	_, err = svc.InitiateCheckFromDossier(context.Background(), 1, 10, "old")
+	assert.ErrorIs(t, err, ErrDossierExpired)
+	_, err = svc.InitiateCheckFromDossier(context.Background(), 3, 10, "share")
+	assert.EqualError(t, err, "property not found or access denied")
+}
+
+func TestInitiateCheckFromDossier_ConsumesCreditWhenConfigured(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	svc := NewSolvencyService(passthroughTxManager{q: mockQuerier}, nil, zap.NewNop(), nil, nil)
+	svc.DossierConsumesCredit = true
+
+	mockQuerier.On("GetDossierShareByToken", mock.Anything, "share").Return(postgres.DossierShare{ID: 5, DossierID: 3}, nil)
+	mockQuerier.On("GetTenantDossier", mock.Anything, int32(3)).Return(postgres.TenantDossier{
+		ID:           3,
+		UserID:       2,
+		AnalysisJson: []byte(`{"monthly_income": 3000}`),
+		ExpiresAt:    pgtype.Timestamp{Time: time.Now().AddDate(0, 1, 0), Valid: true},
+	}, nil)
+	mockQuerier.On("GetPropertyForUpdate", mock.Anything, int32(10)).Return(postgres.Property{ID: 10, OwnerID: pgtype.Int4{Int32: 1, Valid: true}}, nil)
+	mockQuerier.On("GetUserForUpdate", mock.Anything, int32(1)).Return(postgres.User{ID: 1}, nil)
+	mockQuerier.On("GetUserCreditBalanceForUpdate", mock.Anything, int32(1)).Return(int32(0), nil)
+
+	_, err := svc.InitiateCheckFromDossier(context.Background(), 1, 10, "share")
+
+	var insErr *ErrInsufficientCredits
+	assert.ErrorAs(t, err, &insErr)
+	mockQuerier.AssertNotCalled(t, "CreateSolvencyCheck", mock.Anything, mock.Anything)
+}
+
+func TestInitiateCheckFromDossier_DecisionFailureRefunds(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	svc := NewSolvencyService(passthroughTxManager{q: mockQuerier}, nil, zap.NewNop(), nil, nil)
+	svc.DossierConsumesCredit = true
+
+	mockQuerier.On("GetDossierShareByToken", mock.Anything, "share").Return(postgres.DossierShare{ID: 5, DossierID: 3}, nil)
+	mockQuerier.On("GetTenantDossier", mock.Anything, int32(3)).Return(postgres.TenantDossier{
+		ID:           3,
+		UserID:       2,
+		AnalysisJson: []byte(`{"monthly_income": 3000}`),
+		ExpiresAt:    pgtype.Timestamp{Time: time.Now().AddDate(0, 1, 0), Valid: true},
+	}, nil)
+	prop := postgres.Property{ID: 10, OwnerID: pgtype.Int4{Int32: 1, Valid: true}, VacancyCredits: 2}
+	mockQuerier.On("GetPropertyForUpdate", mock.Anything, int32(10)).Return(prop, nil)
+	mockQuerier.On("CreateCreditTransaction", mock.Anything, mock.MatchedBy(func(p postgres.CreateCreditTransactionParams) bool {
+		return p.Amount == -1
+	})).Return(postgres.CreditTransaction{ID: 77}, nil).Once()
+	mockQuerier.On("GetUserById", mock.Anything, int32(2)).Return(postgres.User{ID: 2}, nil)
+	mockQuerier.On("CreateSolvencyCheck", mock.Anything, mock.Anything).Return(postgres.SolvencyCheck{ID: 20}, nil)
+	mockQuerier.On("SetSolvencyCheckDossierShare", mock.Anything, mock.Anything).Return(nil)
+	mockQuerier.On("UpdateSolvencyCheckProfile", mock.Anything, mock.Anything).Return(nil)
+	mockQuerier.On("UpdateSolvencyCheckDocuments", mock.Anything, mock.Anything).Return(nil)
+
+	// The decision cannot load its inputs: the check is cancelled and the credit given back at once
+	mockQuerier.On("GetSolvencyCheckByID", mock.Anything, int32(20)).Return(postgres.SolvencyCheck{}, assert.AnError)
+	mockQuerier.On("GetSolvencyCheckForUpdate", mock.Anything, int32(20)).Return(postgres.SolvencyCheck{
+		ID:                  20,
+		InitiatorOwnerID:    pgtype.Int4{Int32: 1, Valid: true},
+		PropertyID:          pgtype.Int4{Int32: 10, Valid: true},
+		Status:              postgres.NullSolvencyStatus{SolvencyStatus: postgres.SolvencyStatusPending, Valid: true},
+		CreditSource:        pgtype.Text{String: "property", Valid: true},
+		CreditTransactionID: pgtype.Int4{Int32: 77, Valid: true},
+	}, nil)
+	mockQuerier.On("CancelSolvencyCheck", mock.Anything, int32(20)).Return(int64(1), nil)
+	mockQuerier.On("CreateCreditTransaction", mock.Anything, mock.MatchedBy(func(p postgres.CreateCreditTransactionParams) bool {
+		return p.Amount == 1 && p.TransactionType == "refund" && p.RefundOf.Int32 == 77
+	})).Return(postgres.CreditTransaction{}, nil).Once()
+
+	_, err := svc.InitiateCheckFromDossier(context.Background(), 1, 10, "share")
+
+	assert.ErrorIs(t, err, assert.AnError)
+	mockQuerier.AssertExpectations(t)
+}
+
+// trackingTxManager records whether a transaction is open.
+type trackingTxManager struct {
+	q    postgres.Querier
+	open *bool
+}
+
+func (m trackingTxManager) WithTx(ctx context.Context, fn func(postgres.Querier) error) error {
+	*m.open = true
+	defer func() { *m.open = false }()
+	return fn(m.q)
+}
+
+func TestInitiateCheckFromDossier_CopyFailureRefunds(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	mockFileStore := new(MockFileStorage)
+	inTx := false
+	svc := NewSolvencyService(trackingTxManager{q: mockQuerier, open: &inTx}, nil, zap.NewNop(), mockFileStore, nil)
+	svc.DossierConsumesCredit = true
+
+	docs, err := json.Marshal([]CandidateDocument{{ID: "d1", Type: "payslip", StorageKey: "dossiers/2/payslip_d1.pdf"}})
+	require.NoError(t, err)
+	mockQuerier.On("GetDossierShareByToken", mock.Anything, "share").Return(postgres.DossierShare{ID: 5, DossierID: 3}, nil)
+	mockQuerier.On("GetTenantDossier", mock.Anything, int32(3)).Return(postgres.TenantDossier{
+		ID:            3,
+		UserID:        2,
+		AnalysisJson:  []byte(`{"monthly_income": 3000}`),
+		DocumentsJson: docs,
+		ExpiresAt:     pgtype.Timestamp{Time: time.Now().AddDate(0, 1, 0), Valid: true},
+	}, nil)
+	prop := postgres.Property{ID: 10, OwnerID: pgtype.Int4{Int32: 1, Valid: true}, VacancyCredits: 2}
+	mockQuerier.On("GetPropertyForUpdate", mock.Anything, int32(10)).Return(prop, nil)
+	mockQuerier.On("CreateCreditTransaction", mock.Anything, mock.MatchedBy(func(p postgres.CreateCreditTransactionParams) bool {
+		return p.Amount == -1
+	})).Return(postgres.CreditTransaction{ID: 77}, nil).Once()
+	mockQuerier.On("GetUserById", mock.Anything, int32(2)).Return(postgres.User{ID: 2}, nil)
+	mockQuerier.On("CreateSolvencyCheck", mock.Anything, mock.Anything).Return(postgres.SolvencyCheck{ID: 20}, nil)
+	mockQuerier.On("SetSolvencyCheckDossierShare", mock.Anything, mock.Anything).Return(nil)
+	mockQuerier.On("UpdateSolvencyCheckProfile", mock.Anything, mock.Anything).Return(nil)
+
+	// The storage is only reached once the credit is committed
+	mockFileStore.On("Get", "dossiers/2/payslip_d1.pdf").Run(func(mock.Arguments) {
+		assert.False(t, inTx, "documents copied inside the ledger transaction")
+	}).Return([]byte(nil), assert.AnError)
+	mockQuerier.On("GetSolvencyCheckForUpdate", mock.Anything, int32(20)).Return(postgres.SolvencyCheck{
+		ID:                  20,
+		InitiatorOwnerID:    pgtype.Int4{Int32: 1, Valid: true},
+		PropertyID:          pgtype.Int4{Int32: 10, Valid: true},
+		Status:              postgres.NullSolvencyStatus{SolvencyStatus: postgres.SolvencyStatusPending, Valid: true},
+		CreditSource:        pgtype.Text{String: "property", Valid: true},
+		CreditTransactionID: pgtype.Int4{Int32: 77, Valid: true},
+	}, nil)
+	mockQuerier.On("CancelSolvencyCheck", mock.Anything, int32(20)).Return(int64(1), nil)
+	mockQuerier.On("CreateCreditTransaction", mock.Anything, mock.MatchedBy(func(p postgres.CreateCreditTransactionParams) bool {
+		return p.Amount == 1 && p.TransactionType == "refund" && p.RefundOf.Int32 == 77
+	})).Return(postgres.CreditTransaction{}, nil).Once()
+
+	_, err = svc.InitiateCheckFromDossier(context.Background(), 1, 10, "share")
+
+	assert.ErrorIs(t, err, assert.AnError)
+	mockQuerier.AssertExpectations(t)
+	mockQuerier.AssertNotCalled(t, "UpdateSolvencyCheckDocuments", mock.Anything, mock.Anything)
+}
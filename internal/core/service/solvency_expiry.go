@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+	"seculoc-back/internal/platform/logger"
+)
+
+const defaultCheckExpiryDays = 14
+
+// defaultCheckReminderDays are the days after creation on which a silent candidate is reminded.
+var defaultCheckReminderDays = []int{3, 7, 12}
+
+// parseReminderDays reads a comma-separated list of days ("3,7,12"). Invalid entries are ignored;
+// nil means the setting is absent and the defaults apply, an empty slice disables reminders.
+func parseReminderDays(value string) []int {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	days := []int{}
+	for _, part := range strings.Split(value, ",") {
+		if d, err := strconv.Atoi(strings.TrimSpace(part)); err == nil && d > 0 {
+			days = append(days, d)
+		}
+	}
+	sort.Ints(days)
+	return days
+}
+
+// reminderDue tells whether the next reminder of a check created at createdAt is due.
+func (s *SolvencyService) reminderDue(createdAt time.Time, sent int32, now time.Time) bool {
+	if int(sent) >= len(s.ReminderDays) {
+		return false
+	}
+	return !now.Before(createdAt.AddDate(0, 0, s.ReminderDays[sent]))
+}
+
+// SendCheckReminders emails the candidates of checks still waiting for them, on each of ReminderDays.
+// A reminder that cannot be sent is retried on the next run.
+func (s *SolvencyService) SendCheckReminders(ctx context.Context) error {
+	if len(s.ReminderDays) == 0 {
+		return nil
+	}
+	log := logger.FromContext(ctx)
+
+	var checks []postgres.ListSolvencyChecksAwaitingCandidateRow
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		var err error
+		checks, err = q.ListSolvencyChecksAwaitingCandidate(ctx, int32(len(s.ReminderDays)))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list checks awaiting candidate: %w", err)
+	}
+
+	now := time.Now()
+	sent := 0
+	for _, c := range checks {
+		if !s.reminderDue(c.CreatedAt.Time, c.RemindersSent, now) {
+			continue
+		}
+
+		greeting := "Bonjour"
+		if c.CandidateFirstName.String != "" {
+			greeting += " " + c.CandidateFirstName.String
+		}
+		subject := "Rappel : votre dossier de location vous attend"
+		body := fmt.Sprintf("%s,\n\nVotre dossier de location pour le logement situé %s n'est pas encore complété. Il expire le %s.\n\nCompléter mon dossier : %s/check/%s",
+			greeting, c.PropertyAddress, c.ExpiresAt.Time.Format("02/01/2006"), frontendBaseURL(), c.Token.String)
+		if err := s.emailSender.SendNotification(ctx, c.CandidateEmail, subject, body); err != nil {
+			log.Warn("failed to send solvency check reminder", zap.Int32("check_id", c.ID), zap.Error(err))
+			continue
+		}
+
+		err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+			return q.MarkSolvencyCheckReminderSent(ctx, c.ID)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to mark reminder sent: %w", err)
+		}
+		sent++
+	}
+
+	if sent > 0 {
+		log.Info("solvency check reminders sent", zap.Int("count", sent))
+	}
+	return nil
+}
+
+// ExpireStaleChecks expires the checks whose candidate did not answer in time and refunds their
+// credit to its original source, as CancelCheck does. Each check is expired in its own transaction.
+func (s *SolvencyService) ExpireStaleChecks(ctx context.Context) error {
+	log := logger.FromContext(ctx)
+	var ids []int32
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		var err error
+		ids, err = q.ListExpiredSolvencyChecks(ctx)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list expired checks: %w", err)
+	}
+
+	expired := 0
+	for _, id := range ids {
+		var check postgres.SolvencyCheck
+		var owner postgres.User
+		err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+			var err error
+			check, err = q.ExpireSolvencyCheck(ctx, id)
+			if err != nil {
+				return err
+			}
+			if err := refundCheckCredit(ctx, q, check, fmt.Sprintf("Refund for expired solvency check #%d", id)); err != nil {
+				return err
+			}
+			owner, err = q.GetUserById(ctx, check.InitiatorOwnerID.Int32)
+			return err
+		})
+		if err == pgx.ErrNoRows {
+			// Decided or cancelled in the meantime
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to expire check %d: %w", id, err)
+		}
+		expired++
+
+		body := fmt.Sprintf("Le candidat n'a pas complété le dossier de solvabilité n°%d dans le délai imparti. Le dossier a expiré et le crédit utilisé vous a été rendu.", id)
+		if err := s.emailSender.SendNotification(ctx, owner.Email, "Dossier de solvabilité expiré", body); err != nil {
+			log.Warn("failed to notify owner of expired check", zap.Int32("check_id", id), zap.Error(err))
+		}
+	}
+
+	if expired > 0 {
+		log.Info("stale solvency checks expired", zap.Int("count", expired))
+	}
+	return nil
+}
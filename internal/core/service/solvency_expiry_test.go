@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+)
+
+func TestParseReminderDays(t *testing.T) {
+	assert.Nil(t, parseReminderDays(""))
+	assert.Equal(t, []int{2, 5, 9}, parseReminderDays("9, 2,x,5,-1"))
+	assert.Empty(t, parseReminderDays("0"), "a set but empty list disables reminders")
+}
+
+func TestSendCheckReminders(t *testing.T) {
+	svc, mockQuerier, _, mockEmail := setupSolvencyDocuments()
+	svc.ReminderDays = []int{3, 7}
+	now := time.Now()
+	ago := func(days int) pgtype.Timestamp {
+		return pgtype.Timestamp{Time: now.AddDate(0, 0, -days).Add(-time.Minute), Valid: true}
+	}
+	expires := pgtype.Timestamp{Time: now.AddDate(0, 0, 5), Valid: true}
+
+	mockQuerier.On("ListSolvencyChecksAwaitingCandidate", mock.Anything, int32(2)).Return([]postgres.ListSolvencyChecksAwaitingCandidateRow{
+		{ID: 1, Token: pgtype.Text{String: "t1", Valid: true}, CreatedAt: ago(3), ExpiresAt: expires, CandidateEmail: "first@test.com"},
+		{ID: 2, Token: pgtype.Text{String: "t2", Valid: true}, CreatedAt: ago(5), ExpiresAt: expires, RemindersSent: 1, CandidateEmail: "early@test.com"},
+		{ID: 3, Token: pgtype.Text{String: "t3", Valid: true}, CreatedAt: ago(8), ExpiresAt: expires, RemindersSent: 1, CandidateEmail: "ko@test.com"},
+	}, nil)
+	mockEmail.On("SendNotification", mock.Anything, "first@test.com", mock.Anything, mock.MatchedBy(func(body string) bool {
+		return strings.Contains(body, "/check/t1")
+	})).Return(nil)
+	mockEmail.On("SendNotification", mock.Anything, "ko@test.com", mock.Anything, mock.Anything).Return(assert.AnError)
+	mockQuerier.On("MarkSolvencyCheckReminderSent", mock.Anything, int32(1)).Return(nil)
+
+	err := svc.SendCheckReminders(context.Background())
+
+	require.NoError(t, err)
+	// Second reminder of check 2 is due on day 7 only; the failed send of check 3 is retried next run
+	mockEmail.AssertNotCalled(t, "SendNotification", mock.Anything, "early@test.com", mock.Anything, mock.Anything)
+	mockQuerier.AssertNotCalled(t, "MarkSolvencyCheckReminderSent", mock.Anything, int32(3))
+	mockQuerier.AssertExpectations(t)
+	mockEmail.AssertExpectations(t)
+}
+
+func TestExpireStaleChecks_RefundsOriginalSource(t *testing.T) {
+	svc, mockQuerier, _, mockEmail := setupSolvencyDocuments()
+	owner := pgtype.Int4{Int32: 1, Valid: true}
+
+	mockQuerier.On("ListExpiredSolvencyChecks", mock.Anything).Return([]int32{10, 11, 12}, nil)
+	mockQuerier.On("ExpireSolvencyCheck", mock.Anything, int32(10)).Return(postgres.SolvencyCheck{
+		ID: 10, InitiatorOwnerID: owner, PropertyID: pgtype.Int4{Int32: 5, Valid: true}, CreditSource: pgtype.Text{String: "property", Valid: true},
+	}, nil)
+	mockQuerier.On("ExpireSolvencyCheck", mock.Anything, int32(11)).Return(postgres.SolvencyCheck{
+		ID: 11, InitiatorOwnerID: owner, CreditSource: pgtype.Text{String: "global", Valid: true},
+	}, nil)
+	// Check 12 got its decision between the listing and the expiry
+	mockQuerier.On("ExpireSolvencyCheck", mock.Anything, int32(12)).Return(postgres.SolvencyCheck{}, pgx.ErrNoRows)
+	mockQuerier.On("GetPropertyForUpdate", mock.Anything, int32(5)).Return(postgres.Property{ID: 5}, nil).Once()
+	mockQuerier.On("CreateCreditTransaction", mock.Anything, mock.MatchedBy(func(arg postgres.CreateCreditTransactionParams) bool {
+		return arg.UserID == owner && arg.PropertyID.Int32 == 5 && arg.Amount == 1 && arg.Description.String == "Refund for expired solvency check #10"
+	})).Return(postgres.CreditTransaction{}, nil).Once()
+	mockQuerier.On("CreateCreditTransaction", mock.Anything, mock.MatchedBy(func(arg postgres.CreateCreditTransactionParams) bool {
+		return arg.UserID == owner && !arg.PropertyID.Valid && arg.Amount == 1 && arg.TransactionType == "refund" && arg.Description.String == "Refund for expired solvency check #11"
+	})).Return(postgres.CreditTransaction{}, nil).Once()
+	mockQuerier.On("GetUserById", mock.Anything, int32(1)).Return(postgres.User{ID: 1, Email: "owner@test.com"}, nil)
+	mockEmail.On("SendNotification", mock.Anything, "owner@test.com", "Dossier de solvabilité expiré", mock.Anything).Return(nil).Twice()
+
+	err := svc.ExpireStaleChecks(context.Background())
+
+	require.NoError(t, err)
+	mockQuerier.AssertNumberOfCalls(t, "CreateCreditTransaction", 2)
+	mockQuerier.AssertExpectations(t)
+	mockEmail.AssertExpectations(t)
+}
@@ -0,0 +1,686 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+	"seculoc-back/internal/platform/logger"
+)
+
+const (
+	GuarantorStatusInvited   = "invited"
+	GuarantorStatusCompleted = "completed"
+
+	maxGuarantorsPerCheck = 2
+)
+
+var (
+	ErrGuarantorNotFound        = errors.New("guarantor not found")
+	ErrGuarantorLimit           = errors.New("maximum number of guarantors reached for this check")
+	ErrGuarantorAlreadyAnalyzed = errors.New("guarantor income already analyzed")
+	ErrGuarantorBoundToLease    = errors.New("guarantor is bound to a signed lease")
+	ErrCheckClosedForGuarantors = errors.New("this check no longer accepts guarantors")
+	ErrGuarantorDocsIncomplete  = errors.New("an identity document and a proof of income are required")
+	ErrGuarantorDocsSubmitted   = errors.New("guarantor documents already submitted")
+)
+
+// guarantorIncomeProofs are the documents that stand in for the bank analysis of a guarantor
+// who completes their file with documents only.
+var guarantorIncomeProofs = []string{"payslip", "tax_notice", "employment_contract"}
+
+// GuarantorParams identifies the person invited to stand surety for the candidate.
+type GuarantorParams struct {
+	Email     string
+	FirstName string
+	LastName  string
+	Phone     string
+}
+
+// GuarantorDTO is the owner's view of a guarantor.
+type GuarantorDTO struct {
+	ID              int32                  `json:"id"`
+	Email           string                 `json:"email"`
+	FirstName       string                 `json:"first_name"`
+	LastName        string                 `json:"last_name"`
+	Status          string                 `json:"status"`
+	MonthlyIncome   float64                `json:"monthly_income,omitempty"`
+	Analysis        *IncomeAnalysis        `json:"analysis,omitempty"`
+	Documents       []CandidateDocumentDTO `json:"documents"`
+	VerificationURL string                 `json:"verification_url"`
+	LeaseID         int32                  `json:"lease_id,omitempty"`
+	MentionSigned   bool                   `json:"mention_signed"`
+}
+
+// GuarantorPublicView is what the guarantor sees through their own link.
+type GuarantorPublicView struct {
+	FirstName       string                 `json:"first_name"`
+	LastName        string                 `json:"last_name"`
+	Email           string                 `json:"email"`
+	Status          string                 `json:"status"`
+	CandidateName   string                 `json:"candidate_name"`
+	PropertyAddress string                 `json:"property_address"`
+	RentAmount      float64                `json:"rent_amount"`
+	Documents       []CandidateDocumentDTO `json:"documents"`
+	// DeedAvailable is set once the lease is signed: the guarantor must then reproduce the mentions
+	DeedAvailable bool `json:"deed_available"`
+	MentionSigned bool `json:"mention_signed"`
+}
+
+func frontendBaseURL() string {
+	if u := viper.GetString("FRONTEND_URL"); u != "" {
+		return u
+	}
+	return "https://seculoc.com"
+}
+
+func guarantorURL(token string) string {
+	return fmt.Sprintf("%s/guarantor/%s", frontendBaseURL(), token)
+}
+
+// guarantorReference identifies a guarantor towards the bank provider without exposing their token.
+func guarantorReference(guarantorID int32) string {
+	return fmt.Sprintf("guarantor-%d", guarantorID)
+}
+
+func toGuarantorDTO(g postgres.SolvencyGuarantor) GuarantorDTO {
+	dto := GuarantorDTO{
+		ID:              g.ID,
+		Email:           g.Email,
+		FirstName:       g.FirstName.String,
+		LastName:        g.LastName.String,
+		Status:          g.Status,
+		Analysis:        IncomeAnalysisFromJSON(g.AnalysisJson),
+		Documents:       CandidateDocumentsFromJSON(g.DocumentsJson),
+		VerificationURL: guarantorURL(g.Token),
+		LeaseID:         g.LeaseID.Int32,
+		MentionSigned:   g.MentionSignedAt.Valid,
+	}
+	if g.MonthlyIncome.Valid {
+		f, _ := g.MonthlyIncome.Float64Value()
+		dto.MonthlyIncome = f.Float64
+	}
+	return dto
+}
+
+// guarantorByToken locks a guarantor for the public flows.
+func guarantorByToken(ctx context.Context, q postgres.Querier, token string) (postgres.SolvencyGuarantor, error) {
+	g, err := q.GetGuarantorByTokenForUpdate(ctx, token)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return g, ErrGuarantorNotFound
+		}
+		return g, err
+	}
+	return g, nil
+}
+
+// openGuarantorByToken locks a guarantor whose check is still active (not cancelled).
+func openGuarantorByToken(ctx context.Context, q postgres.Querier, token string) (postgres.SolvencyGuarantor, error) {
+	g, err := guarantorByToken(ctx, q, token)
+	if err != nil {
+		return g, err
+	}
+	check, err := q.GetSolvencyCheckByID(ctx, g.CheckID)
+	if err != nil {
+		return g, err
+	}
+	if check.Status.SolvencyStatus == postgres.SolvencyStatusCancelled {
+		return g, ErrCheckClosedForGuarantors
+	}
+	return g, nil
+}
+
+// addGuarantor creates a guarantor on a locked check.
+func addGuarantor(ctx context.Context, q postgres.Querier, check postgres.SolvencyCheck, p GuarantorParams) (postgres.SolvencyGuarantor, error) {
+	if check.Status.SolvencyStatus == postgres.SolvencyStatusCancelled {
+		return postgres.SolvencyGuarantor{}, ErrCheckClosedForGuarantors
+	}
+	count, err := q.CountGuarantorsByCheck(ctx, check.ID)
+	if err != nil {
+		return postgres.SolvencyGuarantor{}, err
+	}
+	if count >= maxGuarantorsPerCheck {
+		return postgres.SolvencyGuarantor{}, ErrGuarantorLimit
+	}
+
+	token, err := GenerateSecureToken()
+	if err != nil {
+		return postgres.SolvencyGuarantor{}, fmt.Errorf("failed to generate secure token: %w", err)
+	}
+	return q.CreateSolvencyGuarantor(ctx, postgres.CreateSolvencyGuarantorParams{
+		CheckID:     check.ID,
+		Email:       strings.TrimSpace(p.Email),
+		FirstName:   pgtype.Text{String: p.FirstName, Valid: p.FirstName != ""},
+		LastName:    pgtype.Text{String: p.LastName, Valid: p.LastName != ""},
+		PhoneNumber: pgtype.Text{String: p.Phone, Valid: p.Phone != ""},
+		Token:       token,
+	})
+}
+
+// AddGuarantor invites a guarantor on a check initiated by the owner.
+func (s *SolvencyService) AddGuarantor(ctx context.Context, ownerID, checkID int32, p GuarantorParams) (*GuarantorDTO, error) {
+	return s.createGuarantor(ctx, func(q postgres.Querier) (postgres.SolvencyCheck, error) {
+		check, err := q.GetSolvencyCheckForUpdate(ctx, checkID)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return check, fmt.Errorf("check not found")
+			}
+			return check, err
+		}
+		if check.InitiatorOwnerID.Int32 != ownerID {
+			return check, ErrCheckNotOwned
+		}
+		return check, nil
+	}, p)
+}
+
+// AddGuarantorByToken lets the candidate invite their guarantor from the check link.
+func (s *SolvencyService) AddGuarantorByToken(ctx context.Context, token string, p GuarantorParams) (*GuarantorDTO, error) {
+	return s.createGuarantor(ctx, func(q postgres.Querier) (postgres.SolvencyCheck, error) {
+		return getCheckByTokenForUpdate(ctx, q, token)
+	}, p)
+}
+
+func (s *SolvencyService) createGuarantor(ctx context.Context, lockCheck func(q postgres.Querier) (postgres.SolvencyCheck, error), p GuarantorParams) (*GuarantorDTO, error) {
+	log := logger.FromContext(ctx)
+
+	var g postgres.SolvencyGuarantor
+	var candidate postgres.User
+	var prop postgres.Property
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		check, err := lockCheck(q)
+		if err != nil {
+			return err
+		}
+		g, err = addGuarantor(ctx, q, check, p)
+		if err != nil {
+			return err
+		}
+		candidate, err = q.GetUserById(ctx, check.CandidateID.Int32)
+		if err != nil {
+			return fmt.Errorf("failed to load candidate: %w", err)
+		}
+		prop, err = q.GetProperty(ctx, check.PropertyID.Int32)
+		if err != nil {
+			return fmt.Errorf("failed to load property: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info("guarantor invited", zap.Int32("check_id", g.CheckID), zap.Int32("guarantor_id", g.ID))
+
+	// Email OUTSIDE the transaction: the guarantor can be re-sent their link from the owner's list
+	body := fmt.Sprintf("Bonjour,\n\n%s %s vous demande de vous porter garant de sa candidature pour le logement situé %s.\n\nConstituer mon dossier de garant : %s",
+		candidate.FirstName.String, candidate.LastName.String, prop.Address, guarantorURL(g.Token))
+	if err := s.emailSender.SendNotification(ctx, g.Email, "Demande de garantie pour une location", body); err != nil {
+		log.Error("failed to send guarantor invitation", zap.Error(err), zap.Int32("guarantor_id", g.ID))
+	}
+
+	dto := toGuarantorDTO(g)
+	return &dto, nil
+}
+
+// ListGuarantors returns the guarantors of a check initiated by the owner.
+func (s *SolvencyService) ListGuarantors(ctx context.Context, ownerID, checkID int32) ([]GuarantorDTO, error) {
+	dtos := []GuarantorDTO{}
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		check, err := q.GetSolvencyCheckByID(ctx, checkID)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return fmt.Errorf("check not found")
+			}
+			return err
+		}
+		if check.InitiatorOwnerID.Int32 != ownerID {
+			return ErrCheckNotOwned
+		}
+		guarantors, err := q.ListGuarantorsByCheck(ctx, checkID)
+		if err != nil {
+			return err
+		}
+		for _, g := range guarantors {
+			dtos = append(dtos, toGuarantorDTO(g))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dtos, nil
+}
+
+// RemoveGuarantor deletes a guarantor and their documents, then re-evaluates the check without their income.
+func (s *SolvencyService) RemoveGuarantor(ctx context.Context, ownerID, checkID, guarantorID int32) error {
+	var removed postgres.SolvencyGuarantor
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		check, err := q.GetSolvencyCheckForUpdate(ctx, checkID)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return fmt.Errorf("check not found")
+			}
+			return err
+		}
+		if check.InitiatorOwnerID.Int32 != ownerID {
+			return ErrCheckNotOwned
+		}
+		removed, err = q.GetSolvencyGuarantor(ctx, guarantorID)
+		if err != nil || removed.CheckID != checkID {
+			return ErrGuarantorNotFound
+		}
+		if removed.LeaseID.Valid {
+			return ErrGuarantorBoundToLease
+		}
+		_, err = q.DeleteSolvencyGuarantor(ctx, postgres.DeleteSolvencyGuarantorParams{ID: guarantorID, CheckID: checkID})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	var keys []string
+	for _, d := range decodeCandidateDocuments(removed.DocumentsJson) {
+		keys = append(keys, d.StorageKey)
+	}
+	s.removeFiles(ctx, keys...)
+
+	if removed.Status == GuarantorStatusCompleted {
+		return s.refreshDecision(ctx, checkID)
+	}
+	return nil
+}
+
+// GetGuarantorByToken returns the guarantor's view of the application they guarantee.
+func (s *SolvencyService) GetGuarantorByToken(ctx context.Context, token string) (*GuarantorPublicView, error) {
+	var view GuarantorPublicView
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		g, err := guarantorByToken(ctx, q, token)
+		if err != nil {
+			return err
+		}
+		check, err := q.GetSolvencyCheckByID(ctx, g.CheckID)
+		if err != nil {
+			return err
+		}
+		candidate, err := q.GetUserById(ctx, check.CandidateID.Int32)
+		if err != nil {
+			return fmt.Errorf("failed to load candidate: %w", err)
+		}
+		prop, err := q.GetProperty(ctx, check.PropertyID.Int32)
+		if err != nil {
+			return fmt.Errorf("failed to load property: %w", err)
+		}
+
+		view = GuarantorPublicView{
+			FirstName:       g.FirstName.String,
+			LastName:        g.LastName.String,
+			Email:           g.Email,
+			Status:          g.Status,
+			CandidateName:   strings.TrimSpace(candidate.FirstName.String + " " + candidate.LastName.String),
+			PropertyAddress: prop.Address,
+			Documents:       CandidateDocumentsFromJSON(g.DocumentsJson),
+			DeedAvailable:   g.LeaseID.Valid,
+			MentionSigned:   g.MentionSignedAt.Valid,
+		}
+		if prop.RentAmount.Valid {
+			f, _ := prop.RentAmount.Float64Value()
+			view.RentAmount = f.Float64
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &view, nil
+}
+
+// UploadGuarantorDocument stores a document uploaded by the guarantor through their own link.
+// The document types and limits are the candidate's.
+func (s *SolvencyService) UploadGuarantorDocument(ctx context.Context, token, docType, filename string, content []byte) (*CandidateDocumentDTO, error) {
+	spec, ok := CandidateDocumentTypes[docType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidCandidateDocType, docType)
+	}
+	if len(content) == 0 {
+		return nil, fmt.Errorf("empty file")
+	}
+	if int64(len(content)) > s.MaxCandidateDocumentBytes {
+		return nil, ErrMediaTooLarge
+	}
+	contentType, ext, err := sniffContentType(content, documentContentTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := CandidateDocument{
+		ID:          generateToken()[:16],
+		Type:        docType,
+		Filename:    filepath.Base(filename),
+		ContentType: contentType,
+		SizeBytes:   int64(len(content)),
+		UploadedAt:  time.Now().UTC().Truncate(time.Second),
+	}
+
+	var guarantorID int32
+	err = s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		g, err := openGuarantorByToken(ctx, q, token)
+		if err != nil {
+			return err
+		}
+		guarantorID = g.ID
+
+		docs := decodeCandidateDocuments(g.DocumentsJson)
+		count := 0
+		for _, d := range docs {
+			if d.Type == docType {
+				count++
+			}
+		}
+		if count >= spec.MaxFiles {
+			return fmt.Errorf("%w (%s: %d)", ErrCandidateDocumentLimit, docType, spec.MaxFiles)
+		}
+
+		doc.StorageKey = fmt.Sprintf("solvency/%d/guarantor_%d/%s_%s%s", g.CheckID, g.ID, docType, doc.ID, ext)
+		if _, err := s.storage.Save(doc.StorageKey, content); err != nil {
+			return fmt.Errorf("failed to store document: %w", err)
+		}
+
+		docsJSON, err := json.Marshal(append(docs, doc))
+		if err != nil {
+			return err
+		}
+		return q.UpdateGuarantorDocuments(ctx, postgres.UpdateGuarantorDocumentsParams{ID: g.ID, DocumentsJson: docsJSON})
+	})
+	if err != nil {
+		if doc.StorageKey != "" {
+			s.removeFiles(ctx, doc.StorageKey)
+		}
+		return nil, err
+	}
+
+	logger.FromContext(ctx).Info("guarantor document uploaded", zap.Int32("guarantor_id", guarantorID), zap.String("type", docType), zap.Int("size", len(content)))
+	dto := doc.toDTO()
+	return &dto, nil
+}
+
+// DeleteGuarantorDocument lets the guarantor remove one of their documents.
+func (s *SolvencyService) DeleteGuarantorDocument(ctx context.Context, token, documentID string) error {
+	var removed CandidateDocument
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		g, err := openGuarantorByToken(ctx, q, token)
+		if err != nil {
+			return err
+		}
+		// A file completed with documents only must keep the pieces it was completed with
+		if g.Status == GuarantorStatusCompleted && len(g.AnalysisJson) == 0 {
+			return ErrGuarantorDocsSubmitted
+		}
+
+		docs := decodeCandidateDocuments(g.DocumentsJson)
+		kept := make([]CandidateDocument, 0, len(docs))
+		for _, d := range docs {
+			if d.ID == documentID {
+				removed = d
+				continue
+			}
+			kept = append(kept, d)
+		}
+		if removed.ID == "" {
+			return ErrCandidateDocumentNotFound
+		}
+		docsJSON, err := json.Marshal(kept)
+		if err != nil {
+			return err
+		}
+		return q.UpdateGuarantorDocuments(ctx, postgres.UpdateGuarantorDocumentsParams{ID: g.ID, DocumentsJson: docsJSON})
+	})
+	if err != nil {
+		return err
+	}
+
+	s.removeFiles(ctx, removed.StorageKey)
+	return nil
+}
+
+// OpenGuarantorDocument streams a guarantor document to the owner who initiated the check.
+// The caller must close the reader.
+func (s *SolvencyService) OpenGuarantorDocument(ctx context.Context, ownerID, checkID, guarantorID int32, documentID string) (io.ReadCloser, *CandidateDocumentDTO, error) {
+	var doc CandidateDocument
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		check, err := q.GetSolvencyCheckByID(ctx, checkID)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return fmt.Errorf("check not found")
+			}
+			return err
+		}
+		if check.InitiatorOwnerID.Int32 != ownerID {
+			return ErrCheckNotOwned
+		}
+		g, err := q.GetSolvencyGuarantor(ctx, guarantorID)
+		if err != nil || g.CheckID != checkID {
+			return ErrGuarantorNotFound
+		}
+		for _, d := range decodeCandidateDocuments(g.DocumentsJson) {
+			if d.ID == documentID {
+				doc = d
+				return nil
+			}
+		}
+		return ErrCandidateDocumentNotFound
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reader, err := s.storage.Open(doc.StorageKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open document: %w", err)
+	}
+	logger.FromContext(ctx).Info("guarantor document accessed", zap.Int32("check_id", checkID), zap.Int32("guarantor_id", guarantorID), zap.String("document_id", documentID))
+	dto := doc.toDTO()
+	return reader, &dto, nil
+}
+
+// invitedGuarantorByToken locks a guarantor whose income has not been analyzed yet.
+func invitedGuarantorByToken(ctx context.Context, q postgres.Querier, token string) (postgres.SolvencyGuarantor, error) {
+	g, err := openGuarantorByToken(ctx, q, token)
+	if err != nil {
+		return g, err
+	}
+	if g.Status != GuarantorStatusInvited {
+		return g, ErrGuarantorAlreadyAnalyzed
+	}
+	return g, nil
+}
+
+// SubmitGuarantorDocuments completes the file of a guarantor who sends documents instead of connecting
+// their bank. It needs an identity document and a proof of income; the guarantor is then bound to the
+// lease like an analyzed one, without counting in the income score.
+func (s *SolvencyService) SubmitGuarantorDocuments(ctx context.Context, token string) error {
+	var g postgres.SolvencyGuarantor
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		var err error
+		g, err = invitedGuarantorByToken(ctx, q, token)
+		if err != nil {
+			return err
+		}
+
+		uploaded := map[string]bool{}
+		for _, d := range decodeCandidateDocuments(g.DocumentsJson) {
+			uploaded[d.Type] = true
+		}
+		hasIncomeProof := false
+		for _, t := range guarantorIncomeProofs {
+			hasIncomeProof = hasIncomeProof || uploaded[t]
+		}
+		if !uploaded["identity"] || !hasIncomeProof {
+			return ErrGuarantorDocsIncomplete
+		}
+		return q.CompleteGuarantorDocuments(ctx, g.ID)
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.FromContext(ctx).Info("guarantor documents submitted", zap.Int32("check_id", g.CheckID), zap.Int32("guarantor_id", g.ID))
+	return nil
+}
+
+// readInvitedGuarantor reads a guarantor of the bank flow without keeping it locked, like readPendingCheck.
+func (s *SolvencyService) readInvitedGuarantor(ctx context.Context, token string) (postgres.SolvencyGuarantor, error) {
+	var g postgres.SolvencyGuarantor
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		var err error
+		g, err = invitedGuarantorByToken(ctx, q, token)
+		return err
+	})
+	return g, err
+}
+
+// StartGuarantorBankConsent opens a consent session for the guarantor's bank connection.
+func (s *SolvencyService) StartGuarantorBankConsent(ctx context.Context, token, redirectURL string) (*ConsentSession, error) {
+	if s.bank == nil {
+		return nil, ErrOpenBankingUnavailable
+	}
+
+	g, err := s.readInvitedGuarantor(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	session, err := s.bank.StartConsent(ctx, ConsentRequest{
+		Reference:   guarantorReference(g.ID),
+		RedirectURL: redirectURL,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start bank consent: %w", err)
+	}
+
+	err = s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		if _, err := invitedGuarantorByToken(ctx, q, token); err != nil {
+			return err
+		}
+		return q.SetGuarantorBankConsent(ctx, postgres.SetGuarantorBankConsentParams{
+			ID:            g.ID,
+			BankProvider:  pgtype.Text{String: s.bank.Name(), Valid: true},
+			BankConsentID: pgtype.Text{String: session.ID, Valid: true},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// CompleteGuarantorBankConsent exchanges the guarantor's authorization code and analyzes their income,
+// like CompleteBankConsent does for the candidate.
+func (s *SolvencyService) CompleteGuarantorBankConsent(ctx context.Context, token, code string) error {
+	if s.bank == nil {
+		return ErrOpenBankingUnavailable
+	}
+
+	g, err := s.readInvitedGuarantor(ctx, token)
+	if err != nil {
+		return err
+	}
+	if !g.BankConsentID.Valid || g.BankProvider.String != s.bank.Name() {
+		return ErrBankConsentMissing
+	}
+	conn, err := s.bank.ExchangeCode(ctx, code)
+	if err != nil {
+		return fmt.Errorf("failed to exchange bank code: %w", err)
+	}
+
+	err = s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		// Still invited, and the code was issued for the consent the guarantor holds now
+		g, err = invitedGuarantorByToken(ctx, q, token)
+		if err != nil {
+			return err
+		}
+		if conn.ConsentID != g.BankConsentID.String {
+			return ErrBankConsentMismatch
+		}
+
+		return q.SetGuarantorBankConnection(ctx, postgres.SetGuarantorBankConnectionParams{
+			ID:               g.ID,
+			BankConnectionID: pgtype.Text{String: conn.ID, Valid: true},
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.analyzeGuarantorConnection(ctx, g, conn.ID)
+	if errors.Is(err, ErrBankDataPending) {
+		logger.FromContext(ctx).Info("guarantor bank data pending, awaiting webhook", zap.Int32("guarantor_id", g.ID))
+		return nil
+	}
+	return err
+}
+
+// analyzeGuarantorConnection analyzes the guarantor's income against the property rent,
+// then re-evaluates the check if it was already decided.
+func (s *SolvencyService) analyzeGuarantorConnection(ctx context.Context, g postgres.SolvencyGuarantor, connectionID string) error {
+	transactions, err := s.fetchBankTransactions(ctx, connectionID)
+	if err != nil {
+		return err
+	}
+
+	var rent float64
+	err = s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		check, err := q.GetSolvencyCheckByID(ctx, g.CheckID)
+		if err != nil {
+			return err
+		}
+		prop, err := q.GetProperty(ctx, check.PropertyID.Int32)
+		if err != nil {
+			return err
+		}
+		if prop.RentAmount.Valid {
+			f, _ := prop.RentAmount.Float64Value()
+			rent = f.Float64
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	analysis := AnalyzeIncome(transactions, rent)
+	analysisJSON, err := json.Marshal(analysis)
+	if err != nil {
+		return fmt.Errorf("failed to encode analysis: %w", err)
+	}
+	err = s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		return q.UpdateGuarantorAnalysis(ctx, postgres.UpdateGuarantorAnalysisParams{
+			ID:            g.ID,
+			MonthlyIncome: numeric(analysis.MonthlyIncome),
+			AnalysisJson:  analysisJSON,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.FromContext(ctx).Info("guarantor income analyzed",
+		zap.Int32("check_id", g.CheckID),
+		zap.Int32("guarantor_id", g.ID),
+		zap.Int("score", analysis.Score),
+		zap.Float64("income", analysis.MonthlyIncome))
+
+	return s.refreshDecision(ctx, g.CheckID)
+}
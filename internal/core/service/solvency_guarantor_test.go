@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+)
+
+func TestCombineIncomeAnalyses(t *testing.T) {
+	candidate := AnalyzeIncome([]TransactionData{
+		{Amount: 1500, Description: "SALAIRE ACME", Date: day("2024-01-28")},
+		{Amount: 1500, Description: "SALAIRE ACME", Date: day("2024-02-28")},
+		{Amount: 1500, Description: "SALAIRE ACME", Date: day("2024-03-28")},
+	}, 900)
+	guarantor := AnalyzeIncome([]TransactionData{
+		{Amount: 2500, Description: "PENSION CNAV", Date: day("2024-02-09")},
+		{Amount: 2500, Description: "PENSION CNAV", Date: day("2024-03-09")},
+	}, 900)
+
+	combined := CombineIncomeAnalyses(candidate, []IncomeAnalysis{guarantor})
+
+	assert.Equal(t, round2(candidate.MonthlyIncome+guarantor.MonthlyIncome), combined.MonthlyIncome)
+	assert.Len(t, combined.IncomeSources, 2)
+	assert.Equal(t, guarantor.PeriodStart, combined.PeriodStart, "the shortest history is retained")
+	assert.Greater(t, combined.Score, candidate.Score)
+}
+
+func TestAddGuarantor_Limit(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	svc := NewSolvencyService(passthroughTxManager{q: mockQuerier}, nil, zap.NewNop(), nil, nil)
+
+	mockQuerier.On("GetSolvencyCheckForUpdate", mock.Anything, int32(7)).Return(postgres.SolvencyCheck{
+		ID:               7,
+		InitiatorOwnerID: pgtype.Int4{Int32: 1, Valid: true},
+		Status:           postgres.NullSolvencyStatus{SolvencyStatus: postgres.SolvencyStatusPending, Valid: true},
+	}, nil)
+	mockQuerier.On("CountGuarantorsByCheck", mock.Anything, int32(7)).Return(int64(2), nil)
+
+	_, err := svc.AddGuarantor(context.Background(), 1, 7, GuarantorParams{Email: "garant@example.com"})
+	assert.ErrorIs(t, err, ErrGuarantorLimit)
+	mockQuerier.AssertNotCalled(t, "CreateSolvencyGuarantor", mock.Anything, mock.Anything)
+
+	_, err = svc.AddGuarantor(context.Background(), 2, 7, GuarantorParams{Email: "garant@example.com"})
+	assert.ErrorIs(t, err, ErrCheckNotOwned)
+}
+
+func TestSubmitGuarantorDocuments(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	svc := NewSolvencyService(passthroughTxManager{q: mockQuerier}, nil, zap.NewNop(), nil, nil)
+
+	docs := func(types ...string) []byte {
+		var list []CandidateDocument
+		for i, docType := range types {
+			list = append(list, CandidateDocument{ID: string(rune('a' + i)), Type: docType, StorageKey: "k"})
+		}
+		raw, _ := json.Marshal(list)
+		return raw
+	}
+	guarantor := postgres.SolvencyGuarantor{ID: 3, CheckID: 7, Status: GuarantorStatusInvited, DocumentsJson: docs("identity")}
+	mockQuerier.On("GetSolvencyCheckByID", mock.Anything, int32(7)).Return(postgres.SolvencyCheck{
+		ID:     7,
+		Status: postgres.NullSolvencyStatus{SolvencyStatus: postgres.SolvencyStatusPending, Valid: true},
+	}, nil)
+
+	// Identity alone is not enough
+	mockQuerier.On("GetGuarantorByTokenForUpdate", mock.Anything, "gtok").Return(guarantor, nil).Once()
+	err := svc.SubmitGuarantorDocuments(context.Background(), "gtok")
+	assert.ErrorIs(t, err, ErrGuarantorDocsIncomplete)
+	mockQuerier.AssertNotCalled(t, "CompleteGuarantorDocuments", mock.Anything, mock.Anything)
+
+	guarantor.DocumentsJson = docs("identity", "tax_notice")
+	mockQuerier.On("GetGuarantorByTokenForUpdate", mock.Anything, "gtok").Return(guarantor, nil).Once()
+	mockQuerier.On("CompleteGuarantorDocuments", mock.Anything, int32(3)).Return(nil).Once()
+	require.NoError(t, svc.SubmitGuarantorDocuments(context.Background(), "gtok"))
+
+	// Once submitted, the file is frozen and cannot be submitted again
+	guarantor.Status = GuarantorStatusCompleted
+	mockQuerier.On("GetGuarantorByTokenForUpdate", mock.Anything, "gtok").Return(guarantor, nil)
+	assert.ErrorIs(t, svc.DeleteGuarantorDocument(context.Background(), "gtok", "a"), ErrGuarantorDocsSubmitted)
+	assert.ErrorIs(t, svc.SubmitGuarantorDocuments(context.Background(), "gtok"), ErrGuarantorAlreadyAnalyzed)
+	mockQuerier.AssertExpectations(t)
+}
+
+func TestCompleteGuarantorBankConsent_CombinesWithRejectedCheck(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	svc := NewSolvencyService(passthroughTxManager{q: mockQuerier}, nil, zap.NewNop(), nil, &stubBankProvider{})
+
+	// The candidate alone earns 1500 € for a 900 € rent: rejected
+	weak := AnalyzeIncome([]TransactionData{
+		{Amount: 1500, Description: "SALAIRE ACME", Date: day("2024-01-28")},
+		{Amount: 1500, Description: "SALAIRE ACME", Date: day("2024-02-28")},
+		{Amount: 1500, Description: "SALAIRE ACME", Date: day("2024-03-28")},
+	}, 900)
+	weakJSON, _ := json.Marshal(weak)
+	check := postgres.SolvencyCheck{
+		ID:               7,
+		InitiatorOwnerID: pgtype.Int4{Int32: 1, Valid: true},
+		PropertyID:       pgtype.Int4{Int32: 10, Valid: true},
+		Status:           postgres.NullSolvencyStatus{SolvencyStatus: postgres.SolvencyStatusRejected, Valid: true},
+		AnalysisJson:     weakJSON,
+	}
+	guarantor := postgres.SolvencyGuarantor{
+		ID:            3,
+		CheckID:       7,
+		Status:        GuarantorStatusInvited,
+		BankProvider:  pgtype.Text{String: "stub", Valid: true},
+		BankConsentID: pgtype.Text{String: "consent-1", Valid: true},
+	}
+
+	mockQuerier.On("GetGuarantorByTokenForUpdate", mock.Anything, "gtok").Return(guarantor, nil)
+	mockQuerier.On("GetSolvencyCheckByID", mock.Anything, int32(7)).Return(check, nil)
+	mockQuerier.On("SetGuarantorBankConnection", mock.Anything, postgres.SetGuarantorBankConnectionParams{
+		ID:               3,
+		BankConnectionID: pgtype.Text{String: "conn-1", Valid: true},
+	}).Return(nil)
+	mockQuerier.On("GetProperty", mock.Anything, int32(10)).Return(postgres.Property{ID: 10, RentAmount: numeric(900)}, nil)
+	var guarantorAnalysis []byte
+	mockQuerier.On("UpdateGuarantorAnalysis", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		guarantorAnalysis = args.Get(1).(postgres.UpdateGuarantorAnalysisParams).AnalysisJson
+	}).Return(nil)
+	mockQuerier.On("GetEffectiveSolvencyPolicy", mock.Anything, mock.Anything).Return(postgres.SolvencyPolicy{
+		OwnerID:              1,
+		IncomeMultiplier:     numeric(3),
+		CountGuarantorIncome: true,
+		AcceptedGuarantees:   []byte(`[]`),
+	}, nil)
+	// The decision reads back the analysis the guarantor flow just stored
+	listCall := mockQuerier.On("ListGuarantorsByCheck", mock.Anything, int32(7)).Return([]postgres.SolvencyGuarantor(nil), nil)
+	listCall.Run(func(mock.Arguments) {
+		analyzed := guarantor
+		analyzed.Status, analyzed.AnalysisJson = GuarantorStatusCompleted, guarantorAnalysis
+		listCall.ReturnArguments = mock.Arguments{[]postgres.SolvencyGuarantor{analyzed}, nil}
+	})
+	var stored postgres.UpdateSolvencyCheckResultParams
+	mockQuerier.On("UpdateSolvencyCheckResult", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(postgres.UpdateSolvencyCheckResultParams)
+	}).Return(int64(1), nil)
+
+	err := svc.CompleteGuarantorBankConsent(context.Background(), "gtok", "c1")
+	require.NoError(t, err)
+
+	// 1500 € + the guarantor's 3000 € cover 3 x 900 €
+	assert.Equal(t, postgres.SolvencyStatusApproved, stored.Status.SolvencyStatus)
+	assert.True(t, stored.CombinedScore.Valid)
+	assert.Equal(t, int32(weak.Score), stored.ScoreResult.Int32, "the candidate's own score is kept")
+	assert.Greater(t, stored.CombinedScore.Int32, stored.ScoreResult.Int32)
+}
+
+func deedFixture() (postgres.Lease, postgres.Property, postgres.User, postgres.User, postgres.SolvencyGuarantor) {
+	lease := postgres.Lease{
+		ID:            4,
+		PropertyID:    pgtype.Int4{Int32: 10, Valid: true},
+		TenantID:      pgtype.Int4{Int32: 2, Valid: true},
+		StartDate:     pgtype.Date{Time: time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC), Valid: true},
+		RentAmount:    numeric(850),
+		ChargesAmount: numeric(50),
+	}
+	prop := postgres.Property{ID: 10, Address: "1 rue de la Paix", OwnerID: pgtype.Int4{Int32: 1, Valid: true}}
+	owner := postgres.User{ID: 1, FirstName: pgtype.Text{String: "Paul", Valid: true}, LastName: pgtype.Text{String: "Durand", Valid: true}}
+	tenant := postgres.User{ID: 2, FirstName: pgtype.Text{String: "Jean", Valid: true}, LastName: pgtype.Text{String: "Martin", Valid: true}}
+	g := postgres.SolvencyGuarantor{
+		ID:        3,
+		CheckID:   7,
+		Email:     "marie@example.com",
+		FirstName: pgtype.Text{String: "Marie", Valid: true},
+		LastName:  pgtype.Text{String: "Martin", Valid: true},
+		Status:    GuarantorStatusCompleted,
+		LeaseID:   pgtype.Int4{Int32: 4, Valid: true},
+	}
+	return lease, prop, owner, tenant, g
+}
+
+func TestBuildGuaranteeDeedData(t *testing.T) {
+	lease, prop, owner, tenant, g := deedFixture()
+
+	data := buildGuaranteeDeedData(lease, prop, owner, tenant, g, time.Date(2024, 8, 20, 0, 0, 0, 0, time.UTC))
+
+	// Unfurnished lease without end date: 3 years of 900 €
+	assert.Equal(t, "3 ans", data.DureeEngagement)
+	assert.Equal(t, "32400.00", data.MontantMaximal)
+	assert.Contains(t, data.Mention, "Martin Marie me porte caution solidaire de Martin Jean")
+	assert.Contains(t, data.Mention, "loyer de 850.00 euros par mois")
+	assert.Contains(t, data.Mention, "au cours duquel le bailleur reçoit notification de la résiliation")
+
+	// Furnished lease: 1 year
+	prop.IsFurnished = pgtype.Bool{Bool: true, Valid: true}
+	assert.Equal(t, "1 an", buildGuaranteeDeedData(lease, prop, owner, tenant, g, time.Now()).DureeEngagement)
+}
+
+func TestSignGuaranteeMention(t *testing.T) {
+	viper.Set("ASSETS_DIR", "../../../assets")
+	defer viper.Set("ASSETS_DIR", "")
+
+	lease, prop, owner, tenant, g := deedFixture()
+	mockQuerier := new(MockQuerier)
+	mockFileStore := new(MockFileStorage)
+	svc := NewSolvencyService(passthroughTxManager{q: mockQuerier}, nil, zap.NewNop(), mockFileStore, nil)
+
+	mockQuerier.On("GetGuarantorByTokenForUpdate", mock.Anything, "gtok").Return(g, nil)
+	mockQuerier.On("GetLease", mock.Anything, int32(4)).Return(lease, nil)
+	mockQuerier.On("GetProperty", mock.Anything, int32(10)).Return(prop, nil)
+	mockQuerier.On("GetUserById", mock.Anything, int32(1)).Return(owner, nil)
+	mockQuerier.On("GetSolvencyCheckByID", mock.Anything, int32(7)).Return(postgres.SolvencyCheck{ID: 7, CandidateID: pgtype.Int4{Int32: 2, Valid: true}}, nil)
+	mockQuerier.On("GetUserById", mock.Anything, int32(2)).Return(tenant, nil)
+
+	mention, err := svc.GetGuaranteeMention(context.Background(), "gtok")
+	require.NoError(t, err)
+	assert.False(t, mention.Signed)
+
+	err = svc.SignGuaranteeMention(context.Background(), "gtok", "Je me porte caution")
+	assert.ErrorIs(t, err, ErrGuaranteeMentionMismatch)
+
+	signed := g
+	signed.MentionText = pgtype.Text{String: mention.Text, Valid: true}
+	signed.MentionSignedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+	mockQuerier.On("SetGuarantorMention", mock.Anything, mock.Anything).Return(nil)
+	mockQuerier.On("GetSolvencyGuarantor", mock.Anything, int32(3)).Return(signed, nil)
+	mockQuerier.On("GetNextDocumentVersion", mock.Anything, postgres.GetNextDocumentVersionParams{DocumentType: DocumentTypeGuaranteeDeed, EntityID: 3}).Return(int32(2), nil)
+	var deed string
+	mockFileStore.On("Save", "documents/guarantee_deed/3/v2.html", mock.Anything).Run(func(args mock.Arguments) {
+		deed = string(args.Get(1).([]byte))
+	}).Return("", nil)
+	mockQuerier.On("CreateDocument", mock.Anything, mock.Anything).Return(postgres.Document{ID: 9, Version: 2}, nil)
+
+	// Case, accents, punctuation and line breaks do not matter
+	typed := strings.Replace(strings.ToLower(mention.Text), " ", "\n", 3)
+	err = svc.SignGuaranteeMention(context.Background(), "gtok", strings.ReplaceAll(typed, ",", ""))
+	require.NoError(t, err)
+	assert.Contains(t, deed, "Mention reproduite par la caution le")
+	mockFileStore.AssertExpectations(t)
+}
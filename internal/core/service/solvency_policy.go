@@ -0,0 +1,340 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+	"seculoc-back/internal/platform/logger"
+)
+
+// EmploymentTypes ranks the situations a candidate can declare: a policy requiring "cdi"
+// also accepts the situations ranked above (civil servant) or equal (retired).
+var EmploymentTypes = map[string]struct {
+	Label string
+	Rank  int
+}{
+	"civil_servant": {Label: "Fonctionnaire", Rank: 6},
+	"cdi":           {Label: "CDI", Rank: 5},
+	"retired":       {Label: "Retraité", Rank: 5},
+	"cdd":           {Label: "CDD", Rank: 4},
+	"self_employed": {Label: "Indépendant", Rank: 3},
+	"interim":       {Label: "Intérim", Rank: 2},
+	"student":       {Label: "Étudiant", Rank: 1},
+	"unemployed":    {Label: "Sans emploi", Rank: 0},
+}
+
+// GuaranteeTypes lists the guarantees a candidate can offer.
+var GuaranteeTypes = map[string]string{
+	"visale":         "Garantie Visale",
+	"garantme":       "Garantme",
+	"guarantor":      "Caution personne physique",
+	"bank_guarantee": "Caution bancaire",
+}
+
+// Policy rule codes stored in solvency_checks.policy_results.
+const (
+	RuleScore            = "score"
+	RuleIncomeMultiplier = "income_multiplier"
+	RuleEmploymentType   = "employment_type"
+	RuleGuarantee        = "guarantee"
+)
+
+// maxIncomeMultiplier bounds the "income ≥ N × rent" rule of a stored policy.
+const maxIncomeMultiplier = 10
+
+var (
+	ErrInvalidEmploymentType   = errors.New("invalid employment type")
+	ErrInvalidGuaranteeType    = errors.New("invalid guarantee type")
+	ErrInvalidIncomeMultiplier = fmt.Errorf("income multiplier must be greater than 0 and at most %d", maxIncomeMultiplier)
+)
+
+// SolvencyPolicy is an owner's acceptance policy, either their default one or a property override.
+// Without any stored policy only the score threshold applies.
+type SolvencyPolicy struct {
+	Scope                string   `json:"scope"` // default, owner or property
+	PropertyID           *int32   `json:"property_id,omitempty"`
+	IncomeMultiplier     float64  `json:"income_multiplier"` // 0 only for the default policy, which has no such rule
+	IncludeCharges       bool     `json:"include_charges"`
+	CountGuarantorIncome bool     `json:"count_guarantor_income"`
+	MinEmploymentType    string   `json:"min_employment_type,omitempty"`
+	AcceptedGuarantees   []string `json:"accepted_guarantees"`
+	RequireGuarantee     bool     `json:"require_guarantee"`
+}
+
+// PolicyRuleResult is the outcome of one rule, shown to the owner next to the check status.
+type PolicyRuleResult struct {
+	Code   string `json:"code"`
+	Label  string `json:"label"`
+	Passed bool   `json:"passed"`
+	Detail string `json:"detail"`
+}
+
+// PolicyInput is what the rules are evaluated against.
+type PolicyInput struct {
+	Score    int
+	MinScore int
+	// CombinedScore scores the candidate with their analyzed guarantors (see CombineIncomeAnalyses)
+	CombinedScore   int
+	MonthlyIncome   float64
+	GuarantorIncome float64
+	Rent            float64
+	Charges         float64
+	EmploymentType  string
+	GuaranteeType   string
+}
+
+func defaultSolvencyPolicy() SolvencyPolicy {
+	return SolvencyPolicy{Scope: "default", AcceptedGuarantees: []string{}}
+}
+
+func policyFromRow(row postgres.SolvencyPolicy) SolvencyPolicy {
+	p := SolvencyPolicy{
+		Scope:                "owner",
+		IncludeCharges:       row.IncludeCharges,
+		CountGuarantorIncome: row.CountGuarantorIncome,
+		MinEmploymentType:    row.MinEmploymentType.String,
+		RequireGuarantee:     row.RequireGuarantee,
+		AcceptedGuarantees:   []string{},
+	}
+	if row.PropertyID.Valid {
+		id := row.PropertyID.Int32
+		p.Scope, p.PropertyID = "property", &id
+	}
+	if row.IncomeMultiplier.Valid {
+		f, _ := row.IncomeMultiplier.Float64Value()
+		p.IncomeMultiplier = f.Float64
+	}
+	_ = json.Unmarshal(row.AcceptedGuarantees, &p.AcceptedGuarantees)
+	return p
+}
+
+// PolicyResultsFromJSON decodes policy_results for API responses (nil when the check was not evaluated).
+func PolicyResultsFromJSON(raw []byte) []PolicyRuleResult {
+	if len(raw) == 0 {
+		return nil
+	}
+	var results []PolicyRuleResult
+	if err := json.Unmarshal(raw, &results); err != nil {
+		return nil
+	}
+	return results
+}
+
+// EvaluatePolicy applies every rule of the policy and returns whether the candidate is accepted,
+// with the outcome of each rule. Rules that do not apply (e.g. no guarantee required nor offered) are omitted.
+func EvaluatePolicy(p SolvencyPolicy, in PolicyInput) (bool, []PolicyRuleResult) {
+	var results []PolicyRuleResult
+
+	score := PolicyRuleResult{
+		Code:   RuleScore,
+		Label:  "Score de solvabilité",
+		Passed: in.Score >= in.MinScore,
+		Detail: fmt.Sprintf("Score de %d pour un minimum de %d", in.Score, in.MinScore),
+	}
+	if p.CountGuarantorIncome && in.GuarantorIncome > 0 {
+		score.Passed = in.CombinedScore >= in.MinScore
+		score.Detail = fmt.Sprintf("Score de %d avec le(s) garant(s) (%d seul) pour un minimum de %d", in.CombinedScore, in.Score, in.MinScore)
+	}
+	results = append(results, score)
+
+	if p.IncomeMultiplier > 0 {
+		rent, rentLabel := in.Rent, "loyer hors charges"
+		if p.IncludeCharges {
+			rent, rentLabel = in.Rent+in.Charges, "loyer charges comprises"
+		}
+		required := round2(p.IncomeMultiplier * rent)
+		income, incomeLabel := in.MonthlyIncome, "revenus"
+		if p.CountGuarantorIncome {
+			income, incomeLabel = in.MonthlyIncome+in.GuarantorIncome, "revenus (garant inclus)"
+		}
+		results = append(results, PolicyRuleResult{
+			Code:   RuleIncomeMultiplier,
+			Label:  fmt.Sprintf("Revenus ≥ %g × %s", p.IncomeMultiplier, rentLabel),
+			Passed: income >= required,
+			Detail: fmt.Sprintf("%.2f € de %s pour %.2f € exigés", round2(income), incomeLabel, required),
+		})
+	}
+
+	if min, ok := EmploymentTypes[p.MinEmploymentType]; ok {
+		r := PolicyRuleResult{Code: RuleEmploymentType, Label: "Situation professionnelle minimale : " + min.Label}
+		if declared, ok := EmploymentTypes[in.EmploymentType]; ok {
+			r.Passed = declared.Rank >= min.Rank
+			r.Detail = "Situation déclarée : " + declared.Label
+		} else {
+			r.Detail = "Situation non déclarée par le candidat"
+		}
+		results = append(results, r)
+	}
+
+	if p.RequireGuarantee || in.GuaranteeType != "" && len(p.AcceptedGuarantees) > 0 {
+		r := PolicyRuleResult{Code: RuleGuarantee, Label: "Garantie acceptée"}
+		switch {
+		case in.GuaranteeType == "":
+			r.Detail = "Aucune garantie proposée"
+		case len(p.AcceptedGuarantees) == 0:
+			r.Passed = true
+			r.Detail = "Garantie proposée : " + GuaranteeTypes[in.GuaranteeType]
+		default:
+			for _, g := range p.AcceptedGuarantees {
+				r.Passed = r.Passed || g == in.GuaranteeType
+			}
+			r.Detail = "Garantie proposée : " + GuaranteeTypes[in.GuaranteeType]
+		}
+		results = append(results, r)
+	}
+
+	accepted := true
+	for _, r := range results {
+		accepted = accepted && r.Passed
+	}
+	return accepted, results
+}
+
+// validatePolicy checks the enumerated values and the bounds of a policy submitted by an owner.
+func validatePolicy(p SolvencyPolicy) error {
+	if p.MinEmploymentType != "" {
+		if _, ok := EmploymentTypes[p.MinEmploymentType]; !ok {
+			return ErrInvalidEmploymentType
+		}
+	}
+	for _, g := range p.AcceptedGuarantees {
+		if _, ok := GuaranteeTypes[g]; !ok {
+			return ErrInvalidGuaranteeType
+		}
+	}
+	if !(p.IncomeMultiplier > 0 && p.IncomeMultiplier <= maxIncomeMultiplier) {
+		return ErrInvalidIncomeMultiplier
+	}
+	return nil
+}
+
+// GetPolicy returns the policy applying to a property (propertyID > 0) or the owner's default policy.
+func (s *SolvencyService) GetPolicy(ctx context.Context, ownerID, propertyID int32) (*SolvencyPolicy, error) {
+	policy := defaultSolvencyPolicy()
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		if propertyID > 0 {
+			prop, err := q.GetProperty(ctx, propertyID)
+			if err != nil || prop.OwnerID.Int32 != ownerID {
+				return fmt.Errorf("property not found or access denied")
+			}
+		}
+		row, err := q.GetEffectiveSolvencyPolicy(ctx, postgres.GetEffectiveSolvencyPolicyParams{
+			OwnerID:    ownerID,
+			PropertyID: pgtype.Int4{Int32: propertyID, Valid: propertyID > 0},
+		})
+		if err == pgx.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		policy = policyFromRow(row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// SetPolicy stores the owner's default policy (propertyID == 0) or a policy specific to one of their properties.
+func (s *SolvencyService) SetPolicy(ctx context.Context, ownerID, propertyID int32, p SolvencyPolicy) (*SolvencyPolicy, error) {
+	if err := validatePolicy(p); err != nil {
+		return nil, err
+	}
+	if p.AcceptedGuarantees == nil {
+		p.AcceptedGuarantees = []string{}
+	}
+	guarantees, err := json.Marshal(p.AcceptedGuarantees)
+	if err != nil {
+		return nil, err
+	}
+	minEmployment := pgtype.Text{String: p.MinEmploymentType, Valid: p.MinEmploymentType != ""}
+
+	var row postgres.SolvencyPolicy
+	err = s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		if propertyID == 0 {
+			var err error
+			row, err = q.UpsertOwnerSolvencyPolicy(ctx, postgres.UpsertOwnerSolvencyPolicyParams{
+				OwnerID:              ownerID,
+				IncomeMultiplier:     numeric(p.IncomeMultiplier),
+				IncludeCharges:       p.IncludeCharges,
+				CountGuarantorIncome: p.CountGuarantorIncome,
+				MinEmploymentType:    minEmployment,
+				AcceptedGuarantees:   guarantees,
+				RequireGuarantee:     p.RequireGuarantee,
+			})
+			return err
+		}
+
+		prop, err := q.GetProperty(ctx, propertyID)
+		if err != nil || prop.OwnerID.Int32 != ownerID {
+			return fmt.Errorf("property not found or access denied")
+		}
+		row, err = q.UpsertPropertySolvencyPolicy(ctx, postgres.UpsertPropertySolvencyPolicyParams{
+			OwnerID:              ownerID,
+			PropertyID:           pgtype.Int4{Int32: propertyID, Valid: true},
+			IncomeMultiplier:     numeric(p.IncomeMultiplier),
+			IncludeCharges:       p.IncludeCharges,
+			CountGuarantorIncome: p.CountGuarantorIncome,
+			MinEmploymentType:    minEmployment,
+			AcceptedGuarantees:   guarantees,
+			RequireGuarantee:     p.RequireGuarantee,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx).Info("solvency policy updated", zap.Int32("owner_id", ownerID), zap.Int32("property_id", propertyID))
+	policy := policyFromRow(row)
+	return &policy, nil
+}
+
+// DeletePropertyPolicy removes a property override: the owner's default policy applies again.
+func (s *SolvencyService) DeletePropertyPolicy(ctx context.Context, ownerID, propertyID int32) error {
+	return s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		rows, err := q.DeletePropertySolvencyPolicy(ctx, postgres.DeletePropertySolvencyPolicyParams{
+			PropertyID: pgtype.Int4{Int32: propertyID, Valid: true},
+			OwnerID:    ownerID,
+		})
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return fmt.Errorf("property not found or access denied")
+		}
+		return nil
+	})
+}
+
+// UpdateCandidateProfile records the situation and guarantee declared by the candidate, used by the policy rules.
+func (s *SolvencyService) UpdateCandidateProfile(ctx context.Context, token, employmentType, guaranteeType string) error {
+	if _, ok := EmploymentTypes[employmentType]; !ok && employmentType != "" {
+		return ErrInvalidEmploymentType
+	}
+	if _, ok := GuaranteeTypes[guaranteeType]; !ok && guaranteeType != "" {
+		return ErrInvalidGuaranteeType
+	}
+
+	return s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		check, err := pendingCheckByToken(ctx, q, token)
+		// The candidate may still complete their profile while documents are requested
+		if err != nil && !(errors.Is(err, ErrCheckAlreadyProcessed) && check.Status.SolvencyStatus == postgres.SolvencyStatusInsufficientDocs) {
+			return err
+		}
+		return q.UpdateSolvencyCheckProfile(ctx, postgres.UpdateSolvencyCheckProfileParams{
+			ID:             check.ID,
+			EmploymentType: pgtype.Text{String: employmentType, Valid: employmentType != ""},
+			GuaranteeType:  pgtype.Text{String: guaranteeType, Valid: guaranteeType != ""},
+		})
+	})
+}
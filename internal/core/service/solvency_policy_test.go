@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+)
+
+// expectAnalysisLookups mocks what analyzeTransactions reads: the check, a 900 € property, no stored policy and no guarantor.
+func expectAnalysisLookups(q *MockQuerier, check postgres.SolvencyCheck) {
+	q.On("GetSolvencyCheckByID", mock.Anything, check.ID).Return(check, nil)
+	q.On("GetProperty", mock.Anything, check.PropertyID.Int32).Return(postgres.Property{ID: check.PropertyID.Int32, RentAmount: numeric(900)}, nil)
+	q.On("GetEffectiveSolvencyPolicy", mock.Anything, mock.Anything).Return(postgres.SolvencyPolicy{}, pgx.ErrNoRows)
+	q.On("ListGuarantorsByCheck", mock.Anything, check.ID).Return([]postgres.SolvencyGuarantor{}, nil)
+}
+
+func resultsByCode(results []PolicyRuleResult) map[string]bool {
+	m := map[string]bool{}
+	for _, r := range results {
+		m[r.Code] = r.Passed
+	}
+	return m
+}
+
+func TestEvaluatePolicy_DefaultOnlyChecksScore(t *testing.T) {
+	accepted, results := EvaluatePolicy(defaultSolvencyPolicy(), PolicyInput{Score: 70, MinScore: 60, MonthlyIncome: 1000, Rent: 900})
+
+	assert.True(t, accepted)
+	assert.Equal(t, map[string]bool{RuleScore: true}, resultsByCode(results))
+}
+
+func TestEvaluatePolicy_IncomeMultiplier(t *testing.T) {
+	policy := SolvencyPolicy{IncomeMultiplier: 3, IncludeCharges: true}
+	in := PolicyInput{Score: 80, MinScore: 60, MonthlyIncome: 2800, Rent: 850, Charges: 100}
+
+	// 3 x 950 = 2850 > 2800
+	accepted, results := EvaluatePolicy(policy, in)
+	assert.False(t, accepted)
+	assert.False(t, resultsByCode(results)[RuleIncomeMultiplier])
+
+	// Charges excluded: 3 x 850 = 2550
+	policy.IncludeCharges = false
+	accepted, _ = EvaluatePolicy(policy, in)
+	assert.True(t, accepted)
+
+	// Guarantor income only counts when the policy says so
+	policy.IncludeCharges = true
+	in.GuarantorIncome, in.CombinedScore = 1000, 85
+	accepted, _ = EvaluatePolicy(policy, in)
+	assert.False(t, accepted)
+	policy.CountGuarantorIncome = true
+	accepted, results = EvaluatePolicy(policy, in)
+	assert.True(t, accepted)
+	assert.Contains(t, results[1].Detail, "garant inclus")
+
+	// With guarantor income counted, the score rule uses the combined score
+	in.CombinedScore = 40
+	accepted, results = EvaluatePolicy(policy, in)
+	assert.False(t, accepted)
+	assert.False(t, resultsByCode(results)[RuleScore])
+}
+
+func TestEvaluatePolicy_EmploymentAndGuarantee(t *testing.T) {
+	policy := SolvencyPolicy{MinEmploymentType: "cdi", AcceptedGuarantees: []string{"visale"}, RequireGuarantee: true}
+	in := PolicyInput{Score: 80, MinScore: 60}
+
+	accepted, results := EvaluatePolicy(policy, in)
+	assert.False(t, accepted)
+	assert.Equal(t, map[string]bool{RuleScore: true, RuleEmploymentType: false, RuleGuarantee: false}, resultsByCode(results))
+
+	in.EmploymentType, in.GuaranteeType = "civil_servant", "visale"
+	accepted, _ = EvaluatePolicy(policy, in)
+	assert.True(t, accepted)
+
+	in.EmploymentType, in.GuaranteeType = "cdd", "guarantor"
+	_, results = EvaluatePolicy(policy, in)
+	assert.Equal(t, map[string]bool{RuleScore: true, RuleEmploymentType: false, RuleGuarantee: false}, resultsByCode(results))
+
+	// A guarantee that is neither required nor restricted is not a rule
+	accepted, results = EvaluatePolicy(SolvencyPolicy{}, in)
+	assert.True(t, accepted)
+	assert.Len(t, results, 1)
+}
+
+func TestAnalyzeTransactions_AppliesPropertyPolicy(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	svc := NewSolvencyService(passthroughTxManager{q: mockQuerier}, nil, zap.NewNop(), nil, nil)
+	check := postgres.SolvencyCheck{
+		ID:               7,
+		InitiatorOwnerID: pgtype.Int4{Int32: 1, Valid: true},
+		PropertyID:       pgtype.Int4{Int32: 10, Valid: true},
+		EmploymentType:   pgtype.Text{String: "cdd", Valid: true},
+	}
+
+	mockQuerier.On("GetSolvencyCheckByID", mock.Anything, int32(7)).Return(check, nil)
+	mockQuerier.On("GetProperty", mock.Anything, int32(10)).Return(postgres.Property{ID: 10, RentAmount: numeric(900), RentChargesAmount: numeric(100)}, nil)
+	mockQuerier.On("GetEffectiveSolvencyPolicy", mock.Anything, postgres.GetEffectiveSolvencyPolicyParams{
+		OwnerID:    1,
+		PropertyID: pgtype.Int4{Int32: 10, Valid: true},
+	}).Return(postgres.SolvencyPolicy{
+		OwnerID:            1,
+		PropertyID:         pgtype.Int4{Int32: 10, Valid: true},
+		IncomeMultiplier:   numeric(3),
+		IncludeCharges:     true,
+		MinEmploymentType:  pgtype.Text{String: "cdi", Valid: true},
+		AcceptedGuarantees: []byte(`[]`),
+	}, nil)
+	mockQuerier.On("ListGuarantorsByCheck", mock.Anything, int32(7)).Return([]postgres.SolvencyGuarantor{}, nil)
+	var stored postgres.UpdateSolvencyCheckResultParams
+	mockQuerier.On("UpdateSolvencyCheckResult", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(postgres.UpdateSolvencyCheckResultParams)
+	}).Return(int64(1), nil)
+
+	err := svc.analyzeTransactions(context.Background(), 7, []TransactionData{
+		{Amount: 3200, Description: "SALAIRE ACME", Date: day("2024-01-28")},
+		{Amount: 3200, Description: "SALAIRE ACME", Date: day("2024-02-28")},
+		{Amount: 3200, Description: "SALAIRE ACME", Date: day("2024-03-28")},
+	})
+	require.NoError(t, err)
+
+	// Good score and 3200 >= 3 x 1000, but a CDD does not meet the CDI requirement
+	assert.Equal(t, postgres.SolvencyStatusRejected, stored.Status.SolvencyStatus)
+	assert.Equal(t, map[string]bool{RuleScore: true, RuleIncomeMultiplier: true, RuleEmploymentType: false},
+		resultsByCode(PolicyResultsFromJSON(stored.PolicyResults)))
+}
+
+func TestSetPolicy_RejectsForeignProperty(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	svc := NewSolvencyService(passthroughTxManager{q: mockQuerier}, nil, zap.NewNop(), nil, nil)
+
+	mockQuerier.On("GetProperty", mock.Anything, int32(10)).Return(postgres.Property{ID: 10, OwnerID: pgtype.Int4{Int32: 2, Valid: true}}, nil)
+
+	_, err := svc.SetPolicy(context.Background(), 1, 10, SolvencyPolicy{IncomeMultiplier: 3})
+	assert.EqualError(t, err, "property not found or access denied")
+	mockQuerier.AssertNotCalled(t, "UpsertPropertySolvencyPolicy", mock.Anything, mock.Anything)
+
+	_, err = svc.SetPolicy(context.Background(), 1, 0, SolvencyPolicy{MinEmploymentType: "astronaut"})
+	assert.ErrorIs(t, err, ErrInvalidEmploymentType)
+}
+
+func TestSetPolicy_RejectsNonPositiveMultiplier(t *testing.T) {
+	svc := NewSolvencyService(passthroughTxManager{q: new(MockQuerier)}, nil, zap.NewNop(), nil, nil)
+
+	for _, m := range []float64{0, -3, 12} {
+		_, err := svc.SetPolicy(context.Background(), 1, 0, SolvencyPolicy{IncomeMultiplier: m})
+		assert.ErrorIs(t, err, ErrInvalidIncomeMultiplier, "multiplier %v", m)
+	}
+}
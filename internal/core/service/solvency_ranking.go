@@ -0,0 +1,376 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+	"seculoc-back/internal/platform/logger"
+)
+
+// Owner selection among the candidates of a property (solvency_checks.selection)
+const (
+	SelectionShortlisted = "shortlisted"
+	SelectionDeclined    = "declined"
+)
+
+var (
+	ErrInvalidSelection  = errors.New("selection must be shortlisted, declined or none")
+	ErrCheckNotCompleted = errors.New("only a completed check can be shortlisted or declined")
+	ErrCandidateDeclined = errors.New("this candidate was already declined and notified")
+)
+
+// Ranking signals
+const (
+	SignalScore        = "score"
+	SignalEffortRate   = "effort_rate"
+	SignalStability    = "income_stability"
+	SignalCompleteness = "documents"
+	SignalGuarantee    = "guarantee"
+)
+
+// rankingSignals weigh the signals used to compare candidates; the weights add up to 100.
+var rankingSignals = []struct {
+	Code   string
+	Label  string
+	Weight float64
+}{
+	{SignalScore, "Score de solvabilité", 35},
+	{SignalEffortRate, "Taux d'effort", 25},
+	{SignalStability, "Stabilité des revenus", 15},
+	{SignalCompleteness, "Complétude du dossier", 10},
+	{SignalGuarantee, "Garantie", 15},
+}
+
+// CandidateSignal is one criterion of the comparison. Points is the weighted contribution to the
+// ranking score; GapToBest is how many points the candidate trails the best one on this signal.
+type CandidateSignal struct {
+	Code      string  `json:"code"`
+	Label     string  `json:"label"`
+	Value     float64 `json:"value"`
+	Display   string  `json:"display"`
+	Points    float64 `json:"points"`
+	MaxPoints float64 `json:"max_points"`
+	Best      bool    `json:"best"`
+	GapToBest float64 `json:"gap_to_best"`
+}
+
+// RankedCandidate is a completed check in the comparison. Declined candidates are listed last, unranked.
+type RankedCandidate struct {
+	Rank           int               `json:"rank"`
+	CheckID        int32             `json:"check_id"`
+	CandidateEmail string            `json:"candidate_email"`
+	CandidateName  string            `json:"candidate_name"`
+	Status         string            `json:"status"`
+	Selection      string            `json:"selection,omitempty"`
+	RankingScore   int               `json:"ranking_score"`
+	Signals        []CandidateSignal `json:"signals"`
+	Highlights     []string          `json:"highlights"`
+}
+
+type CandidateComparison struct {
+	PropertyID int32             `json:"property_id"`
+	Candidates []RankedCandidate `json:"candidates"`
+	// AwaitingCandidates counts the checks still waiting for their candidate, not compared yet
+	AwaitingCandidates int `json:"awaiting_candidates"`
+}
+
+func checkCompleted(status postgres.SolvencyStatus) bool {
+	return status == postgres.SolvencyStatusApproved || status == postgres.SolvencyStatusRejected
+}
+
+// candidateSignals computes the raw signals of a completed check, each normalized between 0 and 1.
+func candidateSignals(c postgres.ListSolvencyChecksByPropertyRow) ([]CandidateSignal, []string) {
+	analysis := IncomeAnalysisFromJSON(c.AnalysisJson)
+	if analysis == nil {
+		analysis = &IncomeAnalysis{}
+	}
+
+	var missing []string
+	uploaded := map[string]bool{}
+	for _, d := range CandidateDocumentsFromJSON(c.DocumentsJson) {
+		uploaded[d.Type] = true
+	}
+	for code, t := range CandidateDocumentTypes {
+		if !uploaded[code] {
+			missing = append(missing, t.Label)
+		}
+	}
+	sort.Strings(missing)
+
+	signals := make([]CandidateSignal, 0, len(rankingSignals))
+	for _, def := range rankingSignals {
+		sig := CandidateSignal{Code: def.Code, Label: def.Label, MaxPoints: def.Weight}
+		var norm float64
+		switch def.Code {
+		case SignalScore:
+			score := c.ScoreResult.Int32
+			sig.Display = fmt.Sprintf("%d/100", score)
+			if c.CombinedScore.Valid {
+				score = c.CombinedScore.Int32
+				sig.Display = fmt.Sprintf("%d/100 avec garants", score)
+			}
+			sig.Value = float64(score)
+			norm = float64(score) / 100
+		case SignalEffortRate:
+			sig.Value = analysis.EffortRate
+			if analysis.RentAmount <= 0 {
+				sig.Display = "Loyer non renseigné"
+				break
+			}
+			if analysis.MonthlyIncome <= 0 {
+				sig.Display = "Aucun revenu détecté"
+				break
+			}
+			sig.Display = fmt.Sprintf("%.0f %%", analysis.EffortRate*100)
+			// Full points up to 25 %, none from 50 %
+			norm = math.Max(0, math.Min(1, (0.50-analysis.EffortRate)/0.25))
+		case SignalStability:
+			if analysis.MonthlyIncome > 0 {
+				sig.Value = round2(analysis.MonthlyRecurringIncome / analysis.MonthlyIncome)
+			}
+			sig.Display = fmt.Sprintf("%.0f %% de revenus récurrents", sig.Value*100)
+			norm = sig.Value
+		case SignalCompleteness:
+			sig.Value = float64(len(uploaded))
+			sig.Display = fmt.Sprintf("%d/%d pièces", len(uploaded), len(CandidateDocumentTypes))
+			norm = sig.Value / float64(len(CandidateDocumentTypes))
+		case SignalGuarantee:
+			switch {
+			case c.GuaranteeType.String != "":
+				sig.Display = GuaranteeTypes[c.GuaranteeType.String]
+				norm = 1
+			case c.CombinedScore.Valid:
+				sig.Display = GuaranteeTypes["guarantor"]
+				norm = 1
+			default:
+				sig.Display = "Aucune garantie"
+			}
+			sig.Value = norm
+		}
+		sig.Points = math.Round(def.Weight*norm*10) / 10
+		signals = append(signals, sig)
+	}
+	return signals, missing
+}
+
+// rankCandidates orders the completed checks by ranking score (earliest check first on a tie) and
+// compares every signal with the best candidate's.
+func rankCandidates(rows []postgres.ListSolvencyChecksByPropertyRow) []RankedCandidate {
+	candidates := []RankedCandidate{}
+	missingByCheck := map[int32][]string{}
+	for _, row := range rows {
+		signals, missing := candidateSignals(row)
+		total := 0.0
+		for _, s := range signals {
+			total += s.Points
+		}
+		candidates = append(candidates, RankedCandidate{
+			CheckID:        row.ID,
+			CandidateEmail: row.CandidateEmail,
+			CandidateName:  strings.TrimSpace(row.CandidateLastName.String + " " + row.CandidateFirstName.String),
+			Status:         string(row.Status.SolvencyStatus),
+			Selection:      row.Selection.String,
+			RankingScore:   int(math.Round(total)),
+			Signals:        signals,
+		})
+		missingByCheck[row.ID] = missing
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		di, dj := candidates[i].Selection == SelectionDeclined, candidates[j].Selection == SelectionDeclined
+		if di != dj {
+			return dj
+		}
+		return candidates[i].RankingScore > candidates[j].RankingScore
+	})
+
+	// Best value of each signal among the candidates still in the running
+	best := map[string]CandidateSignal{}
+	compared := 0
+	for _, c := range candidates {
+		if c.Selection == SelectionDeclined {
+			continue
+		}
+		compared++
+		for _, s := range c.Signals {
+			if b, ok := best[s.Code]; !ok || s.Points > b.Points {
+				best[s.Code] = s
+			}
+		}
+	}
+
+	for i := range candidates {
+		c := &candidates[i]
+		if c.Selection != SelectionDeclined {
+			c.Rank = i + 1
+		}
+		c.Highlights = []string{}
+		for j := range c.Signals {
+			s := &c.Signals[j]
+			b, ok := best[s.Code]
+			if !ok {
+				continue
+			}
+			s.GapToBest = math.Round((b.Points-s.Points)*10) / 10
+			s.Best = s.GapToBest <= 0
+			if compared < 2 || c.Selection == SelectionDeclined {
+				continue
+			}
+			switch {
+			case s.Best && s.Points > 0 && uniqueBest(candidates, s.Code, b.Points):
+				c.Highlights = append(c.Highlights, fmt.Sprintf("Meilleur critère « %s » : %s", s.Label, s.Display))
+			case s.GapToBest >= s.MaxPoints/4:
+				c.Highlights = append(c.Highlights, fmt.Sprintf("En retrait sur « %s » : %s contre %s", s.Label, s.Display, b.Display))
+			}
+		}
+		if missing := missingByCheck[c.CheckID]; len(missing) > 0 {
+			c.Highlights = append(c.Highlights, "Pièces absentes : "+strings.Join(missing, ", "))
+		}
+	}
+	return candidates
+}
+
+// uniqueBest tells whether a single candidate in the running reaches the best points of a signal.
+func uniqueBest(candidates []RankedCandidate, code string, points float64) bool {
+	n := 0
+	for _, c := range candidates {
+		if c.Selection == SelectionDeclined {
+			continue
+		}
+		for _, s := range c.Signals {
+			if s.Code == code && s.Points >= points {
+				n++
+			}
+		}
+	}
+	return n == 1
+}
+
+// CompareCandidates ranks the completed checks of a property using the score, the effort rate, the
+// income stability, the completeness of the file and the guarantee, and highlights their differences.
+func (s *SolvencyService) CompareCandidates(ctx context.Context, ownerID, propertyID int32) (*CandidateComparison, error) {
+	comparison := CandidateComparison{PropertyID: propertyID}
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		prop, err := q.GetProperty(ctx, propertyID)
+		if err != nil || prop.OwnerID.Int32 != ownerID {
+			return fmt.Errorf("property not found or access denied")
+		}
+		rows, err := q.ListSolvencyChecksByProperty(ctx, pgtype.Int4{Int32: propertyID, Valid: true})
+		if err != nil {
+			return err
+		}
+
+		var completed []postgres.ListSolvencyChecksByPropertyRow
+		for _, row := range rows {
+			// Another owner's checks on a property that changed hands are not theirs to compare
+			if row.InitiatorOwnerID.Int32 != ownerID {
+				continue
+			}
+			switch {
+			case checkCompleted(row.Status.SolvencyStatus):
+				completed = append(completed, row)
+			case acceptsDocuments(row.Status.SolvencyStatus):
+				comparison.AwaitingCandidates++
+			}
+		}
+		comparison.Candidates = rankCandidates(completed)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &comparison, nil
+}
+
+// SetCandidateSelection shortlists or declines the candidate of a completed check ("none" clears the
+// shortlist). A declined candidate is notified by email, which cannot be undone.
+func (s *SolvencyService) SetCandidateSelection(ctx context.Context, ownerID, checkID int32, selection string) error {
+	log := logger.FromContext(ctx).With(zap.Int32("check_id", checkID))
+	if selection == "none" {
+		selection = ""
+	}
+	if selection != "" && selection != SelectionShortlisted && selection != SelectionDeclined {
+		return ErrInvalidSelection
+	}
+
+	var candidate postgres.User
+	var prop postgres.Property
+	notify := false
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		check, err := q.GetSolvencyCheckForUpdate(ctx, checkID)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return fmt.Errorf("check not found")
+			}
+			return err
+		}
+		if check.InitiatorOwnerID.Int32 != ownerID {
+			return ErrCheckNotOwned
+		}
+		if !checkCompleted(check.Status.SolvencyStatus) {
+			return ErrCheckNotCompleted
+		}
+		if check.Selection.String == selection {
+			return nil
+		}
+		if check.Selection.String == SelectionDeclined {
+			return ErrCandidateDeclined
+		}
+
+		err = q.SetSolvencyCheckSelection(ctx, postgres.SetSolvencyCheckSelectionParams{
+			ID:        checkID,
+			Selection: pgtype.Text{String: selection, Valid: selection != ""},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update selection: %w", err)
+		}
+		if selection != SelectionDeclined {
+			return nil
+		}
+
+		if candidate, err = q.GetUserById(ctx, check.CandidateID.Int32); err != nil {
+			return fmt.Errorf("failed to load candidate: %w", err)
+		}
+		if prop, err = q.GetProperty(ctx, check.PropertyID.Int32); err != nil {
+			return fmt.Errorf("failed to load property: %w", err)
+		}
+		notify = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Info("candidate selection updated", zap.String("selection", selection))
+	if notify {
+		s.notifyDeclinedCandidate(ctx, candidate, prop)
+	}
+	return nil
+}
+
+// notifyDeclinedCandidate tells a candidate their application was not retained. The email carries no
+// reason, score or information about the other candidates, and reminds the candidate of their rights
+// over their data (GDPR).
+func (s *SolvencyService) notifyDeclinedCandidate(ctx context.Context, candidate postgres.User, prop postgres.Property) {
+	greeting := "Bonjour"
+	if candidate.FirstName.String != "" {
+		greeting += " " + candidate.FirstName.String
+	}
+	subject := "Votre candidature pour un logement"
+	body := fmt.Sprintf("%s,\n\nNous vous remercions de l'intérêt porté au logement situé %s. Le propriétaire n'a pas retenu votre candidature.\n\n"+
+		"Les données et pièces de votre dossier ont été utilisées uniquement pour l'étude de cette candidature et n'ont pas été communiquées à d'autres personnes. "+
+		"Elles ne sont conservées que pendant la durée nécessaire puis supprimées. Vous pouvez à tout moment demander l'accès à vos données ou leur suppression en répondant à ce message.",
+		greeting, prop.Address)
+	if err := s.emailSender.SendNotification(ctx, candidate.Email, subject, body); err != nil {
+		logger.FromContext(ctx).Error("failed to notify declined candidate", zap.Error(err), zap.String("email", candidate.Email))
+	}
+}
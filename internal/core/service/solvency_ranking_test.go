@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+)
+
+func rankingRow(t *testing.T, id int32, score int32, analysis IncomeAnalysis, docTypes []string, guarantee string) postgres.ListSolvencyChecksByPropertyRow {
+	rawAnalysis, err := json.Marshal(analysis)
+	require.NoError(t, err)
+	var docs []CandidateDocument
+	for _, typ := range docTypes {
+		docs = append(docs, CandidateDocument{ID: typ, Type: typ})
+	}
+	rawDocs, err := json.Marshal(docs)
+	require.NoError(t, err)
+	return postgres.ListSolvencyChecksByPropertyRow{
+		ID:                 id,
+		InitiatorOwnerID:   pgtype.Int4{Int32: 1, Valid: true},
+		Status:             postgres.NullSolvencyStatus{SolvencyStatus: postgres.SolvencyStatusApproved, Valid: true},
+		ScoreResult:        pgtype.Int4{Int32: score, Valid: true},
+		AnalysisJson:       rawAnalysis,
+		DocumentsJson:      rawDocs,
+		GuaranteeType:      pgtype.Text{String: guarantee, Valid: guarantee != ""},
+		CandidateEmail:     "c" + string(rune('0'+id)) + "@test.com",
+		CandidateFirstName: pgtype.Text{String: "Candidat", Valid: true},
+		CandidateLastName:  pgtype.Text{String: string(rune('A' + id - 1)), Valid: true},
+	}
+}
+
+func signal(c RankedCandidate, code string) CandidateSignal {
+	for _, s := range c.Signals {
+		if s.Code == code {
+			return s
+		}
+	}
+	return CandidateSignal{}
+}
+
+func TestRankCandidates(t *testing.T) {
+	allDocs := []string{"identity", "payslip", "tax_notice", "employment_contract"}
+	rows := []postgres.ListSolvencyChecksByPropertyRow{
+		// A: decent score, high effort rate, no guarantee, incomplete file
+		rankingRow(t, 1, 70, IncomeAnalysis{MonthlyIncome: 2000, MonthlyRecurringIncome: 2000, RentAmount: 900, EffortRate: 0.45}, []string{"identity"}, ""),
+		// B: best score and effort rate, Visale
+		rankingRow(t, 2, 90, IncomeAnalysis{MonthlyIncome: 4000, MonthlyRecurringIncome: 3000, RentAmount: 800, EffortRate: 0.20}, allDocs, "visale"),
+		// C: good but declined by the owner
+		rankingRow(t, 3, 95, IncomeAnalysis{MonthlyIncome: 5000, MonthlyRecurringIncome: 5000, RentAmount: 750, EffortRate: 0.15}, allDocs, "visale"),
+	}
+	rows[2].Selection = pgtype.Text{String: SelectionDeclined, Valid: true}
+
+	ranked := rankCandidates(rows)
+
+	require.Len(t, ranked, 3)
+	assert.Equal(t, int32(2), ranked[0].CheckID)
+	assert.Equal(t, 1, ranked[0].Rank)
+	assert.Equal(t, int32(1), ranked[1].CheckID)
+	assert.Equal(t, 2, ranked[1].Rank)
+	assert.Equal(t, int32(3), ranked[2].CheckID, "declined candidates come last")
+	assert.Zero(t, ranked[2].Rank)
+
+	// B: 35*0.9 + 25 + 15*0.75 + 10 + 15
+	assert.Equal(t, 93, ranked[0].RankingScore)
+	assert.True(t, signal(ranked[0], SignalEffortRate).Best)
+	assert.Equal(t, "20 %", signal(ranked[0], SignalEffortRate).Display)
+	assert.Contains(t, ranked[0].Highlights, "Meilleur critère « Garantie » : Garantie Visale")
+
+	// A: 35*0.7 + 25*0.2 + 15 + 10*0.25 + 0
+	assert.Equal(t, 47, ranked[1].RankingScore)
+	assert.Equal(t, 20.0, signal(ranked[1], SignalEffortRate).GapToBest)
+	assert.Contains(t, ranked[1].Highlights, "En retrait sur « Taux d'effort » : 45 % contre 20 %")
+	assert.Contains(t, ranked[1].Highlights, "Meilleur critère « Stabilité des revenus » : 100 % de revenus récurrents")
+	assert.Contains(t, ranked[1].Highlights, "Pièces absentes : Avis d'imposition, Bulletin de salaire, Contrat de travail")
+}
+
+func TestCompareCandidates_OnlyCompletedChecks(t *testing.T) {
+	svc, mockQuerier, _, _ := setupSolvencyDocuments()
+	pending := rankingRow(t, 4, 0, IncomeAnalysis{}, nil, "")
+	pending.Status.SolvencyStatus = postgres.SolvencyStatusPending
+
+	mockQuerier.On("GetProperty", mock.Anything, int32(10)).Return(postgres.Property{ID: 10, OwnerID: pgtype.Int4{Int32: 1, Valid: true}}, nil)
+	mockQuerier.On("ListSolvencyChecksByProperty", mock.Anything, pgtype.Int4{Int32: 10, Valid: true}).Return([]postgres.ListSolvencyChecksByPropertyRow{
+		rankingRow(t, 1, 70, IncomeAnalysis{MonthlyIncome: 2000, EffortRate: 0.3}, nil, ""),
+		pending,
+	}, nil)
+
+	comparison, err := svc.CompareCandidates(context.Background(), 1, 10)
+
+	require.NoError(t, err)
+	require.Len(t, comparison.Candidates, 1)
+	assert.Equal(t, 1, comparison.AwaitingCandidates)
+	// Nothing to compare with a single candidate: only the missing pieces are pointed out
+	assert.Equal(t, []string{"Pièces absentes : Avis d'imposition, Bulletin de salaire, Contrat de travail, Pièce d'identité"}, comparison.Candidates[0].Highlights)
+
+	_, err = svc.CompareCandidates(context.Background(), 2, 10)
+	assert.EqualError(t, err, "property not found or access denied")
+}
+
+func TestSetCandidateSelection_DeclineNotifiesOnce(t *testing.T) {
+	svc, mockQuerier, _, mockEmail := setupSolvencyDocuments()
+	check := postgres.SolvencyCheck{
+		ID:               7,
+		InitiatorOwnerID: pgtype.Int4{Int32: 1, Valid: true},
+		CandidateID:      pgtype.Int4{Int32: 2, Valid: true},
+		PropertyID:       pgtype.Int4{Int32: 10, Valid: true},
+		Status:           postgres.NullSolvencyStatus{SolvencyStatus: postgres.SolvencyStatusRejected, Valid: true},
+		ScoreResult:      pgtype.Int4{Int32: 42, Valid: true},
+	}
+	call := mockQuerier.On("GetSolvencyCheckForUpdate", mock.Anything, int32(7)).Return(check, nil)
+	mockQuerier.On("SetSolvencyCheckSelection", mock.Anything, postgres.SetSolvencyCheckSelectionParams{
+		ID: 7, Selection: pgtype.Text{String: SelectionDeclined, Valid: true},
+	}).Return(nil).Once()
+	mockQuerier.On("GetUserById", mock.Anything, int32(2)).Return(postgres.User{ID: 2, Email: "cand@test.com", FirstName: pgtype.Text{String: "Léa", Valid: true}}, nil)
+	mockQuerier.On("GetProperty", mock.Anything, int32(10)).Return(postgres.Property{ID: 10, Address: "1 rue A"}, nil)
+	mockEmail.On("SendNotification", mock.Anything, "cand@test.com", mock.Anything, mock.MatchedBy(func(body string) bool {
+		// Nothing about the score or the other candidates
+		return strings.HasPrefix(body, "Bonjour Léa,") && strings.Contains(body, "1 rue A") && !strings.Contains(body, "42")
+	})).Return(nil).Once()
+
+	err := svc.SetCandidateSelection(context.Background(), 1, 7, SelectionDeclined)
+	require.NoError(t, err)
+
+	// Declining again is a no-op; a declined candidate cannot be shortlisted back
+	check.Selection = pgtype.Text{String: SelectionDeclined, Valid: true}
+	call.ReturnArguments = mock.Arguments{check, nil}
+	require.NoError(t, svc.SetCandidateSelection(context.Background(), 1, 7, SelectionDeclined))
+	assert.ErrorIs(t, svc.SetCandidateSelection(context.Background(), 1, 7, SelectionShortlisted), ErrCandidateDeclined)
+
+	assert.ErrorIs(t, svc.SetCandidateSelection(context.Background(), 3, 7, SelectionShortlisted), ErrCheckNotOwned)
+	assert.ErrorIs(t, svc.SetCandidateSelection(context.Background(), 1, 7, "maybe"), ErrInvalidSelection)
+	mockQuerier.AssertExpectations(t)
+	mockEmail.AssertExpectations(t)
+}
+
+func TestSetCandidateSelection_PendingCheck(t *testing.T) {
+	svc, mockQuerier, _, _ := setupSolvencyDocuments()
+	mockQuerier.On("GetSolvencyCheckForUpdate", mock.Anything, int32(7)).Return(postgres.SolvencyCheck{
+		ID:               7,
+		InitiatorOwnerID: pgtype.Int4{Int32: 1, Valid: true},
+		Status:           postgres.NullSolvencyStatus{SolvencyStatus: postgres.SolvencyStatusPending, Valid: true},
+	}, nil)
+
+	err := svc.SetCandidateSelection(context.Background(), 1, 7, SelectionShortlisted)
+
+	assert.ErrorIs(t, err, ErrCheckNotCompleted)
+	mockQuerier.AssertNotCalled(t, "SetSolvencyCheckSelection", mock.Anything, mock.Anything)
+}
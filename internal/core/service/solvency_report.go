@@ -0,0 +1,391 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+	"seculoc-back/internal/platform/logger"
+)
+
+const solvencyReportTemplate = "rapport_solvabilite.md"
+
+// solvencyReportURL is the authenticated endpoint serving the latest report, stored in report_url.
+func solvencyReportURL(checkID int32) string {
+	return fmt.Sprintf("/api/v1/solvency/check/%d/report", checkID)
+}
+
+type SolvencyReportFactor struct {
+	Libelle string
+	Points  string
+	Detail  string
+}
+
+type SolvencyReportRule struct {
+	Libelle  string
+	Resultat string
+	Detail   string
+}
+
+type SolvencyReportMonth struct {
+	Mois      string
+	Recurrent string
+	Autres    string
+	Total     string
+}
+
+type SolvencyReportFlow struct {
+	Contrepartie string
+	Nature       string
+	Montant      string
+	Periodicite  string
+}
+
+type SolvencyReportDocument struct {
+	Libelle  string
+	Deposees int
+	Statut   string
+}
+
+// SolvencyReportData fills assets/templates/solvency/rapport_solvabilite.md.
+type SolvencyReportData struct {
+	Reference   int32
+	DateRapport string
+
+	CandidatNom       string
+	CandidatEmail     string
+	CandidatTelephone string
+
+	AdresseLogement string
+	Loyer           string
+
+	Decision        string
+	Score           int
+	ScoreMinimum    int
+	ScoreAvecGarant int // 0 sans garant analysé
+	TauxEffort      string
+	TauxEndettement string
+	Facteurs        []SolvencyReportFactor
+	Regles          []SolvencyReportRule
+
+	Periode              string
+	MoisCouverts         string
+	RevenuMensuel        string
+	RevenuRecurrent      string
+	AutresRevenus        string
+	ParMois              []SolvencyReportMonth
+	Sources              []SolvencyReportFlow
+	VirementsExclus      int
+	RemboursementsExclus int
+
+	Charges []SolvencyReportFlow
+	Pieces  []SolvencyReportDocument
+}
+
+var flowLabels = map[string]string{
+	FlowSalary:   "Salaire",
+	FlowBenefits: "Prestations sociales",
+	FlowPension:  "Pension / retraite",
+	FlowRent:     "Loyer",
+	FlowLoan:     "Crédit",
+}
+
+func flowLabel(category string) string {
+	if l, ok := flowLabels[category]; ok {
+		return l
+	}
+	return "Autre"
+}
+
+func periodicityLabel(days int) string {
+	switch {
+	case days <= 10:
+		return "Hebdomadaire"
+	case days <= 20:
+		return "Bimensuelle"
+	default:
+		return "Mensuelle"
+	}
+}
+
+// cell escapes the Markdown table separator in free text (labels, owner comments).
+func cell(s string) string { return strings.ReplaceAll(s, "|", "\\|") }
+
+func euros(v float64) string { return fmt.Sprintf("%.2f", v) }
+
+func percent(ratio float64) string { return fmt.Sprintf("%.0f %%", ratio*100) }
+
+func reportFlows(flows []RecurringFlow) []SolvencyReportFlow {
+	rows := make([]SolvencyReportFlow, 0, len(flows))
+	for _, f := range flows {
+		rows = append(rows, SolvencyReportFlow{
+			Contrepartie: cell(f.Counterparty),
+			Nature:       flowLabel(f.Category),
+			Montant:      euros(f.MonthlyAmount),
+			Periodicite:  periodicityLabel(f.IntervalDays),
+		})
+	}
+	return rows
+}
+
+// buildSolvencyReportData gathers everything the owner sees in the report.
+func buildSolvencyReportData(check postgres.SolvencyCheck, candidate postgres.User, prop postgres.Property, analysis *IncomeAnalysis, minScore int, now time.Time) SolvencyReportData {
+	if analysis == nil {
+		analysis = &IncomeAnalysis{}
+	}
+	rent := analysis.RentAmount
+	if prop.RentAmount.Valid {
+		f, _ := prop.RentAmount.Float64Value()
+		rent = f.Float64
+	}
+
+	data := SolvencyReportData{
+		Reference:   check.ID,
+		DateRapport: now.Format("02/01/2006"),
+
+		CandidatNom:       fmt.Sprintf("%s %s", candidate.LastName.String, candidate.FirstName.String),
+		CandidatEmail:     candidate.Email,
+		CandidatTelephone: candidate.PhoneNumber.String,
+
+		AdresseLogement: prop.Address,
+		Loyer:           euros(rent),
+
+		Score:           analysis.Score,
+		ScoreMinimum:    minScore,
+		ScoreAvecGarant: int(check.CombinedScore.Int32),
+		TauxEffort:      percent(analysis.EffortRate),
+		TauxEndettement: percent(analysis.DebtRatio),
+
+		Periode:              "aucune transaction",
+		MoisCouverts:         fmt.Sprintf("%.1f", analysis.MonthsCovered),
+		RevenuMensuel:        euros(analysis.MonthlyIncome),
+		RevenuRecurrent:      euros(analysis.MonthlyRecurringIncome),
+		AutresRevenus:        euros(analysis.MonthlyOtherIncome),
+		Sources:              reportFlows(analysis.IncomeSources),
+		Charges:              reportFlows(analysis.RecurringDebits),
+		VirementsExclus:      analysis.ExcludedTransfers,
+		RemboursementsExclus: analysis.ExcludedRefunds,
+	}
+	if data.CandidatTelephone == "" {
+		data.CandidatTelephone = "Non renseigné"
+	}
+	if analysis.PeriodStart != "" {
+		data.Periode = fmt.Sprintf("du %s au %s", analysis.PeriodStart, analysis.PeriodEnd)
+	}
+
+	switch check.Status.SolvencyStatus {
+	case postgres.SolvencyStatusApproved:
+		data.Decision = "Dossier accepté"
+	case postgres.SolvencyStatusRejected:
+		data.Decision = "Dossier refusé"
+	default:
+		data.Decision = "En cours d'analyse"
+	}
+
+	for _, f := range analysis.Factors {
+		points := "—"
+		if f.MaxPoints > 0 {
+			points = fmt.Sprintf("%d / %d", f.Points, f.MaxPoints)
+		}
+		data.Facteurs = append(data.Facteurs, SolvencyReportFactor{Libelle: f.Label, Points: points, Detail: cell(f.Detail)})
+	}
+
+	for _, r := range PolicyResultsFromJSON(check.PolicyResults) {
+		rule := SolvencyReportRule{Libelle: r.Label, Resultat: "Non respectée", Detail: cell(r.Detail)}
+		if r.Passed {
+			rule.Resultat = "Respectée"
+		}
+		data.Regles = append(data.Regles, rule)
+	}
+
+	for _, m := range analysis.IncomeByMonth {
+		data.ParMois = append(data.ParMois, SolvencyReportMonth{
+			Mois:      m.Month,
+			Recurrent: euros(m.Recurring),
+			Autres:    euros(m.Other),
+			Total:     euros(round2(m.Recurring + m.Other)),
+		})
+	}
+
+	// Document checklist: every type the candidate may provide, with what was uploaded or requested
+	uploaded := make(map[string]int)
+	for _, d := range CandidateDocumentsFromJSON(check.DocumentsJson) {
+		uploaded[d.Type]++
+	}
+	requested := make(map[string]string)
+	for _, m := range MissingDocumentsFromJSON(check.MissingDocuments) {
+		requested[m.Type] = m.Comment
+	}
+	types := make([]string, 0, len(CandidateDocumentTypes))
+	for t := range CandidateDocumentTypes {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	for _, t := range types {
+		doc := SolvencyReportDocument{Libelle: CandidateDocumentTypes[t].Label, Deposees: uploaded[t], Statut: "Non fournie"}
+		if comment, ok := requested[t]; ok && doc.Deposees == 0 {
+			doc.Statut = "Demandée"
+			if comment != "" {
+				doc.Statut += " : " + cell(comment)
+			}
+		} else if doc.Deposees > 0 {
+			doc.Statut = "Fournie"
+		}
+		data.Pieces = append(data.Pieces, doc)
+	}
+
+	return data
+}
+
+// renderSolvencyReportHTML renders the report template as a standalone HTML page.
+func renderSolvencyReportHTML(data SolvencyReportData) ([]byte, error) {
+	content, err := readDocumentTemplate("solvency", solvencyReportTemplate)
+	if err != nil {
+		return nil, err
+	}
+	body, err := renderMarkdownTemplate("solvency_report", content, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(fmt.Sprintf(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="UTF-8">
+<title>Rapport de solvabilité</title>
+<style>
+body { font-family: 'Helvetica', 'Arial', sans-serif; max-width: 800px; margin: 40px auto; padding: 20px; line-height: 1.5; color: #333; font-size: 13px; }
+h1, h2, h3 { color: #000; border-bottom: 2px solid #333; padding-bottom: 6px; margin-top: 24px; }
+h1 { font-size: 22px; text-align: center; border: none; text-transform: uppercase; letter-spacing: 2px; }
+table { width: 100%%; border-collapse: collapse; margin: 10px 0 16px; page-break-inside: avoid; }
+th, td { border: 1px solid #ccc; padding: 6px 8px; text-align: left; vertical-align: top; }
+th { background: #f2f2f2; }
+p { margin-bottom: 0.8em; text-align: justify; }
+</style>
+</head>
+<body>
+%s
+</body>
+</html>`, body)), nil
+}
+
+// generateReport renders the report of a processed check and stores it as a new document version.
+// Without a working PDF renderer (no headless browser available), the HTML version is stored instead.
+func (s *SolvencyService) generateReport(ctx context.Context, checkID int32) (postgres.Document, error) {
+	log := logger.FromContext(ctx)
+
+	var check postgres.SolvencyCheck
+	var candidate postgres.User
+	var prop postgres.Property
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		var err error
+		check, err = q.GetSolvencyCheckByID(ctx, checkID)
+		if err != nil {
+			return err
+		}
+		candidate, err = q.GetUserById(ctx, check.CandidateID.Int32)
+		if err != nil {
+			return fmt.Errorf("candidate not found: %w", err)
+		}
+		prop, err = q.GetProperty(ctx, check.PropertyID.Int32)
+		if err != nil {
+			return fmt.Errorf("property not found: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return postgres.Document{}, err
+	}
+
+	data := buildSolvencyReportData(check, candidate, prop, IncomeAnalysisFromJSON(check.AnalysisJson), s.MinScore, time.Now())
+	html, err := renderSolvencyReportHTML(data)
+	if err != nil {
+		return postgres.Document{}, err
+	}
+
+	content, ext, contentType, filename := html, ".html", "text/html; charset=utf-8", "rapport_solvabilite.html"
+	if pdf, err := s.renderPDF(html); err == nil {
+		content, ext, contentType, filename = pdf, ".pdf", "application/pdf", "rapport_solvabilite.pdf"
+	} else {
+		log.Warn("solvency report PDF rendering failed, storing HTML", zap.Int32("check_id", checkID), zap.Error(err))
+	}
+
+	var doc postgres.Document
+	err = s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		var err error
+		doc, err = storeDocumentVersion(ctx, q, s.storage, DocumentTypeSolvencyReport, checkID, ext, contentType, filename, content)
+		return err
+	})
+	if err != nil {
+		return postgres.Document{}, fmt.Errorf("failed to save solvency report: %w", err)
+	}
+
+	log.Info("solvency report generated", zap.Int32("check_id", checkID), zap.Int32("version", doc.Version))
+	return doc, nil
+}
+
+// OpenSolvencyReport streams the latest report of a check to its initiator (or the candidate),
+// generating it first for checks processed before reports existed. Each access is audited.
+// The caller must close the reader.
+func (s *SolvencyService) OpenSolvencyReport(ctx context.Context, userID, checkID int32, ipAddress, userAgent string) (io.ReadCloser, *DocumentDTO, error) {
+	var doc postgres.Document
+	var generate bool
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		probe := postgres.Document{DocumentType: DocumentTypeSolvencyReport, EntityID: checkID}
+		if err := authorizeDocument(ctx, q, probe, userID); err != nil {
+			return err
+		}
+
+		var err error
+		doc, err = q.GetLatestDocument(ctx, postgres.GetLatestDocumentParams{DocumentType: DocumentTypeSolvencyReport, EntityID: checkID})
+		if err == pgx.ErrNoRows {
+			check, err := q.GetSolvencyCheckByID(ctx, checkID)
+			if err != nil {
+				return err
+			}
+			if len(check.AnalysisJson) == 0 {
+				return ErrDocumentNotFound
+			}
+			generate = true
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if generate {
+		doc, err = s.generateReport(ctx, checkID)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	err = s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		return q.CreateDocumentAccessLog(ctx, postgres.CreateDocumentAccessLogParams{
+			DocumentID: doc.ID,
+			UserID:     userID,
+			IpAddress:  pgtype.Text{String: ipAddress, Valid: ipAddress != ""},
+			UserAgent:  pgtype.Text{String: userAgent, Valid: userAgent != ""},
+		})
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reader, err := s.storage.Open(doc.StorageKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open document: %w", err)
+	}
+	dto := toDocumentDTO(doc)
+	return reader, &dto, nil
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+)
+
+func processedCheck(t *testing.T) postgres.SolvencyCheck {
+	analysis := AnalyzeIncome([]TransactionData{
+		{Amount: 3000, Description: "SALAIRE ACME", Date: day("2024-01-28")},
+		{Amount: 3000, Description: "SALAIRE ACME", Date: day("2024-02-28")},
+		{Amount: 3000, Description: "SALAIRE ACME", Date: day("2024-03-28")},
+		{Amount: -650, Description: "PRLV LOYER FONCIA", Date: day("2024-01-05")},
+		{Amount: -650, Description: "PRLV LOYER FONCIA", Date: day("2024-02-05")},
+		{Amount: -650, Description: "PRLV LOYER FONCIA", Date: day("2024-03-05")},
+	}, 900)
+	analysisJSON, err := json.Marshal(analysis)
+	require.NoError(t, err)
+	docs, _ := json.Marshal([]CandidateDocument{{ID: "a", Type: "identity"}, {ID: "b", Type: "payslip"}})
+	missing, _ := json.Marshal([]MissingDocument{{Type: "tax_notice", Comment: "Avis 2023"}})
+	_, rules := EvaluatePolicy(SolvencyPolicy{IncomeMultiplier: 3}, PolicyInput{Score: analysis.Score, MinScore: 60, MonthlyIncome: analysis.MonthlyIncome, Rent: 900})
+	results, _ := json.Marshal(rules)
+
+	return postgres.SolvencyCheck{
+		ID:               7,
+		InitiatorOwnerID: pgtype.Int4{Int32: 1, Valid: true},
+		CandidateID:      pgtype.Int4{Int32: 2, Valid: true},
+		PropertyID:       pgtype.Int4{Int32: 10, Valid: true},
+		Status:           postgres.NullSolvencyStatus{SolvencyStatus: postgres.SolvencyStatusApproved, Valid: true},
+		AnalysisJson:     analysisJSON,
+		DocumentsJson:    docs,
+		MissingDocuments: missing,
+		PolicyResults:    results,
+	}
+}
+
+func TestBuildSolvencyReportData(t *testing.T) {
+	check := processedCheck(t)
+	candidate := postgres.User{FirstName: pgtype.Text{String: "Jean", Valid: true}, LastName: pgtype.Text{String: "Martin", Valid: true}, Email: "jean@example.com"}
+	prop := postgres.Property{Address: "1 rue de la Paix", RentAmount: numeric(900)}
+
+	data := buildSolvencyReportData(check, candidate, prop, IncomeAnalysisFromJSON(check.AnalysisJson), 60, time.Date(2024, 4, 2, 0, 0, 0, 0, time.UTC))
+
+	assert.Equal(t, "Martin Jean", data.CandidatNom)
+	assert.Equal(t, "Non renseigné", data.CandidatTelephone)
+	assert.Equal(t, "900.00", data.Loyer)
+	assert.Equal(t, "Dossier accepté", data.Decision)
+	assert.Equal(t, "30 %", data.TauxEffort)
+	require.Len(t, data.Regles, 2)
+	assert.Equal(t, "Respectée", data.Regles[1].Resultat)
+	assert.Len(t, data.ParMois, 3)
+	assert.Equal(t, "3000.00", data.ParMois[0].Total)
+	require.Len(t, data.Charges, 1)
+	assert.Equal(t, "Loyer", data.Charges[0].Nature)
+	assert.Equal(t, "Mensuelle", data.Charges[0].Periodicite)
+
+	statuses := map[string]string{}
+	for _, p := range data.Pieces {
+		statuses[p.Libelle] = p.Statut
+	}
+	assert.Equal(t, map[string]string{
+		"Pièce d'identité":    "Fournie",
+		"Bulletin de salaire": "Fournie",
+		"Avis d'imposition":   "Demandée : Avis 2023",
+		"Contrat de travail":  "Non fournie",
+	}, statuses)
+}
+
+func TestGenerateReport_StoresPDFVersion(t *testing.T) {
+	viper.Set("ASSETS_DIR", "../../../assets")
+	defer viper.Set("ASSETS_DIR", "")
+
+	mockQuerier := new(MockQuerier)
+	mockFileStore := new(MockFileStorage)
+	svc := NewSolvencyService(passthroughTxManager{q: mockQuerier}, nil, zap.NewNop(), mockFileStore, nil)
+	var html string
+	svc.renderPDF = func(b []byte) ([]byte, error) {
+		html = string(b)
+		return []byte("%PDF-1.7"), nil
+	}
+
+	mockQuerier.On("GetSolvencyCheckByID", mock.Anything, int32(7)).Return(processedCheck(t), nil)
+	mockQuerier.On("GetUserById", mock.Anything, int32(2)).Return(postgres.User{ID: 2, FirstName: pgtype.Text{String: "Jean", Valid: true}, LastName: pgtype.Text{String: "<b>Martin</b>", Valid: true}}, nil)
+	mockQuerier.On("GetProperty", mock.Anything, int32(10)).Return(postgres.Property{ID: 10, Address: "1 rue de la Paix", RentAmount: numeric(900)}, nil)
+	mockQuerier.On("GetNextDocumentVersion", mock.Anything, postgres.GetNextDocumentVersionParams{DocumentType: DocumentTypeSolvencyReport, EntityID: 7}).Return(int32(1), nil)
+	mockFileStore.On("Save", "documents/solvency_report/7/v1.pdf", []byte("%PDF-1.7")).Return("documents/solvency_report/7/v1.pdf", nil)
+	mockQuerier.On("CreateDocument", mock.Anything, mock.MatchedBy(func(p postgres.CreateDocumentParams) bool {
+		return p.ContentType == "application/pdf" && p.Version == 1
+	})).Return(postgres.Document{ID: 3, Version: 1}, nil)
+
+	doc, err := svc.generateReport(context.Background(), 7)
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), doc.ID)
+
+	assert.Contains(t, html, "1 rue de la Paix")
+	assert.Contains(t, html, "<table>")
+	assert.Contains(t, html, "2024-02")
+	assert.Contains(t, html, "FONCIA")
+	assert.Contains(t, html, "CNIL")
+	assert.NotContains(t, html, "<b>Martin</b>", "candidate input must not inject markup")
+	mockFileStore.AssertExpectations(t)
+}
+
+func TestGenerateReport_FallsBackToHTML(t *testing.T) {
+	viper.Set("ASSETS_DIR", "../../../assets")
+	defer viper.Set("ASSETS_DIR", "")
+
+	mockQuerier := new(MockQuerier)
+	mockFileStore := new(MockFileStorage)
+	svc := NewSolvencyService(passthroughTxManager{q: mockQuerier}, nil, zap.NewNop(), mockFileStore, nil)
+	svc.renderPDF = func([]byte) ([]byte, error) { return nil, errors.New("no browser") }
+
+	mockQuerier.On("GetSolvencyCheckByID", mock.Anything, int32(7)).Return(processedCheck(t), nil)
+	mockQuerier.On("GetUserById", mock.Anything, int32(2)).Return(postgres.User{ID: 2}, nil)
+	mockQuerier.On("GetProperty", mock.Anything, int32(10)).Return(postgres.Property{ID: 10}, nil)
+	mockQuerier.On("GetNextDocumentVersion", mock.Anything, mock.Anything).Return(int32(2), nil)
+	mockFileStore.On("Save", "documents/solvency_report/7/v2.html", mock.Anything).Return("", nil)
+	mockQuerier.On("CreateDocument", mock.Anything, mock.Anything).Return(postgres.Document{ID: 4, Version: 2}, nil)
+
+	_, err := svc.generateReport(context.Background(), 7)
+	assert.NoError(t, err)
+	mockFileStore.AssertExpectations(t)
+}
+
+func TestOpenSolvencyReport_DeniesOtherUsers(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	svc := NewSolvencyService(passthroughTxManager{q: mockQuerier}, nil, zap.NewNop(), new(MockFileStorage), nil)
+
+	mockQuerier.On("GetSolvencyCheckByID", mock.Anything, int32(7)).Return(processedCheck(t), nil)
+
+	_, _, err := svc.OpenSolvencyReport(context.Background(), 99, 7, "", "")
+	assert.ErrorIs(t, err, ErrDocumentAccessDenied)
+	mockQuerier.AssertNotCalled(t, "GetLatestDocument", mock.Anything, mock.Anything)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
@@ -23,11 +24,16 @@ func (m *mockEmailSender) SendInvitation(ctx context.Context, toEmail, link stri
 	return args.Error(0)
 }
 
+func (m *mockEmailSender) SendNotification(ctx context.Context, toEmail, subject, body string) error {
+	args := m.Called(ctx, toEmail, subject, body)
+	return args.Error(0)
+}
+
 func TestCreateSolvencyCheck_PropertyCredits(t *testing.T) {
 	mockTx := new(MockTxManager)
 	mockQuerier := new(MockQuerier)
 	mockEmail := new(mockEmailSender)
-	svc := NewSolvencyService(mockTx, mockEmail, zap.NewNop())
+	svc := NewSolvencyService(mockTx, mockEmail, zap.NewNop(), nil, nil)
 	ctx := context.Background()
 	userID := int32(1)
 	propID := int32(10)
@@ -46,7 +52,9 @@ func TestCreateSolvencyCheck_PropertyCredits(t *testing.T) {
 		mockQuerier.On("GetPropertyForUpdate", mock.Anything, propID).Return(ownedProp, nil)
 
 		// 2. Decrease Property Credits
-		mockQuerier.On("DecreasePropertyCredits", mock.Anything, propID).Return(nil)
+		mockQuerier.On("CreateCreditTransaction", mock.Anything, mock.MatchedBy(func(arg postgres.CreateCreditTransactionParams) bool {
+			return arg.PropertyID.Int32 == propID && arg.Amount == -1 && arg.TransactionType == "check_usage"
+		})).Return(postgres.CreditTransaction{ID: 41, PropertyID: pgtype.Int4{Int32: propID, Valid: true}}, nil)
 
 		// 3. Find/Create Candidate
 		mockQuerier.On("GetUserByEmail", mock.Anything, email).Return(postgres.User{}, pgx.ErrNoRows)
@@ -86,7 +94,7 @@ func TestCreateSolvencyCheck_GlobalCredits(t *testing.T) {
 	mockTx := new(MockTxManager)
 	mockQuerier := new(MockQuerier)
 	mockEmail := new(mockEmailSender)
-	svc := NewSolvencyService(mockTx, mockEmail, zap.NewNop())
+	svc := NewSolvencyService(mockTx, mockEmail, zap.NewNop(), nil, nil)
 	ctx := context.Background()
 	userID := int32(1)
 	propID := int32(10)
@@ -106,7 +114,7 @@ func TestCreateSolvencyCheck_GlobalCredits(t *testing.T) {
 		// 2. Get Global Balance For Update (> 0)
 		// Lock User First
 		mockQuerier.On("GetUserForUpdate", mock.Anything, userID).Return(postgres.User{ID: userID}, nil)
-		mockQuerier.On("GetUserCreditBalanceForUpdate", mock.Anything, pgtype.Int4{Int32: userID, Valid: true}).Return(int32(5), nil)
+		mockQuerier.On("GetUserCreditBalanceForUpdate", mock.Anything, userID).Return(int32(5), nil)
 
 		// 3. Create Debit Transaction (-1)
 		mockQuerier.On("CreateCreditTransaction", mock.Anything, mock.MatchedBy(func(arg postgres.CreateCreditTransactionParams) bool {
@@ -143,7 +151,7 @@ func TestCreateSolvencyCheck_NotOwner(t *testing.T) {
 	mockTx := new(MockTxManager)
 	mockQuerier := new(MockQuerier)
 	mockEmail := new(mockEmailSender)
-	svc := NewSolvencyService(mockTx, mockEmail, zap.NewNop())
+	svc := NewSolvencyService(mockTx, mockEmail, zap.NewNop(), nil, nil)
 	ctx := context.Background()
 	userID := int32(1)
 	propID := int32(99)
@@ -177,7 +185,7 @@ func TestCreateSolvencyCheck_InsufficientCredits(t *testing.T) {
 	mockTx := new(MockTxManager)
 	mockQuerier := new(MockQuerier)
 	mockEmail := new(mockEmailSender)
-	svc := NewSolvencyService(mockTx, mockEmail, zap.NewNop())
+	svc := NewSolvencyService(mockTx, mockEmail, zap.NewNop(), nil, nil)
 	ctx := context.Background()
 	userID := int32(1)
 
@@ -196,7 +204,7 @@ func TestCreateSolvencyCheck_InsufficientCredits(t *testing.T) {
 		// 2. Get Global Balance For Update (0)
 		// Lock User First
 		mockQuerier.On("GetUserForUpdate", mock.Anything, userID).Return(postgres.User{ID: userID}, nil)
-		mockQuerier.On("GetUserCreditBalanceForUpdate", mock.Anything, pgtype.Int4{Int32: userID, Valid: true}).Return(int32(0), nil)
+		mockQuerier.On("GetUserCreditBalanceForUpdate", mock.Anything, userID).Return(int32(0), nil)
 
 		_ = fn(mockQuerier)
 	})
@@ -215,33 +223,10 @@ func TestCreateSolvencyCheck_InsufficientCredits(t *testing.T) {
 	assert.Equal(t, int32(0), insErr.GlobalBalance)
 }
 
-func TestPurchaseCredits_Success(t *testing.T) {
-	mockTx := new(MockTxManager)
-	mockQuerier := new(MockQuerier)
-	mockEmail := new(mockEmailSender)
-	svc := NewSolvencyService(mockTx, mockEmail, zap.NewNop())
-	ctx := context.Background()
-	userID := int32(1)
-
-	mockTx.On("WithTx", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
-		fn := args.Get(1).(func(postgres.Querier) error)
-		_ = fn(mockQuerier)
-	})
-
-	mockQuerier.On("CreateCreditTransaction", mock.Anything, mock.MatchedBy(func(arg postgres.CreateCreditTransactionParams) bool {
-		return arg.UserID.Int32 == userID && arg.Amount == 20 && arg.TransactionType == "pack_purchase"
-	})).Return(postgres.CreditTransaction{}, nil)
-
-	amount, err := svc.PurchaseCredits(ctx, userID, "pack_20")
-
-	assert.NoError(t, err)
-	assert.Equal(t, int32(20), amount)
-}
-
 func TestCancelCheck_Success_Property(t *testing.T) {
 	mockTx := new(MockTxManager)
 	mockQuerier := new(MockQuerier)
-	svc := NewSolvencyService(mockTx, nil, zap.NewNop())
+	svc := NewSolvencyService(mockTx, nil, zap.NewNop(), nil, nil)
 	ctx := context.Background()
 	ownerID := int32(1)
 	checkID := int32(100)
@@ -251,7 +236,7 @@ func TestCancelCheck_Success_Property(t *testing.T) {
 		q := args.Get(1).(func(postgres.Querier) error)
 
 		// 1. Get Check
-		mockQuerier.On("GetSolvencyCheckByID", mock.Anything, checkID).Return(postgres.SolvencyCheck{
+		mockQuerier.On("GetSolvencyCheckForUpdate", mock.Anything, checkID).Return(postgres.SolvencyCheck{
 			ID:               checkID,
 			InitiatorOwnerID: pgtype.Int4{Int32: ownerID, Valid: true},
 			PropertyID:       pgtype.Int4{Int32: propID, Valid: true},
@@ -260,10 +245,13 @@ func TestCancelCheck_Success_Property(t *testing.T) {
 		}, nil)
 
 		// 2. Mark Cancelled
-		mockQuerier.On("CancelSolvencyCheck", mock.Anything, checkID).Return(nil)
+		mockQuerier.On("CancelSolvencyCheck", mock.Anything, checkID).Return(int64(1), nil)
 
-		// 3. Refund Property
-		mockQuerier.On("IncreasePropertyCredits", mock.Anything, propID).Return(nil)
+		// 3. Refund Property, locked before its wallet
+		mockQuerier.On("GetPropertyForUpdate", mock.Anything, propID).Return(postgres.Property{ID: propID}, nil)
+		mockQuerier.On("CreateCreditTransaction", mock.Anything, mock.MatchedBy(func(arg postgres.CreateCreditTransactionParams) bool {
+			return arg.PropertyID.Int32 == propID && arg.Amount == 1 && arg.TransactionType == "refund"
+		})).Return(postgres.CreditTransaction{}, nil)
 
 		_ = q(mockQuerier)
 	})
@@ -276,7 +264,7 @@ func TestCancelCheck_Success_Property(t *testing.T) {
 func TestCancelCheck_Success_Global(t *testing.T) {
 	mockTx := new(MockTxManager)
 	mockQuerier := new(MockQuerier)
-	svc := NewSolvencyService(mockTx, nil, zap.NewNop())
+	svc := NewSolvencyService(mockTx, nil, zap.NewNop(), nil, nil)
 	ctx := context.Background()
 	ownerID := int32(1)
 	checkID := int32(101)
@@ -285,7 +273,7 @@ func TestCancelCheck_Success_Global(t *testing.T) {
 		q := args.Get(1).(func(postgres.Querier) error)
 
 		// 1. Get Check
-		mockQuerier.On("GetSolvencyCheckByID", mock.Anything, checkID).Return(postgres.SolvencyCheck{
+		mockQuerier.On("GetSolvencyCheckForUpdate", mock.Anything, checkID).Return(postgres.SolvencyCheck{
 			ID:               checkID,
 			InitiatorOwnerID: pgtype.Int4{Int32: ownerID, Valid: true},
 			Status:           postgres.NullSolvencyStatus{SolvencyStatus: postgres.SolvencyStatusPending, Valid: true},
@@ -293,7 +281,7 @@ func TestCancelCheck_Success_Global(t *testing.T) {
 		}, nil)
 
 		// 2. Mark Cancelled
-		mockQuerier.On("CancelSolvencyCheck", mock.Anything, checkID).Return(nil)
+		mockQuerier.On("CancelSolvencyCheck", mock.Anything, checkID).Return(int64(1), nil)
 
 		// 3. Refund Global
 		mockQuerier.On("CreateCreditTransaction", mock.Anything, mock.MatchedBy(func(arg postgres.CreateCreditTransactionParams) bool {
@@ -308,10 +296,48 @@ func TestCancelCheck_Success_Global(t *testing.T) {
 	mockQuerier.AssertExpectations(t)
 }
 
+func TestCancelCheck_InsufficientDocs(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	svc := NewSolvencyService(passthroughTxManager{q: mockQuerier}, nil, zap.NewNop(), nil, nil)
+
+	// The candidate never completed their documents: the owner gets the credit back
+	mockQuerier.On("GetSolvencyCheckForUpdate", mock.Anything, int32(102)).Return(postgres.SolvencyCheck{
+		ID:                  102,
+		InitiatorOwnerID:    pgtype.Int4{Int32: 1, Valid: true},
+		Status:              postgres.NullSolvencyStatus{SolvencyStatus: postgres.SolvencyStatusInsufficientDocs, Valid: true},
+		CreditSource:        pgtype.Text{String: "global", Valid: true},
+		CreditTransactionID: pgtype.Int4{Int32: 55, Valid: true},
+	}, nil)
+	mockQuerier.On("CancelSolvencyCheck", mock.Anything, int32(102)).Return(int64(1), nil)
+	mockQuerier.On("CreateCreditTransaction", mock.Anything, mock.MatchedBy(func(arg postgres.CreateCreditTransactionParams) bool {
+		return arg.UserID.Int32 == 1 && arg.Amount == 1 && arg.TransactionType == "refund" && arg.RefundOf.Int32 == 55
+	})).Return(postgres.CreditTransaction{}, nil)
+
+	assert.NoError(t, svc.CancelCheck(context.Background(), 102, 1))
+	mockQuerier.AssertExpectations(t)
+}
+
+func TestCancelCheck_ExpiredMeanwhile(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	svc := NewSolvencyService(passthroughTxManager{q: mockQuerier}, nil, zap.NewNop(), nil, nil)
+
+	// Read as pending, but the expiry job expired and refunded it before the cancellation
+	mockQuerier.On("GetSolvencyCheckForUpdate", mock.Anything, int32(103)).Return(postgres.SolvencyCheck{
+		ID:               103,
+		InitiatorOwnerID: pgtype.Int4{Int32: 1, Valid: true},
+		Status:           postgres.NullSolvencyStatus{SolvencyStatus: postgres.SolvencyStatusPending, Valid: true},
+		CreditSource:     pgtype.Text{String: "global", Valid: true},
+	}, nil)
+	mockQuerier.On("CancelSolvencyCheck", mock.Anything, int32(103)).Return(int64(0), nil)
+
+	assert.ErrorIs(t, svc.CancelCheck(context.Background(), 103, 1), ErrCheckAlreadyProcessed)
+	mockQuerier.AssertNotCalled(t, "CreateCreditTransaction", mock.Anything, mock.Anything)
+}
+
 func TestCancelCheck_Unauthorized(t *testing.T) {
 	mockTx := new(MockTxManager)
 	mockQuerier := new(MockQuerier)
-	svc := NewSolvencyService(mockTx, nil, zap.NewNop())
+	svc := NewSolvencyService(mockTx, nil, zap.NewNop(), nil, nil)
 	ctx := context.Background()
 	checkID := int32(100)
 
@@ -319,7 +345,7 @@ func TestCancelCheck_Unauthorized(t *testing.T) {
 		q := args.Get(1).(func(postgres.Querier) error)
 
 		// Returns check owned by owner 2, but we try with owner 1
-		mockQuerier.On("GetSolvencyCheckByID", mock.Anything, checkID).Return(postgres.SolvencyCheck{
+		mockQuerier.On("GetSolvencyCheckForUpdate", mock.Anything, checkID).Return(postgres.SolvencyCheck{
 			ID:               checkID,
 			InitiatorOwnerID: pgtype.Int4{Int32: 2, Valid: true},
 		}, nil)
@@ -335,7 +361,7 @@ func TestCancelCheck_Unauthorized(t *testing.T) {
 func TestCancelCheck_AlreadyProcessed(t *testing.T) {
 	mockTx := new(MockTxManager)
 	mockQuerier := new(MockQuerier)
-	svc := NewSolvencyService(mockTx, nil, zap.NewNop())
+	svc := NewSolvencyService(mockTx, nil, zap.NewNop(), nil, nil)
 	ctx := context.Background()
 	ownerID := int32(1)
 	checkID := int32(100)
@@ -343,7 +369,7 @@ func TestCancelCheck_AlreadyProcessed(t *testing.T) {
 	mockTx.On("WithTx", mock.Anything, mock.Anything).Return(errors.New("cannot cancel check with status: approved")).Run(func(args mock.Arguments) {
 		q := args.Get(1).(func(postgres.Querier) error)
 
-		mockQuerier.On("GetSolvencyCheckByID", mock.Anything, checkID).Return(postgres.SolvencyCheck{
+		mockQuerier.On("GetSolvencyCheckForUpdate", mock.Anything, checkID).Return(postgres.SolvencyCheck{
 			ID:               checkID,
 			InitiatorOwnerID: pgtype.Int4{Int32: ownerID, Valid: true},
 			Status:           postgres.NullSolvencyStatus{SolvencyStatus: postgres.SolvencyStatusApproved, Valid: true},
@@ -356,3 +382,44 @@ func TestCancelCheck_AlreadyProcessed(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "cannot cancel check with status")
 }
+
+func TestProcessOpenBankingResult_StoresAnalysis(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	svc := NewSolvencyService(passthroughTxManager{q: mockQuerier}, nil, zap.NewNop(), nil, nil)
+	svc.AllowRawTransactions = true
+	ctx := context.Background()
+
+	mockQuerier.On("GetSolvencyCheckByToken", mock.Anything, pgtype.Text{String: "tok", Valid: true}).Return(postgres.GetSolvencyCheckByTokenRow{
+		ID:         7,
+		PropertyID: pgtype.Int4{Int32: 10, Valid: true},
+		Status:     postgres.NullSolvencyStatus{SolvencyStatus: postgres.SolvencyStatusPending, Valid: true},
+	}, nil)
+	expectAnalysisLookups(mockQuerier, postgres.SolvencyCheck{ID: 7, PropertyID: pgtype.Int4{Int32: 10, Valid: true}})
+
+	var stored postgres.UpdateSolvencyCheckResultParams
+	mockQuerier.On("UpdateSolvencyCheckResult", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(postgres.UpdateSolvencyCheckResultParams)
+	}).Return(int64(1), nil)
+
+	err := svc.ProcessOpenBankingResult(ctx, "tok", []TransactionData{
+		{Amount: 3000, Description: "SALAIRE ACME", Date: time.Date(2024, 1, 28, 0, 0, 0, 0, time.UTC)},
+		{Amount: 3000, Description: "SALAIRE ACME", Date: time.Date(2024, 2, 28, 0, 0, 0, 0, time.UTC)},
+		{Amount: 3000, Description: "SALAIRE ACME", Date: time.Date(2024, 3, 28, 0, 0, 0, 0, time.UTC)},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, postgres.SolvencyStatusApproved, stored.Status.SolvencyStatus)
+	analysis := IncomeAnalysisFromJSON(stored.AnalysisJson)
+	if assert.NotNil(t, analysis) {
+		assert.Equal(t, int32(analysis.Score), stored.ScoreResult.Int32)
+		assert.Equal(t, 3000.0, analysis.MonthlyRecurringIncome)
+		assert.Equal(t, FlowSalary, analysis.IncomeSources[0].Category)
+	}
+}
+
+func TestProcessOpenBankingResult_RawDisabled(t *testing.T) {
+	svc := NewSolvencyService(passthroughTxManager{q: new(MockQuerier)}, nil, zap.NewNop(), nil, nil)
+
+	err := svc.ProcessOpenBankingResult(context.Background(), "tok", []TransactionData{{Amount: 99999}})
+	assert.ErrorIs(t, err, ErrRawTransactionsDisabled)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"go.uber.org/zap"
 
@@ -20,40 +21,24 @@ type TxManager interface {
 // SubscriptionService handles subscription logic.
 type SubscriptionService struct {
 	txManager TxManager
-	// Optional: we could keep a global logger if needed, but we prefer context logger
+	// payments charges the first period of paid plans
+	payments *PaymentService
 }
 
 // NewSubscriptionService creates a new SubscriptionService.
-func NewSubscriptionService(txManager TxManager, l *zap.Logger) *SubscriptionService {
+func NewSubscriptionService(txManager TxManager, payments *PaymentService, l *zap.Logger) *SubscriptionService {
 	return &SubscriptionService{
 		txManager: txManager,
+		payments:  payments,
 	}
 }
 
-// SubscribeUser subscribes a user to a plan.
-func (s *SubscriptionService) SubscribeUser(ctx context.Context, userID int32, plan string, freq string) error {
+// SubscribeUser subscribes a user to a plan. Free plans start right away. Paid plans are 'incomplete'
+// until their first period is paid with paymentMethodID (or the user's saved payment method): the
+// returned payment tells whether it succeeded, failed or awaits 3-D Secure, completed by webhook.
+func (s *SubscriptionService) SubscribeUser(ctx context.Context, userID int32, plan string, freq string, paymentMethodID string) (*PaymentResult, error) {
 	log := logger.FromContext(ctx)
 
-	// Determine plan details (mock logic for demo)
-	var planType postgres.SubPlan
-	var maxProps int32
-	var amount int32
-
-	switch plan {
-	case "premium":
-		planType = postgres.SubPlanPremium
-		maxProps = 5
-		amount = 2990 // 29.90
-	case "serenity":
-		planType = postgres.SubPlanSerenity
-		maxProps = 1
-		amount = 990
-	default:
-		planType = postgres.SubPlanDiscovery
-		maxProps = 1
-		amount = 0
-	}
-
 	var frequency postgres.BillingFreq
 	if freq == "yearly" {
 		frequency = postgres.BillingFreqYearly
@@ -61,28 +46,80 @@ func (s *SubscriptionService) SubscribeUser(ctx context.Context, userID int32, p
 		frequency = postgres.BillingFreqMonthly
 	}
 
+	var payment *PaymentResult
+	var amount int32
+	var invoice *postgres.Invoice
 	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
-		// 1. Create Subscription
-
-		_, err := q.CreateSubscription(ctx, postgres.CreateSubscriptionParams{
-			UserID:    pgtype.Int4{Int32: userID, Valid: true},
-			PlanType:  planType,
-			Frequency: postgres.NullBillingFreq{BillingFreq: frequency, Valid: true},
-			StartDate: pgtype.Date{Time: time.Now(), Valid: true},
-			// EndDate can be null
-			MaxPropertiesLimit: pgtype.Int4{Int32: maxProps, Valid: true},
+		// 1. One subscription at a time: plans are changed with ChangePlan
+		_, err := q.GetUserSubscription(ctx, pgtype.Int4{Int32: userID, Valid: true})
+		if err == nil {
+			return ErrSubscriptionExists
+		}
+		if err != pgx.ErrNoRows {
+			return err
+		}
+
+		// 2. Plan details from the catalog
+		item, err := activeCatalogItem(ctx, q, CatalogKindPlan, plan)
+		if err != nil {
+			return err
+		}
+		amount = planPriceCents(item, frequency)
+		status := "active"
+		if amount > 0 {
+			if !s.payments.available() {
+				return ErrPaymentUnavailable
+			}
+			status = "incomplete"
+		}
+
+		// 3. Create Subscription, its first period starting today
+		start := dateOf(time.Now())
+		end := nextCycleDate(start, start, billingMonths(frequency))
+		sub, err := q.CreateSubscription(ctx, postgres.CreateSubscriptionParams{
+			UserID:             pgtype.Int4{Int32: userID, Valid: true},
+			PlanType:           item.PlanType.SubPlan,
+			Frequency:          postgres.NullBillingFreq{BillingFreq: frequency, Valid: true},
+			StartDate:          pgtype.Date{Time: start, Valid: true},
+			EndDate:            prepaidEndDate(frequency, end),
+			MaxPropertiesLimit: pgtype.Int4{Int32: item.MaxProperties, Valid: true},
+			CatalogItemID:      pgtype.Int4{Int32: item.ID, Valid: true},
+			CurrentPeriodStart: pgtype.Date{Time: start, Valid: true},
+			CurrentPeriodEnd:   pgtype.Date{Time: end, Valid: true},
+			Status:             pgtype.Text{String: status, Valid: true},
 		})
 		if err != nil {
 			return fmt.Errorf("failed to create subscription: %w", err)
 		}
 
-		log.Info("subscription created",
-			zap.Int("user_id", int(userID)),
-			zap.String("plan", plan),
-			zap.Int("amount", int(amount)),
-		)
-		return nil
+		// 4. Free plans: first month of included credits
+		if amount == 0 {
+			if err := recordSubscribed(ctx, q, sub); err != nil {
+				return err
+			}
+			return grantPlanCredits(ctx, q, sub, item, addMonths(start, 1), false)
+		}
+
+		// 5. Paid plans: first period invoiced, then charged once committed; the billing engine renews it
+		created, err := q.CreateInvoice(ctx, postgres.CreateInvoiceParams{
+			UserID:         userID,
+			SubscriptionID: pgtype.Int4{Int32: sub.ID, Valid: true},
+			Description:    fmt.Sprintf("%s (%s)", item.Name, frequency),
+			AmountCents:    amount,
+			PeriodStart:    pgtype.Date{Time: start, Valid: true},
+			PeriodEnd:      pgtype.Date{Time: end, Valid: true},
+			Status:         "open",
+		})
+		if err != nil {
+			return fmt.Errorf("failed to invoice subscription: %w", err)
+		}
+		invoice, err = claimInvoice(ctx, q, created.ID)
+		return err
 	})
+	if err == nil && invoice != nil {
+		// 6. Activated once paid, cancelled if the payment failed
+		payment, err = s.payments.payInvoice(ctx, *invoice, paymentMethodID)
+	}
 
 	if err != nil {
 		log.Error("subscription transaction failed",
@@ -90,48 +127,30 @@ func (s *SubscriptionService) SubscribeUser(ctx context.Context, userID int32, p
 			zap.String("plan", plan),
 			zap.Error(err),
 		)
-		return err
+		return nil, err
 	}
 
-	return nil
+	fields := []zap.Field{
+		zap.Int("user_id", int(userID)),
+		zap.String("plan", plan),
+		zap.Int("amount_cents", int(amount)),
+	}
+	if payment != nil {
+		fields = append(fields, zap.String("payment_status", payment.Status))
+	}
+	log.Info("subscription created", fields...)
+	return payment, nil
 }
 
-// IncreaseLimit allows Serenity and Premium users to add property slots for 9.90/unit.
-func (s *SubscriptionService) IncreaseLimit(ctx context.Context, userID int32, additionalProperties int32) error {
-	log := logger.FromContext(ctx)
-
-	return s.txManager.WithTx(ctx, func(q postgres.Querier) error {
-		// 1. Get Subscription
-		sub, err := q.GetUserSubscription(ctx, pgtype.Int4{Int32: userID, Valid: true})
-		if err != nil {
-			return err
-		}
-
-		// 2. Eligibility Check
-		if sub.PlanType != postgres.SubPlanSerenity && sub.PlanType != postgres.SubPlanPremium {
-			return fmt.Errorf("plan not eligible for limit increase (current: %s)", sub.PlanType)
+// subscriptionCatalogItem returns the catalog version a subscription was taken with. Subscriptions older
+// than the catalog fall back to the plan currently offered under their plan type.
+func subscriptionCatalogItem(ctx context.Context, q postgres.Querier, sub postgres.Subscription) (postgres.CatalogItem, error) {
+	if sub.CatalogItemID.Valid {
+		item, err := q.GetCatalogItem(ctx, sub.CatalogItemID.Int32)
+		if err == pgx.ErrNoRows {
+			return item, ErrCatalogItemNotFound
 		}
-
-		// 3. Cost Calculation
-		// 9.90 EUR per property.
-		cost := 9.90 * float64(additionalProperties)
-
-		// 4. Update Limit
-		err = q.UpdateSubscriptionLimit(ctx, postgres.UpdateSubscriptionLimitParams{
-			UserID:             pgtype.Int4{Int32: userID, Valid: true},
-			MaxPropertiesLimit: pgtype.Int4{Int32: additionalProperties, Valid: true},
-		})
-		if err != nil {
-			return err
-		}
-
-		// 5. Log Billing Event (Future: Insert into Transactions/Invoice)
-		log.Info("subscription limit increased",
-			zap.Int("user_id", int(userID)),
-			zap.Int("added_slots", int(additionalProperties)),
-			zap.Float64("additional_monthly_cost", cost),
-		)
-
-		return nil
-	})
+		return item, err
+	}
+	return activeCatalogItem(ctx, q, CatalogKindPlan, string(sub.PlanType))
 }
@@ -0,0 +1,714 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+	"seculoc-back/internal/platform/logger"
+)
+
+// Subscription event types, the timeline of subscription_events.
+const (
+	SubscriptionEventSubscribed            = "subscribed"
+	SubscriptionEventUpgraded              = "upgraded"
+	SubscriptionEventDowngradeScheduled    = "downgrade_scheduled"
+	SubscriptionEventDowngradeCancelled    = "downgrade_cancelled"
+	SubscriptionEventDowngraded            = "downgraded"
+	SubscriptionEventCancellationScheduled = "cancellation_scheduled"
+	SubscriptionEventCancellationReverted  = "cancellation_reverted"
+	SubscriptionEventCancelled             = "cancelled"
+)
+
+// Plan change outcomes reported in PlanChange.Status.
+const (
+	PlanChangeUpgraded       = "upgraded"
+	PlanChangeScheduled      = "scheduled"
+	PlanChangePaymentPending = "payment_pending"
+	PlanChangePaymentFailed  = "payment_failed"
+)
+
+var (
+	ErrNoSubscription             = errors.New("no current subscription")
+	ErrSubscriptionExists         = errors.New("a subscription is already in progress: change its plan instead")
+	ErrSubscriptionNotActive      = errors.New("subscription is not active")
+	ErrSamePlan                   = errors.New("already subscribed to this plan")
+	ErrCancellationScheduled      = errors.New("subscription ends at period end: resume it first")
+	ErrCancellationNotScheduled   = errors.New("subscription is not cancelled")
+	ErrNoScheduledChange          = errors.New("no plan change scheduled")
+	ErrInvalidPropertiesToArchive = errors.New("properties to archive must be active long-term properties of the owner")
+)
+
+// ErrPropertiesToArchive is returned when a downgrade leaves more long-term properties than the new
+// plan allows: the user chooses ToArchive of them, archived when the downgrade takes effect.
+type ErrPropertiesToArchive struct {
+	Limit      int32
+	ToArchive  int
+	Properties []ArchivableProperty
+}
+
+func (e *ErrPropertiesToArchive) Error() string {
+	return fmt.Sprintf("the new plan allows %d long-term properties: choose %d to archive", e.Limit, e.ToArchive)
+}
+
+// ArchivableProperty is an active long-term property that a downgrade may archive.
+type ArchivableProperty struct {
+	ID      int32  `json:"id"`
+	Name    string `json:"name,omitempty"`
+	Address string `json:"address"`
+}
+
+// PlanChange tells how a plan change was handled. An upgrade applies right away once its prorata is
+// paid; a downgrade is scheduled at the period end.
+type PlanChange struct {
+	Status        string `json:"status"`
+	Plan          string `json:"plan"`
+	EffectiveDate string `json:"effective_date"`
+	// CreditCents is the unused part of the current plan, deducted from ChargeCents, the price of the
+	// new plan for the rest of the period
+	CreditCents    int32 `json:"credit_cents,omitempty"`
+	ChargeCents    int32 `json:"charge_cents,omitempty"`
+	AmountDueCents int32 `json:"amount_due_cents,omitempty"`
+	// ArchivedPropertyIDs are the long-term properties archived when a downgrade takes effect
+	ArchivedPropertyIDs []int32        `json:"archived_property_ids,omitempty"`
+	Payment             *PaymentResult `json:"payment,omitempty"`
+}
+
+// SubscriptionState is the current subscription with its pending changes.
+type SubscriptionState struct {
+	Plan               string `json:"plan"`
+	Frequency          string `json:"frequency,omitempty"`
+	Status             string `json:"status"`
+	CurrentPeriodStart string `json:"current_period_start,omitempty"`
+	CurrentPeriodEnd   string `json:"current_period_end,omitempty"`
+	MaxPropertiesLimit int32  `json:"max_properties_limit"`
+	// CancelAtPeriodEnd: the access ends at CurrentPeriodEnd
+	CancelAtPeriodEnd bool `json:"cancel_at_period_end"`
+	// ScheduledPlan is applied at CurrentPeriodEnd, archiving ScheduledArchivedPropertyIDs
+	ScheduledPlan                string  `json:"scheduled_plan,omitempty"`
+	ScheduledArchivedPropertyIDs []int32 `json:"scheduled_archived_property_ids,omitempty"`
+	// PropertySlots are the long-term properties bought on top of the plan, counted in MaxPropertiesLimit
+	PropertySlots *PropertySlots `json:"property_slots,omitempty"`
+}
+
+// SubscriptionEventDTO is an entry of the subscription history.
+type SubscriptionEventDTO struct {
+	Type                string  `json:"type"`
+	FromPlan            string  `json:"from_plan,omitempty"`
+	ToPlan              string  `json:"to_plan,omitempty"`
+	EffectiveDate       string  `json:"effective_date"`
+	AmountCents         int32   `json:"amount_cents,omitempty"`
+	InvoiceID           int32   `json:"invoice_id,omitempty"`
+	ArchivedPropertyIDs []int32 `json:"archived_property_ids,omitempty"`
+	// SlotQuantity is the number of extra properties added or removed
+	SlotQuantity int32  `json:"slot_quantity,omitempty"`
+	RecordedAt   string `json:"recorded_at"`
+}
+
+// proratedCents is the part of the price of a period for the days left from today to its end.
+func proratedCents(price int32, start, end, today time.Time) int32 {
+	total := end.Sub(start).Hours() / 24
+	left := end.Sub(today).Hours() / 24
+	if total <= 0 || left <= 0 {
+		return 0
+	}
+	return int32(math.Round(float64(price) * min(left, total) / total))
+}
+
+// subscriptionEvent prepares a history entry of a subscription, from its current plan.
+func subscriptionEvent(sub postgres.Subscription, eventType string, effective time.Time) postgres.CreateSubscriptionEventParams {
+	return postgres.CreateSubscriptionEventParams{
+		SubscriptionID:    sub.ID,
+		UserID:            sub.UserID.Int32,
+		EventType:         eventType,
+		FromCatalogItemID: sub.CatalogItemID,
+		EffectiveDate:     pgtype.Date{Time: effective, Valid: true},
+	}
+}
+
+// currentSubscription locks the current subscription of a user.
+func currentSubscription(ctx context.Context, q postgres.Querier, userID int32) (postgres.Subscription, error) {
+	sub, err := q.GetUserSubscriptionForUpdate(ctx, pgtype.Int4{Int32: userID, Valid: true})
+	if err == pgx.ErrNoRows {
+		return sub, ErrNoSubscription
+	}
+	return sub, err
+}
+
+// ChangePlan moves the current subscription to another plan of the catalog, at the same billing
+// frequency. A more expensive plan applies right away: the prorata of the new plan for the days left,
+// less the unused part of the current one, is invoiced and charged with paymentMethodID (or the saved
+// payment method). A cheaper plan is scheduled at the period end; if it allows fewer long-term
+// properties than the owner has, archivePropertyIDs must name the ones to archive then.
+func (s *SubscriptionService) ChangePlan(ctx context.Context, userID int32, plan string, archivePropertyIDs []int32, paymentMethodID string) (*PlanChange, error) {
+	log := logger.FromContext(ctx)
+	today := dateOf(time.Now())
+
+	change := &PlanChange{Plan: plan}
+	var invoice *postgres.Invoice
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		sub, err := currentSubscription(ctx, q, userID)
+		if err != nil {
+			return err
+		}
+		if sub.Status.String != "active" {
+			return ErrSubscriptionNotActive
+		}
+		if sub.CancelAtPeriodEnd {
+			return ErrCancellationScheduled
+		}
+
+		from, err := subscriptionCatalogItem(ctx, q, sub)
+		if err != nil {
+			return err
+		}
+		to, err := activeCatalogItem(ctx, q, CatalogKindPlan, plan)
+		if err != nil {
+			return err
+		}
+		if to.Code == from.Code {
+			return ErrSamePlan
+		}
+
+		freq := sub.Frequency.BillingFreq
+		if planPriceCents(to, freq) > planPriceCents(from, freq) {
+			invoice, err = s.upgrade(ctx, q, sub, from, to, today, change)
+			return err
+		}
+		return scheduleDowngrade(ctx, q, sub, from, to, archivePropertyIDs, change)
+	})
+	if err == nil && invoice != nil {
+		// Charged once the invoice is committed; the new plan applies once it is paid (see settleProrata)
+		change.Payment, err = s.payments.payInvoice(ctx, *invoice, paymentMethodID)
+		switch {
+		case err != nil:
+		case change.Payment.Status == PaymentFailed:
+			change.Status = PlanChangePaymentFailed
+		case change.Payment.Status != PaymentSucceeded:
+			change.Status = PlanChangePaymentPending
+		}
+	}
+	if err != nil {
+		log.Warn("plan change failed", zap.Int32("user_id", userID), zap.String("plan", plan), zap.Error(err))
+		return nil, err
+	}
+
+	log.Info("plan changed",
+		zap.Int32("user_id", userID),
+		zap.String("plan", plan),
+		zap.String("status", change.Status),
+		zap.Int32("amount_due_cents", change.AmountDueCents),
+	)
+	return change, nil
+}
+
+// upgrade invoices the prorata of an upgrade and returns the invoice, claimed, for the caller to charge
+// once committed (nil when nothing is due: the new plan applies right away). The new plan applies once the
+// invoice is paid (see settleProrata), right away or when the provider's webhook confirms the payment.
+func (s *SubscriptionService) upgrade(ctx context.Context, q postgres.Querier, sub postgres.Subscription, from, to postgres.CatalogItem, today time.Time, change *PlanChange) (*postgres.Invoice, error) {
+	freq := sub.Frequency.BillingFreq
+	start, end := sub.CurrentPeriodStart.Time, sub.CurrentPeriodEnd.Time
+	change.EffectiveDate = today.Format("2006-01-02")
+	change.CreditCents = proratedCents(planPriceCents(from, freq), start, end, today)
+	change.ChargeCents = proratedCents(planPriceCents(to, freq), start, end, today)
+	change.AmountDueCents = change.ChargeCents - change.CreditCents
+	change.Status = PlanChangeUpgraded
+	if change.AmountDueCents <= 0 {
+		return nil, applyUpgrade(ctx, q, sub, from, to, today, nil)
+	}
+	if !s.payments.available() {
+		return nil, ErrPaymentUnavailable
+	}
+
+	// No period: the renewal invoices keep theirs; catalog_item_id is the plan paid for
+	invoice, err := q.CreateInvoice(ctx, postgres.CreateInvoiceParams{
+		UserID:         sub.UserID.Int32,
+		SubscriptionID: pgtype.Int4{Int32: sub.ID, Valid: true},
+		CatalogItemID:  pgtype.Int4{Int32: to.ID, Valid: true},
+		Description:    fmt.Sprintf("Upgrade %s to %s (%s), prorated until %s", from.Name, to.Name, freq, end.Format("2006-01-02")),
+		AmountCents:    change.AmountDueCents,
+		Status:         "open",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to invoice upgrade: %w", err)
+	}
+	return claimInvoice(ctx, q, invoice.ID)
+}
+
+// settleProrata records the outcome of the payment of a change made during a period, an upgrade or extra
+// properties: once paid, the invoice is issued and the change applies. A failed one is voided rather than
+// retried: the subscription stays as it was.
+func settleProrata(ctx context.Context, q postgres.Querier, sub postgres.Subscription, invoice postgres.Invoice, payErr error) error {
+	if payErr != nil {
+		_, err := q.MarkInvoiceFailed(ctx, invoice.ID)
+		if err == pgx.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return q.VoidInvoice(ctx, invoice.ID)
+	}
+
+	paid, err := q.MarkInvoicePaid(ctx, invoice.ID)
+	if err == pgx.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := issueInvoice(ctx, q, paid, vatRateBps); err != nil {
+		return err
+	}
+	if sub.Status.String == "cancelled" {
+		// Ended while the payment was confirmed: nothing left to change
+		logger.FromContext(ctx).Warn("prorata paid for an ended subscription", zap.Int32("subscription_id", sub.ID), zap.Int32("invoice_id", invoice.ID))
+		return nil
+	}
+
+	from, err := subscriptionCatalogItem(ctx, q, sub)
+	if err != nil {
+		return err
+	}
+	if invoice.SlotQuantity.Valid {
+		return applySlots(ctx, q, sub, from, invoice.SlotQuantity.Int32, dateOf(time.Now()), &paid)
+	}
+	to, err := q.GetCatalogItem(ctx, invoice.CatalogItemID.Int32)
+	if err != nil {
+		return err
+	}
+	return applyUpgrade(ctx, q, sub, from, to, dateOf(time.Now()), &paid)
+}
+
+// applyUpgrade switches a subscription to a more expensive plan and tops up the credits granted this
+// month to those of the new plan. A downgrade scheduled before is dropped. The extra properties paid are
+// kept until the period end, and beyond if the new plan sells them too.
+func applyUpgrade(ctx context.Context, q postgres.Querier, sub postgres.Subscription, from, to postgres.CatalogItem, today time.Time, invoice *postgres.Invoice) error {
+	slots, err := propertySlots(ctx, q, sub)
+	if err != nil {
+		return err
+	}
+	if !to.SlotPriceCents.Valid && renewalQuantity(slots) > 0 {
+		if err := q.ScheduleSubscriptionItemQuantity(ctx, postgres.ScheduleSubscriptionItemQuantityParams{
+			ID:                slots.ID,
+			ScheduledQuantity: pgtype.Int4{Int32: 0, Valid: true},
+		}); err != nil {
+			return err
+		}
+	}
+	if sub.ScheduledCatalogItemID.Valid {
+		ev := subscriptionEvent(sub, SubscriptionEventDowngradeCancelled, today)
+		ev.ToCatalogItemID = sub.ScheduledCatalogItemID
+		if _, err := q.CreateSubscriptionEvent(ctx, ev); err != nil {
+			return err
+		}
+	}
+	if err := q.ChangeSubscriptionPlan(ctx, postgres.ChangeSubscriptionPlanParams{
+		ID:                 sub.ID,
+		CatalogItemID:      pgtype.Int4{Int32: to.ID, Valid: true},
+		PlanType:           to.PlanType.SubPlan,
+		MaxPropertiesLimit: pgtype.Int4{Int32: propertyLimit(to, slots.Quantity), Valid: true},
+	}); err != nil {
+		return fmt.Errorf("failed to change plan: %w", err)
+	}
+
+	if extra := to.IncludedCredits - sub.GrantedCredits; extra > 0 && sub.NextCreditGrant.Valid {
+		_, err := q.CreateCreditTransaction(ctx, postgres.CreateCreditTransactionParams{
+			UserID:          sub.UserID,
+			Amount:          extra,
+			TransactionType: "plan_upgrade",
+			Description:     pgtype.Text{String: fmt.Sprintf("%s credits (upgrade)", to.Name), Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to top up plan credits: %w", err)
+		}
+		if err := q.RecordSubscriptionCreditGrant(ctx, postgres.RecordSubscriptionCreditGrantParams{
+			ID:              sub.ID,
+			NextCreditGrant: sub.NextCreditGrant,
+			GrantedCredits:  to.IncludedCredits,
+		}); err != nil {
+			return err
+		}
+	}
+
+	ev := subscriptionEvent(sub, SubscriptionEventUpgraded, today)
+	ev.ToCatalogItemID = pgtype.Int4{Int32: to.ID, Valid: true}
+	if invoice != nil {
+		ev.AmountCents = pgtype.Int4{Int32: invoice.AmountCents, Valid: true}
+		ev.InvoiceID = pgtype.Int4{Int32: invoice.ID, Valid: true}
+	}
+	_, err = q.CreateSubscriptionEvent(ctx, ev)
+	return err
+}
+
+// scheduleDowngrade schedules a cheaper plan at the period end, once the properties to archive are
+// known. It replaces a downgrade scheduled before.
+func scheduleDowngrade(ctx context.Context, q postgres.Querier, sub postgres.Subscription, from, to postgres.CatalogItem, archivePropertyIDs []int32, change *PlanChange) error {
+	slots, err := propertySlots(ctx, q, sub)
+	if err != nil {
+		return err
+	}
+	limit := propertyLimit(to, keptSlots(slots, to))
+	properties, err := q.ListActivePropertiesByOwnerAndType(ctx, postgres.ListActivePropertiesByOwnerAndTypeParams{
+		OwnerID:    sub.UserID,
+		RentalType: postgres.PropertyTypeLongTerm,
+	})
+	if err != nil {
+		return err
+	}
+	archived, err := propertiesToArchive(properties, limit, archivePropertyIDs)
+	if err != nil {
+		return err
+	}
+
+	if err := q.ScheduleSubscriptionChange(ctx, postgres.ScheduleSubscriptionChangeParams{
+		ID:                  sub.ID,
+		CatalogItemID:       pgtype.Int4{Int32: to.ID, Valid: true},
+		ArchivedPropertyIds: archived,
+	}); err != nil {
+		return fmt.Errorf("failed to schedule plan change: %w", err)
+	}
+	end := sub.CurrentPeriodEnd.Time
+	ev := subscriptionEvent(sub, SubscriptionEventDowngradeScheduled, end)
+	ev.ToCatalogItemID = pgtype.Int4{Int32: to.ID, Valid: true}
+	ev.ArchivedPropertyIds = archived
+	if _, err := q.CreateSubscriptionEvent(ctx, ev); err != nil {
+		return err
+	}
+
+	change.Status = PlanChangeScheduled
+	change.EffectiveDate = end.Format("2006-01-02")
+	change.ArchivedPropertyIDs = archived
+	return nil
+}
+
+// propertiesToArchive checks the properties chosen to be archived by a downgrade: the owner's active
+// long-term properties beyond the limit of the new plan. None is archived when they fit.
+func propertiesToArchive(properties []postgres.ListActivePropertiesByOwnerAndTypeRow, limit int32, chosen []int32) ([]int32, error) {
+	excess := len(properties) - int(limit)
+	if excess <= 0 {
+		return nil, nil
+	}
+
+	active := make(map[int32]bool, len(properties))
+	for _, p := range properties {
+		active[p.ID] = true
+	}
+	var archived []int32
+	seen := make(map[int32]bool, len(chosen))
+	for _, id := range chosen {
+		if !active[id] {
+			return nil, fmt.Errorf("%w: %d", ErrInvalidPropertiesToArchive, id)
+		}
+		if !seen[id] {
+			seen[id] = true
+			archived = append(archived, id)
+		}
+	}
+	if len(archived) < excess {
+		e := &ErrPropertiesToArchive{Limit: limit, ToArchive: excess}
+		for _, p := range properties {
+			e.Properties = append(e.Properties, ArchivableProperty{ID: p.ID, Name: p.Name.String, Address: p.Address})
+		}
+		return nil, e
+	}
+	return archived, nil
+}
+
+// applyScheduledChange switches a subscription reaching its period end to the plan scheduled, archiving
+// the properties chosen then. Properties created since, beyond the new limit, are archived too, latest
+// first. The subscription is returned as changed, with its new plan.
+func applyScheduledChange(ctx context.Context, q postgres.Querier, sub postgres.Subscription, slots postgres.SubscriptionItem, at time.Time) (postgres.Subscription, postgres.CatalogItem, error) {
+	to, err := q.GetCatalogItem(ctx, sub.ScheduledCatalogItemID.Int32)
+	if err != nil {
+		return sub, to, err
+	}
+
+	archived := sub.ScheduledArchivedPropertyIds
+	if len(archived) > 0 {
+		if _, err := q.ArchiveProperties(ctx, postgres.ArchivePropertiesParams{OwnerID: sub.UserID, Ids: archived}); err != nil {
+			return sub, to, fmt.Errorf("failed to archive properties: %w", err)
+		}
+	}
+	limit := propertyLimit(to, keptSlots(slots, to))
+	extra, err := archiveExcessProperties(ctx, q, sub, limit)
+	if err != nil {
+		return sub, to, err
+	}
+	archived = append(archived, extra...)
+
+	if err := q.ChangeSubscriptionPlan(ctx, postgres.ChangeSubscriptionPlanParams{
+		ID:                 sub.ID,
+		CatalogItemID:      pgtype.Int4{Int32: to.ID, Valid: true},
+		PlanType:           to.PlanType.SubPlan,
+		MaxPropertiesLimit: pgtype.Int4{Int32: limit, Valid: true},
+	}); err != nil {
+		return sub, to, fmt.Errorf("failed to change plan: %w", err)
+	}
+	ev := subscriptionEvent(sub, SubscriptionEventDowngraded, at)
+	ev.ToCatalogItemID = pgtype.Int4{Int32: to.ID, Valid: true}
+	ev.ArchivedPropertyIds = archived
+	if _, err := q.CreateSubscriptionEvent(ctx, ev); err != nil {
+		return sub, to, err
+	}
+
+	sub.CatalogItemID = pgtype.Int4{Int32: to.ID, Valid: true}
+	sub.PlanType = to.PlanType.SubPlan
+	sub.MaxPropertiesLimit = pgtype.Int4{Int32: limit, Valid: true}
+	sub.ScheduledCatalogItemID = pgtype.Int4{}
+	sub.ScheduledArchivedPropertyIds = nil
+	return sub, to, nil
+}
+
+// archiveExcessProperties archives the active long-term properties of the owner beyond limit, latest first.
+func archiveExcessProperties(ctx context.Context, q postgres.Querier, sub postgres.Subscription, limit int32) ([]int32, error) {
+	properties, err := q.ListActivePropertiesByOwnerAndType(ctx, postgres.ListActivePropertiesByOwnerAndTypeParams{
+		OwnerID:    sub.UserID,
+		RentalType: postgres.PropertyTypeLongTerm,
+	})
+	if err != nil {
+		return nil, err
+	}
+	excess := len(properties) - int(limit)
+	if excess <= 0 {
+		return nil, nil
+	}
+	var archived []int32
+	for _, p := range properties[:excess] {
+		archived = append(archived, p.ID)
+	}
+	if _, err := q.ArchiveProperties(ctx, postgres.ArchivePropertiesParams{OwnerID: sub.UserID, Ids: archived}); err != nil {
+		return nil, fmt.Errorf("failed to archive properties: %w", err)
+	}
+	return archived, nil
+}
+
+// recordSubscribed starts the history of a subscription once it is active.
+func recordSubscribed(ctx context.Context, q postgres.Querier, sub postgres.Subscription) error {
+	ev := subscriptionEvent(sub, SubscriptionEventSubscribed, sub.CurrentPeriodStart.Time)
+	ev.FromCatalogItemID, ev.ToCatalogItemID = pgtype.Int4{}, sub.CatalogItemID
+	_, err := q.CreateSubscriptionEvent(ctx, ev)
+	return err
+}
+
+// endSubscription ends the access of a subscription on a date and records it in its history.
+func endSubscription(ctx context.Context, q postgres.Querier, sub postgres.Subscription, at time.Time) error {
+	if err := q.EndSubscription(ctx, postgres.EndSubscriptionParams{
+		ID:      sub.ID,
+		EndedAt: pgtype.Date{Time: at, Valid: true},
+	}); err != nil {
+		return err
+	}
+	_, err := q.CreateSubscriptionEvent(ctx, subscriptionEvent(sub, SubscriptionEventCancelled, at))
+	return err
+}
+
+// dropScheduledChange cancels the downgrade scheduled on a subscription, if any.
+func dropScheduledChange(ctx context.Context, q postgres.Querier, sub postgres.Subscription, today time.Time) error {
+	if !sub.ScheduledCatalogItemID.Valid {
+		return nil
+	}
+	if err := q.ScheduleSubscriptionChange(ctx, postgres.ScheduleSubscriptionChangeParams{ID: sub.ID}); err != nil {
+		return err
+	}
+	ev := subscriptionEvent(sub, SubscriptionEventDowngradeCancelled, today)
+	ev.ToCatalogItemID = sub.ScheduledCatalogItemID
+	_, err := q.CreateSubscriptionEvent(ctx, ev)
+	return err
+}
+
+// Cancel cancels the current subscription. An active one keeps its access until the end of the period
+// paid, then ends instead of renewing; a downgrade scheduled is dropped. A past due one ends right away
+// and its unpaid renewal is voided.
+func (s *SubscriptionService) Cancel(ctx context.Context, userID int32) (*SubscriptionState, error) {
+	today := dateOf(time.Now())
+	var state *SubscriptionState
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		sub, err := currentSubscription(ctx, q, userID)
+		if err != nil {
+			return err
+		}
+
+		switch sub.Status.String {
+		case "active":
+			if !sub.CancelAtPeriodEnd {
+				if err := dropScheduledChange(ctx, q, sub, today); err != nil {
+					return err
+				}
+				if err := q.SetSubscriptionCancelAtPeriodEnd(ctx, postgres.SetSubscriptionCancelAtPeriodEndParams{ID: sub.ID, CancelAtPeriodEnd: true}); err != nil {
+					return err
+				}
+				if _, err := q.CreateSubscriptionEvent(ctx, subscriptionEvent(sub, SubscriptionEventCancellationScheduled, sub.CurrentPeriodEnd.Time)); err != nil {
+					return err
+				}
+			}
+		case "past_due":
+			renewal, err := q.GetInvoiceForPeriod(ctx, postgres.GetInvoiceForPeriodParams{
+				SubscriptionID: pgtype.Int4{Int32: sub.ID, Valid: true},
+				PeriodStart:    sub.CurrentPeriodEnd,
+			})
+			if err != nil && err != pgx.ErrNoRows {
+				return err
+			}
+			if err == nil && renewal.Status == "failed" {
+				if err := q.VoidInvoice(ctx, renewal.ID); err != nil {
+					return err
+				}
+			}
+			if err := endSubscription(ctx, q, sub, today); err != nil {
+				return err
+			}
+		default:
+			return ErrSubscriptionNotActive
+		}
+
+		sub, err = q.GetSubscriptionForUpdate(ctx, sub.ID)
+		if err != nil {
+			return err
+		}
+		state, err = subscriptionState(ctx, q, sub)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.FromContext(ctx).Info("subscription cancelled", zap.Int32("user_id", userID), zap.String("status", state.Status))
+	return state, nil
+}
+
+// Resume reverts the cancellation of a subscription that has not ended yet: it renews again.
+func (s *SubscriptionService) Resume(ctx context.Context, userID int32) (*SubscriptionState, error) {
+	return s.updateSubscription(ctx, userID, func(q postgres.Querier, sub postgres.Subscription) error {
+		if !sub.CancelAtPeriodEnd {
+			return ErrCancellationNotScheduled
+		}
+		if err := q.SetSubscriptionCancelAtPeriodEnd(ctx, postgres.SetSubscriptionCancelAtPeriodEndParams{ID: sub.ID}); err != nil {
+			return err
+		}
+		_, err := q.CreateSubscriptionEvent(ctx, subscriptionEvent(sub, SubscriptionEventCancellationReverted, dateOf(time.Now())))
+		return err
+	})
+}
+
+// CancelScheduledChange drops the downgrade scheduled at the period end: the current plan renews.
+func (s *SubscriptionService) CancelScheduledChange(ctx context.Context, userID int32) (*SubscriptionState, error) {
+	return s.updateSubscription(ctx, userID, func(q postgres.Querier, sub postgres.Subscription) error {
+		if !sub.ScheduledCatalogItemID.Valid {
+			return ErrNoScheduledChange
+		}
+		return dropScheduledChange(ctx, q, sub, dateOf(time.Now()))
+	})
+}
+
+// updateSubscription runs fn on the active subscription of a user and returns its new state.
+func (s *SubscriptionService) updateSubscription(ctx context.Context, userID int32, fn func(postgres.Querier, postgres.Subscription) error) (*SubscriptionState, error) {
+	var state *SubscriptionState
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		sub, err := currentSubscription(ctx, q, userID)
+		if err != nil {
+			return err
+		}
+		if sub.Status.String != "active" {
+			return ErrSubscriptionNotActive
+		}
+		if err := fn(q, sub); err != nil {
+			return err
+		}
+		sub, err = q.GetSubscriptionForUpdate(ctx, sub.ID)
+		if err != nil {
+			return err
+		}
+		state, err = subscriptionState(ctx, q, sub)
+		return err
+	})
+	return state, err
+}
+
+// GetSubscription returns the current subscription of a user with its pending changes.
+func (s *SubscriptionService) GetSubscription(ctx context.Context, userID int32) (*SubscriptionState, error) {
+	var state *SubscriptionState
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		sub, err := q.GetUserSubscription(ctx, pgtype.Int4{Int32: userID, Valid: true})
+		if err == pgx.ErrNoRows {
+			return ErrNoSubscription
+		}
+		if err != nil {
+			return err
+		}
+		state, err = subscriptionState(ctx, q, sub)
+		return err
+	})
+	return state, err
+}
+
+func subscriptionState(ctx context.Context, q postgres.Querier, sub postgres.Subscription) (*SubscriptionState, error) {
+	item, err := subscriptionCatalogItem(ctx, q, sub)
+	if err != nil {
+		return nil, err
+	}
+	state := &SubscriptionState{
+		Plan:                         item.Code,
+		Status:                       sub.Status.String,
+		MaxPropertiesLimit:           sub.MaxPropertiesLimit.Int32,
+		CancelAtPeriodEnd:            sub.CancelAtPeriodEnd,
+		ScheduledArchivedPropertyIDs: sub.ScheduledArchivedPropertyIds,
+	}
+	if sub.Frequency.Valid {
+		state.Frequency = string(sub.Frequency.BillingFreq)
+	}
+	if sub.CurrentPeriodStart.Valid {
+		state.CurrentPeriodStart = sub.CurrentPeriodStart.Time.Format("2006-01-02")
+	}
+	if sub.CurrentPeriodEnd.Valid {
+		state.CurrentPeriodEnd = sub.CurrentPeriodEnd.Time.Format("2006-01-02")
+	}
+	if sub.ScheduledCatalogItemID.Valid {
+		scheduled, err := q.GetCatalogItem(ctx, sub.ScheduledCatalogItemID.Int32)
+		if err != nil {
+			return nil, err
+		}
+		state.ScheduledPlan = scheduled.Code
+	}
+	slots, err := propertySlots(ctx, q, sub)
+	if err != nil {
+		return nil, err
+	}
+	state.PropertySlots = propertySlotsDTO(slots)
+	return state, nil
+}
+
+// History returns the timeline of the subscriptions of a user, oldest first.
+func (s *SubscriptionService) History(ctx context.Context, userID int32) ([]SubscriptionEventDTO, error) {
+	var rows []postgres.ListSubscriptionEventsByUserRow
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		var err error
+		rows, err = q.ListSubscriptionEventsByUser(ctx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]SubscriptionEventDTO, 0, len(rows))
+	for _, r := range rows {
+		events = append(events, SubscriptionEventDTO{
+			Type:                r.EventType,
+			FromPlan:            r.FromPlan.String,
+			ToPlan:              r.ToPlan.String,
+			EffectiveDate:       r.EffectiveDate.Time.Format("2006-01-02"),
+			AmountCents:         r.AmountCents.Int32,
+			InvoiceID:           r.InvoiceID.Int32,
+			ArchivedPropertyIDs: r.ArchivedPropertyIds,
+			SlotQuantity:        r.SlotQuantity.Int32,
+			RecordedAt:          r.CreatedAt.Time.Format(time.RFC3339),
+		})
+	}
+	return events, nil
+}
@@ -0,0 +1,295 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+)
+
+// monthlySub is an active monthly subscription of payingUser, 10 days into a 30 days period.
+func monthlySub(item postgres.CatalogItem) postgres.Subscription {
+	today := dateOf(time.Now())
+	return postgres.Subscription{
+		ID: 8, UserID: pgtype.Int4{Int32: 1, Valid: true}, PlanType: item.PlanType.SubPlan,
+		Frequency: postgres.NullBillingFreq{BillingFreq: postgres.BillingFreqMonthly, Valid: true},
+		Status:    pgtype.Text{String: "active", Valid: true}, StartDate: pgDate(today.AddDate(0, 0, -10)),
+		CurrentPeriodStart: pgDate(today.AddDate(0, 0, -10)), CurrentPeriodEnd: pgDate(today.AddDate(0, 0, 20)),
+		MaxPropertiesLimit: pgtype.Int4{Int32: item.MaxProperties, Valid: true}, CatalogItemID: pgtype.Int4{Int32: item.ID, Valid: true},
+		NextCreditGrant: pgDate(today.AddDate(0, 0, 20)), GrantedCredits: item.IncludedCredits,
+	}
+}
+
+func longTermProperties(ids ...int32) []postgres.ListActivePropertiesByOwnerAndTypeRow {
+	rows := make([]postgres.ListActivePropertiesByOwnerAndTypeRow, 0, len(ids))
+	for _, id := range ids {
+		rows = append(rows, postgres.ListActivePropertiesByOwnerAndTypeRow{ID: id, Address: "1 rue de la Paix"})
+	}
+	return rows
+}
+
+func expectSubscriptionEvent(q *MockQuerier, eventType string) {
+	q.On("CreateSubscriptionEvent", mock.Anything, mock.MatchedBy(func(p postgres.CreateSubscriptionEventParams) bool {
+		return p.EventType == eventType
+	})).Return(postgres.SubscriptionEvent{}, nil).Once()
+}
+
+func TestProratedCents(t *testing.T) {
+	start, end := day("2026-03-01"), day("2026-03-31")
+
+	assert.Equal(t, int32(990), proratedCents(990, start, end, start))
+	assert.Equal(t, int32(660), proratedCents(990, start, end, day("2026-03-11")))
+	assert.Equal(t, int32(0), proratedCents(990, start, end, end))
+}
+
+func TestChangePlan_UpgradeInvoicesProrata(t *testing.T) {
+	payments, mockQuerier, provider := setupPayments()
+	svc := NewSubscriptionService(passthroughTxManager{q: mockQuerier}, payments, zap.NewNop())
+	sub := monthlySub(catalogSerenity)
+
+	mockQuerier.On("GetUserSubscriptionForUpdate", mock.Anything, pgtype.Int4{Int32: 1, Valid: true}).Return(sub, nil)
+	expectNoSlots(mockQuerier)
+	mockQuerier.On("GetCatalogItem", mock.Anything, catalogSerenity.ID).Return(catalogSerenity, nil)
+	mockQuerier.On("GetActiveCatalogItem", mock.Anything, postgres.GetActiveCatalogItemParams{Kind: CatalogKindPlan, Code: "premium"}).Return(catalogPremium, nil)
+
+	// 20 days left out of 30: 1993 for Premium, less 660 unused on Serenity
+	invoice := postgres.Invoice{ID: 30, UserID: 1, SubscriptionID: pgtype.Int4{Int32: 8, Valid: true},
+		CatalogItemID: pgtype.Int4{Int32: catalogPremium.ID, Valid: true}, AmountCents: 1333, Status: "open"}
+	mockQuerier.On("CreateInvoice", mock.Anything, mock.MatchedBy(func(p postgres.CreateInvoiceParams) bool {
+		return p.SubscriptionID.Int32 == 8 && p.CatalogItemID.Int32 == catalogPremium.ID && p.AmountCents == 1333 && !p.PeriodStart.Valid
+	})).Return(invoice, nil)
+	// Committed, then charged
+	expectClaim(mockQuerier, invoice)
+	mockQuerier.On("GetUserById", mock.Anything, int32(1)).Return(payingUser, nil)
+	provider.On("CreatePaymentIntent", mock.Anything, mock.MatchedBy(func(req PaymentIntentRequest) bool {
+		return req.AmountCents == 1333 && req.Reference == "invoice-30"
+	})).Return(&PaymentIntent{ID: "pi_1", Status: PaymentSucceeded}, nil)
+	mockQuerier.On("CreatePaymentTransaction", mock.Anything, mock.Anything).Return(postgres.Transaction{ID: 9}, nil)
+
+	// Paid: issued, then Premium applies with the 10 missing credits of the month
+	mockQuerier.On("GetSubscriptionForUpdate", mock.Anything, int32(8)).Return(sub, nil)
+	mockQuerier.On("MarkInvoicePaid", mock.Anything, int32(30)).Return(invoice, nil)
+	expectInvoiceIssued(mockQuerier, invoiceSeries, 30, 1)
+	mockQuerier.On("GetCatalogItem", mock.Anything, catalogPremium.ID).Return(catalogPremium, nil)
+	mockQuerier.On("ChangeSubscriptionPlan", mock.Anything, postgres.ChangeSubscriptionPlanParams{
+		ID: 8, CatalogItemID: pgtype.Int4{Int32: catalogPremium.ID, Valid: true}, PlanType: postgres.SubPlanPremium,
+		MaxPropertiesLimit: pgtype.Int4{Int32: 5, Valid: true},
+	}).Return(nil)
+	mockQuerier.On("CreateCreditTransaction", mock.Anything, mock.MatchedBy(func(p postgres.CreateCreditTransactionParams) bool {
+		return p.Amount == 10 && p.TransactionType == "plan_upgrade"
+	})).Return(postgres.CreditTransaction{}, nil)
+	mockQuerier.On("RecordSubscriptionCreditGrant", mock.Anything, postgres.RecordSubscriptionCreditGrantParams{
+		ID: 8, NextCreditGrant: sub.NextCreditGrant, GrantedCredits: 30,
+	}).Return(nil)
+	mockQuerier.On("CreateSubscriptionEvent", mock.Anything, mock.MatchedBy(func(p postgres.CreateSubscriptionEventParams) bool {
+		return p.EventType == SubscriptionEventUpgraded && p.FromCatalogItemID.Int32 == catalogSerenity.ID &&
+			p.ToCatalogItemID.Int32 == catalogPremium.ID && p.AmountCents.Int32 == 1333 && p.InvoiceID.Int32 == 30
+	})).Return(postgres.SubscriptionEvent{}, nil)
+
+	change, err := svc.ChangePlan(context.Background(), 1, "premium", nil, "pm_card_visa")
+
+	require.NoError(t, err)
+	assert.Equal(t, PlanChangeUpgraded, change.Status)
+	assert.Equal(t, int32(660), change.CreditCents)
+	assert.Equal(t, int32(1993), change.ChargeCents)
+	assert.Equal(t, int32(1333), change.AmountDueCents)
+	mockQuerier.AssertExpectations(t)
+}
+
+func TestChangePlan_FailedUpgradeKeepsPlan(t *testing.T) {
+	payments, mockQuerier, provider := setupPayments()
+	svc := NewSubscriptionService(passthroughTxManager{q: mockQuerier}, payments, zap.NewNop())
+	sub := monthlySub(catalogSerenity)
+
+	mockQuerier.On("GetUserSubscriptionForUpdate", mock.Anything, mock.Anything).Return(sub, nil)
+	mockQuerier.On("GetCatalogItem", mock.Anything, catalogSerenity.ID).Return(catalogSerenity, nil)
+	mockQuerier.On("GetActiveCatalogItem", mock.Anything, mock.Anything).Return(catalogPremium, nil)
+	invoice := postgres.Invoice{ID: 30, UserID: 1, SubscriptionID: pgtype.Int4{Int32: 8, Valid: true},
+		CatalogItemID: pgtype.Int4{Int32: catalogPremium.ID, Valid: true}, AmountCents: 1333, Status: "open"}
+	mockQuerier.On("CreateInvoice", mock.Anything, mock.Anything).Return(invoice, nil)
+	expectClaim(mockQuerier, invoice)
+	mockQuerier.On("GetUserById", mock.Anything, int32(1)).Return(payingUser, nil)
+	provider.On("CreatePaymentIntent", mock.Anything, mock.Anything).Return(&PaymentIntent{ID: "pi_1", Status: PaymentFailed, FailureReason: "card_declined"}, nil)
+	mockQuerier.On("CreatePaymentTransaction", mock.Anything, mock.Anything).Return(postgres.Transaction{ID: 9}, nil)
+	mockQuerier.On("GetSubscriptionForUpdate", mock.Anything, int32(8)).Return(sub, nil)
+	// Voided, not retried with the renewals
+	mockQuerier.On("MarkInvoiceFailed", mock.Anything, int32(30)).Return(invoice, nil)
+	mockQuerier.On("VoidInvoice", mock.Anything, int32(30)).Return(nil)
+
+	change, err := svc.ChangePlan(context.Background(), 1, "premium", nil, "pm_card_chargeDeclined")
+
+	require.NoError(t, err)
+	assert.Equal(t, PlanChangePaymentFailed, change.Status)
+	mockQuerier.AssertExpectations(t)
+	mockQuerier.AssertNotCalled(t, "ChangeSubscriptionPlan", mock.Anything, mock.Anything)
+	mockQuerier.AssertNotCalled(t, "SetSubscriptionStatus", mock.Anything, mock.Anything)
+}
+
+func TestChangePlan_UpgradeWithoutPaymentsRecordsNothing(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	svc := NewSubscriptionService(passthroughTxManager{q: mockQuerier}, NewPaymentService(passthroughTxManager{q: mockQuerier}, nil, nil, zap.NewNop()), zap.NewNop())
+	sub := monthlySub(catalogSerenity)
+
+	mockQuerier.On("GetUserSubscriptionForUpdate", mock.Anything, mock.Anything).Return(sub, nil)
+	mockQuerier.On("GetCatalogItem", mock.Anything, catalogSerenity.ID).Return(catalogSerenity, nil)
+	mockQuerier.On("GetActiveCatalogItem", mock.Anything, mock.Anything).Return(catalogPremium, nil)
+
+	_, err := svc.ChangePlan(context.Background(), 1, "premium", nil, "")
+
+	// Refused before the prorata is invoiced: the request can be retried as is
+	assert.ErrorIs(t, err, ErrPaymentUnavailable)
+	mockQuerier.AssertNotCalled(t, "CreateInvoice", mock.Anything, mock.Anything)
+}
+
+func TestChangePlan_DowngradeRequiresPropertiesToArchive(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	svc := NewSubscriptionService(passthroughTxManager{q: mockQuerier}, nil, zap.NewNop())
+	sub := monthlySub(catalogPremium)
+
+	mockQuerier.On("GetUserSubscriptionForUpdate", mock.Anything, mock.Anything).Return(sub, nil)
+	expectNoSlots(mockQuerier)
+	mockQuerier.On("GetCatalogItem", mock.Anything, catalogPremium.ID).Return(catalogPremium, nil)
+	mockQuerier.On("GetActiveCatalogItem", mock.Anything, postgres.GetActiveCatalogItemParams{Kind: CatalogKindPlan, Code: "serenity"}).Return(catalogSerenity, nil)
+	mockQuerier.On("ListActivePropertiesByOwnerAndType", mock.Anything, postgres.ListActivePropertiesByOwnerAndTypeParams{
+		OwnerID: sub.UserID, RentalType: postgres.PropertyTypeLongTerm,
+	}).Return(longTermProperties(13, 12, 11), nil)
+
+	// Serenity allows one long-term property: two of the three must be chosen
+	_, err := svc.ChangePlan(context.Background(), 1, "serenity", []int32{12}, "")
+	var archiveErr *ErrPropertiesToArchive
+	require.True(t, errors.As(err, &archiveErr))
+	assert.Equal(t, int32(1), archiveErr.Limit)
+	assert.Equal(t, 2, archiveErr.ToArchive)
+	assert.Len(t, archiveErr.Properties, 3)
+
+	_, err = svc.ChangePlan(context.Background(), 1, "serenity", []int32{12, 99}, "")
+	assert.ErrorIs(t, err, ErrInvalidPropertiesToArchive)
+	mockQuerier.AssertNotCalled(t, "ScheduleSubscriptionChange", mock.Anything, mock.Anything)
+
+	// Scheduled at the period end, nothing archived before
+	mockQuerier.On("ScheduleSubscriptionChange", mock.Anything, postgres.ScheduleSubscriptionChangeParams{
+		ID: 8, CatalogItemID: pgtype.Int4{Int32: catalogSerenity.ID, Valid: true}, ArchivedPropertyIds: []int32{12, 11},
+	}).Return(nil)
+	mockQuerier.On("CreateSubscriptionEvent", mock.Anything, mock.MatchedBy(func(p postgres.CreateSubscriptionEventParams) bool {
+		return p.EventType == SubscriptionEventDowngradeScheduled && p.EffectiveDate == sub.CurrentPeriodEnd &&
+			p.ToCatalogItemID.Int32 == catalogSerenity.ID
+	})).Return(postgres.SubscriptionEvent{}, nil)
+
+	change, err := svc.ChangePlan(context.Background(), 1, "serenity", []int32{12, 11, 12}, "")
+
+	require.NoError(t, err)
+	assert.Equal(t, PlanChangeScheduled, change.Status)
+	assert.Equal(t, sub.CurrentPeriodEnd.Time.Format("2006-01-02"), change.EffectiveDate)
+	assert.Equal(t, []int32{12, 11}, change.ArchivedPropertyIDs)
+	mockQuerier.AssertNotCalled(t, "ArchiveProperties", mock.Anything, mock.Anything)
+	mockQuerier.AssertNotCalled(t, "ChangeSubscriptionPlan", mock.Anything, mock.Anything)
+}
+
+func TestRenewSubscriptions_AppliesScheduledDowngrade(t *testing.T) {
+	svc, mockQuerier, payments, _ := setupBilling()
+	today := dateOf(time.Now())
+	sub := monthlySub(catalogPremium)
+	sub.CurrentPeriodStart, sub.CurrentPeriodEnd = pgDate(addMonths(today, -1)), pgDate(today)
+	sub.StartDate = sub.CurrentPeriodStart
+	sub.ScheduledCatalogItemID = pgtype.Int4{Int32: catalogSerenity.ID, Valid: true}
+	sub.ScheduledArchivedPropertyIds = []int32{11}
+
+	mockQuerier.On("ListSubscriptionsDueForRenewal", mock.Anything, mock.Anything).Return([]int32{8}, nil)
+	mockQuerier.On("GetSubscriptionForUpdate", mock.Anything, int32(8)).Return(sub, nil)
+	expectNoSlots(mockQuerier)
+	mockQuerier.On("GetCatalogItem", mock.Anything, catalogPremium.ID).Return(catalogPremium, nil)
+	mockQuerier.On("GetCatalogItem", mock.Anything, catalogSerenity.ID).Return(catalogSerenity, nil)
+	mockQuerier.On("ArchiveProperties", mock.Anything, postgres.ArchivePropertiesParams{OwnerID: sub.UserID, Ids: []int32{11}}).Return(int64(1), nil)
+	// Property 14 was created after the downgrade was scheduled: the latest is archived too
+	mockQuerier.On("ListActivePropertiesByOwnerAndType", mock.Anything, mock.Anything).Return(longTermProperties(14, 12), nil)
+	mockQuerier.On("ArchiveProperties", mock.Anything, postgres.ArchivePropertiesParams{OwnerID: sub.UserID, Ids: []int32{14}}).Return(int64(1), nil)
+	mockQuerier.On("ChangeSubscriptionPlan", mock.Anything, postgres.ChangeSubscriptionPlanParams{
+		ID: 8, CatalogItemID: pgtype.Int4{Int32: catalogSerenity.ID, Valid: true}, PlanType: postgres.SubPlanSerenity,
+		MaxPropertiesLimit: pgtype.Int4{Int32: 1, Valid: true},
+	}).Return(nil)
+	mockQuerier.On("CreateSubscriptionEvent", mock.Anything, mock.MatchedBy(func(p postgres.CreateSubscriptionEventParams) bool {
+		return p.EventType == SubscriptionEventDowngraded && p.EffectiveDate == pgDate(today) &&
+			assert.ObjectsAreEqual([]int32{11, 14}, p.ArchivedPropertyIds)
+	})).Return(postgres.SubscriptionEvent{}, nil)
+
+	// The next period is invoiced at the Serenity price
+	mockQuerier.On("GetInvoiceForPeriod", mock.Anything, mock.Anything).Return(postgres.Invoice{}, pgx.ErrNoRows)
+	invoice := postgres.Invoice{ID: 31, UserID: 1, SubscriptionID: pgtype.Int4{Int32: 8, Valid: true}, AmountCents: 990, Status: "open"}
+	mockQuerier.On("CreateInvoice", mock.Anything, mock.MatchedBy(func(p postgres.CreateInvoiceParams) bool {
+		return p.AmountCents == 990 && p.PeriodStart.Time.Equal(today)
+	})).Return(invoice, nil)
+	payments.On("Collect", mock.Anything, expectClaim(mockQuerier, invoice)).Return(ErrPaymentPending)
+	mockQuerier.On("MarkInvoicePending", mock.Anything, int32(31)).Return(nil)
+
+	require.NoError(t, svc.RenewSubscriptions(context.Background()))
+	mockQuerier.AssertExpectations(t)
+}
+
+func TestRenewSubscriptions_EndsCancelledSubscription(t *testing.T) {
+	svc, mockQuerier, payments, _ := setupBilling()
+	today := dateOf(time.Now())
+	sub := monthlySub(catalogSerenity)
+	sub.CurrentPeriodEnd, sub.CancelAtPeriodEnd = pgDate(today), true
+
+	mockQuerier.On("ListSubscriptionsDueForRenewal", mock.Anything, mock.Anything).Return([]int32{8}, nil)
+	mockQuerier.On("GetSubscriptionForUpdate", mock.Anything, int32(8)).Return(sub, nil)
+	mockQuerier.On("EndSubscription", mock.Anything, postgres.EndSubscriptionParams{ID: 8, EndedAt: pgDate(today)}).Return(nil)
+	expectSubscriptionEvent(mockQuerier, SubscriptionEventCancelled)
+
+	require.NoError(t, svc.RenewSubscriptions(context.Background()))
+	mockQuerier.AssertExpectations(t)
+	mockQuerier.AssertNotCalled(t, "CreateInvoice", mock.Anything, mock.Anything)
+	payments.AssertNotCalled(t, "Collect", mock.Anything, mock.Anything)
+}
+
+func TestCancel_KeepsAccessUntilPeriodEnd(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	svc := NewSubscriptionService(passthroughTxManager{q: mockQuerier}, nil, zap.NewNop())
+	sub := monthlySub(catalogPremium)
+	sub.ScheduledCatalogItemID = pgtype.Int4{Int32: catalogSerenity.ID, Valid: true}
+	cancelled := sub
+	cancelled.CancelAtPeriodEnd, cancelled.ScheduledCatalogItemID = true, pgtype.Int4{}
+
+	mockQuerier.On("GetUserSubscriptionForUpdate", mock.Anything, pgtype.Int4{Int32: 1, Valid: true}).Return(sub, nil)
+	expectNoSlots(mockQuerier)
+	// The scheduled downgrade is dropped, then the cancellation scheduled
+	mockQuerier.On("ScheduleSubscriptionChange", mock.Anything, postgres.ScheduleSubscriptionChangeParams{ID: 8}).Return(nil)
+	expectSubscriptionEvent(mockQuerier, SubscriptionEventDowngradeCancelled)
+	mockQuerier.On("SetSubscriptionCancelAtPeriodEnd", mock.Anything, postgres.SetSubscriptionCancelAtPeriodEndParams{ID: 8, CancelAtPeriodEnd: true}).Return(nil)
+	mockQuerier.On("CreateSubscriptionEvent", mock.Anything, mock.MatchedBy(func(p postgres.CreateSubscriptionEventParams) bool {
+		return p.EventType == SubscriptionEventCancellationScheduled && p.EffectiveDate == sub.CurrentPeriodEnd
+	})).Return(postgres.SubscriptionEvent{}, nil).Once()
+	mockQuerier.On("GetSubscriptionForUpdate", mock.Anything, int32(8)).Return(cancelled, nil)
+	mockQuerier.On("GetCatalogItem", mock.Anything, catalogPremium.ID).Return(catalogPremium, nil)
+
+	state, err := svc.Cancel(context.Background(), 1)
+
+	require.NoError(t, err)
+	assert.Equal(t, "active", state.Status)
+	assert.True(t, state.CancelAtPeriodEnd)
+	assert.Equal(t, "premium", state.Plan)
+	assert.Empty(t, state.ScheduledPlan)
+	mockQuerier.AssertExpectations(t)
+	mockQuerier.AssertNotCalled(t, "EndSubscription", mock.Anything, mock.Anything)
+}
+
+func TestChangePlan_RejectedWhileCancelled(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	svc := NewSubscriptionService(passthroughTxManager{q: mockQuerier}, nil, zap.NewNop())
+	sub := monthlySub(catalogSerenity)
+	sub.CancelAtPeriodEnd = true
+	mockQuerier.On("GetUserSubscriptionForUpdate", mock.Anything, mock.Anything).Return(sub, nil)
+
+	_, err := svc.ChangePlan(context.Background(), 1, "premium", nil, "")
+
+	assert.ErrorIs(t, err, ErrCancellationScheduled)
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"testing"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"go.uber.org/zap"
@@ -15,18 +17,31 @@ func TestSubscribeUser_Discovery_SetsLimitOne(t *testing.T) {
 	// Setup
 	mockTx := new(MockTxManager)
 	mockQuerier := new(MockQuerier)
-	svc := NewSubscriptionService(mockTx, zap.NewNop())
+	svc := NewSubscriptionService(mockTx, nil, zap.NewNop())
 	ctx := context.Background()
 	userID := int32(50)
 
+	mockQuerier.On("GetUserSubscription", mock.Anything, pgtype.Int4{Int32: userID, Valid: true}).Return(postgres.Subscription{}, pgx.ErrNoRows)
+	mockQuerier.On("GetActiveCatalogItem", mock.Anything, postgres.GetActiveCatalogItemParams{Kind: CatalogKindPlan, Code: "discovery"}).
+		Return(catalogDiscovery, nil)
+
 	// Expect CreateSubscription with MaxPropertiesLimit = 1
 	mockQuerier.On("CreateSubscription", mock.Anything, mock.MatchedBy(func(arg postgres.CreateSubscriptionParams) bool {
 		return arg.UserID.Int32 == userID &&
 			arg.PlanType == postgres.SubPlanDiscovery &&
-			arg.MaxPropertiesLimit.Int32 == 1 // Verify the change
+			arg.MaxPropertiesLimit.Int32 == 1 && // Verify the change
+			arg.Status.String == "active" // Free plans need no payment
 	})).Return(postgres.Subscription{ID: 1}, nil)
 
-	// No credit transaction for discovery plan (amount 0)
+	// Active right away: the history starts
+	mockQuerier.On("CreateSubscriptionEvent", mock.Anything, mock.MatchedBy(func(arg postgres.CreateSubscriptionEventParams) bool {
+		return arg.SubscriptionID == 1 && arg.EventType == SubscriptionEventSubscribed
+	})).Return(postgres.SubscriptionEvent{}, nil)
+
+	// No invoice nor credit transaction for discovery plan (amount 0), only the grant schedule
+	mockQuerier.On("RecordSubscriptionCreditGrant", mock.Anything, mock.MatchedBy(func(arg postgres.RecordSubscriptionCreditGrantParams) bool {
+		return arg.ID == 1 && arg.GrantedCredits == 0
+	})).Return(nil)
 
 	// WithTx
 	mockTx.On("WithTx", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
@@ -35,8 +50,9 @@ func TestSubscribeUser_Discovery_SetsLimitOne(t *testing.T) {
 	})
 
 	// Execute
-	err := svc.SubscribeUser(ctx, userID, "discovery", "monthly")
+	payment, err := svc.SubscribeUser(ctx, userID, "discovery", "monthly", "")
 
 	// Assert
 	assert.NoError(t, err)
+	assert.Nil(t, payment)
 }
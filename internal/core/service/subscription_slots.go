@@ -0,0 +1,348 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+	"seculoc-back/internal/platform/logger"
+)
+
+// subscriptionItemPropertySlot is the add-on selling long-term properties on top of those of the plan.
+const subscriptionItemPropertySlot = "property_slot"
+
+// Subscription event types of the extra properties.
+const (
+	SubscriptionEventSlotsAdded            = "slots_added"
+	SubscriptionEventSlotsRemovalScheduled = "slots_removal_scheduled"
+	SubscriptionEventSlotsRemoved          = "slots_removed"
+)
+
+// Extra properties purchase outcomes reported in SlotChange.Status.
+const (
+	SlotChangeAdded          = "added"
+	SlotChangePaymentPending = "payment_pending"
+	SlotChangePaymentFailed  = "payment_failed"
+)
+
+var (
+	ErrSlotsNotAvailable = errors.New("plan not eligible for limit increase: it sells no extra properties")
+	ErrNotEnoughSlots    = errors.New("not that many extra properties to remove")
+	ErrSlotsInUse        = errors.New("more long-term properties than the limit left: archive some first")
+)
+
+// PropertySlots are the extra long-term properties of a subscription.
+type PropertySlots struct {
+	Quantity int32 `json:"quantity"`
+	// UnitPriceCents is the monthly price of one extra property
+	UnitPriceCents int32 `json:"unit_price_cents"`
+	// QuantityAtRenewal is set when a removal is scheduled at the period end
+	QuantityAtRenewal *int32 `json:"quantity_at_renewal,omitempty"`
+}
+
+// SlotChange tells how a purchase of extra properties was handled: they are added once their prorata
+// until the period end is paid.
+type SlotChange struct {
+	Status             string         `json:"status"`
+	Slots              int32          `json:"slots"`
+	EffectiveDate      string         `json:"effective_date"`
+	AmountDueCents     int32          `json:"amount_due_cents,omitempty"`
+	MaxPropertiesLimit int32          `json:"max_properties_limit"`
+	Payment            *PaymentResult `json:"payment,omitempty"`
+}
+
+// propertySlots returns the extra properties of a subscription, none if it never had any.
+func propertySlots(ctx context.Context, q postgres.Querier, sub postgres.Subscription) (postgres.SubscriptionItem, error) {
+	slots, err := q.GetSubscriptionItem(ctx, postgres.GetSubscriptionItemParams{SubscriptionID: sub.ID, Kind: subscriptionItemPropertySlot})
+	if err == pgx.ErrNoRows {
+		return postgres.SubscriptionItem{SubscriptionID: sub.ID, Kind: subscriptionItemPropertySlot}, nil
+	}
+	return slots, err
+}
+
+// renewalQuantity is the number of extra properties of the next period.
+func renewalQuantity(slots postgres.SubscriptionItem) int32 {
+	if slots.ScheduledQuantity.Valid {
+		return slots.ScheduledQuantity.Int32
+	}
+	return slots.Quantity
+}
+
+// keptSlots is the number of extra properties kept when moving to a plan at the period end: none if
+// the plan does not sell them.
+func keptSlots(slots postgres.SubscriptionItem, plan postgres.CatalogItem) int32 {
+	if !plan.SlotPriceCents.Valid {
+		return 0
+	}
+	return renewalQuantity(slots)
+}
+
+// propertyLimit is the long-term property limit of a plan with extra properties.
+func propertyLimit(plan postgres.CatalogItem, slots int32) int32 {
+	return plan.MaxProperties + slots
+}
+
+// slotsPriceCents is the price of extra properties for a billing period.
+func slotsPriceCents(unitPriceCents, quantity int32, freq postgres.BillingFreq) int32 {
+	return unitPriceCents * quantity * int32(billingMonths(freq))
+}
+
+// renewalDescription describes the invoice of a period.
+func renewalDescription(plan postgres.CatalogItem, freq postgres.BillingFreq, slots int32) string {
+	if slots == 0 {
+		return fmt.Sprintf("%s (%s)", plan.Name, freq)
+	}
+	return fmt.Sprintf("%s (%s) + %d × extra property", plan.Name, freq, slots)
+}
+
+func propertySlotsDTO(slots postgres.SubscriptionItem) *PropertySlots {
+	if slots.Quantity == 0 {
+		return nil
+	}
+	dto := &PropertySlots{Quantity: slots.Quantity, UnitPriceCents: slots.UnitPriceCents}
+	if slots.ScheduledQuantity.Valid {
+		dto.QuantityAtRenewal = &slots.ScheduledQuantity.Int32
+	}
+	return dto
+}
+
+// IncreaseLimit adds extra long-term properties to the current subscription, for plans with a slot
+// price. Their prorata until the period end is invoiced and charged with paymentMethodID (or the saved
+// payment method); they are added once paid and renewed with the plan. Extra properties whose removal
+// is scheduled are kept back first, at no cost: they are paid until the period end.
+func (s *SubscriptionService) IncreaseLimit(ctx context.Context, userID int32, additionalProperties int32, paymentMethodID string) (*SlotChange, error) {
+	log := logger.FromContext(ctx)
+	today := dateOf(time.Now())
+
+	change := &SlotChange{Status: SlotChangeAdded, Slots: additionalProperties, EffectiveDate: today.Format("2006-01-02")}
+	var added int32
+	var invoice *postgres.Invoice
+	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
+		// 1. Get Subscription
+		sub, err := currentSubscription(ctx, q, userID)
+		if err != nil {
+			return err
+		}
+		if sub.Status.String != "active" {
+			return ErrSubscriptionNotActive
+		}
+		if sub.CancelAtPeriodEnd {
+			return ErrCancellationScheduled
+		}
+		change.MaxPropertiesLimit = sub.MaxPropertiesLimit.Int32
+
+		// 2. Eligibility Check: the plan version subscribed, or the current one for older subscriptions
+		item, err := subscriptionCatalogItem(ctx, q, sub)
+		if err != nil {
+			return err
+		}
+		if !item.SlotPriceCents.Valid {
+			return fmt.Errorf("%w (current: %s)", ErrSlotsNotAvailable, sub.PlanType)
+		}
+
+		// 3. Removal scheduled: kept back first
+		slots, err := propertySlots(ctx, q, sub)
+		if err != nil {
+			return err
+		}
+		added = additionalProperties
+		if kept := min(added, slots.Quantity-renewalQuantity(slots)); kept > 0 {
+			scheduled := pgtype.Int4{Int32: renewalQuantity(slots) + kept, Valid: true}
+			if scheduled.Int32 == slots.Quantity {
+				scheduled = pgtype.Int4{}
+			}
+			if err := q.ScheduleSubscriptionItemQuantity(ctx, postgres.ScheduleSubscriptionItemQuantityParams{ID: slots.ID, ScheduledQuantity: scheduled}); err != nil {
+				return err
+			}
+			ev := subscriptionEvent(sub, SubscriptionEventSlotsAdded, today)
+			ev.SlotQuantity = pgtype.Int4{Int32: kept, Valid: true}
+			if _, err := q.CreateSubscriptionEvent(ctx, ev); err != nil {
+				return err
+			}
+			added -= kept
+		}
+		if added == 0 {
+			return nil
+		}
+
+		// 4. Cost Calculation: the days left of the period
+		freq := sub.Frequency.BillingFreq
+		end := sub.CurrentPeriodEnd.Time
+		change.AmountDueCents = proratedCents(slotsPriceCents(item.SlotPriceCents.Int32, added, freq), sub.CurrentPeriodStart.Time, end, today)
+		if change.AmountDueCents <= 0 {
+			change.MaxPropertiesLimit += added
+			return applySlots(ctx, q, sub, item, added, today, nil)
+		}
+
+		// 5. Invoice, charged once committed; the slots are added once paid (see settleProrata)
+		if !s.payments.available() {
+			return ErrPaymentUnavailable
+		}
+		created, err := q.CreateInvoice(ctx, postgres.CreateInvoiceParams{
+			UserID:         userID,
+			SubscriptionID: pgtype.Int4{Int32: sub.ID, Valid: true},
+			SlotQuantity:   pgtype.Int4{Int32: added, Valid: true},
+			Description:    fmt.Sprintf("%d × extra property, %s (%s), prorated until %s", added, item.Name, freq, end.Format("2006-01-02")),
+			AmountCents:    change.AmountDueCents,
+			Status:         "open",
+		})
+		if err != nil {
+			return fmt.Errorf("failed to invoice extra properties: %w", err)
+		}
+		invoice, err = claimInvoice(ctx, q, created.ID)
+		return err
+	})
+	if err == nil && invoice != nil {
+		change.Payment, err = s.payments.payInvoice(ctx, *invoice, paymentMethodID)
+		switch {
+		case err != nil:
+		case change.Payment.Status == PaymentSucceeded:
+			change.MaxPropertiesLimit += added
+		case change.Payment.Status == PaymentFailed:
+			change.Status = SlotChangePaymentFailed
+		default:
+			change.Status = SlotChangePaymentPending
+		}
+	}
+	if err != nil {
+		log.Warn("limit increase failed", zap.Int32("user_id", userID), zap.Int32("added_slots", additionalProperties), zap.Error(err))
+		return nil, err
+	}
+
+	log.Info("subscription limit increased",
+		zap.Int32("user_id", userID),
+		zap.Int32("added_slots", additionalProperties),
+		zap.String("status", change.Status),
+		zap.Int32("amount_due_cents", change.AmountDueCents),
+	)
+	return change, nil
+}
+
+// applySlots adds paid extra properties to a subscription and raises its property limit.
+func applySlots(ctx context.Context, q postgres.Querier, sub postgres.Subscription, plan postgres.CatalogItem, quantity int32, today time.Time, invoice *postgres.Invoice) error {
+	slots, err := q.AddSubscriptionItemQuantity(ctx, postgres.AddSubscriptionItemQuantityParams{
+		SubscriptionID: sub.ID,
+		Kind:           subscriptionItemPropertySlot,
+		Quantity:       quantity,
+		UnitPriceCents: plan.SlotPriceCents.Int32,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add extra properties: %w", err)
+	}
+	if err := q.SetSubscriptionPropertyLimit(ctx, postgres.SetSubscriptionPropertyLimitParams{
+		ID:                 sub.ID,
+		MaxPropertiesLimit: pgtype.Int4{Int32: propertyLimit(plan, slots.Quantity), Valid: true},
+	}); err != nil {
+		return err
+	}
+
+	ev := subscriptionEvent(sub, SubscriptionEventSlotsAdded, today)
+	ev.SlotQuantity = pgtype.Int4{Int32: quantity, Valid: true}
+	if invoice != nil {
+		ev.AmountCents = pgtype.Int4{Int32: invoice.AmountCents, Valid: true}
+		ev.InvoiceID = pgtype.Int4{Int32: invoice.ID, Valid: true}
+	}
+	_, err = q.CreateSubscriptionEvent(ctx, ev)
+	return err
+}
+
+// RemoveSlots schedules the removal of extra long-term properties at the period end: they stay usable
+// and are not refunded until then. The owner's long-term properties must fit in the limit left, that of
+// the plan scheduled if a downgrade is.
+func (s *SubscriptionService) RemoveSlots(ctx context.Context, userID int32, quantity int32) (*SubscriptionState, error) {
+	state, err := s.updateSubscription(ctx, userID, func(q postgres.Querier, sub postgres.Subscription) error {
+		slots, err := propertySlots(ctx, q, sub)
+		if err != nil {
+			return err
+		}
+		left := renewalQuantity(slots) - quantity
+		if left < 0 {
+			return ErrNotEnoughSlots
+		}
+
+		plan, err := subscriptionCatalogItem(ctx, q, sub)
+		if err != nil {
+			return err
+		}
+		if sub.ScheduledCatalogItemID.Valid {
+			if plan, err = q.GetCatalogItem(ctx, sub.ScheduledCatalogItemID.Int32); err != nil {
+				return err
+			}
+		}
+		properties, err := q.ListActivePropertiesByOwnerAndType(ctx, postgres.ListActivePropertiesByOwnerAndTypeParams{
+			OwnerID:    sub.UserID,
+			RentalType: postgres.PropertyTypeLongTerm,
+		})
+		if err != nil {
+			return err
+		}
+		if len(properties) > int(propertyLimit(plan, left)) {
+			return ErrSlotsInUse
+		}
+
+		if err := q.ScheduleSubscriptionItemQuantity(ctx, postgres.ScheduleSubscriptionItemQuantityParams{
+			ID:                slots.ID,
+			ScheduledQuantity: pgtype.Int4{Int32: left, Valid: true},
+		}); err != nil {
+			return err
+		}
+		ev := subscriptionEvent(sub, SubscriptionEventSlotsRemovalScheduled, sub.CurrentPeriodEnd.Time)
+		ev.SlotQuantity = pgtype.Int4{Int32: quantity, Valid: true}
+		_, err = q.CreateSubscriptionEvent(ctx, ev)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.FromContext(ctx).Info("extra properties removal scheduled", zap.Int32("user_id", userID), zap.Int32("removed_slots", quantity))
+	return state, nil
+}
+
+// renewSlots applies the extra properties of the period starting at a renewal: a removal scheduled, or
+// all of them if the plan no longer sells them, at the plan's slot price. Long-term properties created
+// since beyond the limit left are archived, latest first. The extra properties are returned as renewed.
+func renewSlots(ctx context.Context, q postgres.Querier, sub postgres.Subscription, plan postgres.CatalogItem, slots postgres.SubscriptionItem, at time.Time) (postgres.SubscriptionItem, error) {
+	if slots.ID == 0 {
+		return slots, nil
+	}
+	quantity, price := keptSlots(slots, plan), slots.UnitPriceCents
+	if plan.SlotPriceCents.Valid {
+		price = plan.SlotPriceCents.Int32
+	}
+	if quantity == slots.Quantity && price == slots.UnitPriceCents && !slots.ScheduledQuantity.Valid {
+		return slots, nil
+	}
+	if err := q.UpdateSubscriptionItem(ctx, postgres.UpdateSubscriptionItemParams{ID: slots.ID, Quantity: quantity, UnitPriceCents: price}); err != nil {
+		return slots, fmt.Errorf("failed to renew extra properties: %w", err)
+	}
+	removed := slots.Quantity - quantity
+	slots.Quantity, slots.UnitPriceCents, slots.ScheduledQuantity = quantity, price, pgtype.Int4{}
+	if removed <= 0 {
+		return slots, nil
+	}
+
+	ev := subscriptionEvent(sub, SubscriptionEventSlotsRemoved, at)
+	ev.SlotQuantity = pgtype.Int4{Int32: removed, Valid: true}
+	// A downgrade applied already set the limit of the new plan
+	if limit := propertyLimit(plan, quantity); sub.MaxPropertiesLimit.Int32 > limit {
+		archived, err := archiveExcessProperties(ctx, q, sub, limit)
+		if err != nil {
+			return slots, err
+		}
+		if err := q.SetSubscriptionPropertyLimit(ctx, postgres.SetSubscriptionPropertyLimitParams{
+			ID:                 sub.ID,
+			MaxPropertiesLimit: pgtype.Int4{Int32: limit, Valid: true},
+		}); err != nil {
+			return slots, err
+		}
+		ev.ArchivedPropertyIds = archived
+	}
+	_, err := q.CreateSubscriptionEvent(ctx, ev)
+	return slots, err
+}
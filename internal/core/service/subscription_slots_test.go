@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"seculoc-back/internal/adapter/storage/postgres"
+)
+
+func expectNoSlots(q *MockQuerier) {
+	q.On("GetSubscriptionItem", mock.Anything, mock.Anything).Return(postgres.SubscriptionItem{}, pgx.ErrNoRows)
+}
+
+func serenitySlots(quantity int32, scheduled pgtype.Int4) postgres.SubscriptionItem {
+	return postgres.SubscriptionItem{ID: 4, SubscriptionID: 8, Kind: subscriptionItemPropertySlot,
+		Quantity: quantity, UnitPriceCents: 990, ScheduledQuantity: scheduled}
+}
+
+func TestIncreaseLimit_KeepsBackScheduledRemovalFirst(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	svc := NewSubscriptionService(passthroughTxManager{q: mockQuerier}, nil, zap.NewNop())
+	sub := monthlySub(catalogSerenity)
+	sub.MaxPropertiesLimit = pgtype.Int4{Int32: 4, Valid: true}
+
+	mockQuerier.On("GetUserSubscriptionForUpdate", mock.Anything, mock.Anything).Return(sub, nil)
+	mockQuerier.On("GetCatalogItem", mock.Anything, catalogSerenity.ID).Return(catalogSerenity, nil)
+	// 3 extra properties, 2 of which are removed at the period end
+	mockQuerier.On("GetSubscriptionItem", mock.Anything, mock.Anything).Return(serenitySlots(3, pgtype.Int4{Int32: 1, Valid: true}), nil)
+	mockQuerier.On("ScheduleSubscriptionItemQuantity", mock.Anything, postgres.ScheduleSubscriptionItemQuantityParams{
+		ID: 4, ScheduledQuantity: pgtype.Int4{Int32: 2, Valid: true},
+	}).Return(nil)
+	mockQuerier.On("CreateSubscriptionEvent", mock.Anything, mock.MatchedBy(func(p postgres.CreateSubscriptionEventParams) bool {
+		return p.EventType == SubscriptionEventSlotsAdded && p.SlotQuantity.Int32 == 1 && !p.InvoiceID.Valid
+	})).Return(postgres.SubscriptionEvent{}, nil)
+
+	change, err := svc.IncreaseLimit(context.Background(), 1, 1, "")
+
+	require.NoError(t, err)
+	assert.Equal(t, SlotChangeAdded, change.Status)
+	assert.Zero(t, change.AmountDueCents, "paid until the period end already")
+	assert.Equal(t, int32(4), change.MaxPropertiesLimit)
+	mockQuerier.AssertExpectations(t)
+	mockQuerier.AssertNotCalled(t, "CreateInvoice", mock.Anything, mock.Anything)
+}
+
+func TestIncreaseLimit_ChargeErrorVoidsInvoice(t *testing.T) {
+	payments, mockQuerier, provider := setupPayments()
+	svc := NewSubscriptionService(passthroughTxManager{q: mockQuerier}, payments, zap.NewNop())
+	sub := monthlySub(catalogSerenity)
+
+	mockQuerier.On("GetUserSubscriptionForUpdate", mock.Anything, mock.Anything).Return(sub, nil)
+	mockQuerier.On("GetCatalogItem", mock.Anything, catalogSerenity.ID).Return(catalogSerenity, nil)
+	expectNoSlots(mockQuerier)
+	invoice := postgres.Invoice{ID: 31, UserID: 1, SubscriptionID: pgtype.Int4{Int32: 8, Valid: true},
+		SlotQuantity: pgtype.Int4{Int32: 1, Valid: true}, AmountCents: 660, Status: "open"}
+	mockQuerier.On("CreateInvoice", mock.Anything, mock.Anything).Return(invoice, nil)
+	expectClaim(mockQuerier, invoice)
+	mockQuerier.On("GetUserById", mock.Anything, int32(1)).Return(payingUser, nil)
+	provider.On("CreatePaymentIntent", mock.Anything, mock.Anything).Return(nil, errors.New("provider unreachable"))
+	// The invoice, committed before the charge, is voided: the limit stays as it was
+	mockQuerier.On("GetSubscriptionForUpdate", mock.Anything, int32(8)).Return(sub, nil)
+	mockQuerier.On("MarkInvoiceFailed", mock.Anything, int32(31)).Return(invoice, nil).Once()
+	mockQuerier.On("VoidInvoice", mock.Anything, int32(31)).Return(nil).Once()
+
+	_, err := svc.IncreaseLimit(context.Background(), 1, 1, "pm_card_visa")
+
+	require.Error(t, err)
+	mockQuerier.AssertExpectations(t)
+	mockQuerier.AssertNotCalled(t, "AddSubscriptionItemQuantity", mock.Anything, mock.Anything)
+}
+
+func TestRemoveSlots_PropertiesMustFit(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	svc := NewSubscriptionService(passthroughTxManager{q: mockQuerier}, nil, zap.NewNop())
+	sub := monthlySub(catalogSerenity)
+	sub.MaxPropertiesLimit = pgtype.Int4{Int32: 3, Valid: true}
+
+	mockQuerier.On("GetUserSubscriptionForUpdate", mock.Anything, mock.Anything).Return(sub, nil)
+	mockQuerier.On("GetCatalogItem", mock.Anything, catalogSerenity.ID).Return(catalogSerenity, nil)
+	mockQuerier.On("GetSubscriptionItem", mock.Anything, mock.Anything).Return(serenitySlots(2, pgtype.Int4{}), nil)
+	mockQuerier.On("ListActivePropertiesByOwnerAndType", mock.Anything, mock.Anything).Return(longTermProperties(13, 12, 11), nil).Once()
+
+	_, err := svc.RemoveSlots(context.Background(), 1, 3)
+	assert.ErrorIs(t, err, ErrNotEnoughSlots)
+
+	// 1 + 1 properties left for 3 long-term properties
+	_, err = svc.RemoveSlots(context.Background(), 1, 1)
+	assert.ErrorIs(t, err, ErrSlotsInUse)
+
+	mockQuerier.On("ListActivePropertiesByOwnerAndType", mock.Anything, mock.Anything).Return(longTermProperties(12, 11), nil)
+	mockQuerier.On("ScheduleSubscriptionItemQuantity", mock.Anything, postgres.ScheduleSubscriptionItemQuantityParams{
+		ID: 4, ScheduledQuantity: pgtype.Int4{Int32: 1, Valid: true},
+	}).Return(nil)
+	mockQuerier.On("CreateSubscriptionEvent", mock.Anything, mock.MatchedBy(func(p postgres.CreateSubscriptionEventParams) bool {
+		return p.EventType == SubscriptionEventSlotsRemovalScheduled && p.SlotQuantity.Int32 == 1 && p.EffectiveDate == sub.CurrentPeriodEnd
+	})).Return(postgres.SubscriptionEvent{}, nil)
+	mockQuerier.On("GetSubscriptionForUpdate", mock.Anything, int32(8)).Return(sub, nil)
+
+	_, err = svc.RemoveSlots(context.Background(), 1, 1)
+
+	require.NoError(t, err)
+	mockQuerier.AssertExpectations(t)
+}
+
+func TestRenewSubscriptions_InvoicesSlotsAndAppliesRemoval(t *testing.T) {
+	svc, mockQuerier, payments, _ := setupBilling()
+	today := dateOf(time.Now())
+	sub := monthlySub(catalogSerenity)
+	sub.CurrentPeriodStart, sub.CurrentPeriodEnd = pgDate(addMonths(today, -1)), pgDate(today)
+	sub.StartDate = sub.CurrentPeriodStart
+	sub.MaxPropertiesLimit = pgtype.Int4{Int32: 3, Valid: true}
+
+	mockQuerier.On("ListSubscriptionsDueForRenewal", mock.Anything, mock.Anything).Return([]int32{8}, nil)
+	mockQuerier.On("GetSubscriptionForUpdate", mock.Anything, int32(8)).Return(sub, nil)
+	mockQuerier.On("GetCatalogItem", mock.Anything, catalogSerenity.ID).Return(catalogSerenity, nil)
+	// 2 extra properties, 1 removed at the period end
+	mockQuerier.On("GetSubscriptionItem", mock.Anything, mock.Anything).Return(serenitySlots(2, pgtype.Int4{Int32: 1, Valid: true}), nil)
+	mockQuerier.On("UpdateSubscriptionItem", mock.Anything, postgres.UpdateSubscriptionItemParams{ID: 4, Quantity: 1, UnitPriceCents: 990}).Return(nil)
+	// A property was created since the removal was scheduled: the latest is archived
+	mockQuerier.On("ListActivePropertiesByOwnerAndType", mock.Anything, mock.Anything).Return(longTermProperties(14, 12, 11), nil)
+	mockQuerier.On("ArchiveProperties", mock.Anything, postgres.ArchivePropertiesParams{OwnerID: sub.UserID, Ids: []int32{14}}).Return(int64(1), nil)
+	mockQuerier.On("SetSubscriptionPropertyLimit", mock.Anything, postgres.SetSubscriptionPropertyLimitParams{
+		ID: 8, MaxPropertiesLimit: pgtype.Int4{Int32: 2, Valid: true},
+	}).Return(nil)
+	mockQuerier.On("CreateSubscriptionEvent", mock.Anything, mock.MatchedBy(func(p postgres.CreateSubscriptionEventParams) bool {
+		return p.EventType == SubscriptionEventSlotsRemoved && p.SlotQuantity.Int32 == 1 &&
+			assert.ObjectsAreEqual([]int32{14}, p.ArchivedPropertyIds)
+	})).Return(postgres.SubscriptionEvent{}, nil)
+
+	// The plan and the extra property left are invoiced together
+	mockQuerier.On("GetInvoiceForPeriod", mock.Anything, mock.Anything).Return(postgres.Invoice{}, pgx.ErrNoRows)
+	invoice := postgres.Invoice{ID: 32, UserID: 1, SubscriptionID: pgtype.Int4{Int32: 8, Valid: true}, AmountCents: 1980, Status: "open"}
+	mockQuerier.On("CreateInvoice", mock.Anything, mock.MatchedBy(func(p postgres.CreateInvoiceParams) bool {
+		return p.AmountCents == 1980 && p.Description == catalogSerenity.Name+" (monthly) + 1 × extra property" && !p.SlotQuantity.Valid
+	})).Return(invoice, nil)
+	payments.On("Collect", mock.Anything, expectClaim(mockQuerier, invoice)).Return(ErrPaymentPending)
+	mockQuerier.On("MarkInvoicePending", mock.Anything, int32(32)).Return(nil)
+
+	require.NoError(t, svc.RenewSubscriptions(context.Background()))
+	mockQuerier.AssertExpectations(t)
+}
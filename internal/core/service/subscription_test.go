@@ -5,9 +5,11 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest/observer"
 
@@ -23,6 +25,9 @@ func TestSubscribeUser_TransactionFailure_LogsError(t *testing.T) {
 	mockQuerier := new(MockQuerier)
 	expectedErr := errors.New("db connection error")
 
+	mockQuerier.On("GetUserSubscription", mock.Anything, pgtype.Int4{Int32: 123, Valid: true}).Return(postgres.Subscription{}, pgx.ErrNoRows)
+	mockQuerier.On("GetActiveCatalogItem", mock.Anything, postgres.GetActiveCatalogItemParams{Kind: CatalogKindPlan, Code: "premium"}).
+		Return(catalogPremium, nil)
 	// We expect CreateSubscription to be called and fail
 	mockQuerier.On("CreateSubscription", mock.Anything, mock.MatchedBy(func(arg postgres.CreateSubscriptionParams) bool {
 		return arg.UserID.Int32 == 123 && arg.PlanType == postgres.SubPlanPremium
@@ -36,7 +41,7 @@ func TestSubscribeUser_TransactionFailure_LogsError(t *testing.T) {
 	})
 
 	// 3. Initialize Service
-	svc := NewSubscriptionService(mockTx, testLogger)
+	svc := NewSubscriptionService(mockTx, NewPaymentService(mockTx, new(mockPaymentProvider), nil, zap.NewNop()), testLogger)
 
 	// 4. Execute
 	ctx := context.Background()
@@ -44,7 +49,7 @@ func TestSubscribeUser_TransactionFailure_LogsError(t *testing.T) {
 	// We assume the service uses logger.FromContext which looks for "logger" key
 	ctx = context.WithValue(ctx, "logger", testLogger)
 
-	err := svc.SubscribeUser(ctx, 123, "premium", "monthly")
+	_, err := svc.SubscribeUser(ctx, 123, "premium", "monthly", "")
 
 	// 5. Assertions
 	assert.ErrorIs(t, err, expectedErr)
@@ -67,16 +72,51 @@ func TestSubscribeUser_Success_LogsInfo(t *testing.T) {
 
 	// 2. Setup Mocks
 	mockQuerier := new(MockQuerier)
+	provider := new(mockPaymentProvider)
 
-	// Expect CreateSubscription
-	mockQuerier.On("CreateSubscription", mock.Anything, mock.MatchedBy(func(arg postgres.CreateSubscriptionParams) bool {
-		return arg.UserID.Int32 == 123 && arg.PlanType == postgres.SubPlanPremium
-	})).Return(postgres.Subscription{ID: 1}, nil)
+	mockQuerier.On("GetUserSubscription", mock.Anything, pgtype.Int4{Int32: 123, Valid: true}).Return(postgres.Subscription{}, pgx.ErrNoRows)
+	mockQuerier.On("GetActiveCatalogItem", mock.Anything, postgres.GetActiveCatalogItemParams{Kind: CatalogKindPlan, Code: "premium"}).
+		Return(catalogPremium, nil)
 
-	// Expect CreateCreditTransaction (since Premium plan has amount > 0)
+	// Expect CreateSubscription, incomplete until the first period is paid
+	mockQuerier.On("CreateSubscription", mock.Anything, mock.MatchedBy(func(arg postgres.CreateSubscriptionParams) bool {
+		return arg.UserID.Int32 == 123 && arg.PlanType == postgres.SubPlanPremium &&
+			arg.MaxPropertiesLimit.Int32 == 5 && arg.CatalogItemID.Int32 == catalogPremium.ID &&
+			arg.Status.String == "incomplete"
+	})).Return(postgres.Subscription{ID: 1, UserID: pgtype.Int4{Int32: 123, Valid: true}}, nil)
+
+	// Expect the first period to be invoiced (since Premium plan has amount > 0) and charged
+	invoice := postgres.Invoice{ID: 7, UserID: 123, SubscriptionID: pgtype.Int4{Int32: 1, Valid: true}, AmountCents: 2990, Status: "open"}
+	mockQuerier.On("CreateInvoice", mock.Anything, mock.MatchedBy(func(arg postgres.CreateInvoiceParams) bool {
+		return arg.UserID == 123 && arg.AmountCents == 2990 && arg.SubscriptionID.Int32 == 1
+	})).Return(invoice, nil)
+	expectClaim(mockQuerier, invoice)
+	mockQuerier.On("GetUserById", mock.Anything, int32(123)).Return(postgres.User{ID: 123, StripeCustomerID: pgtype.Text{String: "cus_1", Valid: true}}, nil)
+	provider.On("CreatePaymentIntent", mock.Anything, mock.MatchedBy(func(req PaymentIntentRequest) bool {
+		return req.CustomerID == "cus_1" && req.AmountCents == 2990 && req.PaymentMethodID == "pm_card_visa"
+	})).Return(&PaymentIntent{ID: "pi_1", Status: PaymentSucceeded}, nil)
+	mockQuerier.On("CreatePaymentTransaction", mock.Anything, mock.MatchedBy(func(arg postgres.CreatePaymentTransactionParams) bool {
+		return arg.RelatedEntityType.String == "invoice" && arg.RelatedEntityID.Int32 == 7 && arg.Status.String == "success"
+	})).Return(postgres.Transaction{ID: 4}, nil)
+
+	// Paid: the subscription is activated with its first month of included credits
+	mockQuerier.On("GetSubscriptionForUpdate", mock.Anything, int32(1)).Return(postgres.Subscription{
+		ID: 1, UserID: pgtype.Int4{Int32: 123, Valid: true}, Status: pgtype.Text{String: "incomplete", Valid: true},
+		CatalogItemID: pgtype.Int4{Int32: catalogPremium.ID, Valid: true},
+	}, nil)
+	mockQuerier.On("MarkInvoicePaid", mock.Anything, int32(7)).Return(postgres.Invoice{ID: 7, UserID: 123, AmountCents: 2990, Status: "paid"}, nil)
+	expectInvoiceIssued(mockQuerier, invoiceSeries, 7, 1)
+	mockQuerier.On("GetCatalogItem", mock.Anything, catalogPremium.ID).Return(catalogPremium, nil)
+	mockQuerier.On("SetSubscriptionStatus", mock.Anything, postgres.SetSubscriptionStatusParams{ID: 1, Status: pgtype.Text{String: "active", Valid: true}}).Return(nil)
+	mockQuerier.On("CreateSubscriptionEvent", mock.Anything, mock.MatchedBy(func(arg postgres.CreateSubscriptionEventParams) bool {
+		return arg.SubscriptionID == 1 && arg.EventType == SubscriptionEventSubscribed && arg.ToCatalogItemID.Int32 == catalogPremium.ID
+	})).Return(postgres.SubscriptionEvent{}, nil)
 	mockQuerier.On("CreateCreditTransaction", mock.Anything, mock.MatchedBy(func(arg postgres.CreateCreditTransactionParams) bool {
-		return arg.UserID.Int32 == 123 && arg.Amount == 2990 && arg.TransactionType == "plan_purchase"
+		return arg.UserID.Int32 == 123 && arg.Amount == 30 && arg.TransactionType == "plan_renewal"
 	})).Return(postgres.CreditTransaction{ID: 1}, nil)
+	mockQuerier.On("RecordSubscriptionCreditGrant", mock.Anything, mock.MatchedBy(func(arg postgres.RecordSubscriptionCreditGrantParams) bool {
+		return arg.ID == 1 && arg.GrantedCredits == 30
+	})).Return(nil)
 
 	// WithTx mock
 	mockTx := new(MockTxManager)
@@ -86,16 +126,17 @@ func TestSubscribeUser_Success_LogsInfo(t *testing.T) {
 	})
 
 	// 3. Initialize Service
-	svc := NewSubscriptionService(mockTx, testLogger)
+	svc := NewSubscriptionService(mockTx, NewPaymentService(mockTx, provider, nil, zap.NewNop()), testLogger)
 
 	// 4. Execute
 	ctx := context.Background()
 	ctx = context.WithValue(ctx, "logger", testLogger)
 
-	err := svc.SubscribeUser(ctx, 123, "premium", "monthly")
+	payment, err := svc.SubscribeUser(ctx, 123, "premium", "monthly", "pm_card_visa")
 
 	// 5. Assertions
 	assert.NoError(t, err)
+	assert.Equal(t, PaymentSucceeded, payment.Status)
 
 	// Verify Log
 	logs := observedLogs.FilterMessage("subscription created")
@@ -104,48 +145,65 @@ func TestSubscribeUser_Success_LogsInfo(t *testing.T) {
 	fieldMap := logs.All()[0].ContextMap()
 	assert.Equal(t, int64(123), fieldMap["user_id"])
 	assert.Equal(t, "premium", fieldMap["plan"])
+	assert.Equal(t, PaymentSucceeded, fieldMap["payment_status"])
+	mockQuerier.AssertExpectations(t)
 }
 
 func TestIncreaseLimit_Success(t *testing.T) {
 	// Setup
-	mockTx := new(MockTxManager)
-	mockQuerier := new(MockQuerier)
-	svc := NewSubscriptionService(mockTx, zap.NewNop())
+	payments, mockQuerier, provider := setupPayments()
+	svc := NewSubscriptionService(passthroughTxManager{q: mockQuerier}, payments, zap.NewNop())
 	ctx := context.Background()
-	userID := int32(1)
-
-	// Mock 1: Get Subscription (Must be Serenity or Premium)
-	activeSub := postgres.Subscription{
-		ID:       1,
-		UserID:   pgtype.Int4{Int32: userID, Valid: true},
-		PlanType: postgres.SubPlanSerenity,
-		Status:   pgtype.Text{String: "active", Valid: true},
-	}
-	mockQuerier.On("GetUserSubscription", mock.Anything, pgtype.Int4{Int32: userID, Valid: true}).Return(activeSub, nil)
-
-	// Mock 2: Update Limit
-	mockQuerier.On("UpdateSubscriptionLimit", mock.Anything, mock.MatchedBy(func(arg postgres.UpdateSubscriptionLimitParams) bool {
-		return arg.UserID.Int32 == userID && arg.MaxPropertiesLimit.Int32 == 1
-	})).Return(nil)
-
-	// WithTx
-	mockTx.On("WithTx", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
-		fn := args.Get(1).(func(postgres.Querier) error)
-		_ = fn(mockQuerier)
-	})
+	sub := monthlySub(catalogSerenity)
+
+	// Mock 1: Get Subscription (Must be Serenity or Premium), without extra properties yet
+	mockQuerier.On("GetUserSubscriptionForUpdate", mock.Anything, pgtype.Int4{Int32: 1, Valid: true}).Return(sub, nil)
+	mockQuerier.On("GetCatalogItem", mock.Anything, catalogSerenity.ID).Return(catalogSerenity, nil)
+	mockQuerier.On("GetSubscriptionItem", mock.Anything, postgres.GetSubscriptionItemParams{SubscriptionID: 8, Kind: "property_slot"}).
+		Return(postgres.SubscriptionItem{}, pgx.ErrNoRows)
+
+	// Mock 2: 2 slots at 9.90€ for the 20 days left out of 30, invoiced and paid
+	invoice := postgres.Invoice{ID: 31, UserID: 1, SubscriptionID: pgtype.Int4{Int32: 8, Valid: true},
+		SlotQuantity: pgtype.Int4{Int32: 2, Valid: true}, AmountCents: 1320, Status: "open"}
+	mockQuerier.On("CreateInvoice", mock.Anything, mock.MatchedBy(func(p postgres.CreateInvoiceParams) bool {
+		return p.SubscriptionID.Int32 == 8 && p.SlotQuantity.Int32 == 2 && p.AmountCents == 1320 && !p.PeriodStart.Valid
+	})).Return(invoice, nil)
+	// Committed, then charged
+	expectClaim(mockQuerier, invoice)
+	mockQuerier.On("GetUserById", mock.Anything, int32(1)).Return(payingUser, nil)
+	provider.On("CreatePaymentIntent", mock.Anything, mock.Anything).Return(&PaymentIntent{ID: "pi_1", Status: PaymentSucceeded}, nil)
+	mockQuerier.On("CreatePaymentTransaction", mock.Anything, mock.Anything).Return(postgres.Transaction{ID: 9}, nil)
+	mockQuerier.On("GetSubscriptionForUpdate", mock.Anything, int32(8)).Return(sub, nil)
+	mockQuerier.On("MarkInvoicePaid", mock.Anything, int32(31)).Return(invoice, nil)
+	expectInvoiceIssued(mockQuerier, invoiceSeries, 31, 1)
+
+	// Mock 3: Slots added on top of the plan's property
+	mockQuerier.On("AddSubscriptionItemQuantity", mock.Anything, postgres.AddSubscriptionItemQuantityParams{
+		SubscriptionID: 8, Kind: "property_slot", Quantity: 2, UnitPriceCents: 990,
+	}).Return(postgres.SubscriptionItem{ID: 4, SubscriptionID: 8, Kind: "property_slot", Quantity: 2, UnitPriceCents: 990}, nil)
+	mockQuerier.On("SetSubscriptionPropertyLimit", mock.Anything, postgres.SetSubscriptionPropertyLimitParams{
+		ID: 8, MaxPropertiesLimit: pgtype.Int4{Int32: 3, Valid: true},
+	}).Return(nil)
+	mockQuerier.On("CreateSubscriptionEvent", mock.Anything, mock.MatchedBy(func(p postgres.CreateSubscriptionEventParams) bool {
+		return p.EventType == SubscriptionEventSlotsAdded && p.SlotQuantity.Int32 == 2 && p.InvoiceID.Int32 == 31
+	})).Return(postgres.SubscriptionEvent{}, nil)
 
 	// Execute
-	err := svc.IncreaseLimit(ctx, userID, 1) // Add 1 slot
+	change, err := svc.IncreaseLimit(ctx, 1, 2, "pm_card_visa")
 
 	// Assert
-	assert.NoError(t, err)
+	require.NoError(t, err)
+	assert.Equal(t, SlotChangeAdded, change.Status)
+	assert.Equal(t, int32(1320), change.AmountDueCents)
+	assert.Equal(t, int32(3), change.MaxPropertiesLimit)
+	mockQuerier.AssertExpectations(t)
 }
 
 func TestIncreaseLimit_NotEligible(t *testing.T) {
 	// Setup
 	mockTx := new(MockTxManager)
 	mockQuerier := new(MockQuerier)
-	svc := NewSubscriptionService(mockTx, zap.NewNop())
+	svc := NewSubscriptionService(mockTx, nil, zap.NewNop())
 	ctx := context.Background()
 	userID := int32(1)
 
@@ -156,7 +214,9 @@ func TestIncreaseLimit_NotEligible(t *testing.T) {
 		PlanType: postgres.SubPlanDiscovery,
 		Status:   pgtype.Text{String: "active", Valid: true},
 	}
-	mockQuerier.On("GetUserSubscription", mock.Anything, pgtype.Int4{Int32: userID, Valid: true}).Return(activeSub, nil)
+	mockQuerier.On("GetUserSubscriptionForUpdate", mock.Anything, pgtype.Int4{Int32: userID, Valid: true}).Return(activeSub, nil)
+	mockQuerier.On("GetActiveCatalogItem", mock.Anything, postgres.GetActiveCatalogItemParams{Kind: CatalogKindPlan, Code: "discovery"}).
+		Return(catalogDiscovery, nil)
 
 	// WithTx
 	mockTx.On("WithTx", mock.Anything, mock.Anything).Return(errors.New("plan not eligible")).Run(func(args mock.Arguments) {
@@ -165,7 +225,7 @@ func TestIncreaseLimit_NotEligible(t *testing.T) {
 		_ = fn(mockQuerier)
 	})
 
-	err := svc.IncreaseLimit(ctx, userID, 1)
+	_, err := svc.IncreaseLimit(ctx, userID, 1, "")
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "plan not eligible")
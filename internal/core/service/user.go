@@ -35,6 +35,11 @@ type SubscriptionDTO struct {
 	StartDate          string `json:"start_date,omitempty"`
 	EndDate            string `json:"end_date,omitempty"`
 	MaxPropertiesLimit int32  `json:"max_properties_limit"`
+	CurrentPeriodEnd   string `json:"current_period_end,omitempty"`
+	// CancelAtPeriodEnd: the subscription ends at CurrentPeriodEnd instead of renewing
+	CancelAtPeriodEnd bool `json:"cancel_at_period_end"`
+	// PropertySlots are the long-term properties bought on top of the plan, counted in MaxPropertiesLimit
+	PropertySlots *PropertySlots `json:"property_slots,omitempty"`
 }
 
 type UserProfile struct {
@@ -51,6 +56,7 @@ type AuthResponse struct {
 
 type LeaseGenerator interface {
 	GenerateAndSave(ctx context.Context, leaseID int32, userID int32) error
+	GenerateGuaranteeDeeds(ctx context.Context, leaseID, tenantID int32) (int, error)
 }
 
 type UserService struct {
@@ -276,6 +282,7 @@ func (s *UserService) GetFullAuthResponse(ctx context.Context, user *postgres.Us
 	var caps Capabilities
 	var currentContext UserContext = ContextNone
 	var subscription postgres.Subscription
+	var slots postgres.SubscriptionItem
 	var creditBalance int32
 
 	err := s.txManager.WithTx(ctx, func(q postgres.Querier) error {
@@ -302,9 +309,14 @@ func (s *UserService) GetFullAuthResponse(ctx context.Context, user *postgres.Us
 		if err != nil && err != pgx.ErrNoRows {
 			log.Warn("failed to fetch subscription", zap.Error(err))
 		}
+		if subscription.ID != 0 {
+			if slots, err = propertySlots(ctx, q, subscription); err != nil {
+				log.Warn("failed to fetch extra properties", zap.Error(err))
+			}
+		}
 
 		// Fetch Credit Balance
-		balance, err := q.GetUserCreditBalance(ctx, pgtype.Int4{Int32: user.ID, Valid: true})
+		balance, err := q.GetUserCreditBalance(ctx, user.ID)
 		if err != nil && err != pgx.ErrNoRows {
 			log.Warn("failed to fetch credit balance", zap.Error(err))
 		}
@@ -352,6 +364,8 @@ func (s *UserService) GetFullAuthResponse(ctx context.Context, user *postgres.Us
 					PlanType:           string(subscription.PlanType),
 					Status:             subscription.Status.String,
 					MaxPropertiesLimit: subscription.MaxPropertiesLimit.Int32,
+					CancelAtPeriodEnd:  subscription.CancelAtPeriodEnd,
+					PropertySlots:      propertySlotsDTO(slots),
 				}
 				if subscription.Frequency.Valid {
 					dto.Frequency = string(subscription.Frequency.BillingFreq)
@@ -362,6 +376,9 @@ func (s *UserService) GetFullAuthResponse(ctx context.Context, user *postgres.Us
 				if subscription.EndDate.Valid {
 					dto.EndDate = subscription.EndDate.Time.String()
 				}
+				if subscription.CurrentPeriodEnd.Valid {
+					dto.CurrentPeriodEnd = subscription.CurrentPeriodEnd.Time.Format("2006-01-02")
+				}
 				return dto
 			}(),
 			CreditBalance: creditBalance,
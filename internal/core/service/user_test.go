@@ -119,7 +119,7 @@ func TestLogin_Success(t *testing.T) {
 	mockQuerier.On("CountLeasesByTenant", mock.Anything, mock.AnythingOfType("pgtype.Int4")).Return(int64(0), nil)
 	mockQuerier.On("CountBookingsByTenant", mock.Anything, mock.AnythingOfType("pgtype.Int4")).Return(int64(0), nil)
 	mockQuerier.On("GetUserSubscription", mock.Anything, mock.AnythingOfType("pgtype.Int4")).Return(postgres.Subscription{}, pgx.ErrNoRows) // No subscription
-	mockQuerier.On("GetUserCreditBalance", mock.Anything, mock.AnythingOfType("pgtype.Int4")).Return(int32(10), nil)                        // 10 credits
+	mockQuerier.On("GetUserCreditBalance", mock.Anything, int32(1)).Return(int32(10), nil)                                                  // 10 credits
 
 	// Execute
 	resp, err := svc.Login(context.Background(), "test@example.com", "password123")
@@ -172,7 +172,7 @@ func TestLogin_InvalidCredentials(t *testing.T) {
 	mockQuerier3.On("CountLeasesByTenant", mock.Anything, pgtype.Int4{Int32: 1, Valid: true}).Return(int64(0), nil)
 	mockQuerier3.On("CountBookingsByTenant", mock.Anything, pgtype.Int4{Int32: 1, Valid: true}).Return(int64(0), nil)
 	mockQuerier3.On("GetUserSubscription", mock.Anything, mock.AnythingOfType("pgtype.Int4")).Return(postgres.Subscription{}, pgx.ErrNoRows)
-	mockQuerier3.On("GetUserCreditBalance", mock.Anything, mock.AnythingOfType("pgtype.Int4")).Return(int32(0), nil)
+	mockQuerier3.On("GetUserCreditBalance", mock.Anything, mock.AnythingOfType("int32")).Return(int32(0), nil)
 
 	_, err = svc3.Login(context.Background(), "test@example.com", "wrongpassword")
 	assert.Error(t, err)
@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+
+	"github.com/spf13/viper"
+)
+
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// linkSecret is DOCUMENT_LINK_SECRET. It is never shared with JWT_SECRET: rotating one must not
+// invalidate, or forge, the other.
+func linkSecret() ([]byte, error) {
+	secret := viper.GetString("DOCUMENT_LINK_SECRET")
+	if secret == "" {
+		return nil, errors.New("DOCUMENT_LINK_SECRET not setup")
+	}
+	return []byte(secret), nil
+}
+
+// SignLink returns the URL-safe HMAC-SHA256 signature of payload.
+func SignLink(payload string) (string, error) {
+	secret, err := linkSecret()
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// VerifyLink checks signature against payload in constant time.
+func VerifyLink(payload, signature string) error {
+	expected, err := SignLink(payload)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignLink_RoundTrip(t *testing.T) {
+	viper.Set("DOCUMENT_LINK_SECRET", "link-secret")
+	defer viper.Set("DOCUMENT_LINK_SECRET", "")
+
+	sig, err := SignLink("1:2:3:1700000000")
+	assert.NoError(t, err)
+	assert.NoError(t, VerifyLink("1:2:3:1700000000", sig))
+
+	// Any change of the payload (other user, later expiry...) invalidates the signature
+	assert.ErrorIs(t, VerifyLink("1:2:4:1700000000", sig), ErrInvalidSignature)
+	assert.ErrorIs(t, VerifyLink("1:2:3:1800000000", sig), ErrInvalidSignature)
+}
+
+func TestSignLink_RequiresItsOwnSecret(t *testing.T) {
+	viper.Set("DOCUMENT_LINK_SECRET", "")
+	viper.Set("JWT_SECRET", "supersecret")
+	defer viper.Set("JWT_SECRET", "")
+
+	_, err := SignLink("payload")
+	assert.Error(t, err, "JWT_SECRET must not sign document links")
+}
@@ -12,13 +12,14 @@ type Claims struct {
 	UserID         int32  `json:"user_id"`
 	Email          string `json:"email"`
 	CurrentContext string `json:"current_context,omitempty"`
+	Role           string `json:"role,omitempty"` // "admin" for platform administrators
 	jwt.RegisteredClaims
 }
 
 var ErrInvalidToken = errors.New("invalid token")
 
-// GenerateToken generates a JWT token for the user with context.
-func GenerateToken(userID int32, email, currentContext string) (string, error) {
+// GenerateToken generates a JWT token for the user with context and system role.
+func GenerateToken(userID int32, email, currentContext, role string) (string, error) {
 	secret := viper.GetString("JWT_SECRET")
 	if secret == "" {
 		return "", errors.New("JWT_SECRET not setup")
@@ -34,6 +35,7 @@ func GenerateToken(userID int32, email, currentContext string) (string, error) {
 		UserID:         userID,
 		Email:          email,
 		CurrentContext: currentContext,
+		Role:           role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
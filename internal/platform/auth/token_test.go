@@ -14,7 +14,7 @@ func TestToken_Success(t *testing.T) {
 	viper.Set("JWT_EXPIRATION_HOURS", 1)
 
 	// 1. Generate
-	token, err := GenerateToken(123, "test@example.com", "owner")
+	token, err := GenerateToken(123, "test@example.com", "owner", "user")
 	assert.NoError(t, err)
 	assert.NotEmpty(t, token)
 
@@ -25,12 +25,13 @@ func TestToken_Success(t *testing.T) {
 	assert.Equal(t, int32(123), claims.UserID)
 	assert.Equal(t, "test@example.com", claims.Email)
 	assert.Equal(t, "owner", claims.CurrentContext)
+	assert.Equal(t, "user", claims.Role)
 }
 
 func TestToken_MissingSecret(t *testing.T) {
 	viper.Set("JWT_SECRET", "")
 
-	_, err := GenerateToken(1, "mail", "ctx")
+	_, err := GenerateToken(1, "mail", "ctx", "")
 	assert.Error(t, err)
 
 	_, err = ValidateToken("some.token")
@@ -57,7 +58,7 @@ func TestToken_Expired(t *testing.T) {
 
 func TestToken_InvalidSignature(t *testing.T) {
 	viper.Set("JWT_SECRET", "secret")
-	token, _ := GenerateToken(1, "mail", "ctx")
+	token, _ := GenerateToken(1, "mail", "ctx", "")
 
 	viper.Set("JWT_SECRET", "wrongcheck")
 	_, err := ValidateToken(token)
@@ -9,6 +9,7 @@ import (
 
 type EmailSender interface {
 	SendInvitation(ctx context.Context, toEmail, link string) error
+	SendNotification(ctx context.Context, toEmail, subject, body string) error
 }
 
 type MockEmailSender struct {
@@ -27,3 +28,13 @@ func (m *MockEmailSender) SendInvitation(ctx context.Context, toEmail, link stri
 	m.logger.Info("---------------------------------------------------")
 	return nil
 }
+
+// SendNotification sends a plain transactional email (reminders, status changes...).
+func (m *MockEmailSender) SendNotification(ctx context.Context, toEmail, subject, body string) error {
+	m.logger.Info("📧 MOCK EMAIL SENT 📧")
+	m.logger.Info(fmt.Sprintf("To: %s", toEmail))
+	m.logger.Info(fmt.Sprintf("Subject: %s", subject))
+	m.logger.Info(body)
+	m.logger.Info("---------------------------------------------------")
+	return nil
+}
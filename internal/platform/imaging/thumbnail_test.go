@@ -0,0 +1,50 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func makePNG(t *testing.T, w, h int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: 200, G: 100, B: 50, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestThumbnail_DownscalesKeepingRatio(t *testing.T) {
+	thumb, err := Thumbnail(makePNG(t, 800, 400), 200)
+	require.NoError(t, err)
+
+	img, err := jpeg.Decode(bytes.NewReader(thumb))
+	require.NoError(t, err)
+	assert.Equal(t, 200, img.Bounds().Dx())
+	assert.Equal(t, 100, img.Bounds().Dy())
+}
+
+func TestThumbnail_DoesNotUpscale(t *testing.T) {
+	thumb, err := Thumbnail(makePNG(t, 50, 80), 200)
+	require.NoError(t, err)
+
+	img, err := jpeg.Decode(bytes.NewReader(thumb))
+	require.NoError(t, err)
+	assert.Equal(t, 50, img.Bounds().Dx())
+	assert.Equal(t, 80, img.Bounds().Dy())
+}
+
+func TestThumbnail_InvalidImage(t *testing.T) {
+	_, err := Thumbnail([]byte("not an image"), 200)
+	assert.Error(t, err)
+}
@@ -0,0 +1,94 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// JobFunc is a unit of background work. Errors are logged, the job keeps its schedule.
+type JobFunc func(ctx context.Context) error
+
+type job struct {
+	name     string
+	interval time.Duration
+	fn       JobFunc
+}
+
+// Locker keeps a job from running on several instances at once. TryLock reports whether the lock named
+// after the job was acquired; release must then be called once the run is over.
+type Locker interface {
+	TryLock(ctx context.Context, name string) (release func(), acquired bool, err error)
+}
+
+// Scheduler runs registered jobs at a fixed interval in their own goroutine.
+type Scheduler struct {
+	log  *zap.Logger
+	jobs []job
+
+	// Locker, when set, makes each run of a job exclusive across instances: a run finding the job
+	// already running elsewhere is skipped.
+	Locker Locker
+}
+
+func New(log *zap.Logger) *Scheduler {
+	return &Scheduler{log: log}
+}
+
+// Register adds a job. It must be called before Start.
+func (s *Scheduler) Register(name string, interval time.Duration, fn JobFunc) {
+	s.jobs = append(s.jobs, job{name: name, interval: interval, fn: fn})
+}
+
+// Start launches every registered job: each runs once immediately, then at its interval.
+// Jobs stop when ctx is cancelled.
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, j := range s.jobs {
+		go s.loop(ctx, j)
+	}
+}
+
+func (s *Scheduler) loop(ctx context.Context, j job) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	s.runOnce(ctx, j)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.runOnce(ctx, j)
+		}
+	}
+}
+
+func (s *Scheduler) runOnce(ctx context.Context, j job) {
+	// A panicking job must not take the whole server down.
+	defer func() {
+		if p := recover(); p != nil {
+			s.log.Error("scheduled job panicked", zap.String("job", j.name), zap.Any("panic", p))
+		}
+	}()
+
+	if s.Locker != nil {
+		release, acquired, err := s.Locker.TryLock(ctx, j.name)
+		if err != nil {
+			s.log.Error("scheduled job lock failed", zap.String("job", j.name), zap.Error(err))
+			return
+		}
+		if !acquired {
+			s.log.Debug("scheduled job skipped: running on another instance", zap.String("job", j.name))
+			return
+		}
+		defer release()
+	}
+
+	start := time.Now()
+	if err := j.fn(ctx); err != nil {
+		s.log.Error("scheduled job failed", zap.String("job", j.name), zap.Error(err))
+		return
+	}
+	s.log.Debug("scheduled job completed", zap.String("job", j.name), zap.Duration("duration", time.Since(start)))
+}
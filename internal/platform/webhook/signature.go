@@ -0,0 +1,84 @@
+// Package webhook signs and verifies provider webhooks.
+//
+// The signature header has the form "t=<unix timestamp>,v1=<hex HMAC-SHA256>", the HMAC covering
+// "<timestamp>.<raw body>". Binding the timestamp to the body lets receivers reject old deliveries;
+// together with the event ID deduplication done by the caller, this prevents replays.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTolerance is how old (or how far in the future) a delivery may be.
+const DefaultTolerance = 5 * time.Minute
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrExpired          = errors.New("webhook timestamp outside tolerance")
+)
+
+func mac(secret []byte, timestamp int64, payload []byte) string {
+	h := hmac.New(sha256.New, secret)
+	fmt.Fprintf(h, "%d.", timestamp)
+	h.Write(payload)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Sign returns the signature header value for payload sent at t.
+func Sign(secret []byte, t time.Time, payload []byte) string {
+	ts := t.Unix()
+	return fmt.Sprintf("t=%d,v1=%s", ts, mac(secret, ts, payload))
+}
+
+// Verify checks header against payload and rejects deliveries older than tolerance.
+// Several v1 entries are accepted so a provider can sign with two secrets during a rotation.
+func Verify(secret []byte, header string, payload []byte, tolerance time.Duration, now time.Time) error {
+	if len(secret) == 0 {
+		return errors.New("webhook secret not configured")
+	}
+
+	var ts int64
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			parsed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return ErrInvalidSignature
+			}
+			ts = parsed
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if ts == 0 || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+
+	expected := mac(secret, ts, payload)
+	valid := false
+	for _, sig := range signatures {
+		if hmac.Equal([]byte(expected), []byte(sig)) {
+			valid = true
+		}
+	}
+	if !valid {
+		return ErrInvalidSignature
+	}
+
+	if age := now.Sub(time.Unix(ts, 0)); age > tolerance || age < -tolerance {
+		return ErrExpired
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignVerify(t *testing.T) {
+	secret := []byte("whsec")
+	now := time.Unix(1700000000, 0)
+	payload := []byte(`{"id":"evt_1"}`)
+	header := Sign(secret, now, payload)
+
+	assert.NoError(t, Verify(secret, header, payload, DefaultTolerance, now.Add(time.Minute)))
+
+	assert.ErrorIs(t, Verify(secret, header, []byte(`{"id":"evt_2"}`), DefaultTolerance, now), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify([]byte("other"), header, payload, DefaultTolerance, now), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify(secret, header, payload, DefaultTolerance, now.Add(10*time.Minute)), ErrExpired)
+	assert.ErrorIs(t, Verify(secret, "v1=abc", payload, DefaultTolerance, now), ErrInvalidSignature)
+
+	// Re-signing an old body with a fresh timestamp requires the secret
+	forged := "t=" + "1700000600" + header[len("t=1700000000"):]
+	assert.ErrorIs(t, Verify(secret, forged, payload, DefaultTolerance, now.Add(10*time.Minute)), ErrInvalidSignature)
+
+	// Rotation: a second signature made with the new secret is accepted
+	rotated := header + ",v1=" + mac([]byte("new"), now.Unix(), payload)
+	assert.NoError(t, Verify([]byte("new"), rotated, payload, DefaultTolerance, now))
+}
@@ -0,0 +1,56 @@
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestE2E_DeleteAccount(t *testing.T) {
+	ownerEmail := getEmail()
+
+	registerAndLogin(t, ownerEmail, "Owner", "Leaving")
+	ownerToken := login(t, ownerEmail, "password123")
+	w := performRequest(router, "POST", "/api/v1/subscriptions", ownerToken, map[string]string{
+		"plan": "discovery", "frequency": "monthly",
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+	ownerToken = login(t, ownerEmail, "password123")
+	propID := createLongTermProperty(t, ownerToken, "1 rue du Départ")
+
+	// A draft lease nobody signed does not prevent the deletion
+	w = performRequest(router, "POST", "/api/v1/leases/draft", ownerToken, map[string]interface{}{
+		"property_id": propID,
+		"tenant_info": map[string]string{"first_name": "Jean", "last_name": "Draft", "email": getEmail()},
+		"terms": map[string]interface{}{
+			"start_date": "2026-03-01", "rent_amount": 800.0, "charges_amount": 50.0, "deposit_amount": 800.0, "payment_day": 5,
+		},
+		"clauses": []string{},
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	// A check still waiting for its candidate is cancelled with the account
+	w = performRequest(router, "POST", "/api/v1/solvency/check", ownerToken, map[string]interface{}{
+		"property_id": propID, "candidate_email": "cand_" + randomString() + "@example.com",
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var checkResp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &checkResp)
+	checkToken := checkResp["token"].(string)
+
+	w = performRequest(router, "DELETE", "/api/v1/me", ownerToken, nil)
+	require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+
+	var status string
+	err := pool.QueryRow(context.Background(), `SELECT status FROM solvency_checks WHERE token = $1`, checkToken).Scan(&status)
+	require.NoError(t, err)
+	assert.Equal(t, "cancelled", status)
+
+	// The token issued before the deletion is refused
+	w = performRequest(router, "GET", "/api/v1/me/export", ownerToken, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
@@ -0,0 +1,222 @@
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	fakepayment "seculoc-back/internal/adapter/payment/fake"
+	"seculoc-back/internal/adapter/storage/postgres"
+	"seculoc-back/internal/core/service"
+)
+
+func listCreditTransactions(t *testing.T, token, query string) service.CreditTransactionPage {
+	w := performRequest(router, "GET", "/api/v1/me/credits/transactions"+query, token, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var page service.CreditTransactionPage
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+	return page
+}
+
+// setPropertyCredits brings the wallet of a property to credits, with an entry against the system account.
+func setPropertyCredits(t *testing.T, propID int32, credits int) {
+	_, err := pool.Exec(context.Background(), `
+		WITH entry AS (SELECT nextval('credit_entry_seq') AS id),
+		wallet AS (SELECT id, $2::int - balance AS delta FROM credit_accounts WHERE property_id = $1)
+		INSERT INTO credit_transactions (entry_id, account_id, amount, transaction_type, description)
+		SELECT entry.id, wallet.id, wallet.delta, 'opening_balance', 'Test adjustment' FROM entry, wallet
+		UNION ALL
+		SELECT entry.id, s.id, -wallet.delta, 'opening_balance', 'Test adjustment'
+		FROM entry, wallet, credit_accounts s WHERE s.kind = 'system'`, propID, credits)
+	require.NoError(t, err)
+}
+
+func TestE2E_CreditHistory(t *testing.T) {
+	ownerEmail := getEmail()
+	token := registerAndLogin(t, ownerEmail, "Ledger", "Owner")
+	w := performRequest(router, "POST", "/api/v1/subscriptions", token, map[string]string{"plan": "discovery", "frequency": "monthly"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	// First long-term property: 20 vacancy credits on its wallet, 3 welcome credits on the global one
+	propID := createLongTermProperty(t, token, "1 rue du Registre")
+
+	// The check takes a vacancy credit of the property, the cancellation gives it back
+	w = performRequest(router, "POST", "/api/v1/solvency/check", token, map[string]interface{}{
+		"property_id": propID, "candidate_email": "ledger_" + randomString() + "@example.com",
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var check struct {
+		ID int32 `json:"id"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &check))
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/solvency/check/%d/cancel", check.ID), token, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	page := listCreditTransactions(t, token, "")
+	assert.Equal(t, int64(4), page.Total)
+	require.Len(t, page.Transactions, 4)
+	refund, usage, bonus, grant := page.Transactions[0], page.Transactions[1], page.Transactions[2], page.Transactions[3]
+	assert.Equal(t, "refund", refund.Type)
+	assert.Equal(t, service.CreditWalletProperty, refund.Wallet)
+	assert.Equal(t, propID, *refund.PropertyID)
+	assert.Equal(t, int32(20), refund.BalanceAfter)
+	assert.Equal(t, "check_usage", usage.Type)
+	assert.Equal(t, int32(19), usage.BalanceAfter)
+	assert.Equal(t, "initial_free", bonus.Type)
+	assert.Equal(t, service.CreditWalletGlobal, bonus.Wallet)
+	assert.Equal(t, int32(3), bonus.BalanceAfter)
+	assert.Equal(t, "property_grant", grant.Type)
+	assert.Equal(t, propID, *grant.PropertyID)
+	assert.Equal(t, int32(20), grant.BalanceAfter)
+
+	// The check points at the line of the wallet it consumed
+	var usedLine int32
+	require.NoError(t, pool.QueryRow(context.Background(),
+		`SELECT credit_transaction_id FROM solvency_checks WHERE id = $1`, check.ID).Scan(&usedLine))
+	assert.Equal(t, usage.ID, usedLine)
+
+	// Property movements do not count in the global balance
+	var balance int
+	require.NoError(t, pool.QueryRow(context.Background(), `
+		SELECT a.balance FROM credit_accounts a JOIN users u ON u.id = a.user_id
+		WHERE a.kind = 'global' AND u.email = $1`, ownerEmail).Scan(&balance))
+	assert.Equal(t, 3, balance)
+	assert.Equal(t, 3, creditBalance(t, ownerEmail))
+
+	filtered := listCreditTransactions(t, token, "?type=check_usage&page_size=1")
+	assert.Equal(t, int64(1), filtered.Total)
+	require.Len(t, filtered.Transactions, 1)
+	assert.Equal(t, usage.ID, filtered.Transactions[0].ID)
+	assert.Empty(t, listCreditTransactions(t, token, "?page=2").Transactions)
+
+	w = performRequest(router, "GET", "/api/v1/me/credits/transactions?type=bonus", token, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestE2E_CreditBalanceDrift(t *testing.T) {
+	ownerEmail := getEmail()
+	token := registerAndLogin(t, ownerEmail, "Drift", "Owner")
+	w := performRequest(router, "POST", "/api/v1/subscriptions", token, map[string]string{"plan": "discovery", "frequency": "monthly"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	createLongTermProperty(t, token, "2 rue du Registre")
+
+	// A balance changed outside the ledger
+	setBalance := `UPDATE credit_accounts a SET balance = $2 FROM users u WHERE u.id = a.user_id AND a.kind = 'global' AND u.email = $1`
+	_, err := pool.Exec(context.Background(), setBalance, ownerEmail, 50)
+	require.NoError(t, err)
+	defer pool.Exec(context.Background(), setBalance, ownerEmail, 3)
+
+	credits := service.NewCreditService(postgres.NewTxManager(pool), zap.NewNop())
+	assert.ErrorContains(t, credits.ReconcileBalances(context.Background()), "drifted")
+
+	// No entry is written on top of a drifted balance
+	_, err = pool.Exec(context.Background(), `
+		INSERT INTO credit_transactions (user_id, amount, transaction_type) SELECT id, 1, 'refund' FROM users WHERE email = $1`, ownerEmail)
+	assert.ErrorContains(t, err, "but the ledger ends at 3")
+	assert.Equal(t, 3, creditBalance(t, ownerEmail))
+}
+
+func listCreditAccounts(t *testing.T, token string) []service.CreditAccountDTO {
+	w := performRequest(router, "GET", "/api/v1/me/credits/accounts", token, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var accounts []service.CreditAccountDTO
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &accounts))
+	return accounts
+}
+
+func TestE2E_CreditTransfer(t *testing.T) {
+	ownerEmail := getEmail()
+	token := registerAndLogin(t, ownerEmail, "Transfer", "Owner")
+	w := performRequest(router, "POST", "/api/v1/subscriptions", token, map[string]string{"plan": "discovery", "frequency": "monthly"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	propID := createLongTermProperty(t, token, "3 rue du Registre")
+
+	accounts := listCreditAccounts(t, token)
+	require.Len(t, accounts, 2)
+	global, wallet := accounts[0], accounts[1]
+	assert.Equal(t, service.CreditWalletGlobal, global.Wallet)
+	assert.Equal(t, int32(3), global.Balance)
+	assert.Equal(t, propID, *wallet.PropertyID)
+	assert.Equal(t, int32(20), wallet.Balance)
+
+	// From the property to the global wallet
+	w = performRequest(router, "POST", "/api/v1/me/credits/transfers", token, map[string]int32{
+		"from_account_id": wallet.ID, "to_account_id": global.ID, "amount": 5,
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	accounts = listCreditAccounts(t, token)
+	assert.Equal(t, int32(8), accounts[0].Balance)
+	assert.Equal(t, int32(15), accounts[1].Balance)
+	assert.Equal(t, 8, creditBalance(t, ownerEmail))
+
+	var vacancy int
+	require.NoError(t, pool.QueryRow(context.Background(), `SELECT vacancy_credits FROM properties WHERE id = $1`, propID).Scan(&vacancy))
+	assert.Equal(t, 15, vacancy, "vacancy credits follow the wallet")
+
+	// Both lines of the transfer are in the history, as one entry
+	page := listCreditTransactions(t, token, "?type=transfer")
+	require.Len(t, page.Transactions, 2)
+	var entries int
+	require.NoError(t, pool.QueryRow(context.Background(), `
+		SELECT COUNT(DISTINCT entry_id) FROM credit_transactions WHERE id = ANY($1)`,
+		[]int32{page.Transactions[0].ID, page.Transactions[1].ID}).Scan(&entries))
+	assert.Equal(t, 1, entries)
+
+	w = performRequest(router, "POST", "/api/v1/me/credits/transfers", token, map[string]int32{
+		"from_account_id": global.ID, "to_account_id": wallet.ID, "amount": 9,
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code, "more than the global balance")
+
+	// Wallets of another owner are not reachable
+	other := registerAndLogin(t, getEmail(), "Other", "Owner")
+	w = performRequest(router, "POST", "/api/v1/me/credits/transfers", other, map[string]int32{
+		"from_account_id": wallet.ID, "to_account_id": global.ID, "amount": 1,
+	})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// The ledger still matches the balances of both wallets
+	var drifts []postgres.ListCreditBalanceDriftsRow
+	require.NoError(t, postgres.NewTxManager(pool).WithTx(context.Background(), func(q postgres.Querier) error {
+		var err error
+		drifts, err = q.ListCreditBalanceDrifts(context.Background())
+		return err
+	}))
+	for _, d := range drifts {
+		assert.NotEqual(t, wallet.ID, d.AccountID)
+		assert.NotEqual(t, global.ID, d.AccountID)
+	}
+}
+
+func TestE2E_CreditPropertyTopUp(t *testing.T) {
+	ownerEmail := getEmail()
+	token := registerAndLogin(t, ownerEmail, "TopUp", "Owner")
+	w := performRequest(router, "POST", "/api/v1/subscriptions", token, map[string]string{"plan": "discovery", "frequency": "monthly"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	propID := createLongTermProperty(t, token, "4 rue du Registre")
+
+	w = performRequest(router, "POST", "/api/v1/solvency/credits", token, map[string]interface{}{
+		"pack_type": "pack_20", "payment_method_id": fakepayment.CardSuccess, "property_id": propID,
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// The pack goes to the property's wallet, the global wallet keeps its welcome credits
+	accounts := listCreditAccounts(t, token)
+	require.Len(t, accounts, 2)
+	assert.Equal(t, int32(3), accounts[0].Balance)
+	assert.Equal(t, int32(40), accounts[1].Balance)
+	page := listCreditTransactions(t, token, "?type=pack_purchase")
+	require.Len(t, page.Transactions, 1)
+	assert.Equal(t, propID, *page.Transactions[0].PropertyID)
+
+	// Not for the property of another owner
+	other := registerAndLogin(t, getEmail(), "Other", "Owner")
+	w = performRequest(router, "POST", "/api/v1/solvency/credits", other, map[string]interface{}{
+		"pack_type": "pack_20", "payment_method_id": fakepayment.CardSuccess, "property_id": propID,
+	})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -64,6 +64,10 @@ func TestMain(m *testing.M) {
 	viper.Set("JWT_SECRET", "test_secret_for_e2e")
 	viper.Set("ENV", "test")
 	viper.Set("GIN_MODE", "test")
+	viper.Set("OPEN_BANKING_PROVIDER", "fake")
+	viper.Set("OPEN_BANKING_WEBHOOK_SECRET", "e2e-open-banking-secret")
+	viper.Set("PAYMENT_PROVIDER", "fake")
+	viper.Set("PAYMENT_WEBHOOK_SECRET", "e2e-payment-secret")
 
 	// Create temp storage for E2E
 	storageDir, _ := os.MkdirTemp("", "e2e_storage")
@@ -295,9 +299,8 @@ func TestE2E_ErrorCases(t *testing.T) {
 		assert.Fail(t, "vacancy_credits missing in response")
 	}
 
-	// Manually set vacancy credits to 0 to test insufficient funds
-	_, err := pool.Exec(context.Background(), "UPDATE properties SET vacancy_credits = 0 WHERE id = $1", propID)
-	require.NoError(t, err)
+	// Empty the property's wallet to test insufficient funds
+	setPropertyCredits(t, int32(propID), 0)
 
 	w = performRequest(router, "POST", "/api/v1/solvency/check", token, map[string]interface{}{
 		"property_id": propID, "candidate_email": "broke@test.com",
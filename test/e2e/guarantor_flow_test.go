@@ -0,0 +1,121 @@
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// uploadGuarantorDocument posts a small PDF through the guarantor's public link.
+func uploadGuarantorDocument(t *testing.T, guarantorToken, docType string) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	require.NoError(t, mw.WriteField("type", docType))
+	part, err := mw.CreateFormFile("file", docType+".pdf")
+	require.NoError(t, err)
+	part.Write([]byte("%PDF-1.4\n1 0 obj << >> endobj\ntrailer << >>\n%%EOF\n"))
+	require.NoError(t, mw.Close())
+
+	req, _ := http.NewRequest("POST", "/api/v1/solvency/public/guarantor/"+guarantorToken+"/documents", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+}
+
+func TestE2E_DocumentOnlyGuarantorGetsDeed(t *testing.T) {
+	ownerEmail := getEmail()
+	candidateEmail := "candidate_" + randomString() + "@example.com"
+
+	ownerToken := registerAndLogin(t, ownerEmail, "Gaston", "Bailleur")
+	performRequest(router, "POST", "/api/v1/subscriptions", ownerToken, map[string]string{
+		"plan": "discovery", "frequency": "monthly",
+	})
+	w := performRequest(router, "POST", "/api/v1/auth/login", "", map[string]string{
+		"email": ownerEmail, "password": "password123",
+	})
+	var loginResp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &loginResp)
+	ownerToken = loginResp["token"].(string)
+	propID := createLongTermProperty(t, ownerToken, "12 rue des Garants")
+
+	// 1. Check with a guarantor invited by the candidate
+	w = performRequest(router, "POST", "/api/v1/solvency/check", ownerToken, map[string]interface{}{
+		"property_id": propID, "candidate_email": candidateEmail,
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var checkResp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &checkResp)
+	checkToken := checkResp["token"].(string)
+
+	w = performRequest(router, "POST", "/api/v1/solvency/public/check/"+checkToken+"/guarantors", "", map[string]string{
+		"email": "garant_" + randomString() + "@example.com", "first_name": "Paul", "last_name": "Garant",
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var guarantorResp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &guarantorResp)
+	guarantorToken := path.Base(guarantorResp["verification_url"].(string))
+
+	// 2. The guarantor completes their file with documents only
+	uploadGuarantorDocument(t, guarantorToken, "identity")
+	w = performRequest(router, "POST", "/api/v1/solvency/public/guarantor/"+guarantorToken+"/submit", "", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code, "a proof of income is required")
+
+	uploadGuarantorDocument(t, guarantorToken, "payslip")
+	w = performRequest(router, "POST", "/api/v1/solvency/public/guarantor/"+guarantorToken+"/submit", "", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var view map[string]interface{}
+	w = performRequest(router, "GET", "/api/v1/solvency/public/guarantor/"+guarantorToken, "", nil)
+	json.Unmarshal(w.Body.Bytes(), &view)
+	assert.Equal(t, "completed", view["status"])
+	assert.Equal(t, false, view["deed_available"])
+
+	// 3. The candidate is approved, then signs the lease through the owner's invitation
+	w = performRequest(router, "POST", "/api/v1/solvency/public/check/"+checkToken+"/callback", "", map[string]interface{}{
+		"transactions": []map[string]interface{}{
+			{"amount": 3000.0, "description": "Salary Jan", "date": "2024-01-01T00:00:00Z"},
+			{"amount": 3000.0, "description": "Salary Feb", "date": "2024-02-01T00:00:00Z"},
+			{"amount": 3000.0, "description": "Salary Mar", "date": "2024-03-01T00:00:00Z"},
+		},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	candidateToken := registerAndLogin(t, candidateEmail, "Alice", "Candidate")
+	w = performRequest(router, "POST", "/api/v1/invitations", ownerToken, map[string]interface{}{
+		"property_id": propID, "email": candidateEmail,
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var invResp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &invResp)
+	w = performRequest(router, "POST", "/api/v1/invitations/accept", candidateToken, map[string]string{
+		"token": invResp["token"].(string),
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// 4. The guarantor is bound to the lease: they reproduce the mention and get their deed
+	w = performRequest(router, "GET", "/api/v1/solvency/public/guarantor/"+guarantorToken, "", nil)
+	json.Unmarshal(w.Body.Bytes(), &view)
+	require.Equal(t, true, view["deed_available"])
+
+	w = performRequest(router, "GET", "/api/v1/solvency/public/guarantor/"+guarantorToken+"/mention", "", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var mention map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &mention)
+	w = performRequest(router, "POST", "/api/v1/solvency/public/guarantor/"+guarantorToken+"/mention", "", map[string]interface{}{
+		"text": mention["text"],
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = performRequest(router, "GET", "/api/v1/solvency/public/guarantor/"+guarantorToken+"/deed", "", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "Paul")
+	assert.Contains(t, w.Body.String(), "Mention reproduite par la caution le")
+}
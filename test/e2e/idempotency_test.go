@@ -0,0 +1,46 @@
+package e2e
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func performIdempotentRequest(path, token, key string, body interface{}) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", path, bytes.NewBuffer(toJson(body)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Idempotency-Key", key)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestE2E_IdempotentPurchase(t *testing.T) {
+	email := getEmail()
+	token := registerAndLogin(t, email, "Rémi", "Retry")
+	key := "purchase-" + randomString()
+	body := map[string]string{"pack_type": "pack_20"}
+
+	first := performIdempotentRequest("/api/v1/solvency/credits", token, key, body)
+	require.Equal(t, http.StatusOK, first.Code)
+
+	// Client retry after a timeout: same response, credited once
+	retry := performIdempotentRequest("/api/v1/solvency/credits", token, key, body)
+	require.Equal(t, http.StatusOK, retry.Code)
+	assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+	assert.JSONEq(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, 20, creditBalance(t, email))
+
+	// Same key, another request
+	conflict := performIdempotentRequest("/api/v1/subscriptions", token, key, map[string]string{"plan": "discovery", "frequency": "monthly"})
+	assert.Equal(t, http.StatusConflict, conflict.Code)
+
+	// Keys are per user
+	other := registerAndLogin(t, getEmail(), "Other", "User")
+	assert.Equal(t, http.StatusOK, performIdempotentRequest("/api/v1/solvency/credits", other, key, body).Code)
+}
@@ -0,0 +1,69 @@
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	fakepayment "seculoc-back/internal/adapter/payment/fake"
+	"seculoc-back/internal/core/service"
+)
+
+func TestE2E_InvoicesIssuedOnPayment(t *testing.T) {
+	email := getEmail()
+	token := registerAndLogin(t, email, "Iris", "Facture")
+
+	// Declined purchase: no invoice issued
+	w := performRequest(router, "POST", "/api/v1/solvency/credits", token, map[string]string{
+		"pack_type": "pack_20", "payment_method_id": fakepayment.CardDeclined,
+	})
+	require.Equal(t, http.StatusPaymentRequired, w.Code)
+	w = performRequest(router, "POST", "/api/v1/solvency/credits", token, map[string]string{
+		"pack_type": "pack_20", "payment_method_id": fakepayment.CardSuccess,
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest(router, "GET", "/api/v1/me/invoices", token, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var invoices []service.InvoiceDTO
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &invoices))
+	require.Len(t, invoices, 1)
+	invoice := invoices[0]
+	assert.True(t, strings.HasPrefix(invoice.Number, fmt.Sprintf("F-%d-", time.Now().Year())), invoice.Number)
+	assert.Equal(t, service.InvoiceKindInvoice, invoice.Kind)
+	assert.Equal(t, int32(1990), invoice.AmountCents)
+	assert.Equal(t, int32(1658), invoice.AmountExclVatCents)
+	assert.Equal(t, int32(332), invoice.VatCents)
+
+	// Archived on first download, then served as it is
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/me/invoices/%d/pdf", invoice.ID), token, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), strings.ToLower(invoice.Number))
+	var documents int
+	require.NoError(t, pool.QueryRow(context.Background(),
+		`SELECT COUNT(*) FROM documents WHERE document_type = 'invoice' AND entity_id = $1`, invoice.ID).Scan(&documents))
+	assert.Equal(t, 1, documents)
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/me/invoices/%d/pdf", invoice.ID), token, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, pool.QueryRow(context.Background(),
+		`SELECT COUNT(*) FROM documents WHERE document_type = 'invoice' AND entity_id = $1`, invoice.ID).Scan(&documents))
+	assert.Equal(t, 1, documents)
+
+	// Issued invoices are immutable
+	_, err := pool.Exec(context.Background(), `UPDATE invoices SET amount_cents = 1 WHERE id = $1`, invoice.ID)
+	assert.Error(t, err)
+	_, err = pool.Exec(context.Background(), `DELETE FROM invoices WHERE id = $1`, invoice.ID)
+	assert.Error(t, err)
+
+	// Not visible to other users
+	other := registerAndLogin(t, getEmail(), "Otto", "Autre")
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/me/invoices/%d/pdf", invoice.ID), other, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
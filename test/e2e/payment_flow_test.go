@@ -0,0 +1,107 @@
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	fakepayment "seculoc-back/internal/adapter/payment/fake"
+	"seculoc-back/internal/core/service"
+)
+
+func creditBalance(t *testing.T, email string) int {
+	var balance int
+	err := pool.QueryRow(context.Background(), `
+		SELECT COALESCE(SUM(ct.amount), 0)::int FROM credit_transactions ct
+		JOIN users u ON u.id = ct.user_id WHERE u.email = $1 AND ct.property_id IS NULL`, email).Scan(&balance)
+	require.NoError(t, err)
+	return balance
+}
+
+func deliverPaymentWebhook(t *testing.T, event service.PaymentEvent) *httptest.ResponseRecorder {
+	// The fake provider signs statelessly: any instance with the same secret can build the delivery
+	payload, headers := fakepayment.New(viper.GetString("PAYMENT_WEBHOOK_SECRET")).BuildWebhook(event)
+	req, _ := http.NewRequest("POST", "/api/v1/webhooks/payments", bytes.NewReader(payload))
+	req.Header = headers
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestE2E_PaymentOutcomes(t *testing.T) {
+	email := getEmail()
+	token := registerAndLogin(t, email, "Paul", "Payeur")
+
+	// Declined card: nothing granted
+	w := performRequest(router, "POST", "/api/v1/solvency/credits", token, map[string]string{
+		"pack_type": "pack_20", "payment_method_id": fakepayment.CardDeclined,
+	})
+	require.Equal(t, http.StatusPaymentRequired, w.Code)
+	assert.Equal(t, 0, creditBalance(t, email))
+
+	// Accepted card
+	w = performRequest(router, "POST", "/api/v1/solvency/credits", token, map[string]string{
+		"pack_type": "pack_20", "payment_method_id": fakepayment.CardSuccess,
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 20, creditBalance(t, email))
+
+	var status string
+	err := pool.QueryRow(context.Background(), `
+		SELECT t.status FROM transactions t JOIN users u ON u.id = t.user_id
+		WHERE u.email = $1 ORDER BY t.id DESC LIMIT 1`, email).Scan(&status)
+	require.NoError(t, err)
+	assert.Equal(t, "success", status)
+}
+
+func TestE2E_PaymentThreeDSecureCompletedByWebhook(t *testing.T) {
+	email := getEmail()
+	token := registerAndLogin(t, email, "Sophie", "Secure")
+
+	w := performRequest(router, "POST", "/api/v1/subscriptions", token, map[string]string{
+		"plan": "serenity", "frequency": "monthly", "payment_method_id": fakepayment.CardThreeDS,
+	})
+	require.Equal(t, http.StatusAccepted, w.Code)
+	var resp struct {
+		Payment service.PaymentResult `json:"payment"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, service.PaymentRequiresAction, resp.Payment.Status)
+	assert.NotEmpty(t, resp.Payment.NextActionURL)
+
+	// Not active, no credits until the customer authenticated
+	var subStatus, intentID string
+	err := pool.QueryRow(context.Background(), `
+		SELECT s.status FROM subscriptions s JOIN users u ON u.id = s.user_id WHERE u.email = $1`, email).Scan(&subStatus)
+	require.NoError(t, err)
+	assert.Equal(t, "incomplete", subStatus)
+	assert.Equal(t, 0, creditBalance(t, email))
+
+	err = pool.QueryRow(context.Background(), "SELECT stripe_payment_intent_id FROM transactions WHERE id = $1", resp.Payment.TransactionID).Scan(&intentID)
+	require.NoError(t, err)
+
+	// Forged delivery refused
+	req, _ := http.NewRequest("POST", "/api/v1/webhooks/payments", bytes.NewBufferString(`{"id":"evt_forged","type":"payment_intent.succeeded"}`))
+	req.Header.Set(fakepayment.SignatureHeader, "t=1,v1=00")
+	forged := httptest.NewRecorder()
+	router.ServeHTTP(forged, req)
+	assert.Equal(t, http.StatusUnauthorized, forged.Code)
+
+	// 3-D Secure completed, delivered twice
+	event := service.PaymentEvent{ID: "evt_" + randomString(), Type: service.WebhookPaymentSucceeded, PaymentIntentID: intentID}
+	require.Equal(t, http.StatusOK, deliverPaymentWebhook(t, event).Code)
+	require.Equal(t, http.StatusOK, deliverPaymentWebhook(t, event).Code)
+
+	err = pool.QueryRow(context.Background(), `
+		SELECT s.status FROM subscriptions s JOIN users u ON u.id = s.user_id WHERE u.email = $1`, email).Scan(&subStatus)
+	require.NoError(t, err)
+	assert.Equal(t, "active", subStatus)
+	assert.Equal(t, 20, creditBalance(t, email), "first month of serenity credits, granted once")
+}
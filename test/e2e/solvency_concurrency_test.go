@@ -68,15 +68,14 @@ func TestE2E_SolvencyConcurrency(t *testing.T) {
 	json.Unmarshal(w.Body.Bytes(), &propResp)
 	propID := int(propResp["id"].(float64))
 
-	// Manually set credits to 5 directly in DB to control the test strictly
+	// Bring the property's wallet to 5 credits through the ledger to control the test strictly
 	// We need 5 credits. We launch 10 requests. 5 should succeed, 5 should fail (or use global?).
 	// The logic is: Uses Property Credit if > 0. Else Global.
 	// If Global empty, fail.
 	// So if we have 5 Prop Credits, 0 Global.
 	// 5 succeed, 5 fail.
 
-	_, err := pool.Exec(context.Background(), "UPDATE properties SET vacancy_credits = 5 WHERE id = $1", propID)
-	require.NoError(t, err)
+	setPropertyCredits(t, int32(propID), 5)
 
 	// Ensure Global Balance is 0
 	// (It should be 3 from bonus if 'discovery'? Not sure logic logic. Let's wipe transactions or balance).
@@ -126,7 +125,7 @@ func TestE2E_SolvencyConcurrency(t *testing.T) {
 	// Verify final state
 	row := pool.QueryRow(context.Background(), "SELECT vacancy_credits FROM properties WHERE id = $1", propID)
 	var finalCredits int
-	err = row.Scan(&finalCredits)
+	err := row.Scan(&finalCredits)
 	require.NoError(t, err)
 
 	assert.GreaterOrEqual(t, finalCredits, 0, "Credits should not be negative")
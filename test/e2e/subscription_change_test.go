@@ -0,0 +1,197 @@
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	fakepayment "seculoc-back/internal/adapter/payment/fake"
+	"seculoc-back/internal/adapter/storage/postgres"
+	"seculoc-back/internal/core/service"
+	"seculoc-back/internal/platform/email"
+)
+
+func createLongTermProperty(t *testing.T, token, address string) int32 {
+	w := performRequest(router, "POST", "/api/v1/properties", token, map[string]interface{}{
+		"address": address, "rental_type": "long_term", "details": map[string]string{}, "rent_amount": 800,
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var prop struct {
+		ID int32 `json:"id"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &prop))
+	return prop.ID
+}
+
+// endCurrentPeriod moves the subscription of a user, with its invoices, one month back so that its
+// period ends today, then runs the billing engine.
+func endCurrentPeriod(t *testing.T, ownerEmail string) {
+	_, err := pool.Exec(context.Background(), `
+		UPDATE invoices i SET period_start = i.period_start - INTERVAL '1 month', period_end = i.period_end - INTERVAL '1 month'
+		FROM subscriptions s JOIN users u ON u.id = s.user_id
+		WHERE i.subscription_id = s.id AND u.email = $1 AND s.status = 'active' AND i.period_start IS NOT NULL`, ownerEmail)
+	require.NoError(t, err)
+	_, err = pool.Exec(context.Background(), `
+		UPDATE subscriptions s SET start_date = s.start_date - INTERVAL '1 month',
+			current_period_start = s.current_period_start - INTERVAL '1 month', current_period_end = CURRENT_DATE
+		FROM users u WHERE u.id = s.user_id AND u.email = $1 AND s.status = 'active'`, ownerEmail)
+	require.NoError(t, err)
+	billing := service.NewBillingService(postgres.NewTxManager(pool), email.NewMockEmailSender(zap.NewNop()), nil, zap.NewNop())
+	require.NoError(t, billing.RenewSubscriptions(context.Background()))
+}
+
+func TestE2E_PlanChanges(t *testing.T) {
+	ownerEmail := getEmail()
+	token := registerAndLogin(t, ownerEmail, "Gaspard", "Formule")
+
+	w := performRequest(router, "POST", "/api/v1/subscriptions", token, map[string]string{
+		"plan": "serenity", "frequency": "monthly", "payment_method_id": fakepayment.CardSuccess,
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = performRequest(router, "POST", "/api/v1/subscriptions", token, map[string]string{"plan": "premium", "frequency": "monthly"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// Upgrade on the first day: the whole Premium month less the unused Serenity month
+	w = performRequest(router, "POST", "/api/v1/subscriptions/change", token, map[string]string{
+		"plan": "premium", "payment_method_id": fakepayment.CardSuccess,
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var change service.PlanChange
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &change))
+	assert.Equal(t, service.PlanChangeUpgraded, change.Status)
+	assert.Equal(t, int32(2000), change.AmountDueCents)
+	assert.Equal(t, 30, creditBalance(t, ownerEmail), "serenity credits topped up to premium")
+
+	ids := []int32{
+		createLongTermProperty(t, token, "1 rue des Lilas"),
+		createLongTermProperty(t, token, "2 rue des Lilas"),
+		createLongTermProperty(t, token, "3 rue des Lilas"),
+	}
+
+	// Serenity allows one long-term property: the owner must choose two to archive
+	w = performRequest(router, "POST", "/api/v1/subscriptions/change", token, map[string]string{"plan": "serenity"})
+	require.Equal(t, http.StatusConflict, w.Code)
+	var toArchive struct {
+		ToArchive  int                          `json:"to_archive"`
+		Properties []service.ArchivableProperty `json:"properties"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &toArchive))
+	assert.Equal(t, 2, toArchive.ToArchive)
+	assert.Len(t, toArchive.Properties, 3)
+
+	w = performRequest(router, "POST", "/api/v1/subscriptions/change", token, map[string]interface{}{
+		"plan": "serenity", "archive_property_ids": ids[:2],
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &change))
+	assert.Equal(t, service.PlanChangeScheduled, change.Status)
+
+	// Still Premium until the period end
+	w = performRequest(router, "GET", "/api/v1/subscriptions/current", token, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var state service.SubscriptionState
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &state))
+	assert.Equal(t, "premium", state.Plan)
+	assert.Equal(t, "serenity", state.ScheduledPlan)
+
+	endCurrentPeriod(t, ownerEmail)
+
+	w = performRequest(router, "GET", "/api/v1/subscriptions/current", token, nil)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &state))
+	assert.Equal(t, "serenity", state.Plan)
+	assert.Empty(t, state.ScheduledPlan)
+	var active int
+	require.NoError(t, pool.QueryRow(context.Background(),
+		`SELECT COUNT(*) FROM properties WHERE id = ANY($1) AND is_active`, ids).Scan(&active))
+	assert.Equal(t, 1, active)
+
+	// Cancelled at period end: access kept, then ended by the billing engine
+	w = performRequest(router, "POST", "/api/v1/subscriptions/cancel", token, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &state))
+	assert.Equal(t, "active", state.Status)
+	assert.True(t, state.CancelAtPeriodEnd)
+	w = performRequest(router, "POST", "/api/v1/subscriptions/change", token, map[string]string{"plan": "premium"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	endCurrentPeriod(t, ownerEmail)
+
+	w = performRequest(router, "GET", "/api/v1/subscriptions/current", token, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = performRequest(router, "GET", "/api/v1/subscriptions/history", token, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var history []service.SubscriptionEventDTO
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &history))
+	var types []string
+	for _, e := range history {
+		types = append(types, e.Type)
+	}
+	assert.Equal(t, []string{
+		service.SubscriptionEventSubscribed, service.SubscriptionEventUpgraded, service.SubscriptionEventDowngradeScheduled,
+		service.SubscriptionEventDowngraded, service.SubscriptionEventCancellationScheduled, service.SubscriptionEventCancelled,
+	}, types, fmt.Sprintf("%+v", history))
+	assert.Equal(t, "premium", history[1].ToPlan)
+	assert.ElementsMatch(t, ids[:2], history[3].ArchivedPropertyIDs)
+}
+
+func TestE2E_PropertySlots(t *testing.T) {
+	ownerEmail := getEmail()
+	token := registerAndLogin(t, ownerEmail, "Hortense", "Slots")
+
+	w := performRequest(router, "POST", "/api/v1/subscriptions", token, map[string]string{
+		"plan": "serenity", "frequency": "monthly", "payment_method_id": fakepayment.CardSuccess,
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// Bought on the first day of the period: the whole month of 2 slots
+	w = performRequest(router, "POST", "/api/v1/subscriptions/slots", token, map[string]interface{}{
+		"additional_slots": 2, "payment_method_id": fakepayment.CardSuccess,
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var change service.SlotChange
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &change))
+	assert.Equal(t, service.SlotChangeAdded, change.Status)
+	assert.Equal(t, int32(1980), change.AmountDueCents)
+	assert.Equal(t, int32(3), change.MaxPropertiesLimit)
+
+	ids := []int32{
+		createLongTermProperty(t, token, "1 rue des Slots"),
+		createLongTermProperty(t, token, "2 rue des Slots"),
+	}
+
+	// Removing both slots would leave room for 1 of the 2 properties
+	w = performRequest(router, "DELETE", "/api/v1/subscriptions/slots", token, map[string]int{"slots": 2})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = performRequest(router, "DELETE", "/api/v1/subscriptions/slots", token, map[string]int{"slots": 1})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var state service.SubscriptionState
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &state))
+	require.NotNil(t, state.PropertySlots)
+	assert.Equal(t, int32(2), state.PropertySlots.Quantity)
+	require.NotNil(t, state.PropertySlots.QuantityAtRenewal)
+	assert.Equal(t, int32(1), *state.PropertySlots.QuantityAtRenewal)
+	assert.Equal(t, int32(3), state.MaxPropertiesLimit, "usable until the period end")
+
+	endCurrentPeriod(t, ownerEmail)
+
+	w = performRequest(router, "GET", "/api/v1/subscriptions/current", token, nil)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &state))
+	assert.Equal(t, int32(2), state.MaxPropertiesLimit)
+	assert.Nil(t, state.PropertySlots.QuantityAtRenewal)
+	var renewal int32
+	require.NoError(t, pool.QueryRow(context.Background(), `
+		SELECT i.amount_cents FROM invoices i JOIN users u ON u.id = i.user_id
+		WHERE u.email = $1 AND i.period_start = CURRENT_DATE`, ownerEmail).Scan(&renewal))
+	assert.Equal(t, int32(990+990), renewal, "plan and the extra property left")
+	var active int
+	require.NoError(t, pool.QueryRow(context.Background(),
+		`SELECT COUNT(*) FROM properties WHERE id = ANY($1) AND is_active`, ids).Scan(&active))
+	assert.Equal(t, 2, active)
+}